@@ -0,0 +1,24 @@
+// Package v1alpha1 holds the GameServer claim types shared by the REST API,
+// the admission webhook and the status controller. The Crossplane XRD in
+// crossplane/gameplane/definition.yaml is generated from them with
+// controller-gen's CRD generator, so the API's request types and the
+// cluster's schema cannot drift apart.
+//
+// Field comments become schema descriptions and fields take the usual
+// kubebuilder markers. Free-form maps need +kubebuilder:validation:Schemaless
+// with a Type, and free-form lists +gameplane:schema:objects. Fields marked
+// +gameplane:schema:skip exist only in the API, and +gameplane:schema:gameTypes
+// fills an enum from the game catalog.
+//
+// +groupName=gameplane.kubelize.io
+//
+//go:generate go run ../../../cmd/xrdgen -out ../../../../crossplane/gameplane/definition.yaml
+package v1alpha1
+
+const (
+	// Group is the API group of GamePlane resources
+	Group = "gameplane.kubelize.io"
+
+	// Version is the API version of these types
+	Version = "v1alpha1"
+)
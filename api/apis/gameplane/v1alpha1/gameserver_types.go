@@ -0,0 +1,399 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GameServer is a game server claim. Crossplane routes it to the child
+// composition of its game type.
+//
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Game Type",type=string,JSONPath=.spec.gameType
+// +kubebuilder:printcolumn:name="Server Name",type=string,JSONPath=.spec.serverName
+// +kubebuilder:printcolumn:name="Child Type",type=string,JSONPath=.status.childType
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=.status.phase
+// +kubebuilder:printcolumn:name="Players",type=integer,JSONPath=.status.playersOnline
+// +kubebuilder:printcolumn:name="Server IP",type=string,JSONPath=.status.serverIP
+// +kubebuilder:printcolumn:name="Health",type=integer,JSONPath=.status.health.score
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=.metadata.creationTimestamp
+type GameServer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              GameServerSpec   `json:"spec,omitempty"`
+	Status            GameServerStatus `json:"status,omitempty"`
+}
+
+// GameServerList is a list of GameServers
+type GameServerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GameServer `json:"items"`
+}
+
+// GameServerSpec routes to a game-specific child composition
+type GameServerSpec struct {
+	// Type of game server (determines child composition)
+	// +gameplane:schema:gameTypes
+	GameType string `json:"gameType" binding:"required"`
+
+	// Display name for the game server
+	// +kubebuilder:validation:MaxLength=64
+	ServerName string `json:"serverName,omitempty"`
+
+	// Server description visible to players
+	// +kubebuilder:validation:MaxLength=256
+	ServerDescription string `json:"serverDescription,omitempty"`
+
+	// Message of the day shown to players joining the server
+	// +kubebuilder:validation:MaxLength=256
+	MOTD string `json:"motd,omitempty"`
+
+	// Server icon for server browsers, as an https URL or a
+	// data:image/png;base64 URI
+	// +kubebuilder:validation:MaxLength=32768
+	Icon string `json:"icon,omitempty"`
+
+	// Publish the name, description, MOTD, icon, players and endpoint
+	// through the public status API, which needs no credentials
+	PublicStatus bool `json:"publicStatus,omitempty"`
+
+	// Resource allocation for the game server
+	Resources GameServerResources `json:"resources,omitempty"`
+
+	// Network configuration
+	Networking GameServerNetworking `json:"networking,omitempty"`
+
+	// Game-specific configuration (schema varies by gameType)
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	GameConfig map[string]interface{} `json:"gameConfig,omitempty"`
+
+	// World parameters, written through to the game's gameConfig fields by
+	// the API, so only games with a world schema accept them
+	// +gameplane:schema:skip
+	World *GameServerWorld `json:"world,omitempty"`
+
+	// Where the server runs; the GamePlane API picks a cluster in the region
+	Placement *GameServerPlacement `json:"placement,omitempty"`
+
+	// Scale the game workload to zero while keeping its data
+	Stopped bool `json:"stopped,omitempty"`
+
+	// Scheduled start and automatic expiry, for event servers
+	Lifecycle *GameServerLifecycle `json:"lifecycle,omitempty"`
+
+	// Backend GameServers players are forwarded to, for proxy game types
+	Proxy *GameServerProxy `json:"proxy,omitempty"`
+
+	// Read-only assets from the shared asset library, such as mod packs
+	// and maps, mounted into the game container
+	SharedVolumes []GameServerSharedVolume `json:"sharedVolumes,omitempty"`
+
+	// Services the server needs, such as a database or an auth service.
+	// Their reachability is reported in the DependenciesReady condition.
+	Dependencies []GameServerDependency `json:"dependencies,omitempty"`
+
+	// Advanced server configuration
+	Advanced GameServerAdvanced `json:"advanced,omitempty"`
+}
+
+// GameServerWorld holds world parameters. They are stored in the game's own
+// gameConfig fields, so only games with a world schema accept them.
+type GameServerWorld struct {
+	Name string `json:"name,omitempty"`
+	Seed string `json:"seed,omitempty"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// GameServerResources defines resource requirements
+type GameServerResources struct {
+	// CPU allocation (e.g., "2", "4")
+	CPU string `json:"cpu,omitempty"`
+
+	// Memory allocation (e.g., "4Gi", "8Gi")
+	Memory string `json:"memory,omitempty"`
+
+	// Persistent storage size
+	StorageSize string `json:"storageSize,omitempty"`
+
+	// Storage class for persistent volumes
+	StorageClass string `json:"storageClass,omitempty"`
+}
+
+// GameServerNetworking defines networking configuration
+type GameServerNetworking struct {
+	// Kubernetes service type
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer
+	// +kubebuilder:default=LoadBalancer
+	ServiceType string `json:"serviceType,omitempty"`
+
+	// Create ingress for web admin
+	// +kubebuilder:default=false
+	EnableIngress bool `json:"enableIngress,omitempty"`
+
+	// Hostname for ingress
+	IngressHost string `json:"ingressHost,omitempty"`
+}
+
+// GameServerPlacement selects where a GameServer runs
+type GameServerPlacement struct {
+	// Region of the cluster running the server
+	Region string `json:"region,omitempty"`
+}
+
+// GameServerLifecycle schedules when an ephemeral GameServer runs. The
+// GamePlane API starts stopped servers at StartAt and expires them at
+// ExpiresAt, or TTLAfterReady after they first became ready.
+type GameServerLifecycle struct {
+	// Time to start a server created stopped
+	StartAt *metav1.Time `json:"startAt,omitempty"`
+
+	// Time the server expires
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// Lifetime after first becoming ready (e.g. "4h"); resolved into
+	// expiresAt once the server is ready
+	TTLAfterReady string `json:"ttlAfterReady,omitempty"`
+
+	// What happens at expiry: Delete removes the server, Stop scales it
+	// down and moves it to the trash until trashRetention passes
+	// +kubebuilder:validation:Enum=Delete;Stop
+	// +kubebuilder:default=Delete
+	ExpiryAction string `json:"expiryAction,omitempty"`
+
+	// How long a stopped server is kept before it is deleted (e.g. "72h")
+	// +kubebuilder:default="168h"
+	TrashRetention string `json:"trashRetention,omitempty"`
+
+	// Durations before expiry to send warnings (e.g. "1h", "15m")
+	WarnBefore []string `json:"warnBefore,omitempty"`
+
+	// Scripts run before the game starts and when it stops
+	Hooks *GameServerLifecycleHooks `json:"hooks,omitempty"`
+}
+
+// GameServerLifecycleHooks are user scripts stored in ConfigMaps of the
+// GameServer's namespace, such as custom map downloads or permission fixes
+type GameServerLifecycleHooks struct {
+	// Run in order as init containers with the game data mounted, before
+	// the game container starts
+	PreStart []GameServerLifecycleHook `json:"preStart,omitempty"`
+
+	// Run in order in the game container when its pod is stopped, before
+	// the game process is terminated
+	PostStop []GameServerLifecycleHook `json:"postStop,omitempty"`
+}
+
+// GameServerLifecycleHook is one script of a lifecycle hook
+type GameServerLifecycleHook struct {
+	// Name identifies the hook in the activity feed
+	// +kubebuilder:validation:MaxLength=40
+	Name string `json:"name"`
+
+	// ConfigMap in the GameServer's namespace holding the script
+	ConfigMap string `json:"configMap"`
+
+	// Key of the script in the ConfigMap
+	Key string `json:"key"`
+
+	// Image the script runs in; pre-start hooks only, defaults to busybox
+	Image string `json:"image,omitempty"`
+}
+
+// GameServerProxy lists the backends of a proxy GameServer. The GamePlane
+// API renders them into the proxy's routing config and regenerates it as
+// backends come and go.
+type GameServerProxy struct {
+	// How the proxy picks a backend for new connections; the game type
+	// lists the strategies it supports
+	Strategy string `json:"strategy,omitempty"`
+
+	// Backend GameServers in the proxy's namespace
+	Backends []GameServerBackend `json:"backends,omitempty"`
+}
+
+// GameServerBackend is a GameServer behind a proxy
+type GameServerBackend struct {
+	// Name of the backend GameServer
+	Name string `json:"name"`
+
+	// Share of new connections under round-robin
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=1
+	Weight int `json:"weight,omitempty"`
+}
+
+// GameServerSharedVolume mounts an asset of the shared asset library. The
+// GamePlane API provides the asset's volume in the server's namespace.
+type GameServerSharedVolume struct {
+	// Name of the asset in the shared asset library
+	// +kubebuilder:validation:MaxLength=40
+	Name string `json:"name"`
+
+	// Absolute path the asset is mounted at in the game container
+	MountPath string `json:"mountPath"`
+
+	// Directory within the asset to mount instead of its root
+	SubPath string `json:"subPath,omitempty"`
+}
+
+// GameServerDependency is a service the game server needs. It names either
+// a Service in the cluster or an external host.
+type GameServerDependency struct {
+	// Name identifies the dependency in conditions
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+
+	// Service in the cluster; it is reached at
+	// <service>.<namespace>.svc.cluster.local
+	// +kubebuilder:validation:MaxLength=63
+	Service string `json:"service,omitempty"`
+
+	// Namespace of the Service; defaults to the GameServer's namespace
+	// +kubebuilder:validation:MaxLength=63
+	Namespace string `json:"namespace,omitempty"`
+
+	// Host of an endpoint outside the cluster
+	// +kubebuilder:validation:MaxLength=253
+	Host string `json:"host,omitempty"`
+
+	// TCP port the dependency is checked on
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int `json:"port"`
+
+	// WaitForReady holds the game container back until the dependency
+	// accepts connections
+	WaitForReady bool `json:"waitForReady,omitempty"`
+}
+
+// GameServerAdvanced defines advanced configuration
+type GameServerAdvanced struct {
+	// Pod affinity rules
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// +kubebuilder:pruning:PreserveUnknownFields
+	Affinity map[string]interface{} `json:"affinity,omitempty"`
+
+	// Pod tolerations
+	// +kubebuilder:validation:Schemaless
+	// +gameplane:schema:objects
+	Tolerations []map[string]interface{} `json:"tolerations,omitempty"`
+
+	// Custom environment variables
+	CustomEnvVars map[string]string `json:"customEnvVars,omitempty"`
+
+	// Game container image, overriding the game type's default
+	Image string `json:"image,omitempty"`
+
+	// Health probe tuning; unset values keep the game type's defaults
+	Probes *GameServerProbes `json:"probes,omitempty"`
+}
+
+// GameServerProbes tunes the health probes of the game container
+type GameServerProbes struct {
+	// Startup probe, which holds the others off until the game has started
+	Startup *ProbeSettings `json:"startup,omitempty"`
+
+	// Liveness probe, which restarts a game that stopped answering
+	Liveness *ProbeSettings `json:"liveness,omitempty"`
+
+	// Readiness probe, which keeps players away while the game does not answer
+	Readiness *ProbeSettings `json:"readiness,omitempty"`
+
+	// Hold readiness until the game answers its query protocol, so Ready
+	// means players can connect. Defaults to true for games with one.
+	QueryReadiness *bool `json:"queryReadiness,omitempty"`
+}
+
+// ProbeSettings are the timings of one probe
+type ProbeSettings struct {
+	// Seconds after the container started before the first probe
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=3600
+	InitialDelaySeconds *int32 `json:"initialDelaySeconds,omitempty"`
+
+	// Seconds between probes
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=3600
+	PeriodSeconds *int32 `json:"periodSeconds,omitempty"`
+
+	// Seconds a probe may take
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=600
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// Consecutive failures before the probe fails
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=1000
+	FailureThreshold *int32 `json:"failureThreshold,omitempty"`
+}
+
+// GameServerStatus is aggregated from the child composition and the status
+// controller
+type GameServerStatus struct {
+	// Current phase of the game server
+	// +kubebuilder:validation:Enum=Pending;Routing;ChildCreating;Installing;Running;Failed;Terminating
+	Phase string `json:"phase,omitempty"`
+
+	// Type of child composition being used
+	ChildType string `json:"childType,omitempty"`
+
+	// Name of the spawned child resource
+	ChildName string `json:"childName,omitempty"`
+
+	// External IP address of the game server
+	ServerIP string `json:"serverIP,omitempty"`
+
+	// Game port
+	GamePort int `json:"gamePort,omitempty"`
+
+	// Web admin port (if applicable)
+	WebPort int `json:"webPort,omitempty"`
+
+	// Full connection endpoint for players
+	ServerEndpoint string `json:"serverEndpoint,omitempty"`
+
+	// Players currently online, reported by the status controller
+	PlayersOnline int `json:"playersOnline,omitempty"`
+
+	// Composite health score, reported by the status controller
+	Health *GameServerHealth `json:"health,omitempty"`
+
+	// Last status update timestamp
+	LastUpdate *metav1.Time `json:"lastUpdate,omitempty"`
+
+	// Conditions are added to the schema by Crossplane
+	// +gameplane:schema:skip
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// GameServerHealth scores a running game server from 0 (down) to 100
+type GameServerHealth struct {
+	// Weighted score of the checks that could be made
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Score int `json:"score"`
+
+	// Individual checks: readiness, restarts, saturation, disk and query
+	Checks []GameServerHealthCheck `json:"checks,omitempty"`
+}
+
+// GameServerHealthCheck is one input of the health score
+type GameServerHealthCheck struct {
+	// Check name
+	Name string `json:"name"`
+
+	// Score of this check from 0 to 100
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	Score int `json:"score"`
+
+	// Weight of this check in the overall score
+	Weight int `json:"weight"`
+
+	// What the check found
+	Message string `json:"message,omitempty"`
+}
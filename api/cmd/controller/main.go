@@ -0,0 +1,52 @@
+// Command controller keeps GameServer status up to date with live data from
+// the game workload, so the API only has to read status. Run a single
+// replica; status is recomputed on every pass, so nothing is lost when the
+// controller restarts.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kubelize/gameplane/api/internal/status"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+// defaultInterval is how often status is refreshed without RECONCILE_INTERVAL
+const defaultInterval = 30 * time.Second
+
+func main() {
+	restConfig, err := config.GetConfig()
+	if err != nil {
+		log.Fatalf("Failed to get kubernetes config: %v", err)
+	}
+	k8sClient, err := client.New(restConfig, client.Options{Scheme: runtime.NewScheme()})
+	if err != nil {
+		log.Fatalf("Failed to create kubernetes client: %v", err)
+	}
+	kubeClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		log.Fatalf("Failed to create kubernetes core client: %v", err)
+	}
+
+	interval := defaultInterval
+	if raw := os.Getenv("RECONCILE_INTERVAL"); raw != "" {
+		if interval, err = time.ParseDuration(raw); err != nil || interval <= 0 {
+			log.Fatalf("Invalid RECONCILE_INTERVAL %q", raw)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Starting GamePlane status controller (interval %s)", interval)
+	reconciler := &status.Reconciler{Client: k8sClient, Kube: kubeClient}
+	reconciler.Run(ctx, interval)
+}
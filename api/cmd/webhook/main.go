@@ -0,0 +1,246 @@
+// Command webhook serves validating and mutating admission webhooks for
+// GameServer claims, so claims created with kubectl or GitOps get the same
+// gameType validation, gameConfig schema checks, defaulting and namespace
+// quotas as claims created through the API.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/kubelize/gameplane/api/internal/admission"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+// maxReviewSize bounds an AdmissionReview request body
+const maxReviewSize = 3 << 20
+
+// webhook answers AdmissionReviews for GameServer claims
+type webhook struct {
+	client client.Client
+}
+
+// patchOperation is a single RFC 6902 JSON patch operation
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+func main() {
+	restConfig, err := config.GetConfig()
+	if err != nil {
+		log.Fatalf("Failed to get kubernetes config: %v", err)
+	}
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		log.Fatalf("Failed to build scheme: %v", err)
+	}
+	k8sClient, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		log.Fatalf("Failed to create kubernetes client: %v", err)
+	}
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8443"
+	}
+	certFile := os.Getenv("TLS_CERT_FILE")
+	if certFile == "" {
+		certFile = "/etc/webhook/tls/tls.crt"
+	}
+	keyFile := os.Getenv("TLS_KEY_FILE")
+	if keyFile == "" {
+		keyFile = "/etc/webhook/tls/tls.key"
+	}
+
+	hook := &webhook{client: k8sClient}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/validate", hook.serve(hook.validate))
+	mux.HandleFunc("/mutate", hook.serve(hook.mutate))
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	log.Printf("Starting GamePlane admission webhook on port %s", port)
+	if err := server.ListenAndServeTLS(certFile, keyFile); err != nil {
+		log.Fatalf("Failed to start webhook: %v", err)
+	}
+}
+
+// serve decodes an AdmissionReview, passes the request to review and writes
+// the response back with the request's UID
+func (h *webhook) serve(review func(context.Context, *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxReviewSize))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to read request: %v", err), http.StatusBadRequest)
+			return
+		}
+		var in admissionv1.AdmissionReview
+		if err := json.Unmarshal(body, &in); err != nil || in.Request == nil {
+			http.Error(w, "Invalid AdmissionReview", http.StatusBadRequest)
+			return
+		}
+
+		response := review(r.Context(), in.Request)
+		response.UID = in.Request.UID
+		out := admissionv1.AdmissionReview{TypeMeta: in.TypeMeta, Response: response}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(out); err != nil {
+			log.Printf("Failed to write AdmissionReview response: %v", err)
+		}
+	}
+}
+
+// validate rejects claims with an unknown gameType, invalid gameConfig or
+// that would exceed the namespace quota. Updates are only checked when the
+// relevant spec fields change, so Crossplane can keep updating claims that
+// predate the webhook.
+func (h *webhook) validate(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	obj, err := decodeClaim(req.Object.Raw)
+	if err != nil {
+		return denied(http.StatusBadRequest, err.Error())
+	}
+	var old *unstructured.Unstructured
+	if req.Operation == admissionv1.Update {
+		if old, err = decodeClaim(req.OldObject.Raw); err != nil {
+			return denied(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	if old == nil || specChanged(old, obj, "gameType") || specChanged(old, obj, "gameConfig") {
+		if err := admission.Validate(obj); err != nil {
+			return denied(http.StatusUnprocessableEntity, err.Error())
+		}
+	}
+
+	if old == nil || specChanged(old, obj, "gameType") || specChanged(old, obj, "resources") {
+		if err := h.checkQuota(ctx, obj); err != nil {
+			return denied(http.StatusForbidden, err.Error())
+		}
+	}
+	return &admissionv1.AdmissionResponse{Allowed: true}
+}
+
+// checkQuota enforces the quota annotations of the claim's namespace
+func (h *webhook) checkQuota(ctx context.Context, obj *unstructured.Unstructured) error {
+	namespace := &corev1.Namespace{}
+	if err := h.client.Get(ctx, client.ObjectKey{Name: obj.GetNamespace()}, namespace); err != nil {
+		return fmt.Errorf("failed to get namespace %s: %v", obj.GetNamespace(), err)
+	}
+	quota, err := admission.QuotaFromAnnotations(namespace.Annotations)
+	if err != nil || !quota.Limited() {
+		return err
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := h.client.List(ctx, list, client.InNamespace(obj.GetNamespace())); err != nil {
+		return fmt.Errorf("failed to list GameServers: %v", err)
+	}
+	return quota.Check(list.Items, obj)
+}
+
+// mutate adds the standard labels the API sets on every claim
+func (h *webhook) mutate(ctx context.Context, req *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	obj, err := decodeClaim(req.Object.Raw)
+	if err != nil {
+		return denied(http.StatusBadRequest, err.Error())
+	}
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	if gameType == "" {
+		// Left for the validating webhook to reject
+		return &admissionv1.AdmissionResponse{Allowed: true}
+	}
+
+	// generateName claims have no name yet, so the instance label is omitted
+	defaults := admission.Labels(obj.GetName(), gameType)
+	if obj.GetName() == "" {
+		delete(defaults, "app.kubernetes.io/instance")
+	}
+
+	patch := []patchOperation{}
+	labels := obj.GetLabels()
+	if labels == nil {
+		patch = append(patch, patchOperation{Op: "add", Path: "/metadata/labels", Value: defaults})
+	} else {
+		for key, value := range defaults {
+			// The game type label always follows spec.gameType
+			if existing, ok := labels[key]; ok && (existing == value || key != admission.GameTypeLabel) {
+				continue
+			}
+			patch = append(patch, patchOperation{Op: "add", Path: "/metadata/labels/" + escapePatchKey(key), Value: value})
+		}
+	}
+
+	response := &admissionv1.AdmissionResponse{Allowed: true}
+	if len(patch) > 0 {
+		raw, err := json.Marshal(patch)
+		if err != nil {
+			return denied(http.StatusInternalServerError, fmt.Sprintf("Failed to encode patch: %v", err))
+		}
+		patchType := admissionv1.PatchTypeJSONPatch
+		response.Patch = raw
+		response.PatchType = &patchType
+	}
+	return response
+}
+
+// decodeClaim decodes a GameServer claim from an admission request
+func decodeClaim(raw []byte) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(raw); err != nil {
+		return nil, fmt.Errorf("failed to decode GameServer: %v", err)
+	}
+	return obj, nil
+}
+
+// specChanged reports whether a top-level spec field differs between objects
+func specChanged(old, obj *unstructured.Unstructured, field string) bool {
+	before, _, _ := unstructured.NestedFieldNoCopy(old.Object, "spec", field)
+	after, _, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", field)
+	return !reflect.DeepEqual(before, after)
+}
+
+// escapePatchKey escapes a map key for use in a JSON pointer
+func escapePatchKey(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+// denied rejects a request with a message shown to the user
+func denied(code int32, message string) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    code,
+			Message: message,
+		},
+	}
+}
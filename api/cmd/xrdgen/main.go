@@ -0,0 +1,218 @@
+// Command xrdgen generates the GameServer CompositeResourceDefinition from the
+// Go types in apis/gameplane/v1alpha1. The schema is built by controller-gen's
+// CRD generator from the field comments and kubebuilder markers, plus the
+// gameplane:schema markers registered below, and wrapped in the Crossplane
+// XRD envelope.
+//
+//	go generate ./apis/...
+//	go run ./cmd/xrdgen -types apis/gameplane/v1alpha1 -check -out ../crossplane/gameplane/definition.yaml
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubelize/gameplane/api/apis/gameplane/v1alpha1"
+	"github.com/kubelize/gameplane/api/internal/catalog"
+	"golang.org/x/tools/go/packages"
+	apiext "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-tools/pkg/crd"
+	crdmarkers "sigs.k8s.io/controller-tools/pkg/crd/markers"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+	"sigs.k8s.io/yaml"
+)
+
+// header marks the output as generated
+const header = "# Code generated by xrdgen from api/apis/gameplane/v1alpha1. DO NOT EDIT.\n"
+
+// skipMarker leaves an API-only field out of the schema
+const skipMarker = "gameplane:schema:skip"
+
+// gameTypes fills a field's enum from the game catalog
+type gameTypes struct{}
+
+func (gameTypes) ApplyToSchema(schema *apiext.JSONSchemaProps) error {
+	for _, gameType := range catalog.SupportedTypes() {
+		raw, err := json.Marshal(gameType)
+		if err != nil {
+			return err
+		}
+		schema.Enum = append(schema.Enum, apiext.JSON{Raw: raw})
+	}
+	return nil
+}
+
+// objects types a schemaless field as a list of free-form objects, which
+// controller-gen cannot derive from []map[string]interface{}
+type objects struct{}
+
+func (objects) ApplyToSchema(schema *apiext.JSONSchemaProps) error {
+	preserve := true
+	schema.Type = "array"
+	schema.Items = &apiext.JSONSchemaPropsOrArray{Schema: &apiext.JSONSchemaProps{
+		Type:                   "object",
+		XPreserveUnknownFields: &preserve,
+	}}
+	return nil
+}
+
+// skip marks a field for removal; it has no effect on the schema itself
+type skip struct{}
+
+func main() {
+	dir := flag.String("types", ".", "directory of the v1alpha1 package")
+	out := flag.String("out", "", "file to write the XRD to (default stdout)")
+	check := flag.Bool("check", false, "fail if -out is not up to date instead of writing it")
+	flag.Parse()
+
+	rendered, err := render(*dir)
+	if err != nil {
+		log.Fatalf("Failed to generate XRD: %v", err)
+	}
+	switch {
+	case *out == "":
+		os.Stdout.Write(rendered)
+	case *check:
+		existing, err := os.ReadFile(*out)
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", *out, err)
+		}
+		if !bytes.Equal(existing, rendered) {
+			log.Fatalf("%s is out of date; run go generate ./apis/...", *out)
+		}
+	default:
+		if err := os.WriteFile(*out, rendered, 0o644); err != nil {
+			log.Fatalf("Failed to write %s: %v", *out, err)
+		}
+	}
+}
+
+// registry holds controller-gen's CRD markers and the gameplane:schema ones
+func registry() (*markers.Registry, error) {
+	reg := &markers.Registry{}
+	if err := crdmarkers.Register(reg); err != nil {
+		return nil, err
+	}
+	for name, output := range map[string]interface{}{
+		"gameplane:schema:gameTypes": gameTypes{},
+		"gameplane:schema:objects":   objects{},
+		skipMarker:                   skip{},
+	} {
+		if err := reg.Define(name, markers.DescribesField, output); err != nil {
+			return nil, err
+		}
+	}
+	return reg, nil
+}
+
+// render builds the XRD YAML from the types in dir
+func render(dir string) ([]byte, error) {
+	if !filepath.IsAbs(dir) {
+		dir = "./" + filepath.ToSlash(filepath.Clean(dir))
+	}
+	roots, err := loader.LoadRoots(dir)
+	if err != nil {
+		return nil, err
+	}
+	reg, err := registry()
+	if err != nil {
+		return nil, err
+	}
+	parser := &crd.Parser{
+		Collector: &markers.Collector{Registry: reg},
+		Checker:   &loader.TypeChecker{},
+	}
+	crd.AddKnownTypes(parser)
+	for _, root := range roots {
+		parser.NeedPackage(root)
+	}
+	dropSkipped(parser)
+
+	kind := schema.GroupKind{Group: v1alpha1.Group, Kind: "GameServer"}
+	parser.NeedCRDFor(kind, nil)
+	// Like controller-gen, ignore type errors from dependencies checked only
+	// as far as the schema needs them
+	if loader.PrintErrors(roots, packages.TypeError) {
+		return nil, fmt.Errorf("controller-gen reported errors in %s", dir)
+	}
+	definition, ok := parser.CustomResourceDefinitions[kind]
+	if !ok || len(definition.Spec.Versions) == 0 {
+		return nil, fmt.Errorf("no GameServer type in %s", dir)
+	}
+	version := definition.Spec.Versions[0]
+
+	// The claim's envelope is Crossplane's; the XRD declares spec and status
+	openAPI := version.Schema.OpenAPIV3Schema
+	for _, property := range []string{"apiVersion", "kind", "metadata"} {
+		delete(openAPI.Properties, property)
+	}
+	openAPI.Description = ""
+	openAPI.Required = []string{"spec"}
+
+	xrd := map[string]interface{}{
+		"apiVersion": "apiextensions.crossplane.io/v1",
+		"kind":       "CompositeResourceDefinition",
+		"metadata": map[string]interface{}{
+			"name": "xgameservers.gameplane.kubelize.io",
+			"labels": map[string]string{
+				"provider": "kubelize",
+				"service":  "gameserver",
+				"type":     "parent",
+			},
+		},
+		"spec": map[string]interface{}{
+			"group":      v1alpha1.Group,
+			"names":      map[string]string{"kind": "XGameServer", "plural": "xgameservers"},
+			"claimNames": map[string]string{"kind": "GameServer", "plural": "gameservers"},
+			"connectionSecretKeys": []string{
+				"serverIP", "gamePort", "webPort", "serverEndpoint", "serverPassword", "adminPassword",
+			},
+			"versions": []interface{}{
+				map[string]interface{}{
+					"name":                     version.Name,
+					"served":                   true,
+					"referenceable":            true,
+					"schema":                   map[string]interface{}{"openAPIV3Schema": openAPI},
+					"additionalPrinterColumns": version.AdditionalPrinterColumns,
+				},
+			},
+		},
+	}
+	body, err := yaml.Marshal(xrd)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(header), body...), nil
+}
+
+// dropSkipped removes fields marked +gameplane:schema:skip from the cached
+// schemata of their types, before controller-gen flattens them into the CRD
+func dropSkipped(parser *crd.Parser) {
+	for ident, info := range parser.Types {
+		for _, field := range info.Fields {
+			if field.Markers.Get(skipMarker) == nil {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			parser.NeedSchemaFor(ident)
+			typeSchema := parser.Schemata[ident]
+			delete(typeSchema.Properties, name)
+			required := typeSchema.Required[:0]
+			for _, field := range typeSchema.Required {
+				if field != name {
+					required = append(required, field)
+				}
+			}
+			typeSchema.Required = required
+			parser.Schemata[ident] = typeSchema
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Package admission holds the checks applied to GameServer claims both by the
+// API and by the admission webhook, so claims applied with kubectl or GitOps
+// get the same validation, defaulting and quotas as API-created ones.
+package admission
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kubelize/gameplane/api/internal/catalog"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Namespace annotations limiting the GameServers in a namespace. Unset
+// annotations mean no limit.
+const (
+	MaxGameServersAnnotation = "gameplane.kubelize.io/max-gameservers"
+	MaxCPUAnnotation         = "gameplane.kubelize.io/max-cpu"
+	MaxMemoryAnnotation      = "gameplane.kubelize.io/max-memory"
+)
+
+// GameTypeLabel is set on every claim so GameServers can be selected by game
+const GameTypeLabel = "gameplane.kubelize.io/game-type"
+
+// Labels returns the labels every GameServer claim carries
+func Labels(name, gameType string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":     "gameserver",
+		"app.kubernetes.io/instance": name,
+		GameTypeLabel:                gameType,
+	}
+}
+
+// Validate checks a claim's gameType and gameConfig against the catalog.
+// Unknown gameConfig keys are allowed as long as they are plain values, as
+// they are written to the config file as-is.
+func Validate(obj *unstructured.Unstructured) error {
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	if gameType == "" {
+		return fmt.Errorf("spec.gameType is required")
+	}
+	def, ok := catalog.Lookup(gameType)
+	if !ok {
+		return fmt.Errorf("Unsupported game type: %s. Valid types: %s", gameType, strings.Join(catalog.SupportedTypes(), ", "))
+	}
+
+	gameConfig := map[string]interface{}{}
+	if raw, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "gameConfig"); found && raw != nil {
+		config, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("spec.gameConfig must be an object")
+		}
+		gameConfig = config
+	}
+	values := flatten("", gameConfig)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	problems := []string{}
+	for _, key := range keys {
+		value := values[key]
+		field, known := def.Field(key)
+		if !known {
+			switch value.(type) {
+			case string, bool, float64, int64:
+			default:
+				problems = append(problems, fmt.Sprintf("%s: custom values must be a string, number or boolean", key))
+			}
+			continue
+		}
+		if err := catalog.ValidateValue(field, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid gameConfig: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// flatten converts a nested gameConfig into dotted keys
+func flatten(prefix string, config map[string]interface{}) map[string]interface{} {
+	flat := map[string]interface{}{}
+	for key, value := range config {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			for k, v := range flatten(path, nested) {
+				flat[k] = v
+			}
+			continue
+		}
+		flat[path] = value
+	}
+	return flat
+}
+
+// Quota limits the number and total resources of GameServers in a namespace.
+// Zero values mean no limit.
+type Quota struct {
+	MaxGameServers int
+	MaxCPU         resource.Quantity
+	MaxMemory      resource.Quantity
+}
+
+// QuotaFromAnnotations reads a namespace's quota annotations
+func QuotaFromAnnotations(annotations map[string]string) (Quota, error) {
+	quota := Quota{}
+	if raw := annotations[MaxGameServersAnnotation]; raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return quota, fmt.Errorf("invalid %s annotation %q", MaxGameServersAnnotation, raw)
+		}
+		quota.MaxGameServers = n
+	}
+	if raw := annotations[MaxCPUAnnotation]; raw != "" {
+		q, err := resource.ParseQuantity(raw)
+		if err != nil {
+			return quota, fmt.Errorf("invalid %s annotation %q: %v", MaxCPUAnnotation, raw, err)
+		}
+		quota.MaxCPU = q
+	}
+	if raw := annotations[MaxMemoryAnnotation]; raw != "" {
+		q, err := resource.ParseQuantity(raw)
+		if err != nil {
+			return quota, fmt.Errorf("invalid %s annotation %q: %v", MaxMemoryAnnotation, raw, err)
+		}
+		quota.MaxMemory = q
+	}
+	return quota, nil
+}
+
+// Limited reports whether the quota sets any limit
+func (q Quota) Limited() bool {
+	return q.MaxGameServers > 0 || !q.MaxCPU.IsZero() || !q.MaxMemory.IsZero()
+}
+
+// Check reports whether adding or updating obj keeps the namespace within the
+// quota. existing lists the namespace's GameServers; an older copy of obj in
+// it is ignored.
+func (q Quota) Check(existing []unstructured.Unstructured, obj *unstructured.Unstructured) error {
+	if !q.Limited() {
+		return nil
+	}
+
+	count := 1
+	cpu, memory := resource.Quantity{}, resource.Quantity{}
+	add := func(item *unstructured.Unstructured) error {
+		resources := ClaimResources(item)
+		if !q.MaxCPU.IsZero() {
+			value, err := resource.ParseQuantity(resources.CPU)
+			if err != nil {
+				return fmt.Errorf("GameServer %s has invalid CPU %q", item.GetName(), resources.CPU)
+			}
+			cpu.Add(value)
+		}
+		if !q.MaxMemory.IsZero() {
+			value, err := resource.ParseQuantity(resources.Memory)
+			if err != nil {
+				return fmt.Errorf("GameServer %s has invalid memory %q", item.GetName(), resources.Memory)
+			}
+			memory.Add(value)
+		}
+		return nil
+	}
+	if err := add(obj); err != nil {
+		return err
+	}
+	for i := range existing {
+		if existing[i].GetName() == obj.GetName() {
+			continue
+		}
+		count++
+		if err := add(&existing[i]); err != nil {
+			return err
+		}
+	}
+
+	namespace := obj.GetNamespace()
+	if q.MaxGameServers > 0 && count > q.MaxGameServers {
+		return fmt.Errorf("namespace %s is limited to %d GameServers", namespace, q.MaxGameServers)
+	}
+	if !q.MaxCPU.IsZero() && cpu.Cmp(q.MaxCPU) > 0 {
+		return fmt.Errorf("namespace %s is limited to %s CPU across GameServers (requested %s)", namespace, q.MaxCPU.String(), cpu.String())
+	}
+	if !q.MaxMemory.IsZero() && memory.Cmp(q.MaxMemory) > 0 {
+		return fmt.Errorf("namespace %s is limited to %s memory across GameServers (requested %s)", namespace, q.MaxMemory.String(), memory.String())
+	}
+	return nil
+}
+
+// ClaimResources returns a claim's resources with unset values filled from
+// its game's defaults
+func ClaimResources(obj *unstructured.Unstructured) catalog.Resources {
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	resources := catalog.Resources{}
+	resources.CPU, _, _ = unstructured.NestedString(obj.Object, "spec", "resources", "cpu")
+	resources.Memory, _, _ = unstructured.NestedString(obj.Object, "spec", "resources", "memory")
+	resources.StorageSize, _, _ = unstructured.NestedString(obj.Object, "spec", "resources", "storageSize")
+	resources.StorageClass, _, _ = unstructured.NestedString(obj.Object, "spec", "resources", "storageClass")
+	return catalog.EffectiveResources(gameType, resources)
+}
@@ -0,0 +1,285 @@
+// Package bootstrap installs what GamePlane needs in a cluster: the XRDs and
+// Compositions under crossplane/, the composition functions, the
+// provider-kubernetes provider and its ProviderConfig, and the RBAC the
+// provider runs with. It also verifies prerequisites it cannot install, such
+// as metrics-server. Objects that already exist are left untouched, so
+// bootstrapping is safe to repeat and a second run after the provider
+// becomes healthy finishes what the first could not.
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultManifestsDir is the crossplane/ directory of the repository,
+	// relative to the working directory of the API
+	DefaultManifestsDir = "crossplane"
+
+	// providerPackage is the provider-kubernetes version the compositions
+	// are written against
+	providerPackage = "xpkg.upbound.io/crossplane-contrib/provider-kubernetes:v0.11.0"
+
+	// Result actions
+	ActionCreated = "created"
+	ActionExists  = "exists"
+	ActionMissing = "missing"
+	ActionPending = "pending"
+	ActionFailed  = "failed"
+)
+
+// manifestGlobs are the manifests installed, relative to the manifests
+// directory. XRDs sort before Compositions within each directory, and
+// example GameServers and workloads are not part of the platform.
+var manifestGlobs = []string{
+	"gameplane/*definition.yaml",
+	"gameplane/composition.yaml",
+	"games/*/definition.yaml",
+	"games/*/composition.yaml",
+	"examples/functions.yaml",
+	"examples/sa_role_rolebinding.yaml",
+}
+
+// Options control a bootstrap run
+type Options struct {
+	// ManifestsDir holds the crossplane/ manifests; DefaultManifestsDir
+	// when empty
+	ManifestsDir string
+	// Verify only reports what is missing without creating anything
+	Verify bool
+}
+
+// Result is the outcome for one object
+type Result struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	// Source is the manifest the object came from; empty for built-in
+	// objects and checks
+	Source  string `json:"source,omitempty"`
+	Action  string `json:"action"`
+	Message string `json:"message,omitempty"`
+}
+
+// Report lists every object bootstrap installed or verified
+type Report struct {
+	Verify  bool           `json:"verify"`
+	Results []Result       `json:"results"`
+	Counts  map[string]int `json:"counts"`
+	// Complete is true once nothing is missing, pending or failed
+	Complete bool `json:"complete"`
+}
+
+// Run installs or, with Options.Verify, verifies the platform objects
+func Run(ctx context.Context, c client.Client, opts Options) (Report, error) {
+	dir := opts.ManifestsDir
+	if dir == "" {
+		dir = DefaultManifestsDir
+	}
+	objects, err := loadManifests(dir)
+	if err != nil {
+		return Report{}, err
+	}
+	objects = append(objects, providerObjects()...)
+
+	report := Report{Verify: opts.Verify, Results: []Result{}, Counts: map[string]int{}}
+	for _, m := range objects {
+		report.add(apply(ctx, c, m, opts.Verify))
+	}
+	report.add(checkMetricsAPI(ctx, c))
+
+	report.Complete = report.Counts[ActionMissing]+report.Counts[ActionPending]+report.Counts[ActionFailed] == 0
+	return report, nil
+}
+
+// add records a result
+func (r *Report) add(result Result) {
+	r.Results = append(r.Results, result)
+	r.Counts[result.Action]++
+}
+
+// manifest is an object to install and the file it was read from
+type manifest struct {
+	object *unstructured.Unstructured
+	source string
+}
+
+// loadManifests reads the platform manifests from dir
+func loadManifests(dir string) ([]manifest, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("manifests directory %s not found, set MANIFESTS_DIR to the repository's crossplane directory: %w", dir, err)
+	}
+	manifests := []manifest{}
+	seen := map[string]bool{}
+	for _, pattern := range manifestGlobs {
+		files, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			if seen[file] {
+				continue
+			}
+			seen[file] = true
+			objects, err := decodeFile(file)
+			if err != nil {
+				return nil, err
+			}
+			source, _ := filepath.Rel(dir, file)
+			for _, object := range objects {
+				manifests = append(manifests, manifest{object: object, source: source})
+			}
+		}
+	}
+	return manifests, nil
+}
+
+// decodeFile reads every object of a multi-document YAML file
+func decodeFile(file string) ([]*unstructured.Unstructured, error) {
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(raw), 4096)
+	objects := []*unstructured.Unstructured{}
+	for {
+		object := &unstructured.Unstructured{}
+		if err := decoder.Decode(&object.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		if len(object.Object) == 0 {
+			continue
+		}
+		if object.GetKind() == "" || object.GetName() == "" {
+			return nil, fmt.Errorf("failed to parse %s: object without kind or name", file)
+		}
+		objects = append(objects, object)
+	}
+}
+
+// providerObjects are the provider-kubernetes install and its default
+// ProviderConfig. The runtime config pins the provider's service account to
+// the name sa_role_rolebinding.yaml binds.
+func providerObjects() []manifest {
+	runtimeConfig := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "pkg.crossplane.io/v1beta1",
+		"kind":       "DeploymentRuntimeConfig",
+		"metadata":   map[string]interface{}{"name": "gameplane"},
+		"spec": map[string]interface{}{
+			"serviceAccountTemplate": map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "provider-kubernetes"},
+			},
+		},
+	}}
+	provider := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "pkg.crossplane.io/v1",
+		"kind":       "Provider",
+		"metadata":   map[string]interface{}{"name": "provider-kubernetes"},
+		"spec": map[string]interface{}{
+			"package":          providerPackage,
+			"runtimeConfigRef": map[string]interface{}{"name": "gameplane"},
+		},
+	}}
+	providerConfig := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "kubernetes.crossplane.io/v1alpha1",
+		"kind":       "ProviderConfig",
+		"metadata":   map[string]interface{}{"name": "default"},
+		"spec": map[string]interface{}{
+			"credentials": map[string]interface{}{"source": "InjectedIdentity"},
+		},
+	}}
+	return []manifest{{object: runtimeConfig}, {object: provider}, {object: providerConfig}}
+}
+
+// apply creates object unless it already exists or verify is set
+func apply(ctx context.Context, c client.Client, m manifest, verify bool) Result {
+	object := m.object
+	result := Result{Kind: object.GetKind(), Name: object.GetName(), Namespace: object.GetNamespace(), Source: m.source}
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(object.GroupVersionKind())
+	err := c.Get(ctx, client.ObjectKey{Namespace: object.GetNamespace(), Name: object.GetName()}, existing)
+	switch {
+	case err == nil:
+		result.Action = ActionExists
+		return result
+	case meta.IsNoMatchError(err):
+		// The API arrives with a package that is still installing
+		result.Action = ActionPending
+		result.Message = fmt.Sprintf("%s is not served yet; re-run bootstrap once the package providing it is healthy", object.GroupVersionKind().GroupVersion())
+		return result
+	case !apierrors.IsNotFound(err):
+		result.Action = ActionFailed
+		result.Message = fmt.Sprintf("Failed to read: %v", err)
+		return result
+	}
+
+	if verify {
+		result.Action = ActionMissing
+		return result
+	}
+	if err := c.Create(ctx, object.DeepCopy()); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			result.Action = ActionExists
+			return result
+		}
+		result.Action = ActionFailed
+		result.Message = fmt.Sprintf("Failed to create: %v", err)
+		return result
+	}
+	result.Action = ActionCreated
+	return result
+}
+
+// checkMetricsAPI verifies metrics-server serves metrics.k8s.io, which the
+// API reads pod usage from. It is installed separately from GamePlane.
+func checkMetricsAPI(ctx context.Context, c client.Client) Result {
+	result := Result{Kind: "APIService", Name: "v1beta1.metrics.k8s.io"}
+	service := &unstructured.Unstructured{}
+	service.SetGroupVersionKind(schema.GroupVersionKind{Group: "apiregistration.k8s.io", Version: "v1", Kind: "APIService"})
+	err := c.Get(ctx, client.ObjectKey{Name: result.Name}, service)
+	switch {
+	case apierrors.IsNotFound(err) || meta.IsNoMatchError(err):
+		result.Action = ActionMissing
+		result.Message = "metrics-server is not installed; install it for CPU and memory usage (kubectl apply -f https://github.com/kubernetes-sigs/metrics-server/releases/latest/download/components.yaml)"
+	case err != nil:
+		result.Action = ActionFailed
+		result.Message = fmt.Sprintf("Failed to read: %v", err)
+	case !available(service):
+		result.Action = ActionPending
+		result.Message = "metrics-server is installed but not available yet"
+	default:
+		result.Action = ActionExists
+	}
+	return result
+}
+
+// available reports whether an APIService's Available condition is True
+func available(service *unstructured.Unstructured) bool {
+	conditions, _, _ := unstructured.NestedSlice(service.Object, "status", "conditions")
+	for _, raw := range conditions {
+		condition, ok := raw.(map[string]interface{})
+		if ok && condition["type"] == "Available" {
+			return condition["status"] == "True"
+		}
+	}
+	return false
+}
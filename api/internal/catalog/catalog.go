@@ -0,0 +1,628 @@
+// Package catalog describes the supported game types and their gameConfig
+// schemas. It is shared by the API and the admission webhook so both accept
+// the same claims.
+package catalog
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+// GameDefinition describes a supported game type and its configuration schema
+type GameDefinition struct {
+	Type        string       `json:"type"`
+	DisplayName string       `json:"displayName"`
+	ChildKind   string       `json:"childKind"`
+	Image       string       `json:"image"`
+	GamePort    int          `json:"gamePort"`
+	WebPort     int          `json:"webPort,omitempty"`
+	ConfigFile  *ConfigFile  `json:"configFile,omitempty"`
+	AdminList   *AdminList   `json:"adminList,omitempty"`
+	Whitelist   *PlayerList  `json:"whitelist,omitempty"`
+	BanList     *PlayerList  `json:"banList,omitempty"`
+	Console     *ConsoleInfo `json:"console,omitempty"`
+	Wipe        *WipeInfo    `json:"wipe,omitempty"`
+	World       *WorldInfo   `json:"world,omitempty"`
+	Query       *QueryInfo   `json:"query,omitempty"`
+	Linking     *LinkInfo    `json:"linking,omitempty"`
+	Proxy       *ProxyInfo   `json:"proxy,omitempty"`
+	// SteamAppID is the dedicated server's Steam app, for games installed
+	// with SteamCMD
+	SteamAppID int `json:"steamAppId,omitempty"`
+	// DefaultResources are the resources the child composition uses when
+	// spec.resources leaves them unset
+	DefaultResources *Resources `json:"defaultResources,omitempty"`
+	// DefaultProbes are the probe timings the child composition uses when
+	// spec.advanced.probes leaves them unset
+	DefaultProbes *Probes       `json:"defaultProbes,omitempty"`
+	ConfigFields  []ConfigField `json:"configFields"`
+	// ChatPattern matches chat lines in the server log, with named groups
+	// player, message and optionally playerId and channel
+	ChatPattern *regexp.Regexp `json:"-"`
+}
+
+// Resources are CPU, memory and storage as Kubernetes quantities
+type Resources struct {
+	CPU          string `json:"cpu,omitempty"`
+	Memory       string `json:"memory,omitempty"`
+	StorageSize  string `json:"storageSize,omitempty"`
+	StorageClass string `json:"storageClass,omitempty"`
+}
+
+// FallbackResources apply to games without their own defaults
+var FallbackResources = Resources{CPU: "2", Memory: "4Gi", StorageSize: "20Gi"}
+
+// EffectiveResources fills unset resources with the game's defaults, which
+// mirror the defaults of its child composition
+func EffectiveResources(gameType string, resources Resources) Resources {
+	defaults := FallbackResources
+	if def, ok := Lookup(gameType); ok && def.DefaultResources != nil {
+		defaults = *def.DefaultResources
+	}
+	if resources.CPU == "" {
+		resources.CPU = defaults.CPU
+	}
+	if resources.Memory == "" {
+		resources.Memory = defaults.Memory
+	}
+	if resources.StorageSize == "" {
+		resources.StorageSize = defaults.StorageSize
+	}
+	return resources
+}
+
+// Probes are the timings of a game container's health probes
+type Probes struct {
+	Startup   ProbeTiming `json:"startup"`
+	Liveness  ProbeTiming `json:"liveness"`
+	Readiness ProbeTiming `json:"readiness"`
+}
+
+// ProbeTiming holds the timing fields of a Kubernetes probe
+type ProbeTiming struct {
+	InitialDelaySeconds int32 `json:"initialDelaySeconds"`
+	PeriodSeconds       int32 `json:"periodSeconds"`
+	TimeoutSeconds      int32 `json:"timeoutSeconds"`
+	FailureThreshold    int32 `json:"failureThreshold"`
+}
+
+// FallbackProbes apply to games without their own defaults: up to ten
+// minutes to start, a restart after 90 seconds without an answer
+var FallbackProbes = Probes{
+	Startup:   ProbeTiming{InitialDelaySeconds: 10, PeriodSeconds: 10, TimeoutSeconds: 5, FailureThreshold: 60},
+	Liveness:  ProbeTiming{PeriodSeconds: 30, TimeoutSeconds: 10, FailureThreshold: 3},
+	Readiness: ProbeTiming{PeriodSeconds: 15, TimeoutSeconds: 5, FailureThreshold: 2},
+}
+
+// EffectiveProbes returns the probe timings of a game, which mirror the
+// defaults of its child composition
+func EffectiveProbes(gameType string) Probes {
+	if def, ok := Lookup(gameType); ok && def.DefaultProbes != nil {
+		return *def.DefaultProbes
+	}
+	return FallbackProbes
+}
+
+// WipeInfo lists the world and save data removed by a wipe
+type WipeInfo struct {
+	// Paths are shell globs relative to the game data directory; a trailing
+	// slash only matches directories
+	Paths []string `json:"paths"`
+}
+
+// WorldInfo maps the first-class spec.world parameters onto gameConfig paths
+type WorldInfo struct {
+	NameField string `json:"nameField,omitempty"`
+	SeedField string `json:"seedField,omitempty"`
+	SizeField string `json:"sizeField,omitempty"`
+}
+
+// ConsoleInfo describes how to reach a game's remote admin console
+type ConsoleInfo struct {
+	Protocol string `json:"protocol"` // telnet or rcon
+	Port     int    `json:"port"`
+	// PortField is the gameConfig path overriding Port, if configurable
+	PortField string `json:"portField,omitempty"`
+	// EnabledField is the gameConfig path that must be true for the console to listen
+	EnabledField string `json:"enabledField,omitempty"`
+	// PasswordSecret is the suffix of the Secret ({namespace}-{suffix})
+	// holding the console password under PasswordKey
+	PasswordSecret string `json:"-"`
+	PasswordKey    string `json:"-"`
+	// SayCommand is a format string broadcasting a message to all players
+	SayCommand string `json:"sayCommand"`
+	// SaveCommand flushes the world to disk, if the game has one
+	SaveCommand string `json:"saveCommand,omitempty"`
+	// SaveConfirmation is text the save command's reply contains once the
+	// world is on disk; without it any reply counts as saved
+	SaveConfirmation string `json:"saveConfirmation,omitempty"`
+	// PlayersCommand lists the online players, if the game has one
+	PlayersCommand string `json:"playersCommand,omitempty"`
+	// MOTDCommand is a format string changing the message of the day on
+	// the running server, if the game allows it
+	MOTDCommand string `json:"motdCommand,omitempty"`
+	// LiveSettings are the settings the running game changes through its
+	// console without a restart
+	LiveSettings []LiveSetting `json:"liveSettings,omitempty"`
+}
+
+// LiveSetting is a game setting, such as the time of day or a difficulty
+// multiplier, applied with a console command. The change lasts until the
+// server restarts.
+type LiveSetting struct {
+	Name        string        `json:"name"`
+	Type        string        `json:"type"` // string, integer or number
+	Description string        `json:"description"`
+	Minimum     *float64      `json:"minimum,omitempty"`
+	Maximum     *float64      `json:"maximum,omitempty"`
+	Enum        []interface{} `json:"enum,omitempty"`
+	// Command is a format string applying the value
+	Command string `json:"command"`
+}
+
+// QueryInfo describes the server query protocol used to count online players
+type QueryInfo struct {
+	Protocol string `json:"protocol"` // a2s
+	Port     int    `json:"port"`
+}
+
+// LinkInfo describes a game's multi-server setups, where linked
+// GameServers discover each other as a cluster of servers
+type LinkInfo struct {
+	// Roles are the parts a server can play in the setup, e.g. a proxy and
+	// the shards behind it
+	Roles []string `json:"roles"`
+}
+
+// ProxyInfo marks a game type that forwards players to the backend
+// GameServers listed in spec.proxy
+type ProxyInfo struct {
+	// Strategies are the ways the proxy can pick a backend; the first is
+	// the default
+	Strategies []string `json:"strategies"`
+}
+
+// AdminList describes where a game keeps its in-game admin/operator list
+type AdminList struct {
+	// Path is relative to the game data directory
+	Path   string `json:"path"`
+	Format string `json:"format"` // serveradmin-xml or adminlist-txt
+	// LiveReload is true when the game picks up file changes without a restart
+	LiveReload bool `json:"liveReload"`
+	// MaxLevel is the lowest-privilege permission level, if the game has levels
+	MaxLevel int `json:"maxLevel,omitempty"`
+}
+
+// PlayerList describes a file of player IDs the game reads, such as its
+// whitelist or ban list
+type PlayerList struct {
+	// Path is relative to the game data directory
+	Path string `json:"path"`
+	// Format is serveradmin-xml (a section of serveradmin.xml), id-list-txt,
+	// whitelist-json or banned-players-json
+	Format string `json:"format"`
+	// Section is the serveradmin.xml element holding the entries, and
+	// Element the element of each entry (default user)
+	Section string `json:"section,omitempty"`
+	Element string `json:"element,omitempty"`
+	// Header is written as the first line of id-list-txt files
+	Header string `json:"-"`
+	// LiveReload is true when the game picks up file changes without a restart
+	LiveReload bool `json:"liveReload"`
+	// ReloadCommand makes the running game re-read the file, if it has one
+	ReloadCommand string `json:"reloadCommand,omitempty"`
+}
+
+// ConfigFile describes the native config file a game server reads
+type ConfigFile struct {
+	// Path is relative to the game data directory
+	Path   string `json:"path"`
+	Format string `json:"format"` // xml or ini
+	// Section is the INI section holding the settings
+	Section string `json:"section,omitempty"`
+	// SpecKeys maps top-level spec fields to native setting names
+	SpecKeys map[string]string `json:"specKeys,omitempty"`
+}
+
+// ConfigField describes a single gameConfig setting, keyed by its dotted
+// path below spec.gameConfig (e.g. "gameplay.gameDifficulty")
+type ConfigField struct {
+	Path            string        `json:"path"`
+	Type            string        `json:"type"` // string, integer, number or boolean
+	Description     string        `json:"description"`
+	Default         interface{}   `json:"default,omitempty"`
+	Minimum         *float64      `json:"minimum,omitempty"`
+	Maximum         *float64      `json:"maximum,omitempty"`
+	Enum            []interface{} `json:"enum,omitempty"`
+	Secret          bool          `json:"secret,omitempty"`
+	RestartRequired bool          `json:"restartRequired"`
+	// NativeKey is the setting name in the game's own config file
+	NativeKey string `json:"nativeKey,omitempty"`
+}
+
+// bound returns a pointer for ConfigField minimum/maximum values
+func bound(v float64) *float64 {
+	return &v
+}
+
+// commonServerFields are the settings every game composition understands
+var commonServerFields = []ConfigField{
+	{Path: "server.maxPlayers", Type: "integer", Description: "Maximum concurrent players", Minimum: bound(1), RestartRequired: true, NativeKey: "MaxPlayers"},
+	{Path: "server.serverPassword", Type: "string", Description: "Server password (auto-generated if empty)", Secret: true, RestartRequired: true, NativeKey: "ServerPassword"},
+	{Path: "server.adminPassword", Type: "string", Description: "Admin password (auto-generated if empty)", Secret: true, RestartRequired: true, NativeKey: "AdminPassword"},
+}
+
+// games holds the definitions for every supported game type. The SDTD
+// fields mirror crossplane/games/sdtd/definition.yaml; every gameConfig value
+// is rendered into a config file mounted via subPath, so changes only take
+// effect after the pod restarts.
+var games = map[string]GameDefinition{
+	"sdtd": {
+		Type:        "sdtd",
+		DisplayName: "7 Days to Die",
+		ChildKind:   "XSDTDGameServer",
+		Image:       "kubelize/game-servers:0.2.9-sdtd",
+		GamePort:    26900,
+		WebPort:     8080,
+		SteamAppID:  294420,
+		Query:       &QueryInfo{Protocol: "a2s", Port: 26900},
+		// Defaults of crossplane/games/sdtd/composition.yaml
+		DefaultResources: &Resources{CPU: "4", Memory: "8Gi", StorageSize: "50Gi"},
+		DefaultProbes: &Probes{
+			Startup:   ProbeTiming{InitialDelaySeconds: 60, PeriodSeconds: 15, TimeoutSeconds: 5, FailureThreshold: 60},
+			Liveness:  ProbeTiming{PeriodSeconds: 30, TimeoutSeconds: 10, FailureThreshold: 3},
+			Readiness: ProbeTiming{PeriodSeconds: 15, TimeoutSeconds: 5, FailureThreshold: 2},
+		},
+		ConfigFile: &ConfigFile{
+			Path:   "serverconfig.xml",
+			Format: "xml",
+			SpecKeys: map[string]string{
+				"serverName":        "ServerName",
+				"serverDescription": "ServerDescription",
+				"motd":              "ServerLoginConfirmationText",
+			},
+		},
+		AdminList: &AdminList{
+			Path:       "Saves/serveradmin.xml",
+			Format:     "serveradmin-xml",
+			LiveReload: true,
+			MaxLevel:   1000,
+		},
+		Whitelist: &PlayerList{
+			Path:       "Saves/serveradmin.xml",
+			Format:     "serveradmin-xml",
+			Section:    "whitelist",
+			LiveReload: true,
+		},
+		BanList: &PlayerList{
+			Path:       "Saves/serveradmin.xml",
+			Format:     "serveradmin-xml",
+			Section:    "blacklist",
+			Element:    "blacklisted",
+			LiveReload: true,
+		},
+		Console: &ConsoleInfo{
+			Protocol:       "telnet",
+			Port:           8081,
+			PortField:      "admin.telnetPort",
+			EnabledField:   "admin.telnetEnabled",
+			SayCommand:     `say "%s"`,
+			SaveCommand:    "saveworld",
+			PlayersCommand: "listplayers",
+			MOTDCommand:    `setgamepref ServerLoginConfirmationText "%s"`,
+			LiveSettings: []LiveSetting{
+				{Name: "time", Type: "string", Description: "Jump to day or night", Enum: []interface{}{"day", "night"}, Command: "settime %v"},
+				{Name: "difficulty", Type: "integer", Description: "Game difficulty (0=Scavenger to 5=Insane)", Minimum: bound(0), Maximum: bound(5), Command: "setgamepref GameDifficulty %v"},
+				{Name: "lootAbundance", Type: "integer", Description: "Loot abundance in percent", Enum: []interface{}{25, 50, 75, 100, 150, 200}, Command: "setgamepref LootAbundance %v"},
+				{Name: "xpMultiplier", Type: "integer", Description: "XP gain in percent", Enum: []interface{}{25, 50, 75, 100, 125, 150, 175, 200, 300}, Command: "setgamepref XPMultiplier %v"},
+			},
+		},
+		Wipe: &WipeInfo{
+			// Worlds live in Saves/<world>/<game>, next to serveradmin.xml
+			Paths: []string{"Saves/*/", "GeneratedWorlds/*/"},
+		},
+		World: &WorldInfo{
+			NameField: "world.worldName",
+			SeedField: "world.worldGenSeed",
+			SizeField: "world.worldGenSize",
+		},
+		ChatPattern: regexp.MustCompile(`Chat \(from '(?P<playerId>[^']*)', entity id '[^']*', to '(?P<channel>[^']*)'\): '(?P<player>[^']*)': (?P<message>.*)$`),
+		ConfigFields: []ConfigField{
+			{Path: "server.maxPlayers", Type: "integer", Description: "Maximum concurrent players (1-64)", Default: 8, Minimum: bound(1), Maximum: bound(64), RestartRequired: true, NativeKey: "ServerMaxPlayerCount"},
+			{Path: "server.serverPassword", Type: "string", Description: "Server password (auto-generated if empty)", Secret: true, RestartRequired: true, NativeKey: "ServerPassword"},
+			{Path: "server.adminPassword", Type: "string", Description: "Admin password (auto-generated if empty)", Secret: true, RestartRequired: true},
+			{Path: "server.region", Type: "string", Description: "Server region", Default: "NorthAmericaEast", Enum: []interface{}{"NorthAmericaEast", "NorthAmericaWest", "Europe", "Asia", "Oceania"}, RestartRequired: true, NativeKey: "Region"},
+			{Path: "world.worldName", Type: "string", Description: "World name/type", Default: "Navezgane", Enum: []interface{}{"Navezgane", "Random Gen", "PREGEN01", "PREGEN02", "PREGEN03", "PREGEN06", "PREGEN08", "PREGEN10"}, RestartRequired: true, NativeKey: "GameWorld"},
+			{Path: "world.worldGenSeed", Type: "string", Description: "World generation seed", Default: "Random", RestartRequired: true, NativeKey: "WorldGenSeed"},
+			{Path: "world.worldGenSize", Type: "integer", Description: "Generated world size (for Random Gen)", Default: 8192, Enum: []interface{}{6144, 8192, 10240}, RestartRequired: true, NativeKey: "WorldGenSize"},
+			{Path: "gameplay.gameDifficulty", Type: "integer", Description: "Game difficulty (0=Scavenger to 5=Insane)", Default: 1, Minimum: bound(0), Maximum: bound(5), RestartRequired: true, NativeKey: "GameDifficulty"},
+			{Path: "gameplay.dayNightLength", Type: "integer", Description: "Real minutes for 24h game time", Default: 60, Minimum: bound(10), Maximum: bound(120), RestartRequired: true, NativeKey: "DayNightLength"},
+			{Path: "gameplay.dayLightLength", Type: "integer", Description: "Hours of daylight (9-21)", Default: 18, Minimum: bound(9), Maximum: bound(21), RestartRequired: true, NativeKey: "DayLightLength"},
+			{Path: "gameplay.zombieSpawnMode", Type: "string", Description: "How zombies spawn during day", Default: "Walk", Enum: []interface{}{"Walk", "Jog", "Run", "Sprint", "Nightmare"}, RestartRequired: true, NativeKey: "ZombieSpawnMode"},
+			{Path: "gameplay.bloodMoonFrequency", Type: "integer", Description: "Days between blood moons (0=disabled)", Default: 7, Minimum: bound(0), Maximum: bound(60), RestartRequired: true, NativeKey: "BloodMoonFrequency"},
+			{Path: "gameplay.bloodMoonRange", Type: "integer", Description: "Random range for blood moon timing", Default: 0, Minimum: bound(0), Maximum: bound(5), RestartRequired: true, NativeKey: "BloodMoonRange"},
+			{Path: "performance.maxSpawnedZombies", Type: "integer", Description: "Maximum spawned zombies", Default: 60, Minimum: bound(8), Maximum: bound(256), RestartRequired: true, NativeKey: "MaxSpawnedZombies"},
+			{Path: "performance.maxSpawnedAnimals", Type: "integer", Description: "Maximum spawned animals", Default: 50, Minimum: bound(1), Maximum: bound(50), RestartRequired: true, NativeKey: "MaxSpawnedAnimals"},
+			{Path: "performance.serverMaxAllowedViewDistance", Type: "integer", Description: "Max view distance (impacts performance)", Default: 12, Minimum: bound(6), Maximum: bound(12), RestartRequired: true, NativeKey: "ServerMaxAllowedViewDistance"},
+			{Path: "performance.maxChunkAge", Type: "integer", Description: "Max chunk age in game time", Default: -1, RestartRequired: true, NativeKey: "MaxChunkAge"},
+			{Path: "pvp.playerKillingMode", Type: "integer", Description: "PvP mode (0=None, 1=Allies, 2=Strangers, 3=Everyone)", Default: 0, Minimum: bound(0), Maximum: bound(3), RestartRequired: true, NativeKey: "PlayerKillingMode"},
+			{Path: "pvp.playerDamageMultiplier", Type: "number", Description: "Player damage multiplier", Default: 1.0, Minimum: bound(0.1), Maximum: bound(10), RestartRequired: true, NativeKey: "PlayerDamageMultiplier"},
+			{Path: "pvp.zombieDamageMultiplier", Type: "number", Description: "Zombie damage multiplier", Default: 1.0, Minimum: bound(0.1), Maximum: bound(10), RestartRequired: true, NativeKey: "ZombieDamageMultiplier"},
+			{Path: "pvp.blockDamagePlayer", Type: "number", Description: "Block damage by players multiplier", Default: 1.0, Minimum: bound(0.1), Maximum: bound(10), RestartRequired: true, NativeKey: "BlockDamagePlayer"},
+			{Path: "admin.webControlEnabled", Type: "boolean", Description: "Enable web control panel", Default: true, RestartRequired: true, NativeKey: "ControlPanelEnabled"},
+			{Path: "admin.webControlPort", Type: "integer", Description: "Web control panel port", Default: 8080, Minimum: bound(1024), Maximum: bound(65535), RestartRequired: true, NativeKey: "ControlPanelPort"},
+			{Path: "admin.webControlPassword", Type: "string", Description: "Web control password (auto-generated if empty)", Secret: true, RestartRequired: true, NativeKey: "ControlPanelPassword"},
+			{Path: "admin.enableMapRendering", Type: "boolean", Description: "Enable live map in web interface", Default: true, RestartRequired: true, NativeKey: "EnableMapRendering"},
+			{Path: "admin.telnetEnabled", Type: "boolean", Description: "Enable telnet console access", Default: false, RestartRequired: true, NativeKey: "TelnetEnabled"},
+			{Path: "admin.telnetPort", Type: "integer", Description: "Telnet port", Default: 8081, Minimum: bound(1024), Maximum: bound(65535), RestartRequired: true, NativeKey: "TelnetPort"},
+		},
+	},
+	"ce": {
+		Type:        "ce",
+		DisplayName: "Conan Exiles",
+		ChildKind:   "XConanExilesGameServer",
+		Image:       "kubelize/game-servers:0.2.9-ce",
+		GamePort:    7777,
+		WebPort:     27015,
+		SteamAppID:  443030,
+		Query:       &QueryInfo{Protocol: "a2s", Port: 27015},
+		Linking:     &LinkInfo{Roles: []string{"primary", "map"}},
+		ConfigFile: &ConfigFile{
+			Path:     "ConanSandbox/Saved/Config/LinuxServer/ServerSettings.ini",
+			Format:   "ini",
+			Section:  "ServerSettings",
+			SpecKeys: map[string]string{"serverName": "ServerName", "motd": "ServerMessageOfTheDay"},
+		},
+		Whitelist: &PlayerList{
+			Path:   "ConanSandbox/Saved/whitelist.txt",
+			Format: "id-list-txt",
+		},
+		BanList: &PlayerList{
+			Path:   "ConanSandbox/Saved/blacklist.txt",
+			Format: "id-list-txt",
+		},
+		Wipe: &WipeInfo{
+			Paths: []string{"ConanSandbox/Saved/game.db*"},
+		},
+		ConfigFields: commonServerFields,
+	},
+	"pw": {
+		Type:        "pw",
+		DisplayName: "Palworld",
+		ChildKind:   "XPalworldGameServer",
+		Image:       "kubelize/game-servers:0.2.9-pw",
+		GamePort:    8211,
+		WebPort:     8212,
+		SteamAppID:  2394010,
+		Console: &ConsoleInfo{
+			Protocol:         "rcon",
+			Port:             25575,
+			PasswordSecret:   "admin-password",
+			PasswordKey:      "AdminPassword",
+			SayCommand:       "Broadcast %s",
+			SaveCommand:      "Save",
+			SaveConfirmation: "Complete Save",
+			PlayersCommand:   "ShowPlayers",
+		},
+		Wipe: &WipeInfo{
+			Paths: []string{"Pal/Saved/SaveGames/*/"},
+		},
+		ConfigFields: commonServerFields,
+	},
+	"vh": {
+		Type:        "vh",
+		DisplayName: "Valheim",
+		ChildKind:   "XValheimGameServer",
+		Image:       "kubelize/game-servers:0.2.9-vh",
+		GamePort:    2456,
+		WebPort:     2457,
+		SteamAppID:  896660,
+		Query:       &QueryInfo{Protocol: "a2s", Port: 2457},
+		AdminList: &AdminList{
+			Path:       "worlds/adminlist.txt",
+			Format:     "adminlist-txt",
+			LiveReload: true,
+		},
+		Whitelist: &PlayerList{
+			Path:       "worlds/permittedlist.txt",
+			Format:     "id-list-txt",
+			Header:     "// List permitted players ID ONE per line",
+			LiveReload: true,
+		},
+		BanList: &PlayerList{
+			Path:       "worlds/bannedlist.txt",
+			Format:     "id-list-txt",
+			Header:     "// List banned players ID ONE per line",
+			LiveReload: true,
+		},
+		Wipe: &WipeInfo{
+			Paths: []string{"worlds/*.db*", "worlds/*.fwl*", "worlds_local/*.db*", "worlds_local/*.fwl*"},
+		},
+		ConfigFields: commonServerFields,
+	},
+	"we": {
+		Type:         "we",
+		DisplayName:  "Whatever",
+		ChildKind:    "XWhateverGameServer",
+		Image:        "kubelize/game-servers:0.2.9-we",
+		GamePort:     15777,
+		WebPort:      15778,
+		ConfigFields: commonServerFields,
+	},
+	"ln": {
+		Type:        "ln",
+		DisplayName: "Linux",
+		ChildKind:   "XLinuxGameServer",
+		Image:       "kubelize/game-servers:0.2.9-ln",
+		GamePort:    25565,
+		WebPort:     25566,
+		Linking:     &LinkInfo{Roles: []string{"proxy", "shard"}},
+		Console: &ConsoleInfo{
+			Protocol:         "rcon",
+			Port:             25575,
+			PasswordSecret:   "admin-password",
+			PasswordKey:      "AdminPassword",
+			SayCommand:       "say %s",
+			SaveCommand:      "save-all flush",
+			SaveConfirmation: "Saved the game",
+			PlayersCommand:   "list",
+			LiveSettings: []LiveSetting{
+				{Name: "time", Type: "string", Description: "Time of day", Enum: []interface{}{"day", "noon", "night", "midnight"}, Command: "time set %v"},
+				{Name: "weather", Type: "string", Description: "Weather", Enum: []interface{}{"clear", "rain", "thunder"}, Command: "weather %v"},
+				{Name: "difficulty", Type: "string", Description: "Game difficulty", Enum: []interface{}{"peaceful", "easy", "normal", "hard"}, Command: "difficulty %v"},
+			},
+		},
+		Whitelist: &PlayerList{
+			Path:          "whitelist.json",
+			Format:        "whitelist-json",
+			ReloadCommand: "whitelist reload",
+		},
+		BanList: &PlayerList{
+			Path:   "banned-players.json",
+			Format: "banned-players-json",
+		},
+		Wipe: &WipeInfo{
+			Paths: []string{"world/", "world_nether/", "world_the_end/"},
+		},
+		ConfigFields: commonServerFields,
+		ChatPattern:  regexp.MustCompile(`\]: <(?P<player>[^>]+)> (?P<message>.*)$`),
+	},
+	"proxy": {
+		Type:        "proxy",
+		DisplayName: "Proxy",
+		ChildKind:   "XProxyGameServer",
+		Image:       "kubelize/game-servers:0.2.9-proxy",
+		GamePort:    7777,
+		Proxy:       &ProxyInfo{Strategies: []string{"failover", "round-robin"}},
+		// A proxy keeps no world, only its routing config
+		DefaultResources: &Resources{CPU: "500m", Memory: "256Mi", StorageSize: "1Gi"},
+		ConfigFields: []ConfigField{
+			{Path: "proxy.protocol", Type: "string", Description: "Transport forwarded to the backends", Default: "tcp", Enum: []interface{}{"tcp", "udp"}, RestartRequired: true},
+			{Path: "proxy.listenPort", Type: "integer", Description: "Port players connect to", Default: 7777, Minimum: bound(1024), Maximum: bound(65535), RestartRequired: true},
+			{Path: "proxy.maxConnections", Type: "integer", Description: "Maximum concurrent connections (0=unlimited)", Default: 0, Minimum: bound(0), RestartRequired: true},
+		},
+	},
+}
+
+// restartRequiredSpecFields lists top-level spec fields that are rendered
+// into the game's config file. Resource and scheduling changes alter the pod
+// template and are rolled out by Kubernetes on its own, so they are not listed.
+// Lookup returns the catalog definition for a game type
+func Lookup(gameType string) (GameDefinition, bool) {
+	def, ok := games[gameType]
+	return def, ok
+}
+
+// SupportedTypes returns the sorted list of game types in the catalog
+func SupportedTypes() []string {
+	types := make([]string, 0, len(games))
+	for t := range games {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Field returns the catalog entry for a gameConfig path
+func (d GameDefinition) Field(path string) (ConfigField, bool) {
+	for _, f := range d.ConfigFields {
+		if f.Path == path {
+			return f, true
+		}
+	}
+	return ConfigField{}, false
+}
+
+// LiveSetting returns the console's live setting with the given name
+func (c *ConsoleInfo) LiveSetting(name string) (LiveSetting, bool) {
+	if c == nil {
+		return LiveSetting{}, false
+	}
+	for _, setting := range c.LiveSettings {
+		if setting.Name == name {
+			return setting, true
+		}
+	}
+	return LiveSetting{}, false
+}
+
+// Field is the setting as a ConfigField, for validating values with
+// ValidateValue
+func (s LiveSetting) Field() ConfigField {
+	return ConfigField{
+		Path:        s.Name,
+		Type:        s.Type,
+		Description: s.Description,
+		Minimum:     s.Minimum,
+		Maximum:     s.Maximum,
+		Enum:        s.Enum,
+	}
+}
+
+// ValidateValue checks a value against a field's type, range and enum
+func ValidateValue(field ConfigField, value interface{}) error {
+	switch field.Type {
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("expected a string")
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("expected a boolean")
+		}
+	case "integer", "number":
+		n, ok := ToFloat(value)
+		if !ok {
+			return fmt.Errorf("expected a number")
+		}
+		if field.Type == "integer" && n != math.Trunc(n) {
+			return fmt.Errorf("expected an integer")
+		}
+		if field.Minimum != nil && n < *field.Minimum {
+			return fmt.Errorf("must be at least %v", *field.Minimum)
+		}
+		if field.Maximum != nil && n > *field.Maximum {
+			return fmt.Errorf("must be at most %v", *field.Maximum)
+		}
+	}
+
+	if len(field.Enum) > 0 {
+		for _, allowed := range field.Enum {
+			if ValuesEqual(value, allowed) {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %v", field.Enum)
+	}
+	return nil
+}
+
+// ValuesEqual compares config values, treating all numeric types alike
+func ValuesEqual(a, b interface{}) bool {
+	af, aNum := ToFloat(a)
+	bf, bNum := ToFloat(b)
+	if aNum && bNum {
+		return af == bf
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// ToFloat converts any numeric config value to float64
+func ToFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
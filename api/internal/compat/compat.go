@@ -0,0 +1,212 @@
+// Package compat lets API versions share handlers. Handlers keep producing
+// v1 responses; V2 reshapes them into v2's structured errors and paginated
+// lists, and Deprecated announces a version's retirement. A handler that
+// needs a v2-only shape checks Version and calls Native so its response is
+// passed through untouched.
+package compat
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+)
+
+const (
+	// versionKey is the gin context key holding the request's API version
+	versionKey = "gameplane.apiVersion"
+	// nativeKey marks a response that is already in the version's shape
+	nativeKey = "gameplane.nativeResponse"
+
+	// defaultLimit and maxLimit bound v2 list pages
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// Lifecycle are the retirement dates of a deprecated API version
+type Lifecycle struct {
+	// Deprecated is when clients were told to move off the version
+	Deprecated time.Time
+	// Sunset is when the version may stop responding; zero if undecided
+	Sunset time.Time
+}
+
+// Version returns the API version serving the request, "v1" or "v2"
+func Version(c *gin.Context) string {
+	if c.GetString(versionKey) == "v2" {
+		return "v2"
+	}
+	return "v1"
+}
+
+// Native marks the response as already in the request's version shape
+func Native(c *gin.Context) {
+	c.Set(nativeKey, true)
+}
+
+// Deprecated adds Deprecation (RFC 9745) and Sunset (RFC 8594) headers to
+// every response, with a Link to the same path under successorPrefix
+func Deprecated(lifecycle Lifecycle, prefix, successorPrefix string) gin.HandlerFunc {
+	deprecation := fmt.Sprintf("@%d", lifecycle.Deprecated.Unix())
+	sunset := ""
+	if !lifecycle.Sunset.IsZero() {
+		sunset = lifecycle.Sunset.UTC().Format(http.TimeFormat)
+	}
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecation)
+		if sunset != "" {
+			c.Header("Sunset", sunset)
+		}
+		successor := successorPrefix + strings.TrimPrefix(c.Request.URL.Path, prefix)
+		c.Header("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+		c.Next()
+	}
+}
+
+// Error is the v2 error body
+type Error struct {
+	// Code is a stable machine-readable reason, e.g. not_found
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Details carries any further fields of the v1 error response
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// ListMetadata is the v2 pagination envelope
+type ListMetadata struct {
+	// Total counts all items, not just this page
+	Total int `json:"total"`
+	Limit int `json:"limit"`
+	// Continue fetches the next page when passed as ?continue=; empty on
+	// the last page
+	Continue string `json:"continue,omitempty"`
+}
+
+// V2 serves v1 handlers under v2 response shapes:
+//
+//	{"error": "..."}                 -> {"error": {"code": "...", "message": "...", "details": {...}}}
+//	{"items": [...], "total": n}     -> {"items": [page], "metadata": {"total": n, "limit": l, "continue": "..."}}
+//
+// Lists are paged with ?limit= (default 100, at most 1000) and ?continue=.
+func V2() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(versionKey, "v2")
+		offset, limit, err := pageParams(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": Error{Code: errorCode(http.StatusBadRequest), Message: err.Error()}})
+			return
+		}
+
+		w := handlers.Buffer(c)
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		body := w.Body.Bytes()
+		if !c.GetBool(nativeKey) && strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+			if reshaped, ok := reshape(w.Status(), body, offset, limit); ok {
+				body = reshaped
+				w.Header().Del("Content-Length")
+			}
+		}
+		_, _ = c.Writer.Write(body)
+	}
+}
+
+// pageParams reads ?limit= and ?continue=
+func pageParams(c *gin.Context) (offset, limit int, err error) {
+	limit = defaultLimit
+	if raw := c.Query("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 || limit > maxLimit {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+	}
+	if token := c.Query("continue"); token != "" {
+		raw, err := base64.RawURLEncoding.DecodeString(token)
+		if err == nil {
+			offset, err = strconv.Atoi(string(raw))
+		}
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("invalid continue token")
+		}
+	}
+	return offset, limit, nil
+}
+
+// reshape converts a v1 JSON body; ok is false if nothing needed changing
+func reshape(status int, body []byte, offset, limit int) ([]byte, bool) {
+	var object map[string]interface{}
+	if err := json.Unmarshal(body, &object); err != nil {
+		return nil, false
+	}
+
+	if status >= http.StatusBadRequest {
+		message, isString := object["error"].(string)
+		if !isString {
+			return nil, false
+		}
+		v2 := Error{Code: errorCode(status), Message: message}
+		for key, value := range object {
+			if key == "error" {
+				continue
+			}
+			if v2.Details == nil {
+				v2.Details = map[string]interface{}{}
+			}
+			v2.Details[key] = value
+		}
+		data, err := json.Marshal(gin.H{"error": v2})
+		return data, err == nil
+	}
+
+	items, isList := object["items"].([]interface{})
+	if !isList {
+		return nil, false
+	}
+	metadata := ListMetadata{Total: len(items), Limit: limit}
+	end := offset + limit
+	if offset > len(items) {
+		offset = len(items)
+	}
+	if end < len(items) {
+		metadata.Continue = base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(end)))
+	} else {
+		end = len(items)
+	}
+	object["items"] = items[offset:end]
+	delete(object, "total")
+	object["metadata"] = metadata
+	data, err := json.Marshal(object)
+	return data, err == nil
+}
+
+// errorCode names an HTTP error status for v2 error bodies
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_request"
+	case http.StatusUnauthorized:
+		return "unauthenticated"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusUnprocessableEntity:
+		return "unprocessable"
+	case http.StatusTooManyRequests:
+		return "rate_limited"
+	case http.StatusServiceUnavailable:
+		return "unavailable"
+	}
+	if status >= http.StatusInternalServerError {
+		return "internal"
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
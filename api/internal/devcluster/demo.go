@@ -0,0 +1,195 @@
+package devcluster
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// demoServer describes a seeded GameServer and its workload
+type demoServer struct {
+	Namespace  string
+	Name       string
+	GameType   string
+	ServerName string
+	Players    int64
+	Address    string
+	Phase      string
+	GameConfig map[string]interface{}
+}
+
+// demoServers are the GameServers of the dev cluster
+var demoServers = []demoServer{
+	{
+		Namespace:  "default",
+		Name:       "demo-sdtd",
+		GameType:   "sdtd",
+		ServerName: "Demo 7 Days to Die",
+		Players:    3,
+		Address:    "203.0.113.10",
+		GameConfig: map[string]interface{}{
+			"server": map[string]interface{}{"maxPlayers": int64(8)},
+			"world":  map[string]interface{}{"gameWorld": "Navezgane"},
+		},
+	},
+	{
+		Namespace:  "default",
+		Name:       "demo-valheim",
+		GameType:   "vh",
+		ServerName: "Demo Valheim",
+		Players:    0,
+		Address:    "203.0.113.11",
+	},
+	{
+		Namespace:  "default",
+		Name:       "demo-palworld",
+		GameType:   "pw",
+		ServerName: "Demo Palworld",
+		Address:    "203.0.113.12",
+		Phase:      "Installing",
+	},
+}
+
+// composite names the composite resource Crossplane would create
+func (d demoServer) composite() string {
+	return d.Name + "-dev01"
+}
+
+// managedNamespace is the namespace of the server's workload
+func (d demoServer) managedNamespace() string {
+	return d.composite() + "-" + d.GameType
+}
+
+// objects returns the claim and its workload
+func (d demoServer) objects() []client.Object {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"gameType":   d.GameType,
+			"serverName": d.ServerName,
+			"resourceRef": map[string]interface{}{
+				"apiVersion": "gameplane.kubelize.io/v1alpha1",
+				"kind":       "XGameServer",
+				"name":       d.composite(),
+			},
+		},
+		"status": d.status(),
+	}}
+	if d.GameConfig != nil {
+		obj.Object["spec"].(map[string]interface{})["gameConfig"] = d.GameConfig
+	}
+	obj.SetGroupVersionKind(gameServerGVK)
+	obj.SetNamespace(d.Namespace)
+	obj.SetName(d.Name)
+	obj.SetLabels(map[string]string{
+		"app.kubernetes.io/name":          "gameserver",
+		"app.kubernetes.io/instance":      d.Name,
+		"gameplane.kubelize.io/game-type": d.GameType,
+	})
+	obj.SetCreationTimestamp(metav1.NewTime(time.Now().Add(-36 * time.Hour)))
+	return append([]client.Object{obj}, d.workload()...)
+}
+
+// status is the claim status Crossplane and the status controller would set
+func (d demoServer) status() map[string]interface{} {
+	phase := d.Phase
+	if phase == "" {
+		phase = "Running"
+	}
+	ready := "False"
+	if phase == "Running" {
+		ready = "True"
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	return map[string]interface{}{
+		"phase":          phase,
+		"childType":      d.GameType,
+		"childName":      d.composite(),
+		"serverIP":       d.Address,
+		"gamePort":       int64(d.gamePort()),
+		"serverEndpoint": fmt.Sprintf("%s:%d", d.Address, d.gamePort()),
+		"playersOnline":  d.Players,
+		"lastUpdate":     now,
+		"conditions": []interface{}{
+			map[string]interface{}{
+				"type":               "Ready",
+				"status":             ready,
+				"reason":             phase,
+				"message":            "",
+				"lastTransitionTime": now,
+			},
+		},
+	}
+}
+
+// gamePort is a plausible player port for the game type
+func (d demoServer) gamePort() int32 {
+	switch d.GameType {
+	case "sdtd":
+		return 26900
+	case "vh":
+		return 2456
+	case "pw":
+		return 8211
+	}
+	return 7777
+}
+
+// workload returns the namespace, pod, service and volume of the server
+func (d demoServer) workload() []client.Object {
+	namespace := d.managedNamespace()
+	labels := map[string]string{"kubelize.io/gameserver": namespace}
+	phase := corev1.PodRunning
+	if d.Phase != "" && d.Phase != "Running" {
+		phase = corev1.PodPending
+	}
+
+	return []client.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace, Labels: labels}},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: namespace + "-deployment-0", Namespace: namespace, Labels: labels},
+			Spec: corev1.PodSpec{
+				NodeName:   "dev-node",
+				Containers: []corev1.Container{{Name: "game", Image: "kubelize/game-servers:dev-" + d.GameType}},
+			},
+			Status: corev1.PodStatus{
+				Phase:  phase,
+				PodIP:  "10.0.0.10",
+				HostIP: "192.0.2.10",
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  "game",
+					Ready: phase == corev1.PodRunning,
+				}},
+			},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      namespace + "-game-service",
+				Namespace: namespace,
+				Labels:    map[string]string{"kubelize.io/gameserver": namespace, "kubelize.io/service-type": "game"},
+			},
+			Spec: corev1.ServiceSpec{
+				Type:      corev1.ServiceTypeLoadBalancer,
+				ClusterIP: "10.96.0.10",
+				Ports:     []corev1.ServicePort{{Name: "game-udp", Port: d.gamePort(), Protocol: corev1.ProtocolUDP}},
+			},
+			Status: corev1.ServiceStatus{
+				LoadBalancer: corev1.LoadBalancerStatus{Ingress: []corev1.LoadBalancerIngress{{IP: d.Address}}},
+			},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: namespace + "-storage", Namespace: namespace, Labels: labels},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("20Gi")},
+				},
+			},
+			Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+		},
+	}
+}
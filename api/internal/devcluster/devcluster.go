@@ -0,0 +1,333 @@
+// Package devcluster provides an in-memory stand-in for a Kubernetes cluster
+// running Crossplane, so the API can be run and its handlers exercised
+// without a cluster. It backs `gameplane-api --dev` and the gameplanetest
+// harness.
+package devcluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// provisionInterval is how often new claims are "provisioned"
+const provisionInterval = 5 * time.Second
+
+// gameServerGVK is the GameServer claim kind
+var gameServerGVK = schema.GroupVersionKind{
+	Group:   "gameplane.kubelize.io",
+	Version: "v1alpha1",
+	Kind:    "GameServer",
+}
+
+// Cluster is an in-memory cluster. GameServer claims live in Client, core
+// resources in Kube.
+type Cluster struct {
+	Config *rest.Config
+	Client client.Client
+	Kube   kubernetes.Interface
+}
+
+// New creates a cluster holding the given objects. Unstructured objects go
+// to the controller-runtime client, typed core objects to the clientset.
+// The default and gameplane-system namespaces and a single node always
+// exist.
+func New(objects ...client.Object) *Cluster {
+	claims := []client.Object{}
+	core := []runtime.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "gameplane-system"}},
+		devNode(),
+	}
+	for _, obj := range objects {
+		if u, ok := obj.(*unstructured.Unstructured); ok {
+			claims = append(claims, u)
+			continue
+		}
+		core = append(core, obj)
+	}
+
+	claimType := &unstructured.Unstructured{}
+	claimType.SetGroupVersionKind(gameServerGVK)
+	banListType := &unstructured.Unstructured{}
+	banListType.SetGroupVersionKind(gameServerGVK.GroupVersion().WithKind("BanList"))
+	ctrlClient := fake.NewClientBuilder().
+		WithScheme(runtime.NewScheme()).
+		WithObjects(claims...).
+		WithStatusSubresource(claimType, banListType).
+		Build()
+
+	return &Cluster{
+		Config: &rest.Config{Host: "https://dev.gameplane.invalid"},
+		Client: ctrlClient,
+		Kube:   newClientset(core...),
+	}
+}
+
+// Demo creates a cluster seeded with running demo GameServers
+func Demo() *Cluster {
+	objects := []client.Object{}
+	for _, demo := range demoServers {
+		objects = append(objects, demo.objects()...)
+	}
+	return New(objects...)
+}
+
+// Run stands in for Crossplane until the context ends: claims created
+// through the API get a managed namespace, a running pod and a game service,
+// and become Running a few seconds later
+func (c *Cluster) Run(ctx context.Context) {
+	ticker := time.NewTicker(provisionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.provision(ctx); err != nil {
+				log.Printf("Dev cluster failed to provision GameServers: %v", err)
+			}
+			if err := c.takeSnapshots(ctx); err != nil {
+				log.Printf("Dev cluster failed to take volume snapshots: %v", err)
+			}
+		}
+	}
+}
+
+// provision completes every claim without a resourceRef and removes the
+// workload of deleted claims
+func (c *Cluster) provision(ctx context.Context) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gameServerGVK.GroupVersion().WithKind("GameServerList"))
+	if err := c.Client.List(ctx, list); err != nil {
+		return err
+	}
+	managed := map[string]bool{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+		if ref, _, _ := unstructured.NestedString(obj.Object, "spec", "resourceRef", "name"); ref != "" {
+			managed[ref+"-"+gameType] = true
+			continue
+		}
+		demo := demoServer{Namespace: obj.GetNamespace(), Name: obj.GetName(), GameType: gameType, Address: fmt.Sprintf("203.0.113.%d", 100+i%100)}
+		managed[demo.managedNamespace()] = true
+
+		_ = unstructured.SetNestedField(obj.Object, demo.composite(), "spec", "resourceRef", "name")
+		_ = unstructured.SetNestedField(obj.Object, "XGameServer", "spec", "resourceRef", "kind")
+		if err := c.Client.Update(ctx, obj); err != nil {
+			return err
+		}
+		for _, core := range demo.workload() {
+			if err := c.createCore(ctx, core); err != nil {
+				return err
+			}
+		}
+		obj.Object["status"] = demo.status()
+		if err := c.Client.Status().Update(ctx, obj); err != nil {
+			return err
+		}
+	}
+	return c.removeOrphans(ctx, managed)
+}
+
+// takeSnapshots marks new VolumeSnapshots taken and ready, as a CSI driver
+// would
+func (c *Cluster) takeSnapshots(ctx context.Context) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{Group: "snapshot.storage.k8s.io", Version: "v1", Kind: "VolumeSnapshotList"})
+	if err := c.Client.List(ctx, list); err != nil {
+		return err
+	}
+	for i := range list.Items {
+		snapshot := &list.Items[i]
+		if _, taken, _ := unstructured.NestedString(snapshot.Object, "status", "creationTime"); taken {
+			continue
+		}
+		snapshot.Object["status"] = map[string]interface{}{
+			"creationTime": time.Now().UTC().Format(time.RFC3339),
+			"readyToUse":   true,
+			"restoreSize":  "20Gi",
+		}
+		if err := c.Client.Update(ctx, snapshot); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeOrphans deletes the workload namespaces of claims that no longer
+// exist. The fake clientset doesn't cascade, so their contents go first.
+func (c *Cluster) removeOrphans(ctx context.Context, managed map[string]bool) error {
+	namespaces, err := c.Kube.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: "kubelize.io/gameserver"})
+	if err != nil {
+		return err
+	}
+	core := c.Kube.CoreV1()
+	for _, ns := range namespaces.Items {
+		if managed[ns.Name] {
+			continue
+		}
+		all := metav1.ListOptions{}
+		if err := core.Pods(ns.Name).DeleteCollection(ctx, metav1.DeleteOptions{}, all); err != nil {
+			return err
+		}
+		if err := core.PersistentVolumeClaims(ns.Name).DeleteCollection(ctx, metav1.DeleteOptions{}, all); err != nil {
+			return err
+		}
+		services, err := core.Services(ns.Name).List(ctx, all)
+		if err != nil {
+			return err
+		}
+		for _, svc := range services.Items {
+			if err := core.Services(ns.Name).Delete(ctx, svc.Name, metav1.DeleteOptions{}); err != nil {
+				return err
+			}
+		}
+		if err := core.Namespaces().Delete(ctx, ns.Name, metav1.DeleteOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createCore adds a typed core object to the clientset
+func (c *Cluster) createCore(ctx context.Context, obj client.Object) error {
+	var err error
+	switch o := obj.(type) {
+	case *corev1.Namespace:
+		_, err = c.Kube.CoreV1().Namespaces().Create(ctx, o, metav1.CreateOptions{})
+	case *corev1.Pod:
+		_, err = c.Kube.CoreV1().Pods(o.Namespace).Create(ctx, o, metav1.CreateOptions{})
+	case *corev1.Service:
+		_, err = c.Kube.CoreV1().Services(o.Namespace).Create(ctx, o, metav1.CreateOptions{})
+	case *corev1.PersistentVolumeClaim:
+		_, err = c.Kube.CoreV1().PersistentVolumeClaims(o.Namespace).Create(ctx, o, metav1.CreateOptions{})
+	case *corev1.Secret:
+		_, err = c.Kube.CoreV1().Secrets(o.Namespace).Create(ctx, o, metav1.CreateOptions{})
+	default:
+		err = fmt.Errorf("unsupported dev cluster object %T", obj)
+	}
+	return err
+}
+
+// devNode is the node every dev pod runs on
+func devNode() *corev1.Node {
+	capacity := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("16"),
+		corev1.ResourceMemory: resource.MustParse("64Gi"),
+		corev1.ResourcePods:   resource.MustParse("110"),
+	}
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "dev-node",
+			Labels: map[string]string{"kubernetes.io/hostname": "dev-node", "topology.kubernetes.io/region": "dev"},
+		},
+		Status: corev1.NodeStatus{
+			Capacity:    capacity,
+			Allocatable: capacity,
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeInternalIP, Address: "192.0.2.10"},
+				{Type: corev1.NodeExternalIP, Address: "203.0.113.1"},
+			},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: "v1.28.0", OperatingSystem: "linux", Architecture: "amd64"},
+		},
+	}
+}
+
+// clientset is the fake clientset with a REST client answering the raw
+// requests the API makes, such as pod metrics
+type clientset struct {
+	*kubefake.Clientset
+	rest rest.Interface
+}
+
+func newClientset(objects ...runtime.Object) *clientset {
+	restClient, err := rest.RESTClientFor(&rest.Config{
+		Host:      "https://dev.gameplane.invalid",
+		APIPath:   "/api",
+		Transport: roundTripper(serveRaw),
+		ContentConfig: rest.ContentConfig{
+			GroupVersion:         &corev1.SchemeGroupVersion,
+			NegotiatedSerializer: scheme.Codecs.WithoutConversion(),
+		},
+	})
+	if err != nil {
+		panic(fmt.Sprintf("failed to build dev REST client: %v", err))
+	}
+	kube := kubefake.NewSimpleClientset(objects...)
+	// The dev cluster has no RBAC, so every access review is allowed
+	kube.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview).DeepCopy()
+		review.Status = authorizationv1.SubjectAccessReviewStatus{Allowed: true, Reason: "dev cluster"}
+		return true, review, nil
+	})
+	return &clientset{Clientset: kube, rest: restClient}
+}
+
+// CoreV1 returns the fake core client with the dev REST client
+func (c *clientset) CoreV1() corev1client.CoreV1Interface {
+	return coreV1{CoreV1Interface: c.Clientset.CoreV1(), rest: c.rest}
+}
+
+type coreV1 struct {
+	corev1client.CoreV1Interface
+	rest rest.Interface
+}
+
+func (c coreV1) RESTClient() rest.Interface {
+	return c.rest
+}
+
+// roundTripper adapts a function to http.RoundTripper
+type roundTripper func(*http.Request) (*http.Response, error)
+
+func (f roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// serveRaw answers metrics-server pod requests with plausible usage and
+// everything else with 404
+func serveRaw(r *http.Request) (*http.Response, error) {
+	respond := func(status int, body string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    r,
+		}, nil
+	}
+
+	// /apis/metrics.k8s.io/v1beta1/namespaces/{ns}/pods/{name}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) == 7 && parts[1] == "metrics.k8s.io" && parts[5] == "pods" {
+		// Vary usage over time so charts move
+		minute := time.Now().Minute()
+		cpu := resource.NewMilliQuantity(int64(600+minute*20), resource.DecimalSI)
+		memory := resource.NewQuantity(int64(2500+minute*10)<<20, resource.BinarySI)
+		return respond(http.StatusOK, fmt.Sprintf(`{"metadata":{"name":%q,"namespace":%q},"containers":[{"name":"game","usage":{"cpu":%q,"memory":%q}}]}`,
+			parts[6], parts[4], cpu.String(), memory.String()))
+	}
+	return respond(http.StatusNotFound, `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404,"message":"not available in dev mode"}`)
+}
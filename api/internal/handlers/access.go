@@ -0,0 +1,12 @@
+package handlers
+
+import "net/http"
+
+// NamespaceAccess decides which namespaces a caller may read, for
+// installations that give tenants their own namespaces. subject is the
+// Authenticator's subject, empty without authentication.
+type NamespaceAccess interface {
+	// ReadableNamespaces returns the namespaces the caller may read, or
+	// all=true if the caller may read every namespace
+	ReadableNamespaces(r *http.Request, subject string) (namespaces []string, all bool, err error)
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cachedResponse is a successful response kept for reuse
+type cachedResponse struct {
+	contentType string
+	body        []byte
+	expires     time.Time
+}
+
+// Cache serves repeat GETs of the same URL from memory for ttl, for
+// expensive aggregates that dashboards poll. Only 200 responses are cached;
+// the caller's subject and the URL including its query are the key, so
+// callers never see each other's results and ?cluster= selections are kept
+// apart.
+func Cache(ttl time.Duration) gin.HandlerFunc {
+	var mu sync.Mutex
+	entries := map[string]cachedResponse{}
+
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+		key := c.GetString(SubjectKey) + " " + c.Request.URL.String()
+		now := time.Now()
+
+		mu.Lock()
+		entry, ok := entries[key]
+		mu.Unlock()
+		if ok && now.Before(entry.expires) {
+			c.Header("X-Cache", "HIT")
+			c.Data(http.StatusOK, entry.contentType, entry.body)
+			c.Abort()
+			return
+		}
+
+		w := Buffer(c)
+		c.Next()
+		c.Writer = w.ResponseWriter
+		if w.Status() == http.StatusOK {
+			mu.Lock()
+			for k, e := range entries {
+				if now.After(e.expires) {
+					delete(entries, k)
+				}
+			}
+			entries[key] = cachedResponse{
+				contentType: w.Header().Get("Content-Type"),
+				body:        append([]byte(nil), w.Body.Bytes()...),
+				expires:     now.Add(ttl),
+			}
+			mu.Unlock()
+			c.Header("X-Cache", "MISS")
+		}
+		_, _ = c.Writer.Write(w.Body.Bytes())
+	}
+}
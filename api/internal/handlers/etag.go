@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BufferedWriter holds the response body back so middleware can inspect or
+// rewrite it before anything reaches the client. Restore ResponseWriter as
+// the context's writer and write the body out when done.
+type BufferedWriter struct {
+	gin.ResponseWriter
+	Body bytes.Buffer
+}
+
+func (w *BufferedWriter) Write(data []byte) (int, error) {
+	return w.Body.Write(data)
+}
+
+func (w *BufferedWriter) WriteString(s string) (int, error) {
+	return w.Body.WriteString(s)
+}
+
+// Buffer replaces the context's writer with a BufferedWriter
+func Buffer(c *gin.Context) *BufferedWriter {
+	w := &BufferedWriter{ResponseWriter: c.Writer}
+	c.Writer = w
+	return w
+}
+
+// ResourceETag is a weak ETag for a representation of an object at
+// resourceVersion; variant distinguishes representations such as different
+// ?fields= selections
+func ResourceETag(resourceVersion, variant string) string {
+	if variant == "" {
+		return fmt.Sprintf(`W/"%s"`, resourceVersion)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(variant))
+	return fmt.Sprintf(`W/"%s-%x"`, resourceVersion, h.Sum32())
+}
+
+// ETag makes GET requests conditional. Successful responses carry the ETag
+// the handler set, such as a ResourceETag, or else one hashed from the body,
+// and a request whose If-None-Match matches gets 304 Not Modified without a
+// body, so pollers only download what changed.
+func ETag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+		w := Buffer(c)
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if w.Status() == http.StatusOK {
+			etag := w.Header().Get("ETag")
+			if etag == "" {
+				sum := sha256.Sum256(w.Body.Bytes())
+				etag = fmt.Sprintf(`W/"%s"`, hex.EncodeToString(sum[:12]))
+				w.Header().Set("ETag", etag)
+			}
+			if etagMatches(c.GetHeader("If-None-Match"), etag) {
+				w.Header().Del("Content-Type")
+				w.Header().Del("Content-Length")
+				c.Writer.WriteHeader(http.StatusNotModified)
+				c.Writer.WriteHeaderNow()
+				return
+			}
+		}
+		_, _ = c.Writer.Write(w.Body.Bytes())
+	}
+}
+
+// etagMatches applies If-None-Match's weak comparison
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == want {
+			return true
+		}
+	}
+	return false
+}
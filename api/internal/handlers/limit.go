@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limit bounds how many requests run a route at once. Up to queue further
+// requests wait at most wait for a slot; the rest, and those that time out,
+// get 503 with Retry-After so expensive handlers cannot pile up on the API
+// pod and the Kubernetes API server behind it.
+func Limit(concurrency, queue int, wait time.Duration) gin.HandlerFunc {
+	slots := make(chan struct{}, concurrency)
+	waiting := make(chan struct{}, queue)
+	retryAfter := strconv.Itoa(int(wait.Round(time.Second)/time.Second) + 1)
+
+	busy := func(c *gin.Context) {
+		c.Header("Retry-After", retryAfter)
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Too many concurrent requests for this endpoint, retry later",
+		})
+	}
+
+	return func(c *gin.Context) {
+		select {
+		case slots <- struct{}{}:
+		default:
+			select {
+			case waiting <- struct{}{}:
+			default:
+				busy(c)
+				return
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case slots <- struct{}{}:
+				timer.Stop()
+				<-waiting
+			case <-timer.C:
+				<-waiting
+				busy(c)
+				return
+			case <-c.Request.Context().Done():
+				timer.Stop()
+				<-waiting
+				c.Abort()
+				return
+			}
+		}
+		defer func() { <-slots }()
+		c.Next()
+	}
+}
@@ -0,0 +1,98 @@
+// Package handlers holds the HTTP middleware the API server is assembled
+// from, so embedders can plug in their own authentication and metrics.
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authenticator identifies the caller of a request. An error rejects the
+// request with 401 Unauthorized.
+type Authenticator interface {
+	Authenticate(r *http.Request) (subject string, err error)
+}
+
+// MetricsRecorder observes every request served by the API. Route is the
+// matched route pattern (e.g. /api/v1/gameservers/:namespace/:name), or
+// empty for unmatched paths.
+type MetricsRecorder interface {
+	ObserveRequest(method, route string, status int, duration time.Duration)
+}
+
+// TracingMetricsRecorder is a MetricsRecorder that can link an observation
+// to the request's W3C trace ID, e.g. as a Prometheus exemplar. traceID is
+// empty when the request carried no traceparent header.
+type TracingMetricsRecorder interface {
+	MetricsRecorder
+	ObserveRequestTrace(method, route string, status int, duration time.Duration, traceID string)
+}
+
+// InFlightRecorder is a MetricsRecorder that tracks requests being served
+type InFlightRecorder interface {
+	MetricsRecorder
+	StartRequest() (done func())
+}
+
+// SubjectKey is the gin context key holding the authenticated subject
+const SubjectKey = "gameplane.subject"
+
+// Authenticate rejects requests the authenticator does not accept. Paths in
+// public, such as the health check, are served without authentication.
+func Authenticate(auth Authenticator, public ...string) gin.HandlerFunc {
+	skip := map[string]bool{}
+	for _, path := range public {
+		skip[path] = true
+	}
+	return func(c *gin.Context) {
+		if skip[c.FullPath()] || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+		subject, err := auth.Authenticate(c.Request)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.Set(SubjectKey, subject)
+		c.Next()
+	}
+}
+
+// RecordMetrics reports each request to the recorder once it was served
+func RecordMetrics(recorder MetricsRecorder) gin.HandlerFunc {
+	tracing, _ := recorder.(TracingMetricsRecorder)
+	inFlight, _ := recorder.(InFlightRecorder)
+	return func(c *gin.Context) {
+		start := time.Now()
+		if inFlight != nil {
+			defer inFlight.StartRequest()()
+		}
+		c.Next()
+		if tracing != nil {
+			tracing.ObserveRequestTrace(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start), traceID(c.Request))
+			return
+		}
+		recorder.ObserveRequest(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start))
+	}
+}
+
+// traceID returns the trace ID of a W3C traceparent header
+// (version-traceid-parentid-flags), or "" without one
+func traceID(r *http.Request) string {
+	parts := strings.Split(r.Header.Get("traceparent"), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || strings.Trim(parts[1], "0") == "" {
+		return ""
+	}
+	for _, ch := range parts[1] {
+		if !strings.ContainsRune("0123456789abcdef", ch) {
+			return ""
+		}
+	}
+	return parts[1]
+}
@@ -0,0 +1,56 @@
+package k8s
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Config returns the in-cluster configuration, falling back to KUBECONFIG
+// or ~/.kube/config
+func Config() (*rest.Config, error) {
+	// Try in-cluster config first
+	config, err := rest.InClusterConfig()
+	if err == nil {
+		return config, nil
+	}
+
+	// Fall back to kubeconfig file
+	kubeconfig := os.Getenv("KUBECONFIG")
+	if kubeconfig == "" {
+		kubeconfig = os.ExpandEnv("$HOME/.kube/config")
+	}
+
+	config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build config from kubeconfig: %w", err)
+	}
+
+	return config, nil
+}
+
+// NewClients creates the clients used to talk to a cluster: a
+// controller-runtime client for custom resources and a clientset for core
+// resources. Both retry transient API server failures and count their
+// requests in Connections.
+func NewClients(config *rest.Config) (client.Client, kubernetes.Interface, error) {
+	config = rest.CopyConfig(config)
+	config.Wrap(CountingTransport)
+	config.Wrap(RetryTransport)
+	scheme := runtime.NewScheme()
+	k8sClient, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create kubernetes core client: %w", err)
+	}
+	return k8sClient, kubeClient, nil
+}
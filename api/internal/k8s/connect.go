@@ -0,0 +1,121 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ConnectInfo is the address players use to join a GameServer
+type ConnectInfo struct {
+	Host        string        `json:"host,omitempty"`
+	ServiceType string        `json:"serviceType"`
+	Ports       []ConnectPort `json:"ports"`
+}
+
+// ConnectPort is one player-facing port of a GameServer
+type ConnectPort struct {
+	Name     string `json:"name"`
+	Port     int32  `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+// Address renders connect info as host:port of the first port
+func (info *ConnectInfo) Address() string {
+	if info.Host == "" {
+		return ""
+	}
+	if len(info.Ports) == 0 {
+		return info.Host
+	}
+	return fmt.Sprintf("%s:%d", info.Host, info.Ports[0].Port)
+}
+
+// ResolveConnectInfo resolves the player-facing address of a GameServer
+// from its game service: the load balancer address, a node address for
+// NodePort services, or the cluster IP otherwise
+func ResolveConnectInfo(ctx context.Context, kube kubernetes.Interface, obj *unstructured.Unstructured) (*ConnectInfo, error) {
+	namespace, err := ManagedNamespace(obj)
+	if err != nil {
+		return nil, err
+	}
+	services, err := kube.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("kubelize.io/gameserver=%s,kubelize.io/service-type=game", namespace),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list game services: %w", err)
+	}
+	if len(services.Items) == 0 {
+		return nil, fmt.Errorf("no game service found in namespace %s", namespace)
+	}
+	svc := services.Items[0]
+
+	info := &ConnectInfo{ServiceType: string(svc.Spec.Type), Ports: []ConnectPort{}}
+	for _, port := range svc.Spec.Ports {
+		number := port.Port
+		if svc.Spec.Type == corev1.ServiceTypeNodePort {
+			number = port.NodePort
+		}
+		info.Ports = append(info.Ports, ConnectPort{Name: port.Name, Port: number, Protocol: string(port.Protocol)})
+	}
+
+	switch svc.Spec.Type {
+	case corev1.ServiceTypeLoadBalancer:
+		for _, ingress := range svc.Status.LoadBalancer.Ingress {
+			if ingress.IP != "" {
+				info.Host = ingress.IP
+			} else {
+				info.Host = ingress.Hostname
+			}
+			if info.Host != "" {
+				break
+			}
+		}
+		if info.Host == "" {
+			return nil, fmt.Errorf("load balancer address for %s is not assigned yet", svc.Name)
+		}
+	case corev1.ServiceTypeNodePort:
+		host, err := NodeAddress(ctx, kube, obj)
+		if err != nil {
+			return nil, err
+		}
+		info.Host = host
+	default:
+		info.Host = svc.Spec.ClusterIP
+	}
+	return info, nil
+}
+
+// NodeAddress returns the external (or failing that, internal) address of
+// the node running the game pod
+func NodeAddress(ctx context.Context, kube kubernetes.Interface, obj *unstructured.Unstructured) (string, error) {
+	pods, _, err := GameServerPods(ctx, kube, obj)
+	if err != nil {
+		return "", err
+	}
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning || pod.Spec.NodeName == "" {
+			continue
+		}
+		node, err := kube.CoreV1().Nodes().Get(ctx, pod.Spec.NodeName, metav1.GetOptions{})
+		if err != nil {
+			// Fall back to the host IP when nodes cannot be read
+			if pod.Status.HostIP != "" {
+				return pod.Status.HostIP, nil
+			}
+			return "", fmt.Errorf("failed to get node %s: %w", pod.Spec.NodeName, err)
+		}
+		for _, addrType := range []corev1.NodeAddressType{corev1.NodeExternalIP, corev1.NodeExternalDNS, corev1.NodeInternalIP} {
+			for _, addr := range node.Status.Addresses {
+				if addr.Type == addrType && addr.Address != "" {
+					return addr.Address, nil
+				}
+			}
+		}
+	}
+	return "", fmt.Errorf("no running game pod found")
+}
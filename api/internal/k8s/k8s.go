@@ -0,0 +1,101 @@
+// Package k8s holds the helpers for GameServer claims and their managed
+// namespace shared by the API and the status controller.
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ManagedNamespace returns the namespace Crossplane created for a GameServer's
+// workload: {resourceRef.name}-{gameType}. The same value is used for the
+// kubelize.io/gameserver pod label.
+func ManagedNamespace(obj *unstructured.Unstructured) (string, error) {
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	resourceRefName, _, _ := unstructured.NestedString(obj.Object, "spec", "resourceRef", "name")
+	if resourceRefName == "" {
+		return "", fmt.Errorf("GameServer resourceRef.name not set - server may not be ready yet")
+	}
+	return fmt.Sprintf("%s-%s", resourceRefName, gameType), nil
+}
+
+// GameServerPods lists the game server pods in the managed namespace
+func GameServerPods(ctx context.Context, kube kubernetes.Interface, obj *unstructured.Unstructured) ([]corev1.Pod, string, error) {
+	namespace, err := ManagedNamespace(obj)
+	if err != nil {
+		return nil, "", err
+	}
+
+	podList, err := kube.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("kubelize.io/gameserver=%s", namespace),
+	})
+	if err != nil {
+		return nil, namespace, err
+	}
+	return podList.Items, namespace, nil
+}
+
+// SetCondition adds or updates a condition in an unstructured status
+func SetCondition(obj *unstructured.Unstructured, condition metav1.Condition) error {
+	conditions, err := Conditions(obj)
+	if err != nil {
+		return err
+	}
+	meta.SetStatusCondition(&conditions, condition)
+	return writeConditions(obj, conditions)
+}
+
+// RemoveCondition drops a condition from an unstructured status, if present
+func RemoveCondition(obj *unstructured.Unstructured, conditionType string) error {
+	conditions, err := Conditions(obj)
+	if err != nil {
+		return err
+	}
+	if meta.FindStatusCondition(conditions, conditionType) == nil {
+		return nil
+	}
+	meta.RemoveStatusCondition(&conditions, conditionType)
+	return writeConditions(obj, conditions)
+}
+
+// writeConditions replaces status.conditions
+func writeConditions(obj *unstructured.Unstructured, conditions []metav1.Condition) error {
+	out := make([]interface{}, 0, len(conditions))
+	for i := range conditions {
+		m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&conditions[i])
+		if err != nil {
+			return err
+		}
+		out = append(out, m)
+	}
+	return unstructured.SetNestedSlice(obj.Object, out, "status", "conditions")
+}
+
+// Conditions reads status.conditions from an unstructured GameServer
+func Conditions(obj *unstructured.Unstructured) ([]metav1.Condition, error) {
+	raw, _, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := make([]metav1.Condition, 0, len(raw))
+	for _, item := range raw {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var condition metav1.Condition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, &condition); err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
@@ -0,0 +1,93 @@
+package k8s
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+const (
+	// retryAttempts bounds how often a transient failure is tried in total
+	retryAttempts = 4
+
+	// retryBaseDelay and retryMaxDelay bound the exponential backoff
+	retryBaseDelay = 200 * time.Millisecond
+	retryMaxDelay  = 5 * time.Second
+)
+
+// RetryTransport retries requests to the API server that failed for
+// transient reasons with exponential backoff and full jitter. Reads are
+// retried on connection errors and 502, 503 and 504 responses; any request
+// is retried on 429, which the API server rejects before acting on.
+// Responses carrying Retry-After are returned as they are, since client-go
+// already waits and retries those itself.
+func RetryTransport(next http.RoundTripper) http.RoundTripper {
+	return &retryTransport{next: next}
+}
+
+type retryTransport struct {
+	next http.RoundTripper
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt == retryAttempts || !retryable(req, resp, err) {
+			return resp, err
+		}
+		retry := req
+		if req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			retry = req.Clone(req.Context())
+			retry.Body = body
+		}
+		if resp != nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(backoff(attempt))
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		req = retry
+	}
+}
+
+// retryable reports whether a failed attempt may be repeated
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead
+	if err != nil {
+		return idempotent && !errors.Is(err, req.Context().Err())
+	}
+	if resp.Header.Get("Retry-After") != "" {
+		return false
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// backoff returns a random delay up to the exponential bound of attempt
+func backoff(attempt int) time.Duration {
+	bound := retryBaseDelay << (attempt - 1)
+	if bound > retryMaxDelay {
+		bound = retryMaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(bound)) + 1)
+}
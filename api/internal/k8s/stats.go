@@ -0,0 +1,97 @@
+package k8s
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kubelize/gameplane/api/internal/metrics"
+)
+
+// Connections counts requests to the API servers made through clients from
+// NewClients, for runtime diagnostics
+var Connections ConnectionStats
+
+// ConnectionStats are live counts of API server requests
+type ConnectionStats struct {
+	requests atomic.Int64
+	inFlight atomic.Int64
+	streams  atomic.Int64
+}
+
+// ConnectionSnapshot is a point-in-time copy of ConnectionStats
+type ConnectionSnapshot struct {
+	// Requests is the total since start
+	Requests int64 `json:"requests"`
+	// InFlight are requests whose response has not been read to the end
+	InFlight int64 `json:"inFlight"`
+	// Streams are open watches and followed log streams
+	Streams int64 `json:"streams"`
+}
+
+// Snapshot returns the current counts
+func (s *ConnectionStats) Snapshot() ConnectionSnapshot {
+	return ConnectionSnapshot{
+		Requests: s.requests.Load(),
+		InFlight: s.inFlight.Load(),
+		Streams:  s.streams.Load(),
+	}
+}
+
+// CountingTransport tracks requests in Connections until their response
+// body is closed, and observes their latency up to the response headers as
+// the kubernetes or, for metrics.k8s.io, metrics-server dependency
+func CountingTransport(next http.RoundTripper) http.RoundTripper {
+	return countingTransport{next: next}
+}
+
+type countingTransport struct {
+	next http.RoundTripper
+}
+
+func (t countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	query := req.URL.Query()
+	stream := query.Get("watch") == "true" || query.Get("watch") == "1" || query.Get("follow") == "true"
+
+	Connections.requests.Add(1)
+	Connections.inFlight.Add(1)
+	if stream {
+		Connections.streams.Add(1)
+	}
+	done := func() {
+		Connections.inFlight.Add(-1)
+		if stream {
+			Connections.streams.Add(-1)
+		}
+	}
+
+	dependency := metrics.DependencyKubernetes
+	if strings.HasPrefix(req.URL.Path, "/apis/metrics.k8s.io/") {
+		dependency = metrics.DependencyMetricsServer
+	}
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	failed := err != nil || resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+	metrics.ObserveDependency(dependency, req.Method, start, failed)
+	if err != nil {
+		done()
+		return resp, err
+	}
+	resp.Body = &countedBody{ReadCloser: resp.Body, done: done}
+	return resp, nil
+}
+
+// countedBody calls done once when closed
+type countedBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *countedBody) Close() error {
+	b.once.Do(b.done)
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,178 @@
+// Package metrics exposes the API's own Prometheus metrics: RED metrics per
+// route, latency of the services the API depends on, SLO counters that
+// burn-rate alerts are computed from, and GameServer health scores. Request latencies carry the W3C trace
+// ID as an exemplar when the caller sent a traceparent header, which
+// Prometheus scrapes in the OpenMetrics format.
+package metrics
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gameplane"
+
+// Dependencies the API observes
+const (
+	DependencyKubernetes    = "kubernetes"
+	DependencyMetricsServer = "metrics-server"
+	DependencyWebhook       = "webhook"
+	DependencySMTP          = "smtp"
+	DependencyEventBus      = "event-bus"
+)
+
+// defaultSLOLatency is the latency a request must stay under to count as
+// good for the latency SLO, unless SLO_LATENCY_THRESHOLD says otherwise
+const defaultSLOLatency = time.Second
+
+var (
+	registry = prometheus.NewRegistry()
+
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "API requests by route, method and status code.",
+	}, []string{"route", "method", "code"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "API request latency by route and method.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"route", "method"})
+
+	inFlight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "API requests being served.",
+	})
+
+	sloRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "slo",
+		Name:      "requests_total",
+		Help:      "API requests classified against the availability and latency SLOs.",
+	}, []string{"slo", "route", "outcome"})
+
+	sloLatencyThreshold = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "slo",
+		Name:      "latency_threshold_seconds",
+		Help:      "Latency under which a request counts as good for the latency SLO.",
+	})
+
+	dependencyDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "dependency",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of calls to services the API depends on, by outcome.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"dependency", "operation", "outcome"})
+
+	gameServerHealth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "gameserver",
+		Name:      "health_score",
+		Help:      "Composite health score of a GameServer from 0 to 100, as last reported by the status controller.",
+	}, []string{"cluster", "namespace", "name", "game_type"})
+
+	sloLatency = defaultSLOLatency
+)
+
+func init() {
+	if raw := os.Getenv("SLO_LATENCY_THRESHOLD"); raw != "" {
+		if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+			sloLatency = d
+		}
+	}
+	sloLatencyThreshold.Set(sloLatency.Seconds())
+	registry.MustRegister(
+		requests, requestDuration, inFlight, sloRequests, sloLatencyThreshold, dependencyDuration, gameServerHealth,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the metrics for Prometheus, in OpenMetrics when the scraper
+// accepts it so exemplars are included
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{EnableOpenMetrics: true})
+}
+
+// Recorder records API requests; it implements handlers.MetricsRecorder,
+// handlers.TracingMetricsRecorder and handlers.InFlightRecorder
+type Recorder struct{}
+
+// StartRequest counts a request as in flight until the returned func runs
+func (Recorder) StartRequest() (done func()) {
+	inFlight.Inc()
+	return inFlight.Dec
+}
+
+// ObserveRequest records a served request
+func (r Recorder) ObserveRequest(method, route string, status int, duration time.Duration) {
+	r.ObserveRequestTrace(method, route, status, duration, "")
+}
+
+// ObserveRequestTrace records a served request, linking its latency to
+// traceID when set
+func (Recorder) ObserveRequestTrace(method, route string, status int, duration time.Duration, traceID string) {
+	if route == "" {
+		// Unmatched paths would otherwise give every probe its own series
+		route = "unmatched"
+	}
+	requests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	observer := requestDuration.WithLabelValues(route, method)
+	if exemplar, ok := observer.(prometheus.ExemplarObserver); ok && traceID != "" {
+		exemplar.ObserveWithExemplar(duration.Seconds(), prometheus.Labels{"trace_id": traceID})
+	} else {
+		observer.Observe(duration.Seconds())
+	}
+
+	// Client errors are the caller's doing and count as available
+	availability := "good"
+	if status >= http.StatusInternalServerError {
+		availability = "bad"
+	}
+	sloRequests.WithLabelValues("availability", route, availability).Inc()
+	latency := "good"
+	if duration > sloLatency {
+		latency = "bad"
+	}
+	sloRequests.WithLabelValues("latency", route, latency).Inc()
+}
+
+// ObserveDependency records a call to a dependency that started at start
+func ObserveDependency(dependency, operation string, start time.Time, failed bool) {
+	outcome := "success"
+	if failed {
+		outcome = "error"
+	}
+	dependencyDuration.WithLabelValues(dependency, operation, outcome).Observe(time.Since(start).Seconds())
+}
+
+// HealthScore is the health score of one GameServer
+type HealthScore struct {
+	Namespace string
+	Name      string
+	GameType  string
+	Score     int
+}
+
+// SetGameServerHealth replaces the health scores of a cluster's GameServers,
+// dropping servers that are gone or no longer scored
+func SetGameServerHealth(cluster string, scores []HealthScore) {
+	gameServerHealth.DeletePartialMatch(prometheus.Labels{"cluster": cluster})
+	for _, score := range scores {
+		gameServerHealth.WithLabelValues(cluster, score.Namespace, score.Name, score.GameType).Set(float64(score.Score))
+	}
+}
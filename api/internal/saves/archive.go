@@ -0,0 +1,126 @@
+package saves
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Archive is an uploaded zip or gzipped tar
+type Archive struct {
+	file *os.File
+	zip  *zip.Reader
+}
+
+// Open reads an archive from a file, telling zip from tar.gz by content
+func Open(file *os.File) (*Archive, error) {
+	magic := make([]byte, 4)
+	if _, err := file.ReadAt(magic, 0); err != nil {
+		return nil, fmt.Errorf("archive is empty or unreadable")
+	}
+	switch {
+	case bytes.Equal(magic, []byte("PK\x03\x04")), bytes.Equal(magic, []byte("PK\x05\x06")):
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		reader, err := zip.NewReader(file, info.Size())
+		if err != nil {
+			return nil, fmt.Errorf("invalid zip archive: %w", err)
+		}
+		return &Archive{file: file, zip: reader}, nil
+	case magic[0] == 0x1f && magic[1] == 0x8b:
+		return &Archive{file: file}, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive: upload a .zip or .tar.gz")
+	}
+}
+
+// Files lists the regular files of the archive
+func (a *Archive) Files() ([]string, error) {
+	files := []string{}
+	err := a.walk(func(name string, _ int64, _ time.Time, _ io.Reader) error {
+		files = append(files, name)
+		return nil
+	})
+	return files, err
+}
+
+// Rewrite writes the entries named in targets as a gzipped tar, each under
+// its target path, and returns how many it wrote. Entries are matched by
+// their cleaned name, as reported by Plan.
+func (a *Archive) Rewrite(w io.Writer, targets map[string]string) (int, error) {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	written := 0
+	err := a.walk(func(name string, size int64, modified time.Time, body io.Reader) error {
+		to, ok := targets[cleanPath(name)]
+		if !ok || to == "" {
+			return nil
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: to, Mode: 0o644, Size: size, ModTime: modified, Typeflag: tar.TypeReg}); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(tw, body, size); err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		written++
+		return nil
+	})
+	if err != nil {
+		return written, err
+	}
+	if err := tw.Close(); err != nil {
+		return written, err
+	}
+	return written, gz.Close()
+}
+
+// walk calls fn with every regular file of the archive
+func (a *Archive) walk(fn func(name string, size int64, modified time.Time, body io.Reader) error) error {
+	if a.zip != nil {
+		for _, entry := range a.zip.File {
+			if entry.FileInfo().IsDir() || !entry.Mode().IsRegular() {
+				continue
+			}
+			body, err := entry.Open()
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", entry.Name, err)
+			}
+			err = fn(entry.Name, int64(entry.UncompressedSize64), entry.Modified, body)
+			body.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	gz, err := gzip.NewReader(io.NewSectionReader(a.file, 0, 1<<62))
+	if err != nil {
+		return fmt.Errorf("invalid gzip archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid tar archive: %w", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(header.Name, header.Size, header.ModTime, tr); err != nil {
+			return err
+		}
+	}
+}
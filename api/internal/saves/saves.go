@@ -0,0 +1,392 @@
+// Package saves maps save archives from other hosts onto a game's own data
+// directory layout, so worlds can be imported from wherever they ran
+// before.
+//
+// Three layouts are understood:
+//
+//   - native: an archive of the game's data directory, or of any directory
+//     above it, such as a GamePlane world export
+//   - nitrado: a zip of the server's file browser, where the data directory
+//     sits below a folder named after the game (minecraftbukkit/,
+//     7daystodie/, .config/unity3d/IronGate/Valheim/ and so on)
+//   - gportal: a savegame download holding only the world itself (a
+//     Minecraft level folder, a Palworld save folder, Valheim's .db and
+//     .fwl files, Conan's game.db), which is placed where the game expects
+//     it
+package saves
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/kubelize/gameplane/api/internal/catalog"
+)
+
+// Layouts
+const (
+	LayoutAuto    = "auto"
+	LayoutNative  = "native"
+	LayoutNitrado = "nitrado"
+	LayoutGPortal = "gportal"
+)
+
+// Layouts lists the layouts that can be requested, auto detecting one
+var Layouts = []string{LayoutAuto, LayoutNative, LayoutNitrado, LayoutGPortal}
+
+// Mapping moves an archive entry to a path below the game data directory
+type Mapping struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Skip is an archive entry left out of the import
+type Skip struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// Report describes how an archive maps onto a game
+type Report struct {
+	// Layout is the layout the archive was read as
+	Layout string `json:"layout"`
+	// Root is the folder of the archive that holds the data directory
+	Root    string    `json:"root,omitempty"`
+	Mapped  []Mapping `json:"mapped"`
+	Skipped []Skip    `json:"skipped"`
+}
+
+// Targets maps archive entries to their destination, for Archive.Rewrite
+func (r *Report) Targets() map[string]string {
+	targets := make(map[string]string, len(r.Mapped))
+	for _, m := range r.Mapped {
+		targets[m.From] = m.To
+	}
+	return targets
+}
+
+// nitradoRoots are the folders Nitrado keeps each game's data directory in
+var nitradoRoots = map[string][]string{
+	"sdtd": {"7daystodie", "7dtd"},
+	"ce":   {"conanexiles"},
+	"pw":   {"palworld"},
+	"vh":   {".config/unity3d/IronGate/Valheim", "valheim"},
+	"ln":   {"minecraftbukkit", "minecraftvanilla", "minecraftspigot", "minecraftpaper"},
+}
+
+// Reasons for skipping entries
+const (
+	skipHostFile  = "host metadata"
+	skipNotWorld  = "not world data"
+	skipHostCopy  = "backup copy made by the previous host"
+	skipAmbiguous = "cannot tell where this belongs"
+)
+
+// Plan maps the files of an archive onto the data directory of gameType
+// using layout, which may be LayoutAuto. Entries that cannot be placed are
+// reported as skipped; a plan without mappings means nothing is
+// importable.
+func Plan(gameType, layout string, files []string) (*Report, error) {
+	def, ok := catalog.Lookup(gameType)
+	if !ok || def.Wipe == nil || len(def.Wipe.Paths) == 0 {
+		return nil, fmt.Errorf("game type %s does not support world import", gameType)
+	}
+	if layout == "" {
+		layout = LayoutAuto
+	}
+	switch layout {
+	case LayoutAuto, LayoutNative, LayoutNitrado, LayoutGPortal:
+	default:
+		return nil, fmt.Errorf("unknown layout %q (valid: %s)", layout, strings.Join(Layouts, ", "))
+	}
+
+	report := &Report{Mapped: []Mapping{}, Skipped: []Skip{}}
+	candidates := []string{}
+	for _, file := range files {
+		clean := cleanPath(file)
+		if clean == "" || hostFile(clean) {
+			report.Skipped = append(report.Skipped, Skip{Path: file, Reason: skipHostFile})
+			continue
+		}
+		candidates = append(candidates, clean)
+	}
+	sort.Strings(candidates)
+
+	if layout != LayoutGPortal {
+		if root, mapped, ok := mapFromRoot(def.Wipe.Paths, nitradoRoots[gameType], layout, candidates); ok {
+			report.Layout, report.Root = LayoutNative, root
+			if root != "" && containsFold(nitradoRoots[gameType], lastSegments(root, nitradoRoots[gameType])) {
+				report.Layout = LayoutNitrado
+			}
+			report.fill(candidates, mapped, skipNotWorld)
+			return report, nil
+		}
+	}
+	if layout == LayoutAuto || layout == LayoutGPortal {
+		if adapt, ok := adapters[gameType]; ok {
+			mapped, skipped := adapt(candidates)
+			if len(mapped) > 0 {
+				report.Layout = LayoutGPortal
+				for file, reason := range skipped {
+					report.Skipped = append(report.Skipped, Skip{Path: file, Reason: reason})
+				}
+				report.fill(candidates, mapped, skipNotWorld)
+				return report, nil
+			}
+		}
+	}
+
+	report.Layout = layout
+	report.fill(candidates, nil, skipAmbiguous)
+	return report, nil
+}
+
+// fill records mapped entries and skips the remaining candidates for reason
+func (r *Report) fill(candidates []string, mapped map[string]string, reason string) {
+	skipped := map[string]bool{}
+	for _, skip := range r.Skipped {
+		skipped[skip.Path] = true
+	}
+	for _, file := range candidates {
+		if to, ok := mapped[file]; ok {
+			r.Mapped = append(r.Mapped, Mapping{From: file, To: to})
+		} else if !skipped[file] {
+			r.Skipped = append(r.Skipped, Skip{Path: file, Reason: reason})
+		}
+	}
+	sort.Slice(r.Skipped, func(i, j int) bool { return r.Skipped[i].Path < r.Skipped[j].Path })
+}
+
+// mapFromRoot looks for the archive folder that holds the data directory:
+// the one under which the most files match the game's world paths. The
+// nitrado layout only accepts Nitrado's game folders as that root.
+func mapFromRoot(worldPaths, hostRoots []string, layout string, files []string) (string, map[string]string, bool) {
+	byRoot := map[string]map[string]string{}
+	for _, file := range files {
+		segments := strings.Split(file, "/")
+		for i := 0; i < len(segments); i++ {
+			to, ok := matchWorldPath(worldPaths, segments[i:])
+			if !ok {
+				continue
+			}
+			root := strings.Join(segments[:i], "/")
+			if byRoot[root] == nil {
+				byRoot[root] = map[string]string{}
+			}
+			byRoot[root][file] = to
+		}
+	}
+
+	best, found := "", false
+	for root, mapped := range byRoot {
+		if layout == LayoutNitrado && !containsFold(hostRoots, lastSegments(root, hostRoots)) {
+			continue
+		}
+		if !found || len(mapped) > len(byRoot[best]) || (len(mapped) == len(byRoot[best]) && len(root) < len(best)) {
+			best, found = root, true
+		}
+	}
+	return best, byRoot[best], found
+}
+
+// matchWorldPath matches a path against the catalog's world globs, ignoring
+// case, and returns it with the game's own spelling of literal segments. A
+// glob ending in a slash matches everything below a directory.
+func matchWorldPath(worldPaths []string, segments []string) (string, bool) {
+	for _, glob := range worldPaths {
+		dir := strings.HasSuffix(glob, "/")
+		pattern := strings.Split(strings.TrimSuffix(glob, "/"), "/")
+		if len(segments) < len(pattern) || (dir && len(segments) == len(pattern)) || (!dir && len(segments) != len(pattern)) {
+			continue
+		}
+		matched := make([]string, len(segments))
+		copy(matched, segments)
+		ok := true
+		for i, part := range pattern {
+			if m, err := path.Match(strings.ToLower(part), strings.ToLower(segments[i])); err != nil || !m {
+				ok = false
+				break
+			}
+			if !strings.ContainsAny(part, "*?[") {
+				matched[i] = part
+			}
+		}
+		if ok {
+			return strings.Join(matched, "/"), true
+		}
+	}
+	return "", false
+}
+
+// adapters place world-only archives for each game. They return the
+// entries they map and the ones they deliberately skip.
+var adapters = map[string]func(files []string) (map[string]string, map[string]string){
+	"ln":   adaptMinecraft,
+	"pw":   adaptPalworld,
+	"vh":   adaptValheim,
+	"ce":   adaptConan,
+	"sdtd": adaptSevenDays,
+}
+
+// adaptMinecraft places the folder holding level.dat as world/, and the
+// Bukkit-style <level>_nether and <level>_the_end folders beside it
+func adaptMinecraft(files []string) (map[string]string, map[string]string) {
+	mapped := map[string]string{}
+	level, found := markerDir(files, "level.dat")
+	if !found {
+		return mapped, nil
+	}
+	dims := map[string]string{level: "world"}
+	if level != "" {
+		dims[level+"_nether"] = "world_nether"
+		dims[level+"_the_end"] = "world_the_end"
+	}
+	for _, file := range files {
+		for from, to := range dims {
+			if rest, ok := below(file, from); ok {
+				mapped[file] = to + "/" + rest
+			}
+		}
+	}
+	return mapped, nil
+}
+
+// adaptPalworld places a save folder, the one holding Level.sav and named
+// after the server's save ID, below Pal/Saved/SaveGames/0/
+func adaptPalworld(files []string) (map[string]string, map[string]string) {
+	mapped := map[string]string{}
+	save, found := markerDir(files, "Level.sav")
+	if !found || save == "" {
+		return mapped, nil
+	}
+	target := "Pal/Saved/SaveGames/0/" + path.Base(save)
+	for _, file := range files {
+		if rest, ok := below(file, save); ok {
+			mapped[file] = target + "/" + rest
+		}
+	}
+	return mapped, nil
+}
+
+// adaptValheim places world files from anywhere in worlds_local/, leaving
+// out the .old copies the game rotates itself
+func adaptValheim(files []string) (map[string]string, map[string]string) {
+	mapped, skipped := map[string]string{}, map[string]string{}
+	for _, file := range files {
+		base := path.Base(file)
+		lower := strings.ToLower(base)
+		switch {
+		case strings.HasSuffix(lower, ".old"):
+			skipped[file] = skipHostCopy
+		case strings.HasSuffix(lower, ".db") || strings.HasSuffix(lower, ".fwl"):
+			mapped[file] = "worlds_local/" + base
+		}
+	}
+	return mapped, skipped
+}
+
+// adaptConan places game.db; the numbered backups hosts keep beside it are
+// left out
+func adaptConan(files []string) (map[string]string, map[string]string) {
+	mapped, skipped := map[string]string{}, map[string]string{}
+	for _, file := range files {
+		lower := strings.ToLower(path.Base(file))
+		switch {
+		case lower == "game.db":
+			mapped[file] = "ConanSandbox/Saved/game.db"
+		case strings.HasPrefix(lower, "game_backup_") && strings.HasSuffix(lower, ".db"):
+			skipped[file] = skipHostCopy
+		}
+	}
+	return mapped, skipped
+}
+
+// adaptSevenDays places a save, the <world>/<game> folders above main.ttw,
+// below Saves/
+func adaptSevenDays(files []string) (map[string]string, map[string]string) {
+	mapped := map[string]string{}
+	game, found := markerDir(files, "main.ttw")
+	if !found || strings.Count(game, "/") < 1 {
+		return mapped, nil
+	}
+	parent := path.Dir(game)
+	target := "Saves/" + path.Base(parent) + "/" + path.Base(game)
+	for _, file := range files {
+		if rest, ok := below(file, game); ok {
+			mapped[file] = target + "/" + rest
+		}
+	}
+	return mapped, nil
+}
+
+// markerDir returns the shallowest folder holding a file named marker,
+// ignoring case; "" is the archive root
+func markerDir(files []string, marker string) (string, bool) {
+	best, found := "", false
+	for _, file := range files {
+		if !strings.EqualFold(path.Base(file), marker) {
+			continue
+		}
+		dir := path.Dir(file)
+		if dir == "." {
+			dir = ""
+		}
+		if !found || strings.Count(dir, "/") < strings.Count(best, "/") {
+			best, found = dir, true
+		}
+	}
+	return best, found
+}
+
+// below returns file relative to dir when it lies below it
+func below(file, dir string) (string, bool) {
+	if dir == "" {
+		return file, true
+	}
+	if strings.HasPrefix(file, dir+"/") {
+		return strings.TrimPrefix(file, dir+"/"), true
+	}
+	return "", false
+}
+
+// cleanPath normalizes an archive entry name, returning "" for names that
+// would leave the archive
+func cleanPath(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	clean := path.Clean("/" + name)
+	if clean == "/" || strings.Contains(name, "../") {
+		return ""
+	}
+	return strings.TrimPrefix(clean, "/")
+}
+
+// hostFile reports files added by operating systems and host panels
+func hostFile(file string) bool {
+	base := path.Base(file)
+	return strings.HasPrefix(file, "__MACOSX/") || base == ".DS_Store" || base == "Thumbs.db" || base == "desktop.ini"
+}
+
+// lastSegments returns the end of root with as many segments as the
+// longest of roots, so host folders nested deeper still match
+func lastSegments(root string, roots []string) string {
+	segments := strings.Split(root, "/")
+	for _, candidate := range roots {
+		n := strings.Count(candidate, "/") + 1
+		if n <= len(segments) {
+			if tail := strings.Join(segments[len(segments)-n:], "/"); strings.EqualFold(tail, candidate) {
+				return tail
+			}
+		}
+	}
+	return root
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
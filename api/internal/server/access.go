@@ -0,0 +1,115 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+)
+
+// namespaceScope is the set of namespaces a request may read
+type namespaceScope struct {
+	all        bool
+	namespaces map[string]bool
+	// served hides namespaces GameServers do not belong in, even when all
+	// are readable
+	served func(string) bool
+}
+
+// allows reports whether namespace is readable
+func (n namespaceScope) allows(namespace string) bool {
+	if n.served != nil && !n.served(namespace) {
+		return false
+	}
+	return n.all || n.namespaces[namespace]
+}
+
+// sorted returns the readable namespaces of a limited scope
+func (n namespaceScope) sorted() []string {
+	names := make([]string, 0, len(n.namespaces))
+	for name := range n.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// readableNamespaces asks the configured NamespaceAccess which namespaces
+// the caller may read, adding those their teams grant a role in; without
+// one every namespace is readable. Namespaces GameServers are not served
+// in never are.
+func (s *Server) readableNamespaces(c *gin.Context) (namespaceScope, error) {
+	served := s.servedNamespaces(c.Request.Context())
+	if s.access == nil {
+		return namespaceScope{all: true, served: served}, nil
+	}
+	namespaces, all, err := s.access.ReadableNamespaces(c.Request, c.GetString(handlers.SubjectKey))
+	if err != nil {
+		return namespaceScope{}, err
+	}
+	// Team roles add to what the access allows
+	granted, allGranted := s.teamNamespaces(c)
+	scope := namespaceScope{all: all || allGranted, namespaces: map[string]bool{}, served: served}
+	for _, namespace := range append(namespaces, granted...) {
+		scope.namespaces[namespace] = true
+	}
+	return scope, nil
+}
+
+// guardNamespaces rejects requests for a :namespace the caller may not read
+func (s *Server) guardNamespaces(c *gin.Context) {
+	namespace := c.Param("namespace")
+	if namespace == "" {
+		c.Next()
+		return
+	}
+	scoped, err := s.clusters.clusterServer(c.Query("cluster"))
+	if err != nil {
+		// clustered responds with the unknown cluster
+		c.Next()
+		return
+	}
+	scope, err := scoped.readableNamespaces(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to determine readable namespaces: %v", err),
+		})
+		return
+	}
+	if !scope.allows(namespace) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Not permitted to access namespace %s", namespace),
+		})
+		return
+	}
+	c.Next()
+}
+
+// isAdmin reports whether the caller administers the installation, being
+// a configured admin or holding the admin role in every namespace through
+// a team. Without configured admins every caller does.
+func (s *Server) isAdmin(c *gin.Context) bool {
+	if s.admins == nil || s.admins[c.GetString(handlers.SubjectKey)] {
+		return true
+	}
+	return s.teamRole(c, allNamespaces) == roleAdmin
+}
+
+// adminSubjects builds the admin set from subjects, falling back to
+// ADMIN_SUBJECTS; nil when neither names anyone
+func adminSubjects(subjects []string) map[string]bool {
+	if len(subjects) == 0 {
+		subjects = splitList(os.Getenv("ADMIN_SUBJECTS"))
+	}
+	if len(subjects) == 0 {
+		return nil
+	}
+	admins := map[string]bool{}
+	for _, subject := range subjects {
+		admins[subject] = true
+	}
+	return admins
+}
@@ -0,0 +1,389 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// activityConfigMap holds the activity feed in the registry namespace
+	activityConfigMap = "gameplane-activity"
+
+	// activityKey is the ConfigMap key of the feed, newest entry first
+	activityKey = "activity.json"
+
+	// maxActivityEntries and activityRetention bound the feed, keeping the
+	// ConfigMap well below its size limit
+	maxActivityEntries = 2000
+	activityRetention  = 30 * 24 * time.Hour
+
+	// activityBufferSize bounds entries waiting to be written; newer
+	// entries are dropped while the buffer is full
+	activityBufferSize = 256
+
+	// systemActor is the actor of entries GamePlane records on its own
+	systemActor = "gameplane"
+
+	defaultActivityLimit = 50
+	maxActivityLimit     = 200
+)
+
+// Activity kinds
+const (
+	activityAudit     = "audit"
+	activityLifecycle = "lifecycle"
+	activityBackup    = "backup"
+	activityAlert     = "alert"
+)
+
+// activityKinds maps notification event types to activity kinds
+var activityKinds = map[string]string{
+	"ready":            activityLifecycle,
+	"crash":            activityLifecycle,
+	"expiring":         activityLifecycle,
+	"expired":          activityLifecycle,
+	"backup":           activityBackup,
+	"backup-failed":    activityBackup,
+	"alert":            activityAlert,
+	"alert-resolved":   activityAlert,
+	"remediation":      activityAudit,
+	"update":           activityAudit,
+	"update-available": activityLifecycle,
+}
+
+// activityUnaudited are mutating routes left out of the feed, relative to
+// the API version prefix: read-only POSTs and game traffic
+var activityUnaudited = map[string]bool{
+	"/gameservers/estimate":                      true,
+	"/gameservers/preview":                       true,
+	"/gameservers/:namespace/:name/diff":         true,
+	"/gameservers/:namespace/:name/chat/inbound": true,
+}
+
+// Activity is one entry of the activity feed
+type Activity struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	// Kind is audit, lifecycle, backup or alert
+	Kind string `json:"kind"`
+	// Type is the event type, or the method and route of an audit entry
+	Type      string `json:"type"`
+	Actor     string `json:"actor"`
+	Cluster   string `json:"cluster,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Server    string `json:"server,omitempty"`
+	Summary   string `json:"summary"`
+}
+
+// activityLog queues entries for the feed, shared by all clusters. Every
+// replica writes the entries of the requests it served.
+type activityLog struct {
+	entries chan Activity
+}
+
+// newActivityLog creates an empty activity log
+func newActivityLog() *activityLog {
+	return &activityLog{entries: make(chan Activity, activityBufferSize)}
+}
+
+// recordActivity queues an entry without blocking
+func (s *Server) recordActivity(entry Activity) {
+	if s.activity == nil {
+		return
+	}
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	entry.ID = hex.EncodeToString(id)
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	select {
+	case s.activity.entries <- entry:
+	default:
+		log.Printf("Activity buffer full, dropping %s entry for %s/%s", entry.Type, entry.Namespace, entry.Server)
+	}
+}
+
+// recordEventActivity adds a notification event to the feed
+func (s *Server) recordEventActivity(event notificationEvent) {
+	kind, ok := activityKinds[event.Type]
+	if !ok {
+		return
+	}
+	summary := event.Title
+	if event.Message != "" {
+		summary += ": " + event.Message
+	}
+	s.recordActivity(Activity{
+		Time:      event.Time,
+		Kind:      kind,
+		Type:      event.Type,
+		Actor:     systemActor,
+		Cluster:   s.cluster,
+		Namespace: event.Namespace,
+		Server:    event.Server,
+		Summary:   summary,
+	})
+}
+
+// auditActivity records every successful mutating request as an audit
+// entry of the feed
+func (s *Server) auditActivity(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		route := strings.TrimPrefix(c.FullPath(), prefix)
+		if route == "" || activityUnaudited[route] || c.Writer.Status() >= 400 {
+			return
+		}
+		actor := c.GetString(handlers.SubjectKey)
+		if actor == "" {
+			actor = anonymousSubject
+		}
+		cluster := c.Query("cluster")
+		if cluster == "" {
+			cluster = s.clusters.local
+		}
+		summary := c.Request.Method + " " + strings.TrimPrefix(c.Request.URL.Path, prefix)
+		s.recordActivity(Activity{
+			Kind:      activityAudit,
+			Type:      c.Request.Method + " " + route,
+			Actor:     actor,
+			Cluster:   cluster,
+			Namespace: c.Param("namespace"),
+			Server:    c.Param("name"),
+			Summary:   summary,
+		})
+	}
+}
+
+// runActivityLog writes queued entries until the context ends, batching
+// entries that arrive together into one write
+func (s *Server) runActivityLog(ctx context.Context) {
+	for {
+		var batch []Activity
+		select {
+		case <-ctx.Done():
+			return
+		case entry := <-s.activity.entries:
+			batch = append(batch, entry)
+		}
+	drain:
+		for len(batch) < activityBufferSize {
+			select {
+			case entry := <-s.activity.entries:
+				batch = append(batch, entry)
+			default:
+				break drain
+			}
+		}
+		if err := s.appendActivity(ctx, batch); err != nil {
+			log.Printf("Failed to record %d activity entries: %v", len(batch), err)
+		}
+	}
+}
+
+// appendActivity adds entries to the feed, retrying on concurrent writes
+// by other replicas
+func (s *Server) appendActivity(ctx context.Context, batch []Activity) error {
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		var feed []Activity
+		var cm *corev1.ConfigMap
+		if feed, cm, err = s.loadActivity(ctx); err != nil {
+			return err
+		}
+		for _, entry := range batch {
+			feed = insertActivity(feed, entry)
+		}
+		if err = s.saveActivity(ctx, cm, feed); !apierrors.IsConflict(err) && !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// insertActivity adds an entry to a feed kept newest first
+func insertActivity(feed []Activity, entry Activity) []Activity {
+	i := 0
+	for i < len(feed) && feed[i].Time.After(entry.Time) {
+		i++
+	}
+	feed = append(feed, Activity{})
+	copy(feed[i+1:], feed[i:])
+	feed[i] = entry
+	return feed
+}
+
+// loadActivity reads the feed and the ConfigMap holding it, which is nil
+// when nothing was recorded yet
+func (s *Server) loadActivity(ctx context.Context) ([]Activity, *corev1.ConfigMap, error) {
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return nil, nil, err
+	}
+	cm, err := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace).Get(ctx, activityConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return []Activity{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read activity: %w", err)
+	}
+	feed := []Activity{}
+	if raw := cm.Data[activityKey]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &feed); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse activity: %w", err)
+		}
+	}
+	return feed, cm, nil
+}
+
+// saveActivity writes the feed back, dropping entries past the retention,
+// failing on a concurrent change
+func (s *Server) saveActivity(ctx context.Context, cm *corev1.ConfigMap, feed []Activity) error {
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-activityRetention)
+	for len(feed) > 0 && feed[len(feed)-1].Time.Before(cutoff) {
+		feed = feed[:len(feed)-1]
+	}
+	if len(feed) > maxActivityEntries {
+		feed = feed[:maxActivityEntries]
+	}
+	raw, err := json.Marshal(feed)
+	if err != nil {
+		return err
+	}
+	configMaps := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace)
+	if cm == nil {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      activityConfigMap,
+				Namespace: s.clusters.namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "gameplane-api"},
+			},
+			Data: map[string]string{activityKey: string(raw)},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[activityKey] = string(raw)
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// listActivity returns the activity feed, newest first, filtered by
+// ?namespace=, ?kind= and ?actor= (me for the caller). Pages hold ?limit=
+// entries (default 50); ?before= takes the next cursor of the previous
+// page. Callers only see servers in namespaces they can read, and entries
+// about no server only when they are admins or the actor.
+func (s *Server) listActivity(c *gin.Context) {
+	s.respondActivity(c, c.Query("namespace"), nil)
+}
+
+// getGameServerActivity returns the activity feed of one GameServer of
+// the selected cluster, including what happened under the names it had
+// before being renamed
+func (s *Server) getGameServerActivity(c *gin.Context) {
+	names := []string{c.Param("name")}
+	if obj, err := s.getGameServerObject(context.TODO(), c.Param("namespace"), c.Param("name")); err == nil {
+		names = append(names, splitList(obj.GetAnnotations()[renamedFromAnnotation])...)
+	}
+	s.respondActivity(c, c.Param("namespace"), names)
+}
+
+// respondActivity serves a page of the feed, optionally of one namespace
+// or the servers of the given names
+func (s *Server) respondActivity(c *gin.Context, namespace string, names []string) {
+	limit := defaultActivityLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > maxActivityLimit {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("limit must be between 1 and %d", maxActivityLimit),
+			})
+			return
+		}
+		limit = parsed
+	}
+	kind := c.Query("kind")
+	if kind != "" && kind != activityAudit && kind != activityLifecycle && kind != activityBackup && kind != activityAlert {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Unsupported kind %q (valid: audit, lifecycle, backup, alert)", kind),
+		})
+		return
+	}
+	actor := c.Query("actor")
+	if actor == "me" {
+		actor = userPrefsSubject(c)
+	}
+	scope, err := s.readableNamespaces(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to determine readable namespaces: %v", err),
+		})
+		return
+	}
+	feed, _, err := s.loadActivity(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	admin, caller := s.isAdmin(c), userPrefsSubject(c)
+	before := c.Query("before")
+	items := []Activity{}
+	next := ""
+	for _, entry := range feed {
+		if before != "" {
+			if entry.ID == before {
+				before = ""
+			}
+			continue
+		}
+		if (namespace != "" && entry.Namespace != namespace) || (len(names) > 0 && !containsString(names, entry.Server)) {
+			continue
+		}
+		if (kind != "" && entry.Kind != kind) || (actor != "" && entry.Actor != actor) {
+			continue
+		}
+		if len(names) > 0 && entry.Cluster != s.cluster {
+			continue
+		}
+		if (entry.Namespace == "" && !admin && entry.Actor != caller) || (entry.Namespace != "" && !scope.allows(entry.Namespace)) {
+			continue
+		}
+		if len(items) == limit {
+			next = items[len(items)-1].ID
+			break
+		}
+		items = append(items, entry)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"next":  next,
+	})
+}
@@ -0,0 +1,273 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// AdminEntry is a single in-game admin or operator
+type AdminEntry struct {
+	// ID is the player's platform ID (e.g. a SteamID64)
+	ID string `json:"id" binding:"required"`
+	// Platform is the ID's platform, for games that support several
+	Platform string `json:"platform,omitempty"`
+	// Name is a free-form hint about who the player is
+	Name string `json:"name,omitempty"`
+	// Level is the permission level, where 0 is the most privileged
+	Level int `json:"level"`
+}
+
+// serverAdminUser is a <user> element in 7 Days to Die's serveradmin.xml
+type serverAdminUser struct {
+	Platform        string `xml:"platform,attr,omitempty"`
+	UserID          string `xml:"userid,attr"`
+	Name            string `xml:"name,attr,omitempty"`
+	PermissionLevel int    `xml:"permission_level,attr"`
+}
+
+// getGameServerAdmins returns the in-game admin list from the game's own file
+func (s *Server) getGameServerAdmins(c *gin.Context) {
+	obj, def, ok := s.loadGameServerAdminList(c)
+	if !ok {
+		return
+	}
+
+	content, exists, err := s.readOptionalGameFile(context.TODO(), obj, def.AdminList.Path)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to read admin list: %v", err),
+		})
+		return
+	}
+
+	admins := []AdminEntry{}
+	if exists {
+		admins, err = parseAdminList(def.AdminList, content)
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to parse admin list: %v", err),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"admins": admins,
+		"file":   def.AdminList.Path,
+		"format": def.AdminList.Format,
+		"exists": exists,
+	})
+}
+
+// putGameServerAdmins replaces the in-game admin list
+func (s *Server) putGameServerAdmins(c *gin.Context) {
+	var req struct {
+		Admins []AdminEntry `json:"admins"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+
+	obj, def, ok := s.loadGameServerAdminList(c)
+	if !ok {
+		return
+	}
+
+	seen := map[string]bool{}
+	for _, admin := range req.Admins {
+		if err := validateAdminEntry(def.AdminList, admin); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		key := admin.Platform + "/" + admin.ID
+		if seen[key] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Duplicate admin entry for %s", admin.ID),
+			})
+			return
+		}
+		seen[key] = true
+	}
+
+	// Read the existing file so unrelated sections (whitelist, command
+	// permissions) survive the rewrite
+	existing, _, err := s.readOptionalGameFile(context.TODO(), obj, def.AdminList.Path)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to read admin list: %v", err),
+		})
+		return
+	}
+
+	content, err := renderAdminList(def.AdminList, existing, req.Admins)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to render admin list: %v", err),
+		})
+		return
+	}
+
+	if err := s.writeGameFile(context.TODO(), obj, def.AdminList.Path, content); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to write admin list: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"admins":          req.Admins,
+		"file":            def.AdminList.Path,
+		"format":          def.AdminList.Format,
+		"restartRequired": !def.AdminList.LiveReload,
+	})
+}
+
+// loadGameServerAdminList fetches the GameServer and its game's admin list
+// definition, writing the error response itself when either is missing
+func (s *Server) loadGameServerAdminList(c *gin.Context) (*unstructured.Unstructured, GameDefinition, bool) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return nil, GameDefinition{}, false
+	}
+
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, found := lookupGame(gameType)
+	if !found || def.AdminList == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("Game type %s does not support an admin list", gameType),
+		})
+		return nil, GameDefinition{}, false
+	}
+	return obj, def, true
+}
+
+// validateAdminEntry checks an entry can be written to the game's format
+func validateAdminEntry(list *AdminList, admin AdminEntry) error {
+	if admin.ID == "" || strings.ContainsAny(admin.ID, " \t\r\n\"'<>/") {
+		return fmt.Errorf("invalid admin id %q", admin.ID)
+	}
+	if strings.ContainsAny(admin.Name, "\r\n") {
+		return fmt.Errorf("admin name for %s must be a single line", admin.ID)
+	}
+	if admin.Level < 0 || (list.MaxLevel > 0 && admin.Level > list.MaxLevel) {
+		return fmt.Errorf("admin level for %s must be between 0 and %d", admin.ID, list.MaxLevel)
+	}
+	if list.MaxLevel == 0 && admin.Level != 0 {
+		return fmt.Errorf("this game does not support admin permission levels")
+	}
+	return nil
+}
+
+// parseAdminList reads admin entries from the game's file format
+func parseAdminList(list *AdminList, content []byte) ([]AdminEntry, error) {
+	admins := []AdminEntry{}
+	switch list.Format {
+	case "serveradmin-xml":
+		var doc struct {
+			Users struct {
+				Users []serverAdminUser `xml:"user"`
+			} `xml:"users"`
+		}
+		if err := xml.Unmarshal(content, &doc); err != nil {
+			return nil, err
+		}
+		for _, u := range doc.Users.Users {
+			admins = append(admins, AdminEntry{
+				ID:       u.UserID,
+				Platform: u.Platform,
+				Name:     u.Name,
+				Level:    u.PermissionLevel,
+			})
+		}
+	case "adminlist-txt":
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "//") {
+				continue
+			}
+			admins = append(admins, AdminEntry{ID: line})
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported admin list format %q", list.Format)
+	}
+	return admins, nil
+}
+
+// renderAdminList serializes admin entries, keeping the rest of an existing
+// serveradmin.xml intact
+func renderAdminList(list *AdminList, existing []byte, admins []AdminEntry) ([]byte, error) {
+	switch list.Format {
+	case "serveradmin-xml":
+		var users bytes.Buffer
+		users.WriteString("<users>\n")
+		for _, admin := range admins {
+			platform := admin.Platform
+			if platform == "" {
+				platform = "Steam"
+			}
+			raw, err := xml.Marshal(struct {
+				XMLName xml.Name `xml:"user"`
+				serverAdminUser
+			}{serverAdminUser: serverAdminUser{
+				Platform:        platform,
+				UserID:          admin.ID,
+				Name:            admin.Name,
+				PermissionLevel: admin.Level,
+			}})
+			if err != nil {
+				return nil, err
+			}
+			users.WriteString("    ")
+			users.Write(raw)
+			users.WriteString("\n")
+		}
+		users.WriteString("  </users>")
+		return replaceAdminToolsSection(existing, "users", users.String()), nil
+	case "adminlist-txt":
+		var buf bytes.Buffer
+		buf.WriteString("// List admin players ID  ONE per line\n")
+		for _, admin := range admins {
+			buf.WriteString(admin.ID + "\n")
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported admin list format %q", list.Format)
+}
+
+// replaceAdminToolsSection swaps one top-level element of a serveradmin.xml
+// for element, adding it (or the whole file) when missing
+func replaceAdminToolsSection(existing []byte, section, element string) []byte {
+	doc := string(existing)
+	for _, empty := range []string{"<" + section + " />", "<" + section + "/>"} {
+		if strings.Contains(doc, empty) {
+			return []byte(strings.Replace(doc, empty, element, 1))
+		}
+	}
+	start := strings.Index(doc, "<"+section+">")
+	end := strings.Index(doc, "</"+section+">")
+	switch {
+	case start >= 0 && end > start:
+		return []byte(doc[:start] + element + doc[end+len("</"+section+">"):])
+	case strings.Contains(doc, "<adminTools>"):
+		return []byte(strings.Replace(doc, "<adminTools>", "<adminTools>\n  "+element, 1))
+	default:
+		return []byte("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<adminTools>\n  " + element + "\n</adminTools>\n")
+	}
+}
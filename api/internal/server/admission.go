@@ -0,0 +1,37 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kubelize/gameplane/api/internal/admission"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// namespaceQuota loads the GameServer quota of a namespace together with the
+// GameServers already in it. The admission webhook enforces the same quota
+// for claims applied outside the API.
+func (s *Server) namespaceQuota(ctx context.Context, namespace string) (admission.Quota, []unstructured.Unstructured, error) {
+	ns, err := s.kubeClient.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		return admission.Quota{}, nil, fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+	}
+	quota, err := admission.QuotaFromAnnotations(ns.Annotations)
+	if err != nil || !quota.Limited() {
+		return quota, nil, err
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return quota, nil, fmt.Errorf("failed to list GameServers: %w", err)
+	}
+	return quota, list.Items, nil
+}
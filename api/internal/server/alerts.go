@@ -0,0 +1,398 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// alertsAnnotation holds a GameServer's AlertConfig as JSON
+	alertsAnnotation = "gameplane.kubelize.io/alerts"
+
+	// alertEvaluationInterval is how often alert rules are evaluated
+	alertEvaluationInterval = 30 * time.Second
+)
+
+// AlertConfig is the set of alert rules of a GameServer and where firing
+// alerts are delivered
+type AlertConfig struct {
+	Rules    []AlertRule     `json:"rules"`
+	Webhooks []webhookTarget `json:"webhooks"`
+}
+
+// AlertRule fires when a metric compares true against a threshold for a
+// duration. CPU and memory are percentages of the configured resources.
+type AlertRule struct {
+	Name      string  `json:"name"`
+	Enabled   bool    `json:"enabled"`
+	Metric    string  `json:"metric"`   // players, cpu or memory
+	Operator  string  `json:"operator"` // >, >=, <, <= or ==
+	Threshold float64 `json:"threshold"`
+	// For is how long the condition must hold before firing, e.g. "10m"
+	For string `json:"for,omitempty"`
+}
+
+// AlertStatus is the evaluation state of one rule
+type AlertStatus struct {
+	Rule         string     `json:"rule"`
+	Firing       bool       `json:"firing"`
+	PendingSince *time.Time `json:"pendingSince,omitempty"`
+	FiringSince  *time.Time `json:"firingSince,omitempty"`
+}
+
+// alertEvaluator tracks when each rule's condition started holding and which
+// rules are firing. The state is in memory, so alerts still firing after an
+// API restart are delivered again.
+type alertEvaluator struct {
+	mu      sync.Mutex
+	pending map[string]time.Time
+	firing  map[string]time.Time
+}
+
+// validate checks a rule before it is stored
+func (r AlertRule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("alert rules need a name")
+	}
+	switch r.Metric {
+	case "players", "cpu", "memory":
+	default:
+		return fmt.Errorf("alert %q: unsupported metric %q (valid: players, cpu, memory)", r.Name, r.Metric)
+	}
+	switch r.Operator {
+	case ">", ">=", "<", "<=", "==":
+	default:
+		return fmt.Errorf("alert %q: unsupported operator %q (valid: >, >=, <, <=, ==)", r.Name, r.Operator)
+	}
+	if r.For != "" {
+		if d, err := time.ParseDuration(r.For); err != nil || d < 0 {
+			return fmt.Errorf("alert %q: invalid duration %q", r.Name, r.For)
+		}
+	}
+	return nil
+}
+
+// holdFor returns how long the condition must hold
+func (r AlertRule) holdFor() time.Duration {
+	d, _ := time.ParseDuration(r.For)
+	return d
+}
+
+// matches compares a value against the rule's threshold
+func (r AlertRule) matches(value float64) bool {
+	switch r.Operator {
+	case ">":
+		return value > r.Threshold
+	case ">=":
+		return value >= r.Threshold
+	case "<":
+		return value < r.Threshold
+	case "<=":
+		return value <= r.Threshold
+	case "==":
+		return value == r.Threshold
+	}
+	return false
+}
+
+// getGameServerAlerts returns the alert rules and their current state
+func (s *Server) getGameServerAlerts(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	config, err := alertSettings(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"rules":    config.Rules,
+		"webhooks": config.Webhooks,
+		"status":   s.alertStatuses(obj, config),
+	})
+}
+
+// putGameServerAlerts replaces the alert rules of a GameServer
+func (s *Server) putGameServerAlerts(c *gin.Context) {
+	var req AlertConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	names := map[string]bool{}
+	for _, rule := range req.Rules {
+		if err := rule.validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if names[rule.Name] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Duplicate alert rule %q", rule.Name),
+			})
+			return
+		}
+		names[rule.Name] = true
+	}
+	for _, target := range req.Webhooks {
+		if err := validateWebhookTarget(target); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+	if req.Rules == nil {
+		req.Rules = []AlertRule{}
+	}
+	if req.Webhooks == nil {
+		req.Webhooks = []webhookTarget{}
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	raw, err := json.Marshal(req)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[alertsAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update alert rules: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rules":    req.Rules,
+		"webhooks": req.Webhooks,
+		"status":   s.alertStatuses(obj, req),
+	})
+}
+
+// alertStatuses reports the evaluation state of each rule
+func (s *Server) alertStatuses(obj *unstructured.Unstructured, config AlertConfig) []AlertStatus {
+	s.alerts.mu.Lock()
+	defer s.alerts.mu.Unlock()
+	statuses := make([]AlertStatus, 0, len(config.Rules))
+	for _, rule := range config.Rules {
+		key := alertKey(obj, rule)
+		status := AlertStatus{Rule: rule.Name}
+		if since, ok := s.alerts.pending[key]; ok {
+			status.PendingSince = &since
+		}
+		if since, ok := s.alerts.firing[key]; ok {
+			status.Firing = true
+			status.FiringSince = &since
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// evaluateAlerts checks the alert rules of every GameServer, notifying
+// webhooks when an alert fires or resolves
+func (s *Server) evaluateAlerts(ctx context.Context) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+
+	s.alerts.mu.Lock()
+	if s.alerts.pending == nil {
+		s.alerts.pending = map[string]time.Time{}
+		s.alerts.firing = map[string]time.Time{}
+	}
+	s.alerts.mu.Unlock()
+
+	active := map[string]bool{}
+	now := time.Now().UTC()
+	for i := range list.Items {
+		obj := &list.Items[i]
+		config, err := alertSettings(obj)
+		if err != nil {
+			log.Printf("Skipping alerts of GameServer %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+			continue
+		}
+		values := map[string]float64{}
+		for _, rule := range config.Rules {
+			if !rule.Enabled {
+				continue
+			}
+			value, ok := values[rule.Metric]
+			if !ok {
+				if value, ok = s.alertMetric(ctx, obj, rule.Metric); !ok {
+					continue
+				}
+				values[rule.Metric] = value
+			}
+			key := alertKey(obj, rule)
+			active[key] = true
+			s.updateAlert(ctx, obj, config, rule, key, value, now)
+		}
+	}
+
+	s.alerts.mu.Lock()
+	for key := range s.alerts.pending {
+		if !active[key] {
+			delete(s.alerts.pending, key)
+		}
+	}
+	for key := range s.alerts.firing {
+		if !active[key] {
+			delete(s.alerts.firing, key)
+		}
+	}
+	s.alerts.mu.Unlock()
+	return nil
+}
+
+// updateAlert advances one rule's state and delivers transitions
+func (s *Server) updateAlert(ctx context.Context, obj *unstructured.Unstructured, config AlertConfig, rule AlertRule, key string, value float64, now time.Time) {
+	s.alerts.mu.Lock()
+	_, firing := s.alerts.firing[key]
+	var event *notificationEvent
+	if rule.matches(value) {
+		since, pending := s.alerts.pending[key]
+		if !pending {
+			since = now
+			s.alerts.pending[key] = since
+		}
+		if !firing && now.Sub(since) >= rule.holdFor() {
+			s.alerts.firing[key] = now
+			event = alertEvent(obj, rule, value, "alert", fmt.Sprintf("Alert %s firing", rule.Name))
+		}
+	} else {
+		delete(s.alerts.pending, key)
+		if firing {
+			delete(s.alerts.firing, key)
+			event = alertEvent(obj, rule, value, "alert-resolved", fmt.Sprintf("Alert %s resolved", rule.Name))
+		}
+	}
+	s.alerts.mu.Unlock()
+
+	if event == nil {
+		return
+	}
+	for _, target := range config.Webhooks {
+		if err := postWebhook(ctx, target, *event); err != nil {
+			log.Printf("Failed to deliver alert %s for GameServer %s/%s: %v", rule.Name, obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	s.notifySubscribers(ctx, *event)
+	kind := eventAlertFiring
+	if event.Type == "alert-resolved" {
+		kind = eventAlertResolved
+	}
+	s.publishEvent(kind, obj.GetNamespace(), obj.GetName(), map[string]interface{}{
+		"rule":  rule.Name,
+		"value": value,
+	})
+}
+
+// alertMetric reads a metric for alert evaluation. CPU and memory are
+// unavailable while no game pod is running.
+func (s *Server) alertMetric(ctx context.Context, obj *unstructured.Unstructured, metric string) (float64, bool) {
+	if metric == "players" {
+		return float64(gameServerPlayers(obj)), true
+	}
+
+	pods, namespace, err := s.findGameServerPods(ctx, obj)
+	if err != nil {
+		return 0, false
+	}
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		cpu, memory, err := s.getPodMetrics(pod.Name, namespace)
+		if err != nil {
+			return 0, false
+		}
+		gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+		resources := effectiveResources(gameType, gameServerResources(obj))
+		used, limit := quantityMilli(cpu), quantityMilli(resources.CPU)
+		if metric == "memory" {
+			used, limit = quantityMilli(memory), quantityMilli(resources.Memory)
+		}
+		if limit <= 0 {
+			return 0, false
+		}
+		return float64(used) / float64(limit) * 100, true
+	}
+	return 0, false
+}
+
+// alertEvent builds the notification for an alert transition
+func alertEvent(obj *unstructured.Unstructured, rule AlertRule, value float64, kind, title string) *notificationEvent {
+	unit := ""
+	if rule.Metric != "players" {
+		unit = "%"
+	}
+	condition := fmt.Sprintf("%s %s %s%s", rule.Metric, rule.Operator, strconv.FormatFloat(rule.Threshold, 'f', -1, 64), unit)
+	if rule.For != "" {
+		condition += " for " + rule.For
+	}
+	return &notificationEvent{
+		Type:      kind,
+		Namespace: obj.GetNamespace(),
+		Server:    obj.GetName(),
+		Title:     title,
+		Message:   fmt.Sprintf("%s on %s", condition, obj.GetName()),
+		Fields: map[string]string{
+			"Rule":    rule.Name,
+			"Current": strconv.FormatFloat(value, 'f', 1, 64) + unit,
+		},
+	}
+}
+
+// alertKey identifies a rule of a GameServer in the evaluator state
+func alertKey(obj *unstructured.Unstructured, rule AlertRule) string {
+	return obj.GetNamespace() + "/" + obj.GetName() + "/" + rule.Name
+}
+
+// alertSettings reads the alerts annotation
+func alertSettings(obj *unstructured.Unstructured) (AlertConfig, error) {
+	config := AlertConfig{Rules: []AlertRule{}, Webhooks: []webhookTarget{}}
+	raw, ok := obj.GetAnnotations()[alertsAnnotation]
+	if !ok {
+		return config, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &config); err != nil {
+		return AlertConfig{}, fmt.Errorf("invalid %s annotation: %w", alertsAnnotation, err)
+	}
+	return config, nil
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// allocatedLabel marks fleet members handed out by allocate
+	allocatedLabel = "gameplane.kubelize.io/allocated"
+
+	// allocationAnnotation records when and for whom a member was allocated
+	allocationAnnotation = "gameplane.kubelize.io/allocation"
+)
+
+// AllocationRequest narrows which fleet member may be allocated
+type AllocationRequest struct {
+	// Selector matches labels on the member GameServers
+	Selector map[string]string `json:"selector,omitempty"`
+	// Reference identifies the session or lobby the server is allocated to
+	Reference string `json:"reference,omitempty"`
+}
+
+// Allocation is the result of allocating a fleet member
+type Allocation struct {
+	GameServer  string       `json:"gameServer"`
+	Namespace   string       `json:"namespace"`
+	Reference   string       `json:"reference,omitempty"`
+	AllocatedAt time.Time    `json:"allocatedAt"`
+	Players     int          `json:"players"`
+	Capacity    int          `json:"capacity,omitempty"`
+	Connect     *ConnectInfo `json:"connect"`
+}
+
+// allocateFleetServer picks a Ready, unallocated, least-loaded member of a
+// fleet, marks it allocated and returns how to connect to it
+func (s *Server) allocateFleetServer(c *gin.Context) {
+	var req AllocationRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid request body: %v", err),
+			})
+			return
+		}
+	}
+
+	obj, ok := s.loadFleet(c)
+	if !ok {
+		return
+	}
+	fleet, err := fleetFromUnstructured(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to convert Fleet: %v", err),
+		})
+		return
+	}
+	members, err := s.fleetMembers(context.TODO(), fleet)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list fleet members: %v", err),
+		})
+		return
+	}
+
+	// Fall through to the next candidate when one cannot be allocated, e.g.
+	// because another request changed it first or it has no address yet
+	var lastErr error
+	selector := labels.SelectorFromSet(req.Selector)
+	for _, member := range allocationCandidates(members, selector) {
+		allocation, err := s.allocateMember(context.TODO(), member, req.Reference)
+		if err != nil {
+			if !apierrors.IsConflict(err) {
+				lastErr = fmt.Errorf("GameServer %s: %w", member.GetName(), err)
+			}
+			continue
+		}
+		c.JSON(http.StatusOK, allocation)
+		return
+	}
+
+	if lastErr != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": fmt.Sprintf("Failed to allocate from Fleet %s: %v", fleet.Name, lastErr),
+		})
+		return
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error": fmt.Sprintf("No Ready, unallocated GameServer available in Fleet %s", fleet.Name),
+	})
+}
+
+// releaseFleetServer returns an allocated member to the pool
+func (s *Server) releaseFleetServer(c *gin.Context) {
+	fleetName := c.Param("name")
+	obj, err := s.getGameServerObject(context.TODO(), c.Param("namespace"), c.Param("server"))
+	if err != nil || obj.GetLabels()[fleetLabel] != fleetName {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("GameServer %s not found in Fleet %s", c.Param("server"), fleetName),
+		})
+		return
+	}
+
+	memberLabels := obj.GetLabels()
+	delete(memberLabels, allocatedLabel)
+	obj.SetLabels(memberLabels)
+	annotations := obj.GetAnnotations()
+	delete(annotations, allocationAnnotation)
+	obj.SetAnnotations(annotations)
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to release GameServer: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("GameServer %s released", obj.GetName()),
+	})
+}
+
+// allocateMember marks a member allocated. The update carries the member's
+// resourceVersion, so concurrent allocations of the same server conflict
+// instead of both succeeding.
+func (s *Server) allocateMember(ctx context.Context, member *unstructured.Unstructured, reference string) (*Allocation, error) {
+	connect, err := s.gameServerConnectInfo(ctx, member)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	memberLabels := member.GetLabels()
+	if memberLabels == nil {
+		memberLabels = map[string]string{}
+	}
+	memberLabels[allocatedLabel] = "true"
+	member.SetLabels(memberLabels)
+	annotations := member.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	record, err := json.Marshal(struct {
+		AllocatedAt time.Time `json:"allocatedAt"`
+		Reference   string    `json:"reference,omitempty"`
+	}{now, reference})
+	if err != nil {
+		return nil, err
+	}
+	annotations[allocationAnnotation] = string(record)
+	member.SetAnnotations(annotations)
+	if err := s.k8sClient.Update(ctx, member); err != nil {
+		return nil, err
+	}
+
+	return &Allocation{
+		GameServer:  member.GetName(),
+		Namespace:   member.GetNamespace(),
+		Reference:   reference,
+		AllocatedAt: now,
+		Players:     gameServerPlayers(member),
+		Capacity:    gameServerCapacity(member),
+		Connect:     connect,
+	}, nil
+}
+
+// allocationCandidates returns Ready, unallocated members matching the
+// selector, least loaded first
+func allocationCandidates(members []unstructured.Unstructured, selector labels.Selector) []*unstructured.Unstructured {
+	candidates := []*unstructured.Unstructured{}
+	for i := range members {
+		member := &members[i]
+		if gameServerAllocated(member) || !gameServerReady(member) {
+			continue
+		}
+		if !selector.Matches(labels.Set(member.GetLabels())) {
+			continue
+		}
+		candidates = append(candidates, member)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return gameServerLoad(candidates[i]) < gameServerLoad(candidates[j])
+	})
+	return candidates
+}
+
+// gameServerAllocated reports whether a member is allocated
+func gameServerAllocated(obj *unstructured.Unstructured) bool {
+	return obj.GetLabels()[allocatedLabel] == "true"
+}
+
+// gameServerLoad is a member's share of occupied slots, or its player count
+// when the capacity is unknown
+func gameServerLoad(obj *unstructured.Unstructured) float64 {
+	players := float64(gameServerPlayers(obj))
+	if capacity := gameServerCapacity(obj); capacity > 0 {
+		return players / float64(capacity)
+	}
+	return players
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// defaultBufferPercent keeps a fifth of the fleet's slots free
+	defaultBufferPercent = 20
+
+	// defaultScaleUpCooldown and defaultScaleDownCooldown space out
+	// consecutive autoscaler decisions
+	defaultScaleUpCooldown   = time.Minute
+	defaultScaleDownCooldown = 10 * time.Minute
+)
+
+// FleetAutoscaler sizes a fleet from its player occupancy
+type FleetAutoscaler struct {
+	Enabled     bool `json:"enabled"`
+	MinReplicas int  `json:"minReplicas,omitempty"`
+	MaxReplicas int  `json:"maxReplicas"`
+	// BufferPercent is the share of player slots to keep free
+	BufferPercent            *int `json:"bufferPercent,omitempty"`
+	ScaleUpCooldownSeconds   *int `json:"scaleUpCooldownSeconds,omitempty"`
+	ScaleDownCooldownSeconds *int `json:"scaleDownCooldownSeconds,omitempty"`
+}
+
+// FleetAutoscalerStatus records the autoscaler's last decision
+type FleetAutoscalerStatus struct {
+	DesiredReplicas int          `json:"desiredReplicas"`
+	LastScaleTime   *metav1.Time `json:"lastScaleTime,omitempty"`
+	Message         string       `json:"message,omitempty"`
+}
+
+// validate checks the autoscaler bounds
+func (a *FleetAutoscaler) validate() error {
+	if a.MinReplicas < 0 {
+		return fmt.Errorf("autoscaler.minReplicas cannot be negative")
+	}
+	if a.MaxReplicas < 1 || a.MaxReplicas < a.MinReplicas {
+		return fmt.Errorf("autoscaler.maxReplicas must be at least 1 and not below minReplicas")
+	}
+	if b := a.bufferPercent(); b < 0 || b >= 100 {
+		return fmt.Errorf("autoscaler.bufferPercent must be between 0 and 99")
+	}
+	for name, seconds := range map[string]*int{
+		"scaleUpCooldownSeconds":   a.ScaleUpCooldownSeconds,
+		"scaleDownCooldownSeconds": a.ScaleDownCooldownSeconds,
+	} {
+		if seconds != nil && *seconds < 0 {
+			return fmt.Errorf("autoscaler.%s cannot be negative", name)
+		}
+	}
+	return nil
+}
+
+func (a *FleetAutoscaler) bufferPercent() int {
+	if a.BufferPercent == nil {
+		return defaultBufferPercent
+	}
+	return *a.BufferPercent
+}
+
+func (a *FleetAutoscaler) scaleUpCooldown() time.Duration {
+	if a.ScaleUpCooldownSeconds == nil {
+		return defaultScaleUpCooldown
+	}
+	return time.Duration(*a.ScaleUpCooldownSeconds) * time.Second
+}
+
+func (a *FleetAutoscaler) scaleDownCooldown() time.Duration {
+	if a.ScaleDownCooldownSeconds == nil {
+		return defaultScaleDownCooldown
+	}
+	return time.Duration(*a.ScaleDownCooldownSeconds) * time.Second
+}
+
+// autoscaleFleet adjusts spec.replicas so the fleet keeps the configured
+// share of player slots free. Scale-down never removes more servers than are
+// currently empty and unallocated, so players are not dropped from occupied
+// servers.
+func (s *Server) autoscaleFleet(ctx context.Context, obj *unstructured.Unstructured, fleet *Fleet, members []unstructured.Unstructured) *FleetAutoscalerStatus {
+	autoscaler := fleet.Spec.Autoscaler
+	if autoscaler == nil || !autoscaler.Enabled {
+		return nil
+	}
+
+	status := &FleetAutoscalerStatus{DesiredReplicas: fleet.Spec.Replicas}
+	if fleet.Status.Autoscaler != nil {
+		status.LastScaleTime = fleet.Status.Autoscaler.LastScaleTime
+	}
+
+	perServer := gameServerCapacity(newFleetMember(fleet, 0, ""))
+	if perServer <= 0 {
+		status.Message = "Game type has no player capacity; autoscaling is paused"
+		return status
+	}
+
+	players, empty := 0, 0
+	for i := range members {
+		n := gameServerPlayers(&members[i])
+		players += n
+		if n == 0 && !gameServerAllocated(&members[i]) {
+			empty++
+		}
+	}
+
+	// Smallest fleet whose free share of slots is at least the buffer
+	slots := float64(players) * 100 / float64(100-autoscaler.bufferPercent())
+	desired := int(math.Ceil(slots / float64(perServer)))
+	if desired < autoscaler.MinReplicas {
+		desired = autoscaler.MinReplicas
+	}
+	if desired > autoscaler.MaxReplicas {
+		desired = autoscaler.MaxReplicas
+	}
+
+	current := fleet.Spec.Replicas
+	if desired < current && current-desired > empty {
+		desired = current - empty
+		status.Message = fmt.Sprintf("Scale-down limited to %d empty servers", empty)
+	}
+	status.DesiredReplicas = desired
+	if desired == current {
+		return status
+	}
+
+	var sinceLastScale time.Duration = math.MaxInt64
+	if status.LastScaleTime != nil {
+		sinceLastScale = time.Since(status.LastScaleTime.Time)
+	}
+	cooldown := autoscaler.scaleUpCooldown()
+	if desired < current {
+		cooldown = autoscaler.scaleDownCooldown()
+	}
+	if sinceLastScale < cooldown {
+		status.Message = fmt.Sprintf("Waiting for cooldown before scaling to %d", desired)
+		return status
+	}
+
+	if err := unstructured.SetNestedField(obj.Object, int64(desired), "spec", "replicas"); err != nil {
+		status.Message = err.Error()
+		return status
+	}
+	if err := s.k8sClient.Update(ctx, obj); err != nil {
+		log.Printf("Failed to autoscale Fleet %s/%s: %v", fleet.Namespace, fleet.Name, err)
+		status.Message = fmt.Sprintf("Failed to scale to %d: %v", desired, err)
+		return status
+	}
+	log.Printf("Autoscaled Fleet %s/%s from %d to %d replicas (%d players)", fleet.Namespace, fleet.Name, current, desired, players)
+
+	now := metav1.Now()
+	status.LastScaleTime = &now
+	if status.Message == "" {
+		status.Message = fmt.Sprintf("Scaled from %d to %d replicas for %d players", current, desired, players)
+	}
+	fleet.Spec.Replicas = desired
+	return status
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// backgroundTask is a periodic job run by the API server alongside request handling
+type backgroundTask struct {
+	name     string
+	interval time.Duration
+	run      func(s *Server, ctx context.Context) error
+}
+
+// registerBackgroundTask adds a periodic task that starts with the server.
+// The task runs once per registered cluster on every tick.
+func (s *Server) registerBackgroundTask(name string, interval time.Duration, run func(s *Server, ctx context.Context) error) {
+	s.backgroundTasks = append(s.backgroundTasks, backgroundTask{
+		name:     name,
+		interval: interval,
+		run:      run,
+	})
+}
+
+// startBackgroundTasks runs every registered task on its interval until ctx is done
+func (s *Server) startBackgroundTasks(ctx context.Context) {
+	for _, task := range s.backgroundTasks {
+		go func(task backgroundTask) {
+			ticker := time.NewTicker(task.interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					s.runBackgroundTask(ctx, task)
+				}
+			}
+		}(task)
+	}
+}
+
+// runBackgroundTask runs a task once for every registered cluster
+func (s *Server) runBackgroundTask(ctx context.Context, task backgroundTask) {
+	for _, scoped := range s.clusters.servers() {
+		if err := task.run(scoped, ctx); err != nil {
+			log.Printf("Background task %s failed on cluster %s: %v", task.name, scoped.cluster, err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kubelize/gameplane/api/internal/devcluster"
+	"k8s.io/client-go/rest"
+)
+
+// TestBackgroundTasksOnRemoteClusters runs tasks against a registered
+// remote cluster, whose Server is built by forCluster rather than NewServer
+func TestBackgroundTasksOnRemoteClusters(t *testing.T) {
+	s, err := NewServer(Options{DevCluster: devcluster.Demo()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := s.forCluster("remote", &rest.Config{Host: "https://remote.invalid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cluster := devcluster.Demo()
+	remote.k8sClient, remote.kubeClient = cluster.Client, cluster.Kube
+	s.clusters.mu.Lock()
+	s.clusters.clusters["remote"] = &registeredCluster{name: "remote", server: remote}
+	s.clusters.mu.Unlock()
+
+	tasks := map[string]bool{"crash-remediator": true, "updater": true}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, task := range s.backgroundTasks {
+		if tasks[task.name] {
+			s.runBackgroundTask(ctx, task)
+			delete(tasks, task.name)
+		}
+	}
+	for name := range tasks {
+		t.Errorf("background task %s is not registered", name)
+	}
+}
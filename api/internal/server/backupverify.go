@@ -0,0 +1,445 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// backupVerificationAnnotation holds the last BackupVerification of a
+	// VolumeSnapshot as JSON
+	backupVerificationAnnotation = "gameplane.kubelize.io/verification"
+
+	defaultBackupVerifyInterval = 24 * time.Hour
+
+	// backupVerifyCheckInterval is how often the verifier looks for backups
+	// that are due
+	backupVerifyCheckInterval = time.Hour
+
+	// backupSettleTime is how long a new snapshot may take to become ready
+	// before the verifier counts it as failed
+	backupSettleTime = time.Hour
+
+	// backupRestoreTimeout bounds restoring a snapshot into a scratch volume
+	// and reading it back
+	backupRestoreTimeout = 30 * time.Minute
+
+	backupVerifyStep = "verify"
+)
+
+// Verification outcomes
+const (
+	verificationPassed = "passed"
+	verificationFailed = "failed"
+)
+
+// Backup chain states
+const (
+	chainOK         = "ok"
+	chainUnverified = "unverified"
+	chainBroken     = "broken"
+	chainEmpty      = "empty"
+)
+
+// BackupVerification is the outcome of verifying a backup. Without a test
+// restore only the snapshot's state is checked.
+type BackupVerification struct {
+	Status     string    `json:"status"`
+	VerifiedAt time.Time `json:"verifiedAt"`
+	// Restored is set when the backup was restored into a scratch volume
+	// and read back
+	Restored bool `json:"restored"`
+	// Checksum is the SHA-256 over the checksums of every restored file;
+	// snapshots are immutable, so later restores must reproduce it
+	Checksum string `json:"checksum,omitempty"`
+	Files    int64  `json:"files,omitempty"`
+	Bytes    int64  `json:"bytes,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// BackupChain summarizes whether a server's backups can be relied on. It is
+// broken when a backup newer than the last one that passed verification
+// failed.
+type BackupChain struct {
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+	// LastVerified is the newest backup that passed verification
+	LastVerified   string     `json:"lastVerified,omitempty"`
+	LastVerifiedAt *time.Time `json:"lastVerifiedAt,omitempty"`
+}
+
+// BackupVerifyRequest selects how thoroughly a backup is verified
+type BackupVerifyRequest struct {
+	// Restore restores the backup into a scratch volume and checksums it
+	Restore bool `json:"restore"`
+}
+
+// backupFailure is a verification that ran and found the backup unusable,
+// as opposed to one that could not run
+type backupFailure struct {
+	message string
+}
+
+func (f *backupFailure) Error() string {
+	return f.message
+}
+
+// backupChain evaluates backups sorted newest first
+func backupChain(backups []Backup) BackupChain {
+	if len(backups) == 0 {
+		return BackupChain{Status: chainEmpty}
+	}
+	for _, backup := range backups {
+		switch {
+		case backup.Error != "":
+			return BackupChain{Status: chainBroken, Reason: fmt.Sprintf("Snapshot %s failed: %s", backup.Name, backup.Error)}
+		case backup.Verification == nil:
+			continue
+		case backup.Verification.Status == verificationFailed:
+			return BackupChain{Status: chainBroken, Reason: fmt.Sprintf("Backup %s failed verification: %s", backup.Name, backup.Verification.Message)}
+		case backup.Verification.Status == verificationPassed:
+			verifiedAt := backup.Verification.VerifiedAt
+			return BackupChain{Status: chainOK, LastVerified: backup.Name, LastVerifiedAt: &verifiedAt}
+		}
+	}
+	return BackupChain{Status: chainUnverified, Reason: "No backup has been verified"}
+}
+
+// verifyBackupSnapshot verifies one backup of a GameServer as an async
+// operation and returns the operation to poll
+func (s *Server) verifyBackupSnapshot(c *gin.Context) {
+	var req BackupVerifyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid request body: %v", err),
+			})
+			return
+		}
+	}
+
+	obj, err := s.getGameServerObject(context.TODO(), c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+	namespace, err := managedNamespace(obj)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	snapshot, err := s.getBackupSnapshot(context.TODO(), namespace, c.Param("backup"))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Backup not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get backup: %v", err),
+		})
+		return
+	}
+
+	op := s.startOperation("verify-backup", obj.GetNamespace(), obj.GetName(), []string{backupVerifyStep}, backupRestoreTimeout+time.Minute, func(ctx context.Context, t *operationTracker) (interface{}, error) {
+		var verification *BackupVerification
+		err := t.step(backupVerifyStep, func() (string, error) {
+			var err error
+			verification, err = s.verifyBackup(ctx, obj, snapshot.GetName(), req.Restore)
+			if err != nil {
+				return "", err
+			}
+			if verification.Status == verificationFailed {
+				return "", errors.New(verification.Message)
+			}
+			return verification.Message, nil
+		})
+		return verification, err
+	})
+	c.JSON(http.StatusAccepted, op)
+}
+
+// getBackupSnapshot gets a VolumeSnapshot of the data volume of a managed
+// namespace, reporting snapshots of other volumes as not found
+func (s *Server) getBackupSnapshot(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	if err := s.k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, snapshot); err != nil {
+		return nil, err
+	}
+	if volume, _, _ := unstructured.NestedString(snapshot.Object, "spec", "source", "persistentVolumeClaimName"); volume != dataVolumeName(namespace) {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Group: volumeSnapshotGVK.Group, Resource: "volumesnapshots"}, name)
+	}
+	return snapshot, nil
+}
+
+// verifyBackup checks a backup, records the outcome on its VolumeSnapshot
+// and alerts subscribers when it failed. An error means the verification
+// could not run; a backup found unusable is a failed verification.
+func (s *Server) verifyBackup(ctx context.Context, obj *unstructured.Unstructured, name string, restore bool) (*BackupVerification, error) {
+	namespace, err := managedNamespace(obj)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := s.getBackupSnapshot(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	backup := s.backupFromSnapshot(snapshot)
+	verification := &BackupVerification{Status: verificationPassed}
+
+	var failure *backupFailure
+	switch {
+	case backup.Error != "":
+		failure = &backupFailure{fmt.Sprintf("snapshot failed: %s", backup.Error)}
+	case !backup.Ready:
+		failure = &backupFailure{"snapshot is not ready to use"}
+	case backup.Size == "":
+		failure = &backupFailure{"snapshot reports no restore size"}
+	case restore:
+		verification.Restored = true
+		err := s.testRestore(ctx, obj, backup, verification)
+		if err != nil && !errors.As(err, &failure) {
+			return nil, err
+		}
+		previous := backup.Verification
+		if failure == nil && previous != nil && previous.Checksum != "" && previous.Checksum != verification.Checksum {
+			failure = &backupFailure{fmt.Sprintf("restored data differs from the restore of %s", previous.VerifiedAt.Format(time.RFC3339))}
+		}
+	}
+	verification.VerifiedAt = time.Now().UTC()
+	switch {
+	case failure != nil:
+		verification.Status = verificationFailed
+		verification.Message = failure.message
+	case restore:
+		verification.Message = fmt.Sprintf("Restored %d files (%d bytes)", verification.Files, verification.Bytes)
+	default:
+		verification.Message = "Snapshot is ready to restore"
+	}
+
+	// Record on the latest version; the snapshot may have changed status
+	// while a restore ran
+	snapshot, err = s.getBackupSnapshot(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(verification)
+	if err != nil {
+		return nil, err
+	}
+	annotations := snapshot.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[backupVerificationAnnotation] = string(raw)
+	snapshot.SetAnnotations(annotations)
+	if err := s.k8sClient.Update(ctx, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to record verification: %w", err)
+	}
+
+	if verification.Status == verificationFailed {
+		log.Printf("Backup %s/%s of GameServer %s/%s failed verification: %s", namespace, name, obj.GetNamespace(), obj.GetName(), verification.Message)
+		s.notifySubscribers(ctx, notificationEvent{
+			Type:      "backup-failed",
+			Namespace: obj.GetNamespace(),
+			Server:    obj.GetName(),
+			Title:     "Backup chain broken",
+			Message:   fmt.Sprintf("Backup %s failed verification: %s", name, verification.Message),
+			Fields:    map[string]string{"Backup": name, "Restored": strconv.FormatBool(verification.Restored)},
+		})
+	}
+	return verification, nil
+}
+
+// backupManifestScript checksums every file of a restored volume and tests
+// the wipe backup archives on it
+const backupManifestScript = `cd ` + gameDataMountPath + `
+find . -type f | sort > /tmp/files
+echo "files=$(wc -l < /tmp/files)"
+echo "bytes=$(tr '\n' '\0' < /tmp/files | xargs -0 -r stat -c %s | awk '{s+=$1} END {printf "%.0f", s}')"
+echo "checksum=$(tr '\n' '\0' < /tmp/files | xargs -0 -r sha256sum | sha256sum | cut -d' ' -f1)"
+for f in ` + wipeBackupDir + `/*.tar.gz; do
+  [ -e "$f" ] || continue
+  gzip -t "$f" 2>/dev/null || echo "corrupt=$f"
+done
+`
+
+// testRestore restores a backup into a scratch PVC, reads it back with a
+// volume task and fills in the checksums of verification
+func (s *Server) testRestore(ctx context.Context, obj *unstructured.Unstructured, backup Backup, verification *BackupVerification) error {
+	namespace, _ := managedNamespace(obj)
+	size, err := resource.ParseQuantity(backup.Size)
+	if err != nil {
+		return &backupFailure{fmt.Sprintf("snapshot reports an invalid restore size %q", backup.Size)}
+	}
+	scratch, err := s.restoreVolume(ctx, namespace, backup, size, "verify-backup")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Use a fresh context so cleanup still happens after a timeout
+		_ = s.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Delete(context.Background(), scratch.Name, metav1.DeleteOptions{})
+	}()
+
+	out, err := s.runVolumeTask(ctx, obj, volumeTask{
+		Name:     "verify-backup",
+		Script:   backupManifestScript,
+		ReadOnly: true,
+		Timeout:  backupRestoreTimeout,
+		Claim:    scratch.Name,
+	})
+	if err != nil {
+		if apierrors.IsForbidden(err) || apierrors.IsInvalid(err) {
+			return err
+		}
+		return &backupFailure{fmt.Sprintf("test restore failed: %v", err)}
+	}
+
+	corrupt := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "files":
+			verification.Files, _ = strconv.ParseInt(value, 10, 64)
+		case "bytes":
+			verification.Bytes, _ = strconv.ParseInt(value, 10, 64)
+		case "checksum":
+			verification.Checksum = value
+		case "corrupt":
+			corrupt = append(corrupt, strings.TrimPrefix(value, "./"))
+		}
+	}
+	switch {
+	case verification.Checksum == "":
+		return &backupFailure{"test restore produced no checksum"}
+	case verification.Files == 0:
+		return &backupFailure{"restored volume is empty"}
+	case len(corrupt) > 0:
+		return &backupFailure{fmt.Sprintf("corrupt archives in the restored volume: %s", strings.Join(corrupt, ", "))}
+	}
+	return nil
+}
+
+// restoreVolume restores a backup into a new PVC of the managed namespace
+// for a volume task to read. The caller deletes it.
+func (s *Server) restoreVolume(ctx context.Context, namespace string, backup Backup, size resource.Quantity, task string) (*corev1.PersistentVolumeClaim, error) {
+	claims := s.kubeClient.CoreV1().PersistentVolumeClaims(namespace)
+
+	// Restore with the storage class of the live volume, which the
+	// snapshot class's driver serves
+	var storageClass *string
+	if source, err := claims.Get(ctx, backup.Volume, metav1.GetOptions{}); err == nil {
+		storageClass = source.Spec.StorageClassName
+	}
+	group := volumeSnapshotGVK.Group
+	restored, err := claims.Create(ctx, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-restore-%s", backup.Name, newOperationID()[:8]),
+			Namespace: namespace,
+			Labels: map[string]string{
+				volumeTaskLabel:        task,
+				"kubelize.io/task-for": namespace,
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: storageClass,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+			},
+			DataSource: &corev1.TypedLocalObjectReference{
+				APIGroup: &group,
+				Kind:     volumeSnapshotGVK.Kind,
+				Name:     backup.Name,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create restore volume: %w", err)
+	}
+	return restored, nil
+}
+
+// verifyLatestBackups is the background task verifying the newest backup of
+// every server once per BACKUP_VERIFY_INTERVAL. Verifications run one at a
+// time to keep test restores from competing for storage.
+func (s *Server) verifyLatestBackups(ctx context.Context) error {
+	if s.snapshots.verifyInterval == 0 {
+		return nil
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for i := range list.Items {
+		obj := &list.Items[i]
+		namespace, err := managedNamespace(obj)
+		if err != nil {
+			continue
+		}
+		snapshots, err := s.listVolumeSnapshots(ctx, client.InNamespace(namespace))
+		if err != nil {
+			return fmt.Errorf("failed to list volume snapshots: %w", err)
+		}
+		backups := []Backup{}
+		for j := range snapshots {
+			if backup := s.backupFromSnapshot(&snapshots[j]); backup.Volume == dataVolumeName(namespace) {
+				backups = append(backups, backup)
+			}
+		}
+		sort.Slice(backups, func(a, b int) bool { return backups[a].CreatedAt.After(backups[b].CreatedAt) })
+
+		for _, backup := range backups {
+			// Give new snapshots time to be cut before failing them
+			if !backup.Ready && backup.Error == "" && now.Sub(backup.CreatedAt) < backupSettleTime {
+				continue
+			}
+			if v := backup.Verification; v == nil || now.Sub(v.VerifiedAt) >= s.snapshots.verifyInterval {
+				if _, err := s.verifyBackup(ctx, obj, backup.Name, s.snapshots.verifyRestore); err != nil {
+					log.Printf("Failed to verify backup %s/%s: %v", namespace, backup.Name, err)
+				}
+			}
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,775 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var banListGVK = schema.GroupVersionKind{
+	Group:   "gameplane.kubelize.io",
+	Version: "v1alpha1",
+	Kind:    "BanList",
+}
+
+const (
+	// banSyncAnnotation holds a GameServer's banSyncRecord as JSON
+	banSyncAnnotation = "gameplane.kubelize.io/ban-sync"
+
+	// banListSyncInterval is how often ban lists are propagated
+	banListSyncInterval = time.Minute
+
+	// banListRetryInterval spaces retries of servers whose last sync failed
+	banListRetryInterval = 5 * time.Minute
+
+	// banListSyncTimeout bounds rewriting one server's ban file
+	banListSyncTimeout = 5 * time.Minute
+)
+
+// BanListSpec is a shared list of bans and the GameServers it applies to
+type BanListSpec struct {
+	DisplayName string `json:"displayName,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Selector picks the subscribed GameServers; empty selects every server
+	Selector ServerSelector `json:"selector"`
+	Entries  []PlayerEntry  `json:"entries"`
+}
+
+// BanListStatus reports how far a ban list has propagated
+type BanListStatus struct {
+	Servers int                   `json:"servers"`
+	Synced  int                   `json:"synced"`
+	Failed  int                   `json:"failed"`
+	Members []BanListServerStatus `json:"members"`
+}
+
+// BanListServerStatus is the sync state of one subscribed GameServer
+type BanListServerStatus struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Synced    bool   `json:"synced"`
+	// Entries counts the bans written, from every list the server follows
+	Entries  int        `json:"entries"`
+	LastSync *time.Time `json:"lastSync,omitempty"`
+	// RestartRequired is set when the game only reads bans on start
+	RestartRequired bool   `json:"restartRequired,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// BanList shares bans across GameServers, so a player banned once is banned
+// on every subscribed server. BanLists are cluster-scoped and propagated by
+// the API into each game's own ban file.
+type BanList struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              BanListSpec    `json:"spec"`
+	Status            *BanListStatus `json:"status,omitempty"`
+}
+
+// banSyncRecord remembers what the ban lists wrote to a GameServer. Managed
+// lets a later sync lift bans that were removed from the lists without
+// touching bans issued in game.
+type banSyncRecord struct {
+	Hash    string   `json:"hash"`
+	Lists   []string `json:"lists"`
+	Managed []string `json:"managed"`
+	// Changed is set when the last sync rewrote the ban file
+	Changed         bool      `json:"changed,omitempty"`
+	Entries         int       `json:"entries"`
+	LastSync        time.Time `json:"lastSync"`
+	RestartRequired bool      `json:"restartRequired,omitempty"`
+	Error           string    `json:"error,omitempty"`
+}
+
+// banListSync serializes propagation passes
+type banListSync struct {
+	mu sync.Mutex
+}
+
+// listBanLists returns all ban lists with their sync status
+func (s *Server) listBanLists(c *gin.Context) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(banListGVK.GroupVersion().WithKind("BanListList"))
+	if err := s.k8sClient.List(context.TODO(), list); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list BanLists: %v", err),
+		})
+		return
+	}
+
+	banLists := make([]BanList, 0, len(list.Items))
+	for i := range list.Items {
+		banList, err := banListFromUnstructured(&list.Items[i])
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to convert BanList: %v", err),
+			})
+			return
+		}
+		banLists = append(banLists, *banList)
+	}
+	sort.Slice(banLists, func(i, j int) bool { return banLists[i].Name < banLists[j].Name })
+
+	items, ok := selectFields(c, banLists)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(banLists),
+	})
+}
+
+// createBanList creates a ban list and starts propagating it
+func (s *Server) createBanList(c *gin.Context) {
+	var banList BanList
+	if err := c.ShouldBindJSON(&banList); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if banList.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "metadata.name is required",
+		})
+		return
+	}
+	if !s.validBanListSpec(c, &banList.Spec) {
+		return
+	}
+
+	banList.APIVersion = banListGVK.GroupVersion().String()
+	banList.Kind = banListGVK.Kind
+	banList.Namespace = ""
+	banList.Status = nil
+	obj, err := banListToUnstructured(&banList)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := s.k8sClient.Create(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to create BanList: %v", err),
+		})
+		return
+	}
+	s.propagateBanLists()
+	s.respondWithBanList(c, http.StatusCreated, obj)
+}
+
+// getBanList returns a ban list with its sync status
+func (s *Server) getBanList(c *gin.Context) {
+	obj, ok := s.loadBanList(c)
+	if !ok {
+		return
+	}
+	s.respondWithBanList(c, http.StatusOK, obj)
+}
+
+// updateBanList replaces a ban list's spec
+func (s *Server) updateBanList(c *gin.Context) {
+	var req struct {
+		Spec BanListSpec `json:"spec"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if !s.validBanListSpec(c, &req.Spec) {
+		return
+	}
+	obj, ok := s.loadBanList(c)
+	if !ok {
+		return
+	}
+	s.saveBanListSpec(c, obj, req.Spec)
+}
+
+// deleteBanList removes a ban list; its bans are lifted on the servers it
+// applied to during the next sync
+func (s *Server) deleteBanList(c *gin.Context) {
+	obj, ok := s.loadBanList(c)
+	if !ok {
+		return
+	}
+	if err := s.k8sClient.Delete(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete BanList: %v", err),
+		})
+		return
+	}
+	s.propagateBanLists()
+	c.JSON(http.StatusOK, gin.H{
+		"message": "BanList deleted successfully",
+	})
+}
+
+// addBanListEntry bans a player on every subscribed server, replacing an
+// existing entry for the same ID
+func (s *Server) addBanListEntry(c *gin.Context) {
+	var entry PlayerEntry
+	if err := c.ShouldBindJSON(&entry); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if err := validatePlayerEntry(entry); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if entry.Since == nil {
+		now := time.Now().UTC().Truncate(time.Second)
+		entry.Since = &now
+	}
+
+	obj, ok := s.loadBanList(c)
+	if !ok {
+		return
+	}
+	banList, err := banListFromUnstructured(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to convert BanList: %v", err),
+		})
+		return
+	}
+	spec := banList.Spec
+	entries := []PlayerEntry{entry}
+	for _, existing := range spec.Entries {
+		if existing.key() != entry.key() {
+			entries = append(entries, existing)
+		}
+	}
+	spec.Entries = entries
+	sortPlayers(spec.Entries)
+	s.saveBanListSpec(c, obj, spec)
+}
+
+// removeBanListEntry lifts a ban from the list
+func (s *Server) removeBanListEntry(c *gin.Context) {
+	obj, ok := s.loadBanList(c)
+	if !ok {
+		return
+	}
+	banList, err := banListFromUnstructured(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to convert BanList: %v", err),
+		})
+		return
+	}
+	spec := banList.Spec
+	target := PlayerEntry{ID: c.Param("id")}
+	entries := []PlayerEntry{}
+	for _, existing := range spec.Entries {
+		if existing.key() != target.key() {
+			entries = append(entries, existing)
+		}
+	}
+	if len(entries) == len(spec.Entries) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("Player %s is not on the ban list", target.ID),
+		})
+		return
+	}
+	spec.Entries = entries
+	s.saveBanListSpec(c, obj, spec)
+}
+
+// syncBanListNow propagates the ban list to its servers right away, also
+// retrying servers that failed recently
+func (s *Server) syncBanListNow(c *gin.Context) {
+	obj, ok := s.loadBanList(c)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), banListSyncTimeout)
+	defer cancel()
+	if err := s.reconcileBanLists(ctx, obj.GetName()); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to sync BanList: %v", err),
+		})
+		return
+	}
+	obj, ok = s.loadBanList(c)
+	if !ok {
+		return
+	}
+	s.respondWithBanList(c, http.StatusOK, obj)
+}
+
+// getGameServerBans reports the ban lists a GameServer follows and the
+// outcome of its last ban sync
+func (s *Server) getGameServerBans(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	banLists, err := s.loadAllBanLists(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list BanLists: %v", err),
+		})
+		return
+	}
+
+	subscribed := []string{}
+	for _, banList := range banLists {
+		if banList.Spec.Selector.matches(obj) {
+			subscribed = append(subscribed, banList.Name)
+		}
+	}
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, _ := lookupGame(gameType)
+	response := gin.H{
+		"banLists":  subscribed,
+		"supported": def.BanList != nil,
+	}
+	if record := banSync(obj); record != nil {
+		response["lastSync"] = record
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// validBanListSpec checks a spec, responding when it is invalid or selects
+// namespaces the caller cannot read
+func (s *Server) validBanListSpec(c *gin.Context, spec *BanListSpec) bool {
+	if err := validateBanListSpec(spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return false
+	}
+	scope, err := s.readableNamespaces(c)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to determine readable namespaces: %v", err),
+		})
+		return false
+	}
+	if spec.Selector.Namespace != "" && !scope.allows(spec.Selector.Namespace) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Not permitted to access namespace %s", spec.Selector.Namespace),
+		})
+		return false
+	}
+	return true
+}
+
+// validateBanListSpec checks the selector and entries
+func validateBanListSpec(spec *BanListSpec) error {
+	if err := spec.Selector.validate(); err != nil {
+		return err
+	}
+	if spec.Entries == nil {
+		spec.Entries = []PlayerEntry{}
+	}
+	seen := map[string]bool{}
+	for _, entry := range spec.Entries {
+		if err := validatePlayerEntry(entry); err != nil {
+			return err
+		}
+		if seen[entry.key()] {
+			return fmt.Errorf("duplicate ban entry for %s", entry.ID)
+		}
+		seen[entry.key()] = true
+	}
+	return nil
+}
+
+// saveBanListSpec writes a new spec, propagates it and responds with the
+// ban list
+func (s *Server) saveBanListSpec(c *gin.Context, obj *unstructured.Unstructured, spec BanListSpec) {
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&spec)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	obj.Object["spec"] = raw
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update BanList: %v", err),
+		})
+		return
+	}
+	s.propagateBanLists()
+	s.respondWithBanList(c, http.StatusOK, obj)
+}
+
+// respondWithBanList responds with a ban list
+func (s *Server) respondWithBanList(c *gin.Context, status int, obj *unstructured.Unstructured) {
+	banList, err := banListFromUnstructured(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to convert BanList: %v", err),
+		})
+		return
+	}
+	shaped, ok := selectFields(c, banList)
+	if !ok {
+		return
+	}
+	c.JSON(status, shaped)
+}
+
+// loadBanList fetches the ban list named by :banlist, responding on failure
+func (s *Server) loadBanList(c *gin.Context) (*unstructured.Unstructured, bool) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(banListGVK)
+	if err := s.k8sClient.Get(context.TODO(), client.ObjectKey{Name: c.Param("banlist")}, obj); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "BanList not found",
+			})
+			return nil, false
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get BanList: %v", err),
+		})
+		return nil, false
+	}
+	return obj, true
+}
+
+// propagateBanLists starts a propagation pass in the background, so a new
+// ban reaches the servers without waiting for the next interval
+func (s *Server) propagateBanLists() {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), banListSyncTimeout)
+		defer cancel()
+		if err := s.reconcileBanLists(ctx, ""); err != nil {
+			log.Printf("Ban list sync failed: %v", err)
+		}
+	}()
+}
+
+// syncBanLists is the periodic propagation pass
+func (s *Server) syncBanLists(ctx context.Context) error {
+	return s.reconcileBanLists(ctx, "")
+}
+
+// reconcileBanLists writes the bans of every ban list into the GameServers
+// it selects and records the outcome on the lists. A server is only
+// rewritten when the bans it should have changed, or to retry a failure;
+// servers of the force list are rewritten regardless.
+func (s *Server) reconcileBanLists(ctx context.Context, force string) error {
+	s.banLists.mu.Lock()
+	defer s.banLists.mu.Unlock()
+
+	banLists, err := s.loadAllBanLists(ctx)
+	if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+		// Installations without the BanList CRD have nothing to sync
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list BanLists: %w", err)
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+
+	now := time.Now().UTC()
+	members := map[string][]BanListServerStatus{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		if obj.GetDeletionTimestamp() != nil {
+			continue
+		}
+		subscribed := []*BanList{}
+		for _, banList := range banLists {
+			if banList.Spec.Selector.matches(obj) {
+				subscribed = append(subscribed, banList)
+			}
+		}
+		record := banSync(obj)
+		if len(subscribed) == 0 && (record == nil || len(record.Managed) == 0) {
+			continue
+		}
+
+		desired, names := desiredBans(subscribed, now)
+		hash := banHash(desired)
+		forced := containsString(names, force)
+		due := record == nil || record.Hash != hash ||
+			(record.Error != "" && now.Sub(record.LastSync) >= banListRetryInterval)
+		if due || forced {
+			record = s.syncServerBans(ctx, obj, desired, names, hash, record)
+		}
+
+		status := BanListServerStatus{
+			Namespace:       obj.GetNamespace(),
+			Name:            obj.GetName(),
+			Synced:          record.Error == "",
+			Entries:         record.Entries,
+			RestartRequired: record.RestartRequired,
+			Error:           record.Error,
+		}
+		lastSync := record.LastSync
+		status.LastSync = &lastSync
+		for _, name := range names {
+			members[name] = append(members[name], status)
+		}
+	}
+
+	for _, banList := range banLists {
+		status := &BanListStatus{Members: members[banList.Name]}
+		if status.Members == nil {
+			status.Members = []BanListServerStatus{}
+		}
+		for _, member := range status.Members {
+			status.Servers++
+			if member.Synced {
+				status.Synced++
+			} else {
+				status.Failed++
+			}
+		}
+		if err := s.recordBanListStatus(ctx, banList, status); err != nil {
+			log.Printf("Failed to record status of BanList %s: %v", banList.Name, err)
+		}
+	}
+	return nil
+}
+
+// desiredBans merges the unexpired entries of the lists a server follows,
+// returning them with the lists' names
+func desiredBans(banLists []*BanList, now time.Time) ([]PlayerEntry, []string) {
+	sort.Slice(banLists, func(i, j int) bool { return banLists[i].Name < banLists[j].Name })
+	merged := map[string]PlayerEntry{}
+	names := []string{}
+	for _, banList := range banLists {
+		names = append(names, banList.Name)
+		for _, entry := range banList.Spec.Entries {
+			if entry.expired(now) {
+				continue
+			}
+			if _, seen := merged[entry.key()]; !seen {
+				merged[entry.key()] = entry
+			}
+		}
+	}
+	entries := make([]PlayerEntry, 0, len(merged))
+	for _, entry := range merged {
+		entries = append(entries, entry)
+	}
+	sortPlayers(entries)
+	return entries, names
+}
+
+// banHash fingerprints the bans a server should have
+func banHash(entries []PlayerEntry) string {
+	raw, _ := json.Marshal(entries)
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:8])
+}
+
+// syncServerBans rewrites a GameServer's ban file: bans the lists placed
+// earlier are replaced with the desired ones, and bans from other sources
+// are kept. The outcome is recorded on the GameServer.
+func (s *Server) syncServerBans(ctx context.Context, obj *unstructured.Unstructured, desired []PlayerEntry, names []string, hash string, previous *banSyncRecord) *banSyncRecord {
+	record := &banSyncRecord{Hash: hash, Lists: names, Managed: []string{}, Entries: len(desired), LastSync: time.Now().UTC()}
+	for _, entry := range desired {
+		record.Managed = append(record.Managed, entry.key())
+	}
+	changed, err := s.writeServerBans(ctx, obj, desired, previous)
+	if err != nil {
+		record.Error = err.Error()
+		// Keep lifting the old bans on the next attempt
+		if previous != nil {
+			record.Managed = append(record.Managed, previous.Managed...)
+		}
+	}
+	record.Changed = changed
+	if changed {
+		gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+		def, _ := lookupGame(gameType)
+		record.RestartRequired = !def.BanList.LiveReload && def.BanList.ReloadCommand == ""
+	} else if previous != nil && previous.Error == "" {
+		record.RestartRequired = previous.RestartRequired
+	}
+
+	raw, err := json.Marshal(record)
+	if err == nil {
+		var current *unstructured.Unstructured
+		current, err = s.getGameServerObject(ctx, obj.GetNamespace(), obj.GetName())
+		if err == nil {
+			annotations := current.GetAnnotations()
+			if annotations == nil {
+				annotations = map[string]string{}
+			}
+			annotations[banSyncAnnotation] = string(raw)
+			current.SetAnnotations(annotations)
+			err = s.k8sClient.Update(ctx, current)
+		}
+	}
+	if err != nil {
+		log.Printf("Failed to record ban sync of %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+	}
+	return record
+}
+
+// writeServerBans merges the desired bans into the game's ban file and
+// reports whether the file changed
+func (s *Server) writeServerBans(ctx context.Context, obj *unstructured.Unstructured, desired []PlayerEntry, previous *banSyncRecord) (bool, error) {
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, found := lookupGame(gameType)
+	if !found || def.BanList == nil {
+		return false, fmt.Errorf("game type %s does not support ban lists", gameType)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, banListSyncTimeout)
+	defer cancel()
+	existing, exists, err := s.readOptionalGameFile(ctx, obj, def.BanList.Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read ban list: %w", err)
+	}
+	current := []PlayerEntry{}
+	if exists {
+		if current, err = parsePlayerList(def.BanList, existing); err != nil {
+			return false, fmt.Errorf("failed to parse ban list: %w", err)
+		}
+	}
+
+	managed := map[string]bool{}
+	if previous != nil {
+		for _, key := range previous.Managed {
+			managed[key] = true
+		}
+	}
+	merged := map[string]PlayerEntry{}
+	for _, entry := range current {
+		if !managed[entry.key()] {
+			merged[entry.key()] = entry
+		}
+	}
+	for _, entry := range desired {
+		merged[entry.key()] = entry
+	}
+	entries := make([]PlayerEntry, 0, len(merged))
+	for _, entry := range merged {
+		entries = append(entries, entry)
+	}
+	sortPlayers(entries)
+
+	content, err := renderPlayerList(def.BanList, existing, entries)
+	if err != nil {
+		return false, fmt.Errorf("failed to render ban list: %w", err)
+	}
+	if bytes.Equal(content, existing) {
+		return false, nil
+	}
+	if err := s.writeGameFile(ctx, obj, def.BanList.Path, content); err != nil {
+		return false, fmt.Errorf("failed to write ban list: %w", err)
+	}
+	s.reloadPlayerList(ctx, obj, def.BanList)
+	return true, nil
+}
+
+// recordBanListStatus stores a ban list's status when it changed
+func (s *Server) recordBanListStatus(ctx context.Context, banList *BanList, status *BanListStatus) error {
+	// Compare serialized, since times read back from JSON lose their location
+	before, _ := json.Marshal(banList.Status)
+	after, _ := json.Marshal(status)
+	if bytes.Equal(before, after) {
+		return nil
+	}
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+	if err != nil {
+		return err
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(banListGVK)
+	if err := s.k8sClient.Get(ctx, client.ObjectKey{Name: banList.Name}, obj); err != nil {
+		return err
+	}
+	obj.Object["status"] = raw
+	return s.k8sClient.Status().Update(ctx, obj)
+}
+
+// loadAllBanLists reads every ban list
+func (s *Server) loadAllBanLists(ctx context.Context) ([]*BanList, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(banListGVK.GroupVersion().WithKind("BanListList"))
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return nil, err
+	}
+	banLists := make([]*BanList, 0, len(list.Items))
+	for i := range list.Items {
+		banList, err := banListFromUnstructured(&list.Items[i])
+		if err != nil {
+			log.Printf("Skipping invalid BanList %s: %v", list.Items[i].GetName(), err)
+			continue
+		}
+		banLists = append(banLists, banList)
+	}
+	return banLists, nil
+}
+
+// banSync reads the GameServer's last ban sync, or nil before the first
+func banSync(obj *unstructured.Unstructured) *banSyncRecord {
+	raw, ok := obj.GetAnnotations()[banSyncAnnotation]
+	if !ok {
+		return nil
+	}
+	var record banSyncRecord
+	if err := json.Unmarshal([]byte(raw), &record); err != nil {
+		return nil
+	}
+	return &record
+}
+
+func banListFromUnstructured(obj *unstructured.Unstructured) (*BanList, error) {
+	raw, err := json.Marshal(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	banList := &BanList{}
+	if err := json.Unmarshal(raw, banList); err != nil {
+		return nil, err
+	}
+	if banList.Spec.Entries == nil {
+		banList.Spec.Entries = []PlayerEntry{}
+	}
+	return banList, nil
+}
+
+func banListToUnstructured(banList *BanList) (*unstructured.Unstructured, error) {
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(banList)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: raw}, nil
+}
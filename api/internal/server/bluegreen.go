@@ -0,0 +1,620 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// blueGreenAnnotation records a server's part in a blue/green upgrade
+	blueGreenAnnotation = "gameplane.kubelize.io/bluegreen"
+
+	// upgradeTimeout bounds a whole upgrade
+	upgradeTimeout = 3 * time.Hour
+)
+
+// Upgrade strategies
+const (
+	upgradeInPlace   = "inplace"
+	upgradeBlueGreen = "bluegreen"
+)
+
+// Roles in a blue/green upgrade: the candidate is being validated, the
+// active instance takes players and the standby is kept for rollback
+const (
+	blueGreenCandidate = "candidate"
+	blueGreenActive    = "active"
+	blueGreenStandby   = "standby"
+)
+
+// Blue/green upgrade steps, in order
+const (
+	blueGreenStepCreate    = "create-green"
+	blueGreenStepProvision = "wait-for-green"
+	blueGreenStepCopy      = "copy-world"
+	blueGreenStepValidate  = "validate-green"
+	blueGreenStepSwitch    = "switch-traffic"
+	blueGreenStepStart     = "start-standby"
+)
+
+// UpgradeRequest updates one GameServer to a new image or composition
+// revision
+type UpgradeRequest struct {
+	RolloutTarget
+	// ReadyTimeout is how long the updated server may take to become ready
+	ReadyTimeout string `json:"readyTimeout,omitempty"`
+	// Name names the new instance of a blue/green upgrade; defaults to the
+	// current name with a random suffix
+	Name string `json:"name,omitempty"`
+	// KeepRunning leaves the old instance running after a blue/green switch
+	// so a rollback is instant, at the cost of its world moving on
+	KeepRunning bool `json:"keepRunning,omitempty"`
+	// IgnoreModCompatibility upgrades even though a mounted mod is not
+	// known to work with the new game version
+	IgnoreModCompatibility bool `json:"ignoreModCompatibility,omitempty"`
+}
+
+// UpgradeResponse is the upgrade operation with the mod check it passed
+type UpgradeResponse struct {
+	Operation
+	Mods *ModCompatibility `json:"mods,omitempty"`
+}
+
+// BlueGreenState is recorded on both instances of a blue/green upgrade
+type BlueGreenState struct {
+	Role string `json:"role"`
+	// Peer is the other instance in the same namespace
+	Peer       string     `json:"peer"`
+	Operation  string     `json:"operation,omitempty"`
+	SwitchedAt *time.Time `json:"switchedAt,omitempty"`
+}
+
+// blueGreenState reads the blue/green annotation, returning nil when unset
+func blueGreenState(obj *unstructured.Unstructured) *BlueGreenState {
+	raw, ok := obj.GetAnnotations()[blueGreenAnnotation]
+	if !ok {
+		return nil
+	}
+	state := &BlueGreenState{}
+	if err := json.Unmarshal([]byte(raw), state); err != nil {
+		log.Printf("Ignoring invalid %s annotation of GameServer %s/%s: %v", blueGreenAnnotation, obj.GetNamespace(), obj.GetName(), err)
+		return nil
+	}
+	return state
+}
+
+// setBlueGreenState writes or, for nil, removes the blue/green annotation
+func setBlueGreenState(obj *unstructured.Unstructured, state *BlueGreenState) error {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if state == nil {
+		delete(annotations, blueGreenAnnotation)
+		obj.SetAnnotations(annotations)
+		return nil
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	annotations[blueGreenAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// upgradeGameServer updates a GameServer to a new image or composition
+// revision as an operation. ?strategy=inplace (default) updates the server
+// itself; ?strategy=bluegreen provisions an updated copy of it, validates
+// the copy and then switches players over, keeping the old instance.
+func (s *Server) upgradeGameServer(c *gin.Context) {
+	var req UpgradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if req.Image == "" && req.CompositionRevision == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "image or compositionRevision is required",
+		})
+		return
+	}
+	if req.ReadyTimeout == "" {
+		req.ReadyTimeout = defaultRolloutReadyTimeout.String()
+	}
+	readyTimeout, err := time.ParseDuration(req.ReadyTimeout)
+	if err != nil || readyTimeout <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid readyTimeout %q", req.ReadyTimeout),
+		})
+		return
+	}
+	strategy := c.DefaultQuery("strategy", upgradeInPlace)
+	if strategy != upgradeInPlace && strategy != upgradeBlueGreen {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Unsupported strategy %q (valid: %s, %s)", strategy, upgradeInPlace, upgradeBlueGreen),
+		})
+		return
+	}
+
+	namespace := c.Param("namespace")
+	if !s.checkMaintenance(c, namespace) {
+		return
+	}
+	obj, ok := s.upgradableGameServer(c)
+	if !ok {
+		return
+	}
+	if rolloutApplied(obj, req.RolloutTarget) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "GameServer is already at the target",
+		})
+		return
+	}
+	mods, err := s.checkModCompatibility(context.TODO(), obj, req.RolloutTarget, req.IgnoreModCompatibility)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to check mod compatibility: %v", err),
+		})
+		return
+	}
+	if mods != nil && mods.Blocked {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": mods.Reason + "; set ignoreModCompatibility to upgrade anyway",
+			"mods":  mods,
+		})
+		return
+	}
+
+	if strategy == upgradeInPlace {
+		op := s.startOperation("upgrade", namespace, obj.GetName(), []string{"update"}, upgradeTimeout, func(ctx context.Context, t *operationTracker) (interface{}, error) {
+			err := t.step("update", func() (string, error) {
+				if err := s.rolloutServer(ctx, t.op.ID, namespace, obj.GetName(), req.RolloutTarget, readyTimeout); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("Updated to %s", rolloutName(req.RolloutTarget)), nil
+			})
+			return nil, err
+		})
+		c.JSON(http.StatusAccepted, UpgradeResponse{Operation: op, Mods: mods})
+		return
+	}
+
+	if state := blueGreenState(obj); state != nil {
+		if _, err := s.getGameServerObject(context.TODO(), namespace, state.Peer); err == nil {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": fmt.Sprintf("GameServer is the %s of a blue/green upgrade with %s; finalize or roll it back first", state.Role, state.Peer),
+			})
+			return
+		}
+	}
+	if req.Name == "" {
+		base := obj.GetName()
+		if len(base) > 46 {
+			base = base[:46]
+		}
+		req.Name = base + "-" + newOperationID()[:5]
+	}
+	if _, err := s.getGameServerObject(context.TODO(), namespace, req.Name); err == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("GameServer %s/%s already exists", namespace, req.Name),
+		})
+		return
+	}
+
+	steps := []string{blueGreenStepCreate, blueGreenStepProvision, blueGreenStepCopy, blueGreenStepValidate, blueGreenStepSwitch}
+	op := s.startOperation("upgrade-bluegreen", namespace, obj.GetName(), steps, upgradeTimeout, func(ctx context.Context, t *operationTracker) (interface{}, error) {
+		return s.blueGreenUpgrade(ctx, t, obj, req, readyTimeout)
+	})
+	c.JSON(http.StatusAccepted, UpgradeResponse{Operation: op, Mods: mods})
+}
+
+// upgradableGameServer loads the GameServer of the route, writing the error
+// response itself when it cannot be upgraded
+func (s *Server) upgradableGameServer(c *gin.Context) (*unstructured.Unstructured, bool) {
+	obj, err := s.getGameServerObject(context.TODO(), c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return nil, false
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return nil, false
+	}
+	if _, member := obj.GetLabels()[fleetLabel]; member {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Fleet members are managed by their Fleet and cannot be upgraded on their own",
+		})
+		return nil, false
+	}
+	if _, err := managedNamespace(obj); err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+	return obj, true
+}
+
+// blueGreenUpgrade runs the blue/green steps. A green instance that fails
+// to come up or validate is deleted and the blue one is left untouched.
+func (s *Server) blueGreenUpgrade(ctx context.Context, t *operationTracker, blue *unstructured.Unstructured, req UpgradeRequest, readyTimeout time.Duration) (interface{}, error) {
+	namespace := blue.GetNamespace()
+	result := gin.H{"blue": blue.GetName(), "green": req.Name, "target": req.RolloutTarget}
+
+	var green *unstructured.Unstructured
+	err := t.step(blueGreenStepCreate, func() (string, error) {
+		green = blueGreenClaim(blue, req, t.op.ID)
+		if err := s.k8sClient.Create(ctx, green); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Created %s/%s with %s", namespace, req.Name, rolloutName(req.RolloutTarget)), nil
+	})
+	if err != nil {
+		return result, err
+	}
+	fail := func(err error) (interface{}, error) {
+		if delErr := s.k8sClient.Delete(context.Background(), green); delErr != nil && !apierrors.IsNotFound(delErr) {
+			log.Printf("Failed to delete green GameServer %s/%s: %v", namespace, green.GetName(), delErr)
+		} else {
+			result["cleanup"] = fmt.Sprintf("Deleted %s/%s; %s was left untouched", namespace, green.GetName(), blue.GetName())
+		}
+		return result, err
+	}
+
+	err = t.step(blueGreenStepProvision, func() (string, error) {
+		latest, err := s.waitForRunningGameServer(ctx, namespace, req.Name)
+		if err != nil {
+			return "", err
+		}
+		green = latest
+		return "Green instance is running", nil
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	// Players stay on blue meanwhile; what they do after the copy is lost
+	// at the switch
+	err = t.step(blueGreenStepCopy, func() (string, error) {
+		if _, err := s.broadcastInGame(ctx, blue, "Server is being updated; progress after this point may be lost"); err != nil && !errors.Is(err, errConsoleUnsupported) {
+			log.Printf("Upgrade announcement for %s/%s failed: %v", namespace, blue.GetName(), err)
+		}
+		if _, err := s.saveWorldInGame(ctx, blue); err != nil && !errors.Is(err, errConsoleUnsupported) {
+			log.Printf("Saving %s/%s before upgrade failed: %v", namespace, blue.GetName(), err)
+		}
+		sent, err := transferGameData(ctx, s, blue, s, green)
+		if err != nil {
+			return "", err
+		}
+		// Kill without a graceful shutdown so the fresh world is not saved
+		// over the copied one
+		pods, managed, err := s.findGameServerPods(ctx, green)
+		if err != nil {
+			return "", err
+		}
+		noGrace := int64(0)
+		if _, err := s.deleteGameServerPods(ctx, managed, pods, &noGrace); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Copied %d bytes of world data", sent), nil
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	err = t.step(blueGreenStepValidate, func() (string, error) {
+		return s.validateBlueGreen(ctx, green, req.Image, readyTimeout)
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	err = t.step(blueGreenStepSwitch, func() (string, error) {
+		if err := s.switchBlueGreen(ctx, blue.GetNamespace(), blue.GetName(), green.GetName(), t.op.ID, req.KeepRunning); err != nil {
+			return "", err
+		}
+		message := fmt.Sprintf("Players now go to %s; %s is kept for rollback", green.GetName(), blue.GetName())
+		if latest, err := s.getGameServerObject(ctx, namespace, green.GetName()); err == nil {
+			if info, err := s.gameServerConnectInfo(ctx, latest); err == nil {
+				result["connect"] = info
+				message += fmt.Sprintf(" (connect to %s)", info.Address())
+			}
+		}
+		return message, nil
+	})
+	if err != nil {
+		return result, err
+	}
+	s.publishEvent(eventGameServerUpdated, namespace, green.GetName(), map[string]interface{}{
+		"upgrade":  req.RolloutTarget,
+		"strategy": upgradeBlueGreen,
+		"replaces": blue.GetName(),
+	})
+	return result, nil
+}
+
+// blueGreenClaim copies the blue claim for its green instance with the
+// target applied. The ingress host and cluster of servers membership stay
+// with blue until the switch.
+func blueGreenClaim(blue *unstructured.Unstructured, req UpgradeRequest, operationID string) *unstructured.Unstructured {
+	green := migratedClaim(blue, MigrationRequest{Namespace: blue.GetNamespace(), Name: req.Name}, "")
+	_ = applyRolloutTarget(green, req.RolloutTarget)
+	_ = setStopped(green, false)
+	unstructured.RemoveNestedField(green.Object, "spec", "networking", "ingressHost")
+
+	labels := green.GetLabels()
+	delete(labels, serverClusterLabel)
+	green.SetLabels(labels)
+	annotations := green.GetAnnotations()
+	delete(annotations, serverClusterRoleAnnotation)
+	delete(annotations, lifecycleStateAnnotation)
+	green.SetAnnotations(annotations)
+	_ = setBlueGreenState(green, &BlueGreenState{Role: blueGreenCandidate, Peer: blue.GetName(), Operation: operationID})
+	return green
+}
+
+// validateBlueGreen waits for the green instance to be ready on the target
+// and to answer the game's server query, when it has one
+func (s *Server) validateBlueGreen(ctx context.Context, green *unstructured.Unstructured, image string, readyTimeout time.Duration) (string, error) {
+	deadline := time.Now().Add(readyTimeout)
+	pods, err := s.waitForRolloutReady(ctx, green.GetNamespace(), green.GetName(), image, readyTimeout)
+	if err != nil {
+		return "", err
+	}
+	gameType, _, _ := unstructured.NestedString(green.Object, "spec", "gameType")
+
+	// The game may still be loading its world after the pod turns ready
+	ticker := time.NewTicker(rolloutPollInterval)
+	defer ticker.Stop()
+	for {
+		players, err := status.QueryPlayers(ctx, gameType, pods[0].Status.PodIP)
+		if errors.Is(err, status.ErrNoQuery) {
+			return "Green instance is ready; the game has no query protocol to check", nil
+		}
+		if err == nil {
+			return fmt.Sprintf("Green instance is ready and answers queries (%d players)", players), nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("green instance does not answer queries: %w", err)
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// switchBlueGreen moves players from one instance to its peer: the ingress
+// host, cluster of servers membership and proxy backends follow, and the
+// instance switched away from becomes the standby, stopped unless
+// keepRunning is set
+func (s *Server) switchBlueGreen(ctx context.Context, namespace, from, to, operationID string, keepRunning bool) error {
+	old, err := s.getGameServerObject(ctx, namespace, from)
+	if err != nil {
+		return err
+	}
+	next, err := s.getGameServerObject(ctx, namespace, to)
+	if err != nil {
+		return err
+	}
+
+	host, _, _ := unstructured.NestedString(old.Object, "spec", "networking", "ingressHost")
+	cluster := old.GetLabels()[serverClusterLabel]
+	role := old.GetAnnotations()[serverClusterRoleAnnotation]
+	now := time.Now().UTC()
+
+	// Release blue's ingress host and membership first so the two never
+	// claim them at once
+	unstructured.RemoveNestedField(old.Object, "spec", "networking", "ingressHost")
+	if !keepRunning {
+		_ = setStopped(old, true)
+	}
+	labels, annotations := old.GetLabels(), old.GetAnnotations()
+	delete(labels, serverClusterLabel)
+	delete(annotations, serverClusterRoleAnnotation)
+	old.SetLabels(labels)
+	old.SetAnnotations(annotations)
+	if err := setBlueGreenState(old, &BlueGreenState{Role: blueGreenStandby, Peer: to, Operation: operationID, SwitchedAt: &now}); err != nil {
+		return err
+	}
+	if err := s.k8sClient.Update(ctx, old); err != nil {
+		return fmt.Errorf("failed to update %s: %w", from, err)
+	}
+
+	if host != "" {
+		if err := unstructured.SetNestedField(next.Object, host, "spec", "networking", "ingressHost"); err != nil {
+			return err
+		}
+	}
+	if cluster != "" {
+		labels := next.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[serverClusterLabel] = cluster
+		next.SetLabels(labels)
+		annotations := next.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[serverClusterRoleAnnotation] = role
+		next.SetAnnotations(annotations)
+	}
+	if err := setBlueGreenState(next, &BlueGreenState{Role: blueGreenActive, Peer: from, Operation: operationID, SwitchedAt: &now}); err != nil {
+		return err
+	}
+	if err := s.k8sClient.Update(ctx, next); err != nil {
+		return fmt.Errorf("failed to update %s: %w", to, err)
+	}
+
+	if err := s.repointProxyBackends(ctx, namespace, from, to); err != nil {
+		return fmt.Errorf("failed to repoint proxies: %w", err)
+	}
+	if cluster != "" {
+		if _, err := s.linkServerCluster(ctx, namespace, cluster); err != nil {
+			log.Printf("Failed to relink cluster of servers %s/%s: %v", namespace, cluster, err)
+		}
+	}
+	return nil
+}
+
+// repointProxyBackends replaces a backend in every proxy of a namespace
+func (s *Server) repointProxyBackends(ctx context.Context, namespace, from, to string) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		proxy, err := gameServerProxy(obj)
+		if err != nil || proxy == nil {
+			continue
+		}
+		changed := false
+		for j := range proxy.Backends {
+			if proxy.Backends[j].Name == from {
+				proxy.Backends[j].Name = to
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		raw, err := proxySpec(proxy)
+		if err != nil {
+			return err
+		}
+		spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+		spec = runtime.DeepCopyJSON(spec)
+		spec["proxy"] = raw
+		if _, err := s.writeGameServerSpec(ctx, obj, spec); err != nil {
+			return err
+		}
+		if err := s.syncProxyRoutes(ctx, obj); err != nil {
+			log.Printf("Failed to sync routes of proxy %s/%s: %v", namespace, obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// rollbackGameServer switches players back from the active instance of a
+// blue/green upgrade to its standby, starting the standby first if needed
+func (s *Server) rollbackGameServer(c *gin.Context) {
+	namespace := c.Param("namespace")
+	if !s.checkMaintenance(c, namespace) {
+		return
+	}
+	active, standby, ok := s.blueGreenPair(c)
+	if !ok {
+		return
+	}
+
+	steps := []string{blueGreenStepStart, blueGreenStepSwitch}
+	op := s.startOperation("upgrade-rollback", namespace, active.GetName(), steps, upgradeTimeout, func(ctx context.Context, t *operationTracker) (interface{}, error) {
+		result := gin.H{"active": standby.GetName(), "standby": active.GetName()}
+		err := t.step(blueGreenStepStart, func() (string, error) {
+			if stopped, _, _ := unstructured.NestedBool(standby.Object, "spec", "stopped"); stopped {
+				_ = setStopped(standby, false)
+				if err := s.k8sClient.Update(ctx, standby); err != nil {
+					return "", err
+				}
+			}
+			if _, err := s.waitForRolloutReady(ctx, namespace, standby.GetName(), "", defaultRolloutReadyTimeout); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s is ready", standby.GetName()), nil
+		})
+		if err != nil {
+			return result, err
+		}
+		err = t.step(blueGreenStepSwitch, func() (string, error) {
+			if err := s.switchBlueGreen(ctx, namespace, active.GetName(), standby.GetName(), t.op.ID, false); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Players are back on %s; %s is kept as the standby", standby.GetName(), active.GetName()), nil
+		})
+		return result, err
+	})
+	c.JSON(http.StatusAccepted, op)
+}
+
+// finalizeUpgrade deletes the standby of a blue/green upgrade once the new
+// instance is trusted
+func (s *Server) finalizeUpgrade(c *gin.Context) {
+	active, standby, ok := s.blueGreenPair(c)
+	if !ok {
+		return
+	}
+	if err := s.k8sClient.Delete(context.TODO(), standby); err != nil && !apierrors.IsNotFound(err) {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete standby %s: %v", standby.GetName(), err),
+		})
+		return
+	}
+	_ = setBlueGreenState(active, nil)
+	if err := s.k8sClient.Update(context.TODO(), active); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update GameServer: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Upgrade finalized; standby %s deleted", standby.GetName()),
+	})
+}
+
+// blueGreenPair loads the active instance named in the route and its
+// standby, writing the error response itself when there is no such pair
+func (s *Server) blueGreenPair(c *gin.Context) (*unstructured.Unstructured, *unstructured.Unstructured, bool) {
+	active, ok := s.upgradableGameServer(c)
+	if !ok {
+		return nil, nil, false
+	}
+	state := blueGreenState(active)
+	if state == nil || state.Role != blueGreenActive {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "GameServer is not the active instance of a blue/green upgrade",
+		})
+		return nil, nil, false
+	}
+	standby, err := s.getGameServerObject(context.TODO(), active.GetNamespace(), state.Peer)
+	if apierrors.IsNotFound(err) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("Standby %s no longer exists", state.Peer),
+		})
+		return nil, nil, false
+	}
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get standby: %v", err),
+		})
+		return nil, nil, false
+	}
+	return active, standby, true
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/bootstrap"
+)
+
+// bootstrapPlatform installs the XRDs, Compositions, functions, provider and
+// RBAC GamePlane needs, or only reports what is missing with verify. The
+// manifests are read from MANIFESTS_DIR, the repository's crossplane/
+// directory.
+func (s *Server) bootstrapPlatform(c *gin.Context) {
+	var req struct {
+		Verify bool `json:"verify"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid request body: %v", err),
+			})
+			return
+		}
+	}
+	report, err := bootstrap.Run(context.TODO(), s.k8sClient, bootstrap.Options{
+		ManifestsDir: os.Getenv("MANIFESTS_DIR"),
+		Verify:       req.Verify,
+	})
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to bootstrap: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
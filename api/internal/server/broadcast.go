@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// maxBroadcastLength keeps announcements within what game consoles accept
+const maxBroadcastLength = 256
+
+// broadcastGameServer sends an announcement to every online player
+func (s *Server) broadcastGameServer(c *gin.Context) {
+	var req struct {
+		Message string `json:"message" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if utf8.RuneCountInString(req.Message) > maxBroadcastLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Message must be at most %d characters", maxBroadcastLength),
+		})
+		return
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	output, err := s.broadcastInGame(context.TODO(), obj, req.Message)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, errConsoleUnsupported) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error": fmt.Sprintf("Failed to broadcast message: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Broadcast sent",
+		"output":  output,
+	})
+}
+
+// broadcastInGame sends a message to all players through the game console
+// and returns the console's reply. Scheduled jobs call it directly.
+func (s *Server) broadcastInGame(ctx context.Context, obj *unstructured.Unstructured, message string) (string, error) {
+	console, err := s.openGameConsole(ctx, obj)
+	if err != nil {
+		return "", err
+	}
+	defer console.Close()
+
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, _ := lookupGame(gameType)
+	return console.Exec(sayCommand(def.Console, message))
+}
@@ -0,0 +1,233 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// maxMOTDLength matches the spec.motd schema
+	maxMOTDLength = 256
+
+	// maxIconLength matches the spec.icon schema; it fits a 64x64 PNG as a
+	// data URI
+	maxIconLength = 32768
+
+	// iconDataPrefix starts an inline icon
+	iconDataPrefix = "data:image/png;base64,"
+
+	// publicStatusCacheTTL is how long public status answers are reused,
+	// since server browsers poll them
+	publicStatusCacheTTL = 15 * time.Second
+)
+
+// How a MOTD change took effect
+const (
+	motdAppliedLive    = "live"
+	motdAppliedRestart = "restart"
+	motdAppliedStored  = "stored"
+)
+
+// PublicStatus is what the public status API tells players about a server
+type PublicStatus struct {
+	Name        string `json:"name"`
+	Game        string `json:"game"`
+	GameType    string `json:"gameType"`
+	Description string `json:"description,omitempty"`
+	MOTD        string `json:"motd,omitempty"`
+	Icon        string `json:"icon,omitempty"`
+	Online      bool   `json:"online"`
+	Players     int    `json:"players"`
+	MaxPlayers  int    `json:"maxPlayers,omitempty"`
+	Endpoint    string `json:"endpoint,omitempty"`
+}
+
+// validateBrowserMetadata checks the MOTD and icon of a spec
+func validateBrowserMetadata(spec GameServerSpec) error {
+	if utf8.RuneCountInString(spec.MOTD) > maxMOTDLength {
+		return fmt.Errorf("spec.motd must be at most %d characters", maxMOTDLength)
+	}
+	return validateServerIcon(spec.Icon)
+}
+
+// validateServerIcon accepts an https URL or an inline PNG
+func validateServerIcon(icon string) error {
+	if icon == "" {
+		return nil
+	}
+	if len(icon) > maxIconLength {
+		return fmt.Errorf("spec.icon must be at most %d bytes", maxIconLength)
+	}
+	if strings.HasPrefix(icon, iconDataPrefix) {
+		return nil
+	}
+	if u, err := url.Parse(icon); err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("spec.icon must be an https URL or start with %s", iconDataPrefix)
+	}
+	return nil
+}
+
+// putGameServerMOTD changes the message of the day. Games that can change
+// it while running get it through the console right away; for the others
+// it is rendered into the config file and waits for a restart.
+func (s *Server) putGameServerMOTD(c *gin.Context) {
+	var req struct {
+		MOTD *string `json:"motd" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	motd := strings.TrimSpace(*req.MOTD)
+	if utf8.RuneCountInString(motd) > maxMOTDLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("MOTD must be at most %d characters", maxMOTDLength),
+		})
+		return
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, _ := lookupGame(gameType)
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	if motd == "" {
+		delete(spec, "motd")
+	} else {
+		spec["motd"] = motd
+	}
+
+	// A MOTD taken by the running server is rendered from the spec on the
+	// next start, so it does not wait for a restart
+	response := gin.H{"motd": motd}
+	live := false
+	if def.Console != nil && def.Console.MOTDCommand != "" {
+		output, err := s.setMOTDInGame(context.TODO(), obj, motd)
+		if err == nil {
+			live = true
+			response["applied"] = motdAppliedLive
+			response["output"] = output
+		} else {
+			response["liveError"] = fmt.Sprintf("Failed to apply MOTD in game: %v", err)
+		}
+	}
+
+	if live {
+		obj.Object["spec"] = spec
+		if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to update GameServer: %v", err),
+			})
+			return
+		}
+	} else {
+		restartFields, err := s.writeGameServerSpec(context.TODO(), obj, spec)
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to update GameServer: %v", err),
+			})
+			return
+		}
+		response["applied"] = motdAppliedStored
+		if len(restartFields) > 0 {
+			response["applied"] = motdAppliedRestart
+			response["restartFields"] = restartFields
+		}
+	}
+
+	s.publishEvent(eventGameServerUpdated, obj.GetNamespace(), obj.GetName(), map[string]interface{}{
+		"motd":    motd,
+		"applied": response["applied"],
+	})
+	c.JSON(http.StatusOK, response)
+}
+
+// setMOTDInGame changes the MOTD of the running server through its console
+func (s *Server) setMOTDInGame(ctx context.Context, obj *unstructured.Unstructured, motd string) (string, error) {
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, ok := lookupGame(gameType)
+	if !ok || def.Console == nil || def.Console.MOTDCommand == "" {
+		return "", errConsoleUnsupported
+	}
+
+	console, err := s.openGameConsole(ctx, obj)
+	if err != nil {
+		return "", err
+	}
+	defer console.Close()
+	return console.Exec(fmt.Sprintf(def.Console.MOTDCommand, consoleText(motd)))
+}
+
+// getPublicStatus serves the server browser view of a GameServer without
+// credentials. Servers that did not set spec.publicStatus are reported as
+// not found, so their existence is not revealed either.
+func (s *Server) getPublicStatus(c *gin.Context) {
+	obj, err := s.getGameServerObject(context.TODO(), c.Param("namespace"), c.Param("name"))
+	if err != nil && client.IgnoreNotFound(err) != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Status is unavailable",
+		})
+		return
+	}
+	public := false
+	if err == nil {
+		public, _, _ = unstructured.NestedBool(obj.Object, "spec", "publicStatus")
+	}
+	if !public {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "GameServer not found",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, publicStatus(obj))
+}
+
+// publicStatus picks the player-facing details of a GameServer
+func publicStatus(obj *unstructured.Unstructured) PublicStatus {
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	gameType, _, _ := unstructured.NestedString(spec, "gameType")
+	status := PublicStatus{Name: obj.GetName(), GameType: gameType}
+	if name, _, _ := unstructured.NestedString(spec, "serverName"); name != "" {
+		status.Name = name
+	}
+	status.Description, _, _ = unstructured.NestedString(spec, "serverDescription")
+	status.MOTD, _, _ = unstructured.NestedString(spec, "motd")
+	status.Icon, _, _ = unstructured.NestedString(spec, "icon")
+
+	def, ok := lookupGame(gameType)
+	if ok {
+		status.Game = def.DisplayName
+		maxPlayers, found, _ := unstructured.NestedFieldNoCopy(spec, "gameConfig", "server", "maxPlayers")
+		if !found {
+			if field, ok := def.Field("server.maxPlayers"); ok {
+				maxPlayers = field.Default
+			}
+		}
+		if n, ok := toFloat(maxPlayers); ok {
+			status.MaxPlayers = int(n)
+		}
+	}
+
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	stopped, _, _ := unstructured.NestedBool(spec, "stopped")
+	status.Online = phase == "Running" && !stopped
+	if status.Online {
+		players, _, _ := unstructured.NestedInt64(obj.Object, "status", "playersOnline")
+		status.Players = int(players)
+		status.Endpoint, _, _ = unstructured.NestedString(obj.Object, "status", "serverEndpoint")
+	}
+	return status
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/kubelize/gameplane/api/internal/catalog"
+)
+
+// The game catalog lives in internal/catalog so the admission webhook
+// validates claims against the same schemas
+type (
+	GameDefinition = catalog.GameDefinition
+	ConfigField    = catalog.ConfigField
+	ConfigFile     = catalog.ConfigFile
+	ConsoleInfo    = catalog.ConsoleInfo
+	LiveSetting    = catalog.LiveSetting
+	AdminList      = catalog.AdminList
+	PlayerList     = catalog.PlayerList
+	WipeInfo       = catalog.WipeInfo
+	WorldInfo      = catalog.WorldInfo
+)
+
+// restartRequiredSpecFields lists top-level spec fields that are rendered
+// into the game's config file. Resource and scheduling changes alter the pod
+// template and are rolled out by Kubernetes on its own, so they are not listed.
+var restartRequiredSpecFields = map[string]bool{
+	"spec.serverName":        true,
+	"spec.serverDescription": true,
+}
+
+// lookupGame returns the catalog definition for a game type
+func lookupGame(gameType string) (GameDefinition, bool) {
+	return catalog.Lookup(gameType)
+}
+
+// supportedGameTypes returns the sorted list of game types in the catalog
+func supportedGameTypes() []string {
+	return catalog.SupportedTypes()
+}
+
+// validateConfigValue checks a value against a field's type, range and enum
+func validateConfigValue(field ConfigField, value interface{}) error {
+	return catalog.ValidateValue(field, value)
+}
+
+// configValuesEqual compares config values, treating all numeric types alike
+func configValuesEqual(a, b interface{}) bool {
+	return catalog.ValuesEqual(a, b)
+}
+
+// toFloat converts any numeric config value to float64
+func toFloat(value interface{}) (float64, bool) {
+	return catalog.ToFloat(value)
+}
+
+// restartRequiredFields filters a set of changes down to the fields that only
+// take effect after a pod restart. Unknown gameConfig keys are treated as
+// requiring a restart since they end up in the same config file.
+func restartRequiredFields(gameType string, changes []SpecChange) []string {
+	def, _ := lookupGame(gameType)
+
+	fields := []string{}
+	for _, change := range changes {
+		if restartRequiredSpecFields[change.Path] || renderedSpecField(def, change.Path) {
+			fields = append(fields, change.Path)
+			continue
+		}
+		if !strings.HasPrefix(change.Path, "spec.gameConfig.") {
+			continue
+		}
+		configPath := strings.TrimPrefix(change.Path, "spec.gameConfig.")
+		if f, ok := def.Field(configPath); !ok || f.RestartRequired {
+			fields = append(fields, change.Path)
+		}
+	}
+	return fields
+}
+
+// renderedSpecField reports whether a spec path is written into the game's
+// config file, such as spec.motd for games with a MOTD setting
+func renderedSpecField(def GameDefinition, path string) bool {
+	field, ok := strings.CutPrefix(path, "spec.")
+	if !ok || def.ConfigFile == nil {
+		return false
+	}
+	_, ok = def.ConfigFile.SpecKeys[field]
+	return ok
+}
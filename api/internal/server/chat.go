@@ -0,0 +1,402 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// chatRelayAnnotation holds a GameServer's chatRelayConfig as JSON
+	chatRelayAnnotation = "gameplane.kubelize.io/chat-relay"
+
+	// chatRelayTokenHeader authenticates messages relayed back in-game
+	chatRelayTokenHeader = "X-Gameplane-Relay-Token"
+
+	// chatRelayInterval is how often opted-in servers are polled for new chat
+	chatRelayInterval = 15 * time.Second
+
+	// nonPlayerID is the sender id 7 Days to Die logs for console messages
+	nonPlayerID = "-non-player-"
+)
+
+// errChatUnsupported is returned for games whose logs carry no chat lines
+var errChatUnsupported = errors.New("game type does not log chat messages")
+
+// ChatMessage is a single in-game chat line
+type ChatMessage struct {
+	Time     time.Time `json:"time"`
+	Player   string    `json:"player"`
+	PlayerID string    `json:"playerId,omitempty"`
+	Channel  string    `json:"channel,omitempty"`
+	Message  string    `json:"message"`
+}
+
+// chatRelayConfig is the per-server chat relay opt-in
+type chatRelayConfig struct {
+	Enabled bool           `json:"enabled"`
+	Webhook *webhookTarget `json:"webhook,omitempty"`
+	// InGame accepts messages posted to the inbound endpoint and says them in-game
+	InGame bool   `json:"inGame"`
+	Token  string `json:"token,omitempty"`
+}
+
+// chatRelayCursors tracks the last relayed message per GameServer
+type chatRelayCursors struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// getGameServerChat returns chat messages parsed from the game server log
+func (s *Server) getGameServerChat(c *gin.Context) {
+	since, err := parseSince(c.DefaultQuery("since", "1h"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	messages, err := s.readChatMessages(context.TODO(), obj, since)
+	if err != nil {
+		status := errorStatus(c, err)
+		if errors.Is(err, errChatUnsupported) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error": fmt.Sprintf("Failed to read chat: %v", err),
+		})
+		return
+	}
+	if len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": messages,
+		"total":    len(messages),
+		"since":    since,
+	})
+}
+
+// getChatRelay returns the GameServer's chat relay settings
+func (s *Server) getChatRelay(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	relay, err := chatRelaySettings(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, relay)
+}
+
+// putChatRelay stores the GameServer's chat relay settings
+func (s *Server) putChatRelay(c *gin.Context) {
+	var req chatRelayConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if req.Webhook != nil {
+		if err := validateWebhookTarget(*req.Webhook); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+	if req.Enabled && req.Webhook == nil && !req.InGame {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "An enabled chat relay needs a webhook or inGame",
+		})
+		return
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	// The inbound token is server generated and kept across updates
+	current, _ := chatRelaySettings(obj)
+	req.Token = current.Token
+	if req.InGame && req.Token == "" {
+		token := make([]byte, 24)
+		if _, err := rand.Read(token); err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to generate relay token: %v", err),
+			})
+			return
+		}
+		req.Token = hex.EncodeToString(token)
+	}
+
+	raw, err := json.Marshal(req)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[chatRelayAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update chat relay: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// postChatInbound says a message from an external channel in-game
+func (s *Server) postChatInbound(c *gin.Context) {
+	var req struct {
+		Author  string `json:"author" binding:"required"`
+		Message string `json:"message" binding:"required"`
+		Source  string `json:"source"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if req.Source == "" {
+		req.Source = "Discord"
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	relay, _ := chatRelaySettings(obj)
+	token := c.GetHeader(chatRelayTokenHeader)
+	if !relay.Enabled || !relay.InGame || relay.Token == "" ||
+		subtle.ConstantTimeCompare([]byte(token), []byte(relay.Token)) != 1 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "In-game chat relay is not enabled or the relay token is invalid",
+		})
+		return
+	}
+
+	if _, err := s.broadcastInGame(context.TODO(), obj, fmt.Sprintf("[%s] %s: %s", req.Source, req.Author, req.Message)); err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, errConsoleUnsupported) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error": fmt.Sprintf("Failed to relay message: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Message relayed in-game",
+	})
+}
+
+// readChatMessages parses chat lines logged by the game server since a time
+func (s *Server) readChatMessages(ctx context.Context, obj *unstructured.Unstructured, since time.Time) ([]ChatMessage, error) {
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, ok := lookupGame(gameType)
+	if !ok || def.ChatPattern == nil {
+		return nil, errChatUnsupported
+	}
+
+	pods, namespace, err := s.findGameServerPods(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	var pod *corev1.Pod
+	for i := range pods {
+		if pods[i].Status.Phase == corev1.PodRunning {
+			pod = &pods[i]
+			break
+		}
+	}
+	if pod == nil {
+		return nil, fmt.Errorf("no running pod found for GameServer in namespace %s", namespace)
+	}
+
+	opts := &corev1.PodLogOptions{
+		Container:  pod.Spec.Containers[0].Name,
+		Timestamps: true,
+		SinceTime:  &metav1.Time{Time: since},
+	}
+	stream, err := s.kubeClient.CoreV1().Pods(namespace).GetLogs(pod.Name, opts).Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	pattern := def.ChatPattern
+	messages := []ChatMessage{}
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Lines are prefixed with the kubelet's RFC3339 timestamp
+		stamp, text, found := strings.Cut(line, " ")
+		if !found {
+			continue
+		}
+		match := pattern.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+		msg := ChatMessage{}
+		msg.Time, _ = time.Parse(time.RFC3339Nano, stamp)
+		for i, group := range pattern.SubexpNames() {
+			switch group {
+			case "player":
+				msg.Player = match[i]
+			case "playerId":
+				msg.PlayerID = match[i]
+			case "channel":
+				msg.Channel = match[i]
+			case "message":
+				msg.Message = match[i]
+			}
+		}
+		messages = append(messages, msg)
+	}
+	return messages, scanner.Err()
+}
+
+// relayChat posts new chat from opted-in GameServers to their webhooks
+func (s *Server) relayChat(ctx context.Context) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+
+	s.chatRelay.mu.Lock()
+	defer s.chatRelay.mu.Unlock()
+	if s.chatRelay.last == nil {
+		s.chatRelay.last = map[string]time.Time{}
+	}
+
+	active := map[string]bool{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		relay, err := chatRelaySettings(obj)
+		if err != nil || !relay.Enabled || relay.Webhook == nil {
+			continue
+		}
+		key := obj.GetNamespace() + "/" + obj.GetName()
+		active[key] = true
+
+		// Start from now the first time a server is seen so restarts of the
+		// API do not replay old chat
+		cursor, seen := s.chatRelay.last[key]
+		if !seen {
+			s.chatRelay.last[key] = time.Now()
+			continue
+		}
+
+		messages, err := s.readChatMessages(ctx, obj, cursor)
+		if err != nil {
+			log.Printf("Chat relay for %s failed: %v", key, err)
+			continue
+		}
+		for _, msg := range messages {
+			if !msg.Time.After(cursor) {
+				continue
+			}
+			// Skip console messages, which include chat relayed in-game
+			if msg.PlayerID != nonPlayerID {
+				event := notificationEvent{
+					Type:      "chat",
+					Namespace: obj.GetNamespace(),
+					Server:    obj.GetName(),
+					Title:     "Chat",
+					Message:   fmt.Sprintf("**%s**: %s", msg.Player, msg.Message),
+					Time:      msg.Time,
+					Username:  obj.GetName(),
+				}
+				if err := postWebhook(ctx, *relay.Webhook, event); err != nil {
+					log.Printf("Chat relay for %s failed: %v", key, err)
+					break
+				}
+			}
+			s.chatRelay.last[key] = msg.Time
+		}
+	}
+
+	for key := range s.chatRelay.last {
+		if !active[key] {
+			delete(s.chatRelay.last, key)
+		}
+	}
+	return nil
+}
+
+// chatRelaySettings reads the chat relay annotation
+func chatRelaySettings(obj *unstructured.Unstructured) (chatRelayConfig, error) {
+	relay := chatRelayConfig{}
+	raw, ok := obj.GetAnnotations()[chatRelayAnnotation]
+	if !ok {
+		return relay, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &relay); err != nil {
+		return chatRelayConfig{}, fmt.Errorf("invalid %s annotation: %w", chatRelayAnnotation, err)
+	}
+	return relay, nil
+}
+
+// parseSince accepts an RFC3339 timestamp or a duration before now
+func parseSince(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("invalid since %q: use an RFC3339 time or a duration like 30m", value)
+	}
+	return time.Now().Add(-d), nil
+}
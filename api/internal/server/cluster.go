@@ -0,0 +1,287 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// listNamespaces returns the namespaces the caller may read
+func (s *Server) listNamespaces(c *gin.Context) {
+	namespaces, err := s.kubeClient.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list namespaces: %v", err),
+		})
+		return
+	}
+
+	scope, err := s.readableNamespaces(c)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to determine readable namespaces: %v", err),
+		})
+		return
+	}
+
+	// Filter to the namespaces the caller may read
+	result := make([]string, 0, len(namespaces.Items))
+	for _, ns := range namespaces.Items {
+		if scope.allows(ns.Name) {
+			result = append(result, ns.Name)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"namespaces": result,
+	})
+}
+
+// clusterInfoTTL is how long cluster details are served before they are
+// read again in the background
+const clusterInfoTTL = 5 * time.Minute
+
+// ClusterDetails describes a cluster's capacity, nodes and the components
+// GameServers rely on
+type ClusterDetails struct {
+	Version   string `json:"version"`
+	NodeCount int    `json:"nodeCount"`
+	Platform  string `json:"platform"`
+
+	Capacity    ClusterResources `json:"capacity"`
+	Allocatable ClusterResources `json:"allocatable"`
+	Nodes       []ClusterNode    `json:"nodes"`
+
+	StorageClasses  []StorageClassSummary  `json:"storageClasses"`
+	SnapshotClasses []SnapshotClassSummary `json:"snapshotClasses"`
+	// MetricsServer reports whether metrics.k8s.io is served, which CPU
+	// and memory usage depend on
+	MetricsServer bool           `json:"metricsServer"`
+	Crossplane    CrossplaneInfo `json:"crossplane"`
+
+	RefreshedAt time.Time `json:"refreshedAt"`
+}
+
+// ClusterResources totals the resources of every node
+type ClusterResources struct {
+	CPU     string `json:"cpu"`
+	Memory  string `json:"memory"`
+	Storage string `json:"ephemeralStorage"`
+}
+
+// ClusterNode is a node's platform and versions
+type ClusterNode struct {
+	Name             string `json:"name"`
+	Ready            bool   `json:"ready"`
+	OS               string `json:"os"`
+	OSImage          string `json:"osImage"`
+	Architecture     string `json:"architecture"`
+	KubeletVersion   string `json:"kubeletVersion"`
+	ContainerRuntime string `json:"containerRuntime"`
+}
+
+// CrossplaneInfo gives the versions of Crossplane and its packages
+type CrossplaneInfo struct {
+	Installed bool                `json:"installed"`
+	Version   string              `json:"version,omitempty"`
+	Providers []CrossplanePackage `json:"providers"`
+	Functions []CrossplanePackage `json:"functions"`
+}
+
+// CrossplanePackage is an installed Provider or Function
+type CrossplanePackage struct {
+	Name    string `json:"name"`
+	Package string `json:"package"`
+	Version string `json:"version"`
+	Healthy bool   `json:"healthy"`
+}
+
+// clusterInfoCache keeps a cluster's details, which take a dozen calls to
+// gather
+type clusterInfoCache struct {
+	mu         sync.Mutex
+	details    *ClusterDetails
+	refreshing bool
+}
+
+// getClusterInfo returns the cluster's version, capacity, nodes and
+// components. Details older than clusterInfoTTL are served while they are
+// read again in the background.
+func (s *Server) getClusterInfo(c *gin.Context) {
+	cache := s.clusterInfo
+	cache.mu.Lock()
+	details := cache.details
+	if details != nil && time.Since(details.RefreshedAt) >= clusterInfoTTL && !cache.refreshing {
+		cache.refreshing = true
+		go func() {
+			refreshed, err := s.readClusterDetails(context.Background())
+			cache.mu.Lock()
+			defer cache.mu.Unlock()
+			cache.refreshing = false
+			if err != nil {
+				log.Printf("Failed to refresh cluster info: %v", err)
+				return
+			}
+			cache.details = refreshed
+		}()
+	}
+	cache.mu.Unlock()
+
+	if details == nil {
+		var err error
+		if details, err = s.readClusterDetails(c.Request.Context()); err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to read cluster info: %v", err),
+			})
+			return
+		}
+		cache.mu.Lock()
+		cache.details = details
+		cache.mu.Unlock()
+	}
+	c.JSON(http.StatusOK, details)
+}
+
+// readClusterDetails gathers the cluster's details. Only the version and
+// nodes are required; missing optional components are reported as absent.
+func (s *Server) readClusterDetails(ctx context.Context) (*ClusterDetails, error) {
+	version, err := s.kubeClient.Discovery().ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster version: %w", err)
+	}
+	nodes, err := s.kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes: %w", err)
+	}
+
+	details := &ClusterDetails{
+		Version:         version.String(),
+		NodeCount:       len(nodes.Items),
+		Platform:        version.Platform,
+		Nodes:           make([]ClusterNode, 0, len(nodes.Items)),
+		StorageClasses:  []StorageClassSummary{},
+		SnapshotClasses: []SnapshotClassSummary{},
+		RefreshedAt:     time.Now().UTC(),
+	}
+	capacity, allocatable := corev1.ResourceList{}, corev1.ResourceList{}
+	for _, node := range nodes.Items {
+		for name, quantity := range node.Status.Capacity {
+			total := capacity[name]
+			total.Add(quantity)
+			capacity[name] = total
+		}
+		for name, quantity := range node.Status.Allocatable {
+			total := allocatable[name]
+			total.Add(quantity)
+			allocatable[name] = total
+		}
+		info := node.Status.NodeInfo
+		details.Nodes = append(details.Nodes, ClusterNode{
+			Name:             node.Name,
+			Ready:            nodeReady(&node),
+			OS:               info.OperatingSystem,
+			OSImage:          info.OSImage,
+			Architecture:     info.Architecture,
+			KubeletVersion:   info.KubeletVersion,
+			ContainerRuntime: info.ContainerRuntimeVersion,
+		})
+	}
+	details.Capacity = clusterResources(capacity)
+	details.Allocatable = clusterResources(allocatable)
+
+	if snapshotClasses, err := s.listSnapshotClasses(ctx); err == nil {
+		details.SnapshotClasses = snapshotClasses
+	}
+	if classes, err := s.listStorageClasses(ctx, details.SnapshotClasses); err == nil {
+		details.StorageClasses = classes
+	}
+	if _, err := s.kubeClient.Discovery().ServerResourcesForGroupVersion("metrics.k8s.io/v1beta1"); err == nil {
+		details.MetricsServer = true
+	}
+	details.Crossplane = s.readCrossplaneInfo(ctx)
+	return details, nil
+}
+
+// nodeReady reports whether a node's Ready condition is True
+func nodeReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// clusterResources renders summed node resources
+func clusterResources(list corev1.ResourceList) ClusterResources {
+	return ClusterResources{
+		CPU:     formatMillicores(list.Cpu().MilliValue()),
+		Memory:  formatMemoryBytes(list.Memory().Value()),
+		Storage: formatMemoryBytes(list.StorageEphemeral().Value()),
+	}
+}
+
+// readCrossplaneInfo reads the Crossplane version from its deployment's
+// image and the versions of installed packages
+func (s *Server) readCrossplaneInfo(ctx context.Context) CrossplaneInfo {
+	info := CrossplaneInfo{Providers: []CrossplanePackage{}, Functions: []CrossplanePackage{}}
+	deployment, err := s.kubeClient.AppsV1().Deployments(crossplaneNamespace()).Get(ctx, "crossplane", metav1.GetOptions{})
+	if err == nil {
+		info.Installed = true
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			if container.Name == "crossplane" || info.Version == "" {
+				info.Version = imageTag(container.Image)
+			}
+		}
+	}
+	for _, kind := range []string{"Provider", "Function"} {
+		version := "v1"
+		if kind == "Function" {
+			version = "v1beta1"
+		}
+		items, err := s.listPlatform(ctx, schema.GroupVersionKind{Group: "pkg.crossplane.io", Version: version, Kind: kind + "List"})
+		if err != nil {
+			continue
+		}
+		for i := range items {
+			pkg, _, _ := unstructured.NestedString(items[i].Object, "spec", "package")
+			current, _, _ := unstructured.NestedString(items[i].Object, "status", "currentIdentifier")
+			if current == "" {
+				current = pkg
+			}
+			entry := CrossplanePackage{
+				Name:    items[i].GetName(),
+				Package: pkg,
+				Version: imageTag(current),
+				Healthy: conditionTrue(&items[i], "Installed") && conditionTrue(&items[i], "Healthy"),
+			}
+			if kind == "Provider" {
+				info.Providers = append(info.Providers, entry)
+			} else {
+				info.Functions = append(info.Functions, entry)
+			}
+		}
+	}
+	return info
+}
+
+// imageTag returns the tag or digest of an image reference
+func imageTag(image string) string {
+	if i := strings.LastIndex(image, "@"); i >= 0 {
+		return image[i+1:]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[i+1:]
+	}
+	return ""
+}
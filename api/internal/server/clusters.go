@@ -0,0 +1,336 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/k8s"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// clusterSecretLabel marks kubeconfig secrets of registered clusters. Its
+	// value names the cluster; the secret name is used when it is empty.
+	clusterSecretLabel = "gameplane.kubelize.io/cluster"
+
+	// clusterKubeconfigKey is the secret key holding the kubeconfig
+	clusterKubeconfigKey = "kubeconfig"
+
+	// clusterRegistryInterval is how often registry secrets are re-read
+	clusterRegistryInterval = time.Minute
+
+	// defaultLocalClusterName names the cluster the API runs in
+	defaultLocalClusterName = "local"
+
+	// defaultClusterRegistryNamespace holds the kubeconfig secrets
+	defaultClusterRegistryNamespace = "gameplane-system"
+)
+
+// ClusterInfo describes a registered cluster
+type ClusterInfo struct {
+	Name      string            `json:"name"`
+	Local     bool              `json:"local"`
+	Host      string            `json:"host,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Region    string            `json:"region,omitempty"`
+	Version   string            `json:"version,omitempty"`
+	Reachable bool              `json:"reachable"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// registeredCluster is a cluster and the Server scoped to its clients
+type registeredCluster struct {
+	name            string
+	local           bool
+	host            string
+	labels          map[string]string
+	region          string
+	pingEndpoints   []string
+	server          *Server
+	resourceVersion string
+	err             error
+}
+
+// clusterRegistry holds the clusters the API manages. The local cluster is
+// always present; remote clusters come from kubeconfig secrets.
+type clusterRegistry struct {
+	mu        sync.RWMutex
+	local     string
+	namespace string
+	clusters  map[string]*registeredCluster
+}
+
+// newClusterRegistry creates a registry containing only the local cluster.
+// Empty names fall back to CLUSTER_NAME and CLUSTER_REGISTRY_NAMESPACE.
+func newClusterRegistry(local *Server, config *rest.Config, name, namespace string) *clusterRegistry {
+	if name == "" {
+		name = os.Getenv("CLUSTER_NAME")
+	}
+	if name == "" {
+		name = defaultLocalClusterName
+	}
+	if namespace == "" {
+		namespace = os.Getenv("CLUSTER_REGISTRY_NAMESPACE")
+	}
+	if namespace == "" {
+		namespace = defaultClusterRegistryNamespace
+	}
+
+	local.cluster = name
+	region, pingEndpoints := localClusterRegion()
+	return &clusterRegistry{
+		local:     name,
+		namespace: namespace,
+		clusters: map[string]*registeredCluster{
+			name: {
+				name:          name,
+				local:         true,
+				host:          config.Host,
+				region:        region,
+				pingEndpoints: pingEndpoints,
+				server:        local,
+			},
+		},
+	}
+}
+
+// clusterServer returns the Server for a cluster, or the local one when the
+// name is empty
+func (r *clusterRegistry) clusterServer(name string) (*Server, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if name == "" {
+		name = r.local
+	}
+	cluster, ok := r.clusters[name]
+	if !ok {
+		return nil, fmt.Errorf("cluster %q is not registered", name)
+	}
+	if cluster.server == nil {
+		return nil, fmt.Errorf("cluster %q is unavailable: %v", name, cluster.err)
+	}
+	return cluster.server, nil
+}
+
+// servers returns the Servers of every usable cluster, local first
+func (r *clusterRegistry) servers() []*Server {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	servers := []*Server{r.clusters[r.local].server}
+	for _, name := range r.sortedNames() {
+		if cluster := r.clusters[name]; !cluster.local && cluster.server != nil {
+			servers = append(servers, cluster.server)
+		}
+	}
+	return servers
+}
+
+// sortedNames returns cluster names in order; callers hold the lock
+func (r *clusterRegistry) sortedNames() []string {
+	names := make([]string, 0, len(r.clusters))
+	for name := range r.clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// refresh syncs remote clusters with the kubeconfig secrets, only rebuilding
+// clients for secrets that changed
+func (r *clusterRegistry) refresh(ctx context.Context) error {
+	r.mu.RLock()
+	local := r.clusters[r.local].server
+	versions := map[string]string{}
+	for name, cluster := range r.clusters {
+		versions[name] = cluster.resourceVersion
+	}
+	r.mu.RUnlock()
+
+	secrets, err := local.kubeClient.CoreV1().Secrets(r.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: clusterSecretLabel,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list cluster secrets: %w", err)
+	}
+
+	// Build clients for new and changed secrets before taking the write lock
+	seen := map[string]bool{r.local: true}
+	changed := []*registeredCluster{}
+	for _, secret := range secrets.Items {
+		name := secret.Labels[clusterSecretLabel]
+		if name == "" {
+			name = secret.Name
+		}
+		if seen[name] {
+			log.Printf("Ignoring cluster secret %s/%s: cluster %s is already registered", secret.Namespace, secret.Name, name)
+			continue
+		}
+		seen[name] = true
+
+		if version, ok := versions[name]; ok && version == secret.ResourceVersion {
+			continue
+		}
+		cluster := &registeredCluster{
+			name:            name,
+			labels:          secret.Labels,
+			region:          secret.Labels[clusterRegionLabel],
+			pingEndpoints:   splitList(secret.Annotations[clusterPingEndpointsAnnotation]),
+			resourceVersion: secret.ResourceVersion,
+		}
+		config, err := clientcmd.RESTConfigFromKubeConfig(secret.Data[clusterKubeconfigKey])
+		if err == nil {
+			cluster.host = config.Host
+			cluster.server, err = local.forCluster(name, config)
+		}
+		if err != nil {
+			cluster.err = err
+			log.Printf("Failed to load cluster %s from secret %s/%s: %v", name, secret.Namespace, secret.Name, err)
+		} else {
+			log.Printf("Registered cluster %s (%s)", name, cluster.host)
+		}
+		changed = append(changed, cluster)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, cluster := range changed {
+		r.clusters[cluster.name] = cluster
+	}
+	for name := range r.clusters {
+		if !seen[name] {
+			log.Printf("Unregistered cluster %s", name)
+			delete(r.clusters, name)
+		}
+	}
+	return nil
+}
+
+// run refreshes the registry until ctx is done
+func (r *clusterRegistry) run(ctx context.Context) {
+	refresh := func() {
+		if err := r.refresh(ctx); err != nil {
+			log.Printf("Cluster registry refresh failed: %v", err)
+		}
+	}
+	refresh()
+	ticker := time.NewTicker(clusterRegistryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
+
+// forCluster creates a Server sharing this one's configuration but talking
+// to another cluster. Per-cluster background state starts out empty.
+func (s *Server) forCluster(name string, config *rest.Config) (*Server, error) {
+	k8sClient, kubeClient, err := k8s.NewClients(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		k8sClient:      k8sClient,
+		kubeClient:     kubeClient,
+		router:         s.router,
+		port:           s.port,
+		chatRelay:      &chatRelayCursors{},
+		wipeScheduler:  &wipeSchedulerState{},
+		eventScheduler: &eventSchedulerState{},
+		remediator:     &remediatorState{},
+		updater:        &updaterState{},
+		availability:   &availabilityTracker{},
+		alerts:         &alertEvaluator{},
+		cluster:        name,
+		clusters:       s.clusters,
+		operations:     s.operations,
+		events:         s.events,
+		maintenance:    s.maintenance,
+		rollouts:       s.rollouts,
+		workers:        s.workers,
+		limits:         s.limits,
+		leader:         s.leader,
+		snapshots:      s.snapshots,
+		whitelists:     s.whitelists,
+		banLists:       s.banLists,
+		lifecycleHooks: &lifecycleHookState{},
+		playerSessions: s.playerSessions,
+		teams:          s.teams,
+		sessions:       s.sessions,
+		activity:       s.activity,
+		clusterInfo:    &clusterInfoCache{},
+		access:         s.access,
+		namespaces:     s.namespaces.forCluster(),
+		admins:         s.admins,
+	}, nil
+}
+
+// clustered adapts a handler so it runs against the cluster selected by the
+// ?cluster= query parameter, defaulting to the local cluster
+func (s *Server) clustered(handler func(*Server, *gin.Context)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scoped, err := s.clusters.clusterServer(c.Query("cluster"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		handler(scoped, c)
+	}
+}
+
+// listClusters returns the registered clusters and whether they respond
+func (s *Server) listClusters(c *gin.Context) {
+	s.clusters.mu.RLock()
+	clusters := make([]*registeredCluster, 0, len(s.clusters.clusters))
+	for _, name := range s.clusters.sortedNames() {
+		clusters = append(clusters, s.clusters.clusters[name])
+	}
+	s.clusters.mu.RUnlock()
+
+	items := make([]ClusterInfo, len(clusters))
+	var wg sync.WaitGroup
+	for i, cluster := range clusters {
+		items[i] = ClusterInfo{
+			Name:   cluster.name,
+			Local:  cluster.local,
+			Host:   cluster.host,
+			Labels: cluster.labels,
+			Region: cluster.region,
+		}
+		if cluster.server == nil {
+			items[i].Error = cluster.err.Error()
+			continue
+		}
+		wg.Add(1)
+		go func(info *ClusterInfo, server *Server) {
+			defer wg.Done()
+			version, err := server.kubeClient.Discovery().ServerVersion()
+			if err != nil {
+				info.Error = err.Error()
+				return
+			}
+			info.Reachable = true
+			info.Version = version.String()
+		}(&items[i], cluster.server)
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(items),
+	})
+}
@@ -0,0 +1,317 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// secretMask replaces secret config values in responses. Sending it back
+// unchanged in a PUT keeps the stored value.
+const secretMask = "********"
+
+// ConfigEntry is a single gameConfig setting with its schema metadata
+type ConfigEntry struct {
+	ConfigField
+	Value     interface{} `json:"value,omitempty"`
+	IsSet     bool        `json:"isSet"`
+	IsDefault bool        `json:"isDefault"`
+	Custom    bool        `json:"custom,omitempty"` // not described by the game schema
+}
+
+// getGameServerConfig returns the gameConfig as typed entries merged with the game schema
+func (s *Server) getGameServerConfig(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, found := lookupGame(gameType)
+	if !found {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": fmt.Sprintf("Unsupported game type: %s", gameType),
+		})
+		return
+	}
+
+	gameConfig, _, _ := unstructured.NestedMap(obj.Object, "spec", "gameConfig")
+	c.JSON(http.StatusOK, gin.H{
+		"gameType": gameType,
+		"entries":  buildConfigEntries(def, gameConfig),
+	})
+}
+
+// putGameServerConfig replaces the whole gameConfig with the provided flat key/value map
+func (s *Server) putGameServerConfig(c *gin.Context) {
+	var req struct {
+		Values map[string]interface{} `json:"values" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, _ := lookupGame(gameType)
+	current, _, _ := unstructured.NestedMap(obj.Object, "spec", "gameConfig")
+
+	gameConfig := map[string]interface{}{}
+	validationErrors := []string{}
+	for key, value := range req.Values {
+		value, err := resolveConfigValue(def, current, key, value)
+		if err != nil {
+			validationErrors = append(validationErrors, err.Error())
+			continue
+		}
+		if value == nil {
+			continue
+		}
+		if err := unstructured.SetNestedField(gameConfig, value, strings.Split(key, ".")...); err != nil {
+			validationErrors = append(validationErrors, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+	if len(validationErrors) > 0 {
+		sort.Strings(validationErrors)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid game configuration",
+			"details": validationErrors,
+		})
+		return
+	}
+
+	s.saveGameServerConfig(c, obj, def, gameConfig)
+}
+
+// patchGameServerConfig sets or resets (value null) a single gameConfig key
+func (s *Server) patchGameServerConfig(c *gin.Context) {
+	var req struct {
+		Key   string      `json:"key" binding:"required"`
+		Value interface{} `json:"value"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, _ := lookupGame(gameType)
+	gameConfig, _, _ := unstructured.NestedMap(obj.Object, "spec", "gameConfig")
+	if gameConfig == nil {
+		gameConfig = map[string]interface{}{}
+	}
+
+	value, err := resolveConfigValue(def, gameConfig, req.Key, req.Value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	path := strings.Split(req.Key, ".")
+	if value == nil {
+		unstructured.RemoveNestedField(gameConfig, path...)
+	} else if err := unstructured.SetNestedField(gameConfig, value, path...); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("%s: %v", req.Key, err),
+		})
+		return
+	}
+
+	s.saveGameServerConfig(c, obj, def, gameConfig)
+}
+
+// loadGameServerForConfig fetches the GameServer named in the route, writing
+// the error response itself when it cannot
+func (s *Server) loadGameServerForConfig(c *gin.Context) (*unstructured.Unstructured, bool) {
+	obj, err := s.getGameServerObject(context.TODO(), c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return nil, false
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return nil, false
+	}
+	return obj, true
+}
+
+// saveGameServerConfig writes a new gameConfig and responds with the resulting entries
+func (s *Server) saveGameServerConfig(c *gin.Context, obj *unstructured.Unstructured, def GameDefinition, gameConfig map[string]interface{}) {
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	if len(gameConfig) > 0 {
+		spec["gameConfig"] = gameConfig
+	} else {
+		delete(spec, "gameConfig")
+	}
+
+	restartFields, err := s.writeGameServerSpec(context.TODO(), obj, spec)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update GameServer config: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"gameType":        def.Type,
+		"entries":         buildConfigEntries(def, gameConfig),
+		"restartRequired": len(restartFields) > 0,
+		"restartFields":   restartFields,
+	})
+}
+
+// buildConfigEntries merges the schema fields with the values that are set,
+// appending any keys the schema does not describe
+func buildConfigEntries(def GameDefinition, gameConfig map[string]interface{}) []ConfigEntry {
+	values := flattenConfig("", gameConfig)
+
+	entries := make([]ConfigEntry, 0, len(def.ConfigFields)+len(values))
+	known := map[string]bool{}
+	for _, field := range def.ConfigFields {
+		known[field.Path] = true
+		entry := ConfigEntry{ConfigField: field}
+		if value, ok := values[field.Path]; ok {
+			entry.IsSet = true
+			entry.Value = value
+			entry.IsDefault = field.Default != nil && configValuesEqual(value, field.Default)
+		} else {
+			entry.Value = field.Default
+			entry.IsDefault = true
+		}
+		if field.Secret && entry.IsSet {
+			entry.Value = secretMask
+		}
+		entries = append(entries, entry)
+	}
+
+	custom := make([]string, 0)
+	for key := range values {
+		if !known[key] {
+			custom = append(custom, key)
+		}
+	}
+	sort.Strings(custom)
+	for _, key := range custom {
+		entries = append(entries, ConfigEntry{
+			ConfigField: ConfigField{
+				Path:            key,
+				Type:            inferConfigType(values[key]),
+				RestartRequired: true,
+			},
+			Value:  values[key],
+			IsSet:  true,
+			Custom: true,
+		})
+	}
+	return entries
+}
+
+// resolveConfigValue validates a value for a config key and converts it to the
+// form stored in the claim. A nil result means the key should be unset.
+func resolveConfigValue(def GameDefinition, current map[string]interface{}, key string, value interface{}) (interface{}, error) {
+	if key == "" || strings.HasPrefix(key, ".") || strings.HasSuffix(key, ".") || strings.Contains(key, "..") {
+		return nil, fmt.Errorf("invalid config key %q", key)
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	field, known := def.Field(key)
+	if !known {
+		switch value.(type) {
+		case string, bool, float64:
+			return normalizeConfigNumber(value), nil
+		}
+		return nil, fmt.Errorf("%s: custom values must be a string, number or boolean", key)
+	}
+
+	// Masked secrets are echoed back unchanged by the dashboard
+	if field.Secret && value == secretMask {
+		existing, found, _ := unstructured.NestedFieldNoCopy(current, strings.Split(key, ".")...)
+		if !found {
+			return nil, nil
+		}
+		return existing, nil
+	}
+
+	if err := validateConfigValue(field, value); err != nil {
+		return nil, fmt.Errorf("%s: %v", key, err)
+	}
+	return normalizeConfigNumber(value), nil
+}
+
+// flattenConfig converts a nested gameConfig into dotted keys
+func flattenConfig(prefix string, config map[string]interface{}) map[string]interface{} {
+	flat := map[string]interface{}{}
+	for key, value := range config {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			for k, v := range flattenConfig(path, nested) {
+				flat[k] = v
+			}
+			continue
+		}
+		flat[path] = value
+	}
+	return flat
+}
+
+// normalizeConfigNumber stores whole JSON numbers as int64 so they render as
+// integers in the claim
+func normalizeConfigNumber(value interface{}) interface{} {
+	if f, ok := value.(float64); ok && f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+		return int64(f)
+	}
+	return value
+}
+
+// inferConfigType guesses the schema type of a value without a catalog entry
+func inferConfigType(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case int, int32, int64:
+		return "integer"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	}
+	return "object"
+}
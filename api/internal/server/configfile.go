@@ -0,0 +1,314 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// nativeSetting is a single key/value pair in a game's native config file
+type nativeSetting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Path   string `json:"path"` // spec or gameConfig path the value comes from
+	Secret bool   `json:"secret,omitempty"`
+}
+
+// ConfigDrift reports a rendered setting that differs in the live file
+type ConfigDrift struct {
+	Key      string `json:"key"`
+	Path     string `json:"path"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual,omitempty"`
+	Missing  bool   `json:"missing,omitempty"`
+}
+
+// getRenderedConfig renders the GameServer's configuration into the game's
+// native file format and compares it against the file on the data volume
+func (s *Server) getRenderedConfig(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, found := lookupGame(gameType)
+	if !found || def.ConfigFile == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("Game type %s is not configured by a config file", gameType),
+		})
+		return
+	}
+
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	settings := renderNativeSettings(def, spec)
+	rendered, err := renderConfigFile(def.ConfigFile, settings)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to render config file: %v", err),
+		})
+		return
+	}
+
+	if c.Query("raw") == "true" {
+		contentType := "text/plain; charset=utf-8"
+		if def.ConfigFile.Format == "xml" {
+			contentType = "application/xml; charset=utf-8"
+		}
+		c.Data(http.StatusOK, contentType, rendered)
+		return
+	}
+
+	response := gin.H{
+		"gameType": gameType,
+		"fileName": def.ConfigFile.Path,
+		"format":   def.ConfigFile.Format,
+		"rendered": string(rendered),
+		"settings": settings,
+	}
+
+	if c.DefaultQuery("live", "true") == "true" {
+		content, err := s.readGameFile(context.TODO(), obj, def.ConfigFile.Path)
+		if err != nil {
+			response["liveError"] = fmt.Sprintf("Failed to read live config file: %v", err)
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+		liveValues, err := parseConfigFile(def.ConfigFile, content)
+		if err != nil {
+			response["liveError"] = fmt.Sprintf("Failed to parse live config file: %v", err)
+			c.JSON(http.StatusOK, response)
+			return
+		}
+
+		drift := compareNativeSettings(settings, liveValues)
+		response["live"] = gin.H{
+			"content":    string(content),
+			"gameConfig": nativeToGameConfig(def, liveValues),
+		}
+		response["drift"] = drift
+		response["inSync"] = len(drift) == 0
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// renderNativeSettings maps spec and gameConfig values onto native setting
+// names, falling back to schema defaults for unset fields
+func renderNativeSettings(def GameDefinition, spec map[string]interface{}) []nativeSetting {
+	settings := []nativeSetting{}
+
+	specFields := make([]string, 0, len(def.ConfigFile.SpecKeys))
+	for field := range def.ConfigFile.SpecKeys {
+		specFields = append(specFields, field)
+	}
+	sort.Strings(specFields)
+	for _, field := range specFields {
+		value, found, _ := unstructured.NestedFieldNoCopy(spec, field)
+		if !found || isEmptyValue(value) {
+			continue
+		}
+		settings = append(settings, nativeSetting{
+			Key:   def.ConfigFile.SpecKeys[field],
+			Value: formatNativeValue(value),
+			Path:  "spec." + field,
+		})
+	}
+
+	gameConfig, _, _ := unstructured.NestedMap(spec, "gameConfig")
+	values := flattenConfig("", gameConfig)
+	for _, field := range def.ConfigFields {
+		if field.NativeKey == "" {
+			continue
+		}
+		value, set := values[field.Path]
+		if field.Secret {
+			// Secrets are injected from Kubernetes Secrets at startup
+			settings = append(settings, nativeSetting{Key: field.NativeKey, Value: secretMask, Path: "spec.gameConfig." + field.Path, Secret: true})
+			continue
+		}
+		if !set {
+			value = field.Default
+		}
+		if value == nil {
+			continue
+		}
+		settings = append(settings, nativeSetting{
+			Key:   field.NativeKey,
+			Value: formatNativeValue(value),
+			Path:  "spec.gameConfig." + field.Path,
+		})
+	}
+	return settings
+}
+
+// renderConfigFile serializes native settings in the file's format
+func renderConfigFile(file *ConfigFile, settings []nativeSetting) ([]byte, error) {
+	var buf bytes.Buffer
+	switch file.Format {
+	case "xml":
+		buf.WriteString("<?xml version=\"1.0\"?>\n<ServerSettings>\n")
+		for _, setting := range settings {
+			buf.WriteString("\t<property name=\"")
+			if err := xml.EscapeText(&buf, []byte(setting.Key)); err != nil {
+				return nil, err
+			}
+			buf.WriteString("\" value=\"")
+			if err := xml.EscapeText(&buf, []byte(setting.Value)); err != nil {
+				return nil, err
+			}
+			buf.WriteString("\" />\n")
+		}
+		buf.WriteString("</ServerSettings>\n")
+	case "ini":
+		if file.Section != "" {
+			fmt.Fprintf(&buf, "[%s]\n", file.Section)
+		}
+		for _, setting := range settings {
+			fmt.Fprintf(&buf, "%s=%s\n", setting.Key, setting.Value)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q", file.Format)
+	}
+	return buf.Bytes(), nil
+}
+
+// parseConfigFile reads native settings from a config file
+func parseConfigFile(file *ConfigFile, content []byte) (map[string]string, error) {
+	values := map[string]string{}
+	switch file.Format {
+	case "xml":
+		var doc struct {
+			Properties []struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:"value,attr"`
+			} `xml:"property"`
+		}
+		if err := xml.Unmarshal(content, &doc); err != nil {
+			return nil, err
+		}
+		for _, p := range doc.Properties {
+			values[p.Name] = p.Value
+		}
+	case "ini":
+		section := ""
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+				section = strings.TrimSpace(line[1 : len(line)-1])
+				continue
+			}
+			if file.Section != "" && !strings.EqualFold(section, file.Section) {
+				continue
+			}
+			key, value, found := strings.Cut(line, "=")
+			if !found {
+				continue
+			}
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q", file.Format)
+	}
+	return values, nil
+}
+
+// compareNativeSettings lists rendered settings whose live value differs
+func compareNativeSettings(settings []nativeSetting, live map[string]string) []ConfigDrift {
+	drift := []ConfigDrift{}
+	for _, setting := range settings {
+		if setting.Secret {
+			continue
+		}
+		actual, found := live[setting.Key]
+		if !found {
+			drift = append(drift, ConfigDrift{Key: setting.Key, Path: setting.Path, Expected: setting.Value, Missing: true})
+			continue
+		}
+		if !nativeValuesEqual(setting.Value, actual) {
+			drift = append(drift, ConfigDrift{Key: setting.Key, Path: setting.Path, Expected: setting.Value, Actual: actual})
+		}
+	}
+	return drift
+}
+
+// nativeToGameConfig converts parsed native settings back into a nested
+// gameConfig using the schema's types
+func nativeToGameConfig(def GameDefinition, live map[string]string) map[string]interface{} {
+	gameConfig := map[string]interface{}{}
+	for _, field := range def.ConfigFields {
+		if field.NativeKey == "" || field.Secret {
+			continue
+		}
+		raw, found := live[field.NativeKey]
+		if !found {
+			continue
+		}
+		_ = unstructured.SetNestedField(gameConfig, parseNativeValue(field.Type, raw), strings.Split(field.Path, ".")...)
+	}
+	return gameConfig
+}
+
+// formatNativeValue formats a config value the way game config files expect
+func formatNativeValue(value interface{}) string {
+	switch v := value.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	}
+	if f, ok := toFloat(value); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// parseNativeValue converts a native string value to the schema type
+func parseNativeValue(fieldType, raw string) interface{} {
+	switch fieldType {
+	case "boolean":
+		if b, err := strconv.ParseBool(raw); err == nil {
+			return b
+		}
+	case "integer":
+		if i, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			return i
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			return f
+		}
+	}
+	return raw
+}
+
+// nativeValuesEqual compares native values, treating "1" and "1.0" or
+// "True" and "true" as equal
+func nativeValuesEqual(a, b string) bool {
+	if a == b {
+		return true
+	}
+	af, aErr := strconv.ParseFloat(a, 64)
+	bf, bErr := strconv.ParseFloat(b, 64)
+	if aErr == nil && bErr == nil {
+		return af == bf
+	}
+	return strings.EqualFold(a, b)
+}
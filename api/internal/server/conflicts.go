@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// CreateConflict is an existing resource a new GameServer collides with
+type CreateConflict struct {
+	// Field is the part of the request that collides
+	Field     string `json:"field"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	Message   string `json:"message"`
+}
+
+// findCreateConflict checks a new GameServer against existing resources,
+// so a collision is reported up front instead of failing in Crossplane
+// later. It returns nil when nothing collides.
+func (s *Server) findCreateConflict(ctx context.Context, namespace, name string, spec GameServerSpec) (*CreateConflict, error) {
+	existing, err := s.getGameServerObject(ctx, namespace, name)
+	switch {
+	case err == nil:
+		message := fmt.Sprintf("GameServer %s/%s already exists", namespace, name)
+		if existing.GetDeletionTimestamp() != nil {
+			message = fmt.Sprintf("GameServer %s/%s is still being deleted; see GET .../deletion for what it waits on", namespace, name)
+		}
+		return &CreateConflict{Field: "metadata.name", Kind: "GameServer", Namespace: namespace, Name: name, Message: message}, nil
+	case !apierrors.IsNotFound(err):
+		return nil, err
+	}
+
+	host := normalizeHost(spec.Networking.IngressHost)
+	if !spec.Networking.EnableIngress || host == "" {
+		return nil, nil
+	}
+	servers, err := s.listAllGameServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range servers.Items {
+		other, _, _ := unstructured.NestedString(obj.Object, "spec", "networking", "ingressHost")
+		enabled, _, _ := unstructured.NestedBool(obj.Object, "spec", "networking", "enableIngress")
+		if enabled && normalizeHost(other) == host {
+			return &CreateConflict{
+				Field:     "spec.networking.ingressHost",
+				Kind:      "GameServer",
+				Namespace: obj.GetNamespace(),
+				Name:      obj.GetName(),
+				Message:   fmt.Sprintf("Ingress host %s is already used by GameServer %s/%s", host, obj.GetNamespace(), obj.GetName()),
+			}, nil
+		}
+	}
+	// Ingresses of other applications claim hosts as well
+	ingresses, err := s.kubeClient.NetworkingV1().Ingresses("").List(ctx, metav1.ListOptions{})
+	if apierrors.IsForbidden(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, ingress := range ingresses.Items {
+		for _, rule := range ingress.Spec.Rules {
+			if normalizeHost(rule.Host) == host {
+				return &CreateConflict{
+					Field:     "spec.networking.ingressHost",
+					Kind:      "Ingress",
+					Namespace: ingress.Namespace,
+					Name:      ingress.Name,
+					Message:   fmt.Sprintf("Ingress host %s is already used by Ingress %s/%s", host, ingress.Namespace, ingress.Name),
+				}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// normalizeHost compares hostnames case-insensitively, without a trailing dot
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
@@ -0,0 +1,20 @@
+package server
+
+import (
+	"context"
+
+	"github.com/kubelize/gameplane/api/internal/k8s"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Connect details are resolved in internal/k8s, shared with the status
+// controller that publishes them in GameServer status
+type (
+	ConnectInfo = k8s.ConnectInfo
+	ConnectPort = k8s.ConnectPort
+)
+
+// gameServerConnectInfo resolves the player-facing address of a GameServer
+func (s *Server) gameServerConnectInfo(ctx context.Context, obj *unstructured.Unstructured) (*ConnectInfo, error) {
+	return k8s.ResolveConnectInfo(ctx, s.kubeClient, obj)
+}
@@ -0,0 +1,317 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// errConsoleUnsupported is returned for games without a remote console
+var errConsoleUnsupported = errors.New("game type has no remote console")
+
+// errSaveUnconfirmed is returned when the game's reply to a save does not
+// confirm that the world was written
+var errSaveUnconfirmed = errors.New("game did not confirm the save")
+
+// consoleDialTimeout bounds connecting to a game's console port
+const consoleDialTimeout = 5 * time.Second
+
+// gameConsole sends admin commands to a running game server
+type gameConsole interface {
+	Exec(command string) (string, error)
+	Close() error
+}
+
+// openGameConsole connects to the remote console (telnet or RCON) of a
+// GameServer's pod using the protocol from the game catalog
+func (s *Server) openGameConsole(ctx context.Context, obj *unstructured.Unstructured) (gameConsole, error) {
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, ok := lookupGame(gameType)
+	if !ok || def.Console == nil {
+		return nil, errConsoleUnsupported
+	}
+	console := def.Console
+	gameConfig, _, _ := unstructured.NestedMap(obj.Object, "spec", "gameConfig")
+
+	if console.EnabledField != "" {
+		enabled := false
+		if field, found := def.Field(console.EnabledField); found {
+			enabled, _ = field.Default.(bool)
+		}
+		if value, found, _ := unstructured.NestedBool(gameConfig, strings.Split(console.EnabledField, ".")...); found {
+			enabled = value
+		}
+		if !enabled {
+			return nil, fmt.Errorf("%s console is disabled (set gameConfig.%s to true)", console.Protocol, console.EnabledField)
+		}
+	}
+
+	port := console.Port
+	if console.PortField != "" {
+		if value, found, _ := unstructured.NestedFieldNoCopy(gameConfig, strings.Split(console.PortField, ".")...); found {
+			if f, ok := toFloat(value); ok {
+				port = int(f)
+			}
+		}
+	}
+
+	podIP, err := s.runningPodIP(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	namespace, _ := managedNamespace(obj)
+
+	password := ""
+	if console.PasswordSecret != "" {
+		secret, err := s.kubeClient.CoreV1().Secrets(namespace).Get(ctx, fmt.Sprintf("%s-%s", namespace, console.PasswordSecret), metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to read console password: %w", err)
+		}
+		if err == nil {
+			password = string(secret.Data[console.PasswordKey])
+		}
+	}
+
+	dialer := net.Dialer{Timeout: consoleDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(podIP, strconv.Itoa(port)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s console: %w", console.Protocol, err)
+	}
+
+	switch console.Protocol {
+	case "telnet":
+		return newTelnetConsole(conn, password)
+	case "rcon":
+		return newRCONConsole(conn, password)
+	}
+	conn.Close()
+	return nil, fmt.Errorf("unsupported console protocol %q", console.Protocol)
+}
+
+// sayCommand builds the console command that broadcasts a message in-game
+func sayCommand(console *ConsoleInfo, message string) string {
+	return fmt.Sprintf(console.SayCommand, consoleText(message))
+}
+
+// consoleText fits text into a console command argument. Console commands
+// are line based and most games quote the argument.
+func consoleText(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.ReplaceAll(text, `"`, "'")
+}
+
+// telnetConsole speaks the line-based telnet console used by 7 Days to Die
+type telnetConsole struct {
+	conn net.Conn
+}
+
+// newTelnetConsole logs in to a telnet console
+func newTelnetConsole(conn net.Conn, password string) (*telnetConsole, error) {
+	t := &telnetConsole{conn: conn}
+	greeting := t.readUntilIdle(time.Second)
+	if strings.Contains(strings.ToLower(greeting), "password") {
+		if _, err := fmt.Fprintf(conn, "%s\r\n", password); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if reply := t.readUntilIdle(time.Second); strings.Contains(strings.ToLower(reply), "incorrect") {
+			conn.Close()
+			return nil, fmt.Errorf("telnet console rejected the password")
+		}
+	}
+	return t, nil
+}
+
+// Exec runs a command and returns the output printed until the console goes quiet
+func (t *telnetConsole) Exec(command string) (string, error) {
+	if _, err := fmt.Fprintf(t.conn, "%s\r\n", command); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(t.readUntilIdle(750 * time.Millisecond)), nil
+}
+
+// Close ends the telnet session
+func (t *telnetConsole) Close() error {
+	fmt.Fprint(t.conn, "exit\r\n")
+	return t.conn.Close()
+}
+
+// readUntilIdle reads until no data arrives for the idle period, stripping
+// telnet negotiation sequences
+func (t *telnetConsole) readUntilIdle(idle time.Duration) string {
+	var out bytes.Buffer
+	buf := make([]byte, 4096)
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		t.conn.SetReadDeadline(time.Now().Add(idle))
+		n, err := t.conn.Read(buf)
+		out.Write(buf[:n])
+		if err != nil {
+			break
+		}
+	}
+
+	// Drop IAC <command> <option> triples
+	raw := out.Bytes()
+	clean := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == 0xFF && i+2 < len(raw) {
+			i += 2
+			continue
+		}
+		clean = append(clean, raw[i])
+	}
+	return string(clean)
+}
+
+// Source RCON packet types
+const (
+	rconTypeResponse = 0
+	rconTypeCommand  = 2
+	rconTypeAuth     = 3
+)
+
+// rconConsole speaks the Source RCON protocol (Palworld, Conan Exiles, Minecraft)
+type rconConsole struct {
+	conn   net.Conn
+	nextID int32
+}
+
+// newRCONConsole authenticates an RCON connection
+func newRCONConsole(conn net.Conn, password string) (*rconConsole, error) {
+	r := &rconConsole{conn: conn, nextID: 1}
+	if err := r.write(r.nextID, rconTypeAuth, password); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// Servers answer auth with an optional empty response followed by an
+	// auth response whose id is -1 when the password is wrong
+	for {
+		id, packetType, _, err := r.read()
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("rcon auth failed: %w", err)
+		}
+		if packetType != rconTypeCommand { // auth responses reuse type 2
+			continue
+		}
+		if id == -1 {
+			conn.Close()
+			return nil, fmt.Errorf("rcon console rejected the password")
+		}
+		return r, nil
+	}
+}
+
+// Exec runs a command and returns its response body
+func (r *rconConsole) Exec(command string) (string, error) {
+	r.nextID++
+	if err := r.write(r.nextID, rconTypeCommand, command); err != nil {
+		return "", err
+	}
+	for {
+		id, packetType, body, err := r.read()
+		if err != nil {
+			return "", err
+		}
+		if id == r.nextID && packetType == rconTypeResponse {
+			return strings.TrimSpace(body), nil
+		}
+	}
+}
+
+// Close closes the RCON connection
+func (r *rconConsole) Close() error {
+	return r.conn.Close()
+}
+
+// write sends a single RCON packet
+func (r *rconConsole) write(id, packetType int32, body string) error {
+	var buf bytes.Buffer
+	size := int32(4 + 4 + len(body) + 2)
+	binary.Write(&buf, binary.LittleEndian, size)
+	binary.Write(&buf, binary.LittleEndian, id)
+	binary.Write(&buf, binary.LittleEndian, packetType)
+	buf.WriteString(body)
+	buf.Write([]byte{0, 0})
+
+	r.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	_, err := r.conn.Write(buf.Bytes())
+	return err
+}
+
+// read receives a single RCON packet
+func (r *rconConsole) read() (int32, int32, string, error) {
+	r.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var size int32
+	if err := binary.Read(r.conn, binary.LittleEndian, &size); err != nil {
+		return 0, 0, "", err
+	}
+	if size < 10 || size > 1<<20 {
+		return 0, 0, "", fmt.Errorf("invalid rcon packet size %d", size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r.conn, payload); err != nil {
+		return 0, 0, "", err
+	}
+
+	id := int32(binary.LittleEndian.Uint32(payload[0:4]))
+	packetType := int32(binary.LittleEndian.Uint32(payload[4:8]))
+	body := string(bytes.TrimRight(payload[8:], "\x00"))
+	return id, packetType, body, nil
+}
+
+// saveWorldInGame asks the game to flush its world to disk through the
+// console and checks its reply for the game's save confirmation
+func (s *Server) saveWorldInGame(ctx context.Context, obj *unstructured.Unstructured) (string, error) {
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, ok := lookupGame(gameType)
+	if !ok || def.Console == nil || def.Console.SaveCommand == "" {
+		return "", errConsoleUnsupported
+	}
+
+	console, err := s.openGameConsole(ctx, obj)
+	if err != nil {
+		return "", err
+	}
+	defer console.Close()
+	output, err := console.Exec(def.Console.SaveCommand)
+	if err != nil {
+		return output, err
+	}
+	confirmation := def.Console.SaveConfirmation
+	if confirmation != "" && !strings.Contains(strings.ToLower(output), strings.ToLower(confirmation)) {
+		return output, fmt.Errorf("%w: %q", errSaveUnconfirmed, output)
+	}
+	return output, nil
+}
+
+// listPlayersInGame asks the game console who is online and returns its
+// reply as the game formats it
+func (s *Server) listPlayersInGame(ctx context.Context, obj *unstructured.Unstructured) (string, error) {
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, ok := lookupGame(gameType)
+	if !ok || def.Console == nil || def.Console.PlayersCommand == "" {
+		return "", errConsoleUnsupported
+	}
+
+	console, err := s.openGameConsole(ctx, obj)
+	if err != nil {
+		return "", err
+	}
+	defer console.Close()
+	return console.Exec(def.Console.PlayersCommand)
+}
@@ -0,0 +1,276 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/catalog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// hoursPerMonth is the average month used for monthly figures
+	hoursPerMonth = 730
+
+	// openCostTimeout bounds a query to OpenCost
+	openCostTimeout = 10 * time.Second
+)
+
+// CostRates are the prices used to estimate costs. They are read from
+// COST_CPU_CORE_HOUR, COST_MEMORY_GB_HOUR, COST_STORAGE_GB_MONTH and
+// COST_CURRENCY.
+type CostRates struct {
+	Currency       string  `json:"currency"`
+	CPUCoreHour    float64 `json:"cpuCoreHour"`
+	MemoryGBHour   float64 `json:"memoryGBHour"`
+	StorageGBMonth float64 `json:"storageGBMonth"`
+}
+
+// CostBreakdown splits a cost into its resource components
+type CostBreakdown struct {
+	CPU     float64 `json:"cpu"`
+	Memory  float64 `json:"memory"`
+	Storage float64 `json:"storage"`
+	Total   float64 `json:"total"`
+}
+
+// CostReport is the cost of a GameServer over a time range
+type CostReport struct {
+	Source    string              `json:"source"` // rates or opencost
+	Currency  string              `json:"currency"`
+	Range     string              `json:"range"`
+	Hours     float64             `json:"hours"`
+	Resources GameServerResources `json:"resources"`
+	Cost      CostBreakdown       `json:"cost"`
+	Hourly    CostBreakdown       `json:"hourly"`
+	Rates     *CostRates          `json:"rates,omitempty"`
+	Warning   string              `json:"warning,omitempty"`
+}
+
+// loadCostRates reads prices from the environment, with defaults in the
+// range of common cloud list prices
+func loadCostRates() CostRates {
+	rate := func(name string, fallback float64) float64 {
+		if value, err := strconv.ParseFloat(os.Getenv(name), 64); err == nil && value >= 0 {
+			return value
+		}
+		return fallback
+	}
+	currency := os.Getenv("COST_CURRENCY")
+	if currency == "" {
+		currency = "USD"
+	}
+	return CostRates{
+		Currency:       currency,
+		CPUCoreHour:    rate("COST_CPU_CORE_HOUR", 0.031),
+		MemoryGBHour:   rate("COST_MEMORY_GB_HOUR", 0.004),
+		StorageGBMonth: rate("COST_STORAGE_GB_MONTH", 0.10),
+	}
+}
+
+// hourlyCost prices one hour of the given resources
+func (r CostRates) hourlyCost(resources GameServerResources) CostBreakdown {
+	cores := float64(parseCPUToMillicores(resources.CPU)) / 1000
+	memoryGB := float64(parseMemoryToBytes(resources.Memory)) / (1 << 30)
+	storageGB := float64(parseMemoryToBytes(resources.StorageSize)) / (1 << 30)
+	cost := CostBreakdown{
+		CPU:     cores * r.CPUCoreHour,
+		Memory:  memoryGB * r.MemoryGBHour,
+		Storage: storageGB * r.StorageGBMonth / hoursPerMonth,
+	}
+	cost.Total = cost.CPU + cost.Memory + cost.Storage
+	return cost
+}
+
+// scale multiplies a breakdown, rounding to cents
+func (b CostBreakdown) scale(factor float64) CostBreakdown {
+	round := func(v float64) float64 { return math.Round(v*factor*100) / 100 }
+	return CostBreakdown{CPU: round(b.CPU), Memory: round(b.Memory), Storage: round(b.Storage), Total: round(b.Total)}
+}
+
+// estimateGameServerCost prices a GameServer spec before it is created
+func (s *Server) estimateGameServerCost(c *gin.Context) {
+	var req struct {
+		Spec GameServerSpec `json:"spec"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if _, ok := lookupGame(req.Spec.GameType); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Unsupported game type: %s. Valid types: %s", req.Spec.GameType, strings.Join(supportedGameTypes(), ", ")),
+		})
+		return
+	}
+
+	rates := loadCostRates()
+	resources := effectiveResources(req.Spec.GameType, req.Spec.Resources)
+	hourly := rates.hourlyCost(resources)
+	c.JSON(http.StatusOK, gin.H{
+		"currency":  rates.Currency,
+		"resources": resources,
+		"hourly":    hourly.scale(1),
+		"daily":     hourly.scale(24),
+		"monthly":   hourly.scale(hoursPerMonth),
+		"rates":     rates,
+	})
+}
+
+// getGameServerCost reports what a GameServer cost over ?range= (default
+// 30d), from OpenCost when OPENCOST_URL is set and configured rates otherwise
+func (s *Server) getGameServerCost(c *gin.Context) {
+	rangeParam := c.DefaultQuery("range", "30d")
+	window, err := parseRange(rangeParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	obj, err := s.getGameServerObject(context.TODO(), c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+
+	report := gameServerCostFromRates(obj, window)
+	report.Range = rangeParam
+	if openCostURL := os.Getenv("OPENCOST_URL"); openCostURL != "" {
+		if total, err := openCostNamespaceCost(context.TODO(), openCostURL, obj, window); err != nil {
+			report.Warning = fmt.Sprintf("OpenCost unavailable, using configured rates: %v", err)
+		} else {
+			report.Source = "opencost"
+			report.Rates = nil
+			report.Cost = CostBreakdown{Total: math.Round(total*100) / 100}
+		}
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// gameServerCostFromRates prices a GameServer's configured resources for the
+// part of the window it existed
+func gameServerCostFromRates(obj *unstructured.Unstructured, window time.Duration) CostReport {
+	rates := loadCostRates()
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	resources := effectiveResources(gameType, gameServerResources(obj))
+
+	active := window
+	if age := time.Since(obj.GetCreationTimestamp().Time); age < active {
+		active = age
+	}
+	hours := active.Hours()
+	hourly := rates.hourlyCost(resources)
+	return CostReport{
+		Source:    "rates",
+		Currency:  rates.Currency,
+		Hours:     math.Round(hours*10) / 10,
+		Resources: resources,
+		Cost:      hourly.scale(hours),
+		Hourly:    hourly.scale(1),
+		Rates:     &rates,
+	}
+}
+
+// gameServerResources reads spec.resources from a GameServer
+func gameServerResources(obj *unstructured.Unstructured) GameServerResources {
+	resources := GameServerResources{}
+	resources.CPU, _, _ = unstructured.NestedString(obj.Object, "spec", "resources", "cpu")
+	resources.Memory, _, _ = unstructured.NestedString(obj.Object, "spec", "resources", "memory")
+	resources.StorageSize, _, _ = unstructured.NestedString(obj.Object, "spec", "resources", "storageSize")
+	resources.StorageClass, _, _ = unstructured.NestedString(obj.Object, "spec", "resources", "storageClass")
+	return resources
+}
+
+// effectiveResources fills unset resources with the game's defaults, which
+// mirror the defaults of its child composition
+func effectiveResources(gameType string, resources GameServerResources) GameServerResources {
+	return GameServerResources(catalog.EffectiveResources(gameType, catalog.Resources(resources)))
+}
+
+// openCostNamespaceCost asks OpenCost for the total cost of the GameServer's
+// managed namespace over a window
+func openCostNamespaceCost(ctx context.Context, baseURL string, obj *unstructured.Unstructured, window time.Duration) (float64, error) {
+	namespace, err := managedNamespace(obj)
+	if err != nil {
+		return 0, err
+	}
+	query := url.Values{}
+	query.Set("window", fmt.Sprintf("%dh", int(math.Ceil(window.Hours()))))
+	query.Set("aggregate", "namespace")
+	query.Set("accumulate", "true")
+	query.Set("filter", fmt.Sprintf("namespace:%q", namespace))
+
+	ctx, cancel := context.WithTimeout(ctx, openCostTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/allocation/compute?"+query.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("OpenCost returned %s", resp.Status)
+	}
+
+	var body struct {
+		Data []map[string]struct {
+			TotalCost float64 `json:"totalCost"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("failed to decode OpenCost response: %w", err)
+	}
+	total := 0.0
+	for _, set := range body.Data {
+		if allocation, ok := set[namespace]; ok {
+			total += allocation.TotalCost
+		}
+	}
+	return total, nil
+}
+
+// parseRange parses a reporting window such as "30d", "2w" or "12h"
+func parseRange(value string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid range %q: use a duration like 7d, 2w or 12h", value)
+	if value == "" {
+		return 0, invalid
+	}
+	unit := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}[value[len(value)-1]]
+	if unit == 0 {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return 0, invalid
+		}
+		return d, nil
+	}
+	n, err := strconv.Atoi(value[:len(value)-1])
+	if err != nil || n <= 0 {
+		return 0, invalid
+	}
+	return time.Duration(n) * unit, nil
+}
@@ -0,0 +1,442 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// pvcProtectionFinalizer keeps a claim while pods still mount it
+	pvcProtectionFinalizer = "kubernetes.io/pvc-protection"
+
+	// maxDeletionDepth bounds the walk through nested composites
+	maxDeletionDepth = 4
+
+	// namespaceDepth places the game namespace below any composite
+	namespaceDepth = maxDeletionDepth + 3
+)
+
+// DeletionProgress shows what a GameServer's deletion still waits for:
+// the claim, its composites, their composed resources and what those
+// created, down to the game namespace and its volumes and pods
+type DeletionProgress struct {
+	Namespace       string             `json:"namespace"`
+	Name            string             `json:"name"`
+	DeletionStarted time.Time          `json:"deletionStarted"`
+	Elapsed         string             `json:"elapsed"`
+	Remaining       []DeletionResource `json:"remaining"`
+	// Stuck is set once a resource has waited on finalizers for longer
+	// than 15 minutes; admins may then force the deletion
+	Stuck bool `json:"stuck"`
+}
+
+// DeletionResource is a resource a deletion still waits for
+type DeletionResource struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	// Parent is the resource that created it, as kind/name
+	Parent     string   `json:"parent,omitempty"`
+	Deleting   bool     `json:"deleting"`
+	Finalizers []string `json:"finalizers,omitempty"`
+	Stuck      bool     `json:"stuck"`
+	Reason     string   `json:"reason"`
+
+	deletedAt *metav1.Time
+	depth     int
+}
+
+// deletionWalk collects the resources left of a deletion
+type deletionWalk struct {
+	s         *Server
+	now       time.Time
+	remaining []DeletionResource
+	seen      map[string]bool
+	// parents maps namespaces to the Objects that created them
+	parents map[string]string
+}
+
+// add records a remaining resource and explains what it waits for
+func (w *deletionWalk) add(apiVersion, kind string, obj metav1.Object, parent string, depth int) string {
+	id := kind + "/" + obj.GetName()
+	if obj.GetNamespace() != "" {
+		id = kind + "/" + obj.GetNamespace() + "/" + obj.GetName()
+	}
+	if w.seen[id] {
+		return id
+	}
+	w.seen[id] = true
+	resource := DeletionResource{
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		Parent:     parent,
+		Finalizers: obj.GetFinalizers(),
+		deletedAt:  obj.GetDeletionTimestamp(),
+		depth:      depth,
+	}
+	if resource.deletedAt == nil {
+		resource.Reason = "Not deleted yet, waiting for its parent to delete it"
+		w.remaining = append(w.remaining, resource)
+		return id
+	}
+	resource.Deleting = true
+	resource.Stuck = w.now.Sub(resource.deletedAt.Time) > orphanStuckAfter
+	if len(resource.Finalizers) > 0 {
+		resource.Reason = "Waiting on finalizers: " + strings.Join(resource.Finalizers, ", ")
+	} else {
+		resource.Reason = "Being deleted"
+	}
+	w.remaining = append(w.remaining, resource)
+	return id
+}
+
+// get reads a resource, returning nil once it is gone
+func (w *deletionWalk) get(ctx context.Context, apiVersion, kind, namespace, name string) (*unstructured.Unstructured, error) {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil || kind == "" || name == "" {
+		return nil, nil
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gv.WithKind(kind))
+	if err := w.s.k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return obj, nil
+}
+
+// walkComposite adds a composite and the resources it composed. Objects
+// of provider-kubernetes are followed to the resource in their manifest,
+// and composites found there are walked in turn.
+func (w *deletionWalk) walkComposite(ctx context.Context, ref map[string]interface{}, parent string, depth int) error {
+	if depth > maxDeletionDepth {
+		return nil
+	}
+	apiVersion, _ := ref["apiVersion"].(string)
+	kind, _ := ref["kind"].(string)
+	name, _ := ref["name"].(string)
+	composite, err := w.get(ctx, apiVersion, kind, "", name)
+	if err != nil || composite == nil {
+		return err
+	}
+	compositeID := w.add(composite.GetAPIVersion(), composite.GetKind(), composite, parent, depth)
+
+	refs, _, _ := unstructured.NestedSlice(composite.Object, "spec", "resourceRefs")
+	for _, raw := range refs {
+		ref, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		apiVersion, _ := ref["apiVersion"].(string)
+		kind, _ := ref["kind"].(string)
+		name, _ := ref["name"].(string)
+		namespace, _ := ref["namespace"].(string)
+		composed, err := w.get(ctx, apiVersion, kind, namespace, name)
+		if err != nil {
+			return err
+		}
+		if composed == nil {
+			continue
+		}
+		composedID := w.add(composed.GetAPIVersion(), composed.GetKind(), composed, compositeID, depth+1)
+		if !strings.HasPrefix(composed.GetAPIVersion(), "kubernetes.crossplane.io/") || composed.GetKind() != "Object" {
+			continue
+		}
+
+		manifest, _, _ := unstructured.NestedMap(composed.Object, "spec", "forProvider", "manifest")
+		target := &unstructured.Unstructured{Object: manifest}
+		if strings.HasPrefix(target.GetAPIVersion(), "gameplane.kubelize.io/") {
+			// A child composite
+			childRef := map[string]interface{}{"apiVersion": target.GetAPIVersion(), "kind": target.GetKind(), "name": target.GetName()}
+			if err := w.walkComposite(ctx, childRef, composedID, depth+2); err != nil {
+				return err
+			}
+			continue
+		}
+		if target.GetAPIVersion() == "v1" && target.GetKind() == "Namespace" {
+			// Walked with its contents by walkNamespace
+			w.parents[target.GetName()] = composedID
+			continue
+		}
+		live, err := w.get(ctx, target.GetAPIVersion(), target.GetKind(), target.GetNamespace(), target.GetName())
+		if err != nil {
+			return err
+		}
+		if live != nil {
+			w.add(live.GetAPIVersion(), live.GetKind(), live, composedID, depth+2)
+		}
+	}
+	return nil
+}
+
+// walkNamespace adds the game namespace with its volumes and terminating
+// pods, and explains why it is still terminating: its remaining
+// conditions, and the pods keeping volumes protected
+func (w *deletionWalk) walkNamespace(ctx context.Context, namespace string) error {
+	core := w.s.kubeClient.CoreV1()
+	ns, err := core.Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	namespaceID := w.add("v1", "Namespace", ns, w.parents[namespace], namespaceDepth)
+	reasons := []string{}
+	for _, condition := range ns.Status.Conditions {
+		if condition.Status == corev1.ConditionTrue && condition.Message != "" {
+			reasons = append(reasons, condition.Message)
+		}
+	}
+
+	pods, err := core.Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	mountedBy := map[string][]string{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil {
+				mountedBy[volume.PersistentVolumeClaim.ClaimName] = append(mountedBy[volume.PersistentVolumeClaim.ClaimName], pod.Name)
+			}
+		}
+		if pod.DeletionTimestamp != nil {
+			w.add("v1", "Pod", pod, namespaceID, namespaceDepth+1)
+		}
+	}
+	pvcs, err := core.PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for i := range pvcs.Items {
+		w.add("v1", "PersistentVolumeClaim", &pvcs.Items[i], namespaceID, namespaceDepth+1)
+	}
+
+	for i := range w.remaining {
+		resource := &w.remaining[i]
+		if !resource.Deleting {
+			continue
+		}
+		switch {
+		case resource.Kind == "Namespace" && resource.Name == namespace && len(reasons) > 0:
+			resource.Reason = strings.Join(reasons, "; ")
+		case resource.Kind == "PersistentVolumeClaim" && resource.Namespace == namespace && containsString(resource.Finalizers, pvcProtectionFinalizer):
+			if users := mountedBy[resource.Name]; len(users) > 0 {
+				resource.Reason = fmt.Sprintf("PVC protection: still mounted by pod %s", strings.Join(users, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// deletionProgress walks what is left of a GameServer being deleted
+func (s *Server) deletionProgress(ctx context.Context, obj *unstructured.Unstructured) (*DeletionProgress, []DeletionResource, error) {
+	walk := &deletionWalk{s: s, now: time.Now(), seen: map[string]bool{}, parents: map[string]string{}}
+	claimID := walk.add(obj.GetAPIVersion(), obj.GetKind(), obj, "", 0)
+	if ref, ok, _ := unstructured.NestedMap(obj.Object, "spec", "resourceRef"); ok {
+		if err := walk.walkComposite(ctx, ref, claimID, 1); err != nil {
+			return nil, nil, err
+		}
+	}
+	if namespace, err := managedNamespace(obj); err == nil {
+		if err := walk.walkNamespace(ctx, namespace); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	started := obj.GetDeletionTimestamp().Time
+	progress := &DeletionProgress{
+		Namespace:       obj.GetNamespace(),
+		Name:            obj.GetName(),
+		DeletionStarted: started,
+		Elapsed:         walk.now.Sub(started).Round(time.Second).String(),
+		Remaining:       walk.remaining,
+	}
+	for _, resource := range walk.remaining {
+		progress.Stuck = progress.Stuck || resource.Stuck
+	}
+	return progress, walk.remaining, nil
+}
+
+// getGameServerDeletion reports the progress of a GameServer's deletion.
+// Once the deletion finished the GameServer is not found.
+func (s *Server) getGameServerDeletion(c *gin.Context) {
+	obj, err := s.getGameServerObject(context.TODO(), c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+	if obj.GetDeletionTimestamp() == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "GameServer is not being deleted",
+		})
+		return
+	}
+	progress, _, err := s.deletionProgress(context.TODO(), obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to read deletion progress: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, progress)
+}
+
+// forceDeletion unblocks a deletion that has been stuck for longer than
+// orphanStuckAfter. Pods still mounting protected volumes are deleted
+// first, as removing pvc-protection under a running pod would corrupt data.
+// Then the finalizers of stuck resources are removed from the deepest up,
+// so composites are released only after what they composed.
+func (s *Server) forceDeletion(ctx context.Context, obj *unstructured.Unstructured) ([]string, error) {
+	_, remaining, err := s.deletionProgress(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	stuck := false
+	for _, resource := range remaining {
+		stuck = stuck || resource.Stuck
+	}
+	if !stuck {
+		return nil, errDeletionNotStuck
+	}
+
+	forced := []string{}
+	if namespace, err := managedNamespace(obj); err == nil {
+		pods, err := s.kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, pod := range pods.Items {
+			mounts := false
+			for _, volume := range pod.Spec.Volumes {
+				mounts = mounts || volume.PersistentVolumeClaim != nil
+			}
+			if !mounts {
+				continue
+			}
+			grace := int64(0)
+			if err := s.kubeClient.CoreV1().Pods(namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{GracePeriodSeconds: &grace}); err != nil && !apierrors.IsNotFound(err) {
+				return forced, err
+			}
+			forced = append(forced, "Pod/"+namespace+"/"+pod.Name+" deleted")
+		}
+	}
+
+	// Deepest first
+	for depth := namespaceDepth + 1; depth >= 0; depth-- {
+		for _, resource := range remaining {
+			if resource.depth != depth || !resource.Stuck || len(resource.Finalizers) == 0 || resource.Kind == "Pod" {
+				continue
+			}
+			if err := s.removeFinalizers(ctx, resource); err != nil && !apierrors.IsNotFound(err) {
+				return forced, fmt.Errorf("failed to remove finalizers of %s %s: %w", resource.Kind, resource.Name, err)
+			}
+			forced = append(forced, fmt.Sprintf("%s/%s finalizers removed", resource.Kind, resource.Name))
+		}
+	}
+	return forced, nil
+}
+
+// errDeletionNotStuck refuses forcing a deletion that is still progressing
+var errDeletionNotStuck = fmt.Errorf("deletion is still progressing; it can be forced once a resource has waited on finalizers for %s", orphanStuckAfter)
+
+// removeFinalizers clears a remaining resource's finalizers
+func (s *Server) removeFinalizers(ctx context.Context, resource DeletionResource) error {
+	if resource.Kind == "Namespace" && resource.APIVersion == "v1" {
+		return s.cleanupOrphan(ctx, Orphan{Kind: "Namespace", Name: resource.Name, Action: orphanActionRemoveFinalizers})
+	}
+	gv, err := schema.ParseGroupVersion(resource.APIVersion)
+	if err != nil {
+		return err
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gv.WithKind(resource.Kind))
+	if err := s.k8sClient.Get(ctx, client.ObjectKey{Namespace: resource.Namespace, Name: resource.Name}, obj); err != nil {
+		return err
+	}
+	obj.SetFinalizers(nil)
+	return s.k8sClient.Update(ctx, obj)
+}
+
+// forceDeleteGameServer handles DELETE ?force=true. Admins only: the
+// GameServer is deleted if it is not yet, and once its deletion is stuck
+// the remaining finalizers are removed.
+func (s *Server) forceDeleteGameServer(c *gin.Context, namespace, name string) {
+	if !s.isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Forcing a deletion requires admin",
+		})
+		return
+	}
+	ctx := context.TODO()
+	obj, err := s.getGameServerObject(ctx, namespace, name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+	if obj.GetDeletionTimestamp() == nil {
+		if err := s.k8sClient.Delete(ctx, obj); err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to delete GameServer: %v", err),
+			})
+			return
+		}
+		s.publishEvent(eventGameServerDeleted, namespace, name, nil)
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": fmt.Sprintf("GameServer deleted; if its deletion is still stuck after %s, force it again", orphanStuckAfter),
+		})
+		return
+	}
+
+	forced, err := s.forceDeletion(ctx, obj)
+	if err == errDeletionNotStuck {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error":  fmt.Sprintf("Failed to force deletion: %v", err),
+			"forced": forced,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Deletion forced",
+		"forced":  forced,
+	})
+}
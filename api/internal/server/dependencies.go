@@ -0,0 +1,91 @@
+package server
+
+import (
+	"fmt"
+	"net"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// maxDependencies bounds spec.dependencies; each one is dialled on every
+// status pass
+const maxDependencies = 8
+
+// validateDependencies checks the form of spec.dependencies. Hosts end up
+// in the command of the wait-for-dependencies init container, so only DNS
+// names and IP addresses are accepted.
+func validateDependencies(dependencies []GameServerDependency) error {
+	if len(dependencies) > maxDependencies {
+		return fmt.Errorf("a GameServer has at most %d dependencies", maxDependencies)
+	}
+	seen := map[string]bool{}
+	for _, dependency := range dependencies {
+		if errs := validation.IsDNS1123Label(dependency.Name); len(errs) > 0 {
+			return fmt.Errorf("invalid dependency name %q: %s", dependency.Name, errs[0])
+		}
+		if seen[dependency.Name] {
+			return fmt.Errorf("dependency %s is declared twice", dependency.Name)
+		}
+		seen[dependency.Name] = true
+
+		switch {
+		case (dependency.Service == "") == (dependency.Host == ""):
+			return fmt.Errorf("dependency %s needs exactly one of service or host", dependency.Name)
+		case dependency.Service != "" && len(validation.IsDNS1035Label(dependency.Service)) > 0:
+			return fmt.Errorf("dependency %s has an invalid service name %q", dependency.Name, dependency.Service)
+		case dependency.Namespace != "" && dependency.Service == "":
+			return fmt.Errorf("dependency %s sets a namespace without a service", dependency.Name)
+		case dependency.Namespace != "" && len(validation.IsDNS1123Label(dependency.Namespace)) > 0:
+			return fmt.Errorf("dependency %s has an invalid namespace %q", dependency.Name, dependency.Namespace)
+		case dependency.Host != "" && net.ParseIP(dependency.Host) == nil && len(validation.IsDNS1123Subdomain(dependency.Host)) > 0:
+			return fmt.Errorf("dependency %s has an invalid host %q", dependency.Name, dependency.Host)
+		case dependency.Port < 1 || dependency.Port > 65535:
+			return fmt.Errorf("dependency %s has an invalid port %d", dependency.Name, dependency.Port)
+		}
+	}
+	return nil
+}
+
+// dependenciesSpec converts spec.dependencies for the claim
+func dependenciesSpec(dependencies []GameServerDependency) []interface{} {
+	out := make([]interface{}, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		entry := map[string]interface{}{
+			"name": dependency.Name,
+			"port": int64(dependency.Port),
+		}
+		if dependency.Service != "" {
+			entry["service"] = dependency.Service
+		}
+		if dependency.Namespace != "" {
+			entry["namespace"] = dependency.Namespace
+		}
+		if dependency.Host != "" {
+			entry["host"] = dependency.Host
+		}
+		if dependency.WaitForReady {
+			entry["waitForReady"] = true
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+// gameServerDependencies reads spec.dependencies
+func gameServerDependencies(obj *unstructured.Unstructured) []GameServerDependency {
+	raw, _, _ := unstructured.NestedSlice(obj.Object, "spec", "dependencies")
+	dependencies := []GameServerDependency{}
+	for _, item := range raw {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		dependency := GameServerDependency{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(entry, &dependency); err == nil {
+			dependencies = append(dependencies, dependency)
+		}
+	}
+	return dependencies
+}
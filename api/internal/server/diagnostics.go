@@ -0,0 +1,143 @@
+package server
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/k8s"
+)
+
+// startTime is when the process started, for the runtime uptime
+var startTime = time.Now()
+
+// envBool reads a boolean switch from the environment, fallback when unset
+func envBool(name string, fallback bool) (bool, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	enabled, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q", name, raw)
+	}
+	return enabled, nil
+}
+
+// debugRoutes serves the Go profiler and expvar under /debug, behind the
+// same middleware as the API. Profiles hold the command line and heap
+// contents, secrets included, so only admins may fetch them.
+func (s *Server) debugRoutes(root *gin.RouterGroup, middleware ...gin.HandlerFunc) {
+	debug := root.Group("/debug", append(append([]gin.HandlerFunc{}, middleware...), s.requireAdmin)...)
+	debug.GET("/vars", gin.WrapH(expvar.Handler()))
+	debug.GET("/pprof/", gin.WrapF(pprof.Index))
+	debug.GET("/pprof/:profile", func(c *gin.Context) {
+		switch profile := c.Param("profile"); profile {
+		case "cmdline":
+			pprof.Cmdline(c.Writer, c.Request)
+		case "profile":
+			pprof.Profile(c.Writer, c.Request)
+		case "symbol":
+			pprof.Symbol(c.Writer, c.Request)
+		case "trace":
+			pprof.Trace(c.Writer, c.Request)
+		default:
+			pprof.Handler(profile).ServeHTTP(c.Writer, c.Request)
+		}
+	})
+	debug.POST("/pprof/symbol", gin.WrapF(pprof.Symbol))
+}
+
+// requireAdmin rejects callers who are not admins, for routes served by
+// handlers that cannot check themselves
+func (s *Server) requireAdmin(c *gin.Context) {
+	if !s.isAdmin(c) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "Only admins may do this",
+		})
+		return
+	}
+	c.Next()
+}
+
+// RuntimeInfo describes the API process, for diagnosing growth in
+// long-running deployments
+type RuntimeInfo struct {
+	GoVersion  string        `json:"goVersion"`
+	Uptime     string        `json:"uptime"`
+	Goroutines int           `json:"goroutines"`
+	CPUs       int           `json:"cpus"`
+	Memory     RuntimeMemory `json:"memory"`
+	// Kubernetes counts requests to the API servers of every cluster
+	Kubernetes k8s.ConnectionSnapshot `json:"kubernetes"`
+	// Stores are the sizes of in-memory state
+	Stores RuntimeStores `json:"stores"`
+}
+
+// RuntimeMemory is an excerpt of runtime.MemStats, in bytes
+type RuntimeMemory struct {
+	HeapAlloc    uint64    `json:"heapAlloc"`
+	HeapInuse    uint64    `json:"heapInuse"`
+	HeapObjects  uint64    `json:"heapObjects"`
+	Sys          uint64    `json:"sys"`
+	NumGC        uint32    `json:"numGC"`
+	LastGC       time.Time `json:"lastGC"`
+	PauseTotalNs uint64    `json:"pauseTotalNs"`
+}
+
+// RuntimeStores sizes the API's in-memory caches and queues
+type RuntimeStores struct {
+	Operations      int `json:"operations"`
+	Clusters        int `json:"clusters"`
+	BackgroundTasks int `json:"backgroundTasks"`
+	EventQueue      int `json:"eventQueue"`
+	WorkersBusy     int `json:"workersBusy"`
+	WorkersTotal    int `json:"workersTotal"`
+}
+
+// getRuntime reports goroutines, memory, Kubernetes connections and the
+// size of in-memory stores of this replica. Only admins may see it.
+func (s *Server) getRuntime(c *gin.Context) {
+	if !s.isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Only admins may see the runtime",
+		})
+		return
+	}
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	info := RuntimeInfo{
+		GoVersion:  runtime.Version(),
+		Uptime:     time.Since(startTime).Round(time.Second).String(),
+		Goroutines: runtime.NumGoroutine(),
+		CPUs:       runtime.GOMAXPROCS(0),
+		Memory: RuntimeMemory{
+			HeapAlloc:    mem.HeapAlloc,
+			HeapInuse:    mem.HeapInuse,
+			HeapObjects:  mem.HeapObjects,
+			Sys:          mem.Sys,
+			NumGC:        mem.NumGC,
+			LastGC:       time.Unix(0, int64(mem.LastGC)).UTC(),
+			PauseTotalNs: mem.PauseTotalNs,
+		},
+		Kubernetes: k8s.Connections.Snapshot(),
+	}
+
+	s.operations.mu.Lock()
+	info.Stores.Operations = len(s.operations.ops)
+	s.operations.mu.Unlock()
+	info.Stores.Clusters = len(s.clusters.servers())
+	info.Stores.BackgroundTasks = len(s.backgroundTasks)
+	info.Stores.EventQueue = len(s.events.events)
+	info.Stores.WorkersBusy = len(s.workers.slots)
+	info.Stores.WorkersTotal = cap(s.workers.slots)
+
+	c.JSON(http.StatusOK, info)
+}
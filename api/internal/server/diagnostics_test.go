@@ -0,0 +1,35 @@
+package server_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kubelize/gameplane/api/pkg/gameplane/gameplanetest"
+)
+
+func TestDiagnosticsAreForAdmins(t *testing.T) {
+	t.Setenv("DEBUG_ENDPOINTS", "true")
+	h := newHarness(t)
+
+	for _, path := range []string{"/api/v1/admin/runtime", "/debug/vars", "/debug/pprof/cmdline", "/debug/pprof/heap"} {
+		if code := call(t, h, "alice", gameplanetest.Request(http.MethodGet, path, nil), nil); code != http.StatusForbidden {
+			t.Errorf("%s as non-admin: got %d, want 403", path, code)
+		}
+		if code := call(t, h, "root", gameplanetest.Request(http.MethodGet, path, nil), nil); code != http.StatusOK {
+			t.Errorf("%s as admin: got %d, want 200", path, code)
+		}
+	}
+}
+
+func TestAdminReadsAreForAdmins(t *testing.T) {
+	h := newHarness(t)
+
+	for _, path := range []string{"/api/v1/admin/orphans", "/api/v1/admin/prepull", "/api/v1/admin/steamcache"} {
+		if code := call(t, h, "alice", gameplanetest.Request(http.MethodGet, path, nil), nil); code != http.StatusForbidden {
+			t.Errorf("%s as non-admin: got %d, want 403", path, code)
+		}
+		if code := call(t, h, "root", gameplanetest.Request(http.MethodGet, path, nil), nil); code != http.StatusOK {
+			t.Errorf("%s as admin: got %d, want 200", path, code)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// SpecChange describes a single field that differs between two specs
+type SpecChange struct {
+	Path      string      `json:"path"`
+	Operation string      `json:"operation"` // added, removed or modified
+	Old       interface{} `json:"old,omitempty"`
+	New       interface{} `json:"new,omitempty"`
+}
+
+// diffContextLines is the number of unchanged lines shown around each hunk
+const diffContextLines = 3
+
+// diffGameServer previews the changes an update would make without applying them
+func (s *Server) diffGameServer(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	var proposedReq GameServerSpec
+	if err := c.ShouldBindJSON(&proposedReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+
+	obj, err := s.getGameServerObject(context.TODO(), namespace, name)
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+
+	live, err := normalizeForDiff(obj.Object["spec"])
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to read live spec: %v", err),
+		})
+		return
+	}
+	proposed, err := normalizeForDiff(mergedUpdateSpec(obj, proposedReq))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid proposed spec: %v", err),
+		})
+		return
+	}
+
+	changes := diffValues("spec", live, proposed)
+	unified, err := unifiedSpecDiff(live, proposed, "live", "proposed")
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to render diff: %v", err),
+		})
+		return
+	}
+
+	restartFields := restartRequiredFields(proposedReq.GameType, changes)
+	response := gin.H{
+		"name":            name,
+		"namespace":       namespace,
+		"changes":         changes,
+		"changeCount":     len(changes),
+		"unifiedDiff":     unified,
+		"restartRequired": len(restartFields) > 0,
+		"restartFields":   restartFields,
+	}
+
+	// Optionally run the update through the API server as a dry run so
+	// defaulting and admission changes show up in the preview
+	if c.Query("dryRun") == "true" {
+		dryRunObj := obj.DeepCopy()
+		dryRunObj.Object["spec"] = mergedUpdateSpec(obj, proposedReq)
+		if err := s.k8sClient.Update(context.TODO(), dryRunObj, client.DryRunAll); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"error":       fmt.Sprintf("Server-side dry run rejected the update: %v", err),
+				"changes":     changes,
+				"changeCount": len(changes),
+				"unifiedDiff": unified,
+			})
+			return
+		}
+
+		applied, err := normalizeForDiff(dryRunObj.Object["spec"])
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to read dry run result: %v", err),
+			})
+			return
+		}
+		appliedChanges := diffValues("spec", live, applied)
+		appliedUnified, err := unifiedSpecDiff(live, applied, "live", "server-side-applied")
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to render diff: %v", err),
+			})
+			return
+		}
+		response["serverSideApplied"] = gin.H{
+			"spec":        applied,
+			"changes":     appliedChanges,
+			"changeCount": len(appliedChanges),
+			"unifiedDiff": appliedUnified,
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// getGameServerObject fetches a GameServer claim as an unstructured object
+func (s *Server) getGameServerObject(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServer",
+	})
+
+	key := client.ObjectKey{
+		Namespace: namespace,
+		Name:      name,
+	}
+	if err := s.k8sClient.Get(ctx, key, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// normalizeForDiff round-trips a value through JSON so that numbers from
+// unstructured objects (int64) and request bodies (float64) compare equal
+func normalizeForDiff(value interface{}) (interface{}, error) {
+	if value == nil {
+		return map[string]interface{}{}, nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// diffValues recursively compares two JSON values and returns the changed leaf paths
+func diffValues(path string, oldValue, newValue interface{}) []SpecChange {
+	oldMap, oldIsMap := oldValue.(map[string]interface{})
+	newMap, newIsMap := newValue.(map[string]interface{})
+
+	if oldIsMap && newIsMap {
+		keys := make(map[string]struct{}, len(oldMap)+len(newMap))
+		for k := range oldMap {
+			keys[k] = struct{}{}
+		}
+		for k := range newMap {
+			keys[k] = struct{}{}
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for k := range keys {
+			sortedKeys = append(sortedKeys, k)
+		}
+		sort.Strings(sortedKeys)
+
+		changes := []SpecChange{}
+		for _, k := range sortedKeys {
+			childPath := path + "." + k
+			oldChild, inOld := oldMap[k]
+			newChild, inNew := newMap[k]
+			switch {
+			case inOld && !inNew:
+				if !isEmptyValue(oldChild) {
+					changes = append(changes, SpecChange{Path: childPath, Operation: "removed", Old: oldChild})
+				}
+			case !inOld && inNew:
+				if !isEmptyValue(newChild) {
+					changes = append(changes, SpecChange{Path: childPath, Operation: "added", New: newChild})
+				}
+			default:
+				changes = append(changes, diffValues(childPath, oldChild, newChild)...)
+			}
+		}
+		return changes
+	}
+
+	if reflect.DeepEqual(oldValue, newValue) || (isEmptyValue(oldValue) && isEmptyValue(newValue)) {
+		return []SpecChange{}
+	}
+	switch {
+	case isEmptyValue(oldValue):
+		return []SpecChange{{Path: path, Operation: "added", New: newValue}}
+	case isEmptyValue(newValue):
+		return []SpecChange{{Path: path, Operation: "removed", Old: oldValue}}
+	default:
+		return []SpecChange{{Path: path, Operation: "modified", Old: oldValue, New: newValue}}
+	}
+}
+
+// isEmptyValue reports whether a JSON value carries no information. The
+// update handler writes empty strings for unset fields, so these are treated
+// the same as absent keys.
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case map[string]interface{}:
+		for _, child := range v {
+			if !isEmptyValue(child) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// unifiedSpecDiff renders both specs as YAML and returns a unified diff
+func unifiedSpecDiff(oldSpec, newSpec interface{}, oldLabel, newLabel string) (string, error) {
+	oldYAML, err := yaml.Marshal(oldSpec)
+	if err != nil {
+		return "", err
+	}
+	newYAML, err := yaml.Marshal(newSpec)
+	if err != nil {
+		return "", err
+	}
+	return unifiedDiff(oldLabel, newLabel, splitLines(string(oldYAML)), splitLines(string(newYAML))), nil
+}
+
+// splitLines splits text into lines without the trailing empty element
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(text, "\n")
+}
+
+// diffOp is one line of an edit script
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+}
+
+// unifiedDiff produces a unified diff of two line slices using an LCS edit
+// script. Specs are small, so the quadratic table is not a concern.
+func unifiedDiff(oldLabel, newLabel string, oldLines, newLines []string) string {
+	n, m := len(oldLines), len(newLines)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	ops := make([]diffOp, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case oldLines[i] == newLines[j]:
+			ops = append(ops, diffOp{' ', oldLines[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{'-', oldLines[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', newLines[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{'-', oldLines[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{'+', newLines[j]})
+	}
+
+	var out strings.Builder
+	oldLine, newLine := 1, 1
+	for start := 0; start < len(ops); {
+		// Find the next changed line
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+			oldLine++
+			newLine++
+		}
+		if start >= len(ops) {
+			break
+		}
+
+		// Extend the hunk until there is a run of unchanged lines longer than
+		// twice the context size
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run >= len(ops) || run-end > 2*diffContextLines {
+				break
+			}
+			end = run
+		}
+
+		hunkStart := start - diffContextLines
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+		hunkEnd := end + diffContextLines
+		if hunkEnd > len(ops) {
+			hunkEnd = len(ops)
+		}
+
+		leading := start - hunkStart
+		oldStart, newStart := oldLine-leading, newLine-leading
+		oldCount, newCount := 0, 0
+		for _, op := range ops[hunkStart:hunkEnd] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldLabel, newLabel)
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+		for _, op := range ops[hunkStart:hunkEnd] {
+			fmt.Fprintf(&out, "%c%s\n", op.kind, op.line)
+		}
+
+		for _, op := range ops[start:hunkEnd] {
+			if op.kind != '+' {
+				oldLine++
+			}
+			if op.kind != '-' {
+				newLine++
+			}
+		}
+		start = hunkEnd
+	}
+
+	return out.String()
+}
@@ -0,0 +1,786 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/metrics"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// discordBridgesConfigMap holds the Discord bridges in the cluster
+	// registry namespace of the local cluster
+	discordBridgesConfigMap = "gameplane-discord-bridges"
+
+	// discordBridgesKey is the ConfigMap key of the bridge list
+	discordBridgesKey = "bridges.json"
+
+	// discordInteractionsConfigMap remembers recent interaction signatures
+	// in the cluster registry namespace of the local cluster, so every API
+	// replica refuses the same replays
+	discordInteractionsConfigMap = "gameplane-discord-interactions"
+
+	// discordInteractionsKey is the ConfigMap key of the signature ledger
+	discordInteractionsKey = "seen.json"
+
+	// Headers Discord signs interactions with
+	discordSignatureHeader = "X-Signature-Ed25519"
+	discordTimestampHeader = "X-Signature-Timestamp"
+
+	// discordMaxSkew is how far an interaction's timestamp may be from now
+	discordMaxSkew = 5 * time.Minute
+
+	// discordMaxBody bounds an interaction body
+	discordMaxBody = 64 << 10
+
+	// discordFollowupTimeout bounds console commands answered with a
+	// deferred reply; Discord keeps the interaction token for 15 minutes
+	discordFollowupTimeout = 30 * time.Second
+
+	// discordMaxContent keeps replies within Discord's 2000 character limit
+	discordMaxContent = 1900
+)
+
+// Slash commands the bridge answers
+const (
+	discordCommandStatus    = "status"
+	discordCommandPlayers   = "players"
+	discordCommandRestart   = "restart"
+	discordCommandBroadcast = "broadcast"
+	discordCommandBackup    = "backup"
+)
+
+// discordCommands lists every command in the order they are registered
+var discordCommands = []string{discordCommandStatus, discordCommandPlayers, discordCommandRestart, discordCommandBroadcast, discordCommandBackup}
+
+// Interaction and response types of the Discord interactions API
+const (
+	discordInteractionPing    = 1
+	discordInteractionCommand = 2
+
+	discordResponsePong     = 1
+	discordResponseMessage  = 4
+	discordResponseDeferred = 5
+
+	// discordFlagEphemeral shows a reply only to the member who ran the command
+	discordFlagEphemeral = 64
+)
+
+// DiscordBridge answers slash commands from one Discord application. The
+// application's public key authenticates interactions, and the selector
+// scopes which GameServers its commands may reach.
+type DiscordBridge struct {
+	Name          string `json:"name"`
+	ApplicationID string `json:"applicationId"`
+	// PublicKey is the application's hex-encoded Ed25519 public key
+	PublicKey string `json:"publicKey"`
+	// GuildID refuses interactions from other Discord servers when set
+	GuildID string `json:"guildId,omitempty"`
+	// Selector limits the GameServers commands may act on
+	Selector ServerSelector `json:"selector"`
+	// DefaultServer is used when a command names no server, as namespace/name
+	DefaultServer string `json:"defaultServer,omitempty"`
+	// Roles grants commands to members of Discord roles
+	Roles     []DiscordRoleBinding `json:"roles"`
+	CreatedAt time.Time            `json:"createdAt"`
+}
+
+// DiscordRoleBinding grants commands to a Discord role. The guild ID is the
+// role ID of @everyone.
+type DiscordRoleBinding struct {
+	RoleID   string   `json:"roleId"`
+	Commands []string `json:"commands"`
+}
+
+// discordInteraction is the part of a Discord interaction the bridge reads
+type discordInteraction struct {
+	Type          int    `json:"type"`
+	ApplicationID string `json:"application_id"`
+	Token         string `json:"token"`
+	GuildID       string `json:"guild_id"`
+	Data          struct {
+		Name    string `json:"name"`
+		Options []struct {
+			Name  string      `json:"name"`
+			Value interface{} `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+	Member *struct {
+		Roles []string `json:"roles"`
+		User  struct {
+			ID       string `json:"id"`
+			Username string `json:"username"`
+		} `json:"user"`
+	} `json:"member"`
+}
+
+// option returns a command option as a string
+func (i discordInteraction) option(name string) string {
+	for _, option := range i.Data.Options {
+		if option.Name == name {
+			if value, ok := option.Value.(string); ok {
+				return strings.TrimSpace(value)
+			}
+		}
+	}
+	return ""
+}
+
+// allows reports whether the bridge lets the interaction's member run command
+func (b DiscordBridge) allows(i discordInteraction, command string) bool {
+	if i.Member == nil || i.GuildID == "" {
+		return false
+	}
+	roles := append([]string{i.GuildID}, i.Member.Roles...)
+	for _, binding := range b.Roles {
+		if containsString(roles, binding.RoleID) && containsString(binding.Commands, command) {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks a bridge before it is stored
+func (b DiscordBridge) validate() error {
+	if errs := validation.IsDNS1123Label(b.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid name %q: %s", b.Name, strings.Join(errs, ", "))
+	}
+	if b.ApplicationID == "" {
+		return fmt.Errorf("applicationId is required")
+	}
+	if key, err := hex.DecodeString(b.PublicKey); err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("publicKey must be the application's hex-encoded Ed25519 public key")
+	}
+	if err := b.Selector.validate(); err != nil {
+		return err
+	}
+	if b.DefaultServer != "" && !serverRefPattern.MatchString(b.DefaultServer) {
+		return fmt.Errorf("invalid defaultServer %q (expected namespace/name)", b.DefaultServer)
+	}
+	if len(b.Roles) == 0 {
+		return fmt.Errorf("at least one role binding is required")
+	}
+	for _, binding := range b.Roles {
+		if binding.RoleID == "" {
+			return fmt.Errorf("roleId is required")
+		}
+		for _, command := range binding.Commands {
+			if !containsString(discordCommands, command) {
+				return fmt.Errorf("unknown command %q (valid: %s)", command, strings.Join(discordCommands, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// loadDiscordBridges reads all bridges and the ConfigMap holding them,
+// which is nil when none were stored yet
+func (s *Server) loadDiscordBridges(ctx context.Context) ([]DiscordBridge, *corev1.ConfigMap, error) {
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return nil, nil, err
+	}
+	cm, err := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace).Get(ctx, discordBridgesConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return []DiscordBridge{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read Discord bridges: %w", err)
+	}
+	bridges := []DiscordBridge{}
+	if raw := cm.Data[discordBridgesKey]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &bridges); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse Discord bridges: %w", err)
+		}
+	}
+	return bridges, cm, nil
+}
+
+// saveDiscordBridges writes the bridge list back, failing on a concurrent
+// change
+func (s *Server) saveDiscordBridges(ctx context.Context, cm *corev1.ConfigMap, bridges []DiscordBridge) error {
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(bridges)
+	if err != nil {
+		return err
+	}
+	configMaps := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace)
+	if cm == nil {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      discordBridgesConfigMap,
+				Namespace: s.clusters.namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "gameplane-api"},
+			},
+			Data: map[string]string{discordBridgesKey: string(raw)},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[discordBridgesKey] = string(raw)
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// errDiscordReplayed is returned for an interaction received before
+var errDiscordReplayed = errors.New("interaction was already received")
+
+// admitDiscordInteraction records a verified interaction's signature in the
+// shared ledger, failing with errDiscordReplayed for one seen within the
+// allowed clock skew. Hex signatures are compared in lowercase, since
+// verification accepts either case. Writes carry the ConfigMap's
+// resourceVersion, so of two replicas admitting the same interaction only
+// one succeeds; the other re-reads the ledger and finds the replay.
+func (s *Server) admitDiscordInteraction(ctx context.Context, signature string, now time.Time) error {
+	signature = strings.ToLower(signature)
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return err
+	}
+	configMaps := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace)
+	for attempt := 1; ; attempt++ {
+		cm, err := configMaps.Get(ctx, discordInteractionsConfigMap, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			cm, err = nil, nil
+		}
+		if err != nil {
+			return err
+		}
+		seen := map[string]time.Time{}
+		if cm != nil && cm.Data[discordInteractionsKey] != "" {
+			// A damaged ledger starts over rather than locking the bridge out
+			_ = json.Unmarshal([]byte(cm.Data[discordInteractionsKey]), &seen)
+		}
+		for sig, at := range seen {
+			if now.Sub(at) > 2*discordMaxSkew {
+				delete(seen, sig)
+			}
+		}
+		if _, replayed := seen[signature]; replayed {
+			return errDiscordReplayed
+		}
+		seen[signature] = now
+		raw, err := json.Marshal(seen)
+		if err != nil {
+			return err
+		}
+		if cm == nil {
+			_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      discordInteractionsConfigMap,
+					Namespace: s.clusters.namespace,
+					Labels:    map[string]string{"app.kubernetes.io/managed-by": "gameplane-api"},
+				},
+				Data: map[string]string{discordInteractionsKey: string(raw)},
+			}, metav1.CreateOptions{})
+		} else {
+			if cm.Data == nil {
+				cm.Data = map[string]string{}
+			}
+			cm.Data[discordInteractionsKey] = string(raw)
+			_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+		}
+		if err == nil {
+			return nil
+		}
+		if !(apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)) || attempt == 3 {
+			return err
+		}
+	}
+}
+
+// findDiscordBridge returns the named bridge's index, or -1
+func findDiscordBridge(bridges []DiscordBridge, name string) int {
+	for i, bridge := range bridges {
+		if bridge.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// listDiscordBridges returns every bridge
+func (s *Server) listDiscordBridges(c *gin.Context) {
+	bridges, _, err := s.loadDiscordBridges(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	sort.Slice(bridges, func(i, j int) bool { return bridges[i].Name < bridges[j].Name })
+	c.JSON(http.StatusOK, gin.H{
+		"items":    bridges,
+		"total":    len(bridges),
+		"commands": discordCommands,
+	})
+}
+
+// createDiscordBridge stores a new bridge
+func (s *Server) createDiscordBridge(c *gin.Context) {
+	var req DiscordBridge
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	bridges, cm, err := s.loadDiscordBridges(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if findDiscordBridge(bridges, req.Name) >= 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("Discord bridge %s already exists", req.Name),
+		})
+		return
+	}
+	req.CreatedAt = time.Now().UTC()
+	bridges = append(bridges, req)
+	if err := s.saveDiscordBridges(context.TODO(), cm, bridges); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to save Discord bridge: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, req)
+}
+
+// getDiscordBridge returns one bridge
+func (s *Server) getDiscordBridge(c *gin.Context) {
+	bridges, _, err := s.loadDiscordBridges(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	i := findDiscordBridge(bridges, c.Param("bridge"))
+	if i < 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Discord bridge not found",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, bridges[i])
+}
+
+// updateDiscordBridge replaces a bridge, keeping its name and creation time
+func (s *Server) updateDiscordBridge(c *gin.Context) {
+	var req DiscordBridge
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	req.Name = c.Param("bridge")
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	bridges, cm, err := s.loadDiscordBridges(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	i := findDiscordBridge(bridges, req.Name)
+	if i < 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Discord bridge not found",
+		})
+		return
+	}
+	req.CreatedAt = bridges[i].CreatedAt
+	bridges[i] = req
+	if err := s.saveDiscordBridges(context.TODO(), cm, bridges); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to save Discord bridge: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// deleteDiscordBridge removes a bridge; its interactions are refused from
+// then on
+func (s *Server) deleteDiscordBridge(c *gin.Context) {
+	bridges, cm, err := s.loadDiscordBridges(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	i := findDiscordBridge(bridges, c.Param("bridge"))
+	if i < 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Discord bridge not found",
+		})
+		return
+	}
+	bridges = append(bridges[:i], bridges[i+1:]...)
+	if err := s.saveDiscordBridges(context.TODO(), cm, bridges); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete Discord bridge: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Discord bridge deleted",
+	})
+}
+
+// getDiscordCommands returns the slash command definitions to register
+// with Discord's bulk overwrite endpoint
+func (s *Server) getDiscordCommands(c *gin.Context) {
+	serverOption := map[string]interface{}{
+		"type":        3,
+		"name":        "server",
+		"description": "GameServer as namespace/name, or a name in the default server's namespace",
+		"required":    false,
+	}
+	command := func(name, description string, options ...map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"name":        name,
+			"type":        1,
+			"description": description,
+			"options":     append(options, serverOption),
+		}
+	}
+	c.JSON(http.StatusOK, []map[string]interface{}{
+		command(discordCommandStatus, "Show a game server's status"),
+		command(discordCommandPlayers, "List the players online"),
+		command(discordCommandRestart, "Save the world and restart the server", map[string]interface{}{
+			"type":        3,
+			"name":        "reason",
+			"description": "Announced in-game before the restart",
+			"required":    false,
+			"max_length":  128,
+		}),
+		command(discordCommandBroadcast, "Send a message to everyone in-game", map[string]interface{}{
+			"type":        3,
+			"name":        "message",
+			"description": "The message to send",
+			"required":    true,
+			"max_length":  maxBroadcastLength,
+		}),
+		command(discordCommandBackup, "Save the world and take a snapshot"),
+	})
+}
+
+// receiveDiscordInteraction serves a bridge's Discord interactions endpoint
+// without API credentials. Discord signs every interaction with the
+// application's key, and the bridge's role bindings decide which member
+// may run which command.
+func (s *Server) receiveDiscordInteraction(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, discordMaxBody))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": "Request body is too large",
+		})
+		return
+	}
+
+	bridges, _, err := s.loadDiscordBridges(context.TODO())
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Discord bridge is unavailable",
+		})
+		return
+	}
+	i := findDiscordBridge(bridges, c.Param("bridge"))
+	if i < 0 || !validDiscordSignature(bridges[i].PublicKey, c.GetHeader(discordTimestampHeader), body, c.GetHeader(discordSignatureHeader)) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid request signature",
+		})
+		return
+	}
+	bridge := bridges[i]
+	if err := s.admitDiscordInteraction(context.TODO(), c.GetHeader(discordSignatureHeader), time.Now()); err != nil {
+		if errors.Is(err, errDiscordReplayed) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Interaction was already received",
+			})
+			return
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Discord bridge is unavailable",
+		})
+		return
+	}
+
+	var interaction discordInteraction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid interaction: %v", err),
+		})
+		return
+	}
+	switch interaction.Type {
+	case discordInteractionPing:
+		c.JSON(http.StatusOK, gin.H{"type": discordResponsePong})
+		return
+	case discordInteractionCommand:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Unsupported interaction type %d", interaction.Type),
+		})
+		return
+	}
+
+	command := interaction.Data.Name
+	if bridge.GuildID != "" && interaction.GuildID != bridge.GuildID {
+		discordReply(c, "This bridge does not serve this Discord server.", true)
+		return
+	}
+	if !containsString(discordCommands, command) {
+		discordReply(c, fmt.Sprintf("Unknown command /%s.", command), true)
+		return
+	}
+	if !bridge.allows(interaction, command) {
+		discordReply(c, fmt.Sprintf("You are not allowed to run /%s.", command), true)
+		return
+	}
+	obj, err := s.discordTarget(context.TODO(), bridge, interaction.option("server"))
+	if err != nil {
+		discordReply(c, err.Error(), true)
+		return
+	}
+	if command != discordCommandStatus && command != discordCommandPlayers {
+		if state, err := s.currentMaintenance(context.TODO()); err == nil {
+			if window := state.window(obj.GetNamespace(), time.Now()); window != nil {
+				discordReply(c, window.Message, true)
+				return
+			}
+		}
+		log.Printf("Discord bridge %s: %s ran /%s on %s/%s", bridge.Name, interaction.Member.User.Username, command, obj.GetNamespace(), obj.GetName())
+	}
+	s.runDiscordCommand(c, interaction, command, obj)
+}
+
+// runDiscordCommand answers a permitted command for obj. Commands that talk
+// to the game console reply later, as they may outlast Discord's three
+// second deadline.
+func (s *Server) runDiscordCommand(c *gin.Context, interaction discordInteraction, command string, obj *unstructured.Unstructured) {
+	ref := obj.GetNamespace() + "/" + obj.GetName()
+	switch command {
+	case discordCommandStatus:
+		status := publicStatus(obj)
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		if phase == "" {
+			phase = "Pending"
+		}
+		lines := []string{fmt.Sprintf("**%s** (%s) is %s", status.Name, ref, phase)}
+		if status.Online {
+			lines = append(lines, fmt.Sprintf("Players: %d/%d", status.Players, status.MaxPlayers))
+			if status.Endpoint != "" {
+				lines = append(lines, "Connect: `"+status.Endpoint+"`")
+			}
+		}
+		discordReply(c, strings.Join(lines, "\n"), false)
+	case discordCommandPlayers:
+		s.discordDeferred(c, interaction, func(ctx context.Context) string {
+			// Without a console the count from the status still answers
+			output, err := s.listPlayersInGame(ctx, obj)
+			if err != nil {
+				if !errors.Is(err, errConsoleUnsupported) {
+					log.Printf("Failed to list players of %s for Discord: %v", ref, err)
+				}
+				return fmt.Sprintf("%d/%d players online on %s", gameServerPlayers(obj), gameServerCapacity(obj), ref)
+			}
+			return fmt.Sprintf("Players on %s:\n```\n%s\n```", ref, strings.TrimSpace(output))
+		})
+	case discordCommandBroadcast:
+		message := interaction.option("message")
+		if message == "" || utf8.RuneCountInString(message) > maxBroadcastLength {
+			discordReply(c, fmt.Sprintf("The message must be 1 to %d characters.", maxBroadcastLength), true)
+			return
+		}
+		s.discordDeferred(c, interaction, func(ctx context.Context) string {
+			if _, err := s.broadcastInGame(ctx, obj, message); err != nil {
+				return fmt.Sprintf("Failed to broadcast on %s: %v", ref, err)
+			}
+			return fmt.Sprintf("Broadcast on %s: %s", ref, message)
+		})
+	case discordCommandRestart:
+		op := s.startSavedRestart("discord-restart", obj, interaction.option("reason"))
+		discordReply(c, fmt.Sprintf("Restarting %s (operation `%s`).", ref, op.ID), false)
+	case discordCommandBackup:
+		if s.snapshots.class == "" {
+			discordReply(c, "Backups need a VolumeSnapshotClass; none is configured.", true)
+			return
+		}
+		op := s.startSavedBackup("discord-backup", obj)
+		discordReply(c, fmt.Sprintf("Backing up %s (operation `%s`).", ref, op.ID), false)
+	}
+}
+
+// discordTarget resolves a command's server option within the bridge's
+// selector, falling back to the default server
+func (s *Server) discordTarget(ctx context.Context, bridge DiscordBridge, ref string) (*unstructured.Unstructured, error) {
+	if ref == "" {
+		ref = bridge.DefaultServer
+	}
+	if ref == "" {
+		return nil, fmt.Errorf("Name a server; this bridge has no default server.")
+	}
+	if !strings.Contains(ref, "/") {
+		namespace, _, found := strings.Cut(bridge.DefaultServer, "/")
+		if !found {
+			return nil, fmt.Errorf("Name the server as namespace/name.")
+		}
+		ref = namespace + "/" + ref
+	}
+	if !serverRefPattern.MatchString(ref) {
+		return nil, fmt.Errorf("%q is not a valid server; use namespace/name.", ref)
+	}
+	namespace, name, _ := strings.Cut(ref, "/")
+	obj, err := s.getGameServerObject(ctx, namespace, name)
+	if client.IgnoreNotFound(err) != nil {
+		return nil, fmt.Errorf("Failed to get %s: %v", ref, err)
+	}
+	// Servers outside the selector get the same answer as missing ones
+	if err != nil || !bridge.Selector.matches(obj) {
+		return nil, fmt.Errorf("Server %s was not found.", ref)
+	}
+	return obj, nil
+}
+
+// discordReply answers an interaction with a message; ephemeral ones are
+// only shown to the member who ran the command
+func discordReply(c *gin.Context, content string, ephemeral bool) {
+	data := gin.H{
+		"content":          truncateDiscordContent(content),
+		"allowed_mentions": gin.H{"parse": []string{}},
+	}
+	if ephemeral {
+		data["flags"] = discordFlagEphemeral
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"type": discordResponseMessage,
+		"data": data,
+	})
+}
+
+// discordDeferred acknowledges an interaction and edits the reply with
+// run's result once it finishes
+func (s *Server) discordDeferred(c *gin.Context, interaction discordInteraction, run func(ctx context.Context) string) {
+	c.JSON(http.StatusOK, gin.H{"type": discordResponseDeferred})
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), discordFollowupTimeout)
+		defer cancel()
+		content := run(ctx)
+		if err := editDiscordReply(context.Background(), interaction, content); err != nil {
+			log.Printf("Failed to answer Discord /%s: %v", interaction.Data.Name, err)
+		}
+	}()
+}
+
+// editDiscordReply replaces a deferred reply's content
+func editDiscordReply(ctx context.Context, interaction discordInteraction, content string) error {
+	raw, err := json.Marshal(gin.H{
+		"content":          truncateDiscordContent(content),
+		"allowed_mentions": gin.H{"parse": []string{}},
+	})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/webhooks/%s/%s/messages/@original", discordAPIURL, interaction.ApplicationID, interaction.Token)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, url, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gameplane-api")
+
+	start := time.Now()
+	resp, err := webhookClient.Do(req)
+	metrics.ObserveDependency(metrics.DependencyWebhook, "discord-interaction", start, err != nil || resp.StatusCode >= 300)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Discord returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// validDiscordSignature checks Discord's Ed25519 signature of the timestamp
+// followed by the body
+func validDiscordSignature(publicKey, timestamp string, body []byte, signature string) bool {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return false
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := time.Since(time.Unix(unix, 0)); skew > discordMaxSkew || skew < -discordMaxSkew {
+		return false
+	}
+	return ed25519.Verify(ed25519.PublicKey(key), append([]byte(timestamp), body...), sig)
+}
+
+// truncateDiscordContent fits a reply into one Discord message
+func truncateDiscordContent(content string) string {
+	if len(content) <= discordMaxContent {
+		return content
+	}
+	cut := discordMaxContent
+	for cut > 0 && !utf8.RuneStart(content[cut]) {
+		cut--
+	}
+	content = content[:cut] + "…"
+	if strings.Count(content, "```")%2 == 1 {
+		content += "\n```"
+	}
+	return content
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubelize/gameplane/api/internal/devcluster"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDiscordReplaysAreShared(t *testing.T) {
+	s, err := NewServer(Options{DevCluster: devcluster.Demo(), Authenticator: headerAuth{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	now := time.Now()
+	if err := s.admitDiscordInteraction(ctx, "abcdef", now); err != nil {
+		t.Fatalf("first interaction: %v", err)
+	}
+
+	// The ledger lives in the cluster, where every replica reads it
+	cm, err := s.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace).Get(ctx, discordInteractionsConfigMap, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(cm.Data[discordInteractionsKey], "abcdef") {
+		t.Errorf("ledger does not hold the signature: %s", cm.Data[discordInteractionsKey])
+	}
+
+	if err := s.admitDiscordInteraction(ctx, "ABCDEF", now.Add(time.Second)); !errors.Is(err, errDiscordReplayed) {
+		t.Errorf("replayed interaction: got %v, want errDiscordReplayed", err)
+	}
+	if err := s.admitDiscordInteraction(ctx, "abcdef", now.Add(2*discordMaxSkew+time.Second)); err != nil {
+		t.Errorf("interaction past the skew window: %v", err)
+	}
+}
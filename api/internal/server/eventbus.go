@@ -0,0 +1,305 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubelize/gameplane/api/internal/metrics"
+)
+
+const (
+	// eventSchemaVersion identifies the JSON schema of published events,
+	// documented in EVENTS.md
+	eventSchemaVersion = "gameplane.kubelize.io/events/v1"
+
+	// defaultEventSubject is the NATS subject prefix or Kafka topic
+	defaultEventSubject = "gameplane.events"
+
+	// eventBufferSize bounds events waiting to be published; newer events
+	// are dropped while the bus is unreachable and the buffer is full
+	eventBufferSize = 1024
+
+	// eventPublishTimeout bounds a single publish
+	eventPublishTimeout = 10 * time.Second
+)
+
+// Event types published to the bus
+const (
+	eventGameServerCreated         = "gameserver.created"
+	eventGameServerUpdated         = "gameserver.updated"
+	eventGameServerDeleted         = "gameserver.deleted"
+	eventGameServerReady           = "gameserver.ready"
+	eventGameServerDown            = "gameserver.down"
+	eventGameServerWiped           = "gameserver.wiped"
+	eventGameServerExpired         = "gameserver.expired"
+	eventGameServerRenamed         = "gameserver.renamed"
+	eventGameServerRemediated      = "gameserver.remediated"
+	eventGameServerUpdateAvailable = "gameserver.update-available"
+	eventPlayersChanged            = "players.changed"
+	eventAlertFiring               = "alert.firing"
+	eventAlertResolved             = "alert.resolved"
+	eventHookReceived              = "hook.received"
+)
+
+// Event is a GameServer lifecycle or player event as published to the bus
+type Event struct {
+	SchemaVersion string                 `json:"schemaVersion"`
+	ID            string                 `json:"id"`
+	Type          string                 `json:"type"`
+	Time          time.Time              `json:"time"`
+	Cluster       string                 `json:"cluster"`
+	Namespace     string                 `json:"namespace"`
+	Name          string                 `json:"name"`
+	Data          map[string]interface{} `json:"data,omitempty"`
+}
+
+// eventPublisher delivers encoded events to a message bus
+type eventPublisher interface {
+	publish(ctx context.Context, event Event, payload []byte) error
+}
+
+// eventBus queues events and publishes them in the background. It is shared
+// by all clusters; without EVENT_BUS_URL it discards events.
+type eventBus struct {
+	events    chan Event
+	publisher eventPublisher
+}
+
+// newEventBus configures the bus from EVENT_BUS_URL and EVENT_BUS_SUBJECT.
+// The URL is nats://[user:pass@]host:port for NATS, or kafka+http(s)://host
+// for a Kafka REST proxy.
+func newEventBus() (*eventBus, error) {
+	bus := &eventBus{events: make(chan Event, eventBufferSize)}
+	raw := os.Getenv("EVENT_BUS_URL")
+	if raw == "" {
+		return bus, nil
+	}
+	subject := os.Getenv("EVENT_BUS_SUBJECT")
+	if subject == "" {
+		subject = defaultEventSubject
+	}
+
+	target, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EVENT_BUS_URL: %w", err)
+	}
+	switch target.Scheme {
+	case "nats":
+		bus.publisher = &natsPublisher{target: target, subject: subject}
+	case "kafka+http", "kafka+https":
+		proxy := *target
+		proxy.Scheme = strings.TrimPrefix(target.Scheme, "kafka+")
+		bus.publisher = &kafkaRESTPublisher{proxy: proxy.String(), topic: subject}
+	default:
+		return nil, fmt.Errorf("unsupported EVENT_BUS_URL scheme %q (valid: nats, kafka+http, kafka+https)", target.Scheme)
+	}
+	return bus, nil
+}
+
+// run publishes queued events until the context ends
+func (b *eventBus) run(ctx context.Context) {
+	if b.publisher == nil {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-b.events:
+			payload, err := json.Marshal(event)
+			if err != nil {
+				log.Printf("Failed to encode %s event: %v", event.Type, err)
+				continue
+			}
+			publishCtx, cancel := context.WithTimeout(ctx, eventPublishTimeout)
+			start := time.Now()
+			err = b.publisher.publish(publishCtx, event, payload)
+			metrics.ObserveDependency(metrics.DependencyEventBus, "publish", start, err != nil)
+			if err != nil {
+				log.Printf("Failed to publish %s event for %s/%s: %v", event.Type, event.Namespace, event.Name, err)
+			}
+			cancel()
+		}
+	}
+}
+
+// publishEvent queues an event about a GameServer without blocking
+func (s *Server) publishEvent(kind, namespace, name string, data map[string]interface{}) {
+	if s.events == nil || s.events.publisher == nil {
+		return
+	}
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	event := Event{
+		SchemaVersion: eventSchemaVersion,
+		ID:            hex.EncodeToString(id),
+		Type:          kind,
+		Time:          time.Now().UTC(),
+		Cluster:       s.cluster,
+		Namespace:     namespace,
+		Name:          name,
+		Data:          data,
+	}
+	select {
+	case s.events.events <- event:
+	default:
+		log.Printf("Event buffer full, dropping %s event for %s/%s", kind, namespace, name)
+	}
+}
+
+// natsPublisher publishes to NATS core subjects {subject}.{type} over the
+// plain text protocol, reconnecting when the connection drops
+type natsPublisher struct {
+	target  *url.URL
+	subject string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// publish sends one message, retrying once on a fresh connection
+func (p *natsPublisher) publish(ctx context.Context, event Event, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	subject := p.subject + "." + event.Type
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if p.conn == nil {
+			if err = p.connect(ctx); err != nil {
+				continue
+			}
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			_ = p.conn.SetWriteDeadline(deadline)
+		}
+		var msg bytes.Buffer
+		fmt.Fprintf(&msg, "PUB %s %d\r\n", subject, len(payload))
+		msg.Write(payload)
+		msg.WriteString("\r\n")
+		if _, err = p.conn.Write(msg.Bytes()); err == nil {
+			return nil
+		}
+		p.conn.Close()
+		p.conn = nil
+	}
+	return err
+}
+
+// connect opens a connection and sends CONNECT; callers hold the lock
+func (p *natsPublisher) connect(ctx context.Context) error {
+	var dialer net.Dialer
+	host := p.target.Host
+	if p.target.Port() == "" {
+		host = net.JoinHostPort(p.target.Hostname(), "4222")
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(conn)
+	_ = conn.SetReadDeadline(time.Now().Add(eventPublishTimeout))
+	info, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(info, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("unexpected NATS greeting %q: %v", strings.TrimSpace(info), err)
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	options := map[string]interface{}{"verbose": false, "pedantic": false, "name": "gameplane-api", "lang": "go"}
+	if user := p.target.User; user != nil {
+		if password, ok := user.Password(); ok {
+			options["user"], options["pass"] = user.Username(), password
+		} else {
+			options["auth_token"] = user.Username()
+		}
+	}
+	raw, _ := json.Marshal(options)
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\n", raw); err != nil {
+		conn.Close()
+		return err
+	}
+	p.conn = conn
+	go p.readLoop(conn, reader)
+	return nil
+}
+
+// readLoop answers server pings and drops the connection on errors
+func (p *natsPublisher) readLoop(conn net.Conn, reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			p.mu.Lock()
+			_ = conn.SetWriteDeadline(time.Now().Add(eventPublishTimeout))
+			_, err = io.WriteString(conn, "PONG\r\n")
+			p.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			log.Printf("NATS error: %s", strings.TrimSpace(line))
+		}
+		if err != nil {
+			break
+		}
+	}
+	p.mu.Lock()
+	if p.conn == conn {
+		p.conn = nil
+	}
+	p.mu.Unlock()
+	conn.Close()
+}
+
+// kafkaRESTPublisher produces to a Kafka topic through a Confluent-compatible
+// REST proxy, keyed by namespace/name so a server's events stay ordered
+type kafkaRESTPublisher struct {
+	proxy string
+	topic string
+}
+
+// publish produces one record
+func (p *kafkaRESTPublisher) publish(ctx context.Context, event Event, payload []byte) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"records": []map[string]interface{}{{
+			"key":   event.Namespace + "/" + event.Name,
+			"value": json.RawMessage(payload),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	endpoint := strings.TrimSuffix(p.proxy, "/") + "/topics/" + url.PathEscape(p.topic)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Kafka REST proxy returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
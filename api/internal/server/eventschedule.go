@@ -0,0 +1,534 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// eventScheduleAnnotation holds a GameServer's EventSchedule as JSON
+	eventScheduleAnnotation = "gameplane.kubelize.io/event-schedule"
+
+	// lastEventRunsAnnotation holds the last EventRun of each event as JSON
+	lastEventRunsAnnotation = "gameplane.kubelize.io/last-event-runs"
+
+	// eventSchedulerInterval is how often event schedules are evaluated
+	eventSchedulerInterval = 30 * time.Second
+
+	// maxScheduledEvents and maxEventSteps keep the annotation small
+	maxScheduledEvents = 16
+	maxEventSteps      = 10
+
+	// maxEventCommandLength keeps commands within what game consoles accept
+	maxEventCommandLength = 256
+
+	// maxEventStepWait bounds the pause after a step
+	maxEventStepWait = time.Hour
+
+	// eventRunOverhead is added to the waits of an event to bound its run
+	eventRunOverhead = 10 * time.Minute
+)
+
+// Actions of an event step
+const (
+	eventActionBroadcast = "broadcast"
+	eventActionCommand   = "command"
+	eventActionRestart   = "restart"
+)
+
+// EventSchedule lists the in-game events of a GameServer
+type EventSchedule struct {
+	Events []ScheduledEvent `json:"events"`
+}
+
+// ScheduledEvent runs a sequence of steps at the times of its schedule,
+// such as a weekly boss event or a loot multiplier weekend
+type ScheduledEvent struct {
+	Name     string   `json:"name"`
+	Enabled  bool     `json:"enabled"`
+	Schedule Schedule `json:"schedule"`
+	// Announce lists durations before the event to tell players it is
+	// coming up
+	Announce []string    `json:"announce,omitempty"`
+	Steps    []EventStep `json:"steps"`
+}
+
+// EventStep is one step of a scheduled event
+type EventStep struct {
+	// Action is broadcast, command or restart. A restart saves the world
+	// first and must be the last step.
+	Action string `json:"action"`
+	// Message is the announcement of a broadcast step
+	Message string `json:"message,omitempty"`
+	// Command is the console command of a command step
+	Command string `json:"command,omitempty"`
+	// Wait pauses after the step, e.g. "5m"
+	Wait string `json:"wait,omitempty"`
+}
+
+// EventRun records the latest run of a scheduled event
+type EventRun struct {
+	Time time.Time `json:"time"`
+	// Trigger is scheduled or manual
+	Trigger   string `json:"trigger"`
+	Operation string `json:"operation"`
+}
+
+// eventSchedulerState remembers when each schedule was last evaluated so
+// announcements and events fire once per window
+type eventSchedulerState struct {
+	mu        sync.Mutex
+	lastCheck map[string]time.Time
+	running   map[string]bool
+}
+
+// validate checks an event schedule before it is stored
+func (schedule EventSchedule) validate(def GameDefinition) error {
+	if len(schedule.Events) > maxScheduledEvents {
+		return fmt.Errorf("a GameServer has at most %d scheduled events", maxScheduledEvents)
+	}
+	seen := map[string]bool{}
+	for _, event := range schedule.Events {
+		if errs := validation.IsDNS1123Label(event.Name); len(errs) > 0 {
+			return fmt.Errorf("invalid event name %q: %s", event.Name, errs[0])
+		}
+		if seen[event.Name] {
+			return fmt.Errorf("event %s is scheduled twice", event.Name)
+		}
+		seen[event.Name] = true
+		if err := event.Schedule.validate(); err != nil {
+			return fmt.Errorf("event %s: %w", event.Name, err)
+		}
+		for _, announce := range event.Announce {
+			if d, err := time.ParseDuration(announce); err != nil || d <= 0 {
+				return fmt.Errorf("event %s: invalid announce duration %q", event.Name, announce)
+			}
+		}
+		if len(event.Steps) == 0 || len(event.Steps) > maxEventSteps {
+			return fmt.Errorf("event %s needs between 1 and %d steps", event.Name, maxEventSteps)
+		}
+		for i, step := range event.Steps {
+			if err := step.validate(def, i == len(event.Steps)-1); err != nil {
+				return fmt.Errorf("event %s step %d: %w", event.Name, i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validate checks one step; last is set for the final step of its event
+func (step EventStep) validate(def GameDefinition, last bool) error {
+	switch step.Action {
+	case eventActionBroadcast:
+		if strings.TrimSpace(step.Message) == "" {
+			return fmt.Errorf("a broadcast needs a message")
+		}
+		if utf8.RuneCountInString(step.Message) > maxBroadcastLength {
+			return fmt.Errorf("message must be at most %d characters", maxBroadcastLength)
+		}
+	case eventActionCommand:
+		if strings.TrimSpace(step.Command) == "" {
+			return fmt.Errorf("a command step needs a command")
+		}
+		if len(step.Command) > maxEventCommandLength || strings.ContainsAny(step.Command, "\r\n") {
+			return fmt.Errorf("command must be a single line of at most %d characters", maxEventCommandLength)
+		}
+	case eventActionRestart:
+		if !last {
+			return fmt.Errorf("restart must be the last step")
+		}
+	default:
+		return fmt.Errorf("unsupported action %q (valid: %s, %s, %s)", step.Action, eventActionBroadcast, eventActionCommand, eventActionRestart)
+	}
+	if step.Action != eventActionRestart && def.Console == nil {
+		return errConsoleUnsupported
+	}
+	if step.Wait != "" {
+		if d, err := time.ParseDuration(step.Wait); err != nil || d < 0 || d > maxEventStepWait {
+			return fmt.Errorf("invalid wait %q (at most %s)", step.Wait, maxEventStepWait)
+		}
+	}
+	return nil
+}
+
+// getEventSchedule returns the event schedule, each enabled event's next
+// run and the last run of each event
+func (s *Server) getEventSchedule(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	schedule, err := eventSchedule(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if schedule == nil {
+		schedule = &EventSchedule{Events: []ScheduledEvent{}}
+	}
+	c.JSON(http.StatusOK, eventScheduleResponse(obj, *schedule))
+}
+
+// putEventSchedule replaces the GameServer's event schedule
+func (s *Server) putEventSchedule(c *gin.Context) {
+	var schedule EventSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if schedule.Events == nil {
+		schedule.Events = []ScheduledEvent{}
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, _ := lookupGame(gameType)
+	if err := schedule.validate(def); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	raw, err := json.Marshal(schedule)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[eventScheduleAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update event schedule: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, eventScheduleResponse(obj, schedule))
+}
+
+// deleteEventSchedule removes the GameServer's event schedule
+func (s *Server) deleteEventSchedule(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	annotations := obj.GetAnnotations()
+	if _, found := annotations[eventScheduleAnnotation]; !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "GameServer has no event schedule",
+		})
+		return
+	}
+	delete(annotations, eventScheduleAnnotation)
+	delete(annotations, lastEventRunsAnnotation)
+	obj.SetAnnotations(annotations)
+
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete event schedule: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Event schedule deleted",
+	})
+}
+
+// runScheduledEvent runs one event of the schedule now, whether or not it
+// is enabled
+func (s *Server) runScheduledEvent(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	schedule, err := eventSchedule(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	name := c.Param("event")
+	if schedule != nil {
+		for _, event := range schedule.Events {
+			if event.Name == name {
+				c.JSON(http.StatusAccepted, s.startScheduledEvent(obj, event, "manual", nil))
+				return
+			}
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": fmt.Sprintf("GameServer has no scheduled event %s", name),
+	})
+}
+
+// eventScheduleResponse adds the next and last runs to a schedule
+func eventScheduleResponse(obj *unstructured.Unstructured, schedule EventSchedule) gin.H {
+	now := time.Now()
+	next := map[string]time.Time{}
+	for _, event := range schedule.Events {
+		if !event.Enabled {
+			continue
+		}
+		if at, ok := event.Schedule.next(now); ok {
+			next[event.Name] = at
+		}
+	}
+	return gin.H{
+		"schedule": schedule,
+		"nextRuns": next,
+		"lastRuns": lastEventRuns(obj),
+	}
+}
+
+// startScheduledEvent starts an operation running the steps of an event in
+// order. Broadcasts and commands share one console connection; a step that
+// fails ends the run. done, if set, is called once the run ends.
+func (s *Server) startScheduledEvent(obj *unstructured.Unstructured, event ScheduledEvent, trigger string, done func()) Operation {
+	steps := make([]string, len(event.Steps))
+	timeout := eventRunOverhead
+	for i, step := range event.Steps {
+		steps[i] = fmt.Sprintf("%d-%s", i+1, step.Action)
+		if wait, err := time.ParseDuration(step.Wait); err == nil {
+			timeout += wait
+		}
+	}
+
+	op := s.startOperation("scheduled-event", obj.GetNamespace(), obj.GetName(), steps, timeout, func(ctx context.Context, t *operationTracker) (interface{}, error) {
+		var console gameConsole
+		defer func() {
+			if console != nil {
+				console.Close()
+			}
+			if done != nil {
+				done()
+			}
+		}()
+		results := []string{}
+		for i, step := range event.Steps {
+			var output string
+			err := t.step(steps[i], func() (string, error) {
+				var err error
+				if step.Action == eventActionRestart {
+					output, err = s.restartForEvent(ctx, obj)
+					return output, err
+				}
+				if console == nil {
+					if console, err = s.openGameConsole(ctx, obj); err != nil {
+						return "", err
+					}
+				}
+				gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+				def, _ := lookupGame(gameType)
+				command := step.Command
+				if step.Action == eventActionBroadcast {
+					command = sayCommand(def.Console, step.Message)
+				}
+				if output, err = console.Exec(command); err != nil {
+					// Reconnect for the next attempt rather than reuse a
+					// broken connection
+					console.Close()
+					console = nil
+					return "", err
+				}
+				output = strings.TrimSpace(output)
+				return output, nil
+			})
+			if err != nil {
+				return gin.H{"event": event.Name, "trigger": trigger, "output": results}, err
+			}
+			results = append(results, output)
+			if wait, err := time.ParseDuration(step.Wait); err == nil && wait > 0 {
+				select {
+				case <-ctx.Done():
+					return gin.H{"event": event.Name, "trigger": trigger, "output": results}, ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+		}
+		return gin.H{"event": event.Name, "trigger": trigger, "output": results}, nil
+	})
+
+	if err := s.recordEventRun(context.Background(), obj, event.Name, EventRun{Time: op.CreatedAt, Trigger: trigger, Operation: op.ID}); err != nil {
+		log.Printf("Failed to record run of event %s of %s/%s: %v", event.Name, obj.GetNamespace(), obj.GetName(), err)
+	}
+	s.recordActivity(Activity{
+		Kind:      activityLifecycle,
+		Type:      "scheduled-event",
+		Actor:     systemActor,
+		Cluster:   s.cluster,
+		Namespace: obj.GetNamespace(),
+		Server:    obj.GetName(),
+		Summary:   fmt.Sprintf("Started %s event %s (operation %s)", trigger, event.Name, op.ID),
+	})
+	return op
+}
+
+// restartForEvent saves the world and restarts the server pods
+func (s *Server) restartForEvent(ctx context.Context, obj *unstructured.Unstructured) (string, error) {
+	saved := s.saveBeforeAction(ctx, obj, "scheduled-event")
+	pods, namespace, err := s.findGameServerPods(ctx, obj)
+	if err != nil {
+		return "", err
+	}
+	if len(pods) == 0 {
+		return "", fmt.Errorf("no pods found")
+	}
+	restarted, err := s.deleteGameServerPods(ctx, namespace, pods, nil)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s; restarted %s", saved, strings.Join(restarted, ", ")), nil
+}
+
+// recordEventRun stores the latest run of an event on the GameServer,
+// re-reading it so the record is not lost to a conflict
+func (s *Server) recordEventRun(ctx context.Context, obj *unstructured.Unstructured, name string, run EventRun) error {
+	latest, err := s.getGameServerObject(ctx, obj.GetNamespace(), obj.GetName())
+	if err != nil {
+		return err
+	}
+	runs := lastEventRuns(latest)
+	runs[name] = run
+	raw, err := json.Marshal(runs)
+	if err != nil {
+		return err
+	}
+	annotations := latest.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[lastEventRunsAnnotation] = string(raw)
+	latest.SetAnnotations(annotations)
+	return s.k8sClient.Update(ctx, latest)
+}
+
+// runEventSchedules announces upcoming scheduled events and starts due ones
+func (s *Server) runEventSchedules(ctx context.Context) error {
+	list, err := s.listAllGameServers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+
+	now := time.Now()
+	state := s.eventScheduler
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.lastCheck == nil {
+		state.lastCheck = map[string]time.Time{}
+		state.running = map[string]bool{}
+	}
+
+	seen := map[string]bool{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		schedule, err := eventSchedule(obj)
+		if err != nil || schedule == nil {
+			continue
+		}
+		for _, event := range schedule.Events {
+			if !event.Enabled {
+				continue
+			}
+			key := obj.GetNamespace() + "/" + obj.GetName() + "/" + event.Name
+			seen[key] = true
+
+			// The first evaluation only sets the window; events missed
+			// while the API was down are skipped rather than run late
+			lastCheck, known := state.lastCheck[key]
+			state.lastCheck[key] = now
+			if !known || state.running[key] {
+				continue
+			}
+			next, ok := event.Schedule.next(lastCheck)
+			if !ok {
+				continue
+			}
+
+			for _, announce := range event.Announce {
+				before, err := time.ParseDuration(announce)
+				if err != nil {
+					continue
+				}
+				at := next.Add(-before)
+				if at.After(lastCheck) && !at.After(now) {
+					message := fmt.Sprintf("%s starts in %s", event.Name, humanizeDuration(before))
+					if _, err := s.broadcastInGame(ctx, obj, message); err != nil && !errors.Is(err, errConsoleUnsupported) {
+						log.Printf("Event announcement for %s failed: %v", key, err)
+					}
+				}
+			}
+
+			if next.After(now) {
+				continue
+			}
+			state.running[key] = true
+			finished := func(key string) func() {
+				return func() {
+					state.mu.Lock()
+					delete(state.running, key)
+					state.mu.Unlock()
+				}
+			}(key)
+			s.startScheduledEvent(obj, event, "scheduled", finished)
+		}
+	}
+	for key := range state.lastCheck {
+		if !seen[key] {
+			delete(state.lastCheck, key)
+		}
+	}
+	return nil
+}
+
+// eventSchedule reads the event schedule annotation, returning nil when
+// unset
+func eventSchedule(obj *unstructured.Unstructured) (*EventSchedule, error) {
+	raw, ok := obj.GetAnnotations()[eventScheduleAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var schedule EventSchedule
+	if err := json.Unmarshal([]byte(raw), &schedule); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", eventScheduleAnnotation, err)
+	}
+	return &schedule, nil
+}
+
+// lastEventRuns reads the last run of each event
+func lastEventRuns(obj *unstructured.Unstructured) map[string]EventRun {
+	runs := map[string]EventRun{}
+	if raw, ok := obj.GetAnnotations()[lastEventRunsAnnotation]; ok {
+		if err := json.Unmarshal([]byte(raw), &runs); err != nil {
+			log.Printf("Ignoring invalid %s annotation of GameServer %s/%s: %v", lastEventRunsAnnotation, obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	return runs
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fieldSelection is a parsed ?fields= parameter: a tree of the JSON paths a
+// client wants back. A nil selection keeps everything.
+type fieldSelection map[string]fieldSelection
+
+// parseFieldSelection reads ?fields=metadata.name,status.phase. Selecting a
+// field keeps everything below it; paths through lists apply to every
+// element, e.g. status.conditions.type.
+func parseFieldSelection(c *gin.Context) (fieldSelection, error) {
+	raw := c.Query("fields")
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	selection := fieldSelection{}
+	for _, path := range splitList(raw) {
+		node := selection
+		parts := strings.Split(path, ".")
+		for i, part := range parts {
+			if part == "" {
+				return nil, fmt.Errorf("invalid field %q", path)
+			}
+			child, seen := node[part]
+			if i == len(parts)-1 {
+				// The whole subtree is wanted, overriding narrower paths
+				node[part] = nil
+				break
+			}
+			if seen && child == nil {
+				// A broader path already selects this subtree
+				break
+			}
+			if child == nil {
+				child = fieldSelection{}
+				node[part] = child
+			}
+			node = child
+		}
+	}
+	return selection, nil
+}
+
+// apply returns v reduced to the selected fields, going through its JSON form
+func (f fieldSelection) apply(v interface{}) (interface{}, error) {
+	if f == nil {
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return f.pick(generic), nil
+}
+
+func (f fieldSelection) pick(v interface{}) interface{} {
+	if f == nil {
+		return v
+	}
+	switch value := v.(type) {
+	case map[string]interface{}:
+		picked := map[string]interface{}{}
+		for key, child := range f {
+			if field, ok := value[key]; ok {
+				picked[key] = child.pick(field)
+			}
+		}
+		return picked
+	case []interface{}:
+		picked := make([]interface{}, len(value))
+		for i, item := range value {
+			picked[i] = f.pick(item)
+		}
+		return picked
+	}
+	// Selecting below a scalar yields the scalar unchanged
+	return v
+}
+
+// selectFields reduces v, or each element of a slice v, to the fields
+// requested with ?fields=. On a bad selection it responds with 400 and
+// returns false.
+func selectFields(c *gin.Context, v interface{}) (interface{}, bool) {
+	selection, err := parseFieldSelection(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid fields parameter: %v", err),
+		})
+		return nil, false
+	}
+	shaped, err := selection.apply(v)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to select fields: %v", err),
+		})
+		return nil, false
+	}
+	return shaped, true
+}
@@ -0,0 +1,831 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// fleetLabel marks GameServers that belong to a Fleet
+	fleetLabel = "gameplane.kubelize.io/fleet"
+
+	// fleetTemplateHashAnnotation records the template a member was last synced to
+	fleetTemplateHashAnnotation = "gameplane.kubelize.io/fleet-template-hash"
+
+	// fleetReconcileInterval is how often fleets are reconciled in the background
+	fleetReconcileInterval = 15 * time.Second
+
+	// defaultSpreadTopologyKey spreads fleet members across nodes
+	defaultSpreadTopologyKey = "kubernetes.io/hostname"
+)
+
+var fleetGVK = schema.GroupVersionKind{
+	Group:   "gameplane.kubelize.io",
+	Version: "v1alpha1",
+	Kind:    "Fleet",
+}
+
+// FleetSpec describes a group of identical GameServers
+type FleetSpec struct {
+	Replicas int           `json:"replicas"`
+	Template FleetTemplate `json:"template"`
+	Spread   *FleetSpread  `json:"spread,omitempty"`
+	// Autoscaler manages Replicas from player occupancy when enabled
+	Autoscaler *FleetAutoscaler `json:"autoscaler,omitempty"`
+}
+
+// FleetTemplate is the GameServer every fleet member is created from
+type FleetTemplate struct {
+	Labels map[string]string `json:"labels,omitempty"`
+	Spec   GameServerSpec    `json:"spec"`
+}
+
+// FleetSpread spreads member game pods across a node topology
+type FleetSpread struct {
+	TopologyKey string `json:"topologyKey,omitempty"`
+	// Required refuses to co-locate members instead of preferring not to
+	Required bool `json:"required,omitempty"`
+}
+
+// FleetStatus aggregates the state of a fleet's members
+type FleetStatus struct {
+	Replicas      int `json:"replicas"`
+	ReadyReplicas int `json:"readyReplicas"`
+	// AllocatedReplicas counts members handed out by allocate
+	AllocatedReplicas int           `json:"allocatedReplicas"`
+	UpdatedReplicas   int           `json:"updatedReplicas"`
+	Players           int           `json:"players"`
+	Capacity          int           `json:"capacity"`
+	TemplateHash      string        `json:"templateHash,omitempty"`
+	Servers           []FleetMember `json:"servers,omitempty"`
+	LastUpdate        *metav1.Time  `json:"lastUpdate,omitempty"`
+	// Autoscaler is the autoscaler's last decision
+	Autoscaler *FleetAutoscalerStatus `json:"autoscaler,omitempty"`
+	// Canary is the canary running on the fleet, if any
+	Canary *FleetCanaryState `json:"canary,omitempty"`
+}
+
+// FleetMember summarizes one GameServer in a fleet
+type FleetMember struct {
+	Name      string `json:"name"`
+	Phase     string `json:"phase,omitempty"`
+	Ready     bool   `json:"ready"`
+	Allocated bool   `json:"allocated"`
+	Players   int    `json:"players"`
+	Capacity  int    `json:"capacity,omitempty"`
+	Updated   bool   `json:"updated"`
+}
+
+// Fleet is a group of identical GameServers managed as one unit
+type Fleet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              FleetSpec   `json:"spec"`
+	Status            FleetStatus `json:"status,omitempty"`
+}
+
+// listFleets returns Fleets in a namespace, or every namespace the caller
+// may read with "all"
+func (s *Server) listFleets(c *gin.Context) {
+	namespace := c.DefaultQuery("namespace", "default")
+	if namespace == "all" {
+		namespace = ""
+	}
+
+	scope, err := s.readableNamespaces(c)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to determine readable namespaces: %v", err),
+		})
+		return
+	}
+	if namespace != "" && !scope.allows(namespace) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Not permitted to access namespace %s", namespace),
+		})
+		return
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(fleetGVK.GroupVersion().WithKind("FleetList"))
+	var listOpts []client.ListOption
+	if namespace != "" {
+		listOpts = append(listOpts, client.InNamespace(namespace))
+	} else if !scope.all && len(scope.namespaces) == 1 {
+		listOpts = append(listOpts, client.InNamespace(scope.sorted()[0]))
+	}
+	// A caller who may read no namespace gets an empty list
+	if scope.all || len(scope.namespaces) > 0 {
+		if err := s.k8sClient.List(context.TODO(), list, listOpts...); err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to list Fleets: %v", err),
+			})
+			return
+		}
+	}
+
+	fleets := make([]Fleet, 0, len(list.Items))
+	for i := range list.Items {
+		if !scope.allows(list.Items[i].GetNamespace()) {
+			continue
+		}
+		fleet, err := fleetFromUnstructured(&list.Items[i])
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to convert Fleet: %v", err),
+			})
+			return
+		}
+		fleets = append(fleets, *fleet)
+	}
+
+	items, ok := selectFields(c, fleets)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(fleets),
+	})
+}
+
+// createFleet creates a Fleet and its initial members
+func (s *Server) createFleet(c *gin.Context) {
+	var fleet Fleet
+	if err := c.ShouldBindJSON(&fleet); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if fleet.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "metadata.name is required",
+		})
+		return
+	}
+	if fleet.Namespace == "" {
+		fleet.Namespace = "default"
+	}
+	if !s.checkMaintenance(c, fleet.Namespace) {
+		return
+	}
+	if err := validateFleetSpec(&fleet.Spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	fleet.APIVersion = fleetGVK.GroupVersion().String()
+	fleet.Kind = fleetGVK.Kind
+	fleet.Status = FleetStatus{}
+	obj, err := fleetToUnstructured(&fleet)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := s.k8sClient.Create(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to create Fleet: %v", err),
+		})
+		return
+	}
+
+	s.respondWithReconciledFleet(c, http.StatusCreated, obj)
+}
+
+// getFleet returns a Fleet with its live aggregate status
+func (s *Server) getFleet(c *gin.Context) {
+	obj, ok := s.loadFleet(c)
+	if !ok {
+		return
+	}
+	fleet, err := fleetFromUnstructured(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to convert Fleet: %v", err),
+		})
+		return
+	}
+
+	members, err := s.fleetMembers(context.TODO(), fleet)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list fleet members: %v", err),
+		})
+		return
+	}
+	autoscalerStatus := fleet.Status.Autoscaler
+	fleet.Status = fleetStatusFor(fleet, members)
+	fleet.Status.Autoscaler = autoscalerStatus
+	fleet.Status.Canary = fleetCanary(obj)
+	shaped, ok := selectFields(c, fleet)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, shaped)
+}
+
+// updateFleet replaces a Fleet's spec and syncs its members
+func (s *Server) updateFleet(c *gin.Context) {
+	var spec FleetSpec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if err := validateFleetSpec(&spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	obj, ok := s.loadFleet(c)
+	if !ok {
+		return
+	}
+	if state := fleetCanary(obj); state != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("Canary %s is running on this Fleet; abort it or wait for it to finish", state.Operation),
+		})
+		return
+	}
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&spec)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	obj.Object["spec"] = raw
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update Fleet: %v", err),
+		})
+		return
+	}
+
+	s.respondWithReconciledFleet(c, http.StatusOK, obj)
+}
+
+// deleteFleet deletes a Fleet; its members are removed by garbage collection
+// through their owner references
+func (s *Server) deleteFleet(c *gin.Context) {
+	obj, ok := s.loadFleet(c)
+	if !ok {
+		return
+	}
+	if err := s.k8sClient.Delete(context.TODO(), obj, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete Fleet: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Fleet %s deleted", obj.GetName()),
+	})
+}
+
+// scaleFleet sets a Fleet's replica count, either absolutely or by a delta
+func (s *Server) scaleFleet(c *gin.Context) {
+	var req struct {
+		Replicas *int `json:"replicas"`
+		Delta    int  `json:"delta"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if (req.Replicas == nil) == (req.Delta == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Set exactly one of replicas or delta",
+		})
+		return
+	}
+
+	obj, ok := s.loadFleet(c)
+	if !ok {
+		return
+	}
+	if enabled, _, _ := unstructured.NestedBool(obj.Object, "spec", "autoscaler", "enabled"); enabled {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Fleet is managed by its autoscaler; disable it or change its bounds instead",
+		})
+		return
+	}
+	current, _, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	replicas := current + int64(req.Delta)
+	if req.Replicas != nil {
+		replicas = int64(*req.Replicas)
+	}
+	if replicas < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "replicas cannot be negative",
+		})
+		return
+	}
+
+	if err := unstructured.SetNestedField(obj.Object, replicas, "spec", "replicas"); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to scale Fleet: %v", err),
+		})
+		return
+	}
+
+	s.respondWithReconciledFleet(c, http.StatusOK, obj)
+}
+
+// respondWithReconciledFleet reconciles a Fleet right away so the response
+// reflects the requested change
+func (s *Server) respondWithReconciledFleet(c *gin.Context, status int, obj *unstructured.Unstructured) {
+	fleet, err := s.reconcileFleet(context.TODO(), obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to reconcile Fleet: %v", err),
+		})
+		return
+	}
+	c.JSON(status, fleet)
+}
+
+// loadFleet fetches the Fleet named in the route, writing the error response
+// itself when it cannot
+func (s *Server) loadFleet(c *gin.Context) (*unstructured.Unstructured, bool) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(fleetGVK)
+	key := client.ObjectKey{Namespace: c.Param("namespace"), Name: c.Param("name")}
+	if err := s.k8sClient.Get(context.TODO(), key, obj); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Fleet not found",
+			})
+			return nil, false
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get Fleet: %v", err),
+		})
+		return nil, false
+	}
+	return obj, true
+}
+
+// reconcileAllFleets reconciles every Fleet in the cluster
+func (s *Server) reconcileAllFleets(ctx context.Context) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(fleetGVK.GroupVersion().WithKind("FleetList"))
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list Fleets: %w", err)
+	}
+	for i := range list.Items {
+		if _, err := s.reconcileFleet(ctx, &list.Items[i]); err != nil {
+			log.Printf("Failed to reconcile Fleet %s/%s: %v", list.Items[i].GetNamespace(), list.Items[i].GetName(), err)
+		}
+	}
+	return nil
+}
+
+// reconcileFleet creates missing members, removes surplus ones, syncs
+// members to the current template and writes the aggregate status
+func (s *Server) reconcileFleet(ctx context.Context, obj *unstructured.Unstructured) (*Fleet, error) {
+	fleet, err := fleetFromUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	if fleet.DeletionTimestamp != nil {
+		return fleet, nil
+	}
+
+	members, err := s.fleetMembers(ctx, fleet)
+	if err != nil {
+		return nil, err
+	}
+	autoscalerStatus := s.autoscaleFleet(ctx, obj, fleet, members)
+	hash := fleetTemplateHash(fleet)
+
+	// Canary members follow the canary template until it ends
+	canary := fleetCanary(obj)
+	if canary != nil && time.Now().After(canary.Deadline.Time) {
+		log.Printf("Rolling back canary %s of Fleet %s/%s: its deadline passed", canary.Operation, fleet.Namespace, fleet.Name)
+		if err := setFleetCanary(obj, nil); err != nil {
+			return nil, err
+		}
+		if err := s.k8sClient.Update(ctx, obj); err != nil {
+			return nil, fmt.Errorf("failed to remove expired canary: %w", err)
+		}
+		canary = nil
+	}
+	canaryTarget, canaryHash := fleet, hash
+	if canary != nil {
+		canaryTarget = canaryFleet(fleet, canary)
+		canaryHash = fleetTemplateHash(canaryTarget)
+	}
+
+	// Scale up into the lowest free indices so names stay short and stable
+	used := map[int]bool{}
+	for i := range members {
+		used[fleetMemberIndex(fleet.Name, members[i].GetName())] = true
+	}
+	for index := 1; len(members) < fleet.Spec.Replicas; index++ {
+		if used[index] {
+			continue
+		}
+		member := newFleetMember(fleet, index, hash)
+		if err := s.k8sClient.Create(ctx, member); err != nil {
+			return nil, fmt.Errorf("failed to create fleet member %s: %w", member.GetName(), err)
+		}
+		members = append(members, *member)
+		used[index] = true
+	}
+
+	// Scale down starting with the members that matter least to players
+	if surplus := len(members) - fleet.Spec.Replicas; surplus > 0 {
+		order := fleetScaleDownOrder(fleet, members)
+		removed := map[string]bool{}
+		for _, member := range order[:surplus] {
+			if err := s.k8sClient.Delete(ctx, member); client.IgnoreNotFound(err) != nil {
+				return nil, fmt.Errorf("failed to delete fleet member %s: %w", member.GetName(), err)
+			}
+			removed[member.GetName()] = true
+		}
+		kept := members[:0]
+		for _, member := range members {
+			if !removed[member.GetName()] {
+				kept = append(kept, member)
+			}
+		}
+		members = kept
+	}
+
+	// Sync members created from an older template
+	for i := range members {
+		member := &members[i]
+		target, targetHash := fleet, hash
+		if canary != nil && containsString(canary.Members, member.GetName()) {
+			target, targetHash = canaryTarget, canaryHash
+		}
+		if member.GetAnnotations()[fleetTemplateHashAnnotation] == targetHash {
+			continue
+		}
+		annotations := member.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[fleetTemplateHashAnnotation] = targetHash
+		member.SetAnnotations(annotations)
+		if _, err := s.writeGameServerSpec(ctx, member, fleetMemberSpec(target, member)); err != nil {
+			return nil, fmt.Errorf("failed to update fleet member %s: %w", member.GetName(), err)
+		}
+	}
+
+	fleet.Status = fleetStatusFor(fleet, members)
+	fleet.Status.Autoscaler = autoscalerStatus
+	fleet.Status.Canary = canary
+	status, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&fleet.Status)
+	if err != nil {
+		return nil, err
+	}
+	obj.Object["status"] = status
+	if err := s.k8sClient.Status().Update(ctx, obj); err != nil {
+		return nil, fmt.Errorf("failed to update Fleet status: %w", err)
+	}
+	return fleet, nil
+}
+
+// fleetMembers lists the GameServers belonging to a Fleet
+func (s *Server) fleetMembers(ctx context.Context, fleet *Fleet) ([]unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list, client.InNamespace(fleet.Namespace), client.MatchingLabels{fleetLabel: fleet.Name}); err != nil {
+		return nil, err
+	}
+	members := list.Items[:0]
+	for _, item := range list.Items {
+		if item.GetDeletionTimestamp() == nil {
+			members = append(members, item)
+		}
+	}
+	return members, nil
+}
+
+// newFleetMember builds the GameServer claim for a fleet member
+func newFleetMember(fleet *Fleet, index int, hash string) *unstructured.Unstructured {
+	name := fmt.Sprintf("%s-%d", fleet.Name, index)
+	labels := map[string]interface{}{}
+	for k, v := range fleet.Spec.Template.Labels {
+		labels[k] = v
+	}
+	labels["app.kubernetes.io/name"] = "gameserver"
+	labels["app.kubernetes.io/instance"] = name
+	labels["gameplane.kubelize.io/game-type"] = fleet.Spec.Template.Spec.GameType
+	labels[fleetLabel] = fleet.Name
+
+	controller := true
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "gameplane.kubelize.io/v1alpha1",
+			"kind":       "GameServer",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": fleet.Namespace,
+				"labels":    labels,
+				"annotations": map[string]interface{}{
+					fleetTemplateHashAnnotation: hash,
+				},
+			},
+		},
+	}
+	obj.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: fleetGVK.GroupVersion().String(),
+		Kind:       fleetGVK.Kind,
+		Name:       fleet.Name,
+		UID:        fleet.UID,
+		Controller: &controller,
+	}})
+	obj.Object["spec"] = fleetMemberSpec(fleet, nil)
+	return obj
+}
+
+// fleetMemberSpec renders the template spec for a member, keeping the
+// Crossplane-managed fields of an existing member
+func fleetMemberSpec(fleet *Fleet, live *unstructured.Unstructured) map[string]interface{} {
+	spec := buildGameServerSpec(fleet.Spec.Template.Spec)
+	if fleet.Spec.Spread != nil && fleet.Spec.Template.Spec.Advanced.Affinity == nil {
+		advanced, _ := spec["advanced"].(map[string]interface{})
+		if advanced == nil {
+			advanced = map[string]interface{}{}
+		}
+		advanced["affinity"] = fleetSpreadAffinity(fleet)
+		spec["advanced"] = advanced
+	}
+
+	// Round-trip through JSON so the spec only holds JSON-compatible types
+	if raw, err := json.Marshal(spec); err == nil {
+		var normalized map[string]interface{}
+		if json.Unmarshal(raw, &normalized) == nil {
+			spec = normalized
+		}
+	}
+
+	if live != nil {
+		liveSpec, _, _ := unstructured.NestedMap(live.Object, "spec")
+		for _, field := range crossplaneSpecFields {
+			if value, ok := liveSpec[field]; ok {
+				spec[field] = value
+			}
+		}
+	}
+	return spec
+}
+
+// fleetSpreadAffinity builds pod anti-affinity that spreads game pods of the
+// fleet's game type. Game pods only carry the game-type label, so servers of
+// the same game outside the fleet are spread from as well.
+func fleetSpreadAffinity(fleet *Fleet) map[string]interface{} {
+	topologyKey := fleet.Spec.Spread.TopologyKey
+	if topologyKey == "" {
+		topologyKey = defaultSpreadTopologyKey
+	}
+	term := map[string]interface{}{
+		"labelSelector": map[string]interface{}{
+			"matchLabels": map[string]interface{}{
+				"kubelize.io/game-type": fleet.Spec.Template.Spec.GameType,
+			},
+		},
+		// Each member runs in its own namespace
+		"namespaceSelector": map[string]interface{}{},
+		"topologyKey":       topologyKey,
+	}
+
+	if fleet.Spec.Spread.Required {
+		return map[string]interface{}{
+			"podAntiAffinity": map[string]interface{}{
+				"requiredDuringSchedulingIgnoredDuringExecution": []interface{}{term},
+			},
+		}
+	}
+	return map[string]interface{}{
+		"podAntiAffinity": map[string]interface{}{
+			"preferredDuringSchedulingIgnoredDuringExecution": []interface{}{
+				map[string]interface{}{"weight": int64(100), "podAffinityTerm": term},
+			},
+		},
+	}
+}
+
+// fleetScaleDownOrder sorts members by how safe they are to remove:
+// unallocated before allocated, not ready first, then fewest players, then
+// highest index
+func fleetScaleDownOrder(fleet *Fleet, members []unstructured.Unstructured) []*unstructured.Unstructured {
+	order := make([]*unstructured.Unstructured, len(members))
+	for i := range members {
+		order[i] = &members[i]
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if gameServerAllocated(a) != gameServerAllocated(b) {
+			return !gameServerAllocated(a)
+		}
+		if gameServerReady(a) != gameServerReady(b) {
+			return !gameServerReady(a)
+		}
+		if gameServerPlayers(a) != gameServerPlayers(b) {
+			return gameServerPlayers(a) < gameServerPlayers(b)
+		}
+		return fleetMemberIndex(fleet.Name, a.GetName()) > fleetMemberIndex(fleet.Name, b.GetName())
+	})
+	return order
+}
+
+// fleetStatusFor aggregates member state into a FleetStatus
+func fleetStatusFor(fleet *Fleet, members []unstructured.Unstructured) FleetStatus {
+	hash := fleetTemplateHash(fleet)
+	now := metav1.Now()
+	status := FleetStatus{
+		Replicas:     len(members),
+		TemplateHash: hash,
+		Servers:      make([]FleetMember, 0, len(members)),
+		LastUpdate:   &now,
+	}
+	for i := range members {
+		member := &members[i]
+		phase, _, _ := unstructured.NestedString(member.Object, "status", "phase")
+		summary := FleetMember{
+			Name:      member.GetName(),
+			Phase:     phase,
+			Ready:     gameServerReady(member),
+			Allocated: gameServerAllocated(member),
+			Players:   gameServerPlayers(member),
+			Capacity:  gameServerCapacity(member),
+			Updated:   member.GetAnnotations()[fleetTemplateHashAnnotation] == hash,
+		}
+		if summary.Ready {
+			status.ReadyReplicas++
+		}
+		if summary.Allocated {
+			status.AllocatedReplicas++
+		}
+		if summary.Updated {
+			status.UpdatedReplicas++
+		}
+		status.Players += summary.Players
+		status.Capacity += summary.Capacity
+		status.Servers = append(status.Servers, summary)
+	}
+	sort.Slice(status.Servers, func(i, j int) bool {
+		return fleetMemberIndex(fleet.Name, status.Servers[i].Name) < fleetMemberIndex(fleet.Name, status.Servers[j].Name)
+	})
+	return status
+}
+
+// validateFleetSpec checks a fleet spec and its GameServer template
+func validateFleetSpec(spec *FleetSpec) error {
+	if spec.Replicas < 0 {
+		return fmt.Errorf("spec.replicas cannot be negative")
+	}
+	if spec.Autoscaler != nil && spec.Autoscaler.Enabled {
+		if err := spec.Autoscaler.validate(); err != nil {
+			return err
+		}
+	}
+	gameType := spec.Template.Spec.GameType
+	def, ok := lookupGame(gameType)
+	if !ok {
+		return fmt.Errorf("Unsupported game type: %s. Valid types: %s", gameType, strings.Join(supportedGameTypes(), ", "))
+	}
+	if spec.Template.Spec.World != nil {
+		gameConfig, err := applyWorldToGameConfig(def, spec.Template.Spec.GameConfig, spec.Template.Spec.World)
+		if err != nil {
+			return err
+		}
+		spec.Template.Spec.GameConfig = gameConfig
+		spec.Template.Spec.World = nil
+	}
+	if err := validateSharedVolumes(spec.Template.Spec.SharedVolumes); err != nil {
+		return err
+	}
+	if err := validateDependencies(spec.Template.Spec.Dependencies); err != nil {
+		return err
+	}
+	if spec.Template.Spec.Lifecycle != nil {
+		if err := validateLifecycleHooks(spec.Template.Spec.Lifecycle.Hooks); err != nil {
+			return err
+		}
+	}
+	if _, reserved := spec.Template.Labels[fleetLabel]; reserved {
+		return fmt.Errorf("template label %s is managed by the fleet", fleetLabel)
+	}
+	return nil
+}
+
+// fleetTemplateHash identifies the current template and spread settings
+func fleetTemplateHash(fleet *Fleet) string {
+	raw, _ := json.Marshal(struct {
+		Template FleetTemplate `json:"template"`
+		Spread   *FleetSpread  `json:"spread,omitempty"`
+	}{fleet.Spec.Template, fleet.Spec.Spread})
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])[:10]
+}
+
+// fleetMemberIndex parses the index suffix of a member name, or 0
+func fleetMemberIndex(fleetName, memberName string) int {
+	index, err := strconv.Atoi(strings.TrimPrefix(memberName, fleetName+"-"))
+	if err != nil {
+		return 0
+	}
+	return index
+}
+
+// gameServerReady reports whether a GameServer is running or has a true
+// Ready condition
+func gameServerReady(obj *unstructured.Unstructured) bool {
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	if phase == "Running" {
+		return true
+	}
+	conditions, _ := gameServerConditions(obj)
+	for _, condition := range conditions {
+		if condition.Type == "Ready" {
+			return condition.Status == metav1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// gameServerPlayers returns the GameServer's online player count
+func gameServerPlayers(obj *unstructured.Unstructured) int {
+	players, _, _ := unstructured.NestedInt64(obj.Object, "status", "playersOnline")
+	return int(players)
+}
+
+// gameServerCapacity returns the GameServer's player slots from gameConfig
+// or the schema default, or 0 when unknown
+func gameServerCapacity(obj *unstructured.Unstructured) int {
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, _ := lookupGame(gameType)
+	gameConfig, _, _ := unstructured.NestedMap(obj.Object, "spec", "gameConfig")
+	if value, ok := flattenConfig("", gameConfig)["server.maxPlayers"]; ok {
+		if n, ok := toFloat(value); ok {
+			return int(n)
+		}
+	}
+	if field, ok := def.Field("server.maxPlayers"); ok {
+		if n, ok := toFloat(field.Default); ok {
+			return int(n)
+		}
+	}
+	return 0
+}
+
+// fleetFromUnstructured converts a Fleet object
+func fleetFromUnstructured(obj *unstructured.Unstructured) (*Fleet, error) {
+	fleet := &Fleet{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, fleet); err != nil {
+		return nil, err
+	}
+	return fleet, nil
+}
+
+// fleetToUnstructured converts a Fleet for the controller-runtime client
+func fleetToUnstructured(fleet *Fleet) (*unstructured.Unstructured, error) {
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(fleet)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{Object: raw}
+	delete(obj.Object, "status")
+	return obj, nil
+}
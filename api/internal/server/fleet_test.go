@@ -0,0 +1,49 @@
+package server_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kubelize/gameplane/api/pkg/gameplane/gameplanetest"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fleet builds a Fleet for seeding a harness
+func fleet(namespace, name string) client.Object {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": int64(1),
+			"template": map[string]interface{}{"gameType": "sdtd"},
+		},
+	}}
+	obj.SetAPIVersion("gameplane.kubelize.io/v1alpha1")
+	obj.SetKind("Fleet")
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func TestListFleetsInReadableNamespaces(t *testing.T) {
+	h := newHarness(t, fleet("team-alice", "alices"), fleet("team-bob", "bobs"))
+
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if code := call(t, h, "alice", gameplanetest.Request(http.MethodGet, "/api/v1/fleets?namespace=team-bob", nil), nil); code != http.StatusForbidden {
+		t.Errorf("another tenant's namespace: got %d, want 403", code)
+	}
+	if code := call(t, h, "alice", gameplanetest.Request(http.MethodGet, "/api/v1/fleets?namespace=all", nil), &list); code != http.StatusOK {
+		t.Fatalf("all namespaces: got %d, want 200", code)
+	}
+	if len(list.Items) != 1 || list.Items[0].Metadata.Name != "alices" {
+		t.Errorf("all namespaces: got %+v, want only alices", list.Items)
+	}
+	if code := call(t, h, "root", gameplanetest.Request(http.MethodGet, "/api/v1/fleets?namespace=all", nil), &list); code != http.StatusOK || len(list.Items) != 2 {
+		t.Errorf("all namespaces as admin: got %d with %d fleets, want 200 with 2", code, len(list.Items))
+	}
+}
@@ -0,0 +1,600 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// fleetCanaryAnnotation records the canary running on a Fleet; the
+	// reconciler syncs the listed members to the canary template
+	fleetCanaryAnnotation = "gameplane.kubelize.io/fleet-canary"
+
+	// defaultFleetCanarySoak is how long canaries are watched by default
+	defaultFleetCanarySoak = 10 * time.Minute
+
+	// fleetCanaryCheckInterval is how often canaries are checked while
+	// soaking
+	fleetCanaryCheckInterval = 15 * time.Second
+
+	// fleetCanaryMargin is added to the ready timeout and soak for the
+	// operation and the canary's deadline
+	fleetCanaryMargin = 10 * time.Minute
+)
+
+// Fleet canary steps, in order
+const (
+	fleetCanaryStepUpdate  = "update-canaries"
+	fleetCanaryStepReady   = "wait-for-canaries"
+	fleetCanaryStepSoak    = "soak"
+	fleetCanaryStepPromote = "promote"
+)
+
+// Fleet canary phases
+const (
+	fleetCanaryUpdating   = "Updating"
+	fleetCanarySoaking    = "Soaking"
+	fleetCanaryPromoted   = "Promoted"
+	fleetCanaryRolledBack = "RolledBack"
+	fleetCanaryAborted    = "Aborted"
+)
+
+// errFleetCanaryAborted is returned once a canary was aborted through the
+// API while its operation still ran
+var errFleetCanaryAborted = errors.New("canary aborted")
+
+// FleetCanaryRequest tries a template change on some fleet members before
+// the whole fleet
+type FleetCanaryRequest struct {
+	Template FleetTemplate `json:"template"`
+	// Replicas is how many members get the change first; Percent sizes the
+	// subset from the fleet instead. Defaults to one member.
+	Replicas int `json:"replicas,omitempty"`
+	Percent  int `json:"percent,omitempty"`
+	// Soak is how long canaries are watched once ready
+	Soak string `json:"soak,omitempty"`
+	// ReadyTimeout is how long canaries may take to become ready
+	ReadyTimeout string `json:"readyTimeout,omitempty"`
+	// MaxRestarts is how many game container restarts the canaries may
+	// have while soaking
+	MaxRestarts int `json:"maxRestarts,omitempty"`
+	// MaxPlayerDrop rolls back when canaries hold this many percent fewer
+	// players than the other members at the end of the soak; 0 disables
+	// the comparison
+	MaxPlayerDrop int `json:"maxPlayerDrop,omitempty"`
+}
+
+// FleetCanaryState is recorded on a Fleet while a canary runs
+type FleetCanaryState struct {
+	Operation string        `json:"operation"`
+	Template  FleetTemplate `json:"template"`
+	Members   []string      `json:"members"`
+	StartedAt metav1.Time   `json:"startedAt"`
+	// Deadline rolls the canary back if its operation is gone, e.g. after
+	// an API restart
+	Deadline metav1.Time `json:"deadline"`
+}
+
+// FleetCanaryProgress is reported as the canary operation's result
+type FleetCanaryProgress struct {
+	Phase    string              `json:"phase"`
+	Canaries []FleetCanaryMember `json:"canaries"`
+	// PlayersPerCanary and PlayersPerMember compare the canaries with the
+	// rest of the fleet at the end of the soak
+	PlayersPerCanary float64 `json:"playersPerCanary"`
+	PlayersPerMember float64 `json:"playersPerMember"`
+	Message          string  `json:"message,omitempty"`
+}
+
+// FleetCanaryMember is the state of one canary
+type FleetCanaryMember struct {
+	Name    string `json:"name"`
+	Ready   bool   `json:"ready"`
+	Players int    `json:"players"`
+	// Restarts counts game container restarts since the canary was ready
+	Restarts int32  `json:"restarts"`
+	Message  string `json:"message,omitempty"`
+}
+
+// fleetCanary reads the canary annotation, returning nil when unset
+func fleetCanary(obj *unstructured.Unstructured) *FleetCanaryState {
+	raw, ok := obj.GetAnnotations()[fleetCanaryAnnotation]
+	if !ok {
+		return nil
+	}
+	state := &FleetCanaryState{}
+	if err := json.Unmarshal([]byte(raw), state); err != nil {
+		log.Printf("Ignoring invalid %s annotation of Fleet %s/%s: %v", fleetCanaryAnnotation, obj.GetNamespace(), obj.GetName(), err)
+		return nil
+	}
+	return state
+}
+
+// setFleetCanary writes or, for nil, removes the canary annotation
+func setFleetCanary(obj *unstructured.Unstructured, state *FleetCanaryState) error {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if state == nil {
+		delete(annotations, fleetCanaryAnnotation)
+		obj.SetAnnotations(annotations)
+		return nil
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	annotations[fleetCanaryAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// canaryFleet returns a copy of the fleet using the canary template
+func canaryFleet(fleet *Fleet, state *FleetCanaryState) *Fleet {
+	canary := *fleet
+	canary.Spec.Template = state.Template
+	return &canary
+}
+
+// validate checks a canary request against its fleet and fills in defaults
+func (r *FleetCanaryRequest) validate(fleet *Fleet) error {
+	switch {
+	case r.Replicas < 0:
+		return fmt.Errorf("replicas cannot be negative")
+	case r.Percent < 0 || r.Percent > 100:
+		return fmt.Errorf("percent must be between 1 and 100")
+	case r.Replicas > 0 && r.Percent > 0:
+		return fmt.Errorf("Set at most one of replicas or percent")
+	case r.MaxRestarts < 0:
+		return fmt.Errorf("maxRestarts cannot be negative")
+	case r.MaxPlayerDrop < 0 || r.MaxPlayerDrop > 100:
+		return fmt.Errorf("maxPlayerDrop must be between 0 and 100")
+	}
+	if r.Soak == "" {
+		r.Soak = defaultFleetCanarySoak.String()
+	}
+	if r.ReadyTimeout == "" {
+		r.ReadyTimeout = defaultRolloutReadyTimeout.String()
+	}
+	for _, value := range []string{r.Soak, r.ReadyTimeout} {
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 || d > rolloutTimeout {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+	}
+
+	spec := fleet.Spec
+	spec.Template = r.Template
+	if err := validateFleetSpec(&spec); err != nil {
+		return err
+	}
+	r.Template = spec.Template
+	if r.Template.Spec.GameType != fleet.Spec.Template.Spec.GameType {
+		return fmt.Errorf("a canary cannot change the game type")
+	}
+	if fleetTemplateHash(canaryFleet(fleet, &FleetCanaryState{Template: r.Template})) == fleetTemplateHash(fleet) {
+		return fmt.Errorf("template is unchanged")
+	}
+	return nil
+}
+
+// canaryCount sizes the canary subset of a fleet with the given members
+func (r *FleetCanaryRequest) canaryCount(members int) int {
+	switch {
+	case r.Replicas > 0:
+		return r.Replicas
+	case r.Percent > 0:
+		return (members*r.Percent + 99) / 100
+	}
+	return 1
+}
+
+// startFleetCanary applies a template change to a subset of a Fleet,
+// watches it for crashes and player loss, then promotes it to the whole
+// fleet or rolls it back, as an operation
+func (s *Server) startFleetCanary(c *gin.Context) {
+	var req FleetCanaryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	obj, ok := s.loadFleet(c)
+	if !ok {
+		return
+	}
+	if !s.checkMaintenance(c, obj.GetNamespace()) {
+		return
+	}
+	fleet, err := fleetFromUnstructured(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to convert Fleet: %v", err),
+		})
+		return
+	}
+	if state := fleetCanary(obj); state != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("Canary %s is still running on this Fleet; abort it or wait for it to finish", state.Operation),
+		})
+		return
+	}
+	if err := req.validate(fleet); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	members, err := s.fleetMembers(context.TODO(), fleet)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list fleet members: %v", err),
+		})
+		return
+	}
+	count := req.canaryCount(len(members))
+	if count >= len(members) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("A canary needs fewer members than the fleet's %d so the rest keep the current template", len(members)),
+		})
+		return
+	}
+	// Canaries are the members that matter least to players
+	names := []string{}
+	for _, member := range fleetScaleDownOrder(fleet, members)[:count] {
+		names = append(names, member.GetName())
+	}
+	sort.Strings(names)
+
+	readyTimeout, _ := time.ParseDuration(req.ReadyTimeout)
+	soak, _ := time.ParseDuration(req.Soak)
+	namespace, name := obj.GetNamespace(), obj.GetName()
+	steps := []string{fleetCanaryStepUpdate, fleetCanaryStepReady, fleetCanaryStepSoak, fleetCanaryStepPromote}
+	op := s.startOperation("fleet-canary", namespace, name, steps, readyTimeout+soak+fleetCanaryMargin, func(ctx context.Context, t *operationTracker) (interface{}, error) {
+		return s.runFleetCanary(ctx, t, namespace, name, names, req, readyTimeout, soak)
+	})
+	c.JSON(http.StatusAccepted, op)
+}
+
+// abortFleetCanary rolls back the canary running on a Fleet; its operation
+// stops at its next check
+func (s *Server) abortFleetCanary(c *gin.Context) {
+	obj, ok := s.loadFleet(c)
+	if !ok {
+		return
+	}
+	state := fleetCanary(obj)
+	if state == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No canary is running on this Fleet",
+		})
+		return
+	}
+	if err := s.endFleetCanary(context.TODO(), obj.GetNamespace(), obj.GetName(), state.Operation, false); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to roll back canary: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Canary %s rolled back", state.Operation),
+	})
+}
+
+// runFleetCanary runs the canary steps, rolling the canaries back to the
+// fleet's template when any of them fails
+func (s *Server) runFleetCanary(ctx context.Context, t *operationTracker, namespace, name string, names []string, req FleetCanaryRequest, readyTimeout, soak time.Duration) (interface{}, error) {
+	progress := &FleetCanaryProgress{Phase: fleetCanaryUpdating, Canaries: make([]FleetCanaryMember, len(names))}
+	for i, member := range names {
+		progress.Canaries[i] = FleetCanaryMember{Name: member}
+	}
+	var mu sync.Mutex
+	report := func(phase, message string) {
+		mu.Lock()
+		defer mu.Unlock()
+		progress.Phase = phase
+		progress.Message = message
+		snapshot := progress.snapshot()
+		t.update(func(op *Operation) { op.Result = snapshot })
+	}
+	rollback := func(cause error) (interface{}, error) {
+		if errors.Is(cause, errFleetCanaryAborted) {
+			report(fleetCanaryAborted, "Canary was aborted and rolled back")
+			return progress.snapshot(), cause
+		}
+		// The operation's context may be what ran out
+		rollbackCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := s.endFleetCanary(rollbackCtx, namespace, name, t.op.ID, false); err != nil {
+			log.Printf("Failed to roll back canary %s of Fleet %s/%s: %v", t.op.ID, namespace, name, err)
+		}
+		report(fleetCanaryRolledBack, cause.Error())
+		return progress.snapshot(), fmt.Errorf("canary rolled back: %w", cause)
+	}
+
+	report(fleetCanaryUpdating, fmt.Sprintf("Updating %d canaries", len(names)))
+	err := t.step(fleetCanaryStepUpdate, func() (string, error) {
+		obj, err := s.getFleetObject(ctx, namespace, name)
+		if err != nil {
+			return "", err
+		}
+		if state := fleetCanary(obj); state != nil {
+			return "", fmt.Errorf("canary %s is already running on this Fleet", state.Operation)
+		}
+		now := time.Now()
+		state := &FleetCanaryState{
+			Operation: t.op.ID,
+			Template:  req.Template,
+			Members:   names,
+			StartedAt: metav1.NewTime(now),
+			Deadline:  metav1.NewTime(now.Add(readyTimeout + soak + fleetCanaryMargin)),
+		}
+		if err := setFleetCanary(obj, state); err != nil {
+			return "", err
+		}
+		// A concurrent canary conflicts here and fails instead of retrying
+		if err := s.k8sClient.Update(ctx, obj); err != nil {
+			return "", fmt.Errorf("failed to update Fleet: %w", err)
+		}
+		if _, err := s.reconcileFleet(ctx, obj); err != nil {
+			// The background reconciler catches up
+			log.Printf("Failed to reconcile Fleet %s/%s for canary %s: %v", namespace, name, t.op.ID, err)
+		}
+		return fmt.Sprintf("Updated %s", strings.Join(names, ", ")), nil
+	})
+	if err != nil {
+		return rollback(err)
+	}
+
+	baseline := map[types.UID]int32{}
+	err = t.step(fleetCanaryStepReady, func() (string, error) {
+		var wg sync.WaitGroup
+		failed := []string{}
+		for i := range progress.Canaries {
+			wg.Add(1)
+			go func(member *FleetCanaryMember) {
+				defer wg.Done()
+				pods, err := s.waitForRolloutReady(ctx, namespace, member.Name, req.Template.Spec.Advanced.Image, readyTimeout)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					member.Message = err.Error()
+					failed = append(failed, fmt.Sprintf("%s (%v)", member.Name, err))
+					return
+				}
+				member.Ready = true
+				for uid, restarts := range podRestarts(pods) {
+					baseline[uid] = restarts
+				}
+			}(&progress.Canaries[i])
+		}
+		wg.Wait()
+		if len(failed) > 0 {
+			return "", fmt.Errorf("%d of %d canaries failed: %s", len(failed), len(names), strings.Join(failed, ", "))
+		}
+		return fmt.Sprintf("%d canaries ready", len(names)), nil
+	})
+	if err != nil {
+		return rollback(err)
+	}
+
+	report(fleetCanarySoaking, fmt.Sprintf("Watching canaries for %s", soak))
+	err = t.step(fleetCanaryStepSoak, func() (string, error) {
+		end := time.After(soak)
+		ticker := time.NewTicker(fleetCanaryCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-end:
+				if err := s.checkFleetCanary(ctx, namespace, name, t.op.ID, progress, &mu, baseline, req.MaxRestarts); err != nil {
+					return "", err
+				}
+				return s.compareFleetCanary(ctx, namespace, name, names, progress, &mu, req.MaxPlayerDrop)
+			case <-ticker.C:
+				if err := s.checkFleetCanary(ctx, namespace, name, t.op.ID, progress, &mu, baseline, req.MaxRestarts); err != nil {
+					return "", err
+				}
+				report(fleetCanarySoaking, fmt.Sprintf("Watching canaries for %s", soak))
+			}
+		}
+	})
+	if err != nil {
+		return rollback(err)
+	}
+
+	err = t.step(fleetCanaryStepPromote, func() (string, error) {
+		if err := s.endFleetCanary(ctx, namespace, name, t.op.ID, true); err != nil {
+			return "", err
+		}
+		return "Fleet template updated", nil
+	})
+	if err != nil {
+		return rollback(err)
+	}
+	report(fleetCanaryPromoted, "Canaries were healthy; the change was rolled out to the whole fleet")
+	return progress.snapshot(), nil
+}
+
+// checkFleetCanary refreshes the canaries' state, failing when one of them
+// is gone, crashing or restarted too often, or the canary was aborted
+func (s *Server) checkFleetCanary(ctx context.Context, namespace, name, operationID string, progress *FleetCanaryProgress, mu *sync.Mutex, baseline map[types.UID]int32, maxRestarts int) error {
+	obj, err := s.getFleetObject(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+	if state := fleetCanary(obj); state == nil || state.Operation != operationID {
+		return errFleetCanaryAborted
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var restarts int32
+	for i := range progress.Canaries {
+		member := &progress.Canaries[i]
+		server, err := s.getGameServerObject(ctx, namespace, member.Name)
+		if client.IgnoreNotFound(err) == nil && err != nil {
+			return fmt.Errorf("canary %s was removed", member.Name)
+		}
+		if err != nil {
+			return err
+		}
+		member.Ready = gameServerReady(server)
+		member.Players = gameServerPlayers(server)
+		conditions, _ := gameServerConditions(server)
+		for _, condition := range conditions {
+			if condition.Type == status.ConditionCrashing && condition.Status == metav1.ConditionTrue {
+				member.Message = condition.Message
+				return fmt.Errorf("canary %s is crashing: %s", member.Name, condition.Message)
+			}
+		}
+		pods, _, err := s.findGameServerPods(ctx, server)
+		if err != nil {
+			continue
+		}
+		member.Restarts = 0
+		for uid, count := range podRestarts(pods) {
+			member.Restarts += count - baseline[uid]
+		}
+		restarts += member.Restarts
+	}
+	if int(restarts) > maxRestarts {
+		return fmt.Errorf("canaries restarted %d times, more than the allowed %d", restarts, maxRestarts)
+	}
+	return nil
+}
+
+// compareFleetCanary compares players per canary with players per other
+// ready member, failing when the canaries fall behind by more than
+// maxPlayerDrop percent. Fleets without players pass.
+func (s *Server) compareFleetCanary(ctx context.Context, namespace, name string, names []string, progress *FleetCanaryProgress, mu *sync.Mutex, maxPlayerDrop int) (string, error) {
+	obj, err := s.getFleetObject(ctx, namespace, name)
+	if err != nil {
+		return "", err
+	}
+	fleet, err := fleetFromUnstructured(obj)
+	if err != nil {
+		return "", err
+	}
+	members, err := s.fleetMembers(ctx, fleet)
+	if err != nil {
+		return "", err
+	}
+	canaryPlayers, canaries, otherPlayers, others := 0, 0, 0, 0
+	for i := range members {
+		member := &members[i]
+		switch {
+		case containsString(names, member.GetName()):
+			canaryPlayers += gameServerPlayers(member)
+			canaries++
+		case gameServerReady(member):
+			otherPlayers += gameServerPlayers(member)
+			others++
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if canaries > 0 {
+		progress.PlayersPerCanary = float64(canaryPlayers) / float64(canaries)
+	}
+	if others > 0 {
+		progress.PlayersPerMember = float64(otherPlayers) / float64(others)
+	}
+	message := fmt.Sprintf("%.1f players per canary, %.1f per other member", progress.PlayersPerCanary, progress.PlayersPerMember)
+	if maxPlayerDrop == 0 || progress.PlayersPerMember == 0 {
+		return message, nil
+	}
+	if progress.PlayersPerCanary < progress.PlayersPerMember*float64(100-maxPlayerDrop)/100 {
+		return "", fmt.Errorf("%s, more than %d%% fewer", message, maxPlayerDrop)
+	}
+	return message, nil
+}
+
+// endFleetCanary removes a Fleet's canary, first making its template the
+// fleet's when promoting, and reconciles the fleet right away. Rolling
+// back a canary that already ended is a no-op.
+func (s *Server) endFleetCanary(ctx context.Context, namespace, name, operationID string, promote bool) error {
+	obj, err := s.getFleetObject(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+	state := fleetCanary(obj)
+	if state == nil || state.Operation != operationID {
+		if promote {
+			return errFleetCanaryAborted
+		}
+		return nil
+	}
+	if promote {
+		fleet, err := fleetFromUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		fleet.Spec.Template = state.Template
+		raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&fleet.Spec)
+		if err != nil {
+			return err
+		}
+		obj.Object["spec"] = raw
+	}
+	if err := setFleetCanary(obj, nil); err != nil {
+		return err
+	}
+	if err := s.k8sClient.Update(ctx, obj); err != nil {
+		return fmt.Errorf("failed to update Fleet: %w", err)
+	}
+	if _, err := s.reconcileFleet(ctx, obj); err != nil {
+		// The background reconciler catches up
+		log.Printf("Failed to reconcile Fleet %s/%s after its canary: %v", namespace, name, err)
+	}
+	return nil
+}
+
+// getFleetObject fetches a Fleet
+func (s *Server) getFleetObject(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(fleetGVK)
+	if err := s.k8sClient.Get(ctx, client.ObjectKey{Namespace: namespace, Name: name}, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// podRestarts sums container restarts per pod
+func podRestarts(pods []corev1.Pod) map[types.UID]int32 {
+	restarts := map[types.UID]int32{}
+	for _, pod := range pods {
+		for _, container := range pod.Status.ContainerStatuses {
+			restarts[pod.UID] += container.RestartCount
+		}
+	}
+	return restarts
+}
+
+// snapshot copies the progress for the operation result
+func (p *FleetCanaryProgress) snapshot() FleetCanaryProgress {
+	out := *p
+	out.Canaries = append([]FleetCanaryMember(nil), p.Canaries...)
+	return out
+}
@@ -0,0 +1,1482 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/apis/gameplane/v1alpha1"
+	"github.com/kubelize/gameplane/api/internal/admission"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The claim types live in apis/gameplane/v1alpha1, which the Crossplane XRD
+// is generated from
+type (
+	GameServerSpec       = v1alpha1.GameServerSpec
+	GameServerWorld      = v1alpha1.GameServerWorld
+	GameServerResources  = v1alpha1.GameServerResources
+	GameServerNetworking = v1alpha1.GameServerNetworking
+	GameServerAdvanced   = v1alpha1.GameServerAdvanced
+	GameServerLifecycle  = v1alpha1.GameServerLifecycle
+	GameServerLifecycleHooks = v1alpha1.GameServerLifecycleHooks
+	GameServerLifecycleHook  = v1alpha1.GameServerLifecycleHook
+	GameServerProxy      = v1alpha1.GameServerProxy
+	GameServerBackend    = v1alpha1.GameServerBackend
+	GameServerSharedVolume = v1alpha1.GameServerSharedVolume
+	GameServerDependency   = v1alpha1.GameServerDependency
+	GameServerProbes     = v1alpha1.GameServerProbes
+	ProbeSettings        = v1alpha1.ProbeSettings
+	GameServerStatus     = v1alpha1.GameServerStatus
+	GameServerHealth     = v1alpha1.GameServerHealth
+	GameServer           = v1alpha1.GameServer
+	GameServerList       = v1alpha1.GameServerList
+)
+
+// GameServerPort represents a port mapping
+type GameServerPort struct {
+	Name       string `json:"name"`
+	Port       int32  `json:"port"`
+	TargetPort int32  `json:"targetPort"`
+	Protocol   string `json:"protocol"`
+}
+
+var (
+	gameServerGVR = schema.GroupVersionResource{
+		Group:    "gameplane.kubelize.io",
+		Version:  "v1alpha1",
+		Resource: "gameservers",
+	}
+)
+
+// listGameServers returns the GameServers in ?namespace=, or in every
+// namespace the caller may read when it is omitted or "all". ?owner= keeps
+// the servers of one subject, ?owner=me the caller's.
+func (s *Server) listGameServers(c *gin.Context) {
+	namespace := c.Query("namespace")
+	if namespace == "all" {
+		namespace = ""
+	}
+
+	scope, err := s.readableNamespaces(c)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to determine readable namespaces: %v", err),
+		})
+		return
+	}
+	if namespace != "" && !scope.allows(namespace) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Not permitted to access namespace %s", namespace),
+		})
+		return
+	}
+
+	// Create unstructured list to query custom resources
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+
+	var listOpts []client.ListOption
+	if namespace != "" {
+		listOpts = append(listOpts, client.InNamespace(namespace))
+	} else if !scope.all && len(scope.namespaces) == 1 {
+		listOpts = append(listOpts, client.InNamespace(scope.sorted()[0]))
+	}
+
+	// A caller who may read no namespace gets an empty list
+	if scope.all || len(scope.namespaces) > 0 {
+		if err := s.k8sClient.List(context.TODO(), list, listOpts...); err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to list GameServers: %v", err),
+			})
+			return
+		}
+	}
+
+	// Convert unstructured list to GameServer list, keeping readable namespaces
+	owner := c.Query("owner")
+	if owner == "me" {
+		owner = c.GetString(handlers.SubjectKey)
+	}
+	gameServers := make([]gameServerListItem, 0, len(list.Items))
+	for _, item := range list.Items {
+		if !scope.allows(item.GetNamespace()) {
+			continue
+		}
+		if c.Query("owner") != "" && gameServerOwner(&item) != owner {
+			continue
+		}
+		gs, err := unstructuredToGameServer(&item)
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to convert GameServer: %v", err),
+			})
+			return
+		}
+		gameServers = append(gameServers, gameServerListItem{Namespace: gs.Namespace, GameServer: *gs})
+	}
+	sort.Slice(gameServers, func(i, j int) bool {
+		if gameServers[i].Namespace != gameServers[j].Namespace {
+			return gameServers[i].Namespace < gameServers[j].Namespace
+		}
+		return gameServers[i].Name < gameServers[j].Name
+	})
+	switch c.Query("sort") {
+	case "":
+	case "health":
+		// Most unhealthy first; unscored servers, such as stopped ones, last
+		sort.SliceStable(gameServers, func(i, j int) bool {
+			a, b := gameServers[i].Status.Health, gameServers[j].Status.Health
+			if a == nil || b == nil {
+				return a != nil
+			}
+			return a.Score < b.Score
+		})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid sort %q; supported: health", c.Query("sort")),
+		})
+		return
+	}
+
+	items, ok := selectFields(c, gameServers)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(gameServers),
+	})
+}
+
+// gameServerListItem is a listed GameServer with its namespace up front, as
+// lists span namespaces
+type gameServerListItem struct {
+	Namespace string `json:"namespace"`
+	GameServer
+}
+
+// createGameServer creates a new GameServer (Crossplane Composite Resource)
+func (s *Server) createGameServer(c *gin.Context) {
+	var req struct {
+		APIVersion string         `json:"apiVersion"`
+		Kind       string         `json:"kind"`
+		Metadata   metav1.ObjectMeta `json:"metadata"`
+		Spec       GameServerSpec `json:"spec"`
+		// Notify is sent once the new GameServer first becomes ready
+		Notify     *ReadyNotification `json:"notify,omitempty"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+
+	// Set defaults for Crossplane Composite Resource
+	if req.APIVersion == "" {
+		req.APIVersion = "gameplane.kubelize.io/v1alpha1"
+	}
+	if req.Kind == "" {
+		req.Kind = "GameServer"  // This will create a GameServer claim
+	}
+	if req.Metadata.Namespace == "" {
+		req.Metadata.Namespace = "default"
+	}
+	if !s.servedNamespaces(c.Request.Context())(req.Metadata.Namespace) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Not permitted to access namespace %s", req.Metadata.Namespace),
+		})
+		return
+	}
+	if !s.checkMaintenance(c, req.Metadata.Namespace) {
+		return
+	}
+
+	// Validate required fields
+	if req.Metadata.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "metadata.name is required",
+		})
+		return
+	}
+
+	if req.Spec.GameType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "spec.gameType is required",
+		})
+		return
+	}
+
+	// Validate gameType is supported
+	def, ok := lookupGame(req.Spec.GameType)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Unsupported game type: %s. Valid types: %s", req.Spec.GameType, strings.Join(supportedGameTypes(), ", ")),
+		})
+		return
+	}
+
+	if req.Notify != nil {
+		if err := req.Notify.validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+	if err := validateBrowserMetadata(req.Spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Servers scheduled to start later are created stopped
+	if req.Spec.Lifecycle != nil {
+		now := time.Now()
+		if err := validateLifecycle(req.Spec.Lifecycle, now); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if req.Spec.Lifecycle.StartAt != nil && req.Spec.Lifecycle.StartAt.After(now) {
+			req.Spec.Stopped = true
+		}
+	}
+	if err := validateProxy(def, req.Metadata.Name, req.Spec.Proxy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := validateProbes(def, req.Spec.Advanced.Probes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := s.checkSharedAssets(context.TODO(), req.Spec.SharedVolumes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := validateDependencies(req.Spec.Dependencies); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if req.Spec.Lifecycle != nil {
+		if err := s.checkLifecycleHooks(context.TODO(), req.Metadata.Namespace, req.Spec.Lifecycle.Hooks); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	// World parameters are written through to gameConfig
+	if req.Spec.World != nil {
+		gameConfig, err := applyWorldToGameConfig(def, req.Spec.GameConfig, req.Spec.World)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		req.Spec.GameConfig = gameConfig
+	}
+
+	// Placement picks the cluster unless one was requested explicitly
+	if req.Spec.Placement != nil && req.Spec.Placement.Region != "" {
+		region := req.Spec.Placement.Region
+		if cluster := c.Query("cluster"); cluster != "" {
+			if s.clusters.regionOf(cluster) != region {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": fmt.Sprintf("Cluster %s is not in region %s", cluster, region),
+				})
+				return
+			}
+		} else {
+			target, err := s.clusters.serverForRegion(region)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": err.Error(),
+				})
+				return
+			}
+			s = target
+		}
+	}
+
+	// Names and hosts must be free on the chosen cluster
+	conflict, err := s.findCreateConflict(context.TODO(), req.Metadata.Namespace, req.Metadata.Name, req.Spec)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to check for conflicts: %v", err),
+		})
+		return
+	}
+	if conflict != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    conflict.Message,
+			"conflict": conflict,
+		})
+		return
+	}
+
+	// Volumes must be provisionable and the server exposable on the
+	// chosen cluster
+	if err := s.checkStorageClass(context.TODO(), req.Spec.Resources.StorageClass); err != nil {
+		status := errorStatus(c, err)
+		if errors.Is(err, errInvalidStorageClass) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := s.checkNetworking(context.TODO(), req.Spec.Networking); err != nil {
+		status := errorStatus(c, err)
+		if errors.Is(err, errInvalidNetworking) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Build the spec object for Crossplane
+	spec := buildGameServerSpec(req.Spec)
+	if def.SteamAppID > 0 {
+		env, err := s.steamCacheEnv(context.TODO())
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to read Steam cache: %v", err),
+			})
+			return
+		}
+		withSteamCacheEnv(spec, env)
+	}
+
+	// Create unstructured object for Crossplane Composite Resource Claim
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": req.APIVersion,
+			"kind":       req.Kind,
+			"metadata": map[string]interface{}{
+				"name":      req.Metadata.Name,
+				"namespace": req.Metadata.Namespace,
+				"labels": map[string]interface{}{},
+			},
+			"spec": spec,
+		},
+	}
+
+	// Standard labels, then any additional labels from the request
+	metadata := obj.Object["metadata"].(map[string]interface{})
+	labels := metadata["labels"].(map[string]interface{})
+	for k, v := range admission.Labels(req.Metadata.Name, req.Spec.GameType) {
+		labels[k] = v
+	}
+	for k, v := range req.Metadata.Labels {
+		labels[k] = v
+	}
+
+	// The creator owns the server
+	if subject := c.GetString(handlers.SubjectKey); subject != "" {
+		obj.SetAnnotations(map[string]string{ownerAnnotation: subject})
+	}
+
+	// Same schema and quota checks as the admission webhook
+	if err := admission.Validate(obj); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	quota, existing, err := s.namespaceQuota(context.TODO(), req.Metadata.Namespace)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to check namespace quota: %v", err),
+		})
+		return
+	}
+	if err := quota.Check(existing, obj); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("GameServer quota exceeded: %v", err),
+		})
+		return
+	}
+
+	// Always tracked so email subscribers hear about new servers too
+	notify := ReadyNotification{Webhooks: []webhookTarget{}}
+	if req.Notify != nil {
+		notify = *req.Notify
+	}
+	if err := setReadyNotification(obj, notify); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Create the Crossplane Composite Resource Claim
+	if err := s.k8sClient.Create(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to create GameServer: %v", err),
+		})
+		return
+	}
+
+	// Convert back to structured format for response
+	gameServer, err := unstructuredToGameServer(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to convert created GameServer: %v", err),
+		})
+		return
+	}
+
+	s.publishEvent(eventGameServerCreated, obj.GetNamespace(), obj.GetName(), map[string]interface{}{
+		"gameType": req.Spec.GameType,
+	})
+
+	// Warn when the pod will likely stay Pending; the server is created
+	// either way
+	response := createdGameServer{GameServer: gameServer}
+	if !req.Spec.Stopped {
+		hint, err := s.checkScheduling(context.TODO(), req.Spec)
+		if err != nil {
+			log.Printf("Failed to check scheduling of GameServer %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		} else if len(hint.Warnings) > 0 {
+			response.Scheduling = hint
+		}
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// buildGameServerSpec builds the claim spec for a new GameServer, leaving
+// out fields that are not set
+func buildGameServerSpec(gsSpec GameServerSpec) map[string]interface{} {
+	spec := map[string]interface{}{
+		"gameType": gsSpec.GameType,
+	}
+
+	// Add server identification
+	if gsSpec.ServerName != "" {
+		spec["serverName"] = gsSpec.ServerName
+	}
+	if gsSpec.ServerDescription != "" {
+		spec["serverDescription"] = gsSpec.ServerDescription
+	}
+	if gsSpec.MOTD != "" {
+		spec["motd"] = gsSpec.MOTD
+	}
+	if gsSpec.Icon != "" {
+		spec["icon"] = gsSpec.Icon
+	}
+	if gsSpec.PublicStatus {
+		spec["publicStatus"] = true
+	}
+
+	// Add resources if provided
+	if gsSpec.Resources.CPU != "" || gsSpec.Resources.Memory != "" || gsSpec.Resources.StorageSize != "" || gsSpec.Resources.StorageClass != "" {
+		resources := map[string]interface{}{}
+		if gsSpec.Resources.CPU != "" {
+			resources["cpu"] = gsSpec.Resources.CPU
+		}
+		if gsSpec.Resources.Memory != "" {
+			resources["memory"] = gsSpec.Resources.Memory
+		}
+		if gsSpec.Resources.StorageSize != "" {
+			resources["storageSize"] = gsSpec.Resources.StorageSize
+		}
+		if gsSpec.Resources.StorageClass != "" {
+			resources["storageClass"] = gsSpec.Resources.StorageClass
+		}
+		spec["resources"] = resources
+	}
+
+	// Add networking if provided
+	if gsSpec.Networking.ServiceType != "" || gsSpec.Networking.EnableIngress || gsSpec.Networking.IngressHost != "" {
+		networking := map[string]interface{}{}
+		if gsSpec.Networking.ServiceType != "" {
+			networking["serviceType"] = gsSpec.Networking.ServiceType
+		}
+		if gsSpec.Networking.EnableIngress {
+			networking["enableIngress"] = gsSpec.Networking.EnableIngress
+		}
+		if gsSpec.Networking.IngressHost != "" {
+			networking["ingressHost"] = gsSpec.Networking.IngressHost
+		}
+		spec["networking"] = networking
+	}
+
+	// Add placement if provided
+	if gsSpec.Placement != nil && gsSpec.Placement.Region != "" {
+		spec["placement"] = map[string]interface{}{
+			"region": gsSpec.Placement.Region,
+		}
+	}
+
+	// Add lifecycle if provided
+	if gsSpec.Stopped {
+		spec["stopped"] = true
+	}
+	if gsSpec.Lifecycle != nil {
+		if lifecycle, err := lifecycleSpec(gsSpec.Lifecycle); err == nil {
+			spec["lifecycle"] = lifecycle
+		}
+	}
+
+	// Add proxy backends if provided
+	if gsSpec.Proxy != nil {
+		if proxy, err := proxySpec(gsSpec.Proxy); err == nil {
+			spec["proxy"] = proxy
+		}
+	}
+
+	// Add shared asset volumes if provided
+	if len(gsSpec.SharedVolumes) > 0 {
+		spec["sharedVolumes"] = sharedVolumesSpec(gsSpec.SharedVolumes)
+	}
+	if len(gsSpec.Dependencies) > 0 {
+		spec["dependencies"] = dependenciesSpec(gsSpec.Dependencies)
+	}
+
+	// Add game-specific configuration
+	if gsSpec.GameConfig != nil && len(gsSpec.GameConfig) > 0 {
+		spec["gameConfig"] = gsSpec.GameConfig
+	}
+
+	// Add advanced configuration if provided
+	if gsSpec.Advanced.Affinity != nil || len(gsSpec.Advanced.Tolerations) > 0 || len(gsSpec.Advanced.CustomEnvVars) > 0 || gsSpec.Advanced.Image != "" || gsSpec.Advanced.Probes != nil {
+		advanced := map[string]interface{}{}
+		if gsSpec.Advanced.Affinity != nil {
+			advanced["affinity"] = gsSpec.Advanced.Affinity
+		}
+		if len(gsSpec.Advanced.Tolerations) > 0 {
+			advanced["tolerations"] = gsSpec.Advanced.Tolerations
+		}
+		if len(gsSpec.Advanced.CustomEnvVars) > 0 {
+			advanced["customEnvVars"] = gsSpec.Advanced.CustomEnvVars
+		}
+		if gsSpec.Advanced.Image != "" {
+			advanced["image"] = gsSpec.Advanced.Image
+		}
+		if gsSpec.Advanced.Probes != nil {
+			if probes, err := probesSpec(gsSpec.Advanced.Probes); err == nil {
+				advanced["probes"] = probes
+			}
+		}
+		spec["advanced"] = advanced
+	}
+
+	return spec
+}
+
+// getGameServer retrieves a specific GameServer
+func (s *Server) getGameServer(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServer",
+	})
+
+	key := client.ObjectKey{
+		Namespace: namespace,
+		Name:      name,
+	}
+
+	if err := s.k8sClient.Get(context.TODO(), key, obj); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+
+	gameServer, err := unstructuredToGameServer(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to convert GameServer: %v", err),
+		})
+		return
+	}
+
+	shaped, ok := selectFields(c, gameServer)
+	if !ok {
+		return
+	}
+
+	c.Header("ETag", handlers.ResourceETag(obj.GetResourceVersion(), c.Query("fields")))
+	c.JSON(http.StatusOK, shaped)
+}
+
+// updateGameServer updates an existing GameServer
+func (s *Server) updateGameServer(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	var updateReq GameServerSpec
+	if err := c.ShouldBindJSON(&updateReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if err := validateBrowserMetadata(updateReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Get existing GameServer
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServer",
+	})
+
+	key := client.ObjectKey{
+		Namespace: namespace,
+		Name:      name,
+	}
+
+	if err := s.k8sClient.Get(context.TODO(), key, obj); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+
+	def, _ := lookupGame(updateReq.GameType)
+	if err := validateProxy(def, name, updateReq.Proxy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := validateProbes(def, updateReq.Advanced.Probes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := s.checkSharedAssets(context.TODO(), updateReq.SharedVolumes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := validateDependencies(updateReq.Dependencies); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if updateReq.Lifecycle != nil {
+		if err := s.checkLifecycleHooks(context.TODO(), namespace, updateReq.Lifecycle.Hooks); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+	// Only a changed service type is checked, so servers stay editable
+	// on clusters that lost their load balancers
+	liveServiceType, _, _ := unstructured.NestedString(obj.Object, "spec", "networking", "serviceType")
+	if effectiveServiceType(updateReq.Networking.ServiceType) != effectiveServiceType(liveServiceType) {
+		if err := s.checkNetworking(context.TODO(), GameServerNetworking{ServiceType: updateReq.Networking.ServiceType}); err != nil {
+			status := errorStatus(c, err)
+			if errors.Is(err, errInvalidNetworking) {
+				status = http.StatusBadRequest
+			}
+			c.JSON(status, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	// World parameters are written through to gameConfig
+	if updateReq.World != nil {
+		gameConfig, err := applyWorldToGameConfig(def, updateReq.GameConfig, updateReq.World)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		updateReq.GameConfig = gameConfig
+	}
+
+	// Switching the game leaves the saves unusable; images change through
+	// rollouts, which take their own snapshots
+	newSpec := mergedUpdateSpec(obj, updateReq)
+	liveGameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	snapshotName := ""
+	if liveGameType != newSpec["gameType"] {
+		snapshot, err := s.safetySnapshot(context.TODO(), obj, "upgrade", "")
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to take safety snapshot: %v", err),
+			})
+			return
+		}
+		if snapshot != nil {
+			snapshotName = snapshot.Name
+		}
+	}
+
+	// Update spec
+	restartFields, err := s.writeGameServerSpec(context.TODO(), obj, newSpec)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update GameServer: %v", err),
+		})
+		return
+	}
+
+	gameServer, err := unstructuredToGameServer(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to convert updated GameServer: %v", err),
+		})
+		return
+	}
+
+	s.publishEvent(eventGameServerUpdated, obj.GetNamespace(), obj.GetName(), map[string]interface{}{
+		"restartRequired": len(restartFields) > 0,
+		"restartFields":   restartFields,
+		"snapshot":        snapshotName,
+	})
+	c.JSON(http.StatusOK, gameServerUpdateResponse{
+		GameServer:      gameServer,
+		RestartRequired: len(restartFields) > 0,
+		RestartFields:   restartFields,
+	})
+}
+
+// buildUpdateSpec builds the spec object written by updateGameServer
+func buildUpdateSpec(updateReq GameServerSpec) map[string]interface{} {
+	spec := map[string]interface{}{
+		"gameType":          updateReq.GameType,
+		"serverName":        updateReq.ServerName,
+		"serverDescription": updateReq.ServerDescription,
+		"motd":              updateReq.MOTD,
+		"icon":              updateReq.Icon,
+		"publicStatus":      updateReq.PublicStatus,
+		"resources": map[string]interface{}{
+			"cpu":         updateReq.Resources.CPU,
+			"memory":      updateReq.Resources.Memory,
+			"storageSize": updateReq.Resources.StorageSize,
+		},
+		"networking": map[string]interface{}{
+			"serviceType": updateReq.Networking.ServiceType,
+		},
+		"gameConfig": updateReq.GameConfig,
+	}
+	if updateReq.Proxy != nil {
+		if proxy, err := proxySpec(updateReq.Proxy); err == nil {
+			spec["proxy"] = proxy
+		}
+	}
+	if len(updateReq.SharedVolumes) > 0 {
+		spec["sharedVolumes"] = sharedVolumesSpec(updateReq.SharedVolumes)
+	}
+	if len(updateReq.Dependencies) > 0 {
+		spec["dependencies"] = dependenciesSpec(updateReq.Dependencies)
+	}
+	if updateReq.Advanced.Probes != nil {
+		if probes, err := probesSpec(updateReq.Advanced.Probes); err == nil {
+			spec["advanced"] = map[string]interface{}{"probes": probes}
+		}
+	}
+	return spec
+}
+
+// writeGameServerSpec replaces the spec of a GameServer and records any
+// changes that need a pod restart in the PendingRestart condition. It returns
+// the full list of fields now waiting for a restart.
+func (s *Server) writeGameServerSpec(ctx context.Context, obj *unstructured.Unstructured, newSpec map[string]interface{}) ([]string, error) {
+	gameType, _, _ := unstructured.NestedString(newSpec, "gameType")
+
+	// Work out which changes need a pod restart before replacing the spec
+	restartFields := []string{}
+	live, liveErr := normalizeForDiff(obj.Object["spec"])
+	proposed, proposedErr := normalizeForDiff(newSpec)
+	if liveErr == nil && proposedErr == nil {
+		changes := diffValues("spec", live, proposed)
+		restartFields = restartRequiredFields(gameType, changes)
+		// Keep the spec being replaced so crash remediation can roll back
+		if len(changes) > 0 {
+			recordPreviousSpec(obj)
+		}
+	}
+	if len(restartFields) > 0 {
+		restartFields = markPendingRestart(obj, restartFields)
+	}
+
+	obj.Object["spec"] = newSpec
+	if err := s.k8sClient.Update(ctx, obj); err != nil {
+		return nil, err
+	}
+
+	if len(restartFields) > 0 {
+		if err := s.recordPendingRestart(ctx, obj, restartFields); err != nil {
+			log.Printf("Failed to set %s condition on GameServer %s/%s: %v", conditionPendingRestart, obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	return restartFields, nil
+}
+
+// crossplaneSpecFields are claim spec fields managed by Crossplane that must
+// survive a spec replacement, otherwise the claim loses its composite binding
+var crossplaneSpecFields = []string{
+	"resourceRef",
+	"compositionRef",
+	"compositionSelector",
+	"compositionRevisionRef",
+	"compositionUpdatePolicy",
+	"compositeDeletePolicy",
+	"writeConnectionSecretToRef",
+}
+
+// mergedUpdateSpec builds the update spec while keeping Crossplane-managed
+// fields from the live object
+func mergedUpdateSpec(live *unstructured.Unstructured, updateReq GameServerSpec) map[string]interface{} {
+	spec := buildUpdateSpec(updateReq)
+	liveSpec, _, _ := unstructured.NestedMap(live.Object, "spec")
+	for _, field := range crossplaneSpecFields {
+		if value, ok := liveSpec[field]; ok {
+			spec[field] = value
+		}
+	}
+	// Placement only changes by migrating the server
+	if placement, ok := liveSpec["placement"]; ok {
+		spec["placement"] = placement
+	}
+	// Lifecycle is driven by the reaper and the extend endpoint, except for
+	// its hooks
+	for _, field := range []string{"stopped", "lifecycle"} {
+		if value, ok := liveSpec[field]; ok {
+			spec[field] = runtime.DeepCopyJSONValue(value)
+		}
+	}
+	if updateReq.Lifecycle != nil {
+		setLifecycleHooks(spec, updateReq.Lifecycle.Hooks)
+	}
+	// Images change through rollouts
+	if image, ok, _ := unstructured.NestedString(liveSpec, "advanced", "image"); ok {
+		_ = unstructured.SetNestedField(spec, image, "advanced", "image")
+	}
+	return spec
+}
+
+// deleteGameServer deletes a GameServer
+func (s *Server) deleteGameServer(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	if c.Query("force") == "true" {
+		s.forceDeleteGameServer(c, namespace, name)
+		return
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServer",
+	})
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+
+	if err := s.k8sClient.Delete(context.TODO(), obj); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete GameServer: %v", err),
+		})
+		return
+	}
+
+	s.publishEvent(eventGameServerDeleted, namespace, name, nil)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "GameServer deleted successfully",
+	})
+}
+
+// getGameServerLogs retrieves logs for a GameServer
+func (s *Server) getGameServerLogs(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+	
+	lines := c.DefaultQuery("lines", "100")
+	tailLines, err := strconv.ParseInt(lines, 10, 64)
+	if err != nil {
+		tailLines = 100
+	}
+
+	// Find pod associated with GameServer
+	podList, err := s.kubeClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app.kubernetes.io/instance=%s", name),
+	})
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to find pods: %v", err),
+		})
+		return
+	}
+
+	if len(podList.Items) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No pods found for GameServer",
+		})
+		return
+	}
+
+	// Get logs from the first pod
+	pod := podList.Items[0]
+	
+	// This is a simplified implementation - in reality you'd stream the logs
+	c.JSON(http.StatusOK, gin.H{
+		"logs": fmt.Sprintf("Logs for GameServer %s in namespace %s (pod: %s)\nRequested %d lines\n[Log streaming not yet implemented]", name, namespace, pod.Name, tailLines),
+		"pod":  pod.Name,
+	})
+}
+
+// restartGameServer restarts a GameServer by deleting its pod
+func (s *Server) restartGameServer(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	// Find pod associated with GameServer
+	podList, err := s.kubeClient.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app.kubernetes.io/instance=%s", name),
+	})
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to find pods: %v", err),
+		})
+		return
+	}
+
+	if len(podList.Items) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No pods found for GameServer",
+		})
+		return
+	}
+
+	// Save the world first unless ?save=false; a failed save does not
+	// hold back the restart
+	saved := ""
+	if c.Query("save") != "false" {
+		if obj, err := s.getGameServerObject(context.TODO(), namespace, name); err == nil {
+			saved = s.saveBeforeAction(context.TODO(), obj, "restart")
+		}
+	}
+
+	// Delete the pod to trigger restart
+	pod := podList.Items[0]
+	if err := s.kubeClient.CoreV1().Pods(namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{}); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to restart GameServer: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("GameServer %s restarted successfully", name),
+		"pod":     pod.Name,
+		"save":    saved,
+	})
+}
+
+// unstructuredToGameServer converts an unstructured object to a GameServer
+func unstructuredToGameServer(obj *unstructured.Unstructured) (*GameServer, error) {
+	gs := &GameServer{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              obj.GetName(),
+			Namespace:         obj.GetNamespace(),
+			CreationTimestamp: obj.GetCreationTimestamp(),
+			Labels:            obj.GetLabels(),
+			Annotations:       obj.GetAnnotations(),
+		},
+	}
+
+	// Extract spec
+	if spec, found, err := unstructured.NestedMap(obj.Object, "spec"); err == nil && found {
+		gs.Spec.GameType, _, _ = unstructured.NestedString(spec, "gameType")
+		gs.Spec.ServerName, _, _ = unstructured.NestedString(spec, "serverName")
+		gs.Spec.ServerDescription, _, _ = unstructured.NestedString(spec, "serverDescription")
+		gs.Spec.MOTD, _, _ = unstructured.NestedString(spec, "motd")
+		gs.Spec.Icon, _, _ = unstructured.NestedString(spec, "icon")
+		gs.Spec.PublicStatus, _, _ = unstructured.NestedBool(spec, "publicStatus")
+
+		if resources, found, _ := unstructured.NestedMap(spec, "resources"); found {
+			gs.Spec.Resources.CPU, _, _ = unstructured.NestedString(resources, "cpu")
+			gs.Spec.Resources.Memory, _, _ = unstructured.NestedString(resources, "memory")
+			gs.Spec.Resources.StorageSize, _, _ = unstructured.NestedString(resources, "storageSize")
+		}
+
+		if networking, found, _ := unstructured.NestedMap(spec, "networking"); found {
+			gs.Spec.Networking.ServiceType, _, _ = unstructured.NestedString(networking, "serviceType")
+		}
+
+		if region, found, _ := unstructured.NestedString(spec, "placement", "region"); found {
+			gs.Spec.Placement = &GameServerPlacement{Region: region}
+		}
+
+		gs.Spec.Stopped, _, _ = unstructured.NestedBool(spec, "stopped")
+		if lifecycle, err := gameServerLifecycle(obj); err == nil {
+			gs.Spec.Lifecycle = lifecycle
+		}
+		if proxy, err := gameServerProxy(obj); err == nil {
+			gs.Spec.Proxy = proxy
+		}
+		if volumes := gameServerSharedVolumes(obj); len(volumes) > 0 {
+			gs.Spec.SharedVolumes = volumes
+		}
+		if dependencies := gameServerDependencies(obj); len(dependencies) > 0 {
+			gs.Spec.Dependencies = dependencies
+		}
+		if probes, err := gameServerProbes(obj); err == nil {
+			gs.Spec.Advanced.Probes = probes
+		}
+
+		if gameConfig, found, _ := unstructured.NestedMap(spec, "gameConfig"); found {
+			gs.Spec.GameConfig = gameConfig
+		}
+		if def, ok := lookupGame(gs.Spec.GameType); ok {
+			gs.Spec.World = worldFromGameConfig(def, gs.Spec.GameConfig)
+		}
+	}
+
+	// Extract status
+	if status, found, err := unstructured.NestedMap(obj.Object, "status"); err == nil && found {
+		gs.Status.Phase, _, _ = unstructured.NestedString(status, "phase")
+		playersOnline, _, _ := unstructured.NestedInt64(status, "playersOnline")
+		gs.Status.PlayersOnline = int(playersOnline)
+		if health, ok := status["health"].(map[string]interface{}); ok {
+			gs.Status.Health = &GameServerHealth{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(health, gs.Status.Health); err != nil {
+				return nil, fmt.Errorf("invalid status.health: %w", err)
+			}
+		}
+		if conditions, err := gameServerConditions(obj); err == nil && len(conditions) > 0 {
+			gs.Status.Conditions = conditions
+		}
+	}
+
+	return gs, nil
+}
+
+// getGameServerMetrics gets CPU and memory metrics for a GameServer pod
+func (s *Server) getGameServerMetrics(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	// Get the GameServer claim to find the resourceRef and game type
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("gameplane.kubelize.io/v1alpha1")
+	obj.SetKind("GameServer")
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+
+	if err := s.k8sClient.Get(context.TODO(), client.ObjectKey{
+		Namespace: namespace,
+		Name:      name,
+	}, obj); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("GameServer not found: %v", err),
+		})
+		return
+	}
+
+	// Extract configured resources from spec
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	resources, _, _ := unstructured.NestedMap(spec, "resources")
+	configuredCPU, _, _ := unstructured.NestedString(resources, "cpu")
+	configuredMemory, _, _ := unstructured.NestedString(resources, "memory")
+	gameType, _, _ := unstructured.NestedString(spec, "gameType")
+
+	// Get the resourceRef.name from the claim spec (not status)
+	resourceRef, _, _ := unstructured.NestedMap(spec, "resourceRef")
+	resourceRefName, _, _ := unstructured.NestedString(resourceRef, "name")
+
+	if resourceRefName == "" {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "GameServer resourceRef.name not found in status - server may not be ready yet",
+		})
+		return
+	}
+
+	// Construct the actual namespace: {resourceRef.name}-{gameType}
+	actualNamespace := fmt.Sprintf("%s-%s", resourceRefName, gameType)
+
+	// The pod labels use the full XGameServer name (resourceRef.name + gameType)
+	// which matches the namespace pattern
+	expectedPodLabel := fmt.Sprintf("%s-%s", resourceRefName, gameType)
+
+	// Find pods with the gameserver label in the actual namespace
+	podList, err := s.kubeClient.CoreV1().Pods(actualNamespace).List(context.TODO(), metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("kubelize.io/gameserver=%s", expectedPodLabel),
+	})
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list pods in namespace %s: %v", actualNamespace, err),
+		})
+		return
+	}
+
+	if len(podList.Items) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":           fmt.Sprintf("No pods found for GameServer %s in namespace %s", name, actualNamespace),
+			"actualNamespace": actualNamespace,
+			"resourceRefName": resourceRefName,
+			"gameType":        gameType,
+			"claimName":       name,
+		})
+		return
+	}
+
+	pod := podList.Items[0] // Use the first pod
+
+	// Get actual metrics from metrics-server
+	cpuUsage, memoryUsage, err := s.getPodMetrics(pod.Name, actualNamespace)
+	if err != nil {
+		// Fallback to showing pod exists but metrics unavailable
+		c.JSON(http.StatusOK, gin.H{
+			"podName":      pod.Name,
+			"podNamespace": actualNamespace,
+			"metrics": gin.H{
+				"cpu": gin.H{
+					"current":    "0m",
+					"configured": configuredCPU,
+					"percentage": 0,
+				},
+				"memory": gin.H{
+					"current":    "0Mi",
+					"configured": configuredMemory,
+					"percentage": 0,
+				},
+			},
+			"status": "metrics_unavailable",
+			"error":  fmt.Sprintf("Metrics unavailable: %v", err),
+		})
+		return
+	}
+
+	// Calculate percentages based on configured resources
+	cpuPercentage := calculateCPUPercentage(cpuUsage, configuredCPU)
+	memoryPercentage := calculateMemoryPercentage(memoryUsage, configuredMemory)
+
+	// Format the current usage for display
+	formattedCPU := formatCPUForDisplay(cpuUsage)
+	formattedMemory := formatMemoryForDisplay(memoryUsage)
+
+	c.JSON(http.StatusOK, gin.H{
+		"podName":      pod.Name,
+		"podNamespace": actualNamespace,
+		"metrics": gin.H{
+			"cpu": gin.H{
+				"current":    formattedCPU,
+				"configured": configuredCPU,
+				"percentage": cpuPercentage,
+			},
+			"memory": gin.H{
+				"current":    formattedMemory,
+				"configured": configuredMemory,
+				"percentage": memoryPercentage,
+			},
+		},
+		"status": "success",
+	})
+}
+
+// getPodMetrics fetches actual CPU and memory usage from metrics-server
+func (s *Server) getPodMetrics(podName, namespace string) (cpuUsage, memoryUsage string, err error) {
+	// Use metrics-server API to get pod metrics
+	metricsClient := s.kubeClient.CoreV1().RESTClient().
+		Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1").
+		Namespace(namespace).
+		Resource("pods").
+		Name(podName)
+
+	result := metricsClient.Do(context.TODO())
+	if result.Error() != nil {
+		return "", "", fmt.Errorf("failed to get metrics: %v", result.Error())
+	}
+
+	rawBytes, err := result.Raw()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read metrics response: %v", err)
+	}
+
+	// Parse the JSON response
+	var metricsResponse map[string]interface{}
+	if err := json.Unmarshal(rawBytes, &metricsResponse); err != nil {
+		return "", "", fmt.Errorf("failed to parse metrics response: %v", err)
+	}
+
+	// Extract containers metrics
+	containers, ok := metricsResponse["containers"].([]interface{})
+	if !ok || len(containers) == 0 {
+		return "", "", fmt.Errorf("no container metrics found")
+	}
+
+	// Get metrics from the first container (main game server container)
+	container := containers[0].(map[string]interface{})
+	usage, ok := container["usage"].(map[string]interface{})
+	if !ok {
+		return "", "", fmt.Errorf("no usage data found")
+	}
+
+	cpu, ok := usage["cpu"].(string)
+	if !ok {
+		cpu = "0m"
+	}
+
+	memory, ok := usage["memory"].(string)
+	if !ok {
+		memory = "0Mi"
+	}
+
+	return cpu, memory, nil
+}
+
+// calculateCPUPercentage calculates CPU usage percentage from current vs configured
+func calculateCPUPercentage(current, configured string) float64 {
+	currentMillicores := parseCPUToMillicores(current)
+	configuredMillicores := parseCPUToMillicores(configured)
+	
+	// Debug logging
+	fmt.Printf("CPU Calculation: current=%s (%d millicores), configured=%s (%d millicores)\n", 
+		current, currentMillicores, configured, configuredMillicores)
+	
+	if configuredMillicores == 0 {
+		fmt.Printf("CPU: configured is 0, returning 0%%\n")
+		return 0
+	}
+	
+	percentage := (float64(currentMillicores) / float64(configuredMillicores)) * 100
+	fmt.Printf("CPU: calculated percentage = %.2f%%\n", percentage)
+	
+	// Cap at 100% for display purposes, but allow calculation above 100% for burstable resources
+	return percentage
+}
+
+// calculateMemoryPercentage calculates memory usage percentage from current vs configured
+func calculateMemoryPercentage(current, configured string) float64 {
+	currentBytes := parseMemoryToBytes(current)
+	configuredBytes := parseMemoryToBytes(configured)
+	
+	if configuredBytes == 0 {
+		return 0
+	}
+	
+	return (float64(currentBytes) / float64(configuredBytes)) * 100
+}
+
+// parseCPUToMillicores converts CPU strings like "287m", "1.5", "2", "2001669174n" to millicores
+func parseCPUToMillicores(cpu string) int64 {
+	if cpu == "" {
+		return 0
+	}
+	
+	fmt.Printf("Parsing CPU: %s\n", cpu)
+	
+	// Handle nanoseconds (e.g., "2001669174n")
+	if strings.HasSuffix(cpu, "n") {
+		cpu = strings.TrimSuffix(cpu, "n")
+		if val, err := strconv.ParseInt(cpu, 10, 64); err == nil {
+			// Convert nanoseconds to millicores: 1 millicore = 1,000,000 nanoseconds
+			millicores := val / 1000000
+			fmt.Printf("Parsed as nanoseconds, converted to millicores: %d\n", millicores)
+			return millicores
+		}
+	}
+	
+	// Handle millicores (e.g., "287m")
+	if strings.HasSuffix(cpu, "m") {
+		cpu = strings.TrimSuffix(cpu, "m")
+		if val, err := strconv.ParseInt(cpu, 10, 64); err == nil {
+			fmt.Printf("Parsed as millicores: %d\n", val)
+			return val
+		}
+	}
+	
+	// Handle cores (e.g., "1.5", "2")
+	if val, err := strconv.ParseFloat(cpu, 64); err == nil {
+		millicores := int64(val * 1000) // Convert to millicores
+		fmt.Printf("Parsed as cores, converted to millicores: %d\n", millicores)
+		return millicores
+	}
+	
+	fmt.Printf("Failed to parse CPU: %s\n", cpu)
+	return 0
+}
+
+// parseMemoryToBytes converts memory strings like "54Mi", "2Gi", "1024Ki" to bytes
+func parseMemoryToBytes(memory string) int64 {
+	if memory == "" {
+		return 0
+	}
+	
+	// Handle different memory units
+	if strings.HasSuffix(memory, "Ki") {
+		memory = strings.TrimSuffix(memory, "Ki")
+		if val, err := strconv.ParseInt(memory, 10, 64); err == nil {
+			return val * 1024
+		}
+	} else if strings.HasSuffix(memory, "Mi") {
+		memory = strings.TrimSuffix(memory, "Mi")
+		if val, err := strconv.ParseInt(memory, 10, 64); err == nil {
+			return val * 1024 * 1024
+		}
+	} else if strings.HasSuffix(memory, "Gi") {
+		memory = strings.TrimSuffix(memory, "Gi")
+		if val, err := strconv.ParseInt(memory, 10, 64); err == nil {
+			return val * 1024 * 1024 * 1024
+		}
+	} else if strings.HasSuffix(memory, "K") {
+		memory = strings.TrimSuffix(memory, "K")
+		if val, err := strconv.ParseInt(memory, 10, 64); err == nil {
+			return val * 1000
+		}
+	} else if strings.HasSuffix(memory, "M") {
+		memory = strings.TrimSuffix(memory, "M")
+		if val, err := strconv.ParseInt(memory, 10, 64); err == nil {
+			return val * 1000 * 1000
+		}
+	} else if strings.HasSuffix(memory, "G") {
+		memory = strings.TrimSuffix(memory, "G")
+		if val, err := strconv.ParseInt(memory, 10, 64); err == nil {
+			return val * 1000 * 1000 * 1000
+		}
+	}
+	
+	// Handle plain bytes
+	if val, err := strconv.ParseInt(memory, 10, 64); err == nil {
+		return val
+	}
+	
+	return 0
+}
+
+// formatCPUForDisplay converts CPU values to a user-friendly format
+func formatCPUForDisplay(cpu string) string {
+	if cpu == "" {
+		return "0m"
+	}
+	
+	// Handle nanoseconds (e.g., "1998140547n")
+	if strings.HasSuffix(cpu, "n") {
+		cpu = strings.TrimSuffix(cpu, "n")
+		if val, err := strconv.ParseInt(cpu, 10, 64); err == nil {
+			// Convert nanoseconds to millicores
+			millicores := val / 1000000 // 1 millicore = 1,000,000 nanoseconds
+			return fmt.Sprintf("%dm", millicores)
+		}
+	}
+	
+	// Handle millicores (e.g., "287m")
+	if strings.HasSuffix(cpu, "m") {
+		return cpu // Already in the right format
+	}
+	
+	// Handle cores (e.g., "1.5", "2")
+	if val, err := strconv.ParseFloat(cpu, 64); err == nil {
+		if val >= 1 {
+			return fmt.Sprintf("%.1f", val) // Show as cores for values >= 1
+		} else {
+			return fmt.Sprintf("%.0fm", val*1000) // Convert to millicores for values < 1
+		}
+	}
+	
+	return cpu // Return as-is if we can't parse it
+}
+
+// formatMemoryForDisplay converts memory values to a user-friendly format
+func formatMemoryForDisplay(memory string) string {
+	if memory == "" {
+		return "0Mi"
+	}
+	
+	// Parse to bytes first
+	bytes := parseMemoryToBytes(memory)
+	if bytes == 0 {
+		return "0Mi"
+	}
+	
+	// Convert to the most appropriate unit
+	const (
+		KiB = 1024
+		MiB = 1024 * 1024
+		GiB = 1024 * 1024 * 1024
+	)
+	
+	if bytes >= GiB {
+		return fmt.Sprintf("%.1fGi", float64(bytes)/float64(GiB))
+	} else if bytes >= MiB {
+		return fmt.Sprintf("%.0fMi", float64(bytes)/float64(MiB))
+	} else if bytes >= KiB {
+		return fmt.Sprintf("%.0fKi", float64(bytes)/float64(KiB))
+	} else {
+		return fmt.Sprintf("%d", bytes)
+	}
+}
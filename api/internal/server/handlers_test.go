@@ -0,0 +1,167 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubelize/gameplane/api/pkg/gameplane/gameplanetest"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// newDemo starts the API on the demo GameServers of --dev
+func newDemo(t *testing.T) *gameplanetest.Harness {
+	t.Helper()
+	h, err := gameplanetest.NewDemo()
+	if err != nil {
+		t.Fatalf("failed to start API: %v", err)
+	}
+	return h
+}
+
+// demoPath fills a route's parameters with the demo-sdtd GameServer and
+// placeholders for everything else
+func demoPath(route string) string {
+	segments := strings.Split(route, "/")
+	for i, segment := range segments {
+		switch {
+		case segment == ":namespace":
+			segments[i] = "default"
+		case segment == ":name":
+			segments[i] = "demo-sdtd"
+		case strings.HasPrefix(segment, ":"), strings.HasPrefix(segment, "*"):
+			segments[i] = "unknown"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func TestReadRoutes(t *testing.T) {
+	h := newDemo(t)
+
+	for _, route := range h.Server.Router().Routes() {
+		if route.Method != http.MethodGet || !strings.HasPrefix(route.Path, "/api/") {
+			continue
+		}
+		// Panels and tunnels dial the game's pod
+		if strings.Contains(route.Path, "/panel/") || strings.HasSuffix(route.Path, "/tunnel") {
+			continue
+		}
+		t.Run(route.Path, func(t *testing.T) {
+			// Streams end with the request
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			rec := h.Send(gameplanetest.Request(http.MethodGet, demoPath(route.Path), nil).WithContext(ctx))
+			if rec.Code == http.StatusInternalServerError {
+				t.Errorf("got 500: %s", rec.Body)
+			}
+		})
+	}
+}
+
+func TestGameServerLifecycle(t *testing.T) {
+	h := newDemo(t)
+	ctx := context.Background()
+
+	create := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "test-sdtd", "namespace": "default"},
+		"spec":     map[string]interface{}{"gameType": "sdtd", "serverName": "Test"},
+	}
+	if code, err := h.DoJSON(http.MethodPost, "/api/v1/gameservers", create, nil); err != nil || code != http.StatusCreated {
+		t.Fatalf("create: got %d (%v), want 201", code, err)
+	}
+
+	var got struct {
+		Spec struct {
+			ServerName string `json:"serverName"`
+		} `json:"spec"`
+	}
+	if code, err := h.DoJSON(http.MethodGet, "/api/v1/gameservers/default/test-sdtd", nil, &got); err != nil || code != http.StatusOK {
+		t.Fatalf("get: got %d (%v), want 200", code, err)
+	}
+	if got.Spec.ServerName != "Test" {
+		t.Errorf("get: serverName %q, want Test", got.Spec.ServerName)
+	}
+
+	update := map[string]interface{}{"gameType": "sdtd", "serverName": "Renamed"}
+	if code, err := h.DoJSON(http.MethodPut, "/api/v1/gameservers/default/test-sdtd", update, nil); err != nil || code != http.StatusOK {
+		t.Fatalf("update: got %d (%v), want 200", code, err)
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("gameplane.kubelize.io/v1alpha1")
+	obj.SetKind("GameServer")
+	if err := h.Client.Get(ctx, client.ObjectKey{Namespace: "default", Name: "test-sdtd"}, obj); err != nil {
+		t.Fatal(err)
+	}
+	if name, _, _ := unstructured.NestedString(obj.Object, "spec", "serverName"); name != "Renamed" {
+		t.Errorf("update: stored serverName %q, want Renamed", name)
+	}
+
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if code, err := h.DoJSON(http.MethodGet, "/api/v1/gameservers?namespace=default", nil, &list); err != nil || code != http.StatusOK {
+		t.Fatalf("list: got %d (%v), want 200", code, err)
+	}
+	found := false
+	for _, item := range list.Items {
+		found = found || item.Metadata.Name == "test-sdtd"
+	}
+	if !found {
+		t.Errorf("list: test-sdtd missing from %+v", list.Items)
+	}
+
+	if code, err := h.DoJSON(http.MethodDelete, "/api/v1/gameservers/default/test-sdtd", nil, nil); err != nil || code != http.StatusOK {
+		t.Fatalf("delete: got %d (%v), want 200", code, err)
+	}
+	if code, _ := h.DoJSON(http.MethodGet, "/api/v1/gameservers/default/test-sdtd", nil, nil); code != http.StatusNotFound {
+		t.Errorf("get after delete: got %d, want 404", code)
+	}
+}
+
+func TestCreateGameServerValidation(t *testing.T) {
+	h := newDemo(t)
+
+	for name, spec := range map[string]map[string]interface{}{
+		"unknown game type": {"gameType": "tetris"},
+		"missing game type": {"serverName": "Nameless"},
+	} {
+		create := map[string]interface{}{
+			"metadata": map[string]interface{}{"name": "invalid", "namespace": "default"},
+			"spec":     spec,
+		}
+		if code, _ := h.DoJSON(http.MethodPost, "/api/v1/gameservers", create, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", name, code)
+		}
+	}
+}
+
+func TestUnknownGameServer(t *testing.T) {
+	h := newDemo(t)
+
+	for _, path := range []string{
+		"/api/v1/gameservers/default/missing",
+		"/api/v2/gameservers/default/missing",
+		"/api/v1/gameservers/default/missing/status",
+	} {
+		if code, _ := h.DoJSON(http.MethodGet, path, nil, nil); code != http.StatusNotFound {
+			t.Errorf("GET %s: got %d, want 404", path, code)
+		}
+	}
+}
+
+func TestVolumeTasksInDevMode(t *testing.T) {
+	h := newDemo(t)
+
+	// Volume tasks run pods, which the in-memory cluster never starts
+	if code, _ := h.DoJSON(http.MethodGet, "/api/v1/gameservers/default/demo-sdtd/admins", nil, nil); code != http.StatusNotImplemented {
+		t.Errorf("admin list: got %d, want 501", code)
+	}
+}
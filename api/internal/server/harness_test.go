@@ -0,0 +1,80 @@
+package server_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/kubelize/gameplane/api/pkg/gameplane"
+	"github.com/kubelize/gameplane/api/pkg/gameplane/gameplanetest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// subjectHeader names the caller for testAuth
+const subjectHeader = "X-Test-Subject"
+
+// testAuth authenticates requests as the subject in subjectHeader
+type testAuth struct{}
+
+func (testAuth) Authenticate(r *http.Request) (string, error) {
+	if subject := r.Header.Get(subjectHeader); subject != "" {
+		return subject, nil
+	}
+	return "", errors.New("no subject")
+}
+
+// tenants are the namespaces each test subject may read; anyone else,
+// such as root, reads every namespace
+var tenants = map[string][]string{
+	"alice": {"default", "team-alice"},
+	"bob":   {"default", "team-bob"},
+}
+
+// testAccess limits the subjects in tenants to their namespaces
+type testAccess struct{}
+
+func (testAccess) ReadableNamespaces(r *http.Request, subject string) ([]string, bool, error) {
+	namespaces, limited := tenants[subject]
+	return namespaces, !limited, nil
+}
+
+// newHarness starts an API with testAuth and testAccess in front and root as
+// its only admin
+func newHarness(t *testing.T, objects ...client.Object) *gameplanetest.Harness {
+	t.Helper()
+	h, err := gameplanetest.New(objects, gameplane.WithAuth(testAuth{}), gameplane.WithNamespaceAccess(testAccess{}), gameplane.WithAdmins("root"))
+	if err != nil {
+		t.Fatalf("failed to start API: %v", err)
+	}
+	return h
+}
+
+// ownedServer builds a GameServer owned by subject
+func ownedServer(namespace, name, owner string) client.Object {
+	obj := gameplanetest.GameServer(namespace, name, "sdtd", map[string]interface{}{"serverName": name})
+	obj.SetAnnotations(map[string]string{"gameplane.kubelize.io/owner": owner})
+	return obj
+}
+
+// call sends req as subject, or unauthenticated when subject is empty,
+// and decodes a JSON response into out when given
+func call(t *testing.T, h *gameplanetest.Harness, subject string, req *http.Request, out interface{}) int {
+	t.Helper()
+	if subject != "" {
+		req.Header.Set(subjectHeader, subject)
+	}
+	rec := h.Send(req)
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: failed to decode response %q: %v", req.Method, req.URL, rec.Body, err)
+		}
+	}
+	return rec.Code
+}
+
+// bearer adds an Authorization header to req
+func bearer(req *http.Request, token string) *http.Request {
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
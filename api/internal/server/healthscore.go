@@ -0,0 +1,46 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kubelize/gameplane/api/internal/metrics"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// healthExportInterval is how often GameServer health scores are exported
+const healthExportInterval = 30 * time.Second
+
+// exportHealthScores publishes the health score the status controller wrote
+// for each GameServer as the gameplane_gameserver_health_score gauge
+func (s *Server) exportHealthScores(ctx context.Context) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+
+	scores := make([]metrics.HealthScore, 0, len(list.Items))
+	for i := range list.Items {
+		obj := &list.Items[i]
+		score, found, _ := unstructured.NestedInt64(obj.Object, "status", "health", "score")
+		if !found {
+			continue
+		}
+		gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+		scores = append(scores, metrics.HealthScore{
+			Namespace: obj.GetNamespace(),
+			Name:      obj.GetName(),
+			GameType:  gameType,
+			Score:     int(score),
+		})
+	}
+	metrics.SetGameServerHealth(s.cluster, scores)
+	return nil
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gin-contrib/cors"
+)
+
+// defaultCORSOrigins are the local Hugo and frontend dev servers
+var defaultCORSOrigins = []string{"http://localhost:1313", "http://localhost:3000"}
+
+// defaultCORSHeaders are the request headers browsers may send cross-origin
+var defaultCORSHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "If-None-Match"}
+
+// httpSettings configure how the API is reached: which browser origins may
+// call it and, behind an ingress, which proxies are trusted and which path
+// prefix it is served under
+type httpSettings struct {
+	// allowOrigins are allowed CORS origins; "*" allows any, and entries
+	// such as https://*.example.com match subdomains
+	allowOrigins []string
+	allowHeaders []string
+	// trustedProxies are IPs or CIDRs whose X-Forwarded-For and X-Real-IP
+	// headers are believed when determining the client address
+	trustedProxies []string
+	// basePath prefixes every route, e.g. /gameplane; empty serves at /
+	basePath string
+}
+
+// httpSettingsFromEnv reads CORS_ALLOWED_ORIGINS, CORS_ALLOWED_HEADERS,
+// TRUSTED_PROXIES (comma-separated) and BASE_PATH
+func httpSettingsFromEnv() (httpSettings, error) {
+	settings := httpSettings{
+		allowOrigins:   splitList(os.Getenv("CORS_ALLOWED_ORIGINS")),
+		allowHeaders:   splitList(os.Getenv("CORS_ALLOWED_HEADERS")),
+		trustedProxies: splitList(os.Getenv("TRUSTED_PROXIES")),
+	}
+	if len(settings.allowOrigins) == 0 {
+		settings.allowOrigins = defaultCORSOrigins
+	}
+	if len(settings.allowHeaders) == 0 {
+		settings.allowHeaders = defaultCORSHeaders
+	}
+
+	basePath, err := normalizeBasePath(os.Getenv("BASE_PATH"))
+	if err != nil {
+		return httpSettings{}, err
+	}
+	settings.basePath = basePath
+
+	if err := settings.corsConfig().Validate(); err != nil {
+		return httpSettings{}, fmt.Errorf("invalid CORS_ALLOWED_ORIGINS: %w", err)
+	}
+	return settings, nil
+}
+
+// corsConfig builds the CORS middleware configuration
+func (h httpSettings) corsConfig() cors.Config {
+	config := cors.DefaultConfig()
+	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	config.AllowHeaders = h.allowHeaders
+	config.ExposeHeaders = []string{"ETag", "Deprecation", "Sunset", "Link"}
+	for _, origin := range h.allowOrigins {
+		if origin == "*" {
+			config.AllowAllOrigins = true
+			return config
+		}
+		if strings.Contains(origin, "*") {
+			config.AllowWildcard = true
+		}
+	}
+	config.AllowOrigins = h.allowOrigins
+	return config
+}
+
+// normalizeBasePath turns "gameplane/" or "/gameplane" into "/gameplane"
+// and "/" into ""
+func normalizeBasePath(raw string) (string, error) {
+	path := strings.Trim(strings.TrimSpace(raw), "/")
+	if path == "" {
+		return "", nil
+	}
+	if strings.ContainsAny(path, "?#:*") {
+		return "", fmt.Errorf("invalid BASE_PATH %q", raw)
+	}
+	return "/" + path, nil
+}
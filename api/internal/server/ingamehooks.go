@@ -0,0 +1,605 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ingameHooksAnnotation holds a GameServer's IngameHookConfig as JSON
+	ingameHooksAnnotation = "gameplane.kubelize.io/ingame-hooks"
+
+	// ingameHookSecretKey is the key of the signing secret in the hook Secret
+	ingameHookSecretKey = "secret"
+
+	// ingameHookLedgerAnnotation on the hook Secret holds the
+	// ingameHookLedger, so every API replica refuses the same replays and
+	// honours the same cooldowns
+	ingameHookLedgerAnnotation = "gameplane.kubelize.io/hook-ledger"
+
+	// Headers of an in-game hook request
+	ingameHookServerHeader    = "X-Gameplane-Server"
+	ingameHookTimestampHeader = "X-Gameplane-Timestamp"
+	ingameHookSignatureHeader = "X-Gameplane-Signature"
+
+	// ingameHookMaxSkew is how far a request's timestamp may be from now;
+	// signatures are remembered this long to refuse replays
+	ingameHookMaxSkew = 5 * time.Minute
+
+	// ingameHookMaxBody bounds a hook request body
+	ingameHookMaxBody = 64 << 10
+
+	// defaultIngameHookCooldown spaces accepted requests for one action
+	defaultIngameHookCooldown = 5 * time.Minute
+
+	// ingameHookTimeout bounds the operation a hook starts
+	ingameHookTimeout = 15 * time.Minute
+)
+
+// Actions in-game plugins can request
+const (
+	ingameActionRestart = "restart"
+	ingameActionBackup  = "backup"
+	ingameActionSave    = "save"
+)
+
+// ingameActions lists every action in the order they are documented
+var ingameActions = []string{ingameActionRestart, ingameActionBackup, ingameActionSave}
+
+// IngameHookConfig enables in-game hooks for a GameServer
+type IngameHookConfig struct {
+	// Actions the server's plugins may request
+	Actions []string `json:"actions"`
+	// Cooldown is the minimum time between two accepted requests for the
+	// same action (default 5m)
+	Cooldown string `json:"cooldown,omitempty"`
+}
+
+// IngameHookRequest is the body an in-game plugin posts
+type IngameHookRequest struct {
+	Action string `json:"action"`
+	// Reason is shown to players and recorded, e.g. "Vote passed 7/10"
+	Reason string `json:"reason,omitempty"`
+	// Player is who started the vote or request
+	Player string `json:"player,omitempty"`
+}
+
+// ingameHookLedger is the replay and cooldown state of a GameServer's hooks
+type ingameHookLedger struct {
+	// Seen maps the MACs of recent requests to when they arrived
+	Seen map[string]time.Time `json:"seen,omitempty"`
+	// Last maps actions to when a request for them was last accepted
+	Last map[string]time.Time `json:"last,omitempty"`
+}
+
+var (
+	// errIngameHookReplayed is returned for a request received before
+	errIngameHookReplayed = errors.New("request was already received")
+	// errIngameHookCooldown is returned for an action requested too soon
+	errIngameHookCooldown = errors.New("action is cooling down")
+)
+
+// getIngameHooks returns the GameServer's hook settings; the signing secret
+// is only returned when it is created or rotated
+func (s *Server) getIngameHooks(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	config, err := ingameHookConfig(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"config":           config,
+		"enabled":          config != nil,
+		"secretName":       ingameHookSecretName(obj.GetName()),
+		"availableActions": ingameActions,
+	})
+}
+
+// putIngameHooks enables hooks for the given actions, creating the signing
+// secret on first use
+func (s *Server) putIngameHooks(c *gin.Context) {
+	var config IngameHookConfig
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if err := validateIngameHookConfig(config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	secret, created, err := s.ensureIngameHookSecret(context.TODO(), obj, false)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to create hook secret: %v", err),
+		})
+		return
+	}
+
+	raw, err := json.Marshal(config)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[ingameHooksAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update in-game hooks: %v", err),
+		})
+		return
+	}
+
+	response := gin.H{
+		"config":     config,
+		"secretName": ingameHookSecretName(obj.GetName()),
+	}
+	if created {
+		response["secret"] = secret
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// rotateIngameHookSecret replaces the signing secret; plugins using the old
+// one are refused from now on
+func (s *Server) rotateIngameHookSecret(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	if _, found := obj.GetAnnotations()[ingameHooksAnnotation]; !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "In-game hooks are not enabled for this GameServer",
+		})
+		return
+	}
+	secret, _, err := s.ensureIngameHookSecret(context.TODO(), obj, true)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to rotate hook secret: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"secretName": ingameHookSecretName(obj.GetName()),
+		"secret":     secret,
+	})
+}
+
+// deleteIngameHooks disables hooks and removes the signing secret
+func (s *Server) deleteIngameHooks(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	annotations := obj.GetAnnotations()
+	if _, found := annotations[ingameHooksAnnotation]; !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "In-game hooks are not enabled for this GameServer",
+		})
+		return
+	}
+	delete(annotations, ingameHooksAnnotation)
+	obj.SetAnnotations(annotations)
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to disable in-game hooks: %v", err),
+		})
+		return
+	}
+	err := s.kubeClient.CoreV1().Secrets(obj.GetNamespace()).Delete(context.TODO(), ingameHookSecretName(obj.GetName()), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Hooks disabled, but the hook secret could not be deleted: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "In-game hooks disabled",
+	})
+}
+
+// receiveIngameHook serves in-game plugins without API credentials. The
+// request names its GameServer and is signed with that server's secret, so
+// a plugin can only act on its own server, and only with the actions the
+// server allows.
+func (s *Server) receiveIngameHook(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, ingameHookMaxBody))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": "Request body is too large",
+		})
+		return
+	}
+
+	// Every authentication failure gets the same answer, so the endpoint
+	// does not reveal which servers exist or have hooks enabled
+	unauthorized := func() {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Invalid hook signature",
+		})
+	}
+	namespace, name, found := strings.Cut(c.GetHeader(ingameHookServerHeader), "/")
+	if !found || !serverRefPattern.MatchString(namespace+"/"+name) {
+		unauthorized()
+		return
+	}
+	timestamp := c.GetHeader(ingameHookTimestampHeader)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		unauthorized()
+		return
+	}
+	if skew := time.Since(time.Unix(unix, 0)); skew > ingameHookMaxSkew || skew < -ingameHookMaxSkew {
+		unauthorized()
+		return
+	}
+
+	obj, err := s.getGameServerObject(context.TODO(), namespace, name)
+	if err != nil && client.IgnoreNotFound(err) != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Hooks are unavailable",
+		})
+		return
+	}
+	var config *IngameHookConfig
+	if err == nil {
+		config, _ = ingameHookConfig(obj)
+	}
+	if config == nil {
+		unauthorized()
+		return
+	}
+	secret, err := s.kubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), ingameHookSecretName(name), metav1.GetOptions{})
+	if err != nil {
+		unauthorized()
+		return
+	}
+	mac, ok := ingameHookMAC(secret.Data[ingameHookSecretKey], timestamp, body, c.GetHeader(ingameHookSignatureHeader))
+	if !ok {
+		unauthorized()
+		return
+	}
+
+	var req IngameHookRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if !containsString(config.Actions, req.Action) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Action %q is not allowed for this server", req.Action),
+		})
+		return
+	}
+	if len(req.Reason) > 256 || len(req.Player) > 128 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "reason or player is too long",
+		})
+		return
+	}
+	cooldown := defaultIngameHookCooldown
+	if d, err := time.ParseDuration(config.Cooldown); err == nil {
+		cooldown = d
+	}
+	switch err := s.admitIngameHook(context.TODO(), secret, mac, req.Action, cooldown, time.Now()); {
+	case errors.Is(err, errIngameHookReplayed):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Request was already received",
+		})
+		return
+	case errors.Is(err, errIngameHookCooldown):
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": fmt.Sprintf("Action %s was requested less than %s ago", req.Action, cooldown),
+		})
+		return
+	case err != nil:
+		log.Printf("Failed to record in-game hook request for %s/%s: %v", namespace, name, err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Hooks are unavailable",
+		})
+		return
+	}
+
+	s.publishEvent(eventHookReceived, namespace, name, map[string]interface{}{
+		"action": req.Action,
+		"reason": req.Reason,
+		"player": req.Player,
+	})
+	s.runIngameAction(c, obj, req)
+}
+
+// runIngameAction maps a hook request onto the operation for its action
+func (s *Server) runIngameAction(c *gin.Context, obj *unstructured.Unstructured, req IngameHookRequest) {
+	switch req.Action {
+	case ingameActionSave:
+		output, err := s.saveWorldInGame(context.TODO(), obj)
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to save world: %v", err),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"action": req.Action,
+			"output": output,
+		})
+	case ingameActionRestart:
+		c.JSON(http.StatusAccepted, s.startSavedRestart("ingame-restart", obj, req.Reason))
+	case ingameActionBackup:
+		if s.snapshots.class == "" {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": "Backups need a VolumeSnapshotClass; none is configured",
+			})
+			return
+		}
+		c.JSON(http.StatusAccepted, s.startSavedBackup("ingame-backup", obj))
+	}
+}
+
+// startSavedRestart starts an operation that announces reason, saves the
+// world and restarts the server pods
+func (s *Server) startSavedRestart(kind string, obj *unstructured.Unstructured, reason string) Operation {
+	return s.startOperation(kind, obj.GetNamespace(), obj.GetName(), []string{"save", "restart"}, ingameHookTimeout, func(ctx context.Context, t *operationTracker) (interface{}, error) {
+		err := t.step("save", func() (string, error) {
+			if reason != "" {
+				if _, err := s.broadcastInGame(ctx, obj, "Restarting: "+reason); err != nil && !errors.Is(err, errConsoleUnsupported) {
+					log.Printf("Restart announcement for %s/%s failed: %v", obj.GetNamespace(), obj.GetName(), err)
+				}
+			}
+			return s.saveBeforeAction(ctx, obj, kind), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		var restarted []string
+		err = t.step("restart", func() (string, error) {
+			pods, namespace, err := s.findGameServerPods(ctx, obj)
+			if err != nil {
+				return "", err
+			}
+			if len(pods) == 0 {
+				return "", fmt.Errorf("no pods found")
+			}
+			if restarted, err = s.deleteGameServerPods(ctx, namespace, pods, nil); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Restarted %s", strings.Join(restarted, ", ")), nil
+		})
+		return gin.H{"restarted": restarted, "reason": reason}, err
+	})
+}
+
+// startSavedBackup starts an operation that saves the world and takes a
+// VolumeSnapshot. Callers check that a VolumeSnapshotClass is configured.
+func (s *Server) startSavedBackup(kind string, obj *unstructured.Unstructured) Operation {
+	return s.startOperation(kind, obj.GetNamespace(), obj.GetName(), []string{"save", "snapshot"}, ingameHookTimeout, func(ctx context.Context, t *operationTracker) (interface{}, error) {
+		err := t.step("save", func() (string, error) {
+			return s.saveBeforeAction(ctx, obj, kind), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		var backup *Backup
+		err = t.step("snapshot", func() (string, error) {
+			var err error
+			if backup, err = s.safetySnapshot(ctx, obj, kind, t.op.ID); err != nil {
+				return "", err
+			}
+			if backup == nil {
+				return "", fmt.Errorf("the GameServer has no data volume yet")
+			}
+			return fmt.Sprintf("Took snapshot %s", backup.Name), nil
+		})
+		return backup, err
+	})
+}
+
+// saveBeforeAction saves the world ahead of a restart or backup. A
+// failed save does not hold back the action that was asked for.
+func (s *Server) saveBeforeAction(ctx context.Context, obj *unstructured.Unstructured, kind string) string {
+	_, err := s.saveWorldInGame(ctx, obj)
+	switch {
+	case errors.Is(err, errConsoleUnsupported):
+		return "The game has no save command"
+	case err != nil:
+		log.Printf("Failed to save world of %s/%s before %s: %v", obj.GetNamespace(), obj.GetName(), kind, err)
+		return fmt.Sprintf("Save failed: %v", err)
+	}
+	return "World saved"
+}
+
+// ensureIngameHookSecret returns the signing secret, creating it when
+// missing or replacing it when rotate is set. created reports whether the
+// returned secret is new.
+func (s *Server) ensureIngameHookSecret(ctx context.Context, obj *unstructured.Unstructured, rotate bool) (string, bool, error) {
+	secrets := s.kubeClient.CoreV1().Secrets(obj.GetNamespace())
+	name := ingameHookSecretName(obj.GetName())
+	existing, err := secrets.Get(ctx, name, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return "", false, err
+	}
+	if err == nil && !rotate && len(existing.Data[ingameHookSecretKey]) > 0 {
+		return string(existing.Data[ingameHookSecretKey]), false, nil
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", false, err
+	}
+	value := hex.EncodeToString(raw)
+	if err == nil {
+		existing.Data = map[string][]byte{ingameHookSecretKey: []byte(value)}
+		_, err = secrets.Update(ctx, existing, metav1.UpdateOptions{})
+		return value, true, err
+	}
+	_, err = secrets.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: obj.GetNamespace(),
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "gameplane",
+				"app.kubernetes.io/instance":   obj.GetName(),
+			},
+		},
+		Data: map[string][]byte{ingameHookSecretKey: []byte(value)},
+	}, metav1.CreateOptions{})
+	return value, true, err
+}
+
+// validateIngameHookConfig checks the actions and cooldown
+func validateIngameHookConfig(config IngameHookConfig) error {
+	if len(config.Actions) == 0 {
+		return fmt.Errorf("at least one action is required (valid: %s)", strings.Join(ingameActions, ", "))
+	}
+	for _, action := range config.Actions {
+		if !containsString(ingameActions, action) {
+			return fmt.Errorf("unknown action %q (valid: %s)", action, strings.Join(ingameActions, ", "))
+		}
+	}
+	if config.Cooldown != "" {
+		if d, err := time.ParseDuration(config.Cooldown); err != nil || d < 0 {
+			return fmt.Errorf("invalid cooldown %q", config.Cooldown)
+		}
+	}
+	return nil
+}
+
+// ingameHookMAC checks a "sha256=<hex>" HMAC of the timestamp, a dot and
+// the body, and returns the MAC in lowercase hex. Replays are recognised by
+// the MAC rather than the header, which hex decoding accepts in any case.
+func ingameHookMAC(secret []byte, timestamp string, body []byte, signature string) (string, bool) {
+	if len(secret) == 0 {
+		return "", false
+	}
+	given, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || !strings.HasPrefix(signature, "sha256=") {
+		return "", false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	sum := mac.Sum(nil)
+	if !hmac.Equal(given, sum) {
+		return "", false
+	}
+	return hex.EncodeToString(sum), true
+}
+
+// admitIngameHook records a verified request in the ledger on the hook
+// Secret. It fails with errIngameHookReplayed for a MAC seen within the
+// allowed clock skew, and with errIngameHookCooldown while the action's
+// previous request is within its cooldown. Writes carry the Secret's
+// resourceVersion, so of two replicas admitting the same request only one
+// succeeds; the other re-reads the ledger and finds the replay.
+func (s *Server) admitIngameHook(ctx context.Context, secret *corev1.Secret, mac, action string, cooldown time.Duration, now time.Time) error {
+	secrets := s.kubeClient.CoreV1().Secrets(secret.Namespace)
+	for attempt := 1; ; attempt++ {
+		ledger := ingameHookLedger{}
+		if raw := secret.Annotations[ingameHookLedgerAnnotation]; raw != "" {
+			// A damaged ledger starts over rather than locking hooks out
+			_ = json.Unmarshal([]byte(raw), &ledger)
+		}
+		if ledger.Seen == nil {
+			ledger.Seen = map[string]time.Time{}
+		}
+		if ledger.Last == nil {
+			ledger.Last = map[string]time.Time{}
+		}
+		for seen, at := range ledger.Seen {
+			if now.Sub(at) > 2*ingameHookMaxSkew {
+				delete(ledger.Seen, seen)
+			}
+		}
+		if _, replayed := ledger.Seen[mac]; replayed {
+			return errIngameHookReplayed
+		}
+
+		// A request refused for its cooldown is still used up
+		ledger.Seen[mac] = now
+		var result error
+		if last, ok := ledger.Last[action]; ok && now.Sub(last) < cooldown {
+			result = errIngameHookCooldown
+		} else {
+			ledger.Last[action] = now
+		}
+		raw, err := json.Marshal(ledger)
+		if err != nil {
+			return err
+		}
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[ingameHookLedgerAnnotation] = string(raw)
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+		if err == nil {
+			return result
+		}
+		if !apierrors.IsConflict(err) || attempt == 3 {
+			return err
+		}
+		if secret, err = secrets.Get(ctx, secret.Name, metav1.GetOptions{}); err != nil {
+			return err
+		}
+	}
+}
+
+// ingameHookSecretName is the Secret holding a GameServer's signing secret
+func ingameHookSecretName(name string) string {
+	return name + "-ingame-hook"
+}
+
+// ingameHookConfig reads the GameServer's hook settings, or nil when hooks
+// are disabled
+func ingameHookConfig(obj *unstructured.Unstructured) (*IngameHookConfig, error) {
+	raw, ok := obj.GetAnnotations()[ingameHooksAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var config IngameHookConfig
+	if err := json.Unmarshal([]byte(raw), &config); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", ingameHooksAnnotation, err)
+	}
+	return &config, nil
+}
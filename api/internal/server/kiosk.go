@@ -0,0 +1,281 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// kioskTokenUse marks kiosk tokens apart from session and player tokens
+	kioskTokenUse = "kiosk"
+
+	// defaultKioskTokenTTL and maxKioskTokenTTL bound a kiosk token's life
+	defaultKioskTokenTTL = 24 * time.Hour
+	maxKioskTokenTTL     = 90 * 24 * time.Hour
+)
+
+// kioskEndpoints are the read-only views a kiosk token may be scoped to
+var kioskEndpoints = map[string]func(*Server, *gin.Context){
+	"status":  (*Server).getKioskStatus,
+	"metrics": (*Server).getGameServerMetrics,
+	"uptime":  (*Server).getGameServerUptime,
+	"chat":    (*Server).getGameServerChat,
+}
+
+// KioskClaims are carried by a kiosk token
+type KioskClaims struct {
+	TokenID string `json:"jti"`
+	Use     string `json:"use"`
+	// Cluster holds the servers
+	Cluster   string    `json:"cluster"`
+	Servers   []string  `json:"servers"`
+	Endpoints []string  `json:"endpoints"`
+	IssuedAt  time.Time `json:"iat"`
+	ExpiresAt time.Time `json:"exp"`
+}
+
+// allows reports whether the token covers endpoint of namespace/name
+func (k KioskClaims) allows(namespace, name, endpoint string) bool {
+	return containsString(k.Servers, namespace+"/"+name) && containsString(k.Endpoints, endpoint)
+}
+
+// allowsCluster reports whether a ?cluster= given with the request names
+// the token's cluster
+func (k KioskClaims) allowsCluster(cluster string) bool {
+	return cluster == "" || cluster == k.Cluster
+}
+
+// kioskEndpointNames lists the endpoints kiosk tokens may be scoped to
+func kioskEndpointNames() []string {
+	names := make([]string, 0, len(kioskEndpoints))
+	for name := range kioskEndpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// createKioskToken mints a read-only token for some endpoints of some
+// servers, e.g. for a status widget. Callers may only include servers they
+// could change themselves. The token is bound to the cluster picked by
+// ?cluster=, where that was checked.
+func (s *Server) createKioskToken(c *gin.Context) {
+	var req struct {
+		Name      string   `json:"name" binding:"required"`
+		Servers   []string `json:"servers" binding:"required"`
+		Endpoints []string `json:"endpoints"`
+		TTL       string   `json:"ttl"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if len(req.Endpoints) == 0 {
+		req.Endpoints = []string{"status"}
+	}
+	for _, endpoint := range req.Endpoints {
+		if _, ok := kioskEndpoints[endpoint]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Unknown endpoint %q (valid: %s)", endpoint, strings.Join(kioskEndpointNames(), ", ")),
+			})
+			return
+		}
+	}
+	ttl := defaultKioskTokenTTL
+	if req.TTL != "" {
+		var err error
+		if ttl, err = parseRange(req.TTL); err != nil || ttl > maxKioskTokenTTL {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("ttl must be a duration of at most %s, like 12h or 30d", maxKioskTokenTTL),
+			})
+			return
+		}
+	}
+	if len(req.Servers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "at least one server is required",
+		})
+		return
+	}
+	scoped, err := s.clusters.clusterServer(c.Query("cluster"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	for _, ref := range req.Servers {
+		if !serverRefPattern.MatchString(ref) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid server %q (expected namespace/name)", ref),
+			})
+			return
+		}
+		namespace, name, _ := strings.Cut(ref, "/")
+		if !s.mayChangeServer(c, namespace, name) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": fmt.Sprintf("GameServer %s is not yours", ref),
+			})
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	session := Session{
+		ID:        newSessionID(),
+		Subject:   c.GetString(handlers.SubjectKey),
+		Kind:      sessionKindKiosk,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+		Name:      req.Name,
+		Cluster:   scoped.cluster,
+		Servers:   req.Servers,
+		Endpoints: req.Endpoints,
+	}
+	if err := s.recordSession(c.Request.Context(), session); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to create kiosk token: %v", err),
+		})
+		return
+	}
+	token, err := s.signToken(c.Request.Context(), KioskClaims{
+		TokenID:   session.ID,
+		Use:       kioskTokenUse,
+		Cluster:   session.Cluster,
+		Servers:   session.Servers,
+		Endpoints: session.Endpoints,
+		IssuedAt:  now,
+		ExpiresAt: session.ExpiresAt,
+	})
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to create kiosk token: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"id":        session.ID,
+		"name":      session.Name,
+		"token":     token,
+		"cluster":   session.Cluster,
+		"servers":   session.Servers,
+		"endpoints": session.Endpoints,
+		"expiresAt": session.ExpiresAt,
+	})
+}
+
+// listKioskTokens returns the kiosk tokens the caller created, or every
+// one for admins
+func (s *Server) listKioskTokens(c *gin.Context) {
+	subject := c.GetString(handlers.SubjectKey)
+	if s.isAdmin(c) {
+		subject = ""
+	}
+	sessions, err := s.loadSessions(context.TODO(), sessionKindKiosk, subject)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	items := []Session{}
+	for _, session := range sessions {
+		items = append(items, session.public())
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.After(items[j].CreatedAt) })
+	c.JSON(http.StatusOK, gin.H{
+		"items":     items,
+		"total":     len(items),
+		"endpoints": kioskEndpointNames(),
+	})
+}
+
+// deleteKioskToken revokes a kiosk token
+func (s *Server) deleteKioskToken(c *gin.Context) {
+	session, _, err := s.getSession(context.TODO(), c.Param("id"))
+	if err != nil && !apierrors.IsNotFound(err) {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil || session.Kind != sessionKindKiosk || !(s.isAdmin(c) || session.Subject == c.GetString(handlers.SubjectKey)) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Kiosk token not found",
+		})
+		return
+	}
+	if err := s.revokeSession(context.TODO(), session.ID); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to revoke kiosk token: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Kiosk token revoked",
+	})
+}
+
+// serveKiosk answers a kiosk request with the endpoint's handler once the
+// token, taken from ?token= or an Authorization: Bearer header, covers it.
+// The token's cluster picks the server, so a same-named server in another
+// cluster stays out of reach.
+func (s *Server) serveKiosk(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		token = bearerToken(c.Request)
+	}
+	var claims KioskClaims
+	err := s.verifyToken(c.Request.Context(), token, &claims)
+	if err != nil || claims.Use != kioskTokenUse || claims.Cluster == "" || !time.Now().Before(claims.ExpiresAt) || s.sessionRevoked(c.Request.Context(), claims.TokenID) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Kiosk token is invalid, expired or revoked",
+		})
+		return
+	}
+	endpoint := c.Param("endpoint")
+	handler, ok := kioskEndpoints[endpoint]
+	if !ok || !claims.allows(c.Param("namespace"), c.Param("name"), endpoint) || !claims.allowsCluster(c.Query("cluster")) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Kiosk token does not cover this endpoint",
+		})
+		return
+	}
+	scoped, err := s.clusters.clusterServer(claims.Cluster)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	handler(scoped, c)
+}
+
+// getKioskStatus returns the player-facing status of a server, whether or
+// not its status is public
+func (s *Server) getKioskStatus(c *gin.Context) {
+	obj, err := s.getGameServerObject(context.TODO(), c.Param("namespace"), c.Param("name"))
+	if apierrors.IsNotFound(err) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "GameServer not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, publicStatus(obj))
+}
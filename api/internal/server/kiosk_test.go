@@ -0,0 +1,73 @@
+package server_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kubelize/gameplane/api/pkg/gameplane/gameplanetest"
+)
+
+// kioskToken is the response to minting a kiosk token
+type kioskToken struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+}
+
+func TestKioskTokens(t *testing.T) {
+	h := newHarness(t, ownedServer("default", "alices", "alice"), ownedServer("default", "bobs", "bob"))
+
+	mint := func(subject string, servers ...string) (int, kioskToken) {
+		var token kioskToken
+		code := call(t, h, subject, gameplanetest.Request(http.MethodPost, "/api/v1/kiosk/tokens", map[string]interface{}{
+			"name":      "lobby screen",
+			"servers":   servers,
+			"endpoints": []string{"status"},
+		}), &token)
+		return code, token
+	}
+	if code, _ := mint("alice", "default/bobs"); code != http.StatusForbidden {
+		t.Errorf("minting for another's server: got %d, want 403", code)
+	}
+	code, token := mint("alice", "default/alices")
+	if code != http.StatusCreated || token.Token == "" {
+		t.Fatalf("minting for own server: got %d, want 201 with a token", code)
+	}
+
+	kiosk := func(path string) int {
+		return call(t, h, "", gameplanetest.Request(http.MethodGet, path+"token="+token.Token, nil), nil)
+	}
+	if code := kiosk("/api/v1/kiosk/gameservers/default/alices/status?"); code != http.StatusOK {
+		t.Errorf("covered endpoint: got %d, want 200", code)
+	}
+	if code := kiosk("/api/v1/kiosk/gameservers/default/alices/metrics?"); code != http.StatusForbidden {
+		t.Errorf("endpoint outside the token: got %d, want 403", code)
+	}
+	if code := kiosk("/api/v1/kiosk/gameservers/default/bobs/status?"); code != http.StatusForbidden {
+		t.Errorf("server outside the token: got %d, want 403", code)
+	}
+	if code := kiosk("/api/v1/kiosk/gameservers/default/alices/status?cluster=elsewhere&"); code != http.StatusForbidden {
+		t.Errorf("other cluster: got %d, want 403", code)
+	}
+	if code := call(t, h, "", gameplanetest.Request(http.MethodGet, "/api/v1/kiosk/gameservers/default/alices/status?token=forged", nil), nil); code != http.StatusUnauthorized {
+		t.Errorf("forged token: got %d, want 401", code)
+	}
+
+	if code := call(t, h, "bob", gameplanetest.Request(http.MethodDelete, "/api/v1/kiosk/tokens/"+token.ID, nil), nil); code != http.StatusNotFound {
+		t.Errorf("revoking another's token: got %d, want 404", code)
+	}
+	var list struct {
+		Total int `json:"total"`
+	}
+	if code := call(t, h, "bob", gameplanetest.Request(http.MethodGet, "/api/v1/kiosk/tokens", nil), &list); code != http.StatusOK || list.Total != 0 {
+		t.Errorf("listing as another subject: got %d with %d tokens, want 200 with none", code, list.Total)
+	}
+	if code := call(t, h, "alice", gameplanetest.Request(http.MethodGet, "/api/v1/kiosk/tokens", nil), &list); code != http.StatusOK || list.Total != 1 {
+		t.Errorf("listing own tokens: got %d with %d tokens, want 200 with 1", code, list.Total)
+	}
+	if code := call(t, h, "alice", gameplanetest.Request(http.MethodDelete, "/api/v1/kiosk/tokens/"+token.ID, nil), nil); code != http.StatusOK {
+		t.Fatalf("revoking own token: got %d, want 200", code)
+	}
+	if code := kiosk("/api/v1/kiosk/gameservers/default/alices/status?"); code != http.StatusUnauthorized {
+		t.Errorf("revoked token: got %d, want 401", code)
+	}
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubelize/gameplane/api/internal/devcluster"
+)
+
+func TestKioskTokenNeedsCluster(t *testing.T) {
+	s, err := NewServer(Options{DevCluster: devcluster.Demo(), Authenticator: headerAuth{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().UTC()
+	serve := func(cluster string) int {
+		token, err := s.signToken(context.Background(), KioskClaims{
+			TokenID:   newSessionID(),
+			Use:       kioskTokenUse,
+			Cluster:   cluster,
+			Servers:   []string{"default/demo-sdtd"},
+			Endpoints: []string{"status"},
+			IssuedAt:  now,
+			ExpiresAt: now.Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/kiosk/gameservers/default/demo-sdtd/status?token="+token, nil))
+		return rec.Code
+	}
+	if code := serve(s.cluster); code != http.StatusOK {
+		t.Errorf("token for the local cluster: got %d, want 200", code)
+	}
+	if code := serve(""); code != http.StatusUnauthorized {
+		t.Errorf("token without a cluster: got %d, want 401", code)
+	}
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// errorStatus maps a failed call to the HTTP status the caller should see,
+// so a Kubernetes NotFound is a 404 and a throttled request a 429 rather than
+// every failure being a 500. It sets Retry-After when the API server
+// suggested a delay.
+func errorStatus(c *gin.Context, err error) int {
+	if seconds, ok := apierrors.SuggestsClientDelay(err); ok && seconds > 0 {
+		c.Header("Retry-After", strconv.Itoa(seconds))
+	}
+	switch {
+	case errors.Is(err, errVolumeTasksUnavailable):
+		return http.StatusNotImplemented
+	case apierrors.IsNotFound(err):
+		return http.StatusNotFound
+	case apierrors.IsForbidden(err):
+		return http.StatusForbidden
+	case apierrors.IsConflict(err), apierrors.IsAlreadyExists(err):
+		return http.StatusConflict
+	case apierrors.IsInvalid(err):
+		return http.StatusUnprocessableEntity
+	case apierrors.IsBadRequest(err):
+		return http.StatusBadRequest
+	case apierrors.IsTooManyRequests(err):
+		return http.StatusTooManyRequests
+	case apierrors.IsTimeout(err), apierrors.IsServerTimeout(err), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case apierrors.IsServiceUnavailable(err):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,235 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+const (
+	// defaultLeaseName is the Lease API replicas compete for, in the cluster
+	// registry namespace
+	defaultLeaseName = "gameplane-api"
+
+	defaultLeaseDuration = 15 * time.Second
+	defaultRenewDeadline = 10 * time.Second
+	defaultRetryPeriod   = 2 * time.Second
+)
+
+// leaderElection decides which API replica runs the background tasks. Only
+// the leader runs them, so schedules, reapers and autoscalers act once no
+// matter how many replicas serve requests. The API runs no controller-runtime
+// manager, so it campaigns with client-go's elector directly, which also lets
+// the replica's identity be its pod name.
+type leaderElection struct {
+	enabled  bool
+	lease    string
+	identity string
+
+	leaseDuration time.Duration
+	renewDeadline time.Duration
+	retryPeriod   time.Duration
+
+	mu      sync.Mutex
+	leading bool
+	since   time.Time
+}
+
+// newLeaderElection reads the election settings: LEADER_ELECTION=false runs
+// the background tasks on every replica, as in dev mode,
+// LEADER_ELECTION_LEASE names the Lease and LEADER_ELECTION_LEASE_DURATION,
+// _RENEW_DEADLINE and _RETRY_PERIOD tune it. POD_NAME, if set, identifies
+// the replica.
+func newLeaderElection(dev bool) (*leaderElection, error) {
+	election := &leaderElection{
+		enabled:       !dev,
+		lease:         defaultLeaseName,
+		leaseDuration: defaultLeaseDuration,
+		renewDeadline: defaultRenewDeadline,
+		retryPeriod:   defaultRetryPeriod,
+	}
+	if raw := os.Getenv("LEADER_ELECTION"); raw != "" {
+		enabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LEADER_ELECTION %q", raw)
+		}
+		election.enabled = enabled
+	}
+	if lease := os.Getenv("LEADER_ELECTION_LEASE"); lease != "" {
+		election.lease = lease
+	}
+	for env, target := range map[string]*time.Duration{
+		"LEADER_ELECTION_LEASE_DURATION": &election.leaseDuration,
+		"LEADER_ELECTION_RENEW_DEADLINE": &election.renewDeadline,
+		"LEADER_ELECTION_RETRY_PERIOD":   &election.retryPeriod,
+	} {
+		if raw := os.Getenv(env); raw != "" {
+			d, err := time.ParseDuration(raw)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid %s %q", env, raw)
+			}
+			*target = d
+		}
+	}
+	if election.renewDeadline >= election.leaseDuration || election.retryPeriod >= election.renewDeadline {
+		return nil, fmt.Errorf("leader election needs retry period < renew deadline < lease duration")
+	}
+
+	// The pod name is unique and matches the lease holder shown by
+	// GET /cluster/leader. The hostname gets a suffix to tell apart
+	// replicas sharing one, e.g. several local runs.
+	election.identity = os.Getenv("POD_NAME")
+	if election.identity == "" {
+		hostname, _ := os.Hostname()
+		suffix := make([]byte, 4)
+		_, _ = rand.Read(suffix)
+		election.identity = hostname + "_" + hex.EncodeToString(suffix)
+	}
+	return election, nil
+}
+
+// setLeading records a change of leadership of this replica
+func (e *leaderElection) setLeading(leading bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.leading = leading
+	e.since = time.Now().UTC()
+}
+
+// runBackgroundTasks starts the background tasks, on this replica only
+// while it holds the lease when leader election is enabled
+func (s *Server) runBackgroundTasks(ctx context.Context) {
+	if !s.leader.enabled {
+		s.leader.setLeading(true)
+		s.startBackgroundTasks(ctx)
+		return
+	}
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: s.leader.lease, Namespace: s.clusters.namespace},
+		Client:     s.kubeClient.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: s.leader.identity},
+	}
+	config := leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   s.leader.leaseDuration,
+		RenewDeadline:   s.leader.renewDeadline,
+		RetryPeriod:     s.leader.retryPeriod,
+		ReleaseOnCancel: true,
+		Name:            s.leader.lease,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				log.Printf("Leader election: %s is leading, starting background tasks", s.leader.identity)
+				s.leader.setLeading(true)
+				s.startBackgroundTasks(ctx)
+			},
+			OnStoppedLeading: func() {
+				log.Printf("Leader election: %s stopped leading, background tasks stopped", s.leader.identity)
+				s.leader.setLeading(false)
+			},
+			OnNewLeader: func(identity string) {
+				if identity != s.leader.identity {
+					log.Printf("Leader election: %s is leading", identity)
+				}
+			},
+		},
+	}
+	elector, err := leaderelection.NewLeaderElector(config)
+	if err != nil {
+		log.Printf("Leader election: %v; background tasks are not running", err)
+		return
+	}
+	go func() {
+		// Run returns when leadership is lost; campaign again
+		for ctx.Err() == nil {
+			elector.Run(ctx)
+		}
+	}()
+}
+
+// LeaderStatus reports which replica runs the background tasks
+type LeaderStatus struct {
+	Enabled bool `json:"enabled"`
+	// Identity is this replica's identity
+	Identity string `json:"identity"`
+	// Leading reports whether this replica runs the background tasks
+	Leading bool       `json:"leading"`
+	Since   *time.Time `json:"since,omitempty"`
+	// Leader is the current lease holder, empty while nobody holds it
+	Leader string     `json:"leader"`
+	Lease  *LeaseInfo `json:"lease,omitempty"`
+}
+
+// LeaseInfo describes the Lease backing the election
+type LeaseInfo struct {
+	Name            string     `json:"name"`
+	Namespace       string     `json:"namespace"`
+	DurationSeconds int32      `json:"durationSeconds"`
+	AcquireTime     *time.Time `json:"acquireTime,omitempty"`
+	RenewTime       *time.Time `json:"renewTime,omitempty"`
+	Transitions     int32      `json:"transitions"`
+}
+
+// getLeader returns the current leader of the background tasks
+func (s *Server) getLeader(c *gin.Context) {
+	s.leader.mu.Lock()
+	status := LeaderStatus{Enabled: s.leader.enabled, Identity: s.leader.identity, Leading: s.leader.leading}
+	if !s.leader.since.IsZero() {
+		since := s.leader.since
+		status.Since = &since
+	}
+	s.leader.mu.Unlock()
+
+	if !s.leader.enabled {
+		status.Leader = s.leader.identity
+		c.JSON(http.StatusOK, status)
+		return
+	}
+	lease, err := s.kubeClient.CoordinationV1().Leases(s.clusters.namespace).Get(context.TODO(), s.leader.lease, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		c.JSON(http.StatusOK, status)
+		return
+	}
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to read leader lease: %v", err),
+		})
+		return
+	}
+	info := &LeaseInfo{Name: lease.Name, Namespace: lease.Namespace}
+	if lease.Spec.HolderIdentity != nil {
+		status.Leader = *lease.Spec.HolderIdentity
+	}
+	if lease.Spec.LeaseDurationSeconds != nil {
+		info.DurationSeconds = *lease.Spec.LeaseDurationSeconds
+	}
+	if lease.Spec.LeaseTransitions != nil {
+		info.Transitions = *lease.Spec.LeaseTransitions
+	}
+	if lease.Spec.AcquireTime != nil {
+		t := lease.Spec.AcquireTime.Time
+		info.AcquireTime = &t
+	}
+	if lease.Spec.RenewTime != nil {
+		t := lease.Spec.RenewTime.Time
+		info.RenewTime = &t
+		// A holder that stopped renewing no longer leads
+		if time.Since(t) > time.Duration(info.DurationSeconds)*time.Second {
+			status.Leader = ""
+		}
+	}
+	status.Lease = info
+	c.JSON(http.StatusOK, status)
+}
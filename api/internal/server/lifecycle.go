@@ -0,0 +1,450 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// lifecycleStateAnnotation holds a GameServer's LifecycleState as JSON
+	lifecycleStateAnnotation = "gameplane.kubelize.io/lifecycle-state"
+
+	// lifecycleReaperInterval is how often scheduled starts and expiries are checked
+	lifecycleReaperInterval = 30 * time.Second
+
+	// defaultTrashRetention is how long servers stopped at expiry are kept
+	defaultTrashRetention = 7 * 24 * time.Hour
+
+	expiryActionDelete = "Delete"
+	expiryActionStop   = "Stop"
+)
+
+// defaultExpiryWarnings are sent before a GameServer expires
+var defaultExpiryWarnings = []string{"1h", "15m"}
+
+// LifecycleState records what the reaper did to a GameServer
+type LifecycleState struct {
+	// Started is set once a scheduled start happened
+	Started bool `json:"started,omitempty"`
+	// Warned lists the warnBefore entries sent for the current expiresAt
+	Warned []string `json:"warned,omitempty"`
+	// ExpiredAt and PurgeAt are set while a server stopped at expiry is in
+	// the trash
+	ExpiredAt *time.Time `json:"expiredAt,omitempty"`
+	PurgeAt   *time.Time `json:"purgeAt,omitempty"`
+}
+
+// validateLifecycle checks the lifecycle of a new GameServer
+func validateLifecycle(lifecycle *GameServerLifecycle, now time.Time) error {
+	if lifecycle.ExpiresAt != nil && lifecycle.TTLAfterReady != "" {
+		return fmt.Errorf("lifecycle.expiresAt and lifecycle.ttlAfterReady are mutually exclusive")
+	}
+	if lifecycle.ExpiresAt != nil && !lifecycle.ExpiresAt.After(now) {
+		return fmt.Errorf("lifecycle.expiresAt must be in the future")
+	}
+	if lifecycle.StartAt != nil && lifecycle.ExpiresAt != nil && !lifecycle.ExpiresAt.After(lifecycle.StartAt.Time) {
+		return fmt.Errorf("lifecycle.expiresAt must be after lifecycle.startAt")
+	}
+	if lifecycle.TTLAfterReady != "" {
+		if _, err := parseRange(lifecycle.TTLAfterReady); err != nil {
+			return fmt.Errorf("lifecycle.ttlAfterReady: %v", err)
+		}
+	}
+	switch lifecycle.ExpiryAction {
+	case "", expiryActionDelete, expiryActionStop:
+	default:
+		return fmt.Errorf("unsupported lifecycle.expiryAction %q (valid: %s, %s)", lifecycle.ExpiryAction, expiryActionDelete, expiryActionStop)
+	}
+	if lifecycle.TrashRetention != "" {
+		if _, err := parseRange(lifecycle.TrashRetention); err != nil {
+			return fmt.Errorf("lifecycle.trashRetention: %v", err)
+		}
+	}
+	for _, warning := range lifecycle.WarnBefore {
+		if d, err := time.ParseDuration(warning); err != nil || d <= 0 {
+			return fmt.Errorf("invalid lifecycle.warnBefore duration %q", warning)
+		}
+	}
+	return nil
+}
+
+// lifecycleSpec renders a lifecycle for the claim spec
+func lifecycleSpec(lifecycle *GameServerLifecycle) (map[string]interface{}, error) {
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(lifecycle)
+}
+
+// gameServerLifecycle reads spec.lifecycle, returning nil when unset
+func gameServerLifecycle(obj *unstructured.Unstructured) (*GameServerLifecycle, error) {
+	raw, ok, _ := unstructured.NestedMap(obj.Object, "spec", "lifecycle")
+	if !ok {
+		return nil, nil
+	}
+	lifecycle := &GameServerLifecycle{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, lifecycle); err != nil {
+		return nil, fmt.Errorf("invalid spec.lifecycle: %w", err)
+	}
+	return lifecycle, nil
+}
+
+// lifecycleState reads the lifecycle state annotation
+func lifecycleState(obj *unstructured.Unstructured) LifecycleState {
+	state := LifecycleState{}
+	if raw, ok := obj.GetAnnotations()[lifecycleStateAnnotation]; ok {
+		if err := json.Unmarshal([]byte(raw), &state); err != nil {
+			log.Printf("Ignoring invalid %s annotation of GameServer %s/%s: %v", lifecycleStateAnnotation, obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	return state
+}
+
+// setLifecycle writes spec.lifecycle and the state annotation to obj
+func setLifecycle(obj *unstructured.Unstructured, lifecycle *GameServerLifecycle, state LifecycleState) error {
+	spec, err := lifecycleSpec(lifecycle)
+	if err != nil {
+		return err
+	}
+	if err := unstructured.SetNestedMap(obj.Object, spec, "spec", "lifecycle"); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[lifecycleStateAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// setStopped scales a GameServer's workload down or back up
+func setStopped(obj *unstructured.Unstructured, stopped bool) error {
+	if !stopped {
+		unstructured.RemoveNestedField(obj.Object, "spec", "stopped")
+		return nil
+	}
+	return unstructured.SetNestedField(obj.Object, true, "spec", "stopped")
+}
+
+// reapGameServers runs scheduled starts, resolves ttlAfterReady, sends
+// expiry warnings and expires or purges ephemeral GameServers
+func (s *Server) reapGameServers(ctx context.Context) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for i := range list.Items {
+		obj := &list.Items[i]
+		lifecycle, err := gameServerLifecycle(obj)
+		if err != nil {
+			log.Printf("Skipping lifecycle of GameServer %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+			continue
+		}
+		if lifecycle == nil {
+			continue
+		}
+		if err := s.reapGameServer(ctx, obj, lifecycle, now); err != nil {
+			log.Printf("Failed to run lifecycle of GameServer %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// reapGameServer advances one GameServer's lifecycle
+func (s *Server) reapGameServer(ctx context.Context, obj *unstructured.Unstructured, lifecycle *GameServerLifecycle, now time.Time) error {
+	state := lifecycleState(obj)
+	namespace, name := obj.GetNamespace(), obj.GetName()
+
+	// Trashed servers are only waiting to be purged or extended
+	if state.ExpiredAt != nil {
+		if state.PurgeAt == nil || now.Before(*state.PurgeAt) {
+			return nil
+		}
+		if err := s.k8sClient.Delete(ctx, obj); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		log.Printf("Purged expired GameServer %s/%s from the trash", namespace, name)
+		s.publishEvent(eventGameServerDeleted, namespace, name, map[string]interface{}{"reason": "expired"})
+		return nil
+	}
+
+	changed := false
+	if lifecycle.StartAt != nil && !state.Started && !now.Before(lifecycle.StartAt.Time) {
+		if err := setStopped(obj, false); err != nil {
+			return err
+		}
+		state.Started = true
+		changed = true
+		log.Printf("Starting scheduled GameServer %s/%s", namespace, name)
+	}
+
+	if lifecycle.ExpiresAt == nil && lifecycle.TTLAfterReady != "" && gameServerReady(obj) {
+		ttl, err := parseRange(lifecycle.TTLAfterReady)
+		if err != nil {
+			return err
+		}
+		expiresAt := metav1.NewTime(now.Add(ttl))
+		lifecycle.ExpiresAt = &expiresAt
+		changed = true
+	}
+
+	if lifecycle.ExpiresAt != nil {
+		if !now.Before(lifecycle.ExpiresAt.Time) {
+			return s.expireGameServer(ctx, obj, lifecycle, state, now)
+		}
+		if warned := s.warnExpiry(ctx, obj, lifecycle, state, now); warned != nil {
+			state.Warned = warned
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	if err := setLifecycle(obj, lifecycle, state); err != nil {
+		return err
+	}
+	return s.k8sClient.Update(ctx, obj)
+}
+
+// warnExpiry sends the most urgent due warning, returning the new warned
+// list, or nil when nothing was due. Warnings passed while the previous
+// one was pending are folded into it rather than sent back to back.
+func (s *Server) warnExpiry(ctx context.Context, obj *unstructured.Unstructured, lifecycle *GameServerLifecycle, state LifecycleState, now time.Time) []string {
+	warnings := lifecycle.WarnBefore
+	if len(warnings) == 0 {
+		warnings = defaultExpiryWarnings
+	}
+	remaining := lifecycle.ExpiresAt.Sub(now)
+	var due []string
+	for _, warning := range warnings {
+		before, err := time.ParseDuration(warning)
+		if err != nil || remaining > before || containsString(state.Warned, warning) {
+			continue
+		}
+		due = append(due, warning)
+	}
+	if len(due) == 0 {
+		return nil
+	}
+
+	action := "deleted"
+	if lifecycle.ExpiryAction == expiryActionStop {
+		action = "stopped"
+	}
+	message := fmt.Sprintf("This server will be %s in %s", action, humanizeDuration(remaining.Round(time.Minute)))
+	s.notifySubscribers(ctx, notificationEvent{
+		Type:      "expiring",
+		Namespace: obj.GetNamespace(),
+		Server:    obj.GetName(),
+		Title:     "Server expiring",
+		Message:   message,
+		Fields:    map[string]string{"Expires": lifecycle.ExpiresAt.UTC().Format(time.RFC3339)},
+		Time:      now,
+	})
+	if gameServerReady(obj) {
+		if _, err := s.broadcastInGame(ctx, obj, message); err != nil {
+			log.Printf("Expiry warning for %s/%s failed: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+
+	warned := append(append([]string{}, state.Warned...), due...)
+	sort.Strings(warned)
+	return warned
+}
+
+// expireGameServer deletes a GameServer at its deadline, or stops it and
+// moves it to the trash
+func (s *Server) expireGameServer(ctx context.Context, obj *unstructured.Unstructured, lifecycle *GameServerLifecycle, state LifecycleState, now time.Time) error {
+	namespace, name := obj.GetNamespace(), obj.GetName()
+	event := notificationEvent{
+		Type:      "expired",
+		Namespace: namespace,
+		Server:    name,
+		Title:     "Server expired",
+		Fields:    map[string]string{"Expired": lifecycle.ExpiresAt.UTC().Format(time.RFC3339)},
+		Time:      now,
+	}
+
+	if lifecycle.ExpiryAction != expiryActionStop {
+		if err := s.k8sClient.Delete(ctx, obj); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		log.Printf("Deleted expired GameServer %s/%s", namespace, name)
+		s.publishEvent(eventGameServerExpired, namespace, name, map[string]interface{}{"action": expiryActionDelete})
+		s.publishEvent(eventGameServerDeleted, namespace, name, map[string]interface{}{"reason": "expired"})
+		event.Message = fmt.Sprintf("%s reached its expiry and was deleted", name)
+		s.notifySubscribers(ctx, event)
+		return nil
+	}
+
+	retention := defaultTrashRetention
+	if lifecycle.TrashRetention != "" {
+		var err error
+		if retention, err = parseRange(lifecycle.TrashRetention); err != nil {
+			return err
+		}
+	}
+	purgeAt := now.Add(retention)
+	state.ExpiredAt = &now
+	state.PurgeAt = &purgeAt
+	if err := setStopped(obj, true); err != nil {
+		return err
+	}
+	if err := setLifecycle(obj, lifecycle, state); err != nil {
+		return err
+	}
+	if err := s.k8sClient.Update(ctx, obj); err != nil {
+		return err
+	}
+	log.Printf("Stopped expired GameServer %s/%s, deleting it at %s", namespace, name, purgeAt.Format(time.RFC3339))
+	s.publishEvent(eventGameServerExpired, namespace, name, map[string]interface{}{
+		"action":  expiryActionStop,
+		"purgeAt": purgeAt,
+	})
+	event.Message = fmt.Sprintf("%s reached its expiry and was stopped. It will be deleted at %s unless it is extended.", name, purgeAt.Format("2006-01-02 15:04 MST"))
+	event.Fields["Deletes"] = purgeAt.Format(time.RFC3339)
+	s.notifySubscribers(ctx, event)
+	return nil
+}
+
+// extendGameServer pushes back a GameServer's expiry, restoring it from the
+// trash if it was stopped at expiry
+func (s *Server) extendGameServer(c *gin.Context) {
+	var req struct {
+		// Duration is added to the current expiry, e.g. "2h" or "1d"
+		Duration string `json:"duration,omitempty"`
+		// ExpiresAt sets a new expiry instead
+		ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if (req.Duration == "") == (req.ExpiresAt == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Exactly one of duration and expiresAt is required",
+		})
+		return
+	}
+	var extension time.Duration
+	if req.Duration != "" {
+		var err error
+		if extension, err = parseRange(req.Duration); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	obj, err := s.getGameServerObject(context.TODO(), c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+	lifecycle, err := gameServerLifecycle(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if lifecycle == nil || (lifecycle.ExpiresAt == nil && lifecycle.TTLAfterReady == "") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "GameServer has no expiry to extend",
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	state := lifecycleState(obj)
+	restored := state.ExpiredAt != nil
+	switch {
+	case req.ExpiresAt != nil:
+		if !req.ExpiresAt.After(now) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "expiresAt must be in the future",
+			})
+			return
+		}
+		lifecycle.ExpiresAt = req.ExpiresAt
+		lifecycle.TTLAfterReady = ""
+	case lifecycle.ExpiresAt == nil:
+		// Not ready yet, so the lifetime has not started
+		ttl, _ := parseRange(lifecycle.TTLAfterReady)
+		lifecycle.TTLAfterReady = (ttl + extension).String()
+	default:
+		base := lifecycle.ExpiresAt.Time
+		if base.Before(now) {
+			base = now
+		}
+		expiresAt := metav1.NewTime(base.Add(extension))
+		lifecycle.ExpiresAt = &expiresAt
+	}
+
+	state.Warned = nil
+	state.ExpiredAt = nil
+	state.PurgeAt = nil
+	if restored {
+		if err := setStopped(obj, false); err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+	if err := setLifecycle(obj, lifecycle, state); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to extend GameServer: %v", err),
+		})
+		return
+	}
+
+	s.publishEvent(eventGameServerUpdated, obj.GetNamespace(), obj.GetName(), map[string]interface{}{
+		"lifecycle": lifecycle,
+		"restored":  restored,
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "GameServer expiry extended",
+		"lifecycle": lifecycle,
+		"restored":  restored,
+	})
+}
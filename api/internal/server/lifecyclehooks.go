@@ -0,0 +1,364 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// lifecycleHookInterval is how often hook scripts are synced and hook
+	// runs reported
+	lifecycleHookInterval = 30 * time.Second
+
+	// lifecycleHooksConfigMap holds the scripts of a GameServer's hooks in
+	// its managed namespace, keyed by hook name. Compositions mount it.
+	lifecycleHooksConfigMap = "gameplane-hooks"
+
+	// lifecycleHooksLabel marks the hook ConfigMaps the API provides
+	lifecycleHooksLabel = "gameplane.kubelize.io/lifecycle-hooks"
+
+	// hooksReportedAnnotation lists the hook runs of a pod already in the
+	// activity feed
+	hooksReportedAnnotation = "gameplane.kubelize.io/hooks-reported"
+
+	// hookContainerPrefix names the init containers of pre-start hooks
+	hookContainerPrefix = "hook-"
+
+	// postStopReported marks a pod whose post-stop hooks were reported
+	postStopReported = "post-stop"
+
+	// hookEventRetention is how long reported events are remembered;
+	// Kubernetes keeps events for an hour by default
+	hookEventRetention = 2 * time.Hour
+
+	// failedPreStopHook is the event reason of a failed post-stop hook;
+	// post-stop hooks run as the game container's preStop handler
+	failedPreStopHook = "FailedPreStopHook"
+
+	maxLifecycleHooks = 4
+)
+
+// lifecycleHookState remembers the failed post-stop hook events already
+// reported; their pods are gone, so there is nothing to mark
+type lifecycleHookState struct {
+	mu sync.Mutex
+	// reported maps event UIDs to when they were reported
+	reported map[string]time.Time
+	// since is when this replica started reporting; older events were
+	// reported by the previous leader
+	since time.Time
+}
+
+// validateLifecycleHooks checks the form of spec.lifecycle.hooks
+func validateLifecycleHooks(hooks *GameServerLifecycleHooks) error {
+	if hooks == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	phases := []struct {
+		name  string
+		hooks []GameServerLifecycleHook
+	}{{"preStart", hooks.PreStart}, {"postStop", hooks.PostStop}}
+	for _, phase := range phases {
+		if len(phase.hooks) > maxLifecycleHooks {
+			return fmt.Errorf("lifecycle.hooks.%s has at most %d hooks", phase.name, maxLifecycleHooks)
+		}
+		for _, hook := range phase.hooks {
+			switch {
+			case len(hook.Name) > 40 || len(validation.IsDNS1123Label(hook.Name)) > 0:
+				return fmt.Errorf("invalid lifecycle hook name %q", hook.Name)
+			case seen[hook.Name]:
+				return fmt.Errorf("lifecycle hook %s is declared twice", hook.Name)
+			case len(validation.IsDNS1123Subdomain(hook.ConfigMap)) > 0:
+				return fmt.Errorf("lifecycle hook %s has an invalid configMap %q", hook.Name, hook.ConfigMap)
+			case len(validation.IsConfigMapKey(hook.Key)) > 0:
+				return fmt.Errorf("lifecycle hook %s has an invalid key %q", hook.Name, hook.Key)
+			case phase.name == "postStop" && hook.Image != "":
+				return fmt.Errorf("post-stop hook %s runs in the game container and takes no image", hook.Name)
+			case strings.ContainsAny(hook.Image, " \t\n"):
+				return fmt.Errorf("lifecycle hook %s has an invalid image %q", hook.Name, hook.Image)
+			}
+			seen[hook.Name] = true
+		}
+	}
+	return nil
+}
+
+// checkLifecycleHooks validates spec.lifecycle.hooks and checks every
+// script is in its ConfigMap
+func (s *Server) checkLifecycleHooks(ctx context.Context, namespace string, hooks *GameServerLifecycleHooks) error {
+	if err := validateLifecycleHooks(hooks); err != nil {
+		return err
+	}
+	for _, hook := range lifecycleHookList(hooks) {
+		cm, err := s.kubeClient.CoreV1().ConfigMaps(namespace).Get(ctx, hook.ConfigMap, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("ConfigMap %s of lifecycle hook %s not found in namespace %s", hook.ConfigMap, hook.Name, namespace)
+		}
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(cm.Data[hook.Key]) == "" {
+			return fmt.Errorf("ConfigMap %s has no script under key %s for lifecycle hook %s", hook.ConfigMap, hook.Key, hook.Name)
+		}
+	}
+	return nil
+}
+
+// lifecycleHookList returns the pre-start hooks followed by the post-stop
+// hooks
+func lifecycleHookList(hooks *GameServerLifecycleHooks) []GameServerLifecycleHook {
+	if hooks == nil {
+		return nil
+	}
+	return append(append([]GameServerLifecycleHook{}, hooks.PreStart...), hooks.PostStop...)
+}
+
+// setLifecycleHooks replaces the hooks of a claim spec's lifecycle; nil
+// hooks are removed
+func setLifecycleHooks(spec map[string]interface{}, hooks *GameServerLifecycleHooks) {
+	if hooks == nil || (len(hooks.PreStart) == 0 && len(hooks.PostStop) == 0) {
+		unstructured.RemoveNestedField(spec, "lifecycle", "hooks")
+		if lifecycle, ok, _ := unstructured.NestedMap(spec, "lifecycle"); ok && len(lifecycle) == 0 {
+			delete(spec, "lifecycle")
+		}
+		return
+	}
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(hooks)
+	if err != nil {
+		return
+	}
+	_ = unstructured.SetNestedMap(spec, raw, "lifecycle", "hooks")
+}
+
+// gameServerLifecycleHooks reads spec.lifecycle.hooks, returning nil when
+// there are none
+func gameServerLifecycleHooks(obj *unstructured.Unstructured) *GameServerLifecycleHooks {
+	lifecycle, err := gameServerLifecycle(obj)
+	if err != nil || lifecycle == nil || lifecycle.Hooks == nil {
+		return nil
+	}
+	if len(lifecycle.Hooks.PreStart) == 0 && len(lifecycle.Hooks.PostStop) == 0 {
+		return nil
+	}
+	return lifecycle.Hooks
+}
+
+// syncLifecycleHooks copies the hook scripts of every GameServer into its
+// managed namespace, removes copies no server needs anymore and reports
+// hook runs to the activity feed. ConfigMaps cannot be mounted across
+// namespaces, so the scripts are gathered into one ConfigMap per server.
+func (s *Server) syncLifecycleHooks(ctx context.Context) error {
+	list, err := s.listAllGameServers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+	wanted := map[string]bool{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		hooks := gameServerLifecycleHooks(obj)
+		if hooks == nil {
+			continue
+		}
+		namespace, err := managedNamespace(obj)
+		if err != nil {
+			// Not provisioned yet
+			continue
+		}
+		wanted[namespace] = true
+		if err := s.provideLifecycleHooks(ctx, obj, namespace, hooks); err != nil {
+			log.Printf("Failed to provide lifecycle hooks to GameServer %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+		if err := s.reportLifecycleHooks(ctx, obj, namespace, hooks); err != nil {
+			log.Printf("Failed to report lifecycle hooks of GameServer %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+
+	copies, err := s.kubeClient.CoreV1().ConfigMaps(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: lifecycleHooksLabel})
+	if err != nil {
+		return fmt.Errorf("failed to list lifecycle hook ConfigMaps: %w", err)
+	}
+	for _, cm := range copies.Items {
+		if wanted[cm.Namespace] {
+			continue
+		}
+		if err := s.kubeClient.CoreV1().ConfigMaps(cm.Namespace).Delete(ctx, cm.Name, metav1.DeleteOptions{}); client.IgnoreNotFound(err) != nil {
+			log.Printf("Failed to remove lifecycle hooks from namespace %s: %v", cm.Namespace, err)
+		}
+	}
+	return nil
+}
+
+// provideLifecycleHooks writes the scripts of a GameServer's hooks to the
+// ConfigMap in its managed namespace. A missing script leaves the copy as
+// it was, so the pod keeps the last good version.
+func (s *Server) provideLifecycleHooks(ctx context.Context, obj *unstructured.Unstructured, namespace string, hooks *GameServerLifecycleHooks) error {
+	data := map[string]string{}
+	for _, hook := range lifecycleHookList(hooks) {
+		source, err := s.kubeClient.CoreV1().ConfigMaps(obj.GetNamespace()).Get(ctx, hook.ConfigMap, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("hook %s: %w", hook.Name, err)
+		}
+		script, ok := source.Data[hook.Key]
+		if !ok {
+			return fmt.Errorf("hook %s: ConfigMap %s has no key %s", hook.Name, hook.ConfigMap, hook.Key)
+		}
+		data[hook.Name] = script
+	}
+
+	configMaps := s.kubeClient.CoreV1().ConfigMaps(namespace)
+	existing, err := configMaps.Get(ctx, lifecycleHooksConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      lifecycleHooksConfigMap,
+				Namespace: namespace,
+				Labels: map[string]string{
+					lifecycleHooksLabel:            "true",
+					"kubelize.io/gameserver":       namespace,
+					"app.kubernetes.io/managed-by": "gameplane-api",
+				},
+			},
+			Data: data,
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(existing.Data, data) {
+		return nil
+	}
+	existing.Data = data
+	_, err = configMaps.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// reportLifecycleHooks adds hook runs to the activity feed: finished
+// pre-start hook containers, pods stopping with post-stop hooks and failed
+// post-stop hooks. Each run is reported once; reported runs are listed on
+// the pod.
+func (s *Server) reportLifecycleHooks(ctx context.Context, obj *unstructured.Unstructured, namespace string, hooks *GameServerLifecycleHooks) error {
+	pods, _, err := s.findGameServerPods(ctx, obj)
+	if err != nil {
+		return fmt.Errorf("failed to list game pods: %w", err)
+	}
+	report := func(hookType, summary string) {
+		s.recordActivity(Activity{
+			Kind:      activityLifecycle,
+			Type:      hookType,
+			Actor:     systemActor,
+			Cluster:   s.cluster,
+			Namespace: obj.GetNamespace(),
+			Server:    obj.GetName(),
+			Summary:   summary,
+		})
+	}
+
+	for i := range pods {
+		pod := &pods[i]
+		reported := map[string]bool{}
+		for _, run := range strings.Split(pod.Annotations[hooksReportedAnnotation], ",") {
+			if run != "" {
+				reported[run] = true
+			}
+		}
+		before := len(reported)
+
+		for _, status := range pod.Status.InitContainerStatuses {
+			hook, ok := strings.CutPrefix(status.Name, hookContainerPrefix)
+			terminated := status.State.Terminated
+			if !ok || terminated == nil {
+				continue
+			}
+			run := fmt.Sprintf("%s:%d", hook, status.RestartCount)
+			if reported[run] {
+				continue
+			}
+			reported[run] = true
+			if terminated.ExitCode == 0 {
+				report("pre-start-hook", fmt.Sprintf("Pre-start hook %s succeeded in pod %s", hook, pod.Name))
+				continue
+			}
+			summary := fmt.Sprintf("Pre-start hook %s failed in pod %s with exit code %d", hook, pod.Name, terminated.ExitCode)
+			if terminated.Message != "" {
+				summary += ": " + strings.TrimSpace(terminated.Message)
+			}
+			report("pre-start-hook", summary)
+		}
+
+		if len(hooks.PostStop) > 0 && pod.DeletionTimestamp != nil && !reported[postStopReported] {
+			reported[postStopReported] = true
+			names := []string{}
+			for _, hook := range hooks.PostStop {
+				names = append(names, hook.Name)
+			}
+			report("post-stop-hook", fmt.Sprintf("Running post-stop hooks %s in pod %s", strings.Join(names, ", "), pod.Name))
+		}
+
+		if len(reported) == before {
+			continue
+		}
+		runs := make([]string, 0, len(reported))
+		for run := range reported {
+			runs = append(runs, run)
+		}
+		sort.Strings(runs)
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[hooksReportedAnnotation] = strings.Join(runs, ",")
+		if _, err := s.kubeClient.CoreV1().Pods(pod.Namespace).Update(ctx, pod, metav1.UpdateOptions{}); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to mark hook runs of pod %s: %w", pod.Name, err)
+		}
+	}
+
+	if len(hooks.PostStop) == 0 {
+		return nil
+	}
+	events, err := s.kubeClient.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "reason=" + failedPreStopHook,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list events: %w", err)
+	}
+	state := s.lifecycleHooks
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	now := time.Now()
+	if state.reported == nil {
+		state.reported = map[string]time.Time{}
+		state.since = now
+	}
+	for uid, at := range state.reported {
+		if now.Sub(at) > hookEventRetention {
+			delete(state.reported, uid)
+		}
+	}
+	for _, event := range events.Items {
+		seen := event.LastTimestamp.Time
+		if seen.IsZero() {
+			seen = event.EventTime.Time
+		}
+		if _, ok := state.reported[string(event.UID)]; ok || seen.Before(state.since) {
+			continue
+		}
+		state.reported[string(event.UID)] = now
+		report("post-stop-hook", fmt.Sprintf("Post-stop hooks failed in pod %s: %s", event.InvolvedObject.Name, event.Message))
+	}
+	return nil
+}
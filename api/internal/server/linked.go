@@ -0,0 +1,674 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// serverClusterLabel marks the GameServers of a cluster of servers and
+	// the discovery Services generated for them
+	serverClusterLabel = "gameplane.kubelize.io/server-cluster"
+
+	// serverClusterRoleAnnotation records a member's role in its cluster
+	serverClusterRoleAnnotation = "gameplane.kubelize.io/server-cluster-role"
+
+	// linkServicePrefix starts the name of the Service through which a
+	// member reaches a sibling
+	linkServicePrefix = "link-"
+
+	// serverClusterLinkInterval is how often clusters of servers are
+	// relinked, picking up members that finished provisioning
+	serverClusterLinkInterval = 30 * time.Second
+
+	maxServerClusterMembers = 16
+
+	// Environment variables describing the cluster to every member
+	envServerCluster = "GAMEPLANE_SERVER_CLUSTER"
+	envServerRole    = "GAMEPLANE_SERVER_ROLE"
+	envPeers         = "GAMEPLANE_PEERS"
+	envPeerPrefix    = "GAMEPLANE_PEER_"
+)
+
+// serverClusterNamePattern keeps names usable as label values
+var serverClusterNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// ServerCluster is a group of linked GameServers in one namespace, such as
+// a proxy and its shards, that discover each other through generated
+// Services and environment variables
+type ServerCluster struct {
+	Namespace string                `json:"namespace"`
+	Name      string                `json:"name"`
+	Members   []ServerClusterMember `json:"members"`
+}
+
+// ServerClusterMember is one GameServer of a cluster of servers
+type ServerClusterMember struct {
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	GameType string `json:"gameType,omitempty"`
+	Ready    bool   `json:"ready"`
+	// Host is the name siblings resolve the member by, and Target the game
+	// Service it points to, once the member is provisioned
+	Host   string `json:"host"`
+	Target string `json:"target,omitempty"`
+	Port   int32  `json:"port,omitempty"`
+}
+
+// linkTarget is where siblings reach a provisioned member
+type linkTarget struct {
+	host string
+	port int32
+}
+
+// linkServiceName is the Service through which siblings reach a member
+func linkServiceName(member string) string {
+	return linkServicePrefix + member
+}
+
+// peerEnvName turns a member name into an environment variable infix
+func peerEnvName(member string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(member))
+}
+
+// listServerClusters returns the clusters of servers in ?namespace=
+// (default "default"), or all readable namespaces with "all"
+func (s *Server) listServerClusters(c *gin.Context) {
+	namespace := c.DefaultQuery("namespace", "default")
+	scope, err := s.readableNamespaces(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to determine readable namespaces: %v", err),
+		})
+		return
+	}
+	listNamespace := namespace
+	if namespace == "all" {
+		listNamespace = ""
+	} else if !scope.allows(namespace) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Not permitted to access namespace %s", namespace),
+		})
+		return
+	}
+	members, err := s.serverClusterMembers(context.TODO(), listNamespace, "")
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	grouped := map[string][]unstructured.Unstructured{}
+	for _, member := range members {
+		if !scope.allows(member.GetNamespace()) {
+			continue
+		}
+		key := member.GetNamespace() + "/" + member.GetLabels()[serverClusterLabel]
+		grouped[key] = append(grouped[key], member)
+	}
+	items := make([]ServerCluster, 0, len(grouped))
+	for key, group := range grouped {
+		ns, name, _ := strings.Cut(key, "/")
+		items = append(items, serverClusterStatus(ns, name, group, nil))
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Namespace != items[j].Namespace {
+			return items[i].Namespace < items[j].Namespace
+		}
+		return items[i].Name < items[j].Name
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(items),
+	})
+}
+
+// createServerCluster links GameServers into a new cluster of servers
+func (s *Server) createServerCluster(c *gin.Context) {
+	var req struct {
+		Namespace string `json:"namespace"`
+		Name      string `json:"name" binding:"required"`
+		Members   []struct {
+			Name string `json:"name" binding:"required"`
+			Role string `json:"role" binding:"required"`
+		} `json:"members" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if req.Namespace == "" {
+		req.Namespace = "default"
+	}
+	if !serverClusterNamePattern.MatchString(req.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid name %q (lowercase letters, digits and dashes)", req.Name),
+		})
+		return
+	}
+	if len(req.Members) < 2 || len(req.Members) > maxServerClusterMembers {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("A cluster of servers needs 2 to %d members", maxServerClusterMembers),
+		})
+		return
+	}
+	if !s.checkMaintenance(c, req.Namespace) {
+		return
+	}
+	existing, err := s.serverClusterMembers(context.TODO(), req.Namespace, req.Name)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if len(existing) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("Cluster of servers %s/%s already exists", req.Namespace, req.Name),
+		})
+		return
+	}
+
+	objs := make([]*unstructured.Unstructured, 0, len(req.Members))
+	seen := map[string]bool{}
+	for _, member := range req.Members {
+		if seen[member.Name] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("GameServer %s is listed twice", member.Name),
+			})
+			return
+		}
+		seen[member.Name] = true
+		obj, status, err := s.linkableGameServer(context.TODO(), req.Namespace, member.Name, req.Name, member.Role)
+		if err != nil {
+			c.JSON(status, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		objs = append(objs, obj)
+	}
+	for i, obj := range objs {
+		if err := s.setServerClusterMembership(context.TODO(), obj, req.Name, req.Members[i].Role); err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to link GameServer %s: %v", obj.GetName(), err),
+			})
+			return
+		}
+	}
+	s.respondWithLinkedServerCluster(c, http.StatusCreated, req.Namespace, req.Name)
+}
+
+// getServerCluster returns a cluster of servers and how its members reach
+// each other
+func (s *Server) getServerCluster(c *gin.Context) {
+	members, ok := s.loadServerCluster(c)
+	if !ok {
+		return
+	}
+	targets := s.linkTargets(context.TODO(), members)
+	c.JSON(http.StatusOK, serverClusterStatus(c.Param("namespace"), c.Param("name"), members, targets))
+}
+
+// putServerClusterMember adds a GameServer to a cluster of servers or
+// changes its role
+func (s *Server) putServerClusterMember(c *gin.Context) {
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	namespace, name := c.Param("namespace"), c.Param("name")
+	members, ok := s.loadServerCluster(c)
+	if !ok {
+		return
+	}
+	isMember := false
+	for _, member := range members {
+		isMember = isMember || member.GetName() == c.Param("server")
+	}
+	if !isMember && len(members) >= maxServerClusterMembers {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("A cluster of servers has at most %d members", maxServerClusterMembers),
+		})
+		return
+	}
+	obj, status, err := s.linkableGameServer(context.TODO(), namespace, c.Param("server"), name, req.Role)
+	if err != nil {
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := s.setServerClusterMembership(context.TODO(), obj, name, req.Role); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to link GameServer %s: %v", obj.GetName(), err),
+		})
+		return
+	}
+	s.respondWithLinkedServerCluster(c, http.StatusOK, namespace, name)
+}
+
+// deleteServerClusterMember unlinks one GameServer from its cluster
+func (s *Server) deleteServerClusterMember(c *gin.Context) {
+	namespace, name := c.Param("namespace"), c.Param("name")
+	members, ok := s.loadServerCluster(c)
+	if !ok {
+		return
+	}
+	for i := range members {
+		if members[i].GetName() != c.Param("server") {
+			continue
+		}
+		if err := s.unlinkGameServer(context.TODO(), &members[i]); err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to unlink GameServer %s: %v", members[i].GetName(), err),
+			})
+			return
+		}
+		s.respondWithLinkedServerCluster(c, http.StatusOK, namespace, name)
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": fmt.Sprintf("GameServer %s is not in cluster of servers %s/%s", c.Param("server"), namespace, name),
+	})
+}
+
+// deleteServerCluster unlinks every member; the GameServers are kept
+func (s *Server) deleteServerCluster(c *gin.Context) {
+	members, ok := s.loadServerCluster(c)
+	if !ok {
+		return
+	}
+	for i := range members {
+		if err := s.unlinkGameServer(context.TODO(), &members[i]); err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to unlink GameServer %s: %v", members[i].GetName(), err),
+			})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cluster of servers deleted, its GameServers were kept",
+	})
+}
+
+// loadServerCluster lists the members of the cluster of servers named in
+// the route, writing the error response itself when there are none
+func (s *Server) loadServerCluster(c *gin.Context) ([]unstructured.Unstructured, bool) {
+	members, err := s.serverClusterMembers(context.TODO(), c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return nil, false
+	}
+	if len(members) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Cluster of servers not found",
+		})
+		return nil, false
+	}
+	return members, true
+}
+
+// respondWithLinkedServerCluster relinks a cluster of servers right away
+// so the response reflects the requested change
+func (s *Server) respondWithLinkedServerCluster(c *gin.Context, status int, namespace, name string) {
+	cluster, err := s.linkServerCluster(context.TODO(), namespace, name)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to link cluster of servers: %v", err),
+		})
+		return
+	}
+	c.JSON(status, cluster)
+}
+
+// linkableGameServer fetches a GameServer that may join a cluster of
+// servers in a role, returning the status code to respond with otherwise
+func (s *Server) linkableGameServer(ctx context.Context, namespace, name, cluster, role string) (*unstructured.Unstructured, int, error) {
+	if len(linkServiceName(name)) > 63 {
+		return nil, http.StatusBadRequest, fmt.Errorf("GameServer name %s is too long to link", name)
+	}
+	obj, err := s.getGameServerObject(ctx, namespace, name)
+	if apierrors.IsNotFound(err) {
+		return nil, http.StatusNotFound, fmt.Errorf("GameServer %s/%s not found", namespace, name)
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to get GameServer %s: %w", name, err)
+	}
+	if current := obj.GetLabels()[serverClusterLabel]; current != "" && current != cluster {
+		return nil, http.StatusConflict, fmt.Errorf("GameServer %s already belongs to cluster of servers %s", name, current)
+	}
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	game, ok := lookupGame(gameType)
+	if !ok || game.Linking == nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("game type %s does not support clusters of servers", gameType)
+	}
+	if !containsString(game.Linking.Roles, role) {
+		return nil, http.StatusBadRequest, fmt.Errorf("unsupported role %q for %s (valid: %s)", role, gameType, strings.Join(game.Linking.Roles, ", "))
+	}
+	return obj, http.StatusOK, nil
+}
+
+// setServerClusterMembership labels a GameServer as a member in a role
+func (s *Server) setServerClusterMembership(ctx context.Context, obj *unstructured.Unstructured, cluster, role string) error {
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if labels[serverClusterLabel] == cluster && annotations[serverClusterRoleAnnotation] == role {
+		return nil
+	}
+	labels[serverClusterLabel] = cluster
+	annotations[serverClusterRoleAnnotation] = role
+	obj.SetLabels(labels)
+	obj.SetAnnotations(annotations)
+	return s.k8sClient.Update(ctx, obj)
+}
+
+// unlinkGameServer removes a GameServer from its cluster of servers along
+// with its discovery Services and environment variables. Its former
+// siblings drop their Services pointing at it when relinked.
+func (s *Server) unlinkGameServer(ctx context.Context, obj *unstructured.Unstructured) error {
+	if namespace, err := managedNamespace(obj); err == nil {
+		if err := s.syncLinkServices(ctx, namespace, obj.GetLabels()[serverClusterLabel], nil); err != nil {
+			return err
+		}
+	}
+	if err := s.syncLinkEnv(ctx, obj, nil); err != nil {
+		return err
+	}
+	labels, annotations := obj.GetLabels(), obj.GetAnnotations()
+	delete(labels, serverClusterLabel)
+	delete(annotations, serverClusterRoleAnnotation)
+	obj.SetLabels(labels)
+	obj.SetAnnotations(annotations)
+	return s.k8sClient.Update(ctx, obj)
+}
+
+// serverClusterMembers lists GameServers in a cluster of servers, or in
+// any cluster of servers when name is empty, across all namespaces when
+// namespace is empty
+func (s *Server) serverClusterMembers(ctx context.Context, namespace, name string) ([]unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	opts := []client.ListOption{client.HasLabels{serverClusterLabel}}
+	if name != "" {
+		opts = []client.ListOption{client.MatchingLabels{serverClusterLabel: name}}
+	}
+	if namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	if err := s.k8sClient.List(ctx, list, opts...); err != nil {
+		return nil, fmt.Errorf("failed to list GameServers: %w", err)
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].GetName() < list.Items[j].GetName() })
+	return list.Items, nil
+}
+
+// linkTargets finds the game Service of every provisioned member
+func (s *Server) linkTargets(ctx context.Context, members []unstructured.Unstructured) map[string]linkTarget {
+	targets := map[string]linkTarget{}
+	for i := range members {
+		namespace, err := managedNamespace(&members[i])
+		if err != nil {
+			continue
+		}
+		services, err := s.kubeClient.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("kubelize.io/gameserver=%s,kubelize.io/service-type=game", namespace),
+		})
+		if err != nil || len(services.Items) == 0 {
+			continue
+		}
+		svc := services.Items[0]
+		target := linkTarget{host: fmt.Sprintf("%s.%s.svc.cluster.local", svc.Name, namespace)}
+		if len(svc.Spec.Ports) > 0 {
+			target.port = svc.Spec.Ports[0].Port
+		}
+		targets[members[i].GetName()] = target
+	}
+	return targets
+}
+
+// linkAllServerClusters relinks every cluster of servers
+func (s *Server) linkAllServerClusters(ctx context.Context) error {
+	members, err := s.serverClusterMembers(ctx, "", "")
+	if err != nil {
+		return err
+	}
+	linked := map[string]bool{}
+	for _, member := range members {
+		key := member.GetNamespace() + "/" + member.GetLabels()[serverClusterLabel]
+		if linked[key] {
+			continue
+		}
+		linked[key] = true
+		if _, err := s.linkServerCluster(ctx, member.GetNamespace(), member.GetLabels()[serverClusterLabel]); err != nil {
+			log.Printf("Failed to link cluster of servers %s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// linkServerCluster points every member's discovery Services and
+// environment variables at its current siblings
+func (s *Server) linkServerCluster(ctx context.Context, namespace, name string) (ServerCluster, error) {
+	members, err := s.serverClusterMembers(ctx, namespace, name)
+	if err != nil {
+		return ServerCluster{}, err
+	}
+	targets := s.linkTargets(ctx, members)
+	for i := range members {
+		member := &members[i]
+		siblings := map[string]linkTarget{}
+		for j := range members {
+			if target, ok := targets[members[j].GetName()]; ok && j != i {
+				siblings[members[j].GetName()] = target
+			}
+		}
+		if managed, err := managedNamespace(member); err == nil {
+			if err := s.syncLinkServices(ctx, managed, name, siblings); err != nil {
+				return ServerCluster{}, fmt.Errorf("failed to sync Services of %s: %w", member.GetName(), err)
+			}
+		}
+		if err := s.syncLinkEnv(ctx, member, linkEnv(name, member, members, targets)); err != nil {
+			return ServerCluster{}, fmt.Errorf("failed to update %s: %w", member.GetName(), err)
+		}
+	}
+	return serverClusterStatus(namespace, name, members, targets), nil
+}
+
+// syncLinkServices makes the ExternalName Services of a cluster of servers
+// in a member's managed namespace match its siblings
+func (s *Server) syncLinkServices(ctx context.Context, namespace, cluster string, siblings map[string]linkTarget) error {
+	services := s.kubeClient.CoreV1().Services(namespace)
+	existing, err := services.List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", serverClusterLabel, cluster),
+	})
+	if err != nil {
+		return err
+	}
+	current := map[string]*corev1.Service{}
+	for i := range existing.Items {
+		current[existing.Items[i].Name] = &existing.Items[i]
+	}
+
+	for sibling, target := range siblings {
+		desired := corev1.ServiceSpec{
+			Type:         corev1.ServiceTypeExternalName,
+			ExternalName: target.host,
+		}
+		if target.port > 0 {
+			desired.Ports = []corev1.ServicePort{{Name: "game", Port: target.port}}
+		}
+		name := linkServiceName(sibling)
+		svc, ok := current[name]
+		delete(current, name)
+		if !ok {
+			_, err = services.Create(ctx, &corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+					Labels: map[string]string{
+						serverClusterLabel:             cluster,
+						"app.kubernetes.io/managed-by": "gameplane-api",
+					},
+				},
+				Spec: desired,
+			}, metav1.CreateOptions{})
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if svc.Spec.ExternalName == desired.ExternalName && len(svc.Spec.Ports) == len(desired.Ports) && (len(desired.Ports) == 0 || svc.Spec.Ports[0].Port == desired.Ports[0].Port) {
+			continue
+		}
+		svc.Spec.ExternalName = desired.ExternalName
+		svc.Spec.Ports = desired.Ports
+		if _, err := services.Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+	for name := range current {
+		if err := services.Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkEnv builds the environment variables describing a cluster of servers
+// to one member: GAMEPLANE_SERVER_CLUSTER and GAMEPLANE_SERVER_ROLE, then
+// GAMEPLANE_PEERS listing name=role@host:port of every sibling and
+// GAMEPLANE_PEER_<NAME>_HOST and _PORT for each. Hosts are the stable
+// Service names, so only ports appearing after provisioning change them.
+func linkEnv(cluster string, member *unstructured.Unstructured, members []unstructured.Unstructured, targets map[string]linkTarget) map[string]string {
+	env := map[string]string{
+		envServerCluster: cluster,
+		envServerRole:    member.GetAnnotations()[serverClusterRoleAnnotation],
+	}
+	peers := []string{}
+	for i := range members {
+		sibling := members[i].GetName()
+		if sibling == member.GetName() {
+			continue
+		}
+		host := linkServiceName(sibling)
+		address := host
+		env[envPeerPrefix+peerEnvName(sibling)+"_HOST"] = host
+		if port := targets[sibling].port; port > 0 {
+			env[envPeerPrefix+peerEnvName(sibling)+"_PORT"] = strconv.Itoa(int(port))
+			address += ":" + strconv.Itoa(int(port))
+		}
+		peers = append(peers, fmt.Sprintf("%s=%s@%s", sibling, members[i].GetAnnotations()[serverClusterRoleAnnotation], address))
+	}
+	env[envPeers] = strings.Join(peers, ",")
+	return env
+}
+
+// isLinkEnv reports whether an environment variable is managed by linking
+func isLinkEnv(name string) bool {
+	return name == envServerCluster || name == envServerRole || name == envPeers || strings.HasPrefix(name, envPeerPrefix)
+}
+
+// syncLinkEnv replaces the linking environment variables in a GameServer's
+// custom environment, leaving the user's own variables alone
+func (s *Server) syncLinkEnv(ctx context.Context, obj *unstructured.Unstructured, env map[string]string) error {
+	return s.syncManagedEnv(ctx, obj, isLinkEnv, env)
+}
+
+// syncManagedEnv replaces the environment variables a feature manages in a
+// GameServer's custom environment, as told apart by managed, with env. It
+// only writes the spec when they changed, as that restarts the game.
+func (s *Server) syncManagedEnv(ctx context.Context, obj *unstructured.Unstructured, managed func(string) bool, env map[string]string) error {
+	current, _, err := unstructured.NestedStringMap(obj.Object, "spec", "advanced", "customEnvVars")
+	if err != nil {
+		return err
+	}
+	next := map[string]interface{}{}
+	changed := false
+	for key, value := range current {
+		if !managed(key) {
+			next[key] = value
+		} else if env[key] != value {
+			changed = true
+		}
+	}
+	for key, value := range env {
+		if _, ok := current[key]; !ok {
+			changed = true
+		}
+		next[key] = value
+	}
+	if !changed {
+		return nil
+	}
+
+	spec, _, err := unstructured.NestedMap(obj.Object, "spec")
+	if err != nil {
+		return err
+	}
+	spec = runtime.DeepCopyJSON(spec)
+	if len(next) == 0 {
+		unstructured.RemoveNestedField(spec, "advanced", "customEnvVars")
+	} else if err := unstructured.SetNestedMap(spec, next, "advanced", "customEnvVars"); err != nil {
+		return err
+	}
+	_, err = s.writeGameServerSpec(ctx, obj, spec)
+	return err
+}
+
+// serverClusterStatus describes a cluster of servers from its members
+func serverClusterStatus(namespace, name string, members []unstructured.Unstructured, targets map[string]linkTarget) ServerCluster {
+	cluster := ServerCluster{Namespace: namespace, Name: name, Members: []ServerClusterMember{}}
+	for i := range members {
+		obj := &members[i]
+		gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+		member := ServerClusterMember{
+			Name:     obj.GetName(),
+			Role:     obj.GetAnnotations()[serverClusterRoleAnnotation],
+			GameType: gameType,
+			Ready:    gameServerReady(obj),
+			Host:     linkServiceName(obj.GetName()),
+		}
+		if target, ok := targets[obj.GetName()]; ok {
+			member.Target, member.Port = target.host, target.port
+		}
+		cluster.Members = append(cluster.Members, member)
+	}
+	return cluster
+}
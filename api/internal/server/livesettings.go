@@ -0,0 +1,131 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// AppliedLiveSetting is a live setting the game's console took
+type AppliedLiveSetting struct {
+	Name   string      `json:"name"`
+	Value  interface{} `json:"value"`
+	Output string      `json:"output"`
+}
+
+// patchLiveSettings changes settings of the running game, such as the time
+// of day or its difficulty, through the console. The body maps setting
+// names from the game catalog to values; the changes are not stored in the
+// spec and last until the server restarts.
+func (s *Server) patchLiveSettings(c *gin.Context) {
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if len(req) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "No settings given",
+		})
+		return
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, _ := lookupGame(gameType)
+	if def.Console == nil || len(def.Console.LiveSettings) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Game type %s has no live settings", gameType),
+		})
+		return
+	}
+
+	names := make([]string, 0, len(req))
+	for name := range req {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var invalid []string
+	for _, name := range names {
+		setting, ok := def.Console.LiveSetting(name)
+		if !ok {
+			invalid = append(invalid, fmt.Sprintf("%s: not a live setting of %s", name, gameType))
+			continue
+		}
+		if err := validateConfigValue(setting.Field(), req[name]); err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid live settings",
+			"details": invalid,
+		})
+		return
+	}
+
+	applied, err := s.applyLiveSettings(c.Request.Context(), obj, def.Console, req)
+	if err != nil {
+		status := http.StatusBadGateway
+		if errors.Is(err, errConsoleUnsupported) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error":   fmt.Sprintf("Failed to apply live settings: %v", err),
+			"applied": applied,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Live settings applied",
+		"applied": applied,
+	})
+}
+
+// applyLiveSettings runs the console command of each setting, in catalog
+// order, over one console connection. It stops at the first failure and
+// returns the settings applied until then.
+func (s *Server) applyLiveSettings(ctx context.Context, obj *unstructured.Unstructured, console *ConsoleInfo, values map[string]interface{}) ([]AppliedLiveSetting, error) {
+	applied := []AppliedLiveSetting{}
+	session, err := s.openGameConsole(ctx, obj)
+	if err != nil {
+		return applied, err
+	}
+	defer session.Close()
+
+	for _, setting := range console.LiveSettings {
+		value, ok := values[setting.Name]
+		if !ok {
+			continue
+		}
+		output, err := session.Exec(fmt.Sprintf(setting.Command, liveSettingText(value)))
+		if err != nil {
+			return applied, fmt.Errorf("%s: %w", setting.Name, err)
+		}
+		applied = append(applied, AppliedLiveSetting{Name: setting.Name, Value: value, Output: output})
+	}
+	return applied, nil
+}
+
+// liveSettingText formats a validated value as a console command argument
+func liveSettingText(value interface{}) string {
+	if n, ok := toFloat(value); ok {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	if text, ok := value.(string); ok {
+		return consoleText(text)
+	}
+	return fmt.Sprint(value)
+}
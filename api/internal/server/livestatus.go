@@ -0,0 +1,73 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/status"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// GameServerStatusReport is a GameServer's status, as last written by the
+// status controller or polled on request
+type GameServerStatusReport struct {
+	Status GameServerStatus `json:"status"`
+	// Refreshed is set when the status was polled for this request
+	Refreshed bool          `json:"refreshed"`
+	Probe     *status.Probe `json:"probe,omitempty"`
+	// Up is set when a game pod is ready and the game answered its query,
+	// or has none to answer
+	Up        *bool     `json:"up,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// getGameServerStatus returns a GameServer's status. With ?refresh=true the
+// game pod, endpoint and game query are polled right away instead of
+// returning what the status controller last wrote.
+func (s *Server) getGameServerStatus(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	report := GameServerStatusReport{CheckedAt: time.Now().UTC()}
+
+	if c.Query("refresh") == "true" {
+		reconciler := &status.Reconciler{Client: s.k8sClient, Kube: s.kubeClient}
+		probe, err := reconciler.Refresh(c.Request.Context(), obj)
+		if apierrors.IsConflict(err) {
+			// The status controller wrote first; poll the fresh object
+			if obj, ok = s.loadGameServerForConfig(c); !ok {
+				return
+			}
+			probe, err = reconciler.Refresh(c.Request.Context(), obj)
+		}
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to refresh GameServer status: %v", err),
+			})
+			return
+		}
+		up := probe.Ready && (probe.Query == status.QueryAnswered || probe.Query == status.QueryUnsupported)
+		report.Refreshed = true
+		report.Probe = probe
+		report.Up = &up
+	}
+
+	// The whole status, including the endpoint the poll resolved
+	raw, _, _ := unstructured.NestedMap(obj.Object, "status")
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &report.Status); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to convert GameServer status: %v", err),
+		})
+		return
+	}
+	shaped, ok := selectFields(c, report)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, shaped)
+}
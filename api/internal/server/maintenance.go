@@ -0,0 +1,323 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// maintenanceConfigMap holds the maintenance state in the cluster
+	// registry namespace of the local cluster
+	maintenanceConfigMap = "gameplane-maintenance"
+
+	// maintenanceKey is the ConfigMap key of the MaintenanceState
+	maintenanceKey = "maintenance.json"
+
+	// maintenanceCacheTTL bounds how long other API replicas take to notice
+	// a maintenance change
+	maintenanceCacheTTL = 5 * time.Second
+
+	defaultMaintenanceMessage = "GamePlane is in maintenance, changes are disabled until it ends"
+)
+
+// maintenanceExempt are mutating routes that stay available during
+// maintenance, relative to the API version prefix: read-only POSTs, game
+// traffic, per-user settings and the bootstraps and rollouts maintenance is
+// held for
+var maintenanceExempt = map[string]bool{
+	"/admin/bootstrap":                           true,
+	"/admin/rollout":                             true,
+	"/admin/rollout/:id/resume":                  true,
+	"/admin/rollout/:id/abort":                   true,
+	"/maintenance":                               true,
+	"/gameservers/estimate":                      true,
+	"/gameservers/preview":                       true,
+	"/gameservers/:namespace/:name/diff":         true,
+	"/gameservers/:namespace/:name/chat/inbound": true,
+	"/auth/sessions":                             true,
+	"/auth/sessions/:id":                         true,
+	"/userprefs":                                 true,
+	"/userprefs/favorites/:namespace/:name":      true,
+	"/users/me/preferences":                      true,
+}
+
+// MaintenanceWindow blocks mutations while set
+type MaintenanceWindow struct {
+	Message string    `json:"message"`
+	Since   time.Time `json:"since"`
+	// Until ends the window automatically; open-ended when unset
+	Until *time.Time `json:"until,omitempty"`
+	By    string     `json:"by,omitempty"`
+}
+
+// active reports whether the window is in effect at now
+func (w *MaintenanceWindow) active(now time.Time) bool {
+	return w != nil && (w.Until == nil || now.Before(*w.Until))
+}
+
+// MaintenanceState is the installation-wide and per-namespace maintenance
+type MaintenanceState struct {
+	Global     *MaintenanceWindow           `json:"global,omitempty"`
+	Namespaces map[string]MaintenanceWindow `json:"namespaces"`
+}
+
+// window returns the active window covering namespace, global first
+func (m MaintenanceState) window(namespace string, now time.Time) *MaintenanceWindow {
+	if m.Global.active(now) {
+		return m.Global
+	}
+	if namespace == "" {
+		return nil
+	}
+	if window, ok := m.Namespaces[namespace]; ok && window.active(now) {
+		return &window
+	}
+	return nil
+}
+
+// prune drops windows that have ended
+func (m *MaintenanceState) prune(now time.Time) {
+	if !m.Global.active(now) {
+		m.Global = nil
+	}
+	for namespace, window := range m.Namespaces {
+		if !window.active(now) {
+			delete(m.Namespaces, namespace)
+		}
+	}
+}
+
+// maintenanceCache keeps the last read maintenance state for mutating
+// requests, which would otherwise each read the ConfigMap
+type maintenanceCache struct {
+	mu      sync.Mutex
+	state   MaintenanceState
+	fetched time.Time
+}
+
+// currentMaintenance returns the cached maintenance state, refreshing it
+// when stale
+func (s *Server) currentMaintenance(ctx context.Context) (MaintenanceState, error) {
+	s.maintenance.mu.Lock()
+	defer s.maintenance.mu.Unlock()
+	if time.Since(s.maintenance.fetched) < maintenanceCacheTTL {
+		return s.maintenance.state, nil
+	}
+	state, _, err := s.loadMaintenance(ctx)
+	if err != nil {
+		return MaintenanceState{}, err
+	}
+	s.maintenance.state = state
+	s.maintenance.fetched = time.Now()
+	return state, nil
+}
+
+// loadMaintenance reads the maintenance state and the ConfigMap holding it,
+// which is nil when maintenance was never enabled
+func (s *Server) loadMaintenance(ctx context.Context) (MaintenanceState, *corev1.ConfigMap, error) {
+	state := MaintenanceState{Namespaces: map[string]MaintenanceWindow{}}
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return state, nil, err
+	}
+	cm, err := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace).Get(ctx, maintenanceConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return state, nil, nil
+	}
+	if err != nil {
+		return state, nil, fmt.Errorf("failed to read maintenance state: %w", err)
+	}
+	if raw := cm.Data[maintenanceKey]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &state); err != nil {
+			return state, nil, fmt.Errorf("failed to parse maintenance state: %w", err)
+		}
+	}
+	if state.Namespaces == nil {
+		state.Namespaces = map[string]MaintenanceWindow{}
+	}
+	return state, cm, nil
+}
+
+// saveMaintenance writes the maintenance state, failing on a concurrent change
+func (s *Server) saveMaintenance(ctx context.Context, cm *corev1.ConfigMap, state MaintenanceState) error {
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	configMaps := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace)
+	if cm == nil {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      maintenanceConfigMap,
+				Namespace: s.clusters.namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "gameplane-api"},
+			},
+			Data: map[string]string{maintenanceKey: string(raw)},
+		}, metav1.CreateOptions{})
+	} else {
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[maintenanceKey] = string(raw)
+		_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return err
+	}
+
+	s.maintenance.mu.Lock()
+	s.maintenance.state = state
+	s.maintenance.fetched = time.Now()
+	s.maintenance.mu.Unlock()
+	return nil
+}
+
+// guardMaintenance rejects mutating requests with 503 while maintenance
+// covers the installation or the request's :namespace. prefix is the API
+// version group the middleware is installed on.
+func (s *Server) guardMaintenance(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if maintenanceExempt[strings.TrimPrefix(c.FullPath(), prefix)] {
+			c.Next()
+			return
+		}
+		if s.checkMaintenance(c, c.Param("namespace")) {
+			c.Next()
+			return
+		}
+		c.Abort()
+	}
+}
+
+// checkMaintenance responds with 503 and returns false if maintenance
+// covers namespace. Handlers that take the namespace from the request body
+// call it themselves.
+func (s *Server) checkMaintenance(c *gin.Context, namespace string) bool {
+	state, err := s.currentMaintenance(c.Request.Context())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to check maintenance mode: %v", err),
+		})
+		return false
+	}
+	now := time.Now()
+	window := state.window(namespace, now)
+	if window == nil {
+		return true
+	}
+	if window.Until != nil {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(window.Until.Sub(now).Seconds()))))
+	}
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"error":       window.Message,
+		"maintenance": window,
+	})
+	return false
+}
+
+// getMaintenance returns the active maintenance windows
+func (s *Server) getMaintenance(c *gin.Context) {
+	state, _, err := s.loadMaintenance(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	state.prune(time.Now())
+	c.JSON(http.StatusOK, state)
+}
+
+// putMaintenance turns maintenance on or off, for the installation or one
+// namespace
+func (s *Server) putMaintenance(c *gin.Context) {
+	var req struct {
+		Enabled bool `json:"enabled"`
+		// Namespace limits the change to one namespace; empty is global
+		Namespace string     `json:"namespace,omitempty"`
+		Message   string     `json:"message,omitempty"`
+		Until     *time.Time `json:"until,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	now := time.Now().UTC()
+	if req.Until != nil && !req.Until.After(now) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "until must be in the future",
+		})
+		return
+	}
+	if req.Namespace != "" {
+		if scope, err := s.readableNamespaces(c); err != nil || !scope.allows(req.Namespace) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": fmt.Sprintf("Not permitted to access namespace %s", req.Namespace),
+			})
+			return
+		}
+	}
+
+	state, cm, err := s.loadMaintenance(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	state.prune(now)
+
+	var window *MaintenanceWindow
+	if req.Enabled {
+		message := strings.TrimSpace(req.Message)
+		if message == "" {
+			message = defaultMaintenanceMessage
+		}
+		window = &MaintenanceWindow{
+			Message: message,
+			Since:   now,
+			Until:   req.Until,
+			By:      c.GetString(handlers.SubjectKey),
+		}
+	}
+	switch {
+	case req.Namespace == "":
+		state.Global = window
+	case window != nil:
+		state.Namespaces[req.Namespace] = *window
+	default:
+		delete(state.Namespaces, req.Namespace)
+	}
+
+	if err := s.saveMaintenance(context.TODO(), cm, state); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to save maintenance state: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, state)
+}
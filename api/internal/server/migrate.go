@@ -0,0 +1,441 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// migrationTimeout bounds a whole migration
+	migrationTimeout = 2 * time.Hour
+
+	// migrationProvisionTimeout bounds waiting for the destination to start
+	migrationProvisionTimeout = 30 * time.Minute
+
+	// migrationChunkSize is how much world data is sent per upload request.
+	// Chunks are buffered so each request has a Content-Length, which the
+	// receiving busybox httpd needs.
+	migrationChunkSize = 8 << 20
+
+	// migrationPort is where the transfer pods serve their CGI scripts
+	migrationPort = 8080
+
+	// migrationDir holds the incoming archive on the destination volume
+	migrationDir = ".gameplane/migrate"
+)
+
+// Migration steps, in order
+const (
+	migrationStepBackup    = "backup"
+	migrationStepCreate    = "create-destination"
+	migrationStepProvision = "wait-for-destination"
+	migrationStepRestore   = "restore-data"
+	migrationStepDNS       = "swap-dns"
+	migrationStepTeardown  = "teardown-source"
+)
+
+// MigrationRequest selects where a GameServer moves to
+type MigrationRequest struct {
+	Cluster   string `json:"cluster,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	// Name renames the server at the destination; defaults to the current name
+	Name string `json:"name,omitempty"`
+	// KeepSource leaves the original server running after the move
+	KeepSource bool `json:"keepSource,omitempty"`
+}
+
+// migrateGameServer moves a GameServer to another namespace or cluster as an
+// async operation and returns the operation to poll
+func (s *Server) migrateGameServer(c *gin.Context) {
+	var req MigrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+
+	obj, err := s.getGameServerObject(context.TODO(), c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+	if _, member := obj.GetLabels()[fleetLabel]; member {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Fleet members are managed by their Fleet and cannot be migrated",
+		})
+		return
+	}
+	if _, err := managedNamespace(obj); err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if req.Cluster == "" {
+		req.Cluster = s.cluster
+	}
+	if req.Namespace == "" {
+		req.Namespace = obj.GetNamespace()
+	}
+	if req.Name == "" {
+		req.Name = obj.GetName()
+	}
+	if req.Cluster == s.cluster && req.Namespace == obj.GetNamespace() && req.Name == obj.GetName() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Destination is the same as the source",
+		})
+		return
+	}
+	dst, err := s.clusters.clusterServer(req.Cluster)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if _, err := dst.getGameServerObject(context.TODO(), req.Namespace, req.Name); err == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("GameServer %s/%s already exists in cluster %s", req.Namespace, req.Name, req.Cluster),
+		})
+		return
+	}
+
+	steps := []string{migrationStepBackup, migrationStepCreate, migrationStepProvision, migrationStepRestore, migrationStepDNS}
+	if !req.KeepSource {
+		steps = append(steps, migrationStepTeardown)
+	}
+	op := s.startOperation("migrate", obj.GetNamespace(), obj.GetName(), steps, migrationTimeout, func(ctx context.Context, t *operationTracker) (interface{}, error) {
+		return s.migrate(ctx, t, obj, dst, req)
+	})
+	c.JSON(http.StatusAccepted, op)
+}
+
+// migrate runs the migration steps
+func (s *Server) migrate(ctx context.Context, t *operationTracker, src *unstructured.Unstructured, dst *Server, req MigrationRequest) (interface{}, error) {
+	result := gin.H{"cluster": req.Cluster, "namespace": req.Namespace, "name": req.Name}
+
+	// Save and keep a local copy so a failed move can be recovered
+	err := t.step(migrationStepBackup, func() (string, error) {
+		if _, err := s.broadcastInGame(ctx, src, "Server is moving to a new host; progress after this point may be lost"); err != nil && !errors.Is(err, errConsoleUnsupported) {
+			log.Printf("Migration announcement for %s/%s failed: %v", src.GetNamespace(), src.GetName(), err)
+		}
+		if _, err := s.saveWorldInGame(ctx, src); err != nil && !errors.Is(err, errConsoleUnsupported) {
+			log.Printf("Saving %s/%s before migration failed: %v", src.GetNamespace(), src.GetName(), err)
+		}
+		backup := fmt.Sprintf("%s/migrate-%s.tar.gz", wipeBackupDir, time.Now().UTC().Format("20060102T150405Z"))
+		_, err := s.runVolumeTask(ctx, src, volumeTask{
+			Name: "migrate-backup",
+			Script: fmt.Sprintf("cd %s\nmkdir -p %s\ntar czf %s --exclude ./.gameplane .\n",
+				gameDataMountPath, wipeBackupDir, backup),
+			Timeout: wipeTimeout,
+		})
+		if err != nil {
+			return "", err
+		}
+		result["backup"] = backup
+		s.notifySubscribers(ctx, notificationEvent{
+			Type:      "backup",
+			Namespace: src.GetNamespace(),
+			Server:    src.GetName(),
+			Title:     "Backup written",
+			Message:   fmt.Sprintf("Data backed up before migration to %s", backup),
+			Fields:    map[string]string{"Path": backup, "Reason": "migration"},
+		})
+		return fmt.Sprintf("Source backup written to %s", backup), nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	var created *unstructured.Unstructured
+	err = t.step(migrationStepCreate, func() (string, error) {
+		created = migratedClaim(src, req, dst.clusters.regionOf(req.Cluster))
+		if err := dst.k8sClient.Create(ctx, created); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Created %s/%s in cluster %s", req.Namespace, req.Name, req.Cluster), nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	err = t.step(migrationStepProvision, func() (string, error) {
+		latest, err := dst.waitForRunningGameServer(ctx, req.Namespace, req.Name)
+		if err != nil {
+			return "", err
+		}
+		created = latest
+		return "Destination is running", nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	err = t.step(migrationStepRestore, func() (string, error) {
+		sent, err := transferGameData(ctx, s, src, dst, created)
+		if err != nil {
+			return "", err
+		}
+		// Kill without a graceful shutdown so the fresh world is not saved
+		// over the restored one
+		pods, namespace, err := dst.findGameServerPods(ctx, created)
+		if err != nil {
+			return "", err
+		}
+		noGrace := int64(0)
+		if _, err := dst.deleteGameServerPods(ctx, namespace, pods, &noGrace); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Restored %d bytes of world data", sent), nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// Hostnames are part of the spec, so they already point at the copy once
+	// the source's ingress is gone; report the new player address
+	err = t.step(migrationStepDNS, func() (string, error) {
+		info, err := dst.gameServerConnectInfo(ctx, created)
+		if err != nil {
+			return "", err
+		}
+		result["connect"] = info
+		message := fmt.Sprintf("Players now connect to %s", info.Host)
+		if host, _, _ := unstructured.NestedString(created.Object, "spec", "networking", "ingressHost"); host != "" {
+			message += fmt.Sprintf("; %s moves with the server", host)
+		}
+		return message, nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if req.KeepSource {
+		return result, nil
+	}
+	err = t.step(migrationStepTeardown, func() (string, error) {
+		if err := s.k8sClient.Delete(ctx, src); err != nil && !apierrors.IsNotFound(err) {
+			return "", err
+		}
+		return fmt.Sprintf("Deleted %s/%s", src.GetNamespace(), src.GetName()), nil
+	})
+	return result, err
+}
+
+// migratedClaim copies a GameServer claim for its destination, dropping the
+// Crossplane bindings of the source
+func migratedClaim(src *unstructured.Unstructured, req MigrationRequest, region string) *unstructured.Unstructured {
+	spec, _, _ := unstructured.NestedMap(src.Object, "spec")
+	spec = runtime.DeepCopyJSON(spec)
+	for _, field := range crossplaneSpecFields {
+		delete(spec, field)
+	}
+	if region != "" {
+		spec["placement"] = map[string]interface{}{"region": region}
+	}
+
+	labels := map[string]interface{}{}
+	for k, v := range src.GetLabels() {
+		labels[k] = v
+	}
+	labels["app.kubernetes.io/instance"] = req.Name
+
+	// Carry over GamePlane settings such as chat relay and wipe policies
+	annotations := map[string]interface{}{}
+	for k, v := range src.GetAnnotations() {
+		if strings.HasPrefix(k, "gameplane.kubelize.io/") {
+			annotations[k] = v
+		}
+	}
+
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": src.GetAPIVersion(),
+			"kind":       src.GetKind(),
+			"metadata": map[string]interface{}{
+				"name":        req.Name,
+				"namespace":   req.Namespace,
+				"labels":      labels,
+				"annotations": annotations,
+			},
+			"spec": spec,
+		},
+	}
+}
+
+// waitForRunningGameServer polls until a GameServer has a running game pod
+func (s *Server) waitForRunningGameServer(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	ctx, cancel := context.WithTimeout(ctx, migrationProvisionTimeout)
+	defer cancel()
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		obj, err := s.getGameServerObject(ctx, namespace, name)
+		if err == nil {
+			if pods, _, err := s.findGameServerPods(ctx, obj); err == nil {
+				for _, pod := range pods {
+					if pod.Status.Phase == corev1.PodRunning {
+						return obj, nil
+					}
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("GameServer %s/%s did not start within %s", namespace, name, migrationProvisionTimeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// migrationServeScript serves the task files as CGI scripts on migrationPort
+var migrationServeScript = "mkdir -p /tmp/www/cgi-bin\nfor f in /tmp/task/*; do cp \"$f\" /tmp/www/cgi-bin/; done\nchmod +x /tmp/www/cgi-bin/*\n" +
+	fmt.Sprintf("exec httpd -f -p %d -h /tmp/www\n", migrationPort)
+
+// migrationDownloadScript streams the source data as a gzipped tar
+const migrationDownloadScript = `#!/bin/sh
+echo "Content-Type: application/gzip"
+echo ""
+cd ` + gameDataMountPath + ` && tar cz --exclude ./.gameplane .
+`
+
+// migrationUploadScript appends one chunk to the incoming archive
+const migrationUploadScript = `#!/bin/sh
+mkdir -p ` + gameDataMountPath + `/` + migrationDir + `
+head -c "$CONTENT_LENGTH" >> ` + gameDataMountPath + `/` + migrationDir + `/incoming.tar.gz
+echo "Content-Type: text/plain"
+echo ""
+echo ok
+`
+
+// migrationExtractScript replaces the data with the incoming archive,
+// keeping the destination's own .gameplane directory
+const migrationExtractScript = `#!/bin/sh
+echo "Content-Type: text/plain"
+echo ""
+cd ` + gameDataMountPath + ` || exit 0
+if out=$(find . -mindepth 1 -maxdepth 1 ! -name .gameplane -exec rm -rf {} + 2>&1 && tar xzf ` + migrationDir + `/incoming.tar.gz 2>&1); then
+  rm -f ` + migrationDir + `/incoming.tar.gz
+  echo ok
+else
+  echo "error: $out"
+fi
+`
+
+// transferGameData copies the source volume to the destination volume
+// through two short-lived transfer pods, relayed by the API over the pods'
+// API server proxy so it works across clusters. It returns the bytes sent.
+func transferGameData(ctx context.Context, src *Server, srcObj *unstructured.Unstructured, dst *Server, dstObj *unstructured.Unstructured) (int64, error) {
+	srcPod, stopSrc, err := src.startVolumeServer(ctx, srcObj, volumeTask{
+		Name:     "migrate-out",
+		Files:    map[string][]byte{"download": []byte(migrationDownloadScript)},
+		Script:   migrationServeScript,
+		ReadOnly: true,
+		Port:     migrationPort,
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer stopSrc()
+	dstPod, stopDst, err := dst.startVolumeServer(ctx, dstObj, volumeTask{
+		Name: "migrate-in",
+		Files: map[string][]byte{
+			"upload":  []byte(migrationUploadScript),
+			"extract": []byte(migrationExtractScript),
+		},
+		Script: migrationServeScript,
+		Port:   migrationPort,
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer stopDst()
+
+	srcNamespace, _ := managedNamespace(srcObj)
+	dstNamespace, _ := managedNamespace(dstObj)
+	stream, err := migrationProxy(src, http.MethodGet, srcNamespace, srcPod, "download").Stream(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read source data: %w", err)
+	}
+	defer stream.Close()
+
+	sent, err := migrationUpload(ctx, dst, dstNamespace, dstPod, stream)
+	if err != nil {
+		return sent, err
+	}
+	if err := migrationPost(ctx, dst, dstNamespace, dstPod, "extract", nil); err != nil {
+		return sent, err
+	}
+	return sent, nil
+}
+
+// migrationUpload sends an archive to the upload script of a transfer pod
+// in chunks and returns the bytes sent
+func migrationUpload(ctx context.Context, s *Server, namespace, pod string, archive io.Reader) (int64, error) {
+	var sent int64
+	buf := make([]byte, migrationChunkSize)
+	for {
+		n, readErr := io.ReadFull(archive, buf)
+		if n > 0 {
+			if err := migrationPost(ctx, s, namespace, pod, "upload", buf[:n]); err != nil {
+				return sent, err
+			}
+			sent += int64(n)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			return sent, nil
+		}
+		if readErr != nil {
+			return sent, fmt.Errorf("failed to read source data: %w", readErr)
+		}
+	}
+}
+
+// migrationProxy builds a request to a CGI script of a transfer pod through
+// the API server's pod proxy
+func migrationProxy(s *Server, verb, namespace, pod, script string) *rest.Request {
+	return s.kubeClient.CoreV1().RESTClient().Verb(verb).
+		Namespace(namespace).Resource("pods").
+		Name(fmt.Sprintf("%s:%d", pod, migrationPort)).
+		SubResource("proxy").Suffix("cgi-bin", script)
+}
+
+// migrationPost sends a body to a transfer pod script and checks it
+// answered ok. A byte slice body gives the request a Content-Length.
+func migrationPost(ctx context.Context, s *Server, namespace, pod, script string, body []byte) error {
+	if body == nil {
+		body = []byte{}
+	}
+	out, err := migrationProxy(s, http.MethodPost, namespace, pod, script).Body(body).DoRaw(ctx)
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", script, err)
+	}
+	if reply := strings.TrimSpace(string(out)); reply != "ok" {
+		return fmt.Errorf("%s failed: %s", script, reply)
+	}
+	return nil
+}
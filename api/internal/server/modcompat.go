@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Mod compatibility verdicts
+const (
+	modCompatible   = "compatible"
+	modIncompatible = "incompatible"
+	modUnknown      = "unknown"
+)
+
+// ModCompatibility is the check of a GameServer's mods, the shared assets
+// it mounts, against the game version an upgrade moves to
+type ModCompatibility struct {
+	FromVersion string     `json:"fromVersion,omitempty"`
+	ToVersion   string     `json:"toVersion,omitempty"`
+	Mods        []ModCheck `json:"mods"`
+	// Blocked is set when an incompatible mod stops the upgrade, for Reason
+	Blocked  bool     `json:"blocked"`
+	Reason   string   `json:"reason,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// ModCheck is the verdict for one mounted asset
+type ModCheck struct {
+	Asset        string   `json:"asset"`
+	MountPath    string   `json:"mountPath"`
+	Status       string   `json:"status"` // compatible, incompatible or unknown
+	GameVersions []string `json:"gameVersions,omitempty"`
+}
+
+// validateGameVersions checks compatible game versions of an asset. Each is
+// a version such as 0.3.0 or a pattern such as 0.3.*.
+func validateGameVersions(versions []string) error {
+	for _, version := range versions {
+		if version == "" || strings.ContainsAny(version, ", ") {
+			return fmt.Errorf("invalid game version %q", version)
+		}
+		if _, err := path.Match(version, ""); err != nil {
+			return fmt.Errorf("invalid game version pattern %q", version)
+		}
+	}
+	return nil
+}
+
+// imageVersion returns the game version of a game image: its tag without
+// the game type suffix, so kubelize/game-servers:0.3.0-sdtd is 0.3.0
+func imageVersion(image, gameType string) string {
+	name := image[strings.LastIndex(image, "/")+1:]
+	name, _, _ = strings.Cut(name, "@")
+	_, tag, found := strings.Cut(name, ":")
+	if !found {
+		return ""
+	}
+	return strings.TrimSuffix(tag, "-"+gameType)
+}
+
+// gameServerImage returns the image a GameServer runs: its override or the
+// game's default
+func gameServerImage(obj *unstructured.Unstructured) string {
+	if image, _, _ := unstructured.NestedString(obj.Object, "spec", "advanced", "image"); image != "" {
+		return image
+	}
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, _ := lookupGame(gameType)
+	return def.Image
+}
+
+// checkModCompatibility checks every shared asset a GameServer mounts
+// against the game version of an upgrade target. Only image upgrades change
+// the game version; nil means there is nothing to check. The upgrade is
+// blocked by an incompatible mod unless ignore is set; mods without
+// version metadata only warn.
+func (s *Server) checkModCompatibility(ctx context.Context, obj *unstructured.Unstructured, target RolloutTarget, ignore bool) (*ModCompatibility, error) {
+	volumes := gameServerSharedVolumes(obj)
+	if target.Image == "" || len(volumes) == 0 {
+		return nil, nil
+	}
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	report := &ModCompatibility{
+		FromVersion: imageVersion(gameServerImage(obj), gameType),
+		ToVersion:   imageVersion(target.Image, gameType),
+		Mods:        []ModCheck{},
+	}
+	if report.ToVersion == "" {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("Image %s has no version tag, so mod compatibility cannot be checked", target.Image))
+	}
+
+	incompatible := []string{}
+	for _, volume := range volumes {
+		check := ModCheck{Asset: volume.Name, MountPath: volume.MountPath, Status: modUnknown}
+		claim, err := s.kubeClient.CoreV1().PersistentVolumeClaims(s.clusters.namespace).Get(ctx, sharedAssetClaimPrefix+volume.Name, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get shared asset %s: %w", volume.Name, err)
+		}
+		if err == nil {
+			check.GameVersions = splitList(claim.Annotations[sharedAssetGameVersionsAnnotation])
+		}
+		switch {
+		case report.ToVersion == "":
+		case len(check.GameVersions) == 0:
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s has no known compatible game versions", volume.Name))
+		case gameVersionMatches(check.GameVersions, report.ToVersion):
+			check.Status = modCompatible
+		default:
+			check.Status = modIncompatible
+			incompatible = append(incompatible, volume.Name)
+		}
+		report.Mods = append(report.Mods, check)
+	}
+
+	if len(incompatible) > 0 {
+		message := fmt.Sprintf("%s %s not known to work with game version %s", strings.Join(incompatible, ", "), pluralVerb(len(incompatible)), report.ToVersion)
+		if ignore {
+			report.Warnings = append(report.Warnings, message+"; upgrading anyway")
+		} else {
+			report.Blocked = true
+			report.Reason = message
+		}
+	}
+	return report, nil
+}
+
+// gameVersionMatches reports whether a version matches any of the patterns
+func gameVersionMatches(patterns []string, version string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, version); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// pluralVerb is "is" for one subject and "are" for several
+func pluralVerb(n int) string {
+	if n == 1 {
+		return "is"
+	}
+	return "are"
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"log"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// namespaceEnabledLabel opts a namespace in to GameServers; once any
+	// namespace of a cluster carries it, only labelled ones are served
+	namespaceEnabledLabel = "gameplane.kubelize.io/enabled"
+
+	// namespaceFilterTTL is how long the namespace labels of a cluster are
+	// served from memory
+	namespaceFilterTTL = 30 * time.Second
+)
+
+// systemNamespaces are never served, whatever the allow patterns say
+var systemNamespaces = []string{"kube-*", "crossplane-system"}
+
+// namespaceFilter hides namespaces GameServers do not belong in: system
+// namespaces, the registry namespace, the game namespaces GameServers
+// are provisioned into, those matching deny patterns and, when allow
+// patterns are set or namespaces are labelled, those neither match
+type namespaceFilter struct {
+	allow []string
+	deny  []string
+
+	mu       sync.Mutex
+	labelled map[string]bool
+	managed  map[string]bool
+	fetched  time.Time
+}
+
+// newNamespaceFilter builds a filter from the patterns, falling back to
+// NAMESPACE_ALLOW and NAMESPACE_DENY. Patterns are shell globs.
+func newNamespaceFilter(allow, deny []string, registry string) *namespaceFilter {
+	if len(allow) == 0 {
+		allow = splitList(os.Getenv("NAMESPACE_ALLOW"))
+	}
+	if len(deny) == 0 {
+		deny = splitList(os.Getenv("NAMESPACE_DENY"))
+	}
+	for _, pattern := range append(append([]string{}, allow...), deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			log.Printf("Ignoring malformed namespace pattern %q", pattern)
+		}
+	}
+	return &namespaceFilter{
+		allow: allow,
+		deny:  append(append(append([]string{}, systemNamespaces...), registry), deny...),
+	}
+}
+
+// forCluster returns a filter with the same patterns for another cluster,
+// whose namespace labels are read separately
+func (f *namespaceFilter) forCluster() *namespaceFilter {
+	return &namespaceFilter{allow: f.allow, deny: f.deny}
+}
+
+// matchNamespace reports whether namespace matches any of the patterns
+func matchNamespace(patterns []string, namespace string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, namespace); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// servedNamespaces returns whether the cluster's namespaces may hold
+// GameServers, reading their labels when stale. Should that fail, the
+// labels last read are used, so patterns apply regardless.
+func (s *Server) servedNamespaces(ctx context.Context) func(string) bool {
+	f := s.namespaces
+	f.mu.Lock()
+	if time.Since(f.fetched) >= namespaceFilterTTL {
+		namespaces, err := s.kubeClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.Printf("Failed to read namespace labels: %v", err)
+		} else {
+			f.labelled, f.managed = map[string]bool{}, map[string]bool{}
+			for _, ns := range namespaces.Items {
+				if ns.Labels[namespaceEnabledLabel] == "true" {
+					f.labelled[ns.Name] = true
+				}
+				if ns.Labels[managedNamespaceLabel] != "" {
+					f.managed[ns.Name] = true
+				}
+			}
+		}
+		f.fetched = time.Now()
+	}
+	labelled, managed := f.labelled, f.managed
+	f.mu.Unlock()
+
+	return func(namespace string) bool {
+		if managed[namespace] || matchNamespace(f.deny, namespace) {
+			return false
+		}
+		if len(f.allow) == 0 && len(labelled) == 0 {
+			return true
+		}
+		return labelled[namespace] || matchNamespace(f.allow, namespace)
+	}
+}
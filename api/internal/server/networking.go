@@ -0,0 +1,262 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// LoadBalancer support states
+	loadBalancerAvailable   = "available"
+	loadBalancerUnavailable = "unavailable"
+	loadBalancerUnknown     = "unknown"
+
+	// loadBalancerPendingAfter is how long LoadBalancer Services may wait
+	// for an address before the cluster is taken not to provide them
+	loadBalancerPendingAfter = 5 * time.Minute
+
+	// defaultIngressClassAnnotation marks the cluster's default IngressClass
+	defaultIngressClassAnnotation = "ingressclass.kubernetes.io/is-default-class"
+)
+
+// cloudProviders maps node provider ID schemes to cloud providers whose
+// controllers provision load balancers
+var cloudProviders = map[string]string{
+	"aws":          "aws",
+	"gce":          "gcp",
+	"azure":        "azure",
+	"digitalocean": "digitalocean",
+	"hcloud":       "hetzner",
+	"linode":       "linode",
+	"openstack":    "openstack",
+	"oci":          "oracle",
+	"vultr":        "vultr",
+	"exoscale":     "exoscale",
+	"scaleway":     "scaleway",
+}
+
+// ClusterNetworking reports how GameServers can be exposed on a cluster
+type ClusterNetworking struct {
+	LoadBalancer   LoadBalancerSupport `json:"loadBalancer"`
+	IngressClasses []IngressClassInfo  `json:"ingressClasses"`
+	GatewayAPI     GatewayAPISupport   `json:"gatewayAPI"`
+}
+
+// LoadBalancerSupport tells whether LoadBalancer Services get addresses
+type LoadBalancerSupport struct {
+	// Status is available, unavailable or unknown
+	Status string `json:"status"`
+	// Provider is what assigns addresses, e.g. metallb or a cloud
+	Provider string `json:"provider,omitempty"`
+	Reason   string `json:"reason"`
+}
+
+// IngressClassInfo is an IngressClass web admin ingresses can use
+type IngressClassInfo struct {
+	Name       string `json:"name"`
+	Controller string `json:"controller"`
+	Default    bool   `json:"default"`
+}
+
+// GatewayAPISupport tells whether the Gateway API is installed
+type GatewayAPISupport struct {
+	Supported      bool               `json:"supported"`
+	Version        string             `json:"version,omitempty"`
+	GatewayClasses []GatewayClassInfo `json:"gatewayClasses"`
+}
+
+// GatewayClassInfo is an installed GatewayClass
+type GatewayClassInfo struct {
+	Name       string `json:"name"`
+	Controller string `json:"controller"`
+}
+
+// getClusterNetworking reports which exposure modes the cluster supports
+func (s *Server) getClusterNetworking(c *gin.Context) {
+	networking, err := s.readClusterNetworking(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to read cluster networking: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, networking)
+}
+
+// readClusterNetworking detects LoadBalancer support, IngressClasses and
+// the Gateway API
+func (s *Server) readClusterNetworking(ctx context.Context) (*ClusterNetworking, error) {
+	loadBalancer, err := s.detectLoadBalancer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ingressClasses, err := s.listIngressClasses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	networking := &ClusterNetworking{
+		LoadBalancer:   loadBalancer,
+		IngressClasses: ingressClasses,
+		GatewayAPI:     GatewayAPISupport{GatewayClasses: []GatewayClassInfo{}},
+	}
+	for _, version := range []string{"v1", "v1beta1"} {
+		if _, err := s.kubeClient.Discovery().ServerResourcesForGroupVersion("gateway.networking.k8s.io/" + version); err != nil {
+			continue
+		}
+		networking.GatewayAPI.Supported = true
+		networking.GatewayAPI.Version = version
+		classes, err := s.listPlatform(ctx, schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: version, Kind: "GatewayClassList"})
+		if err != nil {
+			return nil, err
+		}
+		for _, class := range classes {
+			controller, _, _ := unstructured.NestedString(class.Object, "spec", "controllerName")
+			networking.GatewayAPI.GatewayClasses = append(networking.GatewayAPI.GatewayClasses, GatewayClassInfo{Name: class.GetName(), Controller: controller})
+		}
+		break
+	}
+	return networking, nil
+}
+
+// detectLoadBalancer works out whether LoadBalancer Services get
+// addresses. Services that got one prove it; otherwise MetalLB, k3s'
+// ServiceLB or nodes of a cloud provider suggest so, and Services that
+// waited for longer than loadBalancerPendingAfter prove the opposite.
+func (s *Server) detectLoadBalancer(ctx context.Context) (LoadBalancerSupport, error) {
+	services, err := s.kubeClient.CoreV1().Services("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return LoadBalancerSupport{}, err
+	}
+	pending := 0
+	for _, service := range services.Items {
+		if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+			continue
+		}
+		if len(service.Status.LoadBalancer.Ingress) > 0 {
+			return LoadBalancerSupport{
+				Status:   loadBalancerAvailable,
+				Provider: s.loadBalancerProvider(ctx),
+				Reason:   fmt.Sprintf("Service %s/%s has a load balancer address", service.Namespace, service.Name),
+			}, nil
+		}
+		if time.Since(service.CreationTimestamp.Time) > loadBalancerPendingAfter {
+			pending++
+		}
+	}
+
+	provider := s.loadBalancerProvider(ctx)
+	switch {
+	case pending > 0:
+		return LoadBalancerSupport{
+			Status:   loadBalancerUnavailable,
+			Provider: provider,
+			Reason:   fmt.Sprintf("%d LoadBalancer Services have waited for an address for more than %s and none has one", pending, loadBalancerPendingAfter),
+		}, nil
+	case provider != "":
+		return LoadBalancerSupport{
+			Status:   loadBalancerAvailable,
+			Provider: provider,
+			Reason:   fmt.Sprintf("%s provides load balancers", provider),
+		}, nil
+	}
+	return LoadBalancerSupport{
+		Status: loadBalancerUnknown,
+		Reason: "No load balancer provider was detected and no LoadBalancer Service exists yet",
+	}, nil
+}
+
+// loadBalancerProvider names what provisions load balancers: MetalLB,
+// k3s' ServiceLB or the cloud the nodes run in; empty when none is found
+func (s *Server) loadBalancerProvider(ctx context.Context) string {
+	if _, err := s.kubeClient.Discovery().ServerResourcesForGroupVersion("metallb.io/v1beta1"); err == nil {
+		return "metallb"
+	}
+	// ServiceLB runs pods per LoadBalancer Service
+	pods, err := s.kubeClient.CoreV1().Pods("kube-system").List(ctx, metav1.ListOptions{LabelSelector: "svccontroller.k3s.cattle.io/svcname"})
+	if err == nil && len(pods.Items) > 0 {
+		return "k3s-servicelb"
+	}
+	nodes, err := s.kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{Limit: 1})
+	if err == nil && len(nodes.Items) > 0 {
+		scheme, _, found := strings.Cut(nodes.Items[0].Spec.ProviderID, "://")
+		if provider, ok := cloudProviders[scheme]; found && ok {
+			return provider
+		}
+	}
+	return ""
+}
+
+// listIngressClasses lists the cluster's IngressClasses, sorted by name
+func (s *Server) listIngressClasses(ctx context.Context) ([]IngressClassInfo, error) {
+	classes, err := s.kubeClient.NetworkingV1().IngressClasses().List(ctx, metav1.ListOptions{})
+	if apierrors.IsNotFound(err) {
+		return []IngressClassInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	infos := []IngressClassInfo{}
+	for _, class := range classes.Items {
+		infos = append(infos, IngressClassInfo{
+			Name:       class.Name,
+			Controller: class.Spec.Controller,
+			Default:    class.Annotations[defaultIngressClassAnnotation] == "true",
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+// effectiveServiceType resolves an unset service type to the default
+func effectiveServiceType(serviceType string) string {
+	if serviceType == "" {
+		return string(corev1.ServiceTypeLoadBalancer)
+	}
+	return serviceType
+}
+
+// errInvalidNetworking marks exposure modes the cluster cannot fulfil
+var errInvalidNetworking = errors.New("invalid spec.networking")
+
+// checkNetworking verifies the cluster can expose a GameServer as
+// requested. LoadBalancer, the default service type, is only refused once
+// the cluster is known not to provide load balancers.
+func (s *Server) checkNetworking(ctx context.Context, networking GameServerNetworking) error {
+	if effectiveServiceType(networking.ServiceType) == string(corev1.ServiceTypeLoadBalancer) {
+		support, err := s.detectLoadBalancer(ctx)
+		if apierrors.IsForbidden(err) {
+			support, err = LoadBalancerSupport{Status: loadBalancerUnknown}, nil
+		}
+		if err != nil {
+			return err
+		}
+		if support.Status == loadBalancerUnavailable {
+			return fmt.Errorf("%w: the cluster does not provide LoadBalancer services (%s); set serviceType to NodePort, or install a load balancer such as MetalLB", errInvalidNetworking, support.Reason)
+		}
+	}
+	if networking.EnableIngress {
+		classes, err := s.listIngressClasses(ctx)
+		if apierrors.IsForbidden(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(classes) == 0 {
+			return fmt.Errorf("%w: enableIngress needs an ingress controller, but the cluster has no IngressClass", errInvalidNetworking)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,422 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+	"github.com/kubelize/gameplane/api/internal/metrics"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// subscriptionsConfigMap holds notification subscriptions in the cluster
+	// registry namespace of the local cluster
+	subscriptionsConfigMap = "gameplane-notification-subscriptions"
+
+	// subscriptionsKey is the ConfigMap key of the subscription list
+	subscriptionsKey = "subscriptions.json"
+)
+
+// emailEventTypes are the events that can be subscribed to by email
+var emailEventTypes = []string{"ready", "crash", "backup", "backup-failed", "alert", "alert-resolved", "remediation", "update-available", "update", "expiring", "expired"}
+
+// emailTemplates render the subject and body of each event type. Events
+// without their own template use the "default" entry.
+var emailTemplates = map[string][2]*template.Template{
+	"ready": emailTemplate("ready",
+		"[gameplane] {{.Server}} is ready",
+		"Your server {{.Server}} in {{.Namespace}} is ready to join.\n{{range $k, $v := .Fields}}\n{{$k}}: {{$v}}{{end}}\n"),
+	"crash": emailTemplate("crash",
+		"[gameplane] {{.Server}} went down",
+		"{{.Server}} in {{.Namespace}} stopped being ready at {{.Time.Format \"2006-01-02 15:04 MST\"}}.\n\n{{.Message}}\n"),
+	"backup": emailTemplate("backup",
+		"[gameplane] Backup of {{.Server}} written",
+		"A backup of {{.Server}} in {{.Namespace}} was written.\n{{range $k, $v := .Fields}}\n{{$k}}: {{$v}}{{end}}\n"),
+	"backup-failed": emailTemplate("backup-failed",
+		"[gameplane] Backup of {{.Server}} failed verification",
+		"{{.Message}}\n\nThe backup chain of {{.Server}} in {{.Namespace}} is broken until a newer backup passes verification.\n{{range $k, $v := .Fields}}\n{{$k}}: {{$v}}{{end}}\n"),
+	"expiring": emailTemplate("expiring",
+		"[gameplane] {{.Server}} is expiring",
+		"{{.Message}}.\n\nExtend it to keep it running.\n{{range $k, $v := .Fields}}\n{{$k}}: {{$v}}{{end}}\n\nServer: {{.Namespace}}/{{.Server}}\n"),
+	"default": emailTemplate("default",
+		"[gameplane] {{.Title}}",
+		"{{.Message}}\n{{range $k, $v := .Fields}}\n{{$k}}: {{$v}}{{end}}\n\nServer: {{.Namespace}}/{{.Server}}\n"),
+}
+
+// NotificationSubscription subscribes an email address to events
+type NotificationSubscription struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	// Subject created the subscription and may list and delete it
+	Subject string `json:"subject,omitempty"`
+	// Events lists event types; empty means all
+	Events []string `json:"events,omitempty"`
+	// Namespace and Server restrict the subscription; empty means all
+	Namespace string    `json:"namespace,omitempty"`
+	Server    string    `json:"server,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// smtpSettings configures outgoing email. It is read from SMTP_HOST,
+// SMTP_PORT, SMTP_USERNAME, SMTP_PASSWORD and SMTP_FROM; email is disabled
+// without SMTP_HOST.
+type smtpSettings struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+// emailTemplate parses a subject and body template pair
+func emailTemplate(name, subject, body string) [2]*template.Template {
+	return [2]*template.Template{
+		template.Must(template.New(name + "-subject").Parse(subject)),
+		template.Must(template.New(name + "-body").Parse(body)),
+	}
+}
+
+// loadSMTPSettings reads SMTP settings from the environment
+func loadSMTPSettings() (smtpSettings, bool) {
+	settings := smtpSettings{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     os.Getenv("SMTP_PORT"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	}
+	if settings.Port == "" {
+		settings.Port = "587"
+	}
+	if settings.From == "" {
+		settings.From = "gameplane@" + settings.Host
+	}
+	return settings, settings.Host != ""
+}
+
+// matches reports whether a subscription wants an event
+func (sub NotificationSubscription) matches(event notificationEvent) bool {
+	if sub.Namespace != "" && sub.Namespace != event.Namespace {
+		return false
+	}
+	if sub.Server != "" && sub.Server != event.Server {
+		return false
+	}
+	if len(sub.Events) == 0 {
+		return true
+	}
+	for _, kind := range sub.Events {
+		if kind == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// emailSubscribers mails an event to every matching subscriber and returns
+// how many messages were sent. It does nothing when SMTP is not configured.
+func (s *Server) emailSubscribers(ctx context.Context, event notificationEvent) (int, error) {
+	settings, ok := loadSMTPSettings()
+	if !ok {
+		return 0, nil
+	}
+	subscriptions, _, err := s.loadSubscriptions(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	sent := 0
+	var lastErr error
+	for _, sub := range subscriptions {
+		if !sub.matches(event) {
+			continue
+		}
+		if err := sendEmail(settings, sub.Email, event); err != nil {
+			lastErr = err
+			log.Printf("Failed to email %s event to %s: %v", event.Type, sub.Email, err)
+			continue
+		}
+		sent++
+	}
+	if sent == 0 && lastErr != nil {
+		return 0, lastErr
+	}
+	return sent, nil
+}
+
+// notifySubscribers emails an event and sends it to the projects containing
+// the server and to subscribed users, logging failures. It is used by
+// background workers that have no caller to report to.
+func (s *Server) notifySubscribers(ctx context.Context, event notificationEvent) {
+	if _, err := s.emailSubscribers(ctx, event); err != nil {
+		log.Printf("Failed to notify subscribers of %s event for %s/%s: %v", event.Type, event.Namespace, event.Server, err)
+	}
+	s.notifyProjects(ctx, event)
+	s.notifyUsers(ctx, event)
+	s.recordEventActivity(event)
+}
+
+// sendEmail renders an event with its template and sends it over SMTP
+func sendEmail(settings smtpSettings, to string, event notificationEvent) error {
+	templates, ok := emailTemplates[event.Type]
+	if !ok {
+		templates = emailTemplates["default"]
+	}
+	var subject, body bytes.Buffer
+	if err := templates[0].Execute(&subject, event); err != nil {
+		return err
+	}
+	if err := templates[1].Execute(&body, event); err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", settings.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.ReplaceAll(subject.String(), "\n", " "))
+	fmt.Fprintf(&msg, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body.String(), "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if settings.Username != "" {
+		auth = smtp.PlainAuth("", settings.Username, settings.Password, settings.Host)
+	}
+	start := time.Now()
+	err := smtp.SendMail(net.JoinHostPort(settings.Host, settings.Port), auth, settings.From, []string{to}, msg.Bytes())
+	metrics.ObserveDependency(metrics.DependencySMTP, "send", start, err != nil)
+	return err
+}
+
+// subscriptionStore returns the Server whose cluster stores subscriptions
+func (s *Server) subscriptionStore() (*Server, error) {
+	return s.clusters.clusterServer("")
+}
+
+// loadSubscriptions reads all subscriptions and the ConfigMap holding them,
+// which is nil when none were stored yet
+func (s *Server) loadSubscriptions(ctx context.Context) ([]NotificationSubscription, *corev1.ConfigMap, error) {
+	store, err := s.subscriptionStore()
+	if err != nil {
+		return nil, nil, err
+	}
+	cm, err := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace).Get(ctx, subscriptionsConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return []NotificationSubscription{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read subscriptions: %w", err)
+	}
+	subscriptions := []NotificationSubscription{}
+	if raw := cm.Data[subscriptionsKey]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &subscriptions); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse subscriptions: %w", err)
+		}
+	}
+	return subscriptions, cm, nil
+}
+
+// saveSubscriptions writes the subscription list back, failing on a
+// concurrent change
+func (s *Server) saveSubscriptions(ctx context.Context, cm *corev1.ConfigMap, subscriptions []NotificationSubscription) error {
+	store, err := s.subscriptionStore()
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(subscriptions)
+	if err != nil {
+		return err
+	}
+	configMaps := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace)
+	if cm == nil {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      subscriptionsConfigMap,
+				Namespace: s.clusters.namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "gameplane-api"},
+			},
+			Data: map[string]string{subscriptionsKey: string(raw)},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[subscriptionsKey] = string(raw)
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// listSubscriptions returns the caller's subscriptions, or everyone's for
+// admins, optionally only those of ?email=
+func (s *Server) listSubscriptions(c *gin.Context) {
+	subscriptions, _, err := s.loadSubscriptions(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	email := strings.ToLower(c.Query("email"))
+	admin := s.isAdmin(c)
+	subject := c.GetString(handlers.SubjectKey)
+	items := []NotificationSubscription{}
+	for _, sub := range subscriptions {
+		if !admin && sub.Subject != subject {
+			continue
+		}
+		if email == "" || strings.ToLower(sub.Email) == email {
+			items = append(items, sub)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+
+	_, enabled := loadSMTPSettings()
+	c.JSON(http.StatusOK, gin.H{
+		"items":        items,
+		"total":        len(items),
+		"emailEnabled": enabled,
+		"eventTypes":   emailEventTypes,
+	})
+}
+
+// createSubscription subscribes an email address to events. Subscriptions
+// may only cover namespaces the caller can read.
+func (s *Server) createSubscription(c *gin.Context) {
+	var req NotificationSubscription
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	address, err := mail.ParseAddress(req.Email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid email address %q", req.Email),
+		})
+		return
+	}
+	for _, kind := range req.Events {
+		if !containsString(emailEventTypes, kind) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Unsupported event type %q (valid: %s)", kind, strings.Join(emailEventTypes, ", ")),
+			})
+			return
+		}
+	}
+	if req.Server != "" && req.Namespace == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "namespace is required when server is set",
+		})
+		return
+	}
+	scope, err := s.readableNamespaces(c)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to determine readable namespaces: %v", err),
+		})
+		return
+	}
+	if req.Namespace == "" && !scope.all {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Subscriptions must name a namespace unless you can read all of them",
+		})
+		return
+	}
+	if req.Namespace != "" && !scope.allows(req.Namespace) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Not permitted to access namespace %s", req.Namespace),
+		})
+		return
+	}
+
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	req.ID = hex.EncodeToString(id)
+	req.Email = address.Address
+	req.Subject = c.GetString(handlers.SubjectKey)
+	req.CreatedAt = time.Now().UTC()
+
+	subscriptions, cm, err := s.loadSubscriptions(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	subscriptions = append(subscriptions, req)
+	if err := s.saveSubscriptions(context.TODO(), cm, subscriptions); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to save subscription: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, req)
+}
+
+// deleteSubscription removes one of the caller's subscriptions, or
+// anyone's for admins
+func (s *Server) deleteSubscription(c *gin.Context) {
+	subscriptions, cm, err := s.loadSubscriptions(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	admin := s.isAdmin(c)
+	subject := c.GetString(handlers.SubjectKey)
+	kept := make([]NotificationSubscription, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		if sub.ID != c.Param("id") || !(admin || sub.Subject == subject) {
+			kept = append(kept, sub)
+		}
+	}
+	if len(kept) == len(subscriptions) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Subscription not found",
+		})
+		return
+	}
+	if err := s.saveSubscriptions(context.TODO(), cm, kept); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete subscription: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Subscription deleted",
+	})
+}
+
+// containsString reports whether a slice contains a value
+func containsString(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
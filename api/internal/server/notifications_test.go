@@ -0,0 +1,55 @@
+package server_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kubelize/gameplane/api/pkg/gameplane/gameplanetest"
+)
+
+func TestSubscriptionsBelongToTheirCreator(t *testing.T) {
+	h := newHarness(t)
+
+	subscribe := func(subject string, body map[string]interface{}) (int, string) {
+		var sub struct {
+			ID string `json:"id"`
+		}
+		code := call(t, h, subject, gameplanetest.Request(http.MethodPost, "/api/v1/notifications/subscriptions", body), &sub)
+		return code, sub.ID
+	}
+	if code, _ := subscribe("alice", map[string]interface{}{"email": "alice@example.com"}); code != http.StatusForbidden {
+		t.Errorf("subscribing to every namespace without reading them: got %d, want 403", code)
+	}
+	if code, _ := subscribe("alice", map[string]interface{}{"email": "alice@example.com", "namespace": "team-bob"}); code != http.StatusForbidden {
+		t.Errorf("subscribing to another tenant's namespace: got %d, want 403", code)
+	}
+	code, id := subscribe("alice", map[string]interface{}{"email": "alice@example.com", "namespace": "team-alice"})
+	if code != http.StatusCreated {
+		t.Fatalf("subscribing to own namespace: got %d, want 201", code)
+	}
+
+	list := func(subject string) int {
+		var subs struct {
+			Total int `json:"total"`
+		}
+		if code := call(t, h, subject, gameplanetest.Request(http.MethodGet, "/api/v1/notifications/subscriptions", nil), &subs); code != http.StatusOK {
+			t.Fatalf("listing as %s: got %d, want 200", subject, code)
+		}
+		return subs.Total
+	}
+	if n := list("bob"); n != 0 {
+		t.Errorf("listing as another subject: got %d subscriptions, want none", n)
+	}
+	if n := list("alice"); n != 1 {
+		t.Errorf("listing own subscriptions: got %d, want 1", n)
+	}
+	if n := list("root"); n != 1 {
+		t.Errorf("listing as admin: got %d subscriptions, want 1", n)
+	}
+	if code := call(t, h, "bob", gameplanetest.Request(http.MethodDelete, "/api/v1/notifications/subscriptions/"+id, nil), nil); code != http.StatusNotFound {
+		t.Errorf("deleting another's subscription: got %d, want 404", code)
+	}
+	if code := call(t, h, "alice", gameplanetest.Request(http.MethodDelete, "/api/v1/notifications/subscriptions/"+id, nil), nil); code != http.StatusOK {
+		t.Errorf("deleting own subscription: got %d, want 200", code)
+	}
+}
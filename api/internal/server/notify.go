@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kubelize/gameplane/api/internal/metrics"
+)
+
+// webhookTarget is an outgoing webhook that receives notifications
+type webhookTarget struct {
+	URL string `json:"url"`
+	// Format is "discord" for Discord channel webhooks, or "generic" for a
+	// plain JSON body
+	Format string `json:"format,omitempty"`
+}
+
+// notificationEvent is a message delivered to webhooks
+type notificationEvent struct {
+	Type      string            `json:"type"`
+	Namespace string            `json:"namespace,omitempty"`
+	Server    string            `json:"server,omitempty"`
+	Title     string            `json:"title"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields,omitempty"`
+	Time      time.Time         `json:"time"`
+	// Username overrides the Discord webhook's display name
+	Username string `json:"-"`
+}
+
+// webhookClient is shared by all outgoing notifications
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// validateWebhookTarget checks a webhook target before it is stored
+func validateWebhookTarget(target webhookTarget) error {
+	if !strings.HasPrefix(target.URL, "https://") && !strings.HasPrefix(target.URL, "http://") {
+		return fmt.Errorf("webhook url must be an http(s) URL")
+	}
+	switch target.Format {
+	case "", "generic", "discord":
+		return nil
+	}
+	return fmt.Errorf("unsupported webhook format %q (valid: generic, discord)", target.Format)
+}
+
+// postWebhook delivers an event to a webhook target in its format
+func postWebhook(ctx context.Context, target webhookTarget, event notificationEvent) error {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	var body interface{} = event
+	if target.Format == "discord" {
+		body = discordPayload(event)
+	}
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gameplane-api")
+
+	start := time.Now()
+	resp, err := webhookClient.Do(req)
+	format := target.Format
+	if format == "" {
+		format = "generic"
+	}
+	metrics.ObserveDependency(metrics.DependencyWebhook, format, start, err != nil || resp.StatusCode >= 300)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// discordPayload renders an event as a Discord webhook message
+func discordPayload(event notificationEvent) map[string]interface{} {
+	payload := map[string]interface{}{}
+	if event.Username != "" {
+		payload["username"] = event.Username
+	}
+
+	// Chat relays read best as plain messages, everything else as an embed
+	if event.Type == "chat" {
+		payload["content"] = event.Message
+		payload["allowed_mentions"] = map[string]interface{}{"parse": []string{}}
+		return payload
+	}
+
+	keys := make([]string, 0, len(event.Fields))
+	for k := range event.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := make([]map[string]interface{}, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, map[string]interface{}{"name": k, "value": event.Fields[k], "inline": true})
+	}
+
+	payload["embeds"] = []map[string]interface{}{{
+		"title":       event.Title,
+		"description": event.Message,
+		"timestamp":   event.Time.Format(time.RFC3339),
+		"fields":      fields,
+		"footer":      map[string]interface{}{"text": fmt.Sprintf("%s/%s", event.Namespace, event.Server)},
+	}}
+	return payload
+}
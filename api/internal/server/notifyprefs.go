@@ -0,0 +1,375 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// notificationPrefsConfigMap holds every user's notification
+	// preferences, one key per user
+	notificationPrefsConfigMap = "gameplane-notification-preferences"
+
+	// maxNotificationChannels and maxUserSubscriptions bound one user's
+	// preferences
+	maxNotificationChannels = 10
+	maxUserSubscriptions    = 50
+)
+
+// NotificationPreferences decide which events reach a user and where. Users
+// only receive the events they subscribed to.
+type NotificationPreferences struct {
+	Subject string `json:"subject"`
+	// Emails and Webhooks are the user's channels
+	Emails   []string        `json:"emails"`
+	Webhooks []webhookTarget `json:"webhooks"`
+	// Subscriptions select events; an event matching any is delivered
+	Subscriptions []UserSubscription `json:"subscriptions"`
+	QuietHours    *QuietHours        `json:"quietHours,omitempty"`
+	UpdatedAt     *time.Time         `json:"updatedAt,omitempty"`
+}
+
+// UserSubscription selects events by type and server
+type UserSubscription struct {
+	// Events lists event types; empty means all
+	Events []string `json:"events,omitempty"`
+	// Namespace and Server restrict the subscription; an empty namespace
+	// means every namespace and needs read access to all of them
+	Namespace string `json:"namespace,omitempty"`
+	Server    string `json:"server,omitempty"`
+}
+
+// QuietHours hold back notifications between Start and End, given as
+// HH:MM in Timezone (UTC by default). The window may span midnight.
+type QuietHours struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Timezone string `json:"timezone,omitempty"`
+	// Except lists event types delivered during quiet hours anyway
+	Except []string `json:"except,omitempty"`
+}
+
+// normalize fills empty collections so clients always get arrays
+func (p *NotificationPreferences) normalize() {
+	if p.Emails == nil {
+		p.Emails = []string{}
+	}
+	if p.Webhooks == nil {
+		p.Webhooks = []webhookTarget{}
+	}
+	if p.Subscriptions == nil {
+		p.Subscriptions = []UserSubscription{}
+	}
+}
+
+// validate checks channels, subscriptions and quiet hours
+func (p *NotificationPreferences) validate() error {
+	if len(p.Emails)+len(p.Webhooks) > maxNotificationChannels {
+		return fmt.Errorf("at most %d emails and webhooks can be set", maxNotificationChannels)
+	}
+	for i, email := range p.Emails {
+		address, err := mail.ParseAddress(email)
+		if err != nil {
+			return fmt.Errorf("invalid email address %q", email)
+		}
+		p.Emails[i] = address.Address
+	}
+	for _, target := range p.Webhooks {
+		if err := validateWebhookTarget(target); err != nil {
+			return err
+		}
+	}
+
+	if len(p.Subscriptions) > maxUserSubscriptions {
+		return fmt.Errorf("at most %d subscriptions can be set", maxUserSubscriptions)
+	}
+	for _, sub := range p.Subscriptions {
+		if err := validateEventTypes(sub.Events); err != nil {
+			return err
+		}
+		if sub.Server != "" && sub.Namespace == "" {
+			return fmt.Errorf("namespace is required when server is set")
+		}
+	}
+
+	if p.QuietHours != nil {
+		if _, err := p.QuietHours.location(); err != nil {
+			return err
+		}
+		for _, clock := range []string{p.QuietHours.Start, p.QuietHours.End} {
+			if _, err := time.Parse("15:04", clock); err != nil {
+				return fmt.Errorf("invalid quiet hours time %q (expected HH:MM)", clock)
+			}
+		}
+		if err := validateEventTypes(p.QuietHours.Except); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateEventTypes checks event types against those delivered to users
+func validateEventTypes(kinds []string) error {
+	for _, kind := range kinds {
+		if !containsString(emailEventTypes, kind) {
+			return fmt.Errorf("unsupported event type %q (valid: %s)", kind, strings.Join(emailEventTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// wants reports whether the preferences subscribe to an event
+func (p NotificationPreferences) wants(event notificationEvent) bool {
+	for _, sub := range p.Subscriptions {
+		subscription := NotificationSubscription{Events: sub.Events, Namespace: sub.Namespace, Server: sub.Server}
+		if subscription.matches(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// location resolves the quiet hours' timezone
+func (q QuietHours) location() (*time.Location, error) {
+	return Schedule{Timezone: q.Timezone}.location()
+}
+
+// holds reports whether quiet hours hold back an event at a time
+func (q *QuietHours) holds(event notificationEvent, at time.Time) bool {
+	if q == nil || containsString(q.Except, event.Type) {
+		return false
+	}
+	loc, err := q.location()
+	if err != nil {
+		return false
+	}
+	start, err1 := time.Parse("15:04", q.Start)
+	end, err2 := time.Parse("15:04", q.End)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	local := at.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	from, until := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	if from <= until {
+		return minute >= from && minute < until
+	}
+	return minute >= from || minute < until
+}
+
+// loadNotificationPrefs reads every user's notification preferences, keyed
+// by subject, and the ConfigMap holding them, which is nil when nobody
+// stored any yet
+func (s *Server) loadNotificationPrefs(ctx context.Context) (map[string]NotificationPreferences, *corev1.ConfigMap, error) {
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return nil, nil, err
+	}
+	cm, err := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace).Get(ctx, notificationPrefsConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return map[string]NotificationPreferences{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read notification preferences: %w", err)
+	}
+	all := map[string]NotificationPreferences{}
+	for key, raw := range cm.Data {
+		var prefs NotificationPreferences
+		if err := json.Unmarshal([]byte(raw), &prefs); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse notification preferences %s: %w", key, err)
+		}
+		prefs.normalize()
+		all[prefs.Subject] = prefs
+	}
+	return all, cm, nil
+}
+
+// saveNotificationPrefs writes a user's notification preferences, or
+// removes them when prefs is nil, failing on a concurrent change
+func (s *Server) saveNotificationPrefs(ctx context.Context, cm *corev1.ConfigMap, subject string, prefs *NotificationPreferences) error {
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return err
+	}
+	configMaps := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace)
+	key := userPrefsKey(subject)
+
+	var raw []byte
+	if prefs != nil {
+		if raw, err = json.Marshal(prefs); err != nil {
+			return err
+		}
+	}
+	if cm == nil {
+		if prefs == nil {
+			return nil
+		}
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      notificationPrefsConfigMap,
+				Namespace: s.clusters.namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "gameplane-api"},
+			},
+			Data: map[string]string{key: string(raw)},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	if prefs == nil {
+		delete(cm.Data, key)
+	} else {
+		cm.Data[key] = string(raw)
+	}
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// notifyUsers delivers an event to every user subscribed to it outside
+// their quiet hours, logging failures, and returns how many messages were
+// sent
+func (s *Server) notifyUsers(ctx context.Context, event notificationEvent) int {
+	all, _, err := s.loadNotificationPrefs(ctx)
+	if err != nil {
+		log.Printf("Failed to read notification preferences for %s event: %v", event.Type, err)
+		return 0
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	settings, smtpEnabled := loadSMTPSettings()
+
+	sent := 0
+	for subject, prefs := range all {
+		if !prefs.wants(event) || prefs.QuietHours.holds(event, event.Time) {
+			continue
+		}
+		for _, target := range prefs.Webhooks {
+			if err := postWebhook(ctx, target, event); err != nil {
+				log.Printf("Failed to send %s event to webhook of %s: %v", event.Type, subject, err)
+				continue
+			}
+			sent++
+		}
+		if !smtpEnabled {
+			continue
+		}
+		for _, email := range prefs.Emails {
+			if err := sendEmail(settings, email, event); err != nil {
+				log.Printf("Failed to email %s event to %s: %v", event.Type, email, err)
+				continue
+			}
+			sent++
+		}
+	}
+	return sent
+}
+
+// getNotificationPrefs returns the caller's notification preferences
+func (s *Server) getNotificationPrefs(c *gin.Context) {
+	subject := userPrefsSubject(c)
+	all, _, err := s.loadNotificationPrefs(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	prefs, ok := all[subject]
+	if !ok {
+		prefs = NotificationPreferences{Subject: subject}
+		prefs.normalize()
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// putNotificationPrefs replaces the caller's notification preferences.
+// Subscriptions may only cover namespaces the caller can read.
+func (s *Server) putNotificationPrefs(c *gin.Context) {
+	var req NotificationPreferences
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	req.normalize()
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	scope, err := s.readableNamespaces(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to determine readable namespaces: %v", err),
+		})
+		return
+	}
+	for _, sub := range req.Subscriptions {
+		if sub.Namespace == "" && !scope.all {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Subscriptions must name a namespace unless you can read all of them",
+			})
+			return
+		}
+		if sub.Namespace != "" && !scope.allows(sub.Namespace) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": fmt.Sprintf("Not permitted to access namespace %s", sub.Namespace),
+			})
+			return
+		}
+	}
+
+	subject := userPrefsSubject(c)
+	_, cm, err := s.loadNotificationPrefs(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	now := time.Now().UTC()
+	req.Subject = subject
+	req.UpdatedAt = &now
+	if err := s.saveNotificationPrefs(context.TODO(), cm, subject, &req); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to save notification preferences: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// deleteNotificationPrefs unsubscribes the caller from every notification
+func (s *Server) deleteNotificationPrefs(c *gin.Context) {
+	_, cm, err := s.loadNotificationPrefs(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := s.saveNotificationPrefs(context.TODO(), cm, userPrefsSubject(c), nil); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to reset notification preferences: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Notification preferences reset",
+	})
+}
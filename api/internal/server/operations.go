@@ -0,0 +1,301 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxFinishedOperations bounds how many completed operations are kept
+const maxFinishedOperations = 100
+
+// Operation states
+const (
+	operationPending   = "Pending"
+	operationRunning   = "Running"
+	operationSucceeded = "Succeeded"
+	operationFailed    = "Failed"
+)
+
+// Operation is a long-running action tracked after its request returns
+type Operation struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Cluster    string          `json:"cluster,omitempty"`
+	Namespace  string          `json:"namespace"`
+	Name       string          `json:"name"`
+	Status     string          `json:"status"`
+	Steps      []OperationStep `json:"steps"`
+	Error      string          `json:"error,omitempty"`
+	Result     interface{}     `json:"result,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+}
+
+// OperationStep is one stage of an Operation
+type OperationStep struct {
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	Message    string     `json:"message,omitempty"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
+// operationStore keeps operations in memory, shared by all clusters
+type operationStore struct {
+	mu  sync.Mutex
+	ops map[string]*Operation
+}
+
+// operationTracker lets a running operation report progress
+type operationTracker struct {
+	store *operationStore
+	op    *Operation
+}
+
+// startOperation records an operation with the given steps and runs it in
+// the background. Operations do not survive an API restart.
+func (s *Server) startOperation(kind, namespace, name string, steps []string, timeout time.Duration, run func(ctx context.Context, t *operationTracker) (interface{}, error)) Operation {
+	op := &Operation{
+		ID:        newOperationID(),
+		Type:      kind,
+		Cluster:   s.cluster,
+		Namespace: namespace,
+		Name:      name,
+		Status:    operationPending,
+		Steps:     make([]OperationStep, len(steps)),
+		CreatedAt: time.Now().UTC(),
+	}
+	for i, step := range steps {
+		op.Steps[i] = OperationStep{Name: step, Status: operationPending}
+	}
+
+	store := s.operations
+	store.mu.Lock()
+	if store.ops == nil {
+		store.ops = map[string]*Operation{}
+	}
+	store.ops[op.ID] = op
+	store.prune()
+	snapshot := op.snapshot()
+	store.mu.Unlock()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		tracker := &operationTracker{store: store, op: op}
+		tracker.update(func(op *Operation) { op.Status = operationRunning })
+
+		result, err := run(ctx, tracker)
+		tracker.update(func(op *Operation) {
+			now := time.Now().UTC()
+			op.FinishedAt = &now
+			op.Result = result
+			if err != nil {
+				op.Status = operationFailed
+				op.Error = err.Error()
+				return
+			}
+			op.Status = operationSucceeded
+		})
+		if err != nil {
+			log.Printf("Operation %s (%s %s/%s) failed: %v", op.ID, kind, namespace, name, err)
+		}
+	}()
+	return snapshot
+}
+
+// newOperationID returns a random operation ID, also used to label work
+// done on behalf of actions that are not tracked operations
+func newOperationID() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// step runs one named step, recording its outcome. fn returns a message to
+// show with the step.
+func (t *operationTracker) step(name string, fn func() (string, error)) error {
+	index := -1
+	t.update(func(op *Operation) {
+		for i := range op.Steps {
+			if op.Steps[i].Name == name {
+				index = i
+				now := time.Now().UTC()
+				op.Steps[i].Status = operationRunning
+				op.Steps[i].StartedAt = &now
+			}
+		}
+	})
+	if index < 0 {
+		return fmt.Errorf("unknown operation step %q", name)
+	}
+
+	message, err := fn()
+	t.update(func(op *Operation) {
+		now := time.Now().UTC()
+		step := &op.Steps[index]
+		step.FinishedAt = &now
+		step.Message = message
+		step.Status = operationSucceeded
+		if err != nil {
+			step.Status = operationFailed
+			step.Message = err.Error()
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// update changes the operation under the store lock
+func (t *operationTracker) update(fn func(op *Operation)) {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+	fn(t.op)
+}
+
+// snapshot copies an operation for a response; callers hold the store lock
+func (op *Operation) snapshot() Operation {
+	out := *op
+	out.Steps = append([]OperationStep(nil), op.Steps...)
+	return out
+}
+
+// prune drops the oldest finished operations over the limit; callers hold
+// the lock
+func (st *operationStore) prune() {
+	finished := []*Operation{}
+	for _, op := range st.ops {
+		if op.FinishedAt != nil {
+			finished = append(finished, op)
+		}
+	}
+	if len(finished) <= maxFinishedOperations {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].FinishedAt.Before(*finished[j].FinishedAt)
+	})
+	for _, op := range finished[:len(finished)-maxFinishedOperations] {
+		delete(st.ops, op.ID)
+	}
+}
+
+// operationVisibility decides which operations a caller may see: admins see
+// all, others those on servers in namespaces they may read. Operations on no
+// single server, such as rollouts, are for admins only.
+type operationVisibility struct {
+	server *Server
+	c      *gin.Context
+	admin  bool
+	scopes map[string]*namespaceScope
+}
+
+func (s *Server) operationVisibility(c *gin.Context) *operationVisibility {
+	return &operationVisibility{server: s, c: c, admin: s.isAdmin(c), scopes: map[string]*namespaceScope{}}
+}
+
+// allows reports whether the caller may see op. Scopes are looked up once
+// per cluster.
+func (v *operationVisibility) allows(op Operation) (bool, error) {
+	if v.admin {
+		return true, nil
+	}
+	if op.Namespace == "" {
+		return false, nil
+	}
+	scope, ok := v.scopes[op.Cluster]
+	if !ok {
+		scoped, err := v.server.clusters.clusterServer(op.Cluster)
+		if err != nil {
+			// The operation's cluster was unregistered since
+			v.scopes[op.Cluster] = nil
+			return false, nil
+		}
+		readable, err := scoped.readableNamespaces(v.c)
+		if err != nil {
+			return false, err
+		}
+		scope = &readable
+		v.scopes[op.Cluster] = scope
+	}
+	return scope != nil && scope.allows(op.Namespace), nil
+}
+
+// listOperations returns the operations the caller may see, newest first,
+// optionally filtered by ?type=
+func (s *Server) listOperations(c *gin.Context) {
+	kind := c.Query("type")
+
+	s.operations.mu.Lock()
+	all := make([]Operation, 0, len(s.operations.ops))
+	for _, op := range s.operations.ops {
+		if kind == "" || op.Type == kind {
+			all = append(all, op.snapshot())
+		}
+	}
+	s.operations.mu.Unlock()
+
+	visibility := s.operationVisibility(c)
+	items := make([]Operation, 0, len(all))
+	for _, op := range all {
+		visible, err := visibility.allows(op)
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to determine readable namespaces: %v", err),
+			})
+			return
+		}
+		if visible {
+			items = append(items, op)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].CreatedAt.After(items[j].CreatedAt)
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(items),
+	})
+}
+
+// getOperation returns one operation, or 404 for operations the caller may
+// not see
+func (s *Server) getOperation(c *gin.Context) {
+	s.operations.mu.Lock()
+	op, ok := s.operations.ops[c.Param("id")]
+	var snapshot Operation
+	if ok {
+		snapshot = op.snapshot()
+	}
+	s.operations.mu.Unlock()
+
+	visible := false
+	if ok {
+		var err error
+		if visible, err = s.operationVisibility(c).allows(snapshot); err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to determine readable namespaces: %v", err),
+			})
+			return
+		}
+	}
+	if !visible {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Operation not found",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
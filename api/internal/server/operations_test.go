@@ -0,0 +1,70 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kubelize/gameplane/api/internal/devcluster"
+)
+
+// headerAuth authenticates requests as the subject in X-Test-Subject
+type headerAuth struct{}
+
+func (headerAuth) Authenticate(r *http.Request) (string, error) {
+	if subject := r.Header.Get("X-Test-Subject"); subject != "" {
+		return subject, nil
+	}
+	return "", errors.New("no subject")
+}
+
+// tenantAccess lets alice read only team-alice and everyone else all
+type tenantAccess struct{}
+
+func (tenantAccess) ReadableNamespaces(r *http.Request, subject string) ([]string, bool, error) {
+	if subject == "alice" {
+		return []string{"team-alice"}, false, nil
+	}
+	return nil, true, nil
+}
+
+func TestOperationsInReadableNamespaces(t *testing.T) {
+	s, err := NewServer(Options{DevCluster: devcluster.New(), Authenticator: headerAuth{}, NamespaceAccess: tenantAccess{}, Admins: []string{"root"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	s.operations.ops = map[string]*Operation{
+		"mine":    {ID: "mine", Type: "export", Cluster: s.cluster, Namespace: "team-alice", Name: "a", CreatedAt: now},
+		"theirs":  {ID: "theirs", Type: "export", Cluster: s.cluster, Namespace: "team-bob", Name: "b", CreatedAt: now},
+		"rollout": {ID: "rollout", Type: "rollout", Cluster: s.cluster, CreatedAt: now},
+	}
+
+	get := func(subject, path string, out interface{}) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Test-Subject", subject)
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, req)
+		if out != nil {
+			_ = json.Unmarshal(rec.Body.Bytes(), out)
+		}
+		return rec.Code
+	}
+	var list struct {
+		Items []Operation `json:"items"`
+	}
+	if code := get("alice", "/api/v1/operations", &list); code != http.StatusOK || len(list.Items) != 1 || list.Items[0].ID != "mine" {
+		t.Errorf("listing as tenant: got %d with %+v, want only mine", code, list.Items)
+	}
+	if code := get("root", "/api/v1/operations", &list); code != http.StatusOK || len(list.Items) != 3 {
+		t.Errorf("listing as admin: got %d with %d operations, want 3", code, len(list.Items))
+	}
+	for id, want := range map[string]int{"mine": http.StatusOK, "theirs": http.StatusNotFound, "rollout": http.StatusNotFound} {
+		if code := get("alice", "/api/v1/operations/"+id, nil); code != want {
+			t.Errorf("getting %s as tenant: got %d, want %d", id, code, want)
+		}
+	}
+}
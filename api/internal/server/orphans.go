@@ -0,0 +1,345 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// orphanGracePeriod spares resources of provisioning still in flight
+	orphanGracePeriod = 30 * time.Minute
+
+	// orphanStuckAfter is how long a deletion may wait on finalizers before
+	// it counts as stuck
+	orphanStuckAfter = 15 * time.Minute
+
+	// orphanJanitorInterval is how often the janitor looks for orphans
+	orphanJanitorInterval = time.Hour
+)
+
+// Orphan cleanup actions
+const (
+	orphanActionDelete           = "delete"
+	orphanActionRemoveFinalizers = "remove-finalizers"
+)
+
+// Orphan is a resource left behind by failed provisioning or manual changes
+type Orphan struct {
+	// ID is kind/namespace/name, or kind/name for cluster resources
+	ID         string    `json:"id"`
+	Kind       string    `json:"kind"`
+	Namespace  string    `json:"namespace,omitempty"`
+	Name       string    `json:"name"`
+	Reason     string    `json:"reason"`
+	Finalizers []string  `json:"finalizers,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	// Action is what cleanup does: delete the resource, or remove the
+	// finalizers of a stuck deletion
+	Action string `json:"action"`
+}
+
+// OrphanCleanupRequest picks the orphans to clean up
+type OrphanCleanupRequest struct {
+	IDs []string `json:"ids,omitempty"`
+	// All deletes every orphan; finalizers are only removed when listed
+	// in IDs
+	All    bool `json:"all,omitempty"`
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// OrphanCleanupResult lists what a cleanup did
+type OrphanCleanupResult struct {
+	DryRun  bool                 `json:"dryRun"`
+	Cleaned []Orphan             `json:"cleaned"`
+	Failed  []OrphanCleanupError `json:"failed"`
+	// Skipped are requested IDs that are no longer orphaned
+	Skipped []string `json:"skipped"`
+}
+
+// OrphanCleanupError is an orphan that could not be cleaned up
+type OrphanCleanupError struct {
+	ID    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// newOrphan describes an orphaned resource
+func newOrphan(kind string, meta metav1.Object, reason, action string) Orphan {
+	id := kind + "/" + meta.GetName()
+	if meta.GetNamespace() != "" {
+		id = kind + "/" + meta.GetNamespace() + "/" + meta.GetName()
+	}
+	return Orphan{
+		ID:         id,
+		Kind:       kind,
+		Namespace:  meta.GetNamespace(),
+		Name:       meta.GetName(),
+		Reason:     reason,
+		Finalizers: meta.GetFinalizers(),
+		CreatedAt:  meta.GetCreationTimestamp().Time,
+		Action:     action,
+	}
+}
+
+// stuckDeletion reports whether an object has been waiting on finalizers
+// for longer than orphanStuckAfter
+func stuckDeletion(meta metav1.Object, now time.Time) bool {
+	deleted := meta.GetDeletionTimestamp()
+	return deleted != nil && now.Sub(deleted.Time) > orphanStuckAfter
+}
+
+// findOrphans scans the cluster for managed namespaces without a
+// GameServer, game volumes and Services nothing owns, released game volumes
+// and deletions stuck on finalizers
+func (s *Server) findOrphans(ctx context.Context) ([]Orphan, error) {
+	list, err := s.listAllGameServers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list GameServers: %w", err)
+	}
+	now := time.Now()
+	orphans := []Orphan{}
+	live := map[string]bool{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		if namespace, err := managedNamespace(obj); err == nil {
+			live[namespace] = true
+		}
+		if stuckDeletion(obj, now) && len(obj.GetFinalizers()) > 0 {
+			orphans = append(orphans, newOrphan("GameServer", obj, fmt.Sprintf("Deleted %s ago, still waiting on finalizers", now.Sub(obj.GetDeletionTimestamp().Time).Round(time.Minute)), orphanActionRemoveFinalizers))
+		}
+	}
+	young := func(meta metav1.Object) bool {
+		return now.Sub(meta.GetCreationTimestamp().Time) < orphanGracePeriod
+	}
+
+	namespaces, err := s.kubeClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: managedNamespaceLabel})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list game namespaces: %w", err)
+	}
+	orphanedNamespaces := map[string]bool{}
+	for i := range namespaces.Items {
+		ns := &namespaces.Items[i]
+		switch {
+		case ns.DeletionTimestamp != nil:
+			if stuckDeletion(ns, now) {
+				orphan := newOrphan("Namespace", ns, fmt.Sprintf("Deleted %s ago, still terminating", now.Sub(ns.DeletionTimestamp.Time).Round(time.Minute)), orphanActionRemoveFinalizers)
+				for _, finalizer := range ns.Spec.Finalizers {
+					orphan.Finalizers = append(orphan.Finalizers, string(finalizer))
+				}
+				orphans = append(orphans, orphan)
+			}
+			orphanedNamespaces[ns.Name] = true
+		case live[ns.Name] || young(ns):
+		default:
+			orphans = append(orphans, newOrphan("Namespace", ns, "No GameServer uses this namespace", orphanActionDelete))
+			orphanedNamespaces[ns.Name] = true
+		}
+	}
+
+	// Game resources outside orphaned namespaces, which go with them
+	unowned := func(meta metav1.Object) bool {
+		owner := meta.GetLabels()[managedNamespaceLabel]
+		return !orphanedNamespaces[meta.GetNamespace()] && !live[owner] && !live[meta.GetNamespace()] && len(meta.GetOwnerReferences()) == 0 && meta.GetDeletionTimestamp() == nil && !young(meta)
+	}
+	claims, err := s.kubeClient.CoreV1().PersistentVolumeClaims("").List(ctx, metav1.ListOptions{LabelSelector: managedNamespaceLabel})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list game volumes: %w", err)
+	}
+	for i := range claims.Items {
+		claim := &claims.Items[i]
+		if unowned(claim) {
+			orphans = append(orphans, newOrphan("PersistentVolumeClaim", claim, fmt.Sprintf("Belongs to GameServer namespace %s, which no GameServer uses", claim.Labels[managedNamespaceLabel]), orphanActionDelete))
+		}
+	}
+	services, err := s.kubeClient.CoreV1().Services("").List(ctx, metav1.ListOptions{LabelSelector: managedNamespaceLabel})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list game Services: %w", err)
+	}
+	for i := range services.Items {
+		service := &services.Items[i]
+		if unowned(service) {
+			orphans = append(orphans, newOrphan("Service", service, fmt.Sprintf("Belongs to GameServer namespace %s, which no GameServer uses", service.Labels[managedNamespaceLabel]), orphanActionDelete))
+		}
+	}
+
+	volumes, err := s.kubeClient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persistent volumes: %w", err)
+	}
+	for i := range volumes.Items {
+		pv := &volumes.Items[i]
+		claim := pv.Spec.ClaimRef
+		if pv.Status.Phase != corev1.VolumeReleased || claim == nil || claim.Name != claim.Namespace+"-storage" || live[claim.Namespace] {
+			continue
+		}
+		orphans = append(orphans, newOrphan("PersistentVolume", pv, fmt.Sprintf("Released by %s/%s and retained", claim.Namespace, claim.Name), orphanActionDelete))
+	}
+
+	sort.Slice(orphans, func(i, j int) bool {
+		return orphans[i].ID < orphans[j].ID
+	})
+	return orphans, nil
+}
+
+// getOrphans lists orphaned resources. They span every namespace, so only
+// admins may see them.
+func (s *Server) getOrphans(c *gin.Context) {
+	if !s.isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Only admins may list orphaned resources",
+		})
+		return
+	}
+	orphans, err := s.findOrphans(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to find orphans: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items": orphans,
+		"total": len(orphans),
+	})
+}
+
+// cleanupOrphans deletes the requested orphans, or removes the finalizers
+// of stuck deletions. The cluster is scanned again first, so only what is
+// still orphaned is touched.
+func (s *Server) cleanupOrphans(c *gin.Context) {
+	var req OrphanCleanupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if !req.All && len(req.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "ids or all is required",
+		})
+		return
+	}
+
+	ctx := context.TODO()
+	orphans, err := s.findOrphans(ctx)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to find orphans: %v", err),
+		})
+		return
+	}
+	requested := map[string]bool{}
+	for _, id := range req.IDs {
+		requested[id] = true
+	}
+
+	result := OrphanCleanupResult{DryRun: req.DryRun, Cleaned: []Orphan{}, Failed: []OrphanCleanupError{}, Skipped: []string{}}
+	for _, orphan := range orphans {
+		selected := requested[orphan.ID] || (req.All && orphan.Action == orphanActionDelete)
+		delete(requested, orphan.ID)
+		if !selected {
+			continue
+		}
+		if !req.DryRun {
+			if err := s.cleanupOrphan(ctx, orphan); err != nil {
+				result.Failed = append(result.Failed, OrphanCleanupError{ID: orphan.ID, Error: err.Error()})
+				continue
+			}
+		}
+		result.Cleaned = append(result.Cleaned, orphan)
+	}
+	for id := range requested {
+		result.Skipped = append(result.Skipped, id)
+	}
+	sort.Strings(result.Skipped)
+	c.JSON(http.StatusOK, result)
+}
+
+// cleanupOrphan carries out an orphan's cleanup action
+func (s *Server) cleanupOrphan(ctx context.Context, orphan Orphan) error {
+	core := s.kubeClient.CoreV1()
+	if orphan.Action == orphanActionRemoveFinalizers {
+		switch orphan.Kind {
+		case "GameServer":
+			obj, err := s.getGameServerObject(ctx, orphan.Namespace, orphan.Name)
+			if err != nil {
+				return err
+			}
+			obj.SetFinalizers(nil)
+			return s.k8sClient.Update(ctx, obj)
+		case "Namespace":
+			ns, err := core.Namespaces().Get(ctx, orphan.Name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if len(ns.Finalizers) > 0 {
+				ns.Finalizers = nil
+				if ns, err = core.Namespaces().Update(ctx, ns, metav1.UpdateOptions{}); err != nil {
+					return err
+				}
+			}
+			ns.Spec.Finalizers = nil
+			_, err = core.Namespaces().Finalize(ctx, ns, metav1.UpdateOptions{})
+			return err
+		}
+		return fmt.Errorf("cannot remove finalizers of a %s", orphan.Kind)
+	}
+
+	var err error
+	switch orphan.Kind {
+	case "Namespace":
+		err = core.Namespaces().Delete(ctx, orphan.Name, metav1.DeleteOptions{})
+	case "PersistentVolumeClaim":
+		err = core.PersistentVolumeClaims(orphan.Namespace).Delete(ctx, orphan.Name, metav1.DeleteOptions{})
+	case "Service":
+		err = core.Services(orphan.Namespace).Delete(ctx, orphan.Name, metav1.DeleteOptions{})
+	case "PersistentVolume":
+		err = core.PersistentVolumes().Delete(ctx, orphan.Name, metav1.DeleteOptions{})
+	default:
+		return fmt.Errorf("cannot delete a %s", orphan.Kind)
+	}
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// runOrphanJanitor logs the orphans it finds. With ORPHAN_CLEANUP=true it
+// also deletes them; stuck deletions are always left to an admin.
+func (s *Server) runOrphanJanitor(ctx context.Context) error {
+	orphans, err := s.findOrphans(ctx)
+	if err != nil {
+		return err
+	}
+	if len(orphans) == 0 {
+		return nil
+	}
+	cleanup := os.Getenv("ORPHAN_CLEANUP") == "true"
+	ids := []string{}
+	for _, orphan := range orphans {
+		if cleanup && orphan.Action == orphanActionDelete {
+			if err := s.cleanupOrphan(ctx, orphan); err != nil {
+				log.Printf("Failed to clean up orphaned %s: %v", orphan.ID, err)
+			} else {
+				log.Printf("Cleaned up orphaned %s: %s", orphan.ID, orphan.Reason)
+			}
+			continue
+		}
+		ids = append(ids, orphan.ID)
+	}
+	if len(ids) > 0 {
+		log.Printf("Found %d orphaned resources, see GET /admin/orphans: %s", len(ids), strings.Join(ids, ", "))
+	}
+	return nil
+}
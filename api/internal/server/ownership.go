@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ownedRoutePrefix starts the routes acting on one GameServer, relative to
+// the API version prefix, which non-admins may call for servers they own
+const ownedRoutePrefix = "/gameservers/:namespace/:name"
+
+// selfServiceRoutes are the other mutating routes non-admins may call:
+// read-only POSTs, server requests and per-user settings. Deciding requests
+// is checked against the request's namespace by its handler.
+var selfServiceRoutes = map[string]bool{
+	"/gameservers/estimate":                 true,
+	"/requests":                             true,
+	"/requests/:request":                    true,
+	"/requests/:request/approve":            true,
+	"/requests/:request/deny":               true,
+	"/auth/sessions":                        true,
+	"/auth/sessions/:id":                    true,
+	"/kiosk/tokens":                         true,
+	"/kiosk/tokens/:id":                     true,
+	"/notifications/subscriptions":          true,
+	"/notifications/subscriptions/:id":      true,
+	"/userprefs":                            true,
+	"/userprefs/favorites/:namespace/:name": true,
+	"/users/me/preferences":                 true,
+}
+
+// gameServerOwner returns the subject owning a GameServer, or "" for
+// servers created before ownership was recorded
+func gameServerOwner(obj *unstructured.Unstructured) string {
+	return obj.GetAnnotations()[ownerAnnotation]
+}
+
+// mayChangeServer reports whether the caller could change a GameServer:
+// admins, namespace operators and its owner may
+func (s *Server) mayChangeServer(c *gin.Context, namespace, name string) bool {
+	if s.isAdmin(c) || s.hasRole(c, namespace, roleOperator) {
+		return true
+	}
+	scoped, err := s.clusters.clusterServer(c.Query("cluster"))
+	if err != nil {
+		return false
+	}
+	obj, err := scoped.getGameServerObject(context.TODO(), namespace, name)
+	if err != nil {
+		return false
+	}
+	subject := c.GetString(handlers.SubjectKey)
+	return subject != "" && gameServerOwner(obj) == subject
+}
+
+// guardOwnership limits non-admins to changing the GameServers they own,
+// plus the self-service routes. Team roles widen this per namespace:
+// operators change every GameServer in it and admins anything under it.
+// Reads are left to guardNamespaces.
+func (s *Server) guardOwnership(prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		route := strings.TrimPrefix(c.FullPath(), prefix)
+		namespace := c.Param("namespace")
+		if s.isAdmin(c) || selfServiceRoutes[route] || (namespace != "" && s.hasRole(c, namespace, roleAdmin)) {
+			c.Next()
+			return
+		}
+		if route != ownedRoutePrefix && !strings.HasPrefix(route, ownedRoutePrefix+"/") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Only admins may do this; request new GameServers at /requests",
+			})
+			return
+		}
+
+		if s.hasRole(c, namespace, roleOperator) {
+			c.Next()
+			return
+		}
+
+		scoped, err := s.clusters.clusterServer(c.Query("cluster"))
+		if err != nil {
+			// clustered responds with the unknown cluster
+			c.Next()
+			return
+		}
+		obj, err := scoped.getGameServerObject(context.TODO(), namespace, c.Param("name"))
+		if apierrors.IsNotFound(err) {
+			// The handler responds with 404
+			c.Next()
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+			})
+			return
+		}
+		subject := c.GetString(handlers.SubjectKey)
+		if subject == "" || gameServerOwner(obj) != subject {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": fmt.Sprintf("GameServer %s/%s is not yours", obj.GetNamespace(), obj.GetName()),
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,56 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/kubelize/gameplane/api/pkg/gameplane/gameplanetest"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestAuthRequired(t *testing.T) {
+	h := newHarness(t)
+
+	if code := call(t, h, "", gameplanetest.Request(http.MethodGet, "/api/v1/gameservers", nil), nil); code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated list: got %d, want 401", code)
+	}
+	if code := call(t, h, "alice", gameplanetest.Request(http.MethodGet, "/api/v1/gameservers", nil), nil); code != http.StatusOK {
+		t.Errorf("authenticated list: got %d, want 200", code)
+	}
+	if code := call(t, h, "", gameplanetest.Request(http.MethodGet, "/api/v1/health", nil), nil); code != http.StatusOK {
+		t.Errorf("health check: got %d, want 200 without authentication", code)
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	h := newHarness(t, ownedServer("default", "alices", "alice"), ownedServer("default", "bobs", "bob"))
+
+	var body map[string]interface{}
+	if code := call(t, h, "alice", gameplanetest.Request(http.MethodPost, "/api/v1/gameservers", gameplanetest.GameServer("default", "new", "sdtd", nil).Object), &body); code != http.StatusForbidden {
+		t.Errorf("non-admin create: got %d, want 403 (%v)", code, body)
+	}
+	if code := call(t, h, "alice", gameplanetest.Request(http.MethodDelete, "/api/v1/gameservers/default/bobs", nil), &body); code != http.StatusForbidden {
+		t.Errorf("deleting another's server: got %d, want 403 (%v)", code, body)
+	}
+	if code := call(t, h, "alice", gameplanetest.Request(http.MethodGet, "/api/v1/gameservers/default/bobs", nil), nil); code != http.StatusOK {
+		t.Errorf("reading another's server: got %d, want 200", code)
+	}
+	if code := call(t, h, "alice", gameplanetest.Request(http.MethodDelete, "/api/v1/gameservers/default/alices", nil), &body); code != http.StatusOK {
+		t.Fatalf("deleting own server: got %d, want 200 (%v)", code, body)
+	}
+	if code := call(t, h, "root", gameplanetest.Request(http.MethodDelete, "/api/v1/gameservers/default/bobs", nil), &body); code != http.StatusOK {
+		t.Fatalf("admin deleting any server: got %d, want 200 (%v)", code, body)
+	}
+
+	for _, name := range []string{"alices", "bobs"} {
+		obj := &unstructured.Unstructured{}
+		obj.SetAPIVersion("gameplane.kubelize.io/v1alpha1")
+		obj.SetKind("GameServer")
+		if err := h.Client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: name}, obj); !apierrors.IsNotFound(err) {
+			t.Errorf("GameServer %s still exists after deletion (err %v)", name, err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// errPanelUnsupported is returned for games without a web admin panel
+var errPanelUnsupported = errors.New("this game has no web admin panel")
+
+// panelSandbox is the Content-Security-Policy of every proxied panel
+// response. Panels are game-controlled pages served from the API's origin;
+// without allow-same-origin they run in an opaque origin, so their scripts
+// cannot read the dashboard's storage or call the API with its credentials.
+const panelSandbox = "sandbox allow-scripts allow-forms allow-popups allow-modals allow-downloads"
+
+// panelTarget returns the address of a GameServer's web admin panel: the
+// web port reported in its status or, before it is, the game's default
+func (s *Server) panelTarget(ctx context.Context, obj *unstructured.Unstructured) (*url.URL, error) {
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, _ := lookupGame(gameType)
+	port := def.WebPort
+	if status, found, _ := unstructured.NestedInt64(obj.Object, "status", "webPort"); found && status > 0 {
+		port = int(status)
+	}
+	if port == 0 {
+		return nil, errPanelUnsupported
+	}
+
+	podIP, err := s.runningPodIP(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	return &url.URL{Scheme: "http", Host: net.JoinHostPort(podIP, strconv.Itoa(port))}, nil
+}
+
+// proxyGameServerPanel forwards requests under .../panel/ to the web admin
+// panel of a GameServer's pod, so panels are reached through the API's
+// authentication instead of an ingress and a password of their own. Only
+// those who may change the server may use its panel. The API's
+// credentials are not passed on to the game, and responses are sandboxed
+// and may only set cookies below the panel path.
+func (s *Server) proxyGameServerPanel(c *gin.Context) {
+	namespace, name := c.Param("namespace"), c.Param("name")
+	if !s.mayChangeServer(c, namespace, name) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Only admins, operators and the owner may open the panel of %s/%s", namespace, name),
+		})
+		return
+	}
+	obj, err := s.getGameServerObject(c.Request.Context(), namespace, name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+	target, err := s.panelTarget(c.Request.Context(), obj)
+	if errors.Is(err, errPanelUnsupported) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	path := c.Param("path")
+	base := strings.TrimSuffix(c.Request.URL.Path, path)
+	proxy := &httputil.ReverseProxy{
+		// Live maps and consoles stream
+		FlushInterval: -1,
+		Director: func(req *http.Request) {
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.URL.Path = path
+			req.URL.RawPath = ""
+			req.Host = target.Host
+			req.Header.Del("Authorization")
+			req.Header.Set("X-Forwarded-Prefix", base)
+			stripCookie(req, playerCookie)
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			// Panels redirect to their own absolute paths
+			if location := resp.Header.Get("Location"); strings.HasPrefix(location, "/") && !strings.HasPrefix(location, "//") {
+				resp.Header.Set("Location", base+location)
+			}
+			// Added next to any policy of the panel's own; browsers
+			// enforce all of them
+			resp.Header.Add("Content-Security-Policy", panelSandbox)
+			resp.Header.Set("X-Content-Type-Options", "nosniff")
+			scopePanelCookies(resp, base)
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			log.Printf("Panel of GameServer %s/%s failed: %v", namespace, name, err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(gin.H{
+				"error": fmt.Sprintf("The web admin panel did not answer: %v", err),
+			})
+		},
+	}
+	proxy.ServeHTTP(c.Writer, c.Request)
+}
+
+// scopePanelCookies keeps the cookies a panel sets below its panel path, so
+// a game cannot overwrite the API's own cookies. Domain-wide cookies and
+// the player session cookie are dropped.
+func scopePanelCookies(resp *http.Response, base string) {
+	cookies := resp.Cookies()
+	resp.Header.Del("Set-Cookie")
+	for _, cookie := range cookies {
+		if cookie.Name == playerCookie || cookie.Domain != "" {
+			continue
+		}
+		cookie.Path = base + "/" + strings.TrimPrefix(cookie.Path, "/")
+		resp.Header.Add("Set-Cookie", cookie.String())
+	}
+}
+
+// stripCookie removes one cookie from a request, keeping the others
+func stripCookie(req *http.Request, name string) {
+	cookies := req.Cookies()
+	req.Header.Del("Cookie")
+	for _, cookie := range cookies {
+		if cookie.Name != name {
+			req.AddCookie(cookie)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package server_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kubelize/gameplane/api/pkg/gameplane/gameplanetest"
+)
+
+func TestPanelPostsHeldDuringMaintenance(t *testing.T) {
+	h := newHarness(t, ownedServer("team-alice", "alices", "alice"))
+
+	body := strings.NewReader(`{"enabled": true, "message": "Upgrading storage"}`)
+	if code := call(t, h, "root", gameplanetest.Request(http.MethodPut, "/api/v1/maintenance", body), nil); code != http.StatusOK {
+		t.Fatalf("enable maintenance: got %d, want 200", code)
+	}
+
+	var resp struct {
+		Error string `json:"error"`
+	}
+	code := call(t, h, "alice", gameplanetest.Request(http.MethodPost, "/api/v2/gameservers/team-alice/alices/panel/settings", strings.NewReader("save=1")), &resp)
+	if code != http.StatusServiceUnavailable || resp.Error != "Upgrading storage" {
+		t.Errorf("panel post during maintenance: got %d %q, want 503 %q", code, resp.Error, "Upgrading storage")
+	}
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// requiredPermission is access the API's service account needs, cluster-wide
+type requiredPermission struct {
+	group       string
+	resource    string
+	subresource string
+	verbs       []string
+	// usedFor explains what fails without the permission
+	usedFor string
+	// optional permissions only back individual features
+	optional bool
+}
+
+// requiredPermissions lists every resource the API reads or writes
+var requiredPermissions = []requiredPermission{
+	{group: "gameplane.kubelize.io", resource: "gameservers", verbs: []string{"get", "list", "watch", "create", "update", "patch", "delete"}, usedFor: "managing GameServer claims"},
+	{group: "gameplane.kubelize.io", resource: "fleets", verbs: []string{"get", "list", "create", "update", "delete"}, usedFor: "fleets", optional: true},
+	{group: "gameplane.kubelize.io", resource: "projects", verbs: []string{"get", "list", "create", "update", "delete"}, usedFor: "projects", optional: true},
+	{group: "gameplane.kubelize.io", resource: "banlists", verbs: []string{"get", "list", "create", "update", "delete"}, usedFor: "shared ban lists", optional: true},
+	{group: "gameplane.kubelize.io", resource: "banlists", subresource: "status", verbs: []string{"update"}, usedFor: "ban list sync status", optional: true},
+	{group: "", resource: "pods", verbs: []string{"get", "list", "delete"}, usedFor: "server status, restarts and console access"},
+	{group: "", resource: "pods", verbs: []string{"create"}, usedFor: "volume tasks such as backups and save migration", optional: true},
+	{group: "", resource: "pods", subresource: "log", verbs: []string{"get"}, usedFor: "logs and chat relays"},
+	{group: "", resource: "pods", subresource: "proxy", verbs: []string{"get", "create"}, usedFor: "save migration", optional: true},
+	{group: "", resource: "configmaps", verbs: []string{"get", "list", "create", "update", "delete"}, usedFor: "schedules, notifications and other installation state"},
+	{group: "", resource: "secrets", verbs: []string{"get"}, usedFor: "RCON console passwords, storage credentials and whitelist source tokens"},
+	{group: "", resource: "secrets", verbs: []string{"list"}, usedFor: "Helm release status", optional: true},
+	{group: "", resource: "secrets", verbs: []string{"create", "update", "delete"}, usedFor: "in-game hook signing secrets and the player session key", optional: true},
+	{group: "", resource: "namespaces", verbs: []string{"get", "list"}, usedFor: "namespace listing and admission"},
+	{group: "", resource: "nodes", verbs: []string{"list"}, usedFor: "cluster info", optional: true},
+	{group: "", resource: "persistentvolumeclaims", verbs: []string{"get", "list"}, usedFor: "storage reports", optional: true},
+	{group: "", resource: "persistentvolumeclaims", verbs: []string{"create", "delete"}, usedFor: "test restores of backups", optional: true},
+	{group: "", resource: "persistentvolumes", verbs: []string{"get", "list"}, usedFor: "storage reports", optional: true},
+	{group: "", resource: "services", verbs: []string{"list"}, usedFor: "the workload view of managed namespaces", optional: true},
+	{group: "apps", resource: "deployments", verbs: []string{"list"}, usedFor: "the workload view of managed namespaces", optional: true},
+	{group: "apps", resource: "statefulsets", verbs: []string{"list"}, usedFor: "the workload view of managed namespaces", optional: true},
+	{group: "networking.k8s.io", resource: "ingresses", verbs: []string{"list"}, usedFor: "the workload view of managed namespaces", optional: true},
+	{group: "snapshot.storage.k8s.io", resource: "volumesnapshots", verbs: []string{"get", "list", "create", "update", "delete"}, usedFor: "safety snapshots and backup verification", optional: true},
+	{group: "coordination.k8s.io", resource: "leases", verbs: []string{"get", "create", "update"}, usedFor: "leader election of background tasks"},
+	{group: "metrics.k8s.io", resource: "pods", verbs: []string{"get", "list"}, usedFor: "CPU and memory usage", optional: true},
+	{group: "helm.crossplane.io", resource: "releases", verbs: []string{"list"}, usedFor: "Helm release status", optional: true},
+	{group: "apiextensions.k8s.io", resource: "customresourcedefinitions", verbs: []string{"get"}, usedFor: "the platform health check", optional: true},
+	{group: "apiextensions.crossplane.io", resource: "compositions", verbs: []string{"list"}, usedFor: "the platform health check", optional: true},
+	{group: "apiextensions.crossplane.io", resource: "compositeresourcedefinitions", verbs: []string{"list"}, usedFor: "the platform health check", optional: true},
+}
+
+// PermissionCheck is the outcome of one access review
+type PermissionCheck struct {
+	Group       string `json:"group,omitempty"`
+	Resource    string `json:"resource"`
+	Subresource string `json:"subresource,omitempty"`
+	Verb        string `json:"verb"`
+	Allowed     bool   `json:"allowed"`
+	Optional    bool   `json:"optional,omitempty"`
+	UsedFor     string `json:"usedFor"`
+	// Reason is the authorizer's explanation, when it gives one
+	Reason string `json:"reason,omitempty"`
+}
+
+// PermissionReport lists the access the API has and lacks
+type PermissionReport struct {
+	// Status is ok, degraded (optional permissions missing) or missing
+	Status string            `json:"status"`
+	Checks []PermissionCheck `json:"checks"`
+	// Missing summarizes denied permissions as "verb resource[/subresource]"
+	Missing []string `json:"missing"`
+}
+
+// getPermissions reviews every permission the API needs with
+// SelfSubjectAccessReviews, so a misconfigured service account shows up as
+// one diagnosis instead of failures scattered across endpoints
+func (s *Server) getPermissions(c *gin.Context) {
+	report := PermissionReport{Status: "ok", Checks: []PermissionCheck{}, Missing: []string{}}
+	for _, permission := range requiredPermissions {
+		for _, verb := range permission.verbs {
+			check, err := s.reviewPermission(context.TODO(), permission, verb)
+			if err != nil {
+				c.JSON(errorStatus(c, err), gin.H{
+					"error": fmt.Sprintf("Failed to review permissions: %v", err),
+				})
+				return
+			}
+			report.Checks = append(report.Checks, check)
+			if check.Allowed {
+				continue
+			}
+			report.Missing = append(report.Missing, permissionString(check))
+			switch {
+			case !check.Optional:
+				report.Status = "missing"
+			case report.Status == "ok":
+				report.Status = "degraded"
+			}
+		}
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// reviewPermission asks the API server whether the API may use verb on the
+// permission's resource in every namespace
+func (s *Server) reviewPermission(ctx context.Context, permission requiredPermission, verb string) (PermissionCheck, error) {
+	review, err := s.kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Group:       permission.group,
+				Resource:    permission.resource,
+				Subresource: permission.subresource,
+				Verb:        verb,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return PermissionCheck{}, err
+	}
+	return PermissionCheck{
+		Group:       permission.group,
+		Resource:    permission.resource,
+		Subresource: permission.subresource,
+		Verb:        verb,
+		Allowed:     review.Status.Allowed,
+		Optional:    permission.optional,
+		UsedFor:     permission.usedFor,
+		Reason:      strings.TrimSpace(review.Status.Reason + " " + review.Status.EvaluationError),
+	}, nil
+}
+
+// permissionString formats a check like kubectl auth can-i
+func permissionString(check PermissionCheck) string {
+	resource := check.Resource
+	if check.Group != "" {
+		resource += "." + check.Group
+	}
+	if check.Subresource != "" {
+		resource += "/" + check.Subresource
+	}
+	return check.Verb + " " + resource
+}
@@ -0,0 +1,314 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// defaultCrossplaneNamespace is where Crossplane is installed unless
+	// CROSSPLANE_NAMESPACE says otherwise
+	defaultCrossplaneNamespace = "crossplane-system"
+
+	// parentXRDName is the XRD offering GameServer claims
+	parentXRDName = "xgameservers.gameplane.kubelize.io"
+
+	// Platform check states
+	checkOK      = "ok"
+	checkWarning = "warning"
+	checkError   = "error"
+)
+
+// requiredFunctions are the composition functions the compositions run
+var requiredFunctions = []string{"function-go-templating", "function-auto-ready"}
+
+// requiredProviders are matched against Provider packages
+var requiredProviders = []string{"provider-kubernetes"}
+
+// platformCRDs are the GamePlane CRDs; optional ones only back extra features
+var platformCRDs = []struct {
+	name     string
+	optional bool
+}{
+	{name: "gameservers.gameplane.kubelize.io"},
+	{name: "fleets.gameplane.kubelize.io", optional: true},
+	{name: "projects.gameplane.kubelize.io", optional: true},
+}
+
+// PlatformCheck is one verified prerequisite of GamePlane
+type PlatformCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	// Remediation suggests how to fix a failed check
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// PlatformHealth reports whether Crossplane and the GamePlane XRDs,
+// Compositions and CRDs are ready to provision GameServers
+type PlatformHealth struct {
+	// Status is healthy, degraded (warnings only) or unhealthy
+	Status    string          `json:"status"`
+	Checks    []PlatformCheck `json:"checks"`
+	CheckedAt time.Time       `json:"checkedAt"`
+}
+
+// getPlatformHealth checks the platform layer GameServers depend on. It
+// responds 503 when GameServers cannot be provisioned.
+func (s *Server) getPlatformHealth(c *gin.Context) {
+	health := s.checkPlatform(context.TODO())
+	status := http.StatusOK
+	if health.Status == "unhealthy" {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, health)
+}
+
+// checkPlatform runs every platform check
+func (s *Server) checkPlatform(ctx context.Context) PlatformHealth {
+	checks := []PlatformCheck{s.checkCrossplane(ctx)}
+	checks = append(checks, s.checkPackages(ctx, "Provider", requiredProviders)...)
+	checks = append(checks, s.checkPackages(ctx, "Function", requiredFunctions)...)
+	checks = append(checks, s.checkCompositionLayer(ctx)...)
+	checks = append(checks, s.checkPlatformCRDs(ctx)...)
+
+	health := PlatformHealth{Status: "healthy", Checks: checks, CheckedAt: time.Now().UTC()}
+	for _, check := range checks {
+		switch {
+		case check.Status == checkError:
+			health.Status = "unhealthy"
+		case check.Status == checkWarning && health.Status == "healthy":
+			health.Status = "degraded"
+		}
+	}
+	return health
+}
+
+// checkCrossplane verifies the Crossplane deployment is available
+func (s *Server) checkCrossplane(ctx context.Context) PlatformCheck {
+	namespace := crossplaneNamespace()
+	check := PlatformCheck{Name: "crossplane"}
+	deployment, err := s.kubeClient.AppsV1().Deployments(namespace).Get(ctx, "crossplane", metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		check.Status = checkError
+		check.Message = fmt.Sprintf("Crossplane deployment not found in namespace %s", namespace)
+		check.Remediation = "Install Crossplane (helm install crossplane crossplane-stable/crossplane -n crossplane-system --create-namespace) or set CROSSPLANE_NAMESPACE"
+	case err != nil:
+		check.Status = checkError
+		check.Message = fmt.Sprintf("Failed to read Crossplane deployment: %v", err)
+	case deployment.Status.AvailableReplicas == 0:
+		check.Status = checkError
+		check.Message = fmt.Sprintf("Crossplane deployment in %s has no available replicas", namespace)
+		check.Remediation = fmt.Sprintf("kubectl -n %s describe deployment crossplane", namespace)
+	default:
+		check.Status = checkOK
+		check.Message = fmt.Sprintf("Crossplane is running (%d available)", deployment.Status.AvailableReplicas)
+	}
+	return check
+}
+
+// crossplaneNamespace is where Crossplane is installed
+func crossplaneNamespace() string {
+	if namespace := os.Getenv("CROSSPLANE_NAMESPACE"); namespace != "" {
+		return namespace
+	}
+	return defaultCrossplaneNamespace
+}
+
+// checkPackages verifies that Crossplane packages of a kind are installed
+// and healthy. Providers are matched by package, Functions by name.
+func (s *Server) checkPackages(ctx context.Context, kind string, required []string) []PlatformCheck {
+	version := "v1"
+	if kind == "Function" {
+		version = "v1beta1"
+	}
+	items, err := s.listPlatform(ctx, schema.GroupVersionKind{Group: "pkg.crossplane.io", Version: version, Kind: kind + "List"})
+	checks := []PlatformCheck{}
+	for _, name := range required {
+		check := PlatformCheck{Name: strings.ToLower(kind) + "/" + name}
+		if err != nil {
+			check.Status = checkError
+			check.Message = fmt.Sprintf("Failed to list Crossplane %ss: %v", kind, err)
+			checks = append(checks, check)
+			continue
+		}
+		var found *unstructured.Unstructured
+		for i := range items {
+			pkg, _, _ := unstructured.NestedString(items[i].Object, "spec", "package")
+			if items[i].GetName() == name || (kind == "Provider" && strings.Contains(pkg, "/"+name)) {
+				found = &items[i]
+				break
+			}
+		}
+		switch {
+		case found == nil:
+			check.Status = checkError
+			check.Message = fmt.Sprintf("%s %s is not installed", kind, name)
+			check.Remediation = fmt.Sprintf("Install the %s %s (see crossplane/examples)", strings.ToLower(kind), name)
+		case !conditionTrue(found, "Installed") || !conditionTrue(found, "Healthy"):
+			check.Status = checkError
+			check.Message = fmt.Sprintf("%s %s is installed but not healthy", kind, found.GetName())
+			check.Remediation = fmt.Sprintf("kubectl describe %s.pkg.crossplane.io %s", strings.ToLower(kind), found.GetName())
+		default:
+			check.Status = checkOK
+			check.Message = fmt.Sprintf("%s %s is healthy", kind, found.GetName())
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// checkCompositionLayer verifies the parent XRD and, for every game type,
+// the child XRD and a Composition
+func (s *Server) checkCompositionLayer(ctx context.Context) []PlatformCheck {
+	xrds, err := s.listPlatform(ctx, schema.GroupVersionKind{Group: "apiextensions.crossplane.io", Version: "v1", Kind: "CompositeResourceDefinitionList"})
+	if err != nil {
+		return []PlatformCheck{{Name: "xrds", Status: checkError, Message: fmt.Sprintf("Failed to list XRDs: %v", err)}}
+	}
+	compositions, err := s.listPlatform(ctx, schema.GroupVersionKind{Group: "apiextensions.crossplane.io", Version: "v1", Kind: "CompositionList"})
+	if err != nil {
+		return []PlatformCheck{{Name: "compositions", Status: checkError, Message: fmt.Sprintf("Failed to list Compositions: %v", err)}}
+	}
+	xrdByKind := map[string]*unstructured.Unstructured{}
+	for i := range xrds {
+		kind, _, _ := unstructured.NestedString(xrds[i].Object, "spec", "names", "kind")
+		xrdByKind[kind] = &xrds[i]
+	}
+	compositionsByKind := map[string][]string{}
+	for _, composition := range compositions {
+		kind, _, _ := unstructured.NestedString(composition.Object, "spec", "compositeTypeRef", "kind")
+		compositionsByKind[kind] = append(compositionsByKind[kind], composition.GetName())
+	}
+
+	checks := []PlatformCheck{
+		xrdCheck("xrd/gameserver", xrdByKind["XGameServer"], "XGameServer", "kubectl apply -f crossplane/gameplane/definition.yaml", true),
+		compositionCheck("composition/gameserver", "GameServer", "XGameServer", compositionsByKind["XGameServer"], "kubectl apply -f crossplane/gameplane/composition.yaml"),
+	}
+	for _, gameType := range supportedGameTypes() {
+		def, _ := lookupGame(gameType)
+		checks = append(checks,
+			xrdCheck("xrd/"+gameType, xrdByKind[def.ChildKind], def.ChildKind, fmt.Sprintf("kubectl apply -f crossplane/games/%s/definition.yaml", gameType), false),
+			compositionCheck("composition/"+gameType, "gameType "+gameType, def.ChildKind, compositionsByKind[def.ChildKind], fmt.Sprintf("kubectl apply -f crossplane/games/%s/composition.yaml", gameType)),
+		)
+	}
+	return checks
+}
+
+// xrdCheck reports whether an XRD exists and is established; offered
+// additionally requires its claim to be served
+func xrdCheck(name string, xrd *unstructured.Unstructured, kind, remediation string, offered bool) PlatformCheck {
+	check := PlatformCheck{Name: name}
+	switch {
+	case xrd == nil:
+		check.Status = checkError
+		check.Message = fmt.Sprintf("XRD for %s missing", kind)
+		check.Remediation = remediation
+	case !conditionTrue(xrd, "Established"):
+		check.Status = checkError
+		check.Message = fmt.Sprintf("XRD %s is not established", xrd.GetName())
+		check.Remediation = fmt.Sprintf("kubectl describe xrd %s", xrd.GetName())
+	case offered && !conditionTrue(xrd, "Offered"):
+		check.Status = checkError
+		check.Message = fmt.Sprintf("XRD %s does not offer its GameServer claim", xrd.GetName())
+		check.Remediation = fmt.Sprintf("kubectl describe xrd %s", xrd.GetName())
+	default:
+		check.Status = checkOK
+		check.Message = fmt.Sprintf("XRD %s is established", xrd.GetName())
+	}
+	return check
+}
+
+// compositionCheck reports whether a Composition exists for kind
+func compositionCheck(name, subject, kind string, compositions []string, remediation string) PlatformCheck {
+	if len(compositions) == 0 {
+		return PlatformCheck{
+			Name:        name,
+			Status:      checkError,
+			Message:     fmt.Sprintf("Composition for %s missing", subject),
+			Remediation: fmt.Sprintf("%s (a Composition with compositeTypeRef kind %s)", remediation, kind),
+		}
+	}
+	sort.Strings(compositions)
+	return PlatformCheck{
+		Name:    name,
+		Status:  checkOK,
+		Message: fmt.Sprintf("Composed by %s", strings.Join(compositions, ", ")),
+	}
+}
+
+// checkPlatformCRDs verifies the GamePlane CRDs are established
+func (s *Server) checkPlatformCRDs(ctx context.Context) []PlatformCheck {
+	checks := []PlatformCheck{}
+	for _, crd := range platformCRDs {
+		check := PlatformCheck{Name: "crd/" + crd.name}
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"})
+		err := s.k8sClient.Get(ctx, client.ObjectKey{Name: crd.name}, obj)
+		missing := checkError
+		if crd.optional {
+			missing = checkWarning
+		}
+		switch {
+		case apierrors.IsNotFound(err):
+			check.Status = missing
+			check.Message = fmt.Sprintf("CRD %s is not installed", crd.name)
+			if crd.name == "gameservers.gameplane.kubelize.io" {
+				check.Remediation = "Crossplane creates it from the GameServer XRD; check xrd/gameserver"
+			} else {
+				check.Remediation = fmt.Sprintf("kubectl apply -f crossplane/gameplane/%s-definition.yaml", strings.TrimSuffix(crd.name, "s.gameplane.kubelize.io"))
+			}
+		case err != nil:
+			check.Status = checkError
+			check.Message = fmt.Sprintf("Failed to read CRD %s: %v", crd.name, err)
+		case !conditionTrue(obj, "Established"):
+			check.Status = missing
+			check.Message = fmt.Sprintf("CRD %s is not established", crd.name)
+			check.Remediation = fmt.Sprintf("kubectl describe crd %s", crd.name)
+		default:
+			check.Status = checkOK
+			check.Message = fmt.Sprintf("CRD %s is established", crd.name)
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// listPlatform lists cluster-scoped platform objects; an API that is not
+// served yields no objects rather than an error
+func (s *Server) listPlatform(ctx context.Context, gvk schema.GroupVersionKind) ([]unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk)
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// conditionTrue reports whether a status condition of obj is True
+func conditionTrue(obj *unstructured.Unstructured, conditionType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, raw := range conditions {
+		condition, ok := raw.(map[string]interface{})
+		if ok && condition["type"] == conditionType {
+			return condition["status"] == "True"
+		}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PlayerServer is a GameServer as a logged-in player sees it
+type PlayerServer struct {
+	PublicStatus
+	Namespace string `json:"namespace"`
+	Server    string `json:"server"`
+	// Whitelisted is set when the player is on the synced whitelist's
+	// manual entries or claims
+	Whitelisted bool `json:"whitelisted"`
+	Claimed     bool `json:"claimed"`
+	// OpenSlots are the whitelist slots still free to claim
+	OpenSlots int `json:"openSlots"`
+}
+
+// PlayerBan is a shared ban list entry naming the player
+type PlayerBan struct {
+	BanList string     `json:"banList"`
+	Reason  string     `json:"reason,omitempty"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// getPlayerMe returns the logged-in player, their session and standing
+func (s *Server) getPlayerMe(c *gin.Context) {
+	player := currentPlayer(c)
+	servers, err := s.playerServers(context.TODO(), player)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list servers: %v", err),
+		})
+		return
+	}
+	bans, err := s.playerBans(context.TODO(), player)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to read ban lists: %v", err),
+		})
+		return
+	}
+
+	whitelisted, claimed := 0, 0
+	for _, server := range servers {
+		if server.Whitelisted {
+			whitelisted++
+		}
+		if server.Claimed {
+			claimed++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"subject": player.Subject,
+		"steamId": player.SteamID,
+		"role":    player.Role,
+		"session": gin.H{
+			"issuedAt":  player.IssuedAt,
+			"expiresAt": player.ExpiresAt,
+		},
+		"stats": gin.H{
+			"whitelisted": whitelisted,
+			"claims":      claimed,
+			"bans":        len(bans),
+		},
+		"bans": bans,
+	})
+}
+
+// listPlayerServers returns the servers a player can see: public ones,
+// ones whitelisting them and ones with slots to claim
+func (s *Server) listPlayerServers(c *gin.Context) {
+	servers, err := s.playerServers(context.TODO(), currentPlayer(c))
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list servers: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items": servers,
+		"total": len(servers),
+	})
+}
+
+// claimWhitelistSlot adds the player to a server's whitelist while slots
+// are free
+func (s *Server) claimWhitelistSlot(c *gin.Context) {
+	s.changeWhitelistClaim(c, true)
+}
+
+// releaseWhitelistSlot removes the player's claim again
+func (s *Server) releaseWhitelistSlot(c *gin.Context) {
+	s.changeWhitelistClaim(c, false)
+}
+
+// changeWhitelistClaim adds or removes the player's claim and syncs the
+// whitelist. Claims are the only change the player role may make.
+func (s *Server) changeWhitelistClaim(c *gin.Context, claim bool) {
+	player := currentPlayer(c)
+	namespace, name := c.Param("namespace"), c.Param("name")
+	if state, err := s.currentMaintenance(context.TODO()); err == nil {
+		if window := state.window(namespace, time.Now()); window != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": window.Message,
+			})
+			return
+		}
+	}
+
+	obj, err := s.getGameServerObject(context.TODO(), namespace, name)
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+	config, _ := whitelistConfig(obj)
+	if config == nil || config.ClaimSlots == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "This server has no whitelist slots to claim",
+		})
+		return
+	}
+
+	held := -1
+	for i, entry := range config.Claims {
+		if entry.ID == player.SteamID {
+			held = i
+		}
+	}
+	switch {
+	case claim && held >= 0, !claim && held < 0:
+		c.JSON(http.StatusOK, gin.H{
+			"claimed": claim,
+		})
+		return
+	case claim && len(config.Claims) >= config.ClaimSlots:
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "Every whitelist slot is taken",
+		})
+		return
+	case claim:
+		now := time.Now().UTC()
+		config.Claims = append(config.Claims, PlayerEntry{ID: player.SteamID, Platform: "Steam", Since: &now})
+	default:
+		config.Claims = append(config.Claims[:held], config.Claims[held+1:]...)
+	}
+
+	if err := setWhitelistConfig(obj, config); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update whitelist: %v", err),
+		})
+		return
+	}
+
+	response := gin.H{"claimed": claim}
+	status, err := s.syncWhitelist(c.Request.Context(), obj)
+	if status != nil {
+		response["restartRequired"] = status.RestartRequired
+	}
+	if err != nil {
+		response["syncError"] = "The whitelist is updated on the next sync"
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// playerServers lists the local cluster's servers visible to the player
+func (s *Server) playerServers(ctx context.Context, player PlayerClaims) ([]PlayerServer, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return nil, err
+	}
+
+	servers := []PlayerServer{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		server := PlayerServer{
+			PublicStatus: publicStatus(obj),
+			Namespace:    obj.GetNamespace(),
+			Server:       obj.GetName(),
+		}
+		if config, _ := whitelistConfig(obj); config != nil {
+			for _, entry := range config.Allow {
+				server.Whitelisted = server.Whitelisted || entry.ID == player.SteamID
+			}
+			for _, entry := range config.Claims {
+				server.Claimed = server.Claimed || entry.ID == player.SteamID
+			}
+			server.Whitelisted = server.Whitelisted || server.Claimed
+			if free := config.ClaimSlots - len(config.Claims); free > 0 {
+				server.OpenSlots = free
+			}
+		}
+		public, _, _ := unstructured.NestedBool(obj.Object, "spec", "publicStatus")
+		if public || server.Whitelisted || server.OpenSlots > 0 {
+			servers = append(servers, server)
+		}
+	}
+	sort.Slice(servers, func(i, j int) bool {
+		if servers[i].Namespace != servers[j].Namespace {
+			return servers[i].Namespace < servers[j].Namespace
+		}
+		return servers[i].Server < servers[j].Server
+	})
+	return servers, nil
+}
+
+// playerBans lists the shared ban list entries naming the player
+func (s *Server) playerBans(ctx context.Context, player PlayerClaims) ([]PlayerBan, error) {
+	bans := []PlayerBan{}
+	banLists, err := s.loadAllBanLists(ctx)
+	// Installations without the BanList definition have no shared bans
+	if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+		return bans, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, banList := range banLists {
+		for _, entry := range banList.Spec.Entries {
+			if entry.ID == player.SteamID && !entry.expired(now) {
+				bans = append(bans, PlayerBan{BanList: banList.Name, Reason: entry.Reason, Expires: entry.Expires})
+			}
+		}
+	}
+	return bans, nil
+}
@@ -0,0 +1,267 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PlayerEntry is one player on a list such as a whitelist or ban list
+type PlayerEntry struct {
+	// ID is the player's platform ID (a SteamID64, or a UUID for Minecraft)
+	ID string `json:"id" binding:"required"`
+	// Platform is the ID's platform, for games that support several
+	Platform string `json:"platform,omitempty"`
+	// Name is a free-form hint about who the player is
+	Name string `json:"name,omitempty"`
+	// Reason, Since and Expires describe a ban; a ban without Expires is
+	// permanent
+	Reason  string     `json:"reason,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// Timestamp layouts of the games' ban files
+const (
+	sdtdBanTimeLayout      = "2006-01-02 15:04:05"
+	minecraftBanTimeLayout = "2006-01-02 15:04:05 -0700"
+)
+
+// sdtdPermanentBan is the unban date 7 Days to Die files use for permanent
+// bans
+const sdtdPermanentBan = "9999-12-31 23:59:59"
+
+// key identifies an entry regardless of its name. Platform IDs do not
+// overlap, and list files without platforms still match entries with them.
+func (p PlayerEntry) key() string {
+	return strings.ToLower(p.ID)
+}
+
+// validatePlayerEntry checks an entry can be written to any list format
+func validatePlayerEntry(entry PlayerEntry) error {
+	if entry.ID == "" || strings.ContainsAny(entry.ID, " \t\r\n\"'<>/,") {
+		return fmt.Errorf("invalid player id %q", entry.ID)
+	}
+	if strings.ContainsAny(entry.Name, "\r\n") || strings.ContainsAny(entry.Reason, "\r\n") {
+		return fmt.Errorf("player name and reason for %s must be a single line", entry.ID)
+	}
+	return nil
+}
+
+// expired reports whether a ban has run out
+func (p PlayerEntry) expired(now time.Time) bool {
+	return p.Expires != nil && !p.Expires.After(now)
+}
+
+// sortPlayers orders entries by ID so rendered files are stable
+func sortPlayers(entries []PlayerEntry) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key() < entries[j].key() })
+}
+
+// parsePlayerList reads entries from the game's file format
+func parsePlayerList(list *PlayerList, content []byte) ([]PlayerEntry, error) {
+	entries := []PlayerEntry{}
+	switch list.Format {
+	case "serveradmin-xml":
+		decoder := xml.NewDecoder(bytes.NewReader(content))
+		inSection := false
+		for {
+			token, err := decoder.Token()
+			if errors.Is(err, io.EOF) {
+				return entries, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			switch t := token.(type) {
+			case xml.StartElement:
+				if t.Name.Local == list.Section {
+					inSection = true
+					continue
+				}
+				if !inSection {
+					continue
+				}
+				entry := PlayerEntry{}
+				for _, attr := range t.Attr {
+					switch attr.Name.Local {
+					case "userid":
+						entry.ID = attr.Value
+					case "platform":
+						entry.Platform = attr.Value
+					case "name":
+						entry.Name = attr.Value
+					case "reason":
+						entry.Reason = attr.Value
+					case "unbandate":
+						if attr.Value == sdtdPermanentBan {
+							continue
+						}
+						if t, err := time.Parse(sdtdBanTimeLayout, attr.Value); err == nil {
+							entry.Expires = &t
+						}
+					}
+				}
+				if entry.ID != "" {
+					entries = append(entries, entry)
+				}
+				if err := decoder.Skip(); err != nil {
+					return nil, err
+				}
+			case xml.EndElement:
+				if t.Name.Local == list.Section {
+					inSection = false
+				}
+			}
+		}
+	case "id-list-txt":
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "//") || strings.HasPrefix(line, "#") {
+				continue
+			}
+			entries = append(entries, PlayerEntry{ID: line})
+		}
+		return entries, scanner.Err()
+	case "whitelist-json":
+		if len(bytes.TrimSpace(content)) == 0 {
+			return entries, nil
+		}
+		var players []struct {
+			UUID string `json:"uuid"`
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(content, &players); err != nil {
+			return nil, err
+		}
+		for _, p := range players {
+			entries = append(entries, PlayerEntry{ID: p.UUID, Name: p.Name})
+		}
+		return entries, nil
+	case "banned-players-json":
+		if len(bytes.TrimSpace(content)) == 0 {
+			return entries, nil
+		}
+		var bans []minecraftBan
+		if err := json.Unmarshal(content, &bans); err != nil {
+			return nil, err
+		}
+		for _, ban := range bans {
+			entry := PlayerEntry{ID: ban.UUID, Name: ban.Name, Reason: ban.Reason}
+			if t, err := time.Parse(minecraftBanTimeLayout, ban.Created); err == nil {
+				entry.Since = &t
+			}
+			if t, err := time.Parse(minecraftBanTimeLayout, ban.Expires); err == nil {
+				entry.Expires = &t
+			}
+			entries = append(entries, entry)
+		}
+		return entries, nil
+	}
+	return nil, fmt.Errorf("unsupported player list format %q", list.Format)
+}
+
+// minecraftBan is an entry of Minecraft's banned-players.json
+type minecraftBan struct {
+	UUID    string `json:"uuid"`
+	Name    string `json:"name"`
+	Created string `json:"created"`
+	Source  string `json:"source"`
+	Expires string `json:"expires"`
+	Reason  string `json:"reason"`
+}
+
+// renderPlayerList serializes entries in the game's format, keeping the rest
+// of an existing serveradmin.xml intact
+func renderPlayerList(list *PlayerList, existing []byte, entries []PlayerEntry) ([]byte, error) {
+	switch list.Format {
+	case "serveradmin-xml":
+		element := list.Element
+		if element == "" {
+			element = "user"
+		}
+		var section bytes.Buffer
+		section.WriteString("<" + list.Section + ">\n")
+		for _, entry := range entries {
+			platform := entry.Platform
+			if platform == "" {
+				platform = "Steam"
+			}
+			item := struct {
+				XMLName   xml.Name
+				Platform  string `xml:"platform,attr"`
+				UserID    string `xml:"userid,attr"`
+				Name      string `xml:"name,attr,omitempty"`
+				UnbanDate string `xml:"unbandate,attr,omitempty"`
+				Reason    string `xml:"reason,attr,omitempty"`
+			}{XMLName: xml.Name{Local: element}, Platform: platform, UserID: entry.ID, Name: entry.Name, Reason: entry.Reason}
+			if element == "blacklisted" {
+				item.UnbanDate = sdtdPermanentBan
+				if entry.Expires != nil {
+					item.UnbanDate = entry.Expires.UTC().Format(sdtdBanTimeLayout)
+				}
+			}
+			raw, err := xml.Marshal(item)
+			if err != nil {
+				return nil, err
+			}
+			section.WriteString("    ")
+			section.Write(raw)
+			section.WriteString("\n")
+		}
+		section.WriteString("  </" + list.Section + ">")
+		return replaceAdminToolsSection(existing, list.Section, section.String()), nil
+	case "id-list-txt":
+		var buf bytes.Buffer
+		if list.Header != "" {
+			buf.WriteString(list.Header + "\n")
+		}
+		for _, entry := range entries {
+			buf.WriteString(entry.ID + "\n")
+		}
+		return buf.Bytes(), nil
+	case "whitelist-json":
+		type player struct {
+			UUID string `json:"uuid"`
+			Name string `json:"name"`
+		}
+		players := make([]player, 0, len(entries))
+		for _, entry := range entries {
+			players = append(players, player{UUID: entry.ID, Name: entry.Name})
+		}
+		raw, err := json.MarshalIndent(players, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(raw, '\n'), nil
+	case "banned-players-json":
+		bans := make([]minecraftBan, 0, len(entries))
+		for _, entry := range entries {
+			ban := minecraftBan{UUID: entry.ID, Name: entry.Name, Source: "GamePlane", Expires: "forever", Reason: entry.Reason}
+			if entry.Since != nil {
+				ban.Created = entry.Since.Format(minecraftBanTimeLayout)
+			}
+			if entry.Expires != nil {
+				ban.Expires = entry.Expires.Format(minecraftBanTimeLayout)
+			}
+			if ban.Reason == "" {
+				ban.Reason = "Banned by an operator."
+			}
+			bans = append(bans, ban)
+		}
+		raw, err := json.MarshalIndent(bans, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(raw, '\n'), nil
+	}
+	return nil, fmt.Errorf("unsupported player list format %q", list.Format)
+}
@@ -0,0 +1,459 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// prepullLabel marks pre-pull DaemonSets and their pods with the name
+	// of the DaemonSet
+	prepullLabel = "gameplane.kubelize.io/prepull"
+
+	// warmPrepullName is the DaemonSet kept running so nodes joining later
+	// are warmed as well
+	warmPrepullName = "gameplane-prepull"
+
+	// prepullPauseImage keeps pre-pull pods running once the images are
+	// pulled by their init containers
+	prepullPauseImage = "registry.k8s.io/pause:3.9"
+
+	// defaultPrepullTimeout is how long nodes may take to pull every image
+	defaultPrepullTimeout = 30 * time.Minute
+
+	// prepullPollInterval is how often pull progress is checked
+	prepullPollInterval = 10 * time.Second
+)
+
+// Pre-pull steps, in order
+const (
+	prepullStepCreate  = "create-daemonset"
+	prepullStepPull    = "pull-images"
+	prepullStepCleanup = "cleanup"
+)
+
+// Image states on a node
+const (
+	prepullPending = "Pending"
+	prepullPulled  = "Pulled"
+	prepullFailed  = "Failed"
+)
+
+// prepullFailureReasons are waiting reasons of an image that cannot be
+// pulled
+var prepullFailureReasons = map[string]bool{
+	"ErrImagePull":      true,
+	"ImagePullBackOff":  true,
+	"InvalidImageName":  true,
+	"ErrImageNeverPull": true,
+}
+
+// PrepullRequest pulls game images onto nodes ahead of provisioning
+type PrepullRequest struct {
+	// GameTypes pulls the catalog images of these games; Images adds
+	// others. Without either, every catalog image is pulled.
+	GameTypes []string `json:"gameTypes,omitempty"`
+	Images    []string `json:"images,omitempty"`
+	// NodeSelector picks the nodes; all nodes by default, including
+	// tainted ones
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Warm keeps the pre-pull running so nodes added later are warmed too
+	Warm bool `json:"warm,omitempty"`
+	// Timeout is how long nodes may take to pull every image
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// PrepullStatus reports the pull state of one pre-pull on every node
+type PrepullStatus struct {
+	Name         string            `json:"name"`
+	Warm         bool              `json:"warm"`
+	Images       []string          `json:"images"`
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	Nodes        []PrepullNode     `json:"nodes"`
+	// Desired is how many nodes the pre-pull targets
+	Desired int `json:"desired"`
+	// Counts tallies images on all nodes by state
+	Counts map[string]int `json:"counts"`
+	// Settled is true once every node pulled every image or failed to
+	Settled bool `json:"settled"`
+}
+
+// PrepullNode is the state of each image on one node
+type PrepullNode struct {
+	Node   string              `json:"node"`
+	Images []PrepullImageState `json:"images"`
+}
+
+// PrepullImageState is the state of one image on a node
+type PrepullImageState struct {
+	Image   string `json:"image"`
+	State   string `json:"state"`
+	Message string `json:"message,omitempty"`
+}
+
+// validate checks a pre-pull request and resolves its images
+func (r *PrepullRequest) validate() ([]string, time.Duration, error) {
+	timeout := defaultPrepullTimeout
+	if r.Timeout != "" {
+		d, err := time.ParseDuration(r.Timeout)
+		if err != nil || d <= 0 {
+			return nil, 0, fmt.Errorf("invalid timeout %q", r.Timeout)
+		}
+		timeout = d
+	}
+	if _, err := labels.ValidatedSelectorFromSet(r.NodeSelector); err != nil {
+		return nil, 0, fmt.Errorf("invalid nodeSelector: %v", err)
+	}
+
+	gameTypes := r.GameTypes
+	if len(gameTypes) == 0 && len(r.Images) == 0 {
+		gameTypes = supportedGameTypes()
+	}
+	images := []string{}
+	for _, gameType := range gameTypes {
+		def, ok := lookupGame(gameType)
+		if !ok {
+			return nil, 0, fmt.Errorf("Unsupported game type: %s. Valid types: %s", gameType, strings.Join(supportedGameTypes(), ", "))
+		}
+		if !containsString(images, def.Image) {
+			images = append(images, def.Image)
+		}
+	}
+	for _, image := range r.Images {
+		if strings.TrimSpace(image) == "" || strings.ContainsAny(image, " \t") {
+			return nil, 0, fmt.Errorf("invalid image %q", image)
+		}
+		if !containsString(images, image) {
+			images = append(images, image)
+		}
+	}
+	return images, timeout, nil
+}
+
+// startPrepull pulls game images onto nodes as an operation. Each node
+// runs a pod whose init containers use the images, so the kubelet pulls
+// them; the pods are removed afterwards unless the pre-pull is warm.
+func (s *Server) startPrepull(c *gin.Context) {
+	var req PrepullRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid request body: %v", err),
+			})
+			return
+		}
+	}
+	images, timeout, err := req.validate()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	namespace := s.clusters.namespace
+	steps := []string{prepullStepCreate, prepullStepPull, prepullStepCleanup}
+	op := s.startOperation("prepull", namespace, strings.Join(images, ", "), steps, timeout+time.Minute, func(ctx context.Context, t *operationTracker) (interface{}, error) {
+		name := warmPrepullName
+		if !req.Warm {
+			name = warmPrepullName + "-" + t.op.ID
+		}
+		return s.runPrepull(ctx, t, name, images, req, timeout)
+	})
+	c.JSON(http.StatusAccepted, op)
+}
+
+// runPrepull creates the pre-pull DaemonSet, waits until every node has
+// settled and removes it again unless it is warm
+func (s *Server) runPrepull(ctx context.Context, t *operationTracker, name string, images []string, req PrepullRequest, timeout time.Duration) (interface{}, error) {
+	daemonSets := s.kubeClient.AppsV1().DaemonSets(s.clusters.namespace)
+	var status *PrepullStatus
+	report := func(latest *PrepullStatus) {
+		status = latest
+		t.update(func(op *Operation) { op.Result = latest })
+	}
+	cleanup := func() (string, error) {
+		if req.Warm {
+			return "Kept running to warm nodes added later", nil
+		}
+		// Use a fresh context so cleanup still happens after a timeout
+		propagation := metav1.DeletePropagationBackground
+		err := daemonSets.Delete(context.Background(), name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return "", err
+		}
+		return "Removed pre-pull pods", nil
+	}
+
+	err := t.step(prepullStepCreate, func() (string, error) {
+		daemonSet := prepullDaemonSet(s.clusters.namespace, name, images, req.NodeSelector)
+		existing, err := daemonSets.Get(ctx, name, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			_, err = daemonSets.Create(ctx, daemonSet, metav1.CreateOptions{})
+		case err == nil:
+			// A warm pre-pull is replaced by the new image list
+			existing.Labels = daemonSet.Labels
+			existing.Annotations = daemonSet.Annotations
+			existing.Spec.Template = daemonSet.Spec.Template
+			_, err = daemonSets.Update(ctx, existing, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Pulling %d images", len(images)), nil
+	})
+	if err != nil {
+		_, _ = cleanup()
+		return nil, err
+	}
+
+	err = t.step(prepullStepPull, func() (string, error) {
+		waitCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		ticker := time.NewTicker(prepullPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-waitCtx.Done():
+				return "", fmt.Errorf("nodes did not settle within %s", timeout)
+			case <-ticker.C:
+			}
+			daemonSet, err := daemonSets.Get(waitCtx, name, metav1.GetOptions{})
+			if err != nil {
+				return "", err
+			}
+			latest, err := s.prepullStatus(waitCtx, daemonSet)
+			if err != nil {
+				return "", err
+			}
+			report(latest)
+			if !latest.Settled {
+				continue
+			}
+			message := fmt.Sprintf("%d images pulled on %d nodes", latest.Counts[prepullPulled], len(latest.Nodes))
+			if latest.Counts[prepullFailed] > 0 {
+				return "", fmt.Errorf("%s, %d failed", message, latest.Counts[prepullFailed])
+			}
+			return message, nil
+		}
+	})
+	if err != nil {
+		_, _ = cleanup()
+		return status, err
+	}
+
+	err = t.step(prepullStepCleanup, cleanup)
+	return status, err
+}
+
+// getPrepulls reports the state of every pre-pull in the cluster, such as
+// the warm one. Only admins may see them.
+func (s *Server) getPrepulls(c *gin.Context) {
+	if !s.isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Only admins may list pre-pulls",
+		})
+		return
+	}
+	list, err := s.kubeClient.AppsV1().DaemonSets(s.clusters.namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: prepullLabel})
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list pre-pulls: %v", err),
+		})
+		return
+	}
+	items := make([]PrepullStatus, 0, len(list.Items))
+	for i := range list.Items {
+		status, err := s.prepullStatus(context.TODO(), &list.Items[i])
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to read pre-pull %s: %v", list.Items[i].Name, err),
+			})
+			return
+		}
+		items = append(items, *status)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(items),
+	})
+}
+
+// deletePrepull removes a pre-pull, such as the warm one. Pulled images
+// stay on the nodes until the kubelet garbage collects them.
+func (s *Server) deletePrepull(c *gin.Context) {
+	name := c.Param("name")
+	daemonSets := s.kubeClient.AppsV1().DaemonSets(s.clusters.namespace)
+	daemonSet, err := daemonSets.Get(context.TODO(), name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || (err == nil && daemonSet.Labels[prepullLabel] == "") {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Pre-pull not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get pre-pull: %v", err),
+		})
+		return
+	}
+	propagation := metav1.DeletePropagationBackground
+	if err := daemonSets.Delete(context.TODO(), name, metav1.DeleteOptions{PropagationPolicy: &propagation}); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete pre-pull: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Pre-pull %s deleted", name),
+	})
+}
+
+// prepullStatus reads the image states from the init containers of a
+// pre-pull's pods
+func (s *Server) prepullStatus(ctx context.Context, daemonSet *appsv1.DaemonSet) (*PrepullStatus, error) {
+	images := []string{}
+	for _, container := range daemonSet.Spec.Template.Spec.InitContainers {
+		images = append(images, container.Image)
+	}
+	status := &PrepullStatus{
+		Name:         daemonSet.Name,
+		Warm:         daemonSet.Name == warmPrepullName,
+		Images:       images,
+		NodeSelector: daemonSet.Spec.Template.Spec.NodeSelector,
+		Nodes:        []PrepullNode{},
+		Desired:      int(daemonSet.Status.DesiredNumberScheduled),
+		Counts:       map[string]int{},
+	}
+
+	pods, err := s.kubeClient.CoreV1().Pods(daemonSet.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: labels.Set{prepullLabel: daemonSet.Name}.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	settled := 0
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		node := PrepullNode{Node: pod.Spec.NodeName, Images: []PrepullImageState{}}
+		states := map[string]corev1.ContainerStatus{}
+		for _, container := range pod.Status.InitContainerStatuses {
+			states[container.Name] = container
+		}
+		// Init containers run in order, so images after one that cannot be
+		// pulled or started are never pulled
+		pulled, blocked := 0, ""
+		for i, image := range images {
+			state := PrepullImageState{Image: image, State: prepullPending}
+			container, ok := states[prepullContainerName(i)]
+			switch {
+			case blocked != "":
+				state.State = prepullFailed
+				state.Message = blocked
+			case !ok:
+			case container.ImageID != "":
+				state.State = prepullPulled
+				pulled++
+				if prepullStartFailed(container) {
+					blocked = fmt.Sprintf("Not pulled because %s could not start", image)
+				}
+			case container.State.Waiting != nil && prepullFailureReasons[container.State.Waiting.Reason]:
+				state.State = prepullFailed
+				state.Message = container.State.Waiting.Message
+				blocked = fmt.Sprintf("Not pulled because %s could not be pulled", image)
+			}
+			status.Counts[state.State]++
+			node.Images = append(node.Images, state)
+		}
+		if blocked != "" || pulled == len(images) {
+			settled++
+		}
+		status.Nodes = append(status.Nodes, node)
+	}
+	sort.Slice(status.Nodes, func(i, j int) bool {
+		return status.Nodes[i].Node < status.Nodes[j].Node
+	})
+	status.Settled = status.Desired > 0 && settled >= status.Desired
+	return status, nil
+}
+
+// prepullDaemonSet builds the DaemonSet pulling images onto the selected
+// nodes. Each image runs as an init container that exits right away, so
+// images need a shell.
+func prepullDaemonSet(namespace, name string, images []string, nodeSelector map[string]string) *appsv1.DaemonSet {
+	podLabels := map[string]string{
+		prepullLabel:                   name,
+		"app.kubernetes.io/managed-by": "gameplane-api",
+	}
+	initContainers := make([]corev1.Container, len(images))
+	for i, image := range images {
+		initContainers[i] = corev1.Container{
+			Name:            prepullContainerName(i),
+			Image:           image,
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			Command:         []string{"sh", "-c", "true"},
+		}
+	}
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    podLabels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{prepullLabel: name}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+				Spec: corev1.PodSpec{
+					NodeSelector:   nodeSelector,
+					Tolerations:    []corev1.Toleration{{Operator: corev1.TolerationOpExists}},
+					InitContainers: initContainers,
+					Containers: []corev1.Container{{
+						Name:  "pause",
+						Image: prepullPauseImage,
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceCPU:    resource.MustParse("1m"),
+								corev1.ResourceMemory: resource.MustParse("8Mi"),
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+// prepullStartFailed reports whether a pulled init container failed to run
+func prepullStartFailed(container corev1.ContainerStatus) bool {
+	if waiting := container.State.Waiting; waiting != nil {
+		switch waiting.Reason {
+		case "CrashLoopBackOff", "CreateContainerError", "RunContainerError":
+			return true
+		}
+	}
+	if terminated := container.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+		return true
+	}
+	return container.LastTerminationState.Terminated != nil && container.LastTerminationState.Terminated.ExitCode != 0
+}
+
+// prepullContainerName names the init container pulling the i-th image
+func prepullContainerName(i int) string {
+	return fmt.Sprintf("pull-%d", i)
+}
@@ -0,0 +1,598 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/gin-gonic/gin"
+	sprig "github.com/go-task/slim-sprig"
+	"github.com/kubelize/gameplane/api/internal/admission"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// compositionResourceNameAnnotation names a resource rendered by
+	// function-go-templating within its composition
+	compositionResourceNameAnnotation = "gotemplating.fn.crossplane.io/composition-resource-name"
+
+	// maxPreviewDepth bounds how many composites deep a preview follows
+	// composed composites, such as the per-game child of a GameServer
+	maxPreviewDepth = 4
+)
+
+var (
+	compositionRevisionGVK = schema.GroupVersionKind{Group: "apiextensions.crossplane.io", Version: "v1", Kind: "CompositionRevision"}
+	xrdListGVK             = schema.GroupVersionKind{Group: "apiextensions.crossplane.io", Version: "v1", Kind: "CompositeResourceDefinitionList"}
+	compositionListGVK     = schema.GroupVersionKind{Group: "apiextensions.crossplane.io", Version: "v1", Kind: "CompositionList"}
+)
+
+// yamlDocumentSeparator splits rendered templates into documents
+var yamlDocumentSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// CompositionPreview is what creating a GameServer would compose
+type CompositionPreview struct {
+	// Claim is the GameServer as the API server would store it
+	Claim        map[string]interface{} `json:"claim"`
+	Compositions []PreviewComposition   `json:"compositions"`
+	Resources    []PreviewResource      `json:"resources"`
+	Warnings     []string               `json:"warnings"`
+}
+
+// PreviewComposition is a Composition the preview ran for one composite
+type PreviewComposition struct {
+	Name string `json:"name"`
+	// Revision is the CompositionRevision used instead of the Composition
+	Revision      string        `json:"revision,omitempty"`
+	CompositeKind string        `json:"compositeKind"`
+	Composite     string        `json:"composite"`
+	Mode          string        `json:"mode"`
+	Steps         []PreviewStep `json:"steps"`
+}
+
+// PreviewStep is one step of a composition function pipeline
+type PreviewStep struct {
+	Step     string `json:"step"`
+	Function string `json:"function"`
+	// Rendered is false for functions the API cannot run
+	Rendered  bool   `json:"rendered"`
+	Resources int    `json:"resources"`
+	Message   string `json:"message,omitempty"`
+}
+
+// PreviewResource is a resource a composition would create
+type PreviewResource struct {
+	Composition string `json:"composition"`
+	Step        string `json:"step"`
+	// Name is the composition resource name
+	Name   string                 `json:"name"`
+	Object map[string]interface{} `json:"object"`
+}
+
+// previewGameServer runs a GameServer create request through a server-side
+// dry run and the Compositions it would select, returning the resources
+// that would be created without creating anything. Pipeline steps of
+// function-go-templating are rendered by the API; the resources of other
+// functions are missing from the preview. A request for an existing
+// GameServer is previewed as an update of it.
+func (s *Server) previewGameServer(c *gin.Context) {
+	var req struct {
+		APIVersion string            `json:"apiVersion"`
+		Kind       string            `json:"kind"`
+		Metadata   metav1.ObjectMeta `json:"metadata"`
+		Spec       GameServerSpec    `json:"spec"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if req.APIVersion == "" {
+		req.APIVersion = "gameplane.kubelize.io/v1alpha1"
+	}
+	if req.Kind == "" {
+		req.Kind = "GameServer"
+	}
+	if req.Metadata.Namespace == "" {
+		req.Metadata.Namespace = "default"
+	}
+	if !s.servedNamespaces(c.Request.Context())(req.Metadata.Namespace) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Not permitted to access namespace %s", req.Metadata.Namespace),
+		})
+		return
+	}
+	if req.Metadata.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "metadata.name is required",
+		})
+		return
+	}
+	def, ok := lookupGame(req.Spec.GameType)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Unsupported game type: %s. Valid types: %s", req.Spec.GameType, strings.Join(supportedGameTypes(), ", ")),
+		})
+		return
+	}
+	if err := validateProbes(def, req.Spec.Advanced.Probes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := validateDependencies(req.Spec.Dependencies); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	spec := buildGameServerSpec(req.Spec)
+	if def.SteamAppID > 0 {
+		env, err := s.steamCacheEnv(context.TODO())
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to read Steam cache: %v", err),
+			})
+			return
+		}
+		withSteamCacheEnv(spec, env)
+	}
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": req.APIVersion,
+			"kind":       req.Kind,
+			"metadata": map[string]interface{}{
+				"name":      req.Metadata.Name,
+				"namespace": req.Metadata.Namespace,
+			},
+			"spec": spec,
+		},
+	}
+	labels := admission.Labels(req.Metadata.Name, req.Spec.GameType)
+	for k, v := range req.Metadata.Labels {
+		labels[k] = v
+	}
+	obj.SetLabels(labels)
+	if err := admission.Validate(obj); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	preview := &CompositionPreview{Compositions: []PreviewComposition{}, Resources: []PreviewResource{}, Warnings: []string{}}
+	err := s.k8sClient.Create(context.TODO(), obj, client.DryRunAll)
+	if apierrors.IsAlreadyExists(err) {
+		var live *unstructured.Unstructured
+		live, err = s.getGameServerObject(context.TODO(), req.Metadata.Namespace, req.Metadata.Name)
+		if err == nil {
+			liveSpec, _, _ := unstructured.NestedMap(live.Object, "spec")
+			for _, field := range crossplaneSpecFields {
+				if value, found := liveSpec[field]; found {
+					spec[field] = value
+				}
+			}
+			obj.SetResourceVersion(live.GetResourceVersion())
+			obj.SetAnnotations(live.GetAnnotations())
+			err = s.k8sClient.Update(context.TODO(), obj, client.DryRunAll)
+			preview.Warnings = append(preview.Warnings, fmt.Sprintf("GameServer %s/%s exists, so it was previewed as an update", req.Metadata.Namespace, req.Metadata.Name))
+		}
+	}
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Server-side dry run failed: %v", err),
+		})
+		return
+	}
+	preview.Claim = obj.Object
+
+	if err := s.renderClaim(context.TODO(), obj, preview); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to render compositions: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// compositionRenderer renders composites with the XRDs and Compositions of
+// the cluster
+type compositionRenderer struct {
+	s            *Server
+	xrdByKind    map[string]*unstructured.Unstructured
+	compositions []unstructured.Unstructured
+	preview      *CompositionPreview
+}
+
+// renderClaim renders the composite resource Crossplane would create for a
+// claim, following composed composites down to their resources
+func (s *Server) renderClaim(ctx context.Context, claim *unstructured.Unstructured, preview *CompositionPreview) error {
+	xrds, err := s.listPlatform(ctx, xrdListGVK)
+	if err != nil {
+		return fmt.Errorf("failed to list XRDs: %w", err)
+	}
+	compositions, err := s.listPlatform(ctx, compositionListGVK)
+	if err != nil {
+		return fmt.Errorf("failed to list Compositions: %w", err)
+	}
+	r := &compositionRenderer{s: s, xrdByKind: map[string]*unstructured.Unstructured{}, compositions: compositions, preview: preview}
+	var claimXRD *unstructured.Unstructured
+	for i := range xrds {
+		kind, _, _ := unstructured.NestedString(xrds[i].Object, "spec", "names", "kind")
+		r.xrdByKind[kind] = &xrds[i]
+		claimKind, _, _ := unstructured.NestedString(xrds[i].Object, "spec", "claimNames", "kind")
+		group, _, _ := unstructured.NestedString(xrds[i].Object, "spec", "group")
+		if claimKind == claim.GetKind() && group == claim.GroupVersionKind().Group {
+			claimXRD = &xrds[i]
+		}
+	}
+	if claimXRD == nil {
+		preview.Warnings = append(preview.Warnings, fmt.Sprintf("No XRD offers %s claims, so only the server-side dry run ran", claim.GetKind()))
+		return nil
+	}
+
+	// Crossplane names the composite after the claim with a random suffix
+	kind, _, _ := unstructured.NestedString(claimXRD.Object, "spec", "names", "kind")
+	composite := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": claim.GetAPIVersion(),
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name": claim.GetName(),
+			"labels": map[string]interface{}{
+				"crossplane.io/claim-name":      claim.GetName(),
+				"crossplane.io/claim-namespace": claim.GetNamespace(),
+				"crossplane.io/composite":       claim.GetName(),
+			},
+		},
+	}}
+	spec, _, _ := unstructured.NestedMap(claim.Object, "spec")
+	spec["claimRef"] = map[string]interface{}{
+		"apiVersion": claim.GetAPIVersion(),
+		"kind":       claim.GetKind(),
+		"name":       claim.GetName(),
+		"namespace":  claim.GetNamespace(),
+	}
+	composite.Object["spec"] = spec
+	return r.render(ctx, composite, 0)
+}
+
+// render runs the Composition selected for a composite and renders the
+// composites among its resources in turn
+func (r *compositionRenderer) render(ctx context.Context, composite *unstructured.Unstructured, depth int) error {
+	if depth >= maxPreviewDepth {
+		r.preview.Warnings = append(r.preview.Warnings, fmt.Sprintf("Stopped following composites at %s %s", composite.GetKind(), composite.GetName()))
+		return nil
+	}
+	composition, revision, err := r.selectComposition(ctx, composite)
+	if err != nil {
+		r.preview.Warnings = append(r.preview.Warnings, err.Error())
+		return nil
+	}
+	mode, _, _ := unstructured.NestedString(composition.Object, "spec", "mode")
+	if mode == "" {
+		mode = "Resources"
+	}
+	result := PreviewComposition{
+		Name:          composition.GetName(),
+		Revision:      revision,
+		CompositeKind: composite.GetKind(),
+		Composite:     composite.GetName(),
+		Mode:          mode,
+		Steps:         []PreviewStep{},
+	}
+	if name := composition.GetLabels()["crossplane.io/composition-name"]; revision != "" && name != "" {
+		result.Name = name
+	}
+	if mode != "Pipeline" {
+		r.preview.Compositions = append(r.preview.Compositions, result)
+		r.preview.Warnings = append(r.preview.Warnings, fmt.Sprintf("Composition %s uses %s mode; only function pipelines are rendered", composition.GetName(), mode))
+		return nil
+	}
+
+	// Functions see the request as JSON, so numbers are floats
+	observed, err := jsonRoundTrip(composite.Object)
+	if err != nil {
+		return err
+	}
+	desired := map[string]interface{}{}
+	request := map[string]interface{}{
+		"observed": map[string]interface{}{
+			"composite": map[string]interface{}{"resource": observed},
+			"resources": map[string]interface{}{},
+		},
+		"desired": map[string]interface{}{
+			"composite": map[string]interface{}{"resource": map[string]interface{}{}},
+			"resources": desired,
+		},
+		"context":        map[string]interface{}{},
+		"extraResources": map[string]interface{}{},
+	}
+
+	composed := []PreviewResource{}
+	pipeline, _, _ := unstructured.NestedSlice(composition.Object, "spec", "pipeline")
+	for _, raw := range pipeline {
+		stepSpec, _ := raw.(map[string]interface{})
+		step := PreviewStep{}
+		step.Step, _, _ = unstructured.NestedString(stepSpec, "step")
+		step.Function, _, _ = unstructured.NestedString(stepSpec, "functionRef", "name")
+		input, _, _ := unstructured.NestedMap(stepSpec, "input")
+		inputKind, _, _ := unstructured.NestedString(input, "kind")
+		source, _, _ := unstructured.NestedString(input, "source")
+		text, _, _ := unstructured.NestedString(input, "inline", "template")
+
+		switch {
+		case inputKind == "GoTemplate" && source == "Inline":
+			resources, err := renderGoTemplate(text, request)
+			if err != nil {
+				step.Message = err.Error()
+				r.preview.Warnings = append(r.preview.Warnings, fmt.Sprintf("Step %s of Composition %s failed: %v", step.Step, composition.GetName(), err))
+				break
+			}
+			step.Rendered = true
+			for i, resource := range resources {
+				name := resourceName(resource, fmt.Sprintf("%s-%d", step.Step, i))
+				desired[name] = map[string]interface{}{"resource": resource}
+				composed = append(composed, PreviewResource{Composition: result.Name, Step: step.Step, Name: name, Object: resource})
+				step.Resources++
+			}
+		case inputKind == "GoTemplate":
+			step.Message = fmt.Sprintf("Templates from source %s are not rendered", source)
+			r.preview.Warnings = append(r.preview.Warnings, fmt.Sprintf("Step %s of Composition %s reads its templates from %s, so its resources are missing", step.Step, composition.GetName(), source))
+		case strings.HasSuffix(step.Function, "auto-ready"):
+			step.Message = "Only marks resources ready"
+		default:
+			step.Message = fmt.Sprintf("Function %s cannot run in the API", step.Function)
+			r.preview.Warnings = append(r.preview.Warnings, fmt.Sprintf("Step %s of Composition %s runs %s, so its changes are missing", step.Step, composition.GetName(), step.Function))
+		}
+		result.Steps = append(result.Steps, step)
+	}
+	r.preview.Compositions = append(r.preview.Compositions, result)
+
+	for _, resource := range composed {
+		r.preview.Resources = append(r.preview.Resources, PreviewResource{
+			Composition: resource.Composition,
+			Step:        resource.Step,
+			Name:        resource.Name,
+			Object:      redactSecret(resource.Object),
+		})
+		if child := r.composedComposite(resource.Object); child != nil {
+			if err := r.render(ctx, child, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// composedComposite returns a composed resource that is itself a
+// composite, directly or as the manifest of a provider-kubernetes Object
+func (r *compositionRenderer) composedComposite(resource map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: resource}
+	if obj.GetKind() == "Object" && strings.HasPrefix(obj.GetAPIVersion(), "kubernetes.crossplane.io/") {
+		manifest, found, _ := unstructured.NestedMap(resource, "spec", "forProvider", "manifest")
+		if !found {
+			return nil
+		}
+		obj = &unstructured.Unstructured{Object: manifest}
+	}
+	if _, ok := r.xrdByKind[obj.GetKind()]; !ok {
+		return nil
+	}
+	return obj
+}
+
+// selectComposition picks the Composition Crossplane would use for a
+// composite: its pinned revision, its compositionRef, its selector, the
+// XRD's default or the only Composition for its kind
+func (r *compositionRenderer) selectComposition(ctx context.Context, composite *unstructured.Unstructured) (*unstructured.Unstructured, string, error) {
+	if revision, _, _ := unstructured.NestedString(composite.Object, "spec", "compositionRevisionRef", "name"); revision != "" {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(compositionRevisionGVK)
+		if err := r.s.k8sClient.Get(ctx, client.ObjectKey{Name: revision}, obj); err != nil {
+			return nil, "", fmt.Errorf("failed to get CompositionRevision %s: %w", revision, err)
+		}
+		return obj, revision, nil
+	}
+
+	candidates := []*unstructured.Unstructured{}
+	for i := range r.compositions {
+		apiVersion, _, _ := unstructured.NestedString(r.compositions[i].Object, "spec", "compositeTypeRef", "apiVersion")
+		kind, _, _ := unstructured.NestedString(r.compositions[i].Object, "spec", "compositeTypeRef", "kind")
+		if apiVersion == composite.GetAPIVersion() && kind == composite.GetKind() {
+			candidates = append(candidates, &r.compositions[i])
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].GetName() < candidates[j].GetName() })
+	byName := func(name string) (*unstructured.Unstructured, string, error) {
+		for _, candidate := range candidates {
+			if candidate.GetName() == name {
+				return candidate, "", nil
+			}
+		}
+		return nil, "", fmt.Errorf("Composition %s for %s not found", name, composite.GetKind())
+	}
+
+	if name, _, _ := unstructured.NestedString(composite.Object, "spec", "compositionRef", "name"); name != "" {
+		return byName(name)
+	}
+	if selector, found, _ := unstructured.NestedStringMap(composite.Object, "spec", "compositionSelector", "matchLabels"); found {
+		for _, candidate := range candidates {
+			labels := candidate.GetLabels()
+			matches := true
+			for k, v := range selector {
+				matches = matches && labels[k] == v
+			}
+			if matches {
+				return candidate, "", nil
+			}
+		}
+		return nil, "", fmt.Errorf("no Composition for %s matches the composition selector", composite.GetKind())
+	}
+	if xrd := r.xrdByKind[composite.GetKind()]; xrd != nil {
+		if name, _, _ := unstructured.NestedString(xrd.Object, "spec", "defaultCompositionRef", "name"); name != "" {
+			return byName(name)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil, "", fmt.Errorf("no Composition for %s", composite.GetKind())
+	case 1:
+		return candidates[0], "", nil
+	}
+	return nil, "", fmt.Errorf("%d Compositions for %s and none is selected", len(candidates), composite.GetKind())
+}
+
+// renderGoTemplate renders a function-go-templating template against a
+// function request, returning the composed resources. Documents that set
+// the composite's status, connection details or the pipeline context are
+// not resources and are skipped.
+func renderGoTemplate(text string, request map[string]interface{}) ([]map[string]interface{}, error) {
+	tmpl := template.New("template")
+	tmpl, err := tmpl.Funcs(previewTemplateFuncs(tmpl)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, request); err != nil {
+		return nil, err
+	}
+
+	composite, _, _ := unstructured.NestedMap(request, "observed", "composite", "resource")
+	resources := []map[string]interface{}{}
+	for i, document := range yamlDocumentSeparator.Split(out.String(), -1) {
+		if strings.TrimSpace(document) == "" {
+			continue
+		}
+		resource := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(document), &resource); err != nil {
+			return nil, fmt.Errorf("document %d is not valid YAML: %w", i+1, err)
+		}
+		if len(resource) == 0 {
+			continue
+		}
+		apiVersion, _ := resource["apiVersion"].(string)
+		kind, _ := resource["kind"].(string)
+		if strings.HasPrefix(apiVersion, "meta.gotemplating.fn.crossplane.io/") || kind == "CompositeConnectionDetails" {
+			continue
+		}
+		if apiVersion == composite["apiVersion"] && kind == composite["kind"] {
+			continue
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+// previewTemplateFuncs are the template functions of function-go-templating
+func previewTemplateFuncs(tmpl *template.Template) template.FuncMap {
+	funcs := sprig.TxtFuncMap()
+	funcs["randAlphaNum"] = randomAlphaNum
+	funcs["toYaml"] = func(value interface{}) (string, error) {
+		out, err := yaml.Marshal(value)
+		return string(out), err
+	}
+	funcs["fromYaml"] = func(text string) (map[string]interface{}, error) {
+		value := map[string]interface{}{}
+		err := yaml.Unmarshal([]byte(text), &value)
+		return value, err
+	}
+	funcs["setResourceNameAnnotation"] = func(name string) string {
+		return fmt.Sprintf("%s: %s", compositionResourceNameAnnotation, name)
+	}
+	funcs["getResourceCondition"] = func(conditionType string, resource map[string]interface{}) map[string]interface{} {
+		conditions, _, _ := unstructured.NestedSlice(resource, "resource", "status", "conditions")
+		for _, raw := range conditions {
+			if condition, ok := raw.(map[string]interface{}); ok && condition["type"] == conditionType {
+				return condition
+			}
+		}
+		return map[string]interface{}{"type": conditionType, "status": "Unknown"}
+	}
+	funcs["getCompositeResource"] = func(request map[string]interface{}) map[string]interface{} {
+		composite, _, _ := unstructured.NestedMap(request, "observed", "composite", "resource")
+		return composite
+	}
+	funcs["getComposedResource"] = func(request map[string]interface{}, name string) map[string]interface{} {
+		resource, _, _ := unstructured.NestedMap(request, "observed", "resources", name, "resource")
+		return resource
+	}
+	funcs["include"] = func(name string, data interface{}) (string, error) {
+		var out bytes.Buffer
+		err := tmpl.ExecuteTemplate(&out, name, data)
+		return out.String(), err
+	}
+	return funcs
+}
+
+// resourceName returns a rendered resource's composition resource name
+func resourceName(resource map[string]interface{}, fallback string) string {
+	if name, _, _ := unstructured.NestedString(resource, "metadata", "annotations", compositionResourceNameAnnotation); name != "" {
+		return name
+	}
+	return fallback
+}
+
+// redactSecret hides the values of a rendered Secret, including one
+// wrapped in a provider-kubernetes Object
+func redactSecret(resource map[string]interface{}) map[string]interface{} {
+	secret := resource
+	manifest, wrapped, _ := unstructured.NestedMap(resource, "spec", "forProvider", "manifest")
+	if wrapped {
+		secret = manifest
+	}
+	if secret["kind"] != "Secret" || secret["apiVersion"] != "v1" {
+		return resource
+	}
+	for _, field := range []string{"data", "stringData"} {
+		if values, ok := secret[field].(map[string]interface{}); ok {
+			for k := range values {
+				values[k] = redactedValue
+			}
+		}
+	}
+	if wrapped {
+		_ = unstructured.SetNestedMap(resource, secret, "spec", "forProvider", "manifest")
+	}
+	return resource
+}
+
+// jsonRoundTrip returns a copy of value as decoded from JSON
+func jsonRoundTrip(value map[string]interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]interface{}{}
+	err = json.Unmarshal(raw, &out)
+	return out, err
+}
+
+// randomAlphaNum returns n random letters and digits
+func randomAlphaNum(n int) string {
+	const letters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	out := make([]byte, n)
+	for i := range out {
+		k, err := rand.Int(rand.Reader, big.NewInt(int64(len(letters))))
+		if err != nil {
+			k = big.NewInt(0)
+		}
+		out[i] = letters[k.Int64()]
+	}
+	return string(out)
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// probeLimits bound each probe timing, mirroring the XRD
+var probeLimits = []struct {
+	field    string
+	value    func(*ProbeSettings) *int32
+	min, max int32
+}{
+	{"initialDelaySeconds", func(p *ProbeSettings) *int32 { return p.InitialDelaySeconds }, 0, 3600},
+	{"periodSeconds", func(p *ProbeSettings) *int32 { return p.PeriodSeconds }, 1, 3600},
+	{"timeoutSeconds", func(p *ProbeSettings) *int32 { return p.TimeoutSeconds }, 1, 600},
+	{"failureThreshold", func(p *ProbeSettings) *int32 { return p.FailureThreshold }, 1, 1000},
+}
+
+// validateProbes checks spec.advanced.probes of a GameServer
+func validateProbes(def GameDefinition, probes *GameServerProbes) error {
+	if probes == nil {
+		return nil
+	}
+	for _, probe := range []struct {
+		name     string
+		settings *ProbeSettings
+	}{{"startup", probes.Startup}, {"liveness", probes.Liveness}, {"readiness", probes.Readiness}} {
+		if probe.settings == nil {
+			continue
+		}
+		for _, limit := range probeLimits {
+			value := limit.value(probe.settings)
+			if value != nil && (*value < limit.min || *value > limit.max) {
+				return fmt.Errorf("spec.advanced.probes.%s.%s must be between %d and %d", probe.name, limit.field, limit.min, limit.max)
+			}
+		}
+	}
+	if probes.QueryReadiness != nil && *probes.QueryReadiness && def.Query == nil {
+		return fmt.Errorf("spec.advanced.probes.queryReadiness needs a query protocol, which %s does not have", def.DisplayName)
+	}
+	return nil
+}
+
+// probesSpec converts spec.advanced.probes for the claim
+func probesSpec(probes *GameServerProbes) (map[string]interface{}, error) {
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(probes)
+}
+
+// gameServerProbes reads spec.advanced.probes of a GameServer, nil if unset
+func gameServerProbes(obj *unstructured.Unstructured) (*GameServerProbes, error) {
+	raw, found, err := unstructured.NestedMap(obj.Object, "spec", "advanced", "probes")
+	if err != nil || !found {
+		return nil, err
+	}
+	probes := &GameServerProbes{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, probes); err != nil {
+		return nil, err
+	}
+	return probes, nil
+}
@@ -0,0 +1,606 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var projectGVK = schema.GroupVersionKind{
+	Group:   "gameplane.kubelize.io",
+	Version: "v1alpha1",
+	Kind:    "Project",
+}
+
+// projectActions are the bulk actions a project can run on its members
+var projectActions = []string{"restart", "broadcast"}
+
+// ProjectSpec groups GameServers, possibly from several namespaces
+type ProjectSpec struct {
+	DisplayName string          `json:"displayName,omitempty"`
+	Description string          `json:"description,omitempty"`
+	Members     []ProjectMember `json:"members"`
+	// Notifications are sent for events of any member, in addition to the
+	// members' own subscriptions
+	Notifications *ProjectNotifications `json:"notifications,omitempty"`
+}
+
+// ProjectMember references a GameServer
+type ProjectMember struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// ProjectNotifications are a project's shared notification settings
+type ProjectNotifications struct {
+	Webhooks []webhookTarget `json:"webhooks,omitempty"`
+	Emails   []string        `json:"emails,omitempty"`
+	// Events limits notifications to these event types; empty sends all
+	Events []string `json:"events,omitempty"`
+}
+
+// ProjectStatus summarizes a project's members
+type ProjectStatus struct {
+	Servers int `json:"servers"`
+	Ready   int `json:"ready"`
+	// Missing counts members whose GameServer no longer exists
+	Missing  int                   `json:"missing"`
+	Players  int                   `json:"players"`
+	Capacity int                   `json:"capacity"`
+	Phases   map[string]int        `json:"phases"`
+	Members  []ProjectMemberStatus `json:"members"`
+}
+
+// ProjectMemberStatus is the live state of one member
+type ProjectMemberStatus struct {
+	ProjectMember
+	GameType string `json:"gameType,omitempty"`
+	Phase    string `json:"phase,omitempty"`
+	Ready    bool   `json:"ready"`
+	Players  int    `json:"players"`
+	Capacity int    `json:"capacity,omitempty"`
+	Missing  bool   `json:"missing,omitempty"`
+}
+
+// Project collects GameServers that are managed together, e.g. "our
+// Valheim cluster" or "tournament servers". Projects are cluster-scoped.
+type Project struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Spec              ProjectSpec    `json:"spec"`
+	Status            *ProjectStatus `json:"status,omitempty"`
+}
+
+// listProjects returns all projects with their summaries
+func (s *Server) listProjects(c *gin.Context) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(projectGVK.GroupVersion().WithKind("ProjectList"))
+	if err := s.k8sClient.List(context.TODO(), list); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list Projects: %v", err),
+		})
+		return
+	}
+
+	projects := make([]Project, 0, len(list.Items))
+	for i := range list.Items {
+		project, err := projectFromUnstructured(&list.Items[i])
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to convert Project: %v", err),
+			})
+			return
+		}
+		if project.Status, err = s.projectStatus(context.TODO(), project); err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to summarize Project %s: %v", project.Name, err),
+			})
+			return
+		}
+		projects = append(projects, *project)
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Name < projects[j].Name })
+
+	items, ok := selectFields(c, projects)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(projects),
+	})
+}
+
+// createProject creates a project
+func (s *Server) createProject(c *gin.Context) {
+	var project Project
+	if err := c.ShouldBindJSON(&project); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if project.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "metadata.name is required",
+		})
+		return
+	}
+	if err := validateProjectSpec(&project.Spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	project.APIVersion = projectGVK.GroupVersion().String()
+	project.Kind = projectGVK.Kind
+	project.Namespace = ""
+	project.Status = nil
+	obj, err := projectToUnstructured(&project)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := s.k8sClient.Create(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to create Project: %v", err),
+		})
+		return
+	}
+	s.respondWithProject(c, http.StatusCreated, obj)
+}
+
+// getProject returns a project with its live summary
+func (s *Server) getProject(c *gin.Context) {
+	obj, ok := s.loadProject(c)
+	if !ok {
+		return
+	}
+	s.respondWithProject(c, http.StatusOK, obj)
+}
+
+// updateProject replaces a project's spec
+func (s *Server) updateProject(c *gin.Context) {
+	var req struct {
+		Spec ProjectSpec `json:"spec"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if err := validateProjectSpec(&req.Spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	obj, ok := s.loadProject(c)
+	if !ok {
+		return
+	}
+	s.saveProjectSpec(c, obj, req.Spec)
+}
+
+// deleteProject removes a project; its GameServers are left alone
+func (s *Server) deleteProject(c *gin.Context) {
+	obj, ok := s.loadProject(c)
+	if !ok {
+		return
+	}
+	if err := s.k8sClient.Delete(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete Project: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Project deleted successfully",
+	})
+}
+
+// addProjectMember adds an existing GameServer to a project
+func (s *Server) addProjectMember(c *gin.Context) {
+	var member ProjectMember
+	if err := c.ShouldBindJSON(&member); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if member.Namespace == "" || member.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "namespace and name are required",
+		})
+		return
+	}
+	if scope, err := s.readableNamespaces(c); err != nil || !scope.allows(member.Namespace) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Not permitted to access namespace %s", member.Namespace),
+		})
+		return
+	}
+	if _, err := s.getGameServerObject(context.TODO(), member.Namespace, member.Name); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+
+	obj, ok := s.loadProject(c)
+	if !ok {
+		return
+	}
+	project, err := projectFromUnstructured(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to convert Project: %v", err),
+		})
+		return
+	}
+	for _, existing := range project.Spec.Members {
+		if existing == member {
+			s.respondWithProject(c, http.StatusOK, obj)
+			return
+		}
+	}
+	project.Spec.Members = append(project.Spec.Members, member)
+	s.saveProjectSpec(c, obj, project.Spec)
+}
+
+// removeProjectMember removes a GameServer from a project
+func (s *Server) removeProjectMember(c *gin.Context) {
+	member := ProjectMember{Namespace: c.Param("namespace"), Name: c.Param("name")}
+	obj, ok := s.loadProject(c)
+	if !ok {
+		return
+	}
+	project, err := projectFromUnstructured(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to convert Project: %v", err),
+		})
+		return
+	}
+	kept := make([]ProjectMember, 0, len(project.Spec.Members))
+	for _, existing := range project.Spec.Members {
+		if existing != member {
+			kept = append(kept, existing)
+		}
+	}
+	if len(kept) == len(project.Spec.Members) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "GameServer is not a member of this project",
+		})
+		return
+	}
+	project.Spec.Members = kept
+	s.saveProjectSpec(c, obj, project.Spec)
+}
+
+// runProjectAction runs a bulk action on every member the caller may access
+func (s *Server) runProjectAction(c *gin.Context) {
+	var req struct {
+		Action  string `json:"action" binding:"required"`
+		Message string `json:"message"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if !containsString(projectActions, req.Action) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Unsupported action %q (valid: %s)", req.Action, strings.Join(projectActions, ", ")),
+		})
+		return
+	}
+	if req.Action == "broadcast" && strings.TrimSpace(req.Message) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "message is required for broadcast",
+		})
+		return
+	}
+
+	obj, ok := s.loadProject(c)
+	if !ok {
+		return
+	}
+	project, err := projectFromUnstructured(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to convert Project: %v", err),
+		})
+		return
+	}
+	scope, err := s.readableNamespaces(c)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to determine readable namespaces: %v", err),
+		})
+		return
+	}
+
+	type memberResult struct {
+		ProjectMember
+		Success bool   `json:"success"`
+		Message string `json:"message,omitempty"`
+		Error   string `json:"error,omitempty"`
+	}
+	results := make([]memberResult, len(project.Spec.Members))
+	s.workers.each(c.Request.Context(), len(results), func(i int) {
+		member := project.Spec.Members[i]
+		results[i].ProjectMember = member
+		if !scope.allows(member.Namespace) {
+			results[i].Error = fmt.Sprintf("not permitted to access namespace %s", member.Namespace)
+			return
+		}
+		message, err := s.projectMemberAction(context.TODO(), member, req.Action, req.Message)
+		if err != nil {
+			results[i].Error = err.Error()
+			return
+		}
+		results[i].Success = true
+		results[i].Message = message
+	})
+	succeeded := 0
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"action":    req.Action,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"results":   results,
+	})
+}
+
+// projectMemberAction runs one bulk action on one member
+func (s *Server) projectMemberAction(ctx context.Context, member ProjectMember, action, message string) (string, error) {
+	obj, err := s.getGameServerObject(ctx, member.Namespace, member.Name)
+	if err != nil {
+		return "", err
+	}
+	switch action {
+	case "restart":
+		pods, namespace, err := s.findGameServerPods(ctx, obj)
+		if err != nil {
+			return "", err
+		}
+		if len(pods) == 0 {
+			return "", fmt.Errorf("no pods found")
+		}
+		deleted, err := s.deleteGameServerPods(ctx, namespace, pods, nil)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("restarted %s", strings.Join(deleted, ", ")), nil
+	case "broadcast":
+		return s.broadcastInGame(ctx, obj, message)
+	}
+	return "", fmt.Errorf("unsupported action %q", action)
+}
+
+// notifyProjects sends an event to the shared notification settings of
+// every project containing the event's server
+func (s *Server) notifyProjects(ctx context.Context, event notificationEvent) {
+	if event.Namespace == "" || event.Server == "" {
+		return
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(projectGVK.GroupVersion().WithKind("ProjectList"))
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		// Installations without the Project CRD have no projects to notify
+		return
+	}
+	settings, smtpEnabled := loadSMTPSettings()
+	member := ProjectMember{Namespace: event.Namespace, Name: event.Server}
+
+	for i := range list.Items {
+		project, err := projectFromUnstructured(&list.Items[i])
+		if err != nil || project.Spec.Notifications == nil || !project.hasMember(member) {
+			continue
+		}
+		notifications := project.Spec.Notifications
+		if len(notifications.Events) > 0 && !containsString(notifications.Events, event.Type) {
+			continue
+		}
+		for _, target := range notifications.Webhooks {
+			if err := postWebhook(ctx, target, event); err != nil {
+				log.Printf("Failed to send %s event to project %s webhook: %v", event.Type, project.Name, err)
+			}
+		}
+		if !smtpEnabled {
+			continue
+		}
+		for _, email := range notifications.Emails {
+			if err := sendEmail(settings, email, event); err != nil {
+				log.Printf("Failed to email %s event to %s for project %s: %v", event.Type, email, project.Name, err)
+			}
+		}
+	}
+}
+
+// hasMember reports whether a project contains a GameServer
+func (p *Project) hasMember(member ProjectMember) bool {
+	for _, existing := range p.Spec.Members {
+		if existing == member {
+			return true
+		}
+	}
+	return false
+}
+
+// projectStatus summarizes the live state of a project's members
+func (s *Server) projectStatus(ctx context.Context, project *Project) (*ProjectStatus, error) {
+	status := &ProjectStatus{Phases: map[string]int{}, Members: []ProjectMemberStatus{}}
+	for _, member := range project.Spec.Members {
+		memberStatus := ProjectMemberStatus{ProjectMember: member}
+		obj, err := s.getGameServerObject(ctx, member.Namespace, member.Name)
+		if client.IgnoreNotFound(err) != nil {
+			return nil, err
+		}
+		if err != nil {
+			memberStatus.Missing = true
+			status.Missing++
+			status.Members = append(status.Members, memberStatus)
+			continue
+		}
+		memberStatus.GameType, _, _ = unstructured.NestedString(obj.Object, "spec", "gameType")
+		memberStatus.Phase, _, _ = unstructured.NestedString(obj.Object, "status", "phase")
+		memberStatus.Ready = gameServerReady(obj)
+		memberStatus.Players = gameServerPlayers(obj)
+		memberStatus.Capacity = gameServerCapacity(obj)
+
+		status.Servers++
+		if memberStatus.Ready {
+			status.Ready++
+		}
+		status.Players += memberStatus.Players
+		status.Capacity += memberStatus.Capacity
+		if memberStatus.Phase != "" {
+			status.Phases[memberStatus.Phase]++
+		}
+		status.Members = append(status.Members, memberStatus)
+	}
+	return status, nil
+}
+
+// saveProjectSpec writes a new spec and responds with the project
+func (s *Server) saveProjectSpec(c *gin.Context, obj *unstructured.Unstructured, spec ProjectSpec) {
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&spec)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	obj.Object["spec"] = raw
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update Project: %v", err),
+		})
+		return
+	}
+	s.respondWithProject(c, http.StatusOK, obj)
+}
+
+// respondWithProject responds with a project and its summary
+func (s *Server) respondWithProject(c *gin.Context, status int, obj *unstructured.Unstructured) {
+	project, err := projectFromUnstructured(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to convert Project: %v", err),
+		})
+		return
+	}
+	if project.Status, err = s.projectStatus(context.TODO(), project); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to summarize Project: %v", err),
+		})
+		return
+	}
+	shaped, ok := selectFields(c, project)
+	if !ok {
+		return
+	}
+	c.JSON(status, shaped)
+}
+
+// loadProject fetches the project named by :project, responding on failure
+func (s *Server) loadProject(c *gin.Context) (*unstructured.Unstructured, bool) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(projectGVK)
+	if err := s.k8sClient.Get(context.TODO(), client.ObjectKey{Name: c.Param("project")}, obj); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "Project not found",
+			})
+			return nil, false
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get Project: %v", err),
+		})
+		return nil, false
+	}
+	return obj, true
+}
+
+// validateProjectSpec checks members and notification settings
+func validateProjectSpec(spec *ProjectSpec) error {
+	if spec.Members == nil {
+		spec.Members = []ProjectMember{}
+	}
+	seen := map[ProjectMember]bool{}
+	for _, member := range spec.Members {
+		if member.Namespace == "" || member.Name == "" {
+			return fmt.Errorf("members need a namespace and name")
+		}
+		if seen[member] {
+			return fmt.Errorf("%s/%s is listed twice", member.Namespace, member.Name)
+		}
+		seen[member] = true
+	}
+	if spec.Notifications == nil {
+		return nil
+	}
+	for _, target := range spec.Notifications.Webhooks {
+		if err := validateWebhookTarget(target); err != nil {
+			return err
+		}
+	}
+	for i, email := range spec.Notifications.Emails {
+		address, err := mail.ParseAddress(email)
+		if err != nil {
+			return fmt.Errorf("invalid email address %q", email)
+		}
+		spec.Notifications.Emails[i] = address.Address
+	}
+	return nil
+}
+
+func projectFromUnstructured(obj *unstructured.Unstructured) (*Project, error) {
+	raw, err := json.Marshal(obj.Object)
+	if err != nil {
+		return nil, err
+	}
+	project := &Project{}
+	if err := json.Unmarshal(raw, project); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+func projectToUnstructured(project *Project) (*unstructured.Unstructured, error) {
+	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(project)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: raw}, nil
+}
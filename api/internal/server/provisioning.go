@@ -0,0 +1,504 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// provisioningConfigMap holds provisioning times in the cluster registry
+	// namespace, so they outlive the servers they were measured on
+	provisioningConfigMap = "gameplane-provisioning"
+
+	// provisioningKey is the ConfigMap key of the measurements
+	provisioningKey = "provisions.json"
+
+	// provisioningTrackInterval is how often new GameServers are checked
+	// for readiness
+	provisioningTrackInterval = 30 * time.Second
+
+	// provisioningRetention is how long measurements are kept
+	provisioningRetention = 90 * 24 * time.Hour
+
+	// maxProvisionRecords bounds the measurements so they fit a ConfigMap
+	maxProvisionRecords = 2000
+
+	// provisioningTimeout is how long a server may take to become ready
+	// before it counts as failed
+	provisioningTimeout = 6 * time.Hour
+
+	// defaultProvisioningObjective is the provisioning time objective
+	// unless PROVISIONING_OBJECTIVE sets another
+	defaultProvisioningObjective = 15 * time.Minute
+
+	// Regressions are flagged when a version's median is this much slower
+	// than the previous version's, by at least the minimum, with enough
+	// samples of both
+	provisioningRegressionFactor  = 1.25
+	provisioningRegressionMinimum = 30 * time.Second
+	provisioningRegressionSamples = 3
+)
+
+// ProvisionRecord is the time one GameServer took from claim creation to
+// Ready, with the image and composition revision it was provisioned with
+type ProvisionRecord struct {
+	UID                 string    `json:"uid"`
+	Namespace           string    `json:"namespace"`
+	Name                string    `json:"name"`
+	GameType            string    `json:"gameType"`
+	Image               string    `json:"image"`
+	CompositionRevision string    `json:"compositionRevision,omitempty"`
+	CreatedAt           time.Time `json:"createdAt"`
+	ReadyAt             time.Time `json:"readyAt,omitempty"`
+	Seconds             float64   `json:"seconds"`
+	// TimedOut servers were not ready within the provisioning timeout
+	TimedOut bool `json:"timedOut,omitempty"`
+}
+
+// ProvisioningReport summarizes provisioning times per game type
+type ProvisioningReport struct {
+	GeneratedAt time.Time `json:"generatedAt"`
+	Days        int       `json:"days"`
+	// ObjectiveSeconds is the provisioning time servers should stay within
+	ObjectiveSeconds float64                  `json:"objectiveSeconds"`
+	GameTypes        []ProvisioningGameType   `json:"gameTypes"`
+	Regressions      []ProvisioningRegression `json:"regressions"`
+	Pending          []PendingProvision       `json:"pending"`
+}
+
+// ProvisioningGameType are the provisioning times of one game type
+type ProvisioningGameType struct {
+	GameType string                `json:"gameType"`
+	Stats    ProvisioningStats     `json:"stats"`
+	Versions []ProvisioningVersion `json:"versions"`
+}
+
+// ProvisioningVersion are the provisioning times of one image and
+// composition revision of a game type
+type ProvisioningVersion struct {
+	Image               string            `json:"image"`
+	CompositionRevision string            `json:"compositionRevision,omitempty"`
+	FirstSeen           time.Time         `json:"firstSeen"`
+	LastSeen            time.Time         `json:"lastSeen"`
+	Stats               ProvisioningStats `json:"stats"`
+}
+
+// ProvisioningStats are percentiles over provisioning times in seconds.
+// Timed out servers count against the objective but not the percentiles.
+type ProvisioningStats struct {
+	Count    int     `json:"count"`
+	TimedOut int     `json:"timedOut"`
+	P50      float64 `json:"p50"`
+	P90      float64 `json:"p90"`
+	P95      float64 `json:"p95"`
+	P99      float64 `json:"p99"`
+	Max      float64 `json:"max"`
+	// WithinObjective is the share of servers ready within the objective
+	WithinObjective float64 `json:"withinObjective"`
+}
+
+// ProvisioningRegression flags a version of a game type that provisions
+// slower than the version before it
+type ProvisioningRegression struct {
+	GameType            string  `json:"gameType"`
+	Image               string  `json:"image"`
+	CompositionRevision string  `json:"compositionRevision,omitempty"`
+	PreviousImage       string  `json:"previousImage"`
+	PreviousRevision    string  `json:"previousCompositionRevision,omitempty"`
+	P50                 float64 `json:"p50"`
+	PreviousP50         float64 `json:"previousP50"`
+	Message             string  `json:"message"`
+}
+
+// PendingProvision is a GameServer still on its way to Ready
+type PendingProvision struct {
+	Namespace      string  `json:"namespace"`
+	Name           string  `json:"name"`
+	GameType       string  `json:"gameType"`
+	WaitingSeconds float64 `json:"waitingSeconds"`
+	OverObjective  bool    `json:"overObjective"`
+}
+
+// provisioningObjective reads PROVISIONING_OBJECTIVE, e.g. "10m"
+func provisioningObjective() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("PROVISIONING_OBJECTIVE")); err == nil && d > 0 {
+		return d
+	}
+	return defaultProvisioningObjective
+}
+
+// provisionVersion is the image and composition revision a GameServer
+// runs, with the catalog image standing in for an unset spec.advanced.image
+func provisionVersion(obj *unstructured.Unstructured) (string, string) {
+	image, _, _ := unstructured.NestedString(obj.Object, "spec", "advanced", "image")
+	if image == "" {
+		gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+		if def, ok := lookupGame(gameType); ok {
+			image = def.Image
+		}
+	}
+	revision, _, _ := unstructured.NestedString(obj.Object, "spec", "compositionRevisionRef", "name")
+	return image, revision
+}
+
+// provisionStart is when a server's provisioning started: its creation, or
+// the start of its lifecycle for servers created stopped until then
+func provisionStart(obj *unstructured.Unstructured) time.Time {
+	start := obj.GetCreationTimestamp().Time
+	if lifecycle, err := gameServerLifecycle(obj); err == nil && lifecycle != nil && lifecycle.StartAt != nil && lifecycle.StartAt.After(start) {
+		start = lifecycle.StartAt.Time
+	}
+	return start
+}
+
+// provisionReadyAt is when a ready GameServer became ready: the transition
+// of its Ready condition, or now when it has none
+func provisionReadyAt(obj *unstructured.Unstructured, now time.Time) time.Time {
+	conditions, _ := gameServerConditions(obj)
+	for _, condition := range conditions {
+		if condition.Type == "Ready" && condition.Status == metav1.ConditionTrue && !condition.LastTransitionTime.IsZero() {
+			return condition.LastTransitionTime.Time
+		}
+	}
+	return now
+}
+
+// trackProvisioning records the provisioning time of GameServers that
+// became ready, or timed out, since the last run
+func (s *Server) trackProvisioning(ctx context.Context) error {
+	list, err := s.listAllGameServers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+	records, cm, err := s.loadProvisionRecords(ctx)
+	if err != nil {
+		return err
+	}
+	recorded := map[string]bool{}
+	for _, record := range records {
+		recorded[record.UID] = true
+	}
+
+	now := time.Now().UTC()
+	added := 0
+	for i := range list.Items {
+		obj := &list.Items[i]
+		stopped, _, _ := unstructured.NestedBool(obj.Object, "spec", "stopped")
+		if recorded[string(obj.GetUID())] || obj.GetDeletionTimestamp() != nil || stopped {
+			continue
+		}
+		start := provisionStart(obj)
+		// Servers older than the timeout were ready before tracking began,
+		// or were already recorded as timed out
+		if start.After(now) || now.Sub(start) > provisioningTimeout+provisioningTrackInterval*2 {
+			continue
+		}
+		gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+		image, revision := provisionVersion(obj)
+		record := ProvisionRecord{
+			UID:                 string(obj.GetUID()),
+			Namespace:           obj.GetNamespace(),
+			Name:                obj.GetName(),
+			GameType:            gameType,
+			Image:               image,
+			CompositionRevision: revision,
+			CreatedAt:           start,
+		}
+		switch {
+		case gameServerReady(obj):
+			readyAt := provisionReadyAt(obj, now)
+			if readyAt.Before(start) {
+				readyAt = now
+			}
+			record.ReadyAt = readyAt
+			record.Seconds = math.Round(readyAt.Sub(start).Seconds())
+		case now.Sub(start) > provisioningTimeout:
+			record.TimedOut = true
+			record.Seconds = math.Round(now.Sub(start).Seconds())
+		default:
+			continue
+		}
+		records = append(records, record)
+		added++
+	}
+	if added == 0 {
+		return nil
+	}
+	return s.saveProvisionRecords(ctx, cm, records)
+}
+
+// loadProvisionRecords reads the measurements and the ConfigMap holding
+// them, which is nil when none were stored yet
+func (s *Server) loadProvisionRecords(ctx context.Context) ([]ProvisionRecord, *corev1.ConfigMap, error) {
+	cm, err := s.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace).Get(ctx, provisioningConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return []ProvisionRecord{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read provisioning times: %w", err)
+	}
+	records := []ProvisionRecord{}
+	if raw := cm.Data[provisioningKey]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &records); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse provisioning times: %w", err)
+		}
+	}
+	return records, cm, nil
+}
+
+// saveProvisionRecords writes the measurements back, dropping those older
+// than the retention and the oldest beyond the limit
+func (s *Server) saveProvisionRecords(ctx context.Context, cm *corev1.ConfigMap, records []ProvisionRecord) error {
+	cutoff := time.Now().Add(-provisioningRetention)
+	kept := []ProvisionRecord{}
+	for _, record := range records {
+		if record.CreatedAt.After(cutoff) {
+			kept = append(kept, record)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].CreatedAt.Before(kept[j].CreatedAt)
+	})
+	if len(kept) > maxProvisionRecords {
+		kept = kept[len(kept)-maxProvisionRecords:]
+	}
+	raw, err := json.Marshal(kept)
+	if err != nil {
+		return err
+	}
+	configMaps := s.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace)
+	if cm == nil {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      provisioningConfigMap,
+				Namespace: s.clusters.namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "gameplane-api"},
+			},
+			Data: map[string]string{provisioningKey: string(raw)},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[provisioningKey] = string(raw)
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// getProvisioningReport reports provisioning time percentiles per game type
+// and version over ?days= (default 30), optionally for one ?gameType=, and
+// flags versions that provision slower than the one before. It spans every
+// namespace, so only admins may see it.
+func (s *Server) getProvisioningReport(c *gin.Context) {
+	if !s.isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Only admins may see the provisioning report",
+		})
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "days must be a positive integer",
+		})
+		return
+	}
+	gameType := c.Query("gameType")
+	if gameType != "" {
+		if _, ok := lookupGame(gameType); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Unsupported game type: %s", gameType),
+			})
+			return
+		}
+	}
+
+	records, _, err := s.loadProvisionRecords(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	list, err := s.listAllGameServers(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list GameServers: %v", err),
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	objective := provisioningObjective()
+	cutoff := now.Add(-time.Duration(days) * 24 * time.Hour)
+	selected := []ProvisionRecord{}
+	recorded := map[string]bool{}
+	for _, record := range records {
+		recorded[record.UID] = true
+		if record.CreatedAt.After(cutoff) && (gameType == "" || record.GameType == gameType) {
+			selected = append(selected, record)
+		}
+	}
+	report := buildProvisioningReport(selected, objective)
+	report.GeneratedAt = now
+	report.Days = days
+
+	for i := range list.Items {
+		obj := &list.Items[i]
+		serverType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+		start := provisionStart(obj)
+		stopped, _, _ := unstructured.NestedBool(obj.Object, "spec", "stopped")
+		if recorded[string(obj.GetUID())] || stopped || gameServerReady(obj) || start.After(now) || now.Sub(start) > provisioningTimeout || (gameType != "" && serverType != gameType) {
+			continue
+		}
+		report.Pending = append(report.Pending, PendingProvision{
+			Namespace:      obj.GetNamespace(),
+			Name:           obj.GetName(),
+			GameType:       serverType,
+			WaitingSeconds: math.Round(now.Sub(start).Seconds()),
+			OverObjective:  now.Sub(start) > objective,
+		})
+	}
+	sort.Slice(report.Pending, func(i, j int) bool {
+		return report.Pending[i].WaitingSeconds > report.Pending[j].WaitingSeconds
+	})
+	c.JSON(http.StatusOK, report)
+}
+
+// buildProvisioningReport groups measurements by game type and version and
+// compares each version with the one before it
+func buildProvisioningReport(records []ProvisionRecord, objective time.Duration) *ProvisioningReport {
+	report := &ProvisioningReport{
+		ObjectiveSeconds: objective.Seconds(),
+		GameTypes:        []ProvisioningGameType{},
+		Regressions:      []ProvisioningRegression{},
+		Pending:          []PendingProvision{},
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+
+	byType := map[string][]ProvisionRecord{}
+	gameTypes := []string{}
+	for _, record := range records {
+		if _, ok := byType[record.GameType]; !ok {
+			gameTypes = append(gameTypes, record.GameType)
+		}
+		byType[record.GameType] = append(byType[record.GameType], record)
+	}
+	sort.Strings(gameTypes)
+
+	for _, gameType := range gameTypes {
+		typeRecords := byType[gameType]
+		entry := ProvisioningGameType{
+			GameType: gameType,
+			Stats:    provisioningStats(typeRecords, objective),
+			Versions: []ProvisioningVersion{},
+		}
+		// Versions in the order they were first provisioned
+		index := map[[2]string]int{}
+		grouped := [][]ProvisionRecord{}
+		for _, record := range typeRecords {
+			key := [2]string{record.Image, record.CompositionRevision}
+			i, ok := index[key]
+			if !ok {
+				i = len(grouped)
+				index[key] = i
+				grouped = append(grouped, nil)
+			}
+			grouped[i] = append(grouped[i], record)
+		}
+		for _, group := range grouped {
+			entry.Versions = append(entry.Versions, ProvisioningVersion{
+				Image:               group[0].Image,
+				CompositionRevision: group[0].CompositionRevision,
+				FirstSeen:           group[0].CreatedAt,
+				LastSeen:            group[len(group)-1].CreatedAt,
+				Stats:               provisioningStats(group, objective),
+			})
+		}
+		for i := 1; i < len(entry.Versions); i++ {
+			current, previous := entry.Versions[i], entry.Versions[i-1]
+			if regression, ok := provisioningRegression(gameType, current, previous); ok {
+				report.Regressions = append(report.Regressions, regression)
+			}
+		}
+		report.GameTypes = append(report.GameTypes, entry)
+	}
+	return report
+}
+
+// provisioningRegression compares a version with the one before it
+func provisioningRegression(gameType string, current, previous ProvisioningVersion) (ProvisioningRegression, bool) {
+	currentCount := current.Stats.Count - current.Stats.TimedOut
+	previousCount := previous.Stats.Count - previous.Stats.TimedOut
+	if currentCount < provisioningRegressionSamples || previousCount < provisioningRegressionSamples {
+		return ProvisioningRegression{}, false
+	}
+	slower := current.Stats.P50 - previous.Stats.P50
+	if current.Stats.P50 < previous.Stats.P50*provisioningRegressionFactor || slower < provisioningRegressionMinimum.Seconds() {
+		return ProvisioningRegression{}, false
+	}
+	changed := "image"
+	switch {
+	case current.Image != previous.Image && current.CompositionRevision != previous.CompositionRevision:
+		changed = "image and composition revision"
+	case current.Image == previous.Image:
+		changed = "composition revision"
+	}
+	return ProvisioningRegression{
+		GameType:            gameType,
+		Image:               current.Image,
+		CompositionRevision: current.CompositionRevision,
+		PreviousImage:       previous.Image,
+		PreviousRevision:    previous.CompositionRevision,
+		P50:                 current.Stats.P50,
+		PreviousP50:         previous.Stats.P50,
+		Message:             fmt.Sprintf("%s servers take %s to provision since the %s changed, up from %s", gameType, time.Duration(current.Stats.P50)*time.Second, changed, time.Duration(previous.Stats.P50)*time.Second),
+	}, true
+}
+
+// provisioningStats computes nearest-rank percentiles of the servers that
+// became ready, and the share of all servers within the objective
+func provisioningStats(records []ProvisionRecord, objective time.Duration) ProvisioningStats {
+	stats := ProvisioningStats{Count: len(records)}
+	seconds := []float64{}
+	within := 0
+	for _, record := range records {
+		if record.TimedOut {
+			stats.TimedOut++
+			continue
+		}
+		seconds = append(seconds, record.Seconds)
+		if record.Seconds <= objective.Seconds() {
+			within++
+		}
+	}
+	if stats.Count > 0 {
+		stats.WithinObjective = math.Round(float64(within)/float64(stats.Count)*1000) / 1000
+	}
+	if len(seconds) == 0 {
+		return stats
+	}
+	sort.Float64s(seconds)
+	rank := func(p float64) float64 {
+		return math.Round(seconds[int(math.Ceil(float64(len(seconds))*p))-1])
+	}
+	stats.P50, stats.P90, stats.P95, stats.P99 = rank(0.50), rank(0.90), rank(0.95), rank(0.99)
+	stats.Max = math.Round(seconds[len(seconds)-1])
+	return stats
+}
@@ -0,0 +1,305 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// proxyRoutesConfigMap holds a proxy's routing config in its managed
+	// namespace; the proxy reloads it when it changes
+	proxyRoutesConfigMap = "gameplane-proxy-routes"
+
+	// proxyRoutesKey is the ConfigMap key of the routing config
+	proxyRoutesKey = "routes.json"
+
+	// proxyRouteInterval is how often routing configs are regenerated
+	proxyRouteInterval = 15 * time.Second
+
+	maxProxyBackends = 32
+)
+
+// ProxyRoutes is the routing config rendered for a proxy. Only backends
+// that can take players are listed.
+type ProxyRoutes struct {
+	Strategy string             `json:"strategy"`
+	Backends []ProxyRouteTarget `json:"backends"`
+}
+
+// ProxyRouteTarget is where a proxy forwards players for one backend
+type ProxyRouteTarget struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Port    int32  `json:"port"`
+	Weight  int    `json:"weight"`
+}
+
+// ProxyBackendStatus describes one configured backend of a proxy
+type ProxyBackendStatus struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+	Routed bool   `json:"routed"`
+	// Reason explains why a backend is left out of the routes
+	Reason  string `json:"reason,omitempty"`
+	Address string `json:"address,omitempty"`
+	Port    int32  `json:"port,omitempty"`
+}
+
+// ProxyStatus is the routing state of a proxy GameServer
+type ProxyStatus struct {
+	Strategy string               `json:"strategy"`
+	Backends []ProxyBackendStatus `json:"backends"`
+	// Applied is false while the routes differ from the proxy's config
+	Applied bool `json:"applied"`
+}
+
+// proxySpec converts spec.proxy for the claim
+func proxySpec(proxy *GameServerProxy) (map[string]interface{}, error) {
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(proxy)
+}
+
+// gameServerProxy reads spec.proxy, returning nil when unset
+func gameServerProxy(obj *unstructured.Unstructured) (*GameServerProxy, error) {
+	raw, ok, _ := unstructured.NestedMap(obj.Object, "spec", "proxy")
+	if !ok {
+		return nil, nil
+	}
+	proxy := &GameServerProxy{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, proxy); err != nil {
+		return nil, fmt.Errorf("invalid spec.proxy: %w", err)
+	}
+	return proxy, nil
+}
+
+// validateProxy checks spec.proxy of a GameServer. Backends may not exist
+// yet; they are routed once they are created and ready.
+func validateProxy(def GameDefinition, name string, proxy *GameServerProxy) error {
+	if proxy == nil {
+		return nil
+	}
+	if def.Proxy == nil {
+		return fmt.Errorf("spec.proxy is only supported by proxy game types")
+	}
+	if proxy.Strategy != "" && !containsString(def.Proxy.Strategies, proxy.Strategy) {
+		return fmt.Errorf("unsupported proxy strategy %q (valid: %s)", proxy.Strategy, strings.Join(def.Proxy.Strategies, ", "))
+	}
+	if len(proxy.Backends) > maxProxyBackends {
+		return fmt.Errorf("a proxy has at most %d backends", maxProxyBackends)
+	}
+	seen := map[string]bool{}
+	for _, backend := range proxy.Backends {
+		switch {
+		case backend.Name == "":
+			return fmt.Errorf("spec.proxy.backends[].name is required")
+		case backend.Name == name:
+			return fmt.Errorf("a proxy cannot be its own backend")
+		case seen[backend.Name]:
+			return fmt.Errorf("backend %s is listed twice", backend.Name)
+		case backend.Weight < 0 || backend.Weight > 100:
+			return fmt.Errorf("weight of backend %s must be between 1 and 100", backend.Name)
+		}
+		seen[backend.Name] = true
+	}
+	return nil
+}
+
+// proxyStrategy returns the configured strategy or the game's default
+func proxyStrategy(def GameDefinition, proxy *GameServerProxy) string {
+	if proxy != nil && proxy.Strategy != "" {
+		return proxy.Strategy
+	}
+	if def.Proxy != nil && len(def.Proxy.Strategies) > 0 {
+		return def.Proxy.Strategies[0]
+	}
+	return ""
+}
+
+// getGameServerProxy returns the backends of a proxy GameServer, whether
+// each is routed and whether the proxy has picked up the current routes
+func (s *Server) getGameServerProxy(c *gin.Context) {
+	obj, err := s.getGameServerObject(context.TODO(), c.Param("namespace"), c.Param("name"))
+	if apierrors.IsNotFound(err) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "GameServer not found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	if def, ok := lookupGame(gameType); !ok || def.Proxy == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Game type %s is not a proxy", gameType),
+		})
+		return
+	}
+
+	status, routes, err := s.proxyRoutes(context.TODO(), obj)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if namespace, err := managedNamespace(obj); err == nil {
+		cm, err := s.kubeClient.CoreV1().ConfigMaps(namespace).Get(context.TODO(), proxyRoutesConfigMap, metav1.GetOptions{})
+		if err == nil {
+			raw, _ := json.Marshal(routes)
+			status.Applied = cm.Data[proxyRoutesKey] == string(raw)
+		}
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// proxyRoutes resolves the backends of a proxy into its routing config.
+// Backends that are missing, stopped, not ready or not yet provisioned are
+// left out until that changes.
+func (s *Server) proxyRoutes(ctx context.Context, obj *unstructured.Unstructured) (ProxyStatus, ProxyRoutes, error) {
+	proxy, err := gameServerProxy(obj)
+	if err != nil {
+		return ProxyStatus{}, ProxyRoutes{}, err
+	}
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, _ := lookupGame(gameType)
+	strategy := proxyStrategy(def, proxy)
+	status := ProxyStatus{Strategy: strategy, Backends: []ProxyBackendStatus{}}
+	routes := ProxyRoutes{Strategy: strategy, Backends: []ProxyRouteTarget{}}
+	if proxy == nil {
+		return status, routes, nil
+	}
+
+	for _, backend := range proxy.Backends {
+		weight := backend.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		entry := ProxyBackendStatus{Name: backend.Name, Weight: weight}
+		target, err := s.getGameServerObject(ctx, obj.GetNamespace(), backend.Name)
+		switch {
+		case apierrors.IsNotFound(err):
+			entry.Reason = "GameServer not found"
+		case err != nil:
+			entry.Reason = fmt.Sprintf("Failed to get GameServer: %v", err)
+		}
+		if err != nil {
+			status.Backends = append(status.Backends, entry)
+			continue
+		}
+
+		targetType, _, _ := unstructured.NestedString(target.Object, "spec", "gameType")
+		stopped, _, _ := unstructured.NestedBool(target.Object, "spec", "stopped")
+		link, provisioned := s.linkTargets(ctx, []unstructured.Unstructured{*target})[backend.Name]
+		switch {
+		case targetType == gameType:
+			entry.Reason = "Backend is a proxy itself"
+		case stopped:
+			entry.Reason = "GameServer is stopped"
+		case !provisioned || link.port == 0:
+			entry.Reason = "Game service not provisioned yet"
+		case !gameServerReady(target):
+			entry.Reason = "GameServer is not ready"
+		default:
+			entry.Routed = true
+			entry.Address, entry.Port = link.host, link.port
+			routes.Backends = append(routes.Backends, ProxyRouteTarget{
+				Name:    backend.Name,
+				Address: link.host,
+				Port:    link.port,
+				Weight:  weight,
+			})
+		}
+		status.Backends = append(status.Backends, entry)
+	}
+	return status, routes, nil
+}
+
+// syncAllProxyRoutes regenerates the routing config of every proxy
+func (s *Server) syncAllProxyRoutes(ctx context.Context) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+		if def, ok := lookupGame(gameType); !ok || def.Proxy == nil {
+			continue
+		}
+		if err := s.syncProxyRoutes(ctx, obj); err != nil {
+			log.Printf("Failed to sync routes of proxy %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// syncProxyRoutes writes a proxy's routing config into its managed
+// namespace, only touching the ConfigMap when the routes changed
+func (s *Server) syncProxyRoutes(ctx context.Context, obj *unstructured.Unstructured) error {
+	namespace, err := managedNamespace(obj)
+	if err != nil {
+		// Not provisioned yet; routes are written once it is
+		return nil
+	}
+	_, routes, err := s.proxyRoutes(ctx, obj)
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(routes)
+	if err != nil {
+		return err
+	}
+
+	configMaps := s.kubeClient.CoreV1().ConfigMaps(namespace)
+	cm, err := configMaps.Get(ctx, proxyRoutesConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      proxyRoutesConfigMap,
+				Namespace: namespace,
+				Labels: map[string]string{
+					"kubelize.io/gameserver":       namespace,
+					"app.kubernetes.io/managed-by": "gameplane-api",
+				},
+			},
+			Data: map[string]string{proxyRoutesKey: string(raw)},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data[proxyRoutesKey] == string(raw) {
+		return nil
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[proxyRoutesKey] = string(raw)
+	if _, err := configMaps.Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	log.Printf("Updated routes of proxy %s/%s: %d backends", obj.GetNamespace(), obj.GetName(), len(routes.Backends))
+	return nil
+}
@@ -0,0 +1,174 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// readyNotificationAnnotation holds a GameServer's ReadyNotification as JSON
+	readyNotificationAnnotation = "gameplane.kubelize.io/ready-notification"
+
+	// readyNotifierInterval is how often pending ready notifications are checked
+	readyNotifierInterval = 30 * time.Second
+)
+
+// ReadyNotification is sent once when a new GameServer first becomes ready,
+// to its webhooks and to email subscribers
+type ReadyNotification struct {
+	Webhooks []webhookTarget `json:"webhooks"`
+	SentAt   *time.Time      `json:"sentAt,omitempty"`
+}
+
+// validate checks a ready notification before it is stored
+func (n ReadyNotification) validate() error {
+	for _, target := range n.Webhooks {
+		if err := validateWebhookTarget(target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setReadyNotification stores a ready notification on a GameServer that has
+// not been created yet
+func setReadyNotification(obj *unstructured.Unstructured, notification ReadyNotification) error {
+	notification.SentAt = nil
+	raw, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[readyNotificationAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// sendReadyNotifications notifies about GameServers that became ready since
+// the last run
+func (s *Server) sendReadyNotifications(ctx context.Context) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+
+	for i := range list.Items {
+		obj := &list.Items[i]
+		raw, ok := obj.GetAnnotations()[readyNotificationAnnotation]
+		if !ok || !gameServerReady(obj) {
+			continue
+		}
+		notification := ReadyNotification{}
+		if err := json.Unmarshal([]byte(raw), &notification); err != nil {
+			log.Printf("Skipping ready notification of GameServer %s/%s: invalid %s annotation: %v", obj.GetNamespace(), obj.GetName(), readyNotificationAnnotation, err)
+			continue
+		}
+		if notification.SentAt != nil {
+			continue
+		}
+		if err := s.sendReadyNotification(ctx, obj, notification); err != nil {
+			log.Printf("Failed to send ready notification for GameServer %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// sendReadyNotification delivers the notification and records that it was
+// sent. It is only retried when every delivery failed, so a broken target
+// does not repeat it on the others.
+func (s *Server) sendReadyNotification(ctx context.Context, obj *unstructured.Unstructured, notification ReadyNotification) error {
+	event := notificationEvent{
+		Type:      "ready",
+		Namespace: obj.GetNamespace(),
+		Server:    obj.GetName(),
+		Title:     "Server ready",
+		Message:   fmt.Sprintf("%s is ready to join", obj.GetName()),
+		Fields:    map[string]string{},
+	}
+	if info, err := s.gameServerConnectInfo(ctx, obj); err == nil {
+		if address := connectAddress(info); address != "" {
+			event.Fields["Address"] = address
+		}
+		event.Fields["Service"] = info.ServiceType
+	}
+	if credentials, err := s.credentialsLocation(ctx, obj); err == nil && credentials != "" {
+		event.Fields["Credentials"] = credentials
+	}
+
+	delivered := 0
+	var lastErr error
+	for _, target := range notification.Webhooks {
+		if err := postWebhook(ctx, target, event); err != nil {
+			lastErr = err
+			continue
+		}
+		delivered++
+	}
+	if sent, err := s.emailSubscribers(ctx, event); err != nil {
+		lastErr = err
+	} else {
+		delivered += sent
+	}
+	delivered += s.notifyUsers(ctx, event)
+	s.recordEventActivity(event)
+	if delivered == 0 && lastErr != nil {
+		return lastErr
+	}
+
+	now := time.Now().UTC()
+	notification.SentAt = &now
+	raw, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	annotations[readyNotificationAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+	return s.k8sClient.Update(ctx, obj)
+}
+
+// connectAddress renders connect info as host:port of the first port
+func connectAddress(info *ConnectInfo) string {
+	return info.Address()
+}
+
+// credentialsLocation names the Secrets holding a GameServer's generated
+// passwords, without revealing them
+func (s *Server) credentialsLocation(ctx context.Context, obj *unstructured.Unstructured) (string, error) {
+	namespace, err := managedNamespace(obj)
+	if err != nil {
+		return "", err
+	}
+	secrets, err := s.kubeClient.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{LabelSelector: managedNamespaceLabel})
+	if err != nil {
+		return "", err
+	}
+	entries := []string{}
+	for _, secret := range secrets.Items {
+		keys := make([]string, 0, len(secret.Data))
+		for key := range secret.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		entries = append(entries, fmt.Sprintf("%s/%s (%s)", namespace, secret.Name, strings.Join(keys, ", ")))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, "; "), nil
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/apis/gameplane/v1alpha1"
+)
+
+const (
+	// clusterRegionLabel sets a registered cluster's region on its secret
+	clusterRegionLabel = "topology.kubernetes.io/region"
+
+	// clusterPingEndpointsAnnotation lists comma-separated public URLs that
+	// clients can time to estimate their latency to a cluster
+	clusterPingEndpointsAnnotation = "gameplane.kubelize.io/ping-endpoints"
+)
+
+// GameServerPlacement selects where a GameServer runs
+type GameServerPlacement = v1alpha1.GameServerPlacement
+
+// Region is a location GameServers can be placed in
+type Region struct {
+	Name          string   `json:"name"`
+	Clusters      []string `json:"clusters"`
+	PingEndpoints []string `json:"pingEndpoints"`
+	Available     bool     `json:"available"`
+}
+
+// localClusterRegion reads the local cluster's region settings from the
+// environment
+func localClusterRegion() (string, []string) {
+	return os.Getenv("CLUSTER_REGION"), splitList(os.Getenv("CLUSTER_PING_ENDPOINTS"))
+}
+
+// listRegions returns the regions of the registered clusters with their
+// ping endpoints, so clients can pick the lowest-latency location
+func (s *Server) listRegions(c *gin.Context) {
+	s.clusters.mu.RLock()
+	byName := map[string]*Region{}
+	for _, name := range s.clusters.sortedNames() {
+		cluster := s.clusters.clusters[name]
+		if cluster.region == "" {
+			continue
+		}
+		region, ok := byName[cluster.region]
+		if !ok {
+			region = &Region{Name: cluster.region, Clusters: []string{}, PingEndpoints: []string{}}
+			byName[cluster.region] = region
+		}
+		region.Clusters = append(region.Clusters, cluster.name)
+		region.PingEndpoints = append(region.PingEndpoints, cluster.pingEndpoints...)
+		if cluster.server != nil {
+			region.Available = true
+		}
+	}
+	s.clusters.mu.RUnlock()
+
+	regions := make([]Region, 0, len(byName))
+	for _, region := range byName {
+		regions = append(regions, *region)
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		return regions[i].Name < regions[j].Name
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"regions": regions,
+		"total":   len(regions),
+	})
+}
+
+// serverForRegion returns the Server of a usable cluster in a region,
+// preferring the local cluster and otherwise the first by name
+func (r *clusterRegistry) serverForRegion(region string) (*Server, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if local := r.clusters[r.local]; local.region == region {
+		return local.server, nil
+	}
+	for _, name := range r.sortedNames() {
+		if cluster := r.clusters[name]; cluster.region == region && cluster.server != nil {
+			return cluster.server, nil
+		}
+	}
+	return nil, fmt.Errorf("no available cluster in region %q", region)
+}
+
+// regionOf returns the region of a cluster
+func (r *clusterRegistry) regionOf(cluster string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if registered, ok := r.clusters[cluster]; ok {
+		return registered.region
+	}
+	return ""
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
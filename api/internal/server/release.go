@@ -0,0 +1,365 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// helmReleaseListGVK lists provider-helm Releases
+var helmReleaseListGVK = schema.GroupVersionKind{Group: "helm.crossplane.io", Version: "v1beta1", Kind: "ReleaseList"}
+
+// redactedValue replaces values provider-helm reads from Secrets
+const redactedValue = "<redacted>"
+
+// HelmChart identifies a chart
+type HelmChart struct {
+	Name       string `json:"name"`
+	Repository string `json:"repository,omitempty"`
+	Version    string `json:"version,omitempty"`
+	AppVersion string `json:"appVersion,omitempty"`
+}
+
+// HelmRelease is a provider-helm Release composed for a GameServer, with
+// what Helm actually deployed
+type HelmRelease struct {
+	// Name is the Release resource, ReleaseName the Helm release it manages
+	Name        string    `json:"name"`
+	ReleaseName string    `json:"releaseName"`
+	Namespace   string    `json:"namespace"`
+	Chart       HelmChart `json:"chart"`
+	Ready       bool      `json:"ready"`
+	Synced      bool      `json:"synced"`
+	// Message explains why the Release is not ready or synced
+	Message string `json:"message,omitempty"`
+
+	// Deployed is the latest revision in Helm's release storage, nil before
+	// the first install
+	Deployed *HelmRevision `json:"deployed,omitempty"`
+	// Values are the values the Release asks for, with set overrides applied
+	Values map[string]interface{} `json:"values"`
+	// ValuesFrom lists the ConfigMaps and Secrets merged into the values
+	ValuesFrom []string `json:"valuesFrom,omitempty"`
+	// Changes are the differences from the deployed values to Values
+	Changes []SpecChange `json:"changes"`
+	InSync  bool         `json:"inSync"`
+}
+
+// HelmRevision is a revision of a Helm release
+type HelmRevision struct {
+	Revision     int                    `json:"revision"`
+	Status       string                 `json:"status"`
+	Description  string                 `json:"description,omitempty"`
+	Chart        HelmChart              `json:"chart"`
+	LastDeployed *time.Time             `json:"lastDeployed,omitempty"`
+	Values       map[string]interface{} `json:"values"`
+}
+
+// helmStoredRelease is the part of a release in Helm's Secret storage that is
+// reported
+type helmStoredRelease struct {
+	Info struct {
+		Status       string    `json:"status"`
+		Description  string    `json:"description"`
+		LastDeployed time.Time `json:"last_deployed"`
+	} `json:"info"`
+	Chart struct {
+		Metadata struct {
+			Name       string `json:"name"`
+			Version    string `json:"version"`
+			AppVersion string `json:"appVersion"`
+		} `json:"metadata"`
+	} `json:"chart"`
+	Config  map[string]interface{} `json:"config"`
+	Version int                    `json:"version"`
+}
+
+// getGameServerRelease reports the Helm releases composed for a GameServer:
+// the chart and status provider-helm reports, the revision Helm deployed in
+// the managed namespace and how the requested values differ from it
+func (s *Server) getGameServerRelease(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	if _, err := s.getGameServerObject(context.TODO(), namespace, name); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+
+	// Crossplane propagates the claim labels to everything composed for it,
+	// through the child composites
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(helmReleaseListGVK)
+	err := s.k8sClient.List(context.TODO(), list, client.MatchingLabels{
+		"crossplane.io/claim-name":      name,
+		"crossplane.io/claim-namespace": namespace,
+	})
+	if err != nil && !meta.IsNoMatchError(err) && !apierrors.IsNotFound(err) {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list Helm releases: %v", err),
+		})
+		return
+	}
+
+	releases := []HelmRelease{}
+	for i := range list.Items {
+		release, err := s.describeHelmRelease(context.TODO(), &list.Items[i])
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to read Helm release %s: %v", list.Items[i].GetName(), err),
+			})
+			return
+		}
+		releases = append(releases, release)
+	}
+	sort.Slice(releases, func(i, j int) bool { return releases[i].Name < releases[j].Name })
+
+	c.JSON(http.StatusOK, gin.H{
+		"namespace": namespace,
+		"name":      name,
+		"releases":  releases,
+	})
+}
+
+// describeHelmRelease combines a Release resource with its latest revision
+func (s *Server) describeHelmRelease(ctx context.Context, obj *unstructured.Unstructured) (HelmRelease, error) {
+	release := HelmRelease{
+		Name:        obj.GetName(),
+		ReleaseName: obj.GetName(),
+		Ready:       conditionTrue(obj, "Ready"),
+		Synced:      conditionTrue(obj, "Synced"),
+		Changes:     []SpecChange{},
+	}
+	if external := obj.GetAnnotations()["crossplane.io/external-name"]; external != "" {
+		release.ReleaseName = external
+	}
+	release.Namespace, _, _ = unstructured.NestedString(obj.Object, "spec", "forProvider", "namespace")
+	release.Chart.Name, _, _ = unstructured.NestedString(obj.Object, "spec", "forProvider", "chart", "name")
+	release.Chart.Repository, _, _ = unstructured.NestedString(obj.Object, "spec", "forProvider", "chart", "repository")
+	release.Chart.Version, _, _ = unstructured.NestedString(obj.Object, "spec", "forProvider", "chart", "version")
+	if !release.Ready || !release.Synced {
+		release.Message = conditionMessage(obj)
+	}
+
+	values, redacted, err := helmRequestedValues(obj)
+	if err != nil {
+		return release, err
+	}
+	release.Values = values
+	secretSource := false
+	sources, _, _ := unstructured.NestedSlice(obj.Object, "spec", "forProvider", "valuesFrom")
+	for _, raw := range sources {
+		source, _ := raw.(map[string]interface{})
+		for _, kind := range []string{"configMapKeyRef", "secretKeyRef"} {
+			if ref, ok := source[kind].(map[string]interface{}); ok {
+				release.ValuesFrom = append(release.ValuesFrom, fmt.Sprintf("%s %v/%v[%v]", strings.TrimSuffix(kind, "KeyRef"), ref["namespace"], ref["name"], ref["key"]))
+				secretSource = secretSource || kind == "secretKeyRef"
+			}
+		}
+	}
+
+	if release.Namespace == "" {
+		return release, nil
+	}
+	deployed, err := s.latestHelmRevision(ctx, release.Namespace, release.ReleaseName)
+	if err != nil || deployed == nil {
+		return release, err
+	}
+	for _, path := range redacted {
+		setHelmValue(deployed.Values, path, redactedValue, false)
+	}
+	if secretSource {
+		// Helm merged a Secret into the deployed values, anywhere in them
+		deployed.Values = map[string]interface{}{}
+	}
+	release.Deployed = deployed
+
+	// valuesFrom documents are only known to Helm, so they are not compared
+	if len(release.ValuesFrom) == 0 {
+		release.Changes = diffValues("values", deployed.Values, release.Values)
+	}
+	release.InSync = len(release.Changes) == 0 && deployed.Status == "deployed" &&
+		(release.Chart.Version == "" || release.Chart.Version == deployed.Chart.Version)
+	return release, nil
+}
+
+// helmRequestedValues returns the values of a Release with its set overrides
+// applied, and the paths of overrides read from Secrets, which are redacted
+func helmRequestedValues(obj *unstructured.Unstructured) (map[string]interface{}, []string, error) {
+	raw, _, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "forProvider", "values")
+	normalized, err := normalizeForDiff(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	values, _ := normalized.(map[string]interface{})
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+
+	redacted := []string{}
+	overrides, _, _ := unstructured.NestedSlice(obj.Object, "spec", "forProvider", "set")
+	for _, raw := range overrides {
+		override, _ := raw.(map[string]interface{})
+		path, _ := override["name"].(string)
+		if path == "" {
+			continue
+		}
+		if _, fromSource := override["valueFrom"]; fromSource {
+			setHelmValue(values, path, redactedValue, true)
+			redacted = append(redacted, path)
+			continue
+		}
+		value, _ := override["value"].(string)
+		setHelmValue(values, path, helmSetValue(value), true)
+	}
+	return values, redacted, nil
+}
+
+// helmSetValue types a --set value the way Helm does for plain scalars
+func helmSetValue(value string) interface{} {
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return float64(n)
+	}
+	return value
+}
+
+// setHelmValue sets the dotted path in values, creating maps when create is
+// set and leaving values alone when the path does not exist otherwise
+func setHelmValue(values map[string]interface{}, path string, value interface{}, create bool) {
+	keys := strings.Split(path, ".")
+	current := values
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			if !create {
+				return
+			}
+			next = map[string]interface{}{}
+			current[key] = next
+		}
+		current = next
+	}
+	last := keys[len(keys)-1]
+	if _, exists := current[last]; exists || create {
+		current[last] = value
+	}
+}
+
+// latestHelmRevision reads the newest revision of a release from Helm's
+// Secret storage, nil when Helm has not stored one
+func (s *Server) latestHelmRevision(ctx context.Context, namespace, releaseName string) (*HelmRevision, error) {
+	secrets, err := s.kubeClient.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("owner=helm,name=%s", releaseName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	latest, latestVersion := -1, 0
+	for i, secret := range secrets.Items {
+		version, err := strconv.Atoi(secret.Labels["version"])
+		if err == nil && version > latestVersion {
+			latest, latestVersion = i, version
+		}
+	}
+	if latest < 0 {
+		return nil, nil
+	}
+
+	stored, err := decodeHelmRelease(secrets.Items[latest].Data["release"])
+	if err != nil {
+		return nil, fmt.Errorf("decode revision %d: %w", latestVersion, err)
+	}
+	revision := &HelmRevision{
+		Revision:    stored.Version,
+		Status:      stored.Info.Status,
+		Description: stored.Info.Description,
+		Chart: HelmChart{
+			Name:       stored.Chart.Metadata.Name,
+			Version:    stored.Chart.Metadata.Version,
+			AppVersion: stored.Chart.Metadata.AppVersion,
+		},
+		Values: stored.Config,
+	}
+	if revision.Values == nil {
+		revision.Values = map[string]interface{}{}
+	}
+	if !stored.Info.LastDeployed.IsZero() {
+		deployed := stored.Info.LastDeployed.UTC()
+		revision.LastDeployed = &deployed
+	}
+	return revision, nil
+}
+
+// decodeHelmRelease decodes a release as Helm stores it in a Secret:
+// base64-encoded, usually gzipped JSON
+func decodeHelmRelease(data []byte) (*helmStoredRelease, error) {
+	raw, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.HasPrefix(raw, []byte{0x1f, 0x8b}) {
+		reader, err := gzip.NewReader(bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		if raw, err = io.ReadAll(reader); err != nil {
+			return nil, err
+		}
+	}
+	release := &helmStoredRelease{}
+	if err := json.Unmarshal(raw, release); err != nil {
+		return nil, err
+	}
+	return release, nil
+}
+
+// conditionMessage returns the message of the first condition of obj that is
+// not True
+func conditionMessage(obj *unstructured.Unstructured) string {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, raw := range conditions {
+		condition, ok := raw.(map[string]interface{})
+		if ok && condition["status"] != "True" {
+			message, _ := condition["message"].(string)
+			if message == "" {
+				message, _ = condition["reason"].(string)
+			}
+			return message
+		}
+	}
+	return ""
+}
@@ -0,0 +1,598 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/status"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// remediationPolicyAnnotation holds a GameServer's RemediationPolicy as
+	// JSON
+	remediationPolicyAnnotation = "gameplane.kubelize.io/remediation-policy"
+
+	// remediationStateAnnotation holds the RemediationState of a GameServer
+	remediationStateAnnotation = "gameplane.kubelize.io/remediation-state"
+
+	// previousSpecAnnotation holds the spec a GameServer had before its last
+	// change, which the rollback-config action restores
+	previousSpecAnnotation = "gameplane.kubelize.io/previous-spec"
+
+	// remediationInterval is how often crash looping servers are looked for
+	remediationInterval = 30 * time.Second
+
+	// defaultRemediationCooldown is how long a server gets to recover after
+	// an action before the next one runs
+	defaultRemediationCooldown = 10 * time.Minute
+
+	// remediationHistoryLimit is how many past actions are kept per server
+	remediationHistoryLimit = 20
+
+	// remediationTimeout bounds a single action, including restores
+	remediationTimeout = backupRestoreTimeout
+)
+
+// Remediation actions
+const (
+	remediationRestoreBackup  = "restore-backup"
+	remediationRollbackConfig = "rollback-config"
+	remediationDisableMod     = "disable-mod"
+	remediationStop           = "stop"
+)
+
+// remediationActions are the valid actions in the order they are documented
+var remediationActions = []string{remediationRestoreBackup, remediationRollbackConfig, remediationDisableMod, remediationStop}
+
+// RemediationPolicy lists what to do when a GameServer crash loops. The
+// actions escalate: each time the server is still crash looping after the
+// cooldown, the next one runs. Every action is audited and alerted.
+type RemediationPolicy struct {
+	Enabled bool     `json:"enabled"`
+	Actions []string `json:"actions"`
+	// Cooldown is how long to wait after an action before escalating,
+	// e.g. "15m"
+	Cooldown string          `json:"cooldown,omitempty"`
+	Webhooks []webhookTarget `json:"webhooks"`
+}
+
+// RemediationRecord describes one action taken on a crash looping server
+type RemediationRecord struct {
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action"`
+	Success bool      `json:"success"`
+	Message string    `json:"message"`
+	// Backup is the backup restored by restore-backup
+	Backup string `json:"backup,omitempty"`
+	// Snapshot is the safety VolumeSnapshot taken before a restore
+	Snapshot string `json:"snapshot,omitempty"`
+}
+
+// RemediationState tracks the escalation of the current crash loop
+type RemediationState struct {
+	// Step is the index of the next action; it resets once the server
+	// stays up for a cooldown
+	Step       int                 `json:"step"`
+	LastAction *time.Time          `json:"lastAction,omitempty"`
+	History    []RemediationRecord `json:"history"`
+}
+
+// remediatorState remembers which servers have an action in progress
+type remediatorState struct {
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// validate checks a policy before it is stored
+func (p RemediationPolicy) validate() error {
+	if p.Enabled && len(p.Actions) == 0 {
+		return fmt.Errorf("enabled remediation policies need at least one action")
+	}
+	seen := map[string]bool{}
+	for _, action := range p.Actions {
+		if !containsString(remediationActions, action) {
+			return fmt.Errorf("unsupported remediation action %q (valid: %s)", action, strings.Join(remediationActions, ", "))
+		}
+		if seen[action] {
+			return fmt.Errorf("remediation action %q is listed twice", action)
+		}
+		seen[action] = true
+	}
+	if p.Cooldown != "" {
+		if d, err := time.ParseDuration(p.Cooldown); err != nil || d <= 0 {
+			return fmt.Errorf("invalid cooldown %q", p.Cooldown)
+		}
+	}
+	for _, target := range p.Webhooks {
+		if err := validateWebhookTarget(target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cooldown returns how long to wait between actions
+func (p RemediationPolicy) cooldown() time.Duration {
+	if d, err := time.ParseDuration(p.Cooldown); err == nil && d > 0 {
+		return d
+	}
+	return defaultRemediationCooldown
+}
+
+// getRemediation returns the remediation policy and what it has done
+func (s *Server) getRemediation(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	policy, err := remediationPolicy(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	state := remediationState(obj)
+	c.JSON(http.StatusOK, gin.H{
+		"policy":       policy,
+		"crashLooping": crashLooping(obj),
+		"state":        state,
+	})
+}
+
+// putRemediationPolicy stores the GameServer's remediation policy
+func (s *Server) putRemediationPolicy(c *gin.Context) {
+	var policy RemediationPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if err := policy.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if policy.Actions == nil {
+		policy.Actions = []string{}
+	}
+	if policy.Webhooks == nil {
+		policy.Webhooks = []webhookTarget{}
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[remediationPolicyAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update remediation policy: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"policy":       policy,
+		"crashLooping": crashLooping(obj),
+		"state":        remediationState(obj),
+	})
+}
+
+// deleteRemediationPolicy removes the GameServer's remediation policy
+func (s *Server) deleteRemediationPolicy(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	annotations := obj.GetAnnotations()
+	if _, found := annotations[remediationPolicyAnnotation]; !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "GameServer has no remediation policy",
+		})
+		return
+	}
+	delete(annotations, remediationPolicyAnnotation)
+	obj.SetAnnotations(annotations)
+
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete remediation policy: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Remediation policy deleted",
+	})
+}
+
+// runRemediations is the background task applying remediation policies. A
+// crash looping server gets the next action of its policy once the
+// cooldown since the last one has passed; a server that stayed up for a
+// cooldown starts over at the first action.
+func (s *Server) runRemediations(ctx context.Context) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+
+	now := time.Now().UTC()
+	s.remediator.mu.Lock()
+	defer s.remediator.mu.Unlock()
+	if s.remediator.running == nil {
+		s.remediator.running = map[string]bool{}
+	}
+
+	for i := range list.Items {
+		obj := &list.Items[i]
+		key := obj.GetNamespace() + "/" + obj.GetName()
+		policy, err := remediationPolicy(obj)
+		if err != nil || policy == nil || !policy.Enabled || s.remediator.running[key] {
+			continue
+		}
+		state := remediationState(obj)
+		cooling := state.LastAction != nil && now.Sub(*state.LastAction) < policy.cooldown()
+
+		if !crashLooping(obj) {
+			if state.Step > 0 && !cooling {
+				state.Step = 0
+				if err := s.saveRemediationState(ctx, obj, state); err != nil {
+					log.Printf("Failed to reset remediation of %s: %v", key, err)
+				}
+			}
+			continue
+		}
+		if stopped, _, _ := unstructured.NestedBool(obj.Object, "spec", "stopped"); stopped || cooling || state.Step >= len(policy.Actions) {
+			continue
+		}
+
+		s.remediator.running[key] = true
+		go func(obj *unstructured.Unstructured, key string, policy *RemediationPolicy, action string) {
+			defer func() {
+				s.remediator.mu.Lock()
+				delete(s.remediator.running, key)
+				s.remediator.mu.Unlock()
+			}()
+			ctx, cancel := context.WithTimeout(context.Background(), remediationTimeout)
+			defer cancel()
+			s.remediate(ctx, obj, policy, action)
+		}(obj, key, policy, policy.Actions[state.Step])
+	}
+	return nil
+}
+
+// remediate runs one action on a crash looping server, records it in the
+// server's remediation state and alerts about it
+func (s *Server) remediate(ctx context.Context, obj *unstructured.Unstructured, policy *RemediationPolicy, action string) {
+	key := obj.GetNamespace() + "/" + obj.GetName()
+	log.Printf("GameServer %s is crash looping, running remediation %s", key, action)
+
+	record := RemediationRecord{Time: time.Now().UTC(), Action: action}
+	var err error
+	switch action {
+	case remediationRestoreBackup:
+		err = s.remediateRestoreBackup(ctx, obj, &record)
+	case remediationRollbackConfig:
+		err = s.remediateRollbackConfig(ctx, obj, &record)
+	case remediationDisableMod:
+		err = s.remediateDisableMod(ctx, obj, &record)
+	case remediationStop:
+		_ = setStopped(obj, true)
+		if err = s.k8sClient.Update(ctx, obj); err == nil {
+			record.Message = "Stopped the server; start it again once the crash is fixed"
+		}
+	}
+	record.Success = err == nil
+	if err != nil {
+		record.Message = fmt.Sprintf("Remediation %s failed: %v", action, err)
+		log.Printf("Remediation %s of %s failed: %v", action, key, err)
+	}
+
+	// The actions update the server, so record on the latest version
+	latest, getErr := s.getGameServerObject(ctx, obj.GetNamespace(), obj.GetName())
+	if getErr == nil {
+		// Changes made by remediation are not for a later rollback to undo
+		if record.Success && (action == remediationRollbackConfig || action == remediationDisableMod) {
+			annotations := latest.GetAnnotations()
+			delete(annotations, previousSpecAnnotation)
+			latest.SetAnnotations(annotations)
+		}
+		state := remediationState(latest)
+		state.Step++
+		state.LastAction = &record.Time
+		state.History = append([]RemediationRecord{record}, state.History...)
+		if len(state.History) > remediationHistoryLimit {
+			state.History = state.History[:remediationHistoryLimit]
+		}
+		getErr = s.saveRemediationState(ctx, latest, state)
+	}
+	if getErr != nil {
+		log.Printf("Failed to record remediation %s of %s: %v", action, key, getErr)
+	}
+
+	title := fmt.Sprintf("%s was crash looping: ran %s", obj.GetName(), action)
+	if err != nil {
+		title = fmt.Sprintf("%s is crash looping: %s failed", obj.GetName(), action)
+	}
+	event := notificationEvent{
+		Type:      "remediation",
+		Namespace: obj.GetNamespace(),
+		Server:    obj.GetName(),
+		Title:     title,
+		Message:   record.Message,
+		Fields:    map[string]string{"Action": action},
+	}
+	if record.Backup != "" {
+		event.Fields["Backup"] = record.Backup
+	}
+	if record.Snapshot != "" {
+		event.Fields["Safety snapshot"] = record.Snapshot
+	}
+	for _, target := range policy.Webhooks {
+		if err := postWebhook(ctx, target, event); err != nil {
+			log.Printf("Failed to deliver remediation of %s: %v", key, err)
+		}
+	}
+	s.notifySubscribers(ctx, event)
+	s.publishEvent(eventGameServerRemediated, obj.GetNamespace(), obj.GetName(), map[string]interface{}{
+		"action":  action,
+		"success": record.Success,
+		"message": record.Message,
+	})
+}
+
+// remediateRestoreBackup replaces the server's data with its newest backup
+// that did not fail verification. A safety snapshot of the broken data is
+// taken first, and remediation's own safety snapshots are never restored.
+func (s *Server) remediateRestoreBackup(ctx context.Context, obj *unstructured.Unstructured, record *RemediationRecord) error {
+	namespace, err := managedNamespace(obj)
+	if err != nil {
+		return err
+	}
+	snapshots, err := s.listVolumeSnapshots(ctx, client.InNamespace(namespace))
+	if err != nil {
+		return fmt.Errorf("failed to list volume snapshots: %w", err)
+	}
+	backups := []Backup{}
+	for i := range snapshots {
+		backup := s.backupFromSnapshot(&snapshots[i])
+		if backup.Volume != dataVolumeName(namespace) || !backup.Ready || backup.Error != "" || backup.Operation == "remediation" {
+			continue
+		}
+		if backup.Verification != nil && backup.Verification.Status == verificationFailed {
+			continue
+		}
+		backups = append(backups, backup)
+	}
+	if len(backups) == 0 {
+		return errors.New("no usable backup")
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	backup := backups[0]
+	size, err := resource.ParseQuantity(backup.Size)
+	if err != nil {
+		return fmt.Errorf("backup %s reports an invalid restore size %q", backup.Name, backup.Size)
+	}
+
+	snapshot, err := s.safetySnapshot(ctx, obj, "remediation", "")
+	if err != nil {
+		return fmt.Errorf("safety snapshot failed: %w", err)
+	}
+	if snapshot != nil {
+		record.Snapshot = snapshot.Name
+	}
+
+	restored, err := s.restoreVolume(ctx, namespace, backup, size, "remediation")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Use a fresh context so cleanup still happens after a timeout
+		_ = s.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Delete(context.Background(), restored.Name, metav1.DeleteOptions{})
+	}()
+	if _, err := s.runVolumeTask(ctx, obj, volumeTask{
+		Name:    "remediation",
+		Script:  fmt.Sprintf("cd %s\nfind . -mindepth 1 -maxdepth 1 -exec rm -rf {} +\ncp -R %s/. .\n", gameDataMountPath, volumeTaskSourcePath),
+		Timeout: remediationTimeout,
+		Source:  restored.Name,
+	}); err != nil {
+		return err
+	}
+
+	// Kill without a graceful shutdown so the broken world is not saved
+	// over the restored one
+	pods, _, err := s.findGameServerPods(ctx, obj)
+	if err != nil {
+		return fmt.Errorf("backup restored but failed to find pods: %w", err)
+	}
+	noGrace := int64(0)
+	if _, err := s.deleteGameServerPods(ctx, namespace, pods, &noGrace); err != nil {
+		return fmt.Errorf("backup restored but failed to restart GameServer: %w", err)
+	}
+	record.Backup = backup.Name
+	record.Message = fmt.Sprintf("Restored backup %s from %s", backup.Name, backup.CreatedAt.Format(time.RFC3339))
+	return nil
+}
+
+// remediateRollbackConfig restores the spec the server had before its last
+// change
+func (s *Server) remediateRollbackConfig(ctx context.Context, obj *unstructured.Unstructured, record *RemediationRecord) error {
+	raw, ok := obj.GetAnnotations()[previousSpecAnnotation]
+	if !ok {
+		return errors.New("no earlier spec recorded")
+	}
+	previous := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(raw), &previous); err != nil {
+		return fmt.Errorf("invalid %s annotation: %w", previousSpecAnnotation, err)
+	}
+	live, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	for _, field := range crossplaneSpecFields {
+		if value, found := live[field]; found {
+			previous[field] = value
+		}
+	}
+
+	changes := []string{}
+	if before, err := normalizeForDiff(live); err == nil {
+		if after, err := normalizeForDiff(previous); err == nil {
+			for _, change := range diffValues("spec", before, after) {
+				changes = append(changes, change.Path)
+			}
+		}
+	}
+	if _, err := s.writeGameServerSpec(ctx, obj, previous); err != nil {
+		return fmt.Errorf("failed to roll back spec: %w", err)
+	}
+
+	// Crash looping pods would take the backoff to pick up the change
+	pods, namespace, err := s.findGameServerPods(ctx, obj)
+	if err != nil {
+		return fmt.Errorf("spec rolled back but failed to find pods: %w", err)
+	}
+	if _, err := s.deleteGameServerPods(ctx, namespace, pods, nil); err != nil {
+		return fmt.Errorf("spec rolled back but failed to restart GameServer: %w", err)
+	}
+	latest, err := s.getGameServerObject(ctx, obj.GetNamespace(), obj.GetName())
+	if err != nil {
+		return fmt.Errorf("spec rolled back but failed to get GameServer: %w", err)
+	}
+	if len(pendingRestartFields(latest)) > 0 {
+		if err := s.clearPendingRestart(ctx, latest, "Pending configuration changes were applied by a crash remediation"); err != nil {
+			return fmt.Errorf("spec rolled back but failed to update GameServer: %w", err)
+		}
+	}
+
+	record.Message = "Rolled back the last spec change"
+	if len(changes) > 0 {
+		record.Message += ": " + strings.Join(changes, ", ")
+	}
+	return nil
+}
+
+// remediateDisableMod unmounts the most recently added shared asset, which
+// is how mod packs are installed
+func (s *Server) remediateDisableMod(ctx context.Context, obj *unstructured.Unstructured, record *RemediationRecord) error {
+	volumes, _, _ := unstructured.NestedSlice(obj.Object, "spec", "sharedVolumes")
+	if len(volumes) == 0 {
+		return errors.New("no shared assets are mounted")
+	}
+	last, _ := volumes[len(volumes)-1].(map[string]interface{})
+	name, _, _ := unstructured.NestedString(last, "name")
+	mountPath, _, _ := unstructured.NestedString(last, "mountPath")
+
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	if len(volumes) == 1 {
+		delete(spec, "sharedVolumes")
+	} else {
+		spec["sharedVolumes"] = volumes[:len(volumes)-1]
+	}
+	if _, err := s.writeGameServerSpec(ctx, obj, spec); err != nil {
+		return fmt.Errorf("failed to unmount shared asset %s: %w", name, err)
+	}
+	record.Message = fmt.Sprintf("Unmounted shared asset %s from %s", name, mountPath)
+	return nil
+}
+
+// crashLooping reports whether the status controller found a game
+// container in CrashLoopBackOff
+func crashLooping(obj *unstructured.Unstructured) bool {
+	conditions, _ := gameServerConditions(obj)
+	for _, condition := range conditions {
+		if condition.Type == status.ConditionCrashing && condition.Status == metav1.ConditionTrue && condition.Reason == "CrashLoopBackOff" {
+			return true
+		}
+	}
+	return false
+}
+
+// recordPreviousSpec keeps a GameServer's current spec, without the fields
+// Crossplane manages, for rollback-config
+func recordPreviousSpec(obj *unstructured.Unstructured) {
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	for _, field := range crossplaneSpecFields {
+		delete(spec, field)
+	}
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[previousSpecAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+}
+
+// remediationPolicy reads the remediation policy annotation, returning nil
+// when unset
+func remediationPolicy(obj *unstructured.Unstructured) (*RemediationPolicy, error) {
+	raw, ok := obj.GetAnnotations()[remediationPolicyAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var policy RemediationPolicy
+	if err := json.Unmarshal([]byte(raw), &policy); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", remediationPolicyAnnotation, err)
+	}
+	return &policy, nil
+}
+
+// remediationState reads the remediation state annotation. A missing or
+// invalid one starts over.
+func remediationState(obj *unstructured.Unstructured) RemediationState {
+	state := RemediationState{History: []RemediationRecord{}}
+	if raw, ok := obj.GetAnnotations()[remediationStateAnnotation]; ok {
+		_ = json.Unmarshal([]byte(raw), &state)
+	}
+	return state
+}
+
+// saveRemediationState writes the remediation state annotation
+func (s *Server) saveRemediationState(ctx context.Context, obj *unstructured.Unstructured, state RemediationState) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[remediationStateAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+	return s.k8sClient.Update(ctx, obj)
+}
@@ -0,0 +1,310 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// renamedFromAnnotation records the names a GameServer had before, so
+	// history kept under them can still be found
+	renamedFromAnnotation = "gameplane.kubelize.io/renamed-from"
+
+	// crossplanePausedAnnotation stops Crossplane reconciling a resource
+	crossplanePausedAnnotation = "crossplane.io/paused"
+
+	// renameTimeout bounds a rebinding rename
+	renameTimeout = 5 * time.Minute
+
+	// Rename strategies
+	renameStrategyRebind  = "rebind"
+	renameStrategyMigrate = "migrate"
+)
+
+// Rename steps, in order
+const (
+	renameStepPause   = "pause-claim"
+	renameStepRebind  = "rebind-composite"
+	renameStepCreate  = "create-claim"
+	renameStepRelease = "remove-old-claim"
+	renameStepProxies = "update-proxies"
+)
+
+// RenameRequest names the new name of a GameServer
+type RenameRequest struct {
+	Name string `json:"name"`
+	// Strategy is rebind (default), which binds a new claim to the
+	// existing composite so the data stays in place, or migrate, which
+	// copies the data to a freshly provisioned server
+	Strategy string `json:"strategy,omitempty"`
+}
+
+// renameGameServer renames a GameServer as an async operation. Kubernetes
+// names are immutable, so a claim with the new name replaces the old one.
+func (s *Server) renameGameServer(c *gin.Context) {
+	var req RenameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if errs := validation.IsDNS1123Label(req.Name); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid name %q: %s", req.Name, strings.Join(errs, "; ")),
+		})
+		return
+	}
+	if req.Strategy == "" {
+		req.Strategy = renameStrategyRebind
+	}
+	if req.Strategy != renameStrategyRebind && req.Strategy != renameStrategyMigrate {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Unknown strategy %q (valid: %s, %s)", req.Strategy, renameStrategyRebind, renameStrategyMigrate),
+		})
+		return
+	}
+
+	obj, err := s.getGameServerObject(context.TODO(), c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+	if req.Name == obj.GetName() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "The new name is the current name",
+		})
+		return
+	}
+	if _, member := obj.GetLabels()[fleetLabel]; member {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Fleet members are managed by their Fleet and cannot be renamed",
+		})
+		return
+	}
+	if obj.GetDeletionTimestamp() != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "GameServer is being deleted",
+		})
+		return
+	}
+	if _, err := managedNamespace(obj); err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if _, err := s.getGameServerObject(context.TODO(), obj.GetNamespace(), req.Name); err == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("GameServer %s/%s already exists", obj.GetNamespace(), req.Name),
+		})
+		return
+	}
+
+	if req.Strategy == renameStrategyMigrate {
+		migration := MigrationRequest{Cluster: s.cluster, Namespace: obj.GetNamespace(), Name: req.Name}
+		steps := []string{migrationStepBackup, migrationStepCreate, migrationStepProvision, migrationStepRestore, migrationStepDNS, migrationStepTeardown, renameStepProxies}
+		op := s.startOperation("rename", obj.GetNamespace(), obj.GetName(), steps, migrationTimeout, func(ctx context.Context, t *operationTracker) (interface{}, error) {
+			result, err := s.migrate(ctx, t, withRenamedFrom(obj), s, migration)
+			if err != nil {
+				return result, err
+			}
+			err = t.step(renameStepProxies, func() (string, error) {
+				if err := s.repointProxyBackends(ctx, obj.GetNamespace(), obj.GetName(), req.Name); err != nil {
+					return "", err
+				}
+				return "Pointed proxies at the new name", nil
+			})
+			if err == nil {
+				s.publishEvent(eventGameServerRenamed, obj.GetNamespace(), req.Name, map[string]interface{}{
+					"previousName": obj.GetName(),
+				})
+			}
+			return result, err
+		})
+		c.JSON(http.StatusAccepted, op)
+		return
+	}
+
+	steps := []string{renameStepPause, renameStepRebind, renameStepCreate, renameStepRelease, renameStepProxies}
+	op := s.startOperation("rename", obj.GetNamespace(), obj.GetName(), steps, renameTimeout, func(ctx context.Context, t *operationTracker) (interface{}, error) {
+		return s.rebindGameServer(ctx, t, obj, req.Name)
+	})
+	c.JSON(http.StatusAccepted, op)
+}
+
+// rebindGameServer replaces a claim with one of a new name bound to the
+// same composite, so everything the composite created stays in place.
+// The old claim is paused first: deleting a claim Crossplane still
+// reconciles would delete its composite with it.
+func (s *Server) rebindGameServer(ctx context.Context, t *operationTracker, obj *unstructured.Unstructured, name string) (interface{}, error) {
+	namespace, oldName := obj.GetNamespace(), obj.GetName()
+	result := gin.H{"namespace": namespace, "name": name, "previousName": oldName}
+	ref, _, _ := unstructured.NestedMap(obj.Object, "spec", "resourceRef")
+
+	err := t.step(renameStepPause, func() (string, error) {
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[crossplanePausedAnnotation] = "true"
+		obj.SetAnnotations(annotations)
+		if err := s.k8sClient.Update(ctx, obj); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Paused reconciliation of %s/%s", namespace, oldName), nil
+	})
+	if err != nil {
+		return result, err
+	}
+	created := false
+	defer func() {
+		if !created {
+			s.resumeClaim(namespace, oldName)
+		}
+	}()
+
+	err = t.step(renameStepRebind, func() (string, error) {
+		if ref == nil {
+			return "Not provisioned yet; nothing to rebind", nil
+		}
+		apiVersion, _ := ref["apiVersion"].(string)
+		kind, _ := ref["kind"].(string)
+		compositeName, _ := ref["name"].(string)
+		gv, err := schema.ParseGroupVersion(apiVersion)
+		if err != nil {
+			return "", err
+		}
+		composite := &unstructured.Unstructured{}
+		composite.SetGroupVersionKind(gv.WithKind(kind))
+		if err := s.k8sClient.Get(ctx, client.ObjectKey{Name: compositeName}, composite); err != nil {
+			return "", err
+		}
+		claimRef := map[string]interface{}{
+			"apiVersion": obj.GetAPIVersion(),
+			"kind":       obj.GetKind(),
+			"namespace":  namespace,
+			"name":       name,
+		}
+		if err := unstructured.SetNestedMap(composite.Object, claimRef, "spec", "claimRef"); err != nil {
+			return "", err
+		}
+		if err := s.k8sClient.Update(ctx, composite); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Composite %s now belongs to %s", compositeName, name), nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	err = t.step(renameStepCreate, func() (string, error) {
+		claim := migratedClaim(withRenamedFrom(obj), MigrationRequest{Namespace: namespace, Name: name}, "")
+		// Keep the composite binding migratedClaim drops
+		spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+		for _, field := range crossplaneSpecFields {
+			if value, ok := spec[field]; ok {
+				claim.Object["spec"].(map[string]interface{})[field] = runtime.DeepCopyJSONValue(value)
+			}
+		}
+		if err := s.k8sClient.Create(ctx, claim); err != nil {
+			return "", err
+		}
+		created = true
+		return fmt.Sprintf("Created %s/%s", namespace, name), nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	err = t.step(renameStepRelease, func() (string, error) {
+		latest, err := s.getGameServerObject(ctx, namespace, oldName)
+		if apierrors.IsNotFound(err) {
+			return "Already gone", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		latest.SetFinalizers(nil)
+		if err := s.k8sClient.Update(ctx, latest); err != nil {
+			return "", err
+		}
+		if err := s.k8sClient.Delete(ctx, latest); err != nil && !apierrors.IsNotFound(err) {
+			return "", err
+		}
+		return fmt.Sprintf("Removed %s/%s without deleting its composite", namespace, oldName), nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	err = t.step(renameStepProxies, func() (string, error) {
+		if err := s.repointProxyBackends(ctx, namespace, oldName, name); err != nil {
+			return "", err
+		}
+		return "Pointed proxies at the new name", nil
+	})
+	if err != nil {
+		return result, err
+	}
+	s.publishEvent(eventGameServerRenamed, namespace, name, map[string]interface{}{
+		"previousName": oldName,
+	})
+	return result, nil
+}
+
+// resumeClaim lets Crossplane reconcile a claim again after a rename
+// failed before its replacement existed. A composite already rebound is
+// claimed back by it.
+func (s *Server) resumeClaim(namespace, name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	obj, err := s.getGameServerObject(ctx, namespace, name)
+	if err != nil {
+		log.Printf("Failed to resume GameServer %s/%s: %v", namespace, name, err)
+		return
+	}
+	annotations := obj.GetAnnotations()
+	delete(annotations, crossplanePausedAnnotation)
+	obj.SetAnnotations(annotations)
+	if err := s.k8sClient.Update(ctx, obj); err != nil {
+		log.Printf("Failed to resume GameServer %s/%s: %v", namespace, name, err)
+	}
+}
+
+// withRenamedFrom returns a copy of a claim recording its current name
+// among the names it had
+func withRenamedFrom(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	copied := obj.DeepCopy()
+	annotations := copied.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	previous := obj.GetName()
+	if earlier := annotations[renamedFromAnnotation]; earlier != "" {
+		previous = earlier + "," + previous
+	}
+	annotations[renamedFromAnnotation] = previous
+	copied.SetAnnotations(annotations)
+	return copied
+}
@@ -0,0 +1,270 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// managedNamespaceLabel marks namespaces created by a game composition
+const managedNamespaceLabel = "kubelize.io/gameserver"
+
+// UtilizationReport lists resources that could be reclaimed
+type UtilizationReport struct {
+	GeneratedAt     time.Time          `json:"generatedAt"`
+	Days            int                `json:"days"`
+	Currency        string             `json:"currency"`
+	Idle            []IdleServer       `json:"idle"`
+	Underused       []UnderusedServer  `json:"underused"`
+	OrphanedVolumes []OrphanedVolume   `json:"orphanedVolumes"`
+	Reclaimable     ReclaimableSummary `json:"reclaimable"`
+	Notes           []string           `json:"notes"`
+}
+
+// IdleServer is a GameServer nobody played on during the report window
+type IdleServer struct {
+	Namespace     string              `json:"namespace"`
+	Name          string              `json:"name"`
+	GameType      string              `json:"gameType"`
+	ObservedHours int                 `json:"observedHours"`
+	Resources     GameServerResources `json:"resources"`
+	MonthlyCost   float64             `json:"monthlyCost"`
+}
+
+// UnderusedServer is a GameServer with confident rightsizing savings
+type UnderusedServer struct {
+	Namespace      string              `json:"namespace"`
+	Name           string              `json:"name"`
+	Confidence     string              `json:"confidence"`
+	Current        GameServerResources `json:"current"`
+	Recommended    GameServerResources `json:"recommended"`
+	MonthlySavings float64             `json:"monthlySavings"`
+}
+
+// OrphanedVolume is storage left behind by a deleted GameServer. Wipe and
+// migration backups live on the data volume, so they are reclaimed with it.
+type OrphanedVolume struct {
+	Kind         string  `json:"kind"` // PersistentVolumeClaim or PersistentVolume
+	Namespace    string  `json:"namespace,omitempty"`
+	Name         string  `json:"name"`
+	Size         string  `json:"size"`
+	StorageClass string  `json:"storageClass,omitempty"`
+	Reason       string  `json:"reason"`
+	MonthlyCost  float64 `json:"monthlyCost"`
+}
+
+// ReclaimableSummary totals what the report's findings would free
+type ReclaimableSummary struct {
+	CPU         string  `json:"cpu"`
+	Memory      string  `json:"memory"`
+	Storage     string  `json:"storage"`
+	MonthlyCost float64 `json:"monthlyCost"`
+}
+
+// getUtilizationReport lists GameServers without players over ?days= (default
+// 7), servers whose resources can be confidently reduced, and volumes whose
+// GameServer no longer exists. It spans every namespace, so only admins may
+// see it.
+func (s *Server) getUtilizationReport(c *gin.Context) {
+	if !s.isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Only admins may see the utilization report",
+		})
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "7"))
+	if err != nil || days <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "days must be a positive integer",
+		})
+		return
+	}
+
+	report, err := s.buildUtilizationReport(context.TODO(), days)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to build utilization report: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// buildUtilizationReport gathers the findings of a utilization report
+func (s *Server) buildUtilizationReport(ctx context.Context, days int) (*UtilizationReport, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return nil, fmt.Errorf("failed to list GameServers: %w", err)
+	}
+
+	rates := loadCostRates()
+	window := time.Duration(days) * 24 * time.Hour
+	report := &UtilizationReport{
+		GeneratedAt:     time.Now().UTC(),
+		Days:            days,
+		Currency:        rates.Currency,
+		Idle:            []IdleServer{},
+		Underused:       []UnderusedServer{},
+		OrphanedVolumes: []OrphanedVolume{},
+		Notes:           []string{},
+	}
+	var cpuMillis, memoryBytes, storageBytes int64
+	var monthly float64
+
+	live := map[string]bool{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		namespace, err := managedNamespace(obj)
+		if err != nil {
+			continue
+		}
+		live[namespace] = true
+
+		history, err := s.gameServerUsage(ctx, obj, window)
+		if err != nil {
+			report.Notes = append(report.Notes, fmt.Sprintf("%s/%s: %v", obj.GetNamespace(), obj.GetName(), err))
+			continue
+		}
+		gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+		resources := effectiveResources(gameType, gameServerResources(obj))
+
+		if observed, idle := idleHours(history); idle && time.Since(obj.GetCreationTimestamp().Time) >= window {
+			cost := rates.hourlyCost(resources).scale(hoursPerMonth).Total
+			report.Idle = append(report.Idle, IdleServer{
+				Namespace:     obj.GetNamespace(),
+				Name:          obj.GetName(),
+				GameType:      gameType,
+				ObservedHours: observed,
+				Resources:     resources,
+				MonthlyCost:   cost,
+			})
+			cpuMillis += quantityMilli(resources.CPU)
+			memoryBytes += quantityMilli(resources.Memory) / 1000
+			storageBytes += quantityMilli(resources.StorageSize) / 1000
+			monthly += cost
+			continue
+		}
+
+		recommendation := recommendResources(obj, history)
+		if recommendation.Changed && recommendation.Confidence != "low" && recommendation.Savings.Total > 0 {
+			report.Underused = append(report.Underused, UnderusedServer{
+				Namespace:      obj.GetNamespace(),
+				Name:           obj.GetName(),
+				Confidence:     recommendation.Confidence,
+				Current:        recommendation.Current,
+				Recommended:    recommendation.Recommended,
+				MonthlySavings: recommendation.Savings.Total,
+			})
+			cpuMillis += quantityMilli(recommendation.Current.CPU) - quantityMilli(recommendation.Recommended.CPU)
+			memoryBytes += (quantityMilli(recommendation.Current.Memory) - quantityMilli(recommendation.Recommended.Memory)) / 1000
+			monthly += recommendation.Savings.Total
+		}
+	}
+
+	orphaned, err := s.orphanedVolumes(ctx, live)
+	if err != nil {
+		return nil, err
+	}
+	for i := range orphaned {
+		bytes := quantityMilli(orphaned[i].Size) / 1000
+		orphaned[i].MonthlyCost = rates.hourlyCost(GameServerResources{StorageSize: orphaned[i].Size}).scale(hoursPerMonth).Total
+		storageBytes += bytes
+		monthly += orphaned[i].MonthlyCost
+	}
+	report.OrphanedVolumes = orphaned
+
+	sort.Slice(report.Idle, func(i, j int) bool { return report.Idle[i].MonthlyCost > report.Idle[j].MonthlyCost })
+	sort.Slice(report.Underused, func(i, j int) bool {
+		return report.Underused[i].MonthlySavings > report.Underused[j].MonthlySavings
+	})
+	report.Reclaimable = ReclaimableSummary{
+		CPU:         formatMillicores(cpuMillis),
+		Memory:      formatMemoryBytes(memoryBytes),
+		Storage:     formatMemoryBytes(storageBytes),
+		MonthlyCost: CostBreakdown{Total: monthly}.scale(1).Total,
+	}
+	return report, nil
+}
+
+// idleHours reports how many hours of history were recorded and whether no
+// player was seen in any of them
+func idleHours(history []UsageHour) (int, bool) {
+	observed := 0
+	for _, hour := range history {
+		if hour.Samples == 0 {
+			continue
+		}
+		if hour.PlayersMax > 0 {
+			return 0, false
+		}
+		observed++
+	}
+	return observed, observed > 0
+}
+
+// orphanedVolumes finds data volumes in game namespaces without a GameServer
+// and released volumes whose claim was in such a namespace
+func (s *Server) orphanedVolumes(ctx context.Context, live map[string]bool) ([]OrphanedVolume, error) {
+	namespaces, err := s.kubeClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: managedNamespaceLabel})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list game namespaces: %w", err)
+	}
+	orphaned := []OrphanedVolume{}
+	for _, ns := range namespaces.Items {
+		if live[ns.Name] {
+			continue
+		}
+		pvcs, err := s.kubeClient.CoreV1().PersistentVolumeClaims(ns.Name).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list volumes in %s: %w", ns.Name, err)
+		}
+		for _, pvc := range pvcs.Items {
+			size := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+			volume := OrphanedVolume{
+				Kind:      "PersistentVolumeClaim",
+				Namespace: pvc.Namespace,
+				Name:      pvc.Name,
+				Size:      size.String(),
+				Reason:    "No GameServer uses this namespace",
+			}
+			if pvc.Spec.StorageClassName != nil {
+				volume.StorageClass = *pvc.Spec.StorageClassName
+			}
+			orphaned = append(orphaned, volume)
+		}
+	}
+
+	pvs, err := s.kubeClient.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persistent volumes: %w", err)
+	}
+	for _, pv := range pvs.Items {
+		claim := pv.Spec.ClaimRef
+		if pv.Status.Phase != corev1.VolumeReleased || claim == nil || claim.Name != claim.Namespace+"-storage" || live[claim.Namespace] {
+			continue
+		}
+		size := pv.Spec.Capacity[corev1.ResourceStorage]
+		orphaned = append(orphaned, OrphanedVolume{
+			Kind:         "PersistentVolume",
+			Name:         pv.Name,
+			Size:         size.String(),
+			StorageClass: pv.Spec.StorageClassName,
+			Reason:       fmt.Sprintf("Released by %s/%s and retained", claim.Namespace, claim.Name),
+		})
+	}
+	return orphaned, nil
+}
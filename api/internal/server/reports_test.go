@@ -0,0 +1,23 @@
+package server_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kubelize/gameplane/api/pkg/gameplane/gameplanetest"
+)
+
+func TestReportsAreForAdmins(t *testing.T) {
+	h := newHarness(t, ownedServer("default", "alices", "alice"))
+
+	for _, path := range []string{"/api/v1/reports/utilization", "/api/v1/reports/provisioning"} {
+		// The admin's response is cached first, and must not be served to
+		// anyone else
+		if code := call(t, h, "root", gameplanetest.Request(http.MethodGet, path, nil), nil); code != http.StatusOK {
+			t.Errorf("%s as admin: got %d, want 200", path, code)
+		}
+		if code := call(t, h, "alice", gameplanetest.Request(http.MethodGet, path, nil), nil); code != http.StatusForbidden {
+			t.Errorf("%s as non-admin: got %d, want 403", path, code)
+		}
+	}
+}
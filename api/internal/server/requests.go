@@ -0,0 +1,520 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/admission"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// serverRequestsConfigMap holds server requests in the cluster
+	// registry namespace of the local cluster
+	serverRequestsConfigMap = "gameplane-server-requests"
+
+	// serverRequestsKey is the ConfigMap key of the request list
+	serverRequestsKey = "requests.json"
+
+	// ownerAnnotation records the subject a GameServer belongs to
+	ownerAnnotation = "gameplane.kubelize.io/owner"
+
+	// maxPendingRequests bounds the requests one subject has waiting
+	maxPendingRequests = 3
+
+	// serverRequestRetention is how long decided requests are kept
+	serverRequestRetention = 30 * 24 * time.Hour
+)
+
+// Server request states
+const (
+	requestPending   = "pending"
+	requestApproved  = "approved"
+	requestDenied    = "denied"
+	requestWithdrawn = "withdrawn"
+)
+
+// serverSizes are the resources a request may ask for
+var serverSizes = map[string]GameServerResources{
+	"small":  {CPU: "1", Memory: "4Gi", StorageSize: "20Gi"},
+	"medium": {CPU: "2", Memory: "8Gi", StorageSize: "50Gi"},
+	"large":  {CPU: "4", Memory: "16Gi", StorageSize: "100Gi"},
+}
+
+// ServerRequest asks an admin for a new GameServer
+type ServerRequest struct {
+	ID string `json:"id"`
+	// Requester is the subject that submitted the request and owns the
+	// GameServer once approved
+	Requester  string `json:"requester,omitempty"`
+	Cluster    string `json:"cluster,omitempty"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name" binding:"required"`
+	GameType   string `json:"gameType" binding:"required"`
+	ServerName string `json:"serverName,omitempty"`
+	// Size is one of serverSizes
+	Size string `json:"size"`
+	// Duration, if set, deletes the server that long after it first
+	// becomes ready (e.g. "3d")
+	Duration  string    `json:"duration,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+	// DecidedBy, DecidedAt and Comment are set once an admin decided
+	DecidedBy string     `json:"decidedBy,omitempty"`
+	DecidedAt *time.Time `json:"decidedAt,omitempty"`
+	Comment   string     `json:"comment,omitempty"`
+	// Error is why the last approval could not create the server
+	Error string `json:"error,omitempty"`
+}
+
+// validate checks a submitted request
+func (r ServerRequest) validate() error {
+	if _, ok := lookupGame(r.GameType); !ok {
+		return fmt.Errorf("unsupported game type: %s. Valid types: %s", r.GameType, strings.Join(supportedGameTypes(), ", "))
+	}
+	if _, ok := serverSizes[r.Size]; !ok {
+		return fmt.Errorf("unsupported size %q (valid: %s)", r.Size, strings.Join(serverSizeNames(), ", "))
+	}
+	if r.Duration != "" {
+		if _, err := parseRange(r.Duration); err != nil {
+			return fmt.Errorf("duration: %v", err)
+		}
+	}
+	if len(r.Reason) > 1000 {
+		return fmt.Errorf("reason must be at most 1000 characters")
+	}
+	return nil
+}
+
+// claim builds the GameServer claim an approved request creates
+func (r ServerRequest) claim() *unstructured.Unstructured {
+	spec := GameServerSpec{
+		GameType:   r.GameType,
+		ServerName: r.ServerName,
+		Resources:  serverSizes[r.Size],
+	}
+	if r.Duration != "" {
+		spec.Lifecycle = &GameServerLifecycle{TTLAfterReady: r.Duration}
+	}
+
+	labels := map[string]interface{}{}
+	for k, v := range admission.Labels(r.Name, r.GameType) {
+		labels[k] = v
+	}
+	metadata := map[string]interface{}{
+		"name":      r.Name,
+		"namespace": r.Namespace,
+		"labels":    labels,
+	}
+	if r.Requester != "" {
+		metadata["annotations"] = map[string]interface{}{
+			ownerAnnotation: r.Requester,
+		}
+	}
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "gameplane.kubelize.io/v1alpha1",
+			"kind":       "GameServer",
+			"metadata":   metadata,
+			"spec":       buildGameServerSpec(spec),
+		},
+	}
+}
+
+// serverSizeNames lists the sizes a request may ask for
+func serverSizeNames() []string {
+	names := make([]string, 0, len(serverSizes))
+	for name := range serverSizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadServerRequests reads all requests and the ConfigMap holding them,
+// which is nil when none were stored yet
+func (s *Server) loadServerRequests(ctx context.Context) ([]ServerRequest, *corev1.ConfigMap, error) {
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return nil, nil, err
+	}
+	cm, err := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace).Get(ctx, serverRequestsConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return []ServerRequest{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read server requests: %w", err)
+	}
+	requests := []ServerRequest{}
+	if raw := cm.Data[serverRequestsKey]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &requests); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse server requests: %w", err)
+		}
+	}
+	return requests, cm, nil
+}
+
+// saveServerRequests writes the request list back, dropping requests
+// decided longer ago than the retention, and fails on a concurrent change
+func (s *Server) saveServerRequests(ctx context.Context, cm *corev1.ConfigMap, requests []ServerRequest) error {
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-serverRequestRetention)
+	kept := []ServerRequest{}
+	for _, request := range requests {
+		if request.DecidedAt == nil || request.DecidedAt.After(cutoff) {
+			kept = append(kept, request)
+		}
+	}
+	raw, err := json.Marshal(kept)
+	if err != nil {
+		return err
+	}
+	configMaps := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace)
+	if cm == nil {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      serverRequestsConfigMap,
+				Namespace: s.clusters.namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "gameplane-api"},
+			},
+			Data: map[string]string{serverRequestsKey: string(raw)},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[serverRequestsKey] = string(raw)
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// findServerRequest returns the request's index, or -1
+func findServerRequest(requests []ServerRequest, id string) int {
+	for i, request := range requests {
+		if request.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// listServerRequests returns every request to admins, those in their
+// namespaces to team admins and their own to everyone else, newest first, optionally filtered by ?status=
+func (s *Server) listServerRequests(c *gin.Context) {
+	requests, _, err := s.loadServerRequests(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	subject := c.GetString(handlers.SubjectKey)
+	status := c.Query("status")
+	items := []ServerRequest{}
+	for _, request := range requests {
+		if (request.Requester == subject || s.hasRole(c, request.Namespace, roleAdmin)) && (status == "" || request.Status == status) {
+			items = append(items, request)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.After(items[j].CreatedAt) })
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(items),
+		"sizes": serverSizes,
+	})
+}
+
+// createServerRequest submits a request for a new GameServer
+func (s *Server) createServerRequest(c *gin.Context) {
+	var req ServerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if req.Namespace == "" {
+		req.Namespace = "default"
+	}
+	if req.Size == "" {
+		req.Size = "small"
+	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	scope, err := s.readableNamespaces(c)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to determine readable namespaces: %v", err),
+		})
+		return
+	}
+	if !scope.allows(req.Namespace) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Not permitted to access namespace %s", req.Namespace),
+		})
+		return
+	}
+
+	req.ID = newServerRequestID()
+	req.Requester = c.GetString(handlers.SubjectKey)
+	req.Cluster = c.Query("cluster")
+	req.Status = requestPending
+	req.CreatedAt = time.Now().UTC()
+	req.DecidedBy, req.DecidedAt, req.Comment, req.Error = "", nil, "", ""
+
+	// Catch what admission would reject now rather than at approval
+	if err := admission.Validate(req.claim()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if _, err := s.getGameServerObject(context.TODO(), req.Namespace, req.Name); err == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("GameServer %s/%s already exists", req.Namespace, req.Name),
+		})
+		return
+	}
+
+	requests, cm, err := s.loadServerRequests(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	pending := 0
+	for _, request := range requests {
+		if request.Status != requestPending {
+			continue
+		}
+		if request.Namespace == req.Namespace && request.Name == req.Name && request.Cluster == req.Cluster {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": fmt.Sprintf("A request for %s/%s is already pending", req.Namespace, req.Name),
+			})
+			return
+		}
+		if request.Requester == req.Requester {
+			pending++
+		}
+	}
+	if pending >= maxPendingRequests {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error": fmt.Sprintf("At most %d requests may be pending at once", maxPendingRequests),
+		})
+		return
+	}
+
+	requests = append(requests, req)
+	if err := s.saveServerRequests(context.TODO(), cm, requests); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to save server request: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, req)
+}
+
+// getServerRequest returns one request to its requester or an admin of its
+// namespace
+func (s *Server) getServerRequest(c *gin.Context) {
+	requests, _, err := s.loadServerRequests(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	i := findServerRequest(requests, c.Param("request"))
+	if i < 0 || !(requests[i].Requester == c.GetString(handlers.SubjectKey) || s.hasRole(c, requests[i].Namespace, roleAdmin)) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Server request not found",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, requests[i])
+}
+
+// approveServerRequest creates the requested GameServer, owned by the
+// requester. A failed creation leaves the request pending with the error.
+func (s *Server) approveServerRequest(c *gin.Context) {
+	s.decideServerRequest(c, requestApproved)
+}
+
+// denyServerRequest turns a request down
+func (s *Server) denyServerRequest(c *gin.Context) {
+	s.decideServerRequest(c, requestDenied)
+}
+
+// withdrawServerRequest lets the requester take back a pending request
+func (s *Server) withdrawServerRequest(c *gin.Context) {
+	requests, cm, err := s.loadServerRequests(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	i := findServerRequest(requests, c.Param("request"))
+	if i < 0 || requests[i].Requester != c.GetString(handlers.SubjectKey) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Server request not found",
+		})
+		return
+	}
+	if requests[i].Status != requestPending {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("Server request is already %s", requests[i].Status),
+		})
+		return
+	}
+	now := time.Now().UTC()
+	requests[i].Status = requestWithdrawn
+	requests[i].DecidedBy = requests[i].Requester
+	requests[i].DecidedAt = &now
+	if err := s.saveServerRequests(context.TODO(), cm, requests); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to save server request: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, requests[i])
+}
+
+// decideServerRequest approves or denies a pending request, for admins of
+// its namespace
+func (s *Server) decideServerRequest(c *gin.Context, status string) {
+	var body struct {
+		Comment string `json:"comment"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid request body: %v", err),
+			})
+			return
+		}
+	}
+
+	requests, cm, err := s.loadServerRequests(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	i := findServerRequest(requests, c.Param("request"))
+	if i < 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Server request not found",
+		})
+		return
+	}
+	request := &requests[i]
+	if !s.hasRole(c, request.Namespace, roleAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Only admins decide server requests",
+		})
+		return
+	}
+	if request.Status != requestPending {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("Server request is already %s", request.Status),
+		})
+		return
+	}
+
+	if status == requestApproved {
+		if !s.checkMaintenance(c, request.Namespace) {
+			return
+		}
+		target, err := s.clusters.clusterServer(request.Cluster)
+		if err == nil {
+			err = target.provisionServerRequest(c.Request.Context(), *request)
+		}
+		if err != nil {
+			request.Error = err.Error()
+			if saveErr := s.saveServerRequests(context.TODO(), cm, requests); saveErr != nil {
+				c.JSON(errorStatus(c, saveErr), gin.H{
+					"error": fmt.Sprintf("Failed to save server request: %v", saveErr),
+				})
+				return
+			}
+			c.JSON(errorStatus(c, err), gin.H{
+				"error":   fmt.Sprintf("Failed to create GameServer: %v", err),
+				"request": request,
+			})
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	request.Status = status
+	request.DecidedBy = c.GetString(handlers.SubjectKey)
+	request.DecidedAt = &now
+	request.Comment = body.Comment
+	request.Error = ""
+	if err := s.saveServerRequests(context.TODO(), cm, requests); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to save server request: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, request)
+}
+
+// provisionServerRequest creates an approved request's GameServer with the
+// same checks as a direct create
+func (s *Server) provisionServerRequest(ctx context.Context, request ServerRequest) error {
+	obj := request.claim()
+	if err := admission.Validate(obj); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+	quota, existing, err := s.namespaceQuota(ctx, request.Namespace)
+	if err != nil {
+		return err
+	}
+	if err := quota.Check(existing, obj); err != nil {
+		return apierrors.NewForbidden(gameServerGVR.GroupResource(), request.Name, fmt.Errorf("GameServer quota exceeded: %v", err))
+	}
+	if err := setReadyNotification(obj, ReadyNotification{Webhooks: []webhookTarget{}}); err != nil {
+		return err
+	}
+	if err := s.k8sClient.Create(ctx, obj); err != nil {
+		return err
+	}
+	s.publishEvent(eventGameServerCreated, obj.GetNamespace(), obj.GetName(), map[string]interface{}{
+		"gameType": request.GameType,
+		"request":  request.ID,
+	})
+	return nil
+}
+
+// newServerRequestID returns a random request ID
+func newServerRequestID() string {
+	id := make([]byte, 6)
+	_, _ = rand.Read(id)
+	return "req-" + hex.EncodeToString(id)
+}
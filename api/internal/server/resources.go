@@ -0,0 +1,597 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ResourceKind is an additional claim kind served by the generic resource
+// proxy, resolved from the XRD that offers it
+type ResourceKind struct {
+	Kind    string `json:"kind"`
+	Plural  string `json:"plural,omitempty"`
+	Group   string `json:"group,omitempty"`
+	Version string `json:"version,omitempty"`
+	// Composite is the kind of the composite resource claims bind to
+	Composite string                 `json:"composite,omitempty"`
+	Schema    map[string]interface{} `json:"schema,omitempty"`
+	// Error is set when the configured kind is offered by no XRD
+	Error string `json:"error,omitempty"`
+}
+
+// ResourceRequest creates or replaces a generic claim
+type ResourceRequest struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Namespace   string            `json:"namespace"`
+		Labels      map[string]string `json:"labels,omitempty"`
+		Annotations map[string]string `json:"annotations,omitempty"`
+	} `json:"metadata"`
+	Spec map[string]interface{} `json:"spec"`
+}
+
+// resourceKinds returns the claim kinds served by the generic resource
+// proxy, from RESOURCE_KINDS: a comma-separated list of kinds, each
+// optionally qualified by its group as in VoiceServer.voice.example.com
+func resourceKinds() []string {
+	return splitList(os.Getenv("RESOURCE_KINDS"))
+}
+
+// gvk returns the claim's GroupVersionKind
+func (k *ResourceKind) gvk() schema.GroupVersionKind {
+	return schema.GroupVersionKind{Group: k.Group, Version: k.Version, Kind: k.Kind}
+}
+
+// matches reports whether a route parameter names the kind: its kind or
+// plural, case-insensitively, optionally qualified by its group
+func (k *ResourceKind) matches(name string) bool {
+	name = strings.ToLower(name)
+	for _, candidate := range []string{k.Kind, k.Plural} {
+		candidate = strings.ToLower(candidate)
+		if candidate != "" && (name == candidate || name == candidate+"."+strings.ToLower(k.Group)) {
+			return true
+		}
+	}
+	return false
+}
+
+// servedResourceKinds resolves every configured kind against the XRDs.
+// Kinds no XRD offers are returned with Error set.
+func (s *Server) servedResourceKinds(ctx context.Context) ([]ResourceKind, error) {
+	configured := resourceKinds()
+	if len(configured) == 0 {
+		return []ResourceKind{}, nil
+	}
+	xrds, err := s.listPlatform(ctx, xrdListGVK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list XRDs: %w", err)
+	}
+
+	kinds := make([]ResourceKind, 0, len(configured))
+	for _, entry := range configured {
+		kind, group, _ := strings.Cut(entry, ".")
+		resolved := ResourceKind{Kind: kind, Group: group}
+		switch {
+		case kind == "GameServer":
+			resolved.Error = "GameServer claims are managed at /gameservers"
+		default:
+			resolved.Error = fmt.Sprintf("No XRD offers %s claims", entry)
+			for i := range xrds {
+				if resourceKindFromXRD(&xrds[i], kind, group, &resolved) {
+					resolved.Error = ""
+					break
+				}
+			}
+		}
+		kinds = append(kinds, resolved)
+	}
+	return kinds, nil
+}
+
+// resourceKindFromXRD fills kind from an XRD offering claims of the given
+// kind and, if set, group. It reports whether the XRD matched.
+func resourceKindFromXRD(xrd *unstructured.Unstructured, kind, group string, resolved *ResourceKind) bool {
+	claimKind, _, _ := unstructured.NestedString(xrd.Object, "spec", "claimNames", "kind")
+	xrdGroup, _, _ := unstructured.NestedString(xrd.Object, "spec", "group")
+	if claimKind != kind || (group != "" && xrdGroup != group) {
+		return false
+	}
+	resolved.Group = xrdGroup
+	resolved.Plural, _, _ = unstructured.NestedString(xrd.Object, "spec", "claimNames", "plural")
+	resolved.Composite, _, _ = unstructured.NestedString(xrd.Object, "spec", "names", "kind")
+
+	// Serve the referenceable version, or else the first served one
+	versions, _, _ := unstructured.NestedSlice(xrd.Object, "spec", "versions")
+	var chosen map[string]interface{}
+	for _, raw := range versions {
+		version, ok := raw.(map[string]interface{})
+		if !ok || version["served"] != true {
+			continue
+		}
+		if chosen == nil || version["referenceable"] == true {
+			chosen = version
+		}
+		if version["referenceable"] == true {
+			break
+		}
+	}
+	if chosen == nil {
+		resolved.Error = fmt.Sprintf("XRD %s serves no version", xrd.GetName())
+		return true
+	}
+	resolved.Version, _, _ = unstructured.NestedString(chosen, "name")
+	resolved.Schema, _, _ = unstructured.NestedMap(chosen, "schema", "openAPIV3Schema", "properties", "spec")
+	return true
+}
+
+// resourceKind resolves the :kind route parameter, responding with an
+// error and returning nil if it is not served
+func (s *Server) resourceKind(c *gin.Context) *ResourceKind {
+	kinds, err := s.servedResourceKinds(c.Request.Context())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to resolve resource kinds: %v", err),
+		})
+		return nil
+	}
+	for i := range kinds {
+		if !kinds[i].matches(c.Param("kind")) {
+			continue
+		}
+		if kinds[i].Error != "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": kinds[i].Error})
+			return nil
+		}
+		return &kinds[i]
+	}
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": fmt.Sprintf("Resource kind %s is not served; add it to RESOURCE_KINDS", c.Param("kind")),
+	})
+	return nil
+}
+
+// listResourceKinds returns the additional kinds the proxy serves
+func (s *Server) listResourceKinds(c *gin.Context) {
+	kinds, err := s.servedResourceKinds(c.Request.Context())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to resolve resource kinds: %v", err),
+		})
+		return
+	}
+	items, ok := selectFields(c, kinds)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(kinds),
+	})
+}
+
+// getResourceSchema returns the spec schema of a kind, for the dashboard to
+// render a form from
+func (s *Server) getResourceSchema(c *gin.Context) {
+	kind := s.resourceKind(c)
+	if kind == nil {
+		return
+	}
+	c.JSON(http.StatusOK, kind)
+}
+
+// listResources returns the claims of a kind in ?namespace=, or in every
+// namespace the caller may read when it is omitted or "all"
+func (s *Server) listResources(c *gin.Context) {
+	kind := s.resourceKind(c)
+	if kind == nil {
+		return
+	}
+	namespace := c.Query("namespace")
+	if namespace == "all" {
+		namespace = ""
+	}
+	scope, err := s.readableNamespaces(c)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to determine readable namespaces: %v", err),
+		})
+		return
+	}
+	if namespace != "" && !scope.allows(namespace) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Not permitted to access namespace %s", namespace),
+		})
+		return
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(kind.gvk().GroupVersion().WithKind(kind.Kind + "List"))
+	var listOpts []client.ListOption
+	if namespace != "" {
+		listOpts = append(listOpts, client.InNamespace(namespace))
+	}
+	if scope.all || len(scope.namespaces) > 0 {
+		if err := s.k8sClient.List(c.Request.Context(), list, listOpts...); err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to list %s claims: %v", kind.Kind, err),
+			})
+			return
+		}
+	}
+
+	resources := make([]map[string]interface{}, 0, len(list.Items))
+	for i := range list.Items {
+		if scope.allows(list.Items[i].GetNamespace()) {
+			resources = append(resources, list.Items[i].Object)
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		a, b := &unstructured.Unstructured{Object: resources[i]}, &unstructured.Unstructured{Object: resources[j]}
+		if a.GetNamespace() != b.GetNamespace() {
+			return a.GetNamespace() < b.GetNamespace()
+		}
+		return a.GetName() < b.GetName()
+	})
+
+	items, ok := selectFields(c, resources)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(resources),
+	})
+}
+
+// createResource validates a claim against its XRD's schema and creates it
+func (s *Server) createResource(c *gin.Context) {
+	kind := s.resourceKind(c)
+	if kind == nil {
+		return
+	}
+	var req ResourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if req.Metadata.Namespace == "" {
+		req.Metadata.Namespace = "default"
+	}
+	if !s.servedNamespaces(c.Request.Context())(req.Metadata.Namespace) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Not permitted to access namespace %s", req.Metadata.Namespace),
+		})
+		return
+	}
+	if scope, err := s.readableNamespaces(c); err != nil || !scope.allows(req.Metadata.Namespace) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Not permitted to access namespace %s", req.Metadata.Namespace),
+		})
+		return
+	}
+	if !s.checkMaintenance(c, req.Metadata.Namespace) {
+		return
+	}
+	if req.Metadata.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "metadata.name is required",
+		})
+		return
+	}
+	if !validateResourceSpec(c, kind, req.Spec) {
+		return
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": req.Spec}}
+	obj.SetGroupVersionKind(kind.gvk())
+	obj.SetName(req.Metadata.Name)
+	obj.SetNamespace(req.Metadata.Namespace)
+	obj.SetLabels(req.Metadata.Labels)
+	obj.SetAnnotations(req.Metadata.Annotations)
+	if err := s.k8sClient.Create(c.Request.Context(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to create %s: %v", kind.Kind, err),
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, obj.Object)
+}
+
+// getResource returns one claim
+func (s *Server) getResource(c *gin.Context) {
+	kind := s.resourceKind(c)
+	if kind == nil {
+		return
+	}
+	obj, ok := s.loadResource(c, kind)
+	if !ok {
+		return
+	}
+	shaped, ok := selectFields(c, obj.Object)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, shaped)
+}
+
+// updateResource replaces a claim's spec, and its labels and annotations
+// when given. Spec fields Crossplane manages are kept.
+func (s *Server) updateResource(c *gin.Context) {
+	kind := s.resourceKind(c)
+	if kind == nil {
+		return
+	}
+	var req ResourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if !validateResourceSpec(c, kind, req.Spec) {
+		return
+	}
+	obj, ok := s.loadResource(c, kind)
+	if !ok {
+		return
+	}
+
+	spec := req.Spec
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	liveSpec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	for _, field := range crossplaneSpecFields {
+		if value, ok := liveSpec[field]; ok {
+			spec[field] = value
+		}
+	}
+	obj.Object["spec"] = spec
+	if req.Metadata.Labels != nil {
+		obj.SetLabels(req.Metadata.Labels)
+	}
+	if req.Metadata.Annotations != nil {
+		obj.SetAnnotations(req.Metadata.Annotations)
+	}
+	if err := s.k8sClient.Update(c.Request.Context(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update %s: %v", kind.Kind, err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, obj.Object)
+}
+
+// deleteResource deletes a claim
+func (s *Server) deleteResource(c *gin.Context) {
+	kind := s.resourceKind(c)
+	if kind == nil {
+		return
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(kind.gvk())
+	obj.SetNamespace(c.Param("namespace"))
+	obj.SetName(c.Param("name"))
+	if err := s.k8sClient.Delete(c.Request.Context(), obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": fmt.Sprintf("%s not found", kind.Kind),
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete %s: %v", kind.Kind, err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("%s deleted successfully", kind.Kind),
+	})
+}
+
+// loadResource gets the claim named by the route, responding with an error
+// and returning false if it cannot be read
+func (s *Server) loadResource(c *gin.Context, kind *ResourceKind) (*unstructured.Unstructured, bool) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(kind.gvk())
+	key := client.ObjectKey{Namespace: c.Param("namespace"), Name: c.Param("name")}
+	if err := s.k8sClient.Get(c.Request.Context(), key, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": fmt.Sprintf("%s not found", kind.Kind),
+			})
+			return nil, false
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get %s: %v", kind.Kind, err),
+		})
+		return nil, false
+	}
+	return obj, true
+}
+
+// validateResourceSpec validates a spec against the kind's schema,
+// responding with 400 and every violation and returning false if invalid
+func validateResourceSpec(c *gin.Context, kind *ResourceKind, spec map[string]interface{}) bool {
+	if kind.Schema == nil {
+		return true
+	}
+	var value interface{} = spec
+	if spec == nil {
+		value = map[string]interface{}{}
+	}
+	violations := validateSchema("spec", value, kind.Schema, nil)
+	if len(violations) == 0 {
+		return true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":  fmt.Sprintf("Invalid %s spec: %s", kind.Kind, strings.Join(violations, "; ")),
+		"errors": violations,
+	})
+	return false
+}
+
+// validateSchema checks value against the subset of OpenAPI v3 that XRD
+// schemas use, appending a violation per failed constraint
+func validateSchema(path string, value interface{}, schema map[string]interface{}, violations []string) []string {
+	if value == nil {
+		if schema["nullable"] == true {
+			return violations
+		}
+		return append(violations, fmt.Sprintf("%s must not be null", path))
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return append(violations, fmt.Sprintf("%s must be an object", path))
+		}
+		return validateObject(path, object, schema, violations)
+	case "array":
+		array, ok := value.([]interface{})
+		if !ok {
+			return append(violations, fmt.Sprintf("%s must be an array", path))
+		}
+		if minimum, ok := schemaNumber(schema, "minItems"); ok && float64(len(array)) < minimum {
+			violations = append(violations, fmt.Sprintf("%s must have at least %v items", path, minimum))
+		}
+		if maximum, ok := schemaNumber(schema, "maxItems"); ok && float64(len(array)) > maximum {
+			violations = append(violations, fmt.Sprintf("%s must have at most %v items", path, maximum))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range array {
+				violations = validateSchema(fmt.Sprintf("%s[%d]", path, i), item, items, violations)
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return append(violations, fmt.Sprintf("%s must be a string", path))
+		}
+		if minimum, ok := schemaNumber(schema, "minLength"); ok && float64(len(str)) < minimum {
+			violations = append(violations, fmt.Sprintf("%s must be at least %v characters", path, minimum))
+		}
+		if maximum, ok := schemaNumber(schema, "maxLength"); ok && float64(len(str)) > maximum {
+			violations = append(violations, fmt.Sprintf("%s must be at most %v characters", path, maximum))
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(str) {
+				violations = append(violations, fmt.Sprintf("%s must match %s", path, pattern))
+			}
+		}
+	case "integer", "number":
+		number, ok := value.(float64)
+		if !ok {
+			if i, isInt := value.(int64); isInt {
+				number, ok = float64(i), true
+			}
+		}
+		if !ok {
+			return append(violations, fmt.Sprintf("%s must be a %s", path, schema["type"]))
+		}
+		if schema["type"] == "integer" && number != float64(int64(number)) {
+			return append(violations, fmt.Sprintf("%s must be an integer", path))
+		}
+		if minimum, ok := schemaNumber(schema, "minimum"); ok && number < minimum {
+			violations = append(violations, fmt.Sprintf("%s must be at least %v", path, minimum))
+		}
+		if maximum, ok := schemaNumber(schema, "maximum"); ok && number > maximum {
+			violations = append(violations, fmt.Sprintf("%s must be at most %v", path, maximum))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return append(violations, fmt.Sprintf("%s must be a boolean", path))
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		allowed := false
+		for _, option := range enum {
+			if fmt.Sprint(option) == fmt.Sprint(value) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			violations = append(violations, fmt.Sprintf("%s must be one of %v", path, enum))
+		}
+	}
+	return violations
+}
+
+// validateObject checks an object's required and declared properties.
+// Undeclared properties are rejected unless the schema preserves unknown
+// fields or gives additionalProperties; the spec fields Crossplane adds to
+// every claim are not in the XRD's schema and always allowed.
+func validateObject(path string, object, schema map[string]interface{}, violations []string) []string {
+	properties, _ := schema["properties"].(map[string]interface{})
+	required, _ := schema["required"].([]interface{})
+	for _, raw := range required {
+		if field, ok := raw.(string); ok {
+			if _, present := object[field]; !present {
+				violations = append(violations, fmt.Sprintf("%s.%s is required", path, field))
+			}
+		}
+	}
+
+	preserve := schema["x-kubernetes-preserve-unknown-fields"] == true
+	additional, _ := schema["additionalProperties"].(map[string]interface{})
+	fields := make([]string, 0, len(object))
+	for field := range object {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		if property, ok := properties[field].(map[string]interface{}); ok {
+			violations = validateSchema(path+"."+field, object[field], property, violations)
+			continue
+		}
+		switch {
+		case additional != nil:
+			violations = validateSchema(path+"."+field, object[field], additional, violations)
+		case preserve || schema["additionalProperties"] == true:
+		case path == "spec" && isCrossplaneSpecField(field):
+		case properties == nil && schema["additionalProperties"] == nil:
+			// An object without declared properties holds anything
+		default:
+			violations = append(violations, fmt.Sprintf("%s.%s is not a known field", path, field))
+		}
+	}
+	return violations
+}
+
+// isCrossplaneSpecField reports whether a claim spec field is one Crossplane
+// adds to every claim
+func isCrossplaneSpecField(field string) bool {
+	for _, known := range crossplaneSpecFields {
+		if field == known {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaNumber reads a numeric schema constraint
+func schemaNumber(schema map[string]interface{}, key string) (float64, bool) {
+	switch n := schema[key].(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// conditionPendingRestart is set on a GameServer when applied config
+	// changes only take effect after the game server pod restarts
+	conditionPendingRestart = "PendingRestart"
+
+	// pendingRestartFieldsAnnotation records the fields waiting for a restart
+	pendingRestartFieldsAnnotation = "gameplane.kubelize.io/pending-restart-fields"
+)
+
+// gameServerUpdateResponse is a GameServer annotated with restart information
+type gameServerUpdateResponse struct {
+	*GameServer
+	RestartRequired bool     `json:"restartRequired"`
+	RestartFields   []string `json:"restartFields,omitempty"`
+}
+
+// applyPendingRestart restarts a GameServer to pick up config changes that
+// are waiting on the PendingRestart condition
+func (s *Server) applyPendingRestart(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	obj, err := s.getGameServerObject(context.TODO(), namespace, name)
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+
+	pendingFields := pendingRestartFields(obj)
+	if len(pendingFields) == 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "GameServer has no pending restart",
+		})
+		return
+	}
+
+	pods, podNamespace, err := s.findGameServerPods(context.TODO(), obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to find pods: %v", err),
+		})
+		return
+	}
+	if len(pods) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("No pods found for GameServer %s in namespace %s", name, podNamespace),
+		})
+		return
+	}
+
+	restarted, err := s.deleteGameServerPods(context.TODO(), podNamespace, pods, nil)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to restart GameServer: %v", err),
+		})
+		return
+	}
+
+	// Clear the pending state now that the pods are being replaced
+	if err := s.clearPendingRestart(context.TODO(), obj, "Pending configuration changes were applied by a restart"); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("GameServer restarted but failed to clear pending state: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       fmt.Sprintf("GameServer %s restarted to apply pending changes", name),
+		"pods":          restarted,
+		"appliedFields": pendingFields,
+	})
+}
+
+// markPendingRestart merges fields into the pending restart annotation. It
+// must be called before the GameServer is written; recordPendingRestart then
+// mirrors the state into the status condition.
+func markPendingRestart(obj *unstructured.Unstructured, fields []string) []string {
+	merged := map[string]bool{}
+	for _, f := range pendingRestartFields(obj) {
+		merged[f] = true
+	}
+	for _, f := range fields {
+		merged[f] = true
+	}
+
+	all := make([]string, 0, len(merged))
+	for f := range merged {
+		all = append(all, f)
+	}
+	sort.Strings(all)
+
+	raw, _ := json.Marshal(all)
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[pendingRestartFieldsAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+	return all
+}
+
+// recordPendingRestart sets the PendingRestart condition on a GameServer
+func (s *Server) recordPendingRestart(ctx context.Context, obj *unstructured.Unstructured, fields []string) error {
+	if err := setGameServerCondition(obj, metav1.Condition{
+		Type:    conditionPendingRestart,
+		Status:  metav1.ConditionTrue,
+		Reason:  "ConfigChanged",
+		Message: fmt.Sprintf("Restart required to apply: %s", strings.Join(fields, ", ")),
+	}); err != nil {
+		return err
+	}
+	return s.k8sClient.Status().Update(ctx, obj)
+}
+
+// clearPendingRestart removes the pending restart annotation and marks the
+// PendingRestart condition False after the pods were replaced
+func (s *Server) clearPendingRestart(ctx context.Context, obj *unstructured.Unstructured, message string) error {
+	annotations := obj.GetAnnotations()
+	delete(annotations, pendingRestartFieldsAnnotation)
+	obj.SetAnnotations(annotations)
+	if err := s.k8sClient.Update(ctx, obj); err != nil {
+		return err
+	}
+	if err := setGameServerCondition(obj, metav1.Condition{
+		Type:    conditionPendingRestart,
+		Status:  metav1.ConditionFalse,
+		Reason:  "Restarted",
+		Message: message,
+	}); err != nil {
+		return nil
+	}
+	return s.k8sClient.Status().Update(ctx, obj)
+}
+
+// deleteGameServerPods deletes pods so their Deployment replaces them. A
+// zero grace period kills the game without letting it save on shutdown.
+func (s *Server) deleteGameServerPods(ctx context.Context, namespace string, pods []corev1.Pod, gracePeriod *int64) ([]string, error) {
+	deleted := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		if err := s.kubeClient.CoreV1().Pods(namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{GracePeriodSeconds: gracePeriod}); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, pod.Name)
+	}
+	return deleted, nil
+}
+
+// pendingRestartFields returns the fields recorded as waiting for a restart
+func pendingRestartFields(obj *unstructured.Unstructured) []string {
+	raw, ok := obj.GetAnnotations()[pendingRestartFieldsAnnotation]
+	if !ok || raw == "" {
+		return nil
+	}
+	var fields []string
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		return nil
+	}
+	return fields
+}
+
+// setGameServerCondition adds or updates a condition in an unstructured status
+func setGameServerCondition(obj *unstructured.Unstructured, condition metav1.Condition) error {
+	return k8s.SetCondition(obj, condition)
+}
+
+// gameServerConditions reads status.conditions from an unstructured GameServer
+func gameServerConditions(obj *unstructured.Unstructured) ([]metav1.Condition, error) {
+	return k8s.Conditions(obj)
+}
+
+// managedNamespace returns the namespace Crossplane created for a GameServer's
+// workload: {resourceRef.name}-{gameType}
+func managedNamespace(obj *unstructured.Unstructured) (string, error) {
+	return k8s.ManagedNamespace(obj)
+}
+
+// findGameServerPods lists the game server pods in the managed namespace
+func (s *Server) findGameServerPods(ctx context.Context, obj *unstructured.Unstructured) ([]corev1.Pod, string, error) {
+	return k8s.GameServerPods(ctx, s.kubeClient, obj)
+}
+
+// runningPodIP returns the address of a running game server pod
+func (s *Server) runningPodIP(ctx context.Context, obj *unstructured.Unstructured) (string, error) {
+	pods, namespace, err := s.findGameServerPods(ctx, obj)
+	if err != nil {
+		return "", err
+	}
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodRunning && pod.Status.PodIP != "" {
+			return pod.Status.PodIP, nil
+		}
+	}
+	return "", fmt.Errorf("no running pod found for GameServer in namespace %s", namespace)
+}
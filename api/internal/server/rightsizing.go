@@ -0,0 +1,252 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// cpuHeadroom and memoryHeadroom are added on top of observed usage
+	cpuHeadroom    = 1.25
+	memoryHeadroom = 1.2
+
+	// cpuStepMillis and memoryStepBytes round recommendations up
+	cpuStepMillis   = 250
+	memoryStepBytes = 256 << 20
+
+	// minRecommendedCPU and minRecommendedMemory keep recommendations usable
+	minRecommendedCPU    = 500
+	minRecommendedMemory = 1 << 30
+
+	// rightsizingThreshold is the relative change below which the current
+	// value is kept
+	rightsizingThreshold = 0.1
+)
+
+// ResourceRecommendation suggests new CPU and memory for a GameServer
+type ResourceRecommendation struct {
+	Range       string               `json:"range"`
+	Hours       int                  `json:"hours"`
+	Confidence  string               `json:"confidence"` // low, medium or high
+	Current     GameServerResources  `json:"current"`
+	Recommended GameServerResources  `json:"recommended"`
+	Usage       ResourceUsageSummary `json:"usage"`
+	Changed     bool                 `json:"changed"`
+	Savings     CostBreakdown        `json:"monthlySavings"`
+	Currency    string               `json:"currency"`
+	Notes       []string             `json:"notes,omitempty"`
+}
+
+// ResourceUsageSummary is the observed usage behind a recommendation
+type ResourceUsageSummary struct {
+	CPUP95      string `json:"cpuP95"`
+	CPUPeak     string `json:"cpuPeak"`
+	MemoryPeak  string `json:"memoryPeak"`
+	PlayersPeak int    `json:"playersPeak"`
+}
+
+// getGameServerRecommendations suggests CPU and memory from the usage
+// recorded over ?range= (default 14d)
+func (s *Server) getGameServerRecommendations(c *gin.Context) {
+	_, recommendation, ok := s.loadRecommendation(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, recommendation)
+}
+
+// applyGameServerRecommendations writes the recommended CPU and memory to the
+// GameServer. Low-confidence recommendations need ?force=true.
+func (s *Server) applyGameServerRecommendations(c *gin.Context) {
+	obj, recommendation, ok := s.loadRecommendation(c)
+	if !ok {
+		return
+	}
+	if !recommendation.Changed {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":          "GameServer resources already match the recommendation",
+			"recommendation": recommendation,
+		})
+		return
+	}
+	if recommendation.Confidence == "low" && c.Query("force") != "true" {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":          "Recommendation has low confidence; retry with ?force=true to apply it anyway",
+			"recommendation": recommendation,
+		})
+		return
+	}
+
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	if spec == nil {
+		spec = map[string]interface{}{}
+	}
+	resources, _, _ := unstructured.NestedMap(spec, "resources")
+	if resources == nil {
+		resources = map[string]interface{}{}
+	}
+	resources["cpu"] = recommendation.Recommended.CPU
+	resources["memory"] = recommendation.Recommended.Memory
+	spec["resources"] = resources
+
+	restartFields, err := s.writeGameServerSpec(context.TODO(), obj, spec)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update GameServer resources: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recommendation":  recommendation,
+		"restartRequired": len(restartFields) > 0,
+		"restartFields":   restartFields,
+	})
+}
+
+// loadRecommendation fetches the GameServer named in the route and computes
+// its recommendation, writing the error response itself when it cannot
+func (s *Server) loadRecommendation(c *gin.Context) (*unstructured.Unstructured, ResourceRecommendation, bool) {
+	rangeParam := c.DefaultQuery("range", "14d")
+	window, err := parseRange(rangeParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return nil, ResourceRecommendation{}, false
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return nil, ResourceRecommendation{}, false
+	}
+	history, err := s.gameServerUsage(context.TODO(), obj, window)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to read GameServer usage: %v", err),
+		})
+		return nil, ResourceRecommendation{}, false
+	}
+
+	recommendation := recommendResources(obj, history)
+	recommendation.Range = rangeParam
+	return obj, recommendation, true
+}
+
+// recommendResources sizes CPU on the 95th percentile of hourly peaks, since
+// short bursts are absorbed by throttling, and memory on the overall peak,
+// since running out of it kills the server
+func recommendResources(obj *unstructured.Unstructured, history []UsageHour) ResourceRecommendation {
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	current := effectiveResources(gameType, gameServerResources(obj))
+	rates := loadCostRates()
+	recommendation := ResourceRecommendation{
+		Current:     current,
+		Recommended: current,
+		Currency:    rates.Currency,
+		Notes:       []string{},
+	}
+
+	cpuPeaks := []int64{}
+	var memoryPeak int64
+	for _, hour := range history {
+		if hour.PlayersMax > recommendation.Usage.PlayersPeak {
+			recommendation.Usage.PlayersPeak = hour.PlayersMax
+		}
+		if hour.MetricSamples == 0 {
+			continue
+		}
+		cpuPeaks = append(cpuPeaks, hour.CPUMax)
+		if hour.MemoryMax > memoryPeak {
+			memoryPeak = hour.MemoryMax
+		}
+	}
+	recommendation.Hours = len(cpuPeaks)
+	if len(cpuPeaks) == 0 {
+		recommendation.Confidence = "low"
+		recommendation.Notes = append(recommendation.Notes, "No usage has been recorded yet; metrics-server is required")
+		return recommendation
+	}
+
+	sort.Slice(cpuPeaks, func(i, j int) bool { return cpuPeaks[i] < cpuPeaks[j] })
+	cpuP95 := cpuPeaks[int(math.Ceil(float64(len(cpuPeaks))*0.95))-1]
+	recommendation.Usage.CPUP95 = formatMillicores(cpuP95)
+	recommendation.Usage.CPUPeak = formatMillicores(cpuPeaks[len(cpuPeaks)-1])
+	recommendation.Usage.MemoryPeak = formatMemoryBytes(memoryPeak)
+
+	switch {
+	case recommendation.Hours >= 7*24:
+		recommendation.Confidence = "high"
+	case recommendation.Hours >= 48:
+		recommendation.Confidence = "medium"
+	default:
+		recommendation.Confidence = "low"
+		recommendation.Notes = append(recommendation.Notes, "Less than two days of usage recorded")
+	}
+	if recommendation.Usage.PlayersPeak == 0 {
+		recommendation.Confidence = "low"
+		recommendation.Notes = append(recommendation.Notes, "No players were online while usage was recorded, so load under play is unknown")
+	}
+
+	currentCPU := quantityMilli(current.CPU)
+	cpu := roundUp(int64(float64(cpuP95)*cpuHeadroom), cpuStepMillis, minRecommendedCPU)
+	if changedEnough(currentCPU, cpu) {
+		recommendation.Recommended.CPU = formatMillicores(cpu)
+	}
+	currentMemory := quantityMilli(current.Memory) / 1000
+	memory := roundUp(int64(float64(memoryPeak)*memoryHeadroom), memoryStepBytes, minRecommendedMemory)
+	if changedEnough(currentMemory, memory) {
+		recommendation.Recommended.Memory = formatMemoryBytes(memory)
+	}
+	recommendation.Changed = recommendation.Recommended != current
+
+	if recommendation.Changed {
+		before := rates.hourlyCost(current)
+		after := rates.hourlyCost(recommendation.Recommended)
+		recommendation.Savings = CostBreakdown{
+			CPU:     before.CPU - after.CPU,
+			Memory:  before.Memory - after.Memory,
+			Storage: before.Storage - after.Storage,
+			Total:   before.Total - after.Total,
+		}.scale(hoursPerMonth)
+		if recommendation.Savings.Total < 0 {
+			recommendation.Notes = append(recommendation.Notes, "Observed usage is close to the configured limits; more resources are recommended")
+		}
+	}
+	return recommendation
+}
+
+// changedEnough reports whether a recommended value differs from the current
+// one by more than rightsizingThreshold
+func changedEnough(current, recommended int64) bool {
+	if current <= 0 {
+		return true
+	}
+	return math.Abs(float64(recommended-current))/float64(current) > rightsizingThreshold
+}
+
+// roundUp rounds a value up to a multiple of step, with a floor
+func roundUp(value, step, floor int64) int64 {
+	value = (value + step - 1) / step * step
+	if value < floor {
+		return floor
+	}
+	return value
+}
+
+// formatMillicores renders millicores as a CPU quantity
+func formatMillicores(millis int64) string {
+	return resource.NewMilliQuantity(millis, resource.DecimalSI).String()
+}
+
+// formatMemoryBytes renders bytes as a memory quantity
+func formatMemoryBytes(bytes int64) string {
+	return resource.NewQuantity(bytes, resource.BinarySI).String()
+}
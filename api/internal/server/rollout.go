@@ -0,0 +1,571 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// rolloutTimeout bounds a whole rollout, including time spent paused
+	rolloutTimeout = 24 * time.Hour
+
+	// defaultRolloutReadyTimeout is how long a server may take to become
+	// ready again after it was updated
+	defaultRolloutReadyTimeout = 10 * time.Minute
+
+	// rolloutPollInterval is how often updated servers are checked
+	rolloutPollInterval = 5 * time.Second
+)
+
+// Rollout target states
+const (
+	rolloutPending   = "Pending"
+	rolloutUpdating  = "Updating"
+	rolloutUpdated   = "Updated"
+	rolloutSkipped   = "Skipped"
+	rolloutFailed    = "Failed"
+	rolloutPaused    = "Paused"
+	rolloutRunning   = "Running"
+	rolloutCompleted = "Completed"
+	rolloutAborted   = "Aborted"
+)
+
+// RolloutRequest applies a composition revision or image to a selection of
+// GameServers in waves
+type RolloutRequest struct {
+	Selector ServerSelector  `json:"selector"`
+	Target   RolloutTarget   `json:"target"`
+	Strategy RolloutStrategy `json:"strategy"`
+	// DryRun returns the planned waves without changing anything
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// RolloutTarget is the change applied to every selected GameServer
+type RolloutTarget struct {
+	// CompositionRevision pins the claim to a composition revision
+	CompositionRevision string `json:"compositionRevision,omitempty"`
+	// Image replaces the game container image
+	Image string `json:"image,omitempty"`
+}
+
+// RolloutStrategy controls the pace of a rollout
+type RolloutStrategy struct {
+	// MaxUnavailable is the wave size: servers updated at the same time
+	MaxUnavailable int `json:"maxUnavailable,omitempty"`
+	// ReadyTimeout is how long each server may take to become ready again
+	ReadyTimeout string `json:"readyTimeout,omitempty"`
+	// MaxFailures is how many servers may fail before the rollout pauses
+	MaxFailures int `json:"maxFailures,omitempty"`
+	// PauseBetweenWaves waits between waves, e.g. to watch dashboards
+	PauseBetweenWaves string `json:"pauseBetweenWaves,omitempty"`
+}
+
+// RolloutProgress is reported as the rollout operation's result
+type RolloutProgress struct {
+	Phase  string                  `json:"phase"`
+	Target RolloutTarget           `json:"target"`
+	Waves  [][]RolloutServerStatus `json:"waves"`
+	// Skipped are selected servers left alone: Fleet members, whose Fleet
+	// would revert the change, and servers already at the target
+	Skipped []RolloutServerStatus `json:"skipped"`
+	Counts  map[string]int        `json:"counts"`
+	Message string                `json:"message,omitempty"`
+}
+
+// RolloutServerStatus is the state of one GameServer in a rollout
+type RolloutServerStatus struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Message   string `json:"message,omitempty"`
+}
+
+// rolloutControls holds the rollout in progress; one runs at a time so
+// concurrent rollouts don't fight over the same servers
+type rolloutControls struct {
+	mu      sync.Mutex
+	id      string
+	current *rolloutControl
+}
+
+// rolloutControl signals a paused rollout
+type rolloutControl struct {
+	resume chan struct{}
+	abort  chan struct{}
+	once   sync.Once
+}
+
+// begin reserves the rollout slot, or returns the ID of the running rollout
+func (r *rolloutControls) begin() (*rolloutControl, string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.current != nil {
+		return nil, r.id, false
+	}
+	r.current = &rolloutControl{resume: make(chan struct{}, 1), abort: make(chan struct{})}
+	r.id = ""
+	return r.current, "", true
+}
+
+// started records the operation ID of the reserved rollout
+func (r *rolloutControls) started(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.id = id
+}
+
+// get returns the controls of the running rollout with the given ID
+func (r *rolloutControls) get(id string) (*rolloutControl, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.current == nil || r.id != id {
+		return nil, false
+	}
+	return r.current, true
+}
+
+// end frees the rollout slot
+func (r *rolloutControls) end() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.current = nil
+	r.id = ""
+}
+
+// validate checks a rollout request and fills in defaults
+func (r *RolloutRequest) validate() error {
+	if r.Target.CompositionRevision == "" && r.Target.Image == "" {
+		return fmt.Errorf("target.compositionRevision or target.image is required")
+	}
+	if err := r.Selector.validate(); err != nil {
+		return err
+	}
+	if r.Strategy.MaxUnavailable == 0 {
+		r.Strategy.MaxUnavailable = 1
+	}
+	if r.Strategy.MaxUnavailable < 0 || r.Strategy.MaxFailures < 0 {
+		return fmt.Errorf("strategy.maxUnavailable and strategy.maxFailures must not be negative")
+	}
+	if r.Strategy.ReadyTimeout == "" {
+		r.Strategy.ReadyTimeout = defaultRolloutReadyTimeout.String()
+	}
+	for _, value := range []string{r.Strategy.ReadyTimeout, r.Strategy.PauseBetweenWaves} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			return fmt.Errorf("invalid strategy duration %q", value)
+		}
+	}
+	return nil
+}
+
+// startRollout plans a rollout and runs it as an operation
+func (s *Server) startRollout(c *gin.Context) {
+	var req RolloutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	scope, err := s.readableNamespaces(c)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to determine readable namespaces: %v", err),
+		})
+		return
+	}
+	if req.Selector.Namespace != "" && !scope.allows(req.Selector.Namespace) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": fmt.Sprintf("Not permitted to access namespace %s", req.Selector.Namespace),
+		})
+		return
+	}
+
+	progress, err := s.planRollout(context.TODO(), req, scope)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to select GameServers: %v", err),
+		})
+		return
+	}
+	if req.DryRun {
+		c.JSON(http.StatusOK, progress)
+		return
+	}
+	if len(progress.Waves) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":    "No GameServers need updating",
+			"progress": progress,
+		})
+		return
+	}
+	control, running, ok := s.rollouts.begin()
+	if !ok {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("Rollout %s is still running; abort it or wait for it to finish", running),
+		})
+		return
+	}
+
+	steps := make([]string, len(progress.Waves))
+	for i := range progress.Waves {
+		steps[i] = fmt.Sprintf("wave-%d", i+1)
+	}
+	op := s.startOperation("rollout", req.Selector.Namespace, rolloutName(req.Target), steps, rolloutTimeout, func(ctx context.Context, t *operationTracker) (interface{}, error) {
+		defer s.rollouts.end()
+		return s.runRollout(ctx, t, control, req, progress)
+	})
+	s.rollouts.started(op.ID)
+	c.JSON(http.StatusAccepted, op)
+}
+
+// resumeRollout continues a rollout paused after failures
+func (s *Server) resumeRollout(c *gin.Context) {
+	control, ok := s.rollouts.get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Rollout not found or already finished",
+		})
+		return
+	}
+	select {
+	case control.resume <- struct{}{}:
+	default:
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Rollout resumed",
+	})
+}
+
+// abortRollout stops a rollout after its current wave
+func (s *Server) abortRollout(c *gin.Context) {
+	control, ok := s.rollouts.get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Rollout not found or already finished",
+		})
+		return
+	}
+	control.once.Do(func() { close(control.abort) })
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Rollout aborted",
+	})
+}
+
+// planRollout selects the GameServers and splits them into waves
+func (s *Server) planRollout(ctx context.Context, req RolloutRequest, scope namespaceScope) (*RolloutProgress, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list, req.Selector.listOptions()...); err != nil {
+		return nil, err
+	}
+
+	progress := &RolloutProgress{Phase: rolloutPending, Target: req.Target, Waves: [][]RolloutServerStatus{}}
+	pending, skipped := []RolloutServerStatus{}, []RolloutServerStatus{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		if !scope.allows(obj.GetNamespace()) || !req.Selector.matches(obj) {
+			continue
+		}
+		status := RolloutServerStatus{Namespace: obj.GetNamespace(), Name: obj.GetName(), Status: rolloutPending}
+		switch {
+		case obj.GetLabels()[fleetLabel] != "":
+			status.Status = rolloutSkipped
+			status.Message = "managed by Fleet " + obj.GetLabels()[fleetLabel]
+			skipped = append(skipped, status)
+		case rolloutApplied(obj, req.Target):
+			status.Status = rolloutSkipped
+			status.Message = "already at target"
+			skipped = append(skipped, status)
+		default:
+			pending = append(pending, status)
+		}
+	}
+	byRef := func(items []RolloutServerStatus) {
+		sort.Slice(items, func(i, j int) bool {
+			if items[i].Namespace != items[j].Namespace {
+				return items[i].Namespace < items[j].Namespace
+			}
+			return items[i].Name < items[j].Name
+		})
+	}
+	byRef(pending)
+	byRef(skipped)
+
+	for start := 0; start < len(pending); start += req.Strategy.MaxUnavailable {
+		end := start + req.Strategy.MaxUnavailable
+		if end > len(pending) {
+			end = len(pending)
+		}
+		progress.Waves = append(progress.Waves, pending[start:end])
+	}
+	progress.Skipped = skipped
+	progress.count()
+	return progress, nil
+}
+
+// runRollout updates one wave at a time, pausing when failures exceed
+// maxFailures until the rollout is resumed or aborted
+func (s *Server) runRollout(ctx context.Context, t *operationTracker, control *rolloutControl, req RolloutRequest, progress *RolloutProgress) (interface{}, error) {
+	readyTimeout, _ := time.ParseDuration(req.Strategy.ReadyTimeout)
+	pauseBetween, _ := time.ParseDuration(req.Strategy.PauseBetweenWaves)
+	report := func(phase, message string) {
+		progress.Phase = phase
+		progress.Message = message
+		progress.count()
+		snapshot := progress.snapshot()
+		t.update(func(op *Operation) { op.Result = snapshot })
+	}
+
+	failures := 0
+	for i := range progress.Waves {
+		wave := progress.Waves[i]
+		if i > 0 && pauseBetween > 0 {
+			report(rolloutRunning, fmt.Sprintf("Waiting %s before wave %d", pauseBetween, i+1))
+			select {
+			case <-time.After(pauseBetween):
+			case <-control.abort:
+				report(rolloutAborted, fmt.Sprintf("Aborted before wave %d", i+1))
+				return progress.snapshot(), fmt.Errorf("rollout aborted")
+			case <-ctx.Done():
+				return progress.snapshot(), ctx.Err()
+			}
+		}
+
+		err := t.step(fmt.Sprintf("wave-%d", i+1), func() (string, error) {
+			for j := range wave {
+				wave[j].Status = rolloutUpdating
+			}
+			report(rolloutRunning, fmt.Sprintf("Updating wave %d of %d", i+1, len(progress.Waves)))
+
+			var wg sync.WaitGroup
+			for j := range wave {
+				wg.Add(1)
+				go func(server *RolloutServerStatus) {
+					defer wg.Done()
+					if err := s.rolloutServer(ctx, t.op.ID, server.Namespace, server.Name, req.Target, readyTimeout); err != nil {
+						server.Status = rolloutFailed
+						server.Message = err.Error()
+						return
+					}
+					server.Status = rolloutUpdated
+				}(&wave[j])
+			}
+			wg.Wait()
+
+			failed := []RolloutServerStatus{}
+			for _, server := range wave {
+				if server.Status == rolloutFailed {
+					failed = append(failed, server)
+				}
+			}
+			failures += len(failed)
+			if len(failed) > 0 {
+				return "", fmt.Errorf("%d of %d servers failed: %s", len(failed), len(wave), describeRolloutServers(failed))
+			}
+			return fmt.Sprintf("%d servers updated", len(wave)), nil
+		})
+
+		if err == nil || failures <= req.Strategy.MaxFailures || i == len(progress.Waves)-1 {
+			continue
+		}
+		report(rolloutPaused, fmt.Sprintf("Paused after %d failed servers; resume or abort the rollout", failures))
+		select {
+		case <-control.resume:
+			// Further failures pause again
+			req.Strategy.MaxFailures = failures
+		case <-control.abort:
+			report(rolloutAborted, fmt.Sprintf("Aborted after wave %d", i+1))
+			return progress.snapshot(), fmt.Errorf("rollout aborted after %d failed servers", failures)
+		case <-ctx.Done():
+			return progress.snapshot(), ctx.Err()
+		}
+	}
+
+	if failures > 0 {
+		report(rolloutCompleted, fmt.Sprintf("Completed with %d failed servers", failures))
+		return progress.snapshot(), fmt.Errorf("%d servers failed to update", failures)
+	}
+	report(rolloutCompleted, "All servers updated")
+	return progress.snapshot(), nil
+}
+
+// rolloutServer snapshots one GameServer, applies the target to it and waits
+// for it to become ready again
+func (s *Server) rolloutServer(ctx context.Context, operationID, namespace, name string, target RolloutTarget, readyTimeout time.Duration) error {
+	obj, err := s.getGameServerObject(ctx, namespace, name)
+	if err != nil {
+		return err
+	}
+	if _, err := s.safetySnapshot(ctx, obj, "rollout", operationID); err != nil {
+		return fmt.Errorf("safety snapshot failed: %w", err)
+	}
+	if err := applyRolloutTarget(obj, target); err != nil {
+		return err
+	}
+	if err := s.k8sClient.Update(ctx, obj); err != nil {
+		return fmt.Errorf("failed to update: %w", err)
+	}
+	s.publishEvent(eventGameServerUpdated, namespace, name, map[string]interface{}{"rollout": target})
+	_, err = s.waitForRolloutReady(ctx, namespace, name, target.Image, readyTimeout)
+	return err
+}
+
+// applyRolloutTarget sets the target revision and image on a claim
+func applyRolloutTarget(obj *unstructured.Unstructured, target RolloutTarget) error {
+	if target.CompositionRevision != "" {
+		if err := unstructured.SetNestedField(obj.Object, target.CompositionRevision, "spec", "compositionRevisionRef", "name"); err != nil {
+			return err
+		}
+		if err := unstructured.SetNestedField(obj.Object, "Manual", "spec", "compositionUpdatePolicy"); err != nil {
+			return err
+		}
+	}
+	if target.Image != "" {
+		if err := unstructured.SetNestedField(obj.Object, target.Image, "spec", "advanced", "image"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForRolloutReady polls until a GameServer is ready and all its game
+// pods are, running the image if one is given, and returns its pods
+func (s *Server) waitForRolloutReady(ctx context.Context, namespace, name, image string, readyTimeout time.Duration) ([]corev1.Pod, error) {
+	ctx, cancel := context.WithTimeout(ctx, readyTimeout)
+	defer cancel()
+	ticker := time.NewTicker(rolloutPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("not ready within %s", readyTimeout)
+		case <-ticker.C:
+		}
+		latest, err := s.getGameServerObject(ctx, namespace, name)
+		if err != nil || !gameServerReady(latest) {
+			continue
+		}
+		pods, _, err := s.findGameServerPods(ctx, latest)
+		if err == nil && rolloutPodsReady(pods, image) {
+			return pods, nil
+		}
+	}
+}
+
+// rolloutPodsReady reports whether every game pod is ready and, for image
+// rollouts, runs the new image
+func rolloutPodsReady(pods []corev1.Pod, image string) bool {
+	if len(pods) == 0 {
+		return false
+	}
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning {
+			return false
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status != corev1.ConditionTrue {
+				return false
+			}
+		}
+		if image == "" {
+			continue
+		}
+		found := false
+		for _, container := range pod.Spec.Containers {
+			if container.Image == image {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// rolloutApplied reports whether a GameServer already has the target
+func rolloutApplied(obj *unstructured.Unstructured, target RolloutTarget) bool {
+	if target.CompositionRevision != "" {
+		if revision, _, _ := unstructured.NestedString(obj.Object, "spec", "compositionRevisionRef", "name"); revision != target.CompositionRevision {
+			return false
+		}
+	}
+	if target.Image != "" {
+		if image, _, _ := unstructured.NestedString(obj.Object, "spec", "advanced", "image"); image != target.Image {
+			return false
+		}
+	}
+	return true
+}
+
+// rolloutName describes a target for the operation name
+func rolloutName(target RolloutTarget) string {
+	parts := []string{}
+	if target.CompositionRevision != "" {
+		parts = append(parts, "revision "+target.CompositionRevision)
+	}
+	if target.Image != "" {
+		parts = append(parts, "image "+target.Image)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describeRolloutServers lists servers with their messages
+func describeRolloutServers(servers []RolloutServerStatus) string {
+	parts := make([]string, len(servers))
+	for i, server := range servers {
+		parts[i] = fmt.Sprintf("%s/%s (%s)", server.Namespace, server.Name, server.Message)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// count tallies servers by status
+func (p *RolloutProgress) count() {
+	p.Counts = map[string]int{}
+	for _, wave := range p.Waves {
+		for _, server := range wave {
+			p.Counts[server.Status]++
+		}
+	}
+	if len(p.Skipped) > 0 {
+		p.Counts[rolloutSkipped] = len(p.Skipped)
+	}
+}
+
+// snapshot copies the progress for the operation result
+func (p *RolloutProgress) snapshot() RolloutProgress {
+	out := *p
+	out.Waves = make([][]RolloutServerStatus, len(p.Waves))
+	for i, wave := range p.Waves {
+		out.Waves[i] = append([]RolloutServerStatus(nil), wave...)
+	}
+	out.Skipped = append([]RolloutServerStatus{}, p.Skipped...)
+	out.Counts = map[string]int{}
+	for status, count := range p.Counts {
+		out.Counts[status] = count
+	}
+	return out
+}
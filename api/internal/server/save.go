@@ -0,0 +1,49 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// saveTimeout bounds a save requested through the API, including the
+// console connection
+const saveTimeout = 30 * time.Second
+
+// saveGameServer flushes a GameServer's world to disk with the game's save
+// command and answers once the game confirms it
+func (s *Server) saveGameServer(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), saveTimeout)
+	defer cancel()
+	started := time.Now()
+	output, err := s.saveWorldInGame(ctx, obj)
+	if err != nil {
+		status := http.StatusBadGateway
+		switch {
+		case errors.Is(err, errConsoleUnsupported):
+			status = http.StatusBadRequest
+		case errors.Is(err, context.DeadlineExceeded):
+			status = http.StatusGatewayTimeout
+		}
+		c.JSON(status, gin.H{
+			"error":  fmt.Sprintf("Failed to save world: %v", err),
+			"output": output,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "World saved",
+		"output":   output,
+		"duration": time.Since(started).Round(time.Millisecond).String(),
+	})
+}
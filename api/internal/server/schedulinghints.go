@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// SchedulingHint warns that a new GameServer's pod will likely stay Pending,
+// judged against the nodes' current free capacity
+type SchedulingHint struct {
+	// Feasible is set when some node has room for the pod right now
+	Feasible bool `json:"feasible"`
+	// Eligible counts the nodes the pod may be placed on at all
+	Eligible int `json:"eligible"`
+	// Fitting counts the eligible nodes with enough free CPU and memory
+	Fitting  int      `json:"fitting"`
+	CPU      string   `json:"cpu"`
+	Memory   string   `json:"memory"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// createdGameServer is the create response, with a scheduling hint when
+// the server will likely not start
+type createdGameServer struct {
+	*GameServer
+	Scheduling *SchedulingHint `json:"scheduling,omitempty"`
+}
+
+// checkScheduling reports whether a GameServer's pod can be scheduled now:
+// whether a ready, schedulable node accepts its tolerations and required
+// node affinity and has its CPU and memory requests free. Free capacity is
+// allocatable less the requests of the pods already on the node.
+func (s *Server) checkScheduling(ctx context.Context, spec GameServerSpec) (*SchedulingHint, error) {
+	resources := effectiveResources(spec.GameType, spec.Resources)
+	cpu, err := resource.ParseQuantity(resources.CPU)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CPU %q: %w", resources.CPU, err)
+	}
+	memory, err := resource.ParseQuantity(resources.Memory)
+	if err != nil {
+		return nil, fmt.Errorf("invalid memory %q: %w", resources.Memory, err)
+	}
+	tolerations, err := schedulingTolerations(spec.Advanced.Tolerations)
+	if err != nil {
+		return nil, err
+	}
+	terms, err := requiredNodeTerms(spec.Advanced.Affinity)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := s.kubeClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	pods, err := s.kubeClient.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: "status.phase!=Succeeded,status.phase!=Failed",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	requested := map[string]corev1.ResourceList{}
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		total, ok := requested[pod.Spec.NodeName]
+		if !ok {
+			total = corev1.ResourceList{}
+			requested[pod.Spec.NodeName] = total
+		}
+		for _, container := range pod.Spec.Containers {
+			for name, quantity := range container.Resources.Requests {
+				sum := total[name]
+				sum.Add(quantity)
+				total[name] = sum
+			}
+		}
+	}
+
+	hint := &SchedulingHint{CPU: cpu.String(), Memory: memory.String()}
+	unavailable, tainted, unmatched := 0, 0, 0
+	var mostCPU, mostMemory resource.Quantity
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		switch {
+		case !nodeReady(node) || node.Spec.Unschedulable:
+			unavailable++
+			continue
+		case !toleratesNode(tolerations, node):
+			tainted++
+			continue
+		case !matchesNodeTerms(terms, node):
+			unmatched++
+			continue
+		}
+		hint.Eligible++
+
+		freeCPU, freeMemory := node.Status.Allocatable.Cpu().DeepCopy(), node.Status.Allocatable.Memory().DeepCopy()
+		used := requested[node.Name]
+		freeCPU.Sub(*used.Cpu())
+		freeMemory.Sub(*used.Memory())
+		if freeCPU.Cmp(cpu) >= 0 && freeMemory.Cmp(memory) >= 0 {
+			hint.Fitting++
+		}
+		if freeCPU.Cmp(mostCPU) > 0 {
+			mostCPU = freeCPU
+		}
+		if freeMemory.Cmp(mostMemory) > 0 {
+			mostMemory = freeMemory
+		}
+	}
+	hint.Feasible = hint.Fitting > 0
+
+	switch {
+	case len(nodes.Items) == 0:
+		hint.Warnings = append(hint.Warnings, "The cluster has no nodes, so the server will stay Pending")
+	case hint.Eligible == 0:
+		reasons := []string{}
+		if unavailable > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d not ready or cordoned", unavailable))
+		}
+		if tainted > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d with taints the server does not tolerate", tainted))
+		}
+		if unmatched > 0 {
+			reasons = append(reasons, fmt.Sprintf("%d not matching its node affinity", unmatched))
+		}
+		hint.Warnings = append(hint.Warnings, fmt.Sprintf("No node accepts the server (%s), so it will stay Pending", strings.Join(reasons, ", ")))
+	case !hint.Feasible:
+		hint.Warnings = append(hint.Warnings, fmt.Sprintf("No eligible node has %s CPU and %s memory free (at most %s CPU and %s memory), so the server will stay Pending until capacity frees up or nodes are added",
+			hint.CPU, hint.Memory, formatMillicores(mostCPU.MilliValue()), formatMemoryBytes(mostMemory.Value())))
+	}
+	return hint, nil
+}
+
+// schedulingTolerations converts spec.advanced.tolerations
+func schedulingTolerations(raw []map[string]interface{}) ([]corev1.Toleration, error) {
+	tolerations := make([]corev1.Toleration, 0, len(raw))
+	for _, item := range raw {
+		var toleration corev1.Toleration
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item, &toleration); err != nil {
+			return nil, fmt.Errorf("invalid toleration: %w", err)
+		}
+		tolerations = append(tolerations, toleration)
+	}
+	return tolerations, nil
+}
+
+// requiredNodeTerms returns the required node selector terms of
+// spec.advanced.affinity; a node must match one of them
+func requiredNodeTerms(raw map[string]interface{}) ([]corev1.NodeSelectorTerm, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	var affinity corev1.Affinity
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &affinity); err != nil {
+		return nil, fmt.Errorf("invalid affinity: %w", err)
+	}
+	if affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return nil, nil
+	}
+	return affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms, nil
+}
+
+// toleratesNode reports whether the tolerations allow scheduling onto a
+// node; only NoSchedule and NoExecute taints keep pods off
+func toleratesNode(tolerations []corev1.Toleration, node *corev1.Node) bool {
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for j := range tolerations {
+			if tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesNodeTerms reports whether a node's labels match any of the terms'
+// label expressions; no terms match every node. Field expressions are not
+// checked, so a term of only those matches.
+func matchesNodeTerms(terms []corev1.NodeSelectorTerm, node *corev1.Node) bool {
+	if len(terms) == 0 {
+		return true
+	}
+	operators := map[corev1.NodeSelectorOperator]selection.Operator{
+		corev1.NodeSelectorOpIn:           selection.In,
+		corev1.NodeSelectorOpNotIn:        selection.NotIn,
+		corev1.NodeSelectorOpExists:       selection.Exists,
+		corev1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+		corev1.NodeSelectorOpGt:           selection.GreaterThan,
+		corev1.NodeSelectorOpLt:           selection.LessThan,
+	}
+	nodeLabels := labels.Set(node.Labels)
+	for _, term := range terms {
+		matched := true
+		for _, expression := range term.MatchExpressions {
+			requirement, err := labels.NewRequirement(expression.Key, operators[expression.Operator], expression.Values)
+			if err != nil || !requirement.Matches(nodeLabels) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
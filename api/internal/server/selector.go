@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ServerSelector picks GameServers for rollouts and ban lists; empty fields
+// match all
+type ServerSelector struct {
+	Namespace string `json:"namespace,omitempty"`
+	GameType  string `json:"gameType,omitempty"`
+	// Labels is a label selector such as "tier=event,region!=eu"
+	Labels string `json:"labels,omitempty"`
+	// Names limits the selection to these GameServers, as namespace/name
+	Names []string `json:"names,omitempty"`
+}
+
+// validate checks the selector's game type, labels and references
+func (sel ServerSelector) validate() error {
+	if sel.GameType != "" {
+		if _, ok := lookupGame(sel.GameType); !ok {
+			return fmt.Errorf("Unsupported game type: %s", sel.GameType)
+		}
+	}
+	if sel.Labels != "" {
+		if _, err := labels.Parse(sel.Labels); err != nil {
+			return fmt.Errorf("invalid selector.labels: %v", err)
+		}
+	}
+	for _, ref := range sel.Names {
+		if !serverRefPattern.MatchString(ref) {
+			return fmt.Errorf("invalid server reference %q (expected namespace/name)", ref)
+		}
+	}
+	return nil
+}
+
+// listOptions narrows a GameServer list to the selector's namespace and
+// labels; matches checks the rest
+func (sel ServerSelector) listOptions() []client.ListOption {
+	opts := []client.ListOption{}
+	if sel.Namespace != "" {
+		opts = append(opts, client.InNamespace(sel.Namespace))
+	}
+	if sel.Labels != "" {
+		selector, _ := labels.Parse(sel.Labels)
+		opts = append(opts, client.MatchingLabelsSelector{Selector: selector})
+	}
+	return opts
+}
+
+// matches reports whether a GameServer is selected
+func (sel ServerSelector) matches(obj *unstructured.Unstructured) bool {
+	if sel.Namespace != "" && obj.GetNamespace() != sel.Namespace {
+		return false
+	}
+	if len(sel.Names) > 0 && !containsString(sel.Names, obj.GetNamespace()+"/"+obj.GetName()) {
+		return false
+	}
+	if sel.GameType != "" {
+		if gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType"); gameType != sel.GameType {
+			return false
+		}
+	}
+	if sel.Labels != "" {
+		selector, err := labels.Parse(sel.Labels)
+		if err != nil || !selector.Matches(labels.Set(obj.GetLabels())) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,652 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/compat"
+	"github.com/kubelize/gameplane/api/internal/devcluster"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+	"github.com/kubelize/gameplane/api/internal/k8s"
+	"github.com/kubelize/gameplane/api/internal/metrics"
+	"github.com/kubelize/gameplane/api/internal/webui"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Server represents the API server
+type Server struct {
+	k8sClient   client.Client
+	kubeClient  kubernetes.Interface
+	router      *gin.Engine
+	port        string
+	// basePath prefixes every route when served under a sub-path
+	basePath    string
+	// debug serves /debug/pprof and /debug/vars
+	debug       bool
+
+	backgroundTasks []backgroundTask
+	chatRelay       *chatRelayCursors
+	wipeScheduler   *wipeSchedulerState
+	eventScheduler  *eventSchedulerState
+	remediator      *remediatorState
+	updater         *updaterState
+	availability    *availabilityTracker
+	alerts          *alertEvaluator
+	events          *eventBus
+	maintenance     *maintenanceCache
+	rollouts        *rolloutControls
+	workers         *workerPool
+	limits          *routeLimits
+	leader          *leaderElection
+	snapshots       *snapshotPolicy
+	whitelists      *whitelistSyncState
+	banLists        *banListSync
+	lifecycleHooks  *lifecycleHookState
+	playerSessions  *playerSessionKey
+	teams           *teamCache
+	sessions        *sessionCache
+	activity        *activityLog
+
+	// cluster names the cluster this Server's clients talk to
+	cluster    string
+	clusters   *clusterRegistry
+	operations *operationStore
+
+	// access limits readable namespaces; nil allows all
+	access handlers.NamespaceAccess
+
+	// namespaces hides system and other namespaces GameServers do not
+	// belong in
+	namespaces *namespaceFilter
+
+	// clusterInfo caches the cluster's details
+	clusterInfo *clusterInfoCache
+
+	// admins are the subjects administering the installation; nil makes
+	// every caller an admin
+	admins map[string]bool
+
+	// devCluster, in dev mode, stands in for Crossplane
+	devCluster *devcluster.Cluster
+}
+
+// Options customise a Server; the zero value is the standalone API
+type Options struct {
+	// Authenticator, if set, must accept every API request except the
+	// health check
+	Authenticator handlers.Authenticator
+
+	// NamespaceAccess, if set, limits the namespaces callers may read;
+	// otherwise every namespace is readable
+	NamespaceAccess handlers.NamespaceAccess
+
+	// Admins are the subjects allowed to administer the installation,
+	// overriding ADMIN_SUBJECTS. Without either every caller is an admin.
+	Admins []string
+
+	// NamespaceAllow and NamespaceDeny are glob patterns of the namespaces
+	// GameServers may live in, overriding NAMESPACE_ALLOW and
+	// NAMESPACE_DENY. System namespaces are always denied.
+	NamespaceAllow []string
+	NamespaceDeny  []string
+
+	// Metrics, if set, observes every request
+	Metrics handlers.MetricsRecorder
+
+	// ClusterName and ClusterRegistryNamespace override CLUSTER_NAME and
+	// CLUSTER_REGISTRY_NAMESPACE
+	ClusterName              string
+	ClusterRegistryNamespace string
+
+	// Config, K8sClient and KubeClient replace the clients built from the
+	// environment, e.g. with fakes in tests
+	Config     *rest.Config
+	K8sClient  client.Client
+	KubeClient kubernetes.Interface
+
+	// DevCluster, if set, serves everything from an in-memory cluster that
+	// provisions new GameServers itself, replacing any clients above
+	DevCluster *devcluster.Cluster
+}
+
+// NewServer creates a new API server instance
+func NewServer(opts Options) (*Server, error) {
+	config := opts.Config
+	k8sClient, kubeClient := opts.K8sClient, opts.KubeClient
+	if opts.DevCluster != nil {
+		config, k8sClient, kubeClient = opts.DevCluster.Config, opts.DevCluster.Client, opts.DevCluster.Kube
+	}
+	if k8sClient == nil || kubeClient == nil {
+		// Create Kubernetes client
+		var err error
+		if config == nil {
+			if config, err = k8s.Config(); err != nil {
+				return nil, fmt.Errorf("failed to get kubernetes config: %w", err)
+			}
+		}
+		if k8sClient, kubeClient, err = k8s.NewClients(config); err != nil {
+			return nil, err
+		}
+	}
+	if config == nil {
+		config = &rest.Config{}
+	}
+
+	events, err := newEventBus()
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err := httpSettingsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	workers, err := newWorkerPool()
+	if err != nil {
+		return nil, err
+	}
+
+	leader, err := newLeaderElection(opts.DevCluster != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := newSnapshotPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	// DEBUG_ENDPOINTS serves /debug/pprof and /debug/vars
+	debug, err := envBool("DEBUG_ENDPOINTS", false)
+	if err != nil {
+		return nil, err
+	}
+	// METRICS serves the API's Prometheus metrics on /metrics
+	builtinMetrics, err := envBool("METRICS", true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Setup Gin router
+	router := gin.Default()
+	if err := router.SetTrustedProxies(settings.trustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	
+	// Configure CORS
+	router.Use(cors.New(settings.corsConfig()))
+	if builtinMetrics {
+		router.Use(handlers.RecordMetrics(metrics.Recorder{}))
+		router.GET(settings.basePath+"/metrics", gin.WrapH(metrics.Handler()))
+	}
+	if opts.Metrics != nil {
+		router.Use(handlers.RecordMetrics(opts.Metrics))
+	}
+	router.Use(handlers.Compress())
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	server := &Server{
+		k8sClient:      k8sClient,
+		kubeClient:     kubeClient,
+		router:         router,
+		port:           port,
+		basePath:       settings.basePath,
+		debug:          debug,
+		chatRelay:      &chatRelayCursors{},
+		wipeScheduler:  &wipeSchedulerState{},
+		eventScheduler: &eventSchedulerState{},
+		remediator:     &remediatorState{},
+		updater:        &updaterState{},
+		availability:   &availabilityTracker{},
+		alerts:         &alertEvaluator{},
+		events:         events,
+		maintenance:    &maintenanceCache{},
+		rollouts:       &rolloutControls{},
+		workers:        workers,
+		limits:         &routeLimits{},
+		leader:         leader,
+		snapshots:      snapshots,
+		whitelists:     &whitelistSyncState{},
+		banLists:       &banListSync{},
+		lifecycleHooks: &lifecycleHookState{},
+		playerSessions: &playerSessionKey{},
+		teams:          &teamCache{},
+		sessions:       &sessionCache{},
+		activity:       newActivityLog(),
+		clusterInfo:    &clusterInfoCache{},
+		access:         opts.NamespaceAccess,
+		admins:         adminSubjects(opts.Admins),
+		devCluster:     opts.DevCluster,
+	}
+	server.clusters = newClusterRegistry(server, config, opts.ClusterName, opts.ClusterRegistryNamespace)
+	server.namespaces = newNamespaceFilter(opts.NamespaceAllow, opts.NamespaceDeny, server.clusters.namespace)
+	server.operations = &operationStore{}
+
+	apiMiddleware := []gin.HandlerFunc{}
+	if opts.Authenticator != nil {
+		// Session access tokens stand in for the authenticator's credentials
+		auth := sessionAuthenticator{server: server, next: opts.Authenticator}
+		apiMiddleware = append(apiMiddleware, handlers.Authenticate(auth, server.basePath+"/api/v1/health", server.basePath+"/api/v2/health"))
+	}
+	server.setupRoutes(apiMiddleware...)
+	server.setupBackgroundTasks()
+	return server, nil
+}
+
+// aggregateCacheTTL is how long cluster-wide aggregates polled by the
+// dashboard are served from memory
+const aggregateCacheTTL = 15 * time.Second
+
+// apiV1Lifecycle announces v1's retirement in favour of v2
+var apiV1Lifecycle = compat.Lifecycle{
+	Deprecated: time.Date(2026, time.October, 14, 0, 0, 0, 0, time.UTC),
+	Sunset:     time.Date(2027, time.April, 14, 0, 0, 0, 0, time.UTC),
+}
+
+// setupRoutes configures the API routes
+func (s *Server) setupRoutes(middleware ...gin.HandlerFunc) {
+	root := s.router.Group(s.basePath)
+
+	// v1 keeps its response shapes and announces its retirement; v2 serves
+	// the same handlers through the compatibility shim
+	v1 := root.Group("/api/v1", compat.Deprecated(apiV1Lifecycle, s.basePath+"/api/v1", s.basePath+"/api/v2"), handlers.ETag())
+	v1.Use(middleware...)
+	s.apiRoutes(v1)
+	v2 := root.Group("/api/v2", handlers.ETag(), compat.V2())
+	v2.Use(middleware...)
+	s.apiRoutes(v2)
+
+	// Server browsers read the public status API without credentials
+	for _, version := range []string{"/api/v1", "/api/v2"} {
+		root.GET(version+"/public/gameservers/:namespace/:name", handlers.Cache(publicStatusCacheTTL), s.clustered((*Server).getPublicStatus))
+	}
+
+	// In-game plugins and Discord sign their requests instead
+	for _, version := range []string{"/api/v1", "/api/v2"} {
+		root.POST(version+"/hooks/ingame", s.clustered((*Server).receiveIngameHook))
+		root.POST(version+"/hooks/discord/:bridge", s.clustered((*Server).receiveDiscordInteraction))
+	}
+
+	// Sessions are refreshed and ended with their own tokens
+	for _, version := range []string{"/api/v1", "/api/v2"} {
+		root.POST(version+"/auth/refresh", s.refreshSession)
+		root.POST(version+"/auth/logout", s.logout)
+	}
+
+	// Kiosk tokens carry their own read-only scope
+	for _, version := range []string{"/api/v1", "/api/v2"} {
+		root.GET(version+"/kiosk/gameservers/:namespace/:name/:endpoint", s.serveKiosk)
+	}
+
+	// Web admin panels and tunnels are streamed through, past the buffering
+	// of the API groups, behind the same authentication; form posts through
+	// a panel are audited and held during maintenance like API calls
+	for _, version := range []string{"/api/v1", "/api/v2"} {
+		prefix := s.basePath + version
+		panel := append(append([]gin.HandlerFunc{}, middleware...), s.auditActivity(prefix), s.guardNamespaces, s.guardMaintenance(prefix), s.clustered((*Server).proxyGameServerPanel))
+		root.Any(version+"/gameservers/:namespace/:name/panel/*path", panel...)
+		tunnel := append(append([]gin.HandlerFunc{}, middleware...), s.guardNamespaces, s.clustered((*Server).tunnelGameServer))
+		root.GET(version+"/gameservers/:namespace/:name/tunnel", tunnel...)
+	}
+
+	// Players log in with Steam and get a read-only view of their servers
+	for _, version := range []string{"/api/v1", "/api/v2"} {
+		root.GET(version+"/auth/steam/login", s.steamLogin)
+		root.GET(version+"/auth/steam/callback", s.steamCallback)
+		player := root.Group(version+"/player", s.requirePlayer)
+		player.GET("/me", s.clustered((*Server).getPlayerMe))
+		player.GET("/servers", s.clustered((*Server).listPlayerServers))
+		player.POST("/servers/:namespace/:name/whitelist/claim", s.clustered((*Server).claimWhitelistSlot))
+		player.DELETE("/servers/:namespace/:name/whitelist/claim", s.clustered((*Server).releaseWhitelistSlot))
+	}
+
+	if s.debug {
+		s.debugRoutes(root, middleware...)
+	}
+
+	// Serve static files (Hugo build output)
+	public, static := webui.Assets()
+	root.StaticFS("/static", http.FS(static))
+	root.GET("/", webui.Handler(public, s.basePath))
+	s.router.NoRoute(webui.Handler(public, s.basePath))
+}
+
+// apiRoutes registers the API on a versioned group
+func (s *Server) apiRoutes(api *gin.RouterGroup) {
+	api.Use(s.auditActivity(api.BasePath()), s.guardNamespaces, s.guardOwnership(api.BasePath()), s.guardMaintenance(api.BasePath()))
+
+	// Health check
+	api.GET("/health", s.healthCheck)
+	
+	// GameServer management
+	gameservers := api.Group("/gameservers")
+	{
+		gameservers.GET("", s.clustered((*Server).listGameServers))
+		gameservers.POST("", s.clustered((*Server).createGameServer))
+		gameservers.POST("/estimate", s.clustered((*Server).estimateGameServerCost))
+		gameservers.POST("/preview", s.clustered((*Server).previewGameServer))
+		gameservers.GET("/:namespace/:name", s.clustered((*Server).getGameServer))
+		gameservers.PUT("/:namespace/:name", s.clustered((*Server).updateGameServer))
+		gameservers.DELETE("/:namespace/:name", s.clustered((*Server).deleteGameServer))
+		gameservers.GET("/:namespace/:name/deletion", s.clustered((*Server).getGameServerDeletion))
+		gameservers.GET("/:namespace/:name/status", s.limit("gameserver-status", 8), s.clustered((*Server).getGameServerStatus))
+		gameservers.GET("/:namespace/:name/logs", s.clustered((*Server).getGameServerLogs))
+		gameservers.GET("/:namespace/:name/metrics", s.clustered((*Server).getGameServerMetrics))
+		gameservers.GET("/:namespace/:name/workload", s.clustered((*Server).getGameServerWorkload))
+		gameservers.GET("/:namespace/:name/release", s.clustered((*Server).getGameServerRelease))
+		gameservers.POST("/:namespace/:name/restart", s.clustered((*Server).restartGameServer))
+		gameservers.POST("/:namespace/:name/diff", s.clustered((*Server).diffGameServer))
+		gameservers.POST("/:namespace/:name/apply-pending", s.clustered((*Server).applyPendingRestart))
+		gameservers.GET("/:namespace/:name/config", s.clustered((*Server).getGameServerConfig))
+		gameservers.PUT("/:namespace/:name/config", s.clustered((*Server).putGameServerConfig))
+		gameservers.PATCH("/:namespace/:name/config", s.clustered((*Server).patchGameServerConfig))
+		gameservers.GET("/:namespace/:name/config/rendered", s.clustered((*Server).getRenderedConfig))
+		gameservers.GET("/:namespace/:name/admins", s.clustered((*Server).getGameServerAdmins))
+		gameservers.PUT("/:namespace/:name/admins", s.clustered((*Server).putGameServerAdmins))
+		gameservers.GET("/:namespace/:name/whitelist", s.clustered((*Server).getWhitelist))
+		gameservers.PUT("/:namespace/:name/whitelist", s.clustered((*Server).putWhitelist))
+		gameservers.DELETE("/:namespace/:name/whitelist", s.clustered((*Server).deleteWhitelist))
+		gameservers.POST("/:namespace/:name/whitelist/sync", s.clustered((*Server).syncWhitelistNow))
+		gameservers.GET("/:namespace/:name/hooks", s.clustered((*Server).getIngameHooks))
+		gameservers.PUT("/:namespace/:name/hooks", s.clustered((*Server).putIngameHooks))
+		gameservers.DELETE("/:namespace/:name/hooks", s.clustered((*Server).deleteIngameHooks))
+		gameservers.POST("/:namespace/:name/hooks/rotate", s.clustered((*Server).rotateIngameHookSecret))
+		gameservers.GET("/:namespace/:name/bans", s.clustered((*Server).getGameServerBans))
+		gameservers.GET("/:namespace/:name/chat", s.clustered((*Server).getGameServerChat))
+		gameservers.GET("/:namespace/:name/chat/relay", s.clustered((*Server).getChatRelay))
+		gameservers.PUT("/:namespace/:name/chat/relay", s.clustered((*Server).putChatRelay))
+		gameservers.POST("/:namespace/:name/chat/inbound", s.clustered((*Server).postChatInbound))
+		gameservers.POST("/:namespace/:name/broadcast", s.clustered((*Server).broadcastGameServer))
+		gameservers.POST("/:namespace/:name/save", s.clustered((*Server).saveGameServer))
+		gameservers.PATCH("/:namespace/:name/live-settings", s.clustered((*Server).patchLiveSettings))
+		gameservers.PUT("/:namespace/:name/motd", s.clustered((*Server).putGameServerMOTD))
+		gameservers.GET("/:namespace/:name/wipe", s.clustered((*Server).getWipe))
+		gameservers.POST("/:namespace/:name/wipe", s.clustered((*Server).wipeGameServer))
+		gameservers.PUT("/:namespace/:name/wipe/policy", s.clustered((*Server).putWipePolicy))
+		gameservers.DELETE("/:namespace/:name/wipe/policy", s.clustered((*Server).deleteWipePolicy))
+		gameservers.GET("/:namespace/:name/events-schedule", s.clustered((*Server).getEventSchedule))
+		gameservers.PUT("/:namespace/:name/events-schedule", s.clustered((*Server).putEventSchedule))
+		gameservers.DELETE("/:namespace/:name/events-schedule", s.clustered((*Server).deleteEventSchedule))
+		gameservers.POST("/:namespace/:name/events-schedule/:event/run", s.clustered((*Server).runScheduledEvent))
+		gameservers.GET("/:namespace/:name/remediation", s.clustered((*Server).getRemediation))
+		gameservers.PUT("/:namespace/:name/remediation/policy", s.clustered((*Server).putRemediationPolicy))
+		gameservers.DELETE("/:namespace/:name/remediation/policy", s.clustered((*Server).deleteRemediationPolicy))
+		gameservers.GET("/:namespace/:name/updates", s.clustered((*Server).getUpdates))
+		gameservers.PUT("/:namespace/:name/updates/policy", s.clustered((*Server).putUpdatePolicy))
+		gameservers.DELETE("/:namespace/:name/updates/policy", s.clustered((*Server).deleteUpdatePolicy))
+		gameservers.POST("/:namespace/:name/world/regenerate", s.clustered((*Server).regenerateWorld))
+		gameservers.GET("/:namespace/:name/worlds", s.clustered((*Server).listWorlds))
+		gameservers.POST("/:namespace/:name/worlds", s.clustered((*Server).createWorld))
+		gameservers.DELETE("/:namespace/:name/worlds/:world", s.clustered((*Server).deleteWorld))
+		gameservers.POST("/:namespace/:name/worlds/:world/activate", s.clustered((*Server).activateWorld))
+		gameservers.POST("/:namespace/:name/worlds/:world/import", s.clustered((*Server).importWorld))
+		gameservers.POST("/:namespace/:name/migrate", s.clustered((*Server).migrateGameServer))
+		gameservers.POST("/:namespace/:name/rename", s.clustered((*Server).renameGameServer))
+		gameservers.POST("/:namespace/:name/upgrade", s.clustered((*Server).upgradeGameServer))
+		gameservers.POST("/:namespace/:name/upgrade/rollback", s.clustered((*Server).rollbackGameServer))
+		gameservers.POST("/:namespace/:name/upgrade/finalize", s.clustered((*Server).finalizeUpgrade))
+		gameservers.GET("/:namespace/:name/backups", s.clustered((*Server).listBackups))
+		gameservers.POST("/:namespace/:name/backups/:backup/verify", s.clustered((*Server).verifyBackupSnapshot))
+		gameservers.POST("/:namespace/:name/export", s.clustered((*Server).exportWorld))
+		gameservers.GET("/:namespace/:name/exports", s.clustered((*Server).listWorldExports))
+		gameservers.GET("/:namespace/:name/cost", s.clustered((*Server).getGameServerCost))
+		gameservers.GET("/:namespace/:name/recommendations", s.clustered((*Server).getGameServerRecommendations))
+		gameservers.POST("/:namespace/:name/recommendations/apply", s.clustered((*Server).applyGameServerRecommendations))
+		gameservers.GET("/:namespace/:name/uptime", s.clustered((*Server).getGameServerUptime))
+		gameservers.GET("/:namespace/:name/activity", s.clustered((*Server).getGameServerActivity))
+		gameservers.GET("/:namespace/:name/alerts", s.clustered((*Server).getGameServerAlerts))
+		gameservers.PUT("/:namespace/:name/alerts", s.clustered((*Server).putGameServerAlerts))
+		gameservers.POST("/:namespace/:name/extend", s.clustered((*Server).extendGameServer))
+		gameservers.GET("/:namespace/:name/proxy", s.clustered((*Server).getGameServerProxy))
+	}
+
+	// Fleet management
+	fleets := api.Group("/fleets")
+	{
+		fleets.GET("", s.clustered((*Server).listFleets))
+		fleets.POST("", s.clustered((*Server).createFleet))
+		fleets.GET("/:namespace/:name", s.clustered((*Server).getFleet))
+		fleets.PUT("/:namespace/:name", s.clustered((*Server).updateFleet))
+		fleets.DELETE("/:namespace/:name", s.clustered((*Server).deleteFleet))
+		fleets.POST("/:namespace/:name/scale", s.clustered((*Server).scaleFleet))
+		fleets.POST("/:namespace/:name/canary", s.clustered((*Server).startFleetCanary))
+		fleets.DELETE("/:namespace/:name/canary", s.clustered((*Server).abortFleetCanary))
+		fleets.POST("/:namespace/:name/allocate", s.clustered((*Server).allocateFleetServer))
+		fleets.DELETE("/:namespace/:name/allocations/:server", s.clustered((*Server).releaseFleetServer))
+	}
+
+	// Clusters of servers link GameServers that discover each other
+	serverClusters := api.Group("/clusters-of-servers")
+	{
+		serverClusters.GET("", s.clustered((*Server).listServerClusters))
+		serverClusters.POST("", s.clustered((*Server).createServerCluster))
+		serverClusters.GET("/:namespace/:name", s.clustered((*Server).getServerCluster))
+		serverClusters.DELETE("/:namespace/:name", s.clustered((*Server).deleteServerCluster))
+		serverClusters.PUT("/:namespace/:name/members/:server", s.clustered((*Server).putServerClusterMember))
+		serverClusters.DELETE("/:namespace/:name/members/:server", s.clustered((*Server).deleteServerClusterMember))
+	}
+
+	// Projects group GameServers across namespaces
+	projects := api.Group("/projects")
+	{
+		projects.GET("", s.limit("projects", 4), s.clustered((*Server).listProjects))
+		projects.POST("", s.clustered((*Server).createProject))
+		projects.GET("/:project", s.limit("project", 8), s.clustered((*Server).getProject))
+		projects.PUT("/:project", s.clustered((*Server).updateProject))
+		projects.DELETE("/:project", s.clustered((*Server).deleteProject))
+		projects.POST("/:project/members", s.clustered((*Server).addProjectMember))
+		projects.DELETE("/:project/members/:namespace/:name", s.clustered((*Server).removeProjectMember))
+		projects.POST("/:project/actions", s.limit("project-actions", 2), s.clustered((*Server).runProjectAction))
+	}
+
+	banLists := api.Group("/banlists")
+	{
+		banLists.GET("", s.clustered((*Server).listBanLists))
+		banLists.POST("", s.clustered((*Server).createBanList))
+		banLists.GET("/:banlist", s.clustered((*Server).getBanList))
+		banLists.PUT("/:banlist", s.clustered((*Server).updateBanList))
+		banLists.DELETE("/:banlist", s.clustered((*Server).deleteBanList))
+		banLists.POST("/:banlist/entries", s.clustered((*Server).addBanListEntry))
+		banLists.DELETE("/:banlist/entries/:id", s.clustered((*Server).removeBanListEntry))
+		banLists.POST("/:banlist/sync", s.limit("banlist-sync", 2), s.clustered((*Server).syncBanListNow))
+	}
+
+	// Additional claim kinds from RESOURCE_KINDS, validated against their XRDs
+	resources := api.Group("/resources")
+	{
+		resources.GET("", s.clustered((*Server).listResourceKinds))
+		resources.GET("/:kind", s.clustered((*Server).listResources))
+		resources.POST("/:kind", s.clustered((*Server).createResource))
+		resources.GET("/:kind/schema", s.clustered((*Server).getResourceSchema))
+		resources.GET("/:kind/:namespace/:name", s.clustered((*Server).getResource))
+		resources.PUT("/:kind/:namespace/:name", s.clustered((*Server).updateResource))
+		resources.DELETE("/:kind/:namespace/:name", s.clustered((*Server).deleteResource))
+	}
+
+	// Shared asset library of read-only volumes mounted by many servers
+	sharedAssets := api.Group("/shared-assets")
+	{
+		sharedAssets.GET("", s.clustered((*Server).listSharedAssets))
+		sharedAssets.POST("", s.clustered((*Server).createSharedAsset))
+		sharedAssets.GET("/:asset", s.clustered((*Server).getSharedAsset))
+		sharedAssets.PATCH("/:asset", s.clustered((*Server).updateSharedAsset))
+		sharedAssets.DELETE("/:asset", s.clustered((*Server).deleteSharedAsset))
+		sharedAssets.POST("/:asset/load", s.clustered((*Server).loadSharedAssetContent))
+	}
+
+	// Installation-wide reports
+	api.GET("/reports/provisioning", handlers.Cache(aggregateCacheTTL), s.clustered((*Server).getProvisioningReport))
+	api.GET("/reports/utilization", handlers.Cache(aggregateCacheTTL), s.limit("utilization", 2), s.clustered((*Server).getUtilizationReport))
+
+	// Cluster registry
+	api.GET("/clusters", s.listClusters)
+	api.GET("/regions", s.listRegions)
+
+	// Notification subscriptions
+	api.GET("/notifications/subscriptions", s.listSubscriptions)
+	api.POST("/notifications/subscriptions", s.createSubscription)
+	api.DELETE("/notifications/subscriptions/:id", s.deleteSubscription)
+
+	// Discord slash command bridges
+	api.GET("/discord/bridges", s.listDiscordBridges)
+	api.POST("/discord/bridges", s.createDiscordBridge)
+	api.GET("/discord/bridges/:bridge", s.getDiscordBridge)
+	api.PUT("/discord/bridges/:bridge", s.updateDiscordBridge)
+	api.DELETE("/discord/bridges/:bridge", s.deleteDiscordBridge)
+	api.GET("/discord/bridges/:bridge/commands", s.getDiscordCommands)
+
+	// Server requests, for callers who may not create GameServers directly
+	api.GET("/requests", s.listServerRequests)
+	api.POST("/requests", s.clustered((*Server).createServerRequest))
+	api.GET("/requests/:request", s.getServerRequest)
+	api.DELETE("/requests/:request", s.withdrawServerRequest)
+	api.POST("/requests/:request/approve", s.approveServerRequest)
+	api.POST("/requests/:request/deny", s.denyServerRequest)
+
+	// Sessions of the authenticated caller
+	api.POST("/auth/sessions", s.createSession)
+	api.GET("/auth/sessions", s.listSessions)
+	api.DELETE("/auth/sessions/:id", s.deleteSession)
+
+	// Kiosk tokens for dashboards and widgets
+	api.POST("/kiosk/tokens", s.createKioskToken)
+	api.GET("/kiosk/tokens", s.listKioskTokens)
+	api.DELETE("/kiosk/tokens/:id", s.deleteKioskToken)
+
+	// Teams grant their members roles in namespaces
+	api.GET("/teams", s.listTeams)
+	api.POST("/teams", s.createTeam)
+	api.GET("/teams/:team", s.getTeam)
+	api.PUT("/teams/:team", s.updateTeam)
+	api.DELETE("/teams/:team", s.deleteTeam)
+	api.POST("/teams/:team/members", s.addTeamMember)
+	api.DELETE("/teams/:team/members/:subject", s.removeTeamMember)
+
+	// Installation administration
+	admin := api.Group("/admin")
+	{
+		admin.GET("/runtime", s.getRuntime)
+		admin.POST("/bootstrap", s.limit("bootstrap", 1), s.clustered((*Server).bootstrapPlatform))
+		admin.POST("/rollout", s.clustered((*Server).startRollout))
+		admin.POST("/rollout/:id/resume", s.resumeRollout)
+		admin.POST("/rollout/:id/abort", s.abortRollout)
+		admin.GET("/prepull", s.clustered((*Server).getPrepulls))
+		admin.POST("/prepull", s.clustered((*Server).startPrepull))
+		admin.DELETE("/prepull/:name", s.clustered((*Server).deletePrepull))
+		admin.GET("/orphans", s.clustered((*Server).getOrphans))
+		admin.POST("/orphans/cleanup", s.clustered((*Server).cleanupOrphans))
+		admin.GET("/steamcache", s.clustered((*Server).getSteamCache))
+		admin.PUT("/steamcache", s.clustered((*Server).putSteamCache))
+		admin.DELETE("/steamcache", s.clustered((*Server).deleteSteamCache))
+	}
+
+	// Maintenance mode
+	api.GET("/maintenance", s.getMaintenance)
+	api.PUT("/maintenance", s.putMaintenance)
+
+	// Per-user preferences
+	api.GET("/userprefs", s.getUserPrefs)
+	api.PUT("/userprefs", s.putUserPrefs)
+	api.DELETE("/userprefs", s.deleteUserPrefs)
+	api.PUT("/userprefs/favorites/:namespace/:name", s.putFavorite)
+	api.DELETE("/userprefs/favorites/:namespace/:name", s.deleteFavorite)
+	api.GET("/users/me/preferences", s.getNotificationPrefs)
+	api.PUT("/users/me/preferences", s.putNotificationPrefs)
+	api.DELETE("/users/me/preferences", s.deleteNotificationPrefs)
+
+	// Off-cluster storage
+	api.GET("/storage/health", s.limit("storage-health", 2), s.clustered((*Server).getStorageHealth))
+
+	// Long-running operations
+	// Activity feed of audit entries, lifecycle events, backups and alerts
+	api.GET("/activity", s.listActivity)
+
+	api.GET("/operations", s.listOperations)
+	api.GET("/operations/:id", s.getOperation)
+
+	// Namespace management
+	api.GET("/namespaces", s.clustered((*Server).listNamespaces))
+	
+	// Cluster info
+	api.GET("/cluster/info", handlers.Cache(aggregateCacheTTL), s.clustered((*Server).getClusterInfo))
+	api.GET("/cluster/storageclasses", handlers.Cache(aggregateCacheTTL), s.clustered((*Server).getStorageClasses))
+	api.GET("/cluster/networking", handlers.Cache(aggregateCacheTTL), s.clustered((*Server).getClusterNetworking))
+	api.GET("/cluster/gameplane-health", s.limit("platform-health", 2), s.clustered((*Server).getPlatformHealth))
+	api.GET("/cluster/leader", s.getLeader)
+	api.GET("/cluster/permissions", s.limit("permissions", 2), s.clustered((*Server).getPermissions))
+}
+
+// setupBackgroundTasks registers the periodic jobs run alongside the API
+func (s *Server) setupBackgroundTasks() {
+	s.registerBackgroundTask("chat-relay", chatRelayInterval, (*Server).relayChat)
+	s.registerBackgroundTask("wipe-scheduler", wipeSchedulerInterval, (*Server).runWipeSchedules)
+	s.registerBackgroundTask("event-scheduler", eventSchedulerInterval, (*Server).runEventSchedules)
+	s.registerBackgroundTask("fleet-reconciler", fleetReconcileInterval, (*Server).reconcileAllFleets)
+	s.registerBackgroundTask("server-cluster-linker", serverClusterLinkInterval, (*Server).linkAllServerClusters)
+	s.registerBackgroundTask("proxy-router", proxyRouteInterval, (*Server).syncAllProxyRoutes)
+	s.registerBackgroundTask("shared-volume-sync", sharedVolumeSyncInterval, (*Server).syncSharedVolumes)
+	s.registerBackgroundTask("lifecycle-hooks", lifecycleHookInterval, (*Server).syncLifecycleHooks)
+	s.registerBackgroundTask("steam-cache-sync", steamCacheSyncInterval, (*Server).syncSteamCache)
+	s.registerBackgroundTask("orphan-janitor", orphanJanitorInterval, (*Server).runOrphanJanitor)
+	s.registerBackgroundTask("usage-sampler", usageSampleInterval, (*Server).sampleUsage)
+	s.registerBackgroundTask("availability-tracker", availabilityInterval, (*Server).trackAvailability)
+	s.registerBackgroundTask("health-exporter", healthExportInterval, (*Server).exportHealthScores)
+	s.registerBackgroundTask("alert-evaluator", alertEvaluationInterval, (*Server).evaluateAlerts)
+	s.registerBackgroundTask("crash-remediator", remediationInterval, (*Server).runRemediations)
+	s.registerBackgroundTask("updater", updaterInterval, (*Server).runUpdates)
+	s.registerBackgroundTask("ready-notifier", readyNotifierInterval, (*Server).sendReadyNotifications)
+	s.registerBackgroundTask("provisioning-tracker", provisioningTrackInterval, (*Server).trackProvisioning)
+	s.registerBackgroundTask("lifecycle-reaper", lifecycleReaperInterval, (*Server).reapGameServers)
+	s.registerBackgroundTask("snapshot-pruner", autoSnapshotPruneInterval, (*Server).pruneAllAutoSnapshots)
+	s.registerBackgroundTask("backup-verifier", backupVerifyCheckInterval, (*Server).verifyLatestBackups)
+	s.registerBackgroundTask("whitelist-sync", whitelistCheckInterval, (*Server).syncDueWhitelists)
+	s.registerBackgroundTask("ban-list-sync", banListSyncInterval, (*Server).syncBanLists)
+	s.registerBackgroundTask("session-reaper", sessionReapInterval, (*Server).reapSessions)
+}
+
+// healthCheck returns the health status of the API
+func (s *Server) healthCheck(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "healthy",
+		"timestamp": time.Now().UTC(),
+		"version":   "1.0.0",
+	})
+}
+
+// StartBackground starts the cluster registry refresh, the event bus, the
+// activity log and the background tasks, which only run on the elected
+// leader replica
+func (s *Server) StartBackground() {
+	go s.clusters.run(context.Background())
+	go s.events.run(context.Background())
+	go s.runActivityLog(context.Background())
+	s.runBackgroundTasks(context.Background())
+	if s.devCluster != nil {
+		go s.devCluster.Run(context.Background())
+	}
+}
+
+// Start starts the API server
+func (s *Server) Start() error {
+	log.Printf("Starting GamePlane API server on port %s", s.port)
+	s.StartBackground()
+	return s.router.Run(":" + s.port)
+}
+
+// Router returns the server's router, for embedders adding their own routes
+func (s *Server) Router() *gin.Engine {
+	return s.router
+}
@@ -0,0 +1,676 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// sessionSecretPrefix starts the name of the Secret holding each
+	// session, in the cluster registry namespace of the local cluster
+	sessionSecretPrefix = "gameplane-session-"
+
+	// sessionKey is the Secret key of the session
+	sessionKey = "session.json"
+
+	// Labels of session Secrets: the session kind, a hash of its subject
+	// and, once revoked, the revocation mark revocation checks list by
+	sessionLabel        = "gameplane.kubelize.io/session"
+	sessionSubjectLabel = "gameplane.kubelize.io/session-subject"
+	sessionRevokedLabel = "gameplane.kubelize.io/session-revoked"
+
+	// sessionCacheTTL bounds how long other API replicas keep accepting a
+	// revoked session's access tokens
+	sessionCacheTTL = 5 * time.Second
+
+	// sessionReapInterval is how often expired sessions are deleted
+	sessionReapInterval = 10 * time.Minute
+
+	// defaultAccessTokenTTL and defaultRefreshTokenTTL are how long access
+	// tokens and sessions last, unless SESSION_ACCESS_TTL and
+	// SESSION_REFRESH_TTL say otherwise
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 7 * 24 * time.Hour
+
+	// accessTokenUse marks session access tokens apart from player tokens
+	accessTokenUse = "access"
+)
+
+// Kinds of session
+const (
+	sessionKindAPI    = "api"
+	sessionKindPlayer = "player"
+	sessionKindKiosk  = "kiosk"
+)
+
+// Session is a login that can be refreshed and revoked. Access tokens are
+// short-lived and stateless; the refresh token is stored only as a hash.
+type Session struct {
+	ID          string     `json:"id"`
+	Subject     string     `json:"subject"`
+	Kind        string     `json:"kind"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	RefreshedAt *time.Time `json:"refreshedAt,omitempty"`
+	// ExpiresAt ends the session; refreshing does not extend it
+	ExpiresAt time.Time  `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	UserAgent string     `json:"userAgent,omitempty"`
+	// Name, Cluster, Servers and Endpoints describe kiosk tokens
+	Name      string   `json:"name,omitempty"`
+	Cluster   string   `json:"cluster,omitempty"`
+	Servers   []string `json:"servers,omitempty"`
+	Endpoints []string `json:"endpoints,omitempty"`
+	// RefreshHash is the current refresh token's hash and PreviousHash the
+	// one it replaced, whose reuse revokes the session
+	RefreshHash  string `json:"refreshHash,omitempty"`
+	PreviousHash string `json:"previousHash,omitempty"`
+}
+
+// public returns the session without its token hashes
+func (s Session) public() Session {
+	s.RefreshHash, s.PreviousHash = "", ""
+	return s
+}
+
+// SessionClaims are carried by an access token
+type SessionClaims struct {
+	Subject   string    `json:"sub"`
+	SessionID string    `json:"sid"`
+	Use       string    `json:"use"`
+	IssuedAt  time.Time `json:"iat"`
+	ExpiresAt time.Time `json:"exp"`
+}
+
+// sessionTokens is the response to a login or refresh
+type sessionTokens struct {
+	SessionID    string    `json:"sessionId"`
+	AccessToken  string    `json:"accessToken"`
+	RefreshToken string    `json:"refreshToken"`
+	TokenType    string    `json:"tokenType"`
+	ExpiresIn    int       `json:"expiresIn"`
+	ExpiresAt    time.Time `json:"sessionExpiresAt"`
+}
+
+// sessionCache keeps the IDs of revoked sessions for revocation checks
+type sessionCache struct {
+	mu      sync.Mutex
+	revoked map[string]bool
+	fetched time.Time
+}
+
+// sessionAuthenticator accepts session access tokens and hands every other
+// request to the configured Authenticator
+type sessionAuthenticator struct {
+	server *Server
+	next   handlers.Authenticator
+}
+
+// Authenticate implements handlers.Authenticator
+func (a sessionAuthenticator) Authenticate(r *http.Request) (string, error) {
+	if token := bearerToken(r); token != "" {
+		if claims, err := a.server.verifyAccessToken(r.Context(), token); err == nil {
+			return claims.Subject, nil
+		}
+	}
+	return a.next.Authenticate(r)
+}
+
+// sessionTTLs reads the access token and session lifetimes
+func sessionTTLs() (access, refresh time.Duration) {
+	access, refresh = defaultAccessTokenTTL, defaultRefreshTokenTTL
+	if d, err := time.ParseDuration(os.Getenv("SESSION_ACCESS_TTL")); err == nil && d > 0 {
+		access = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("SESSION_REFRESH_TTL")); err == nil && d > 0 {
+		refresh = d
+	}
+	return access, refresh
+}
+
+// createSession starts a session for the authenticated caller, e.g. after
+// the dashboard's OIDC login, so it can stop sending the OIDC token
+func (s *Server) createSession(c *gin.Context) {
+	subject := c.GetString(handlers.SubjectKey)
+	if subject == "" {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Sessions need an authenticator",
+		})
+		return
+	}
+	// Sessions cannot be chained to outlive their expiry
+	if _, err := s.verifyAccessToken(c.Request.Context(), bearerToken(c.Request)); err == nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Refresh the session instead of starting one from it",
+		})
+		return
+	}
+
+	_, refreshTTL := sessionTTLs()
+	now := time.Now().UTC()
+	session := Session{
+		ID:        newSessionID(),
+		Subject:   subject,
+		Kind:      sessionKindAPI,
+		CreatedAt: now,
+		ExpiresAt: now.Add(refreshTTL),
+		UserAgent: c.Request.UserAgent(),
+	}
+	refresh := newRefreshToken(session.ID)
+	session.RefreshHash = hashToken(refresh)
+	if err := s.recordSession(c.Request.Context(), session); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to start session: %v", err),
+		})
+		return
+	}
+	tokens, err := s.issueTokens(c.Request.Context(), session, refresh)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to start session: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, tokens)
+}
+
+// refreshSession trades a refresh token for a new access token and a new
+// refresh token. Reusing a replaced refresh token revokes the session, as
+// it was likely stolen.
+func (s *Server) refreshSession(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refreshToken" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	id, _, _ := strings.Cut(req.RefreshToken, ".")
+	hash := hashToken(req.RefreshToken)
+
+	var refreshed Session
+	var refresh string
+	err := s.changeSession(c.Request.Context(), id, func(session *Session) error {
+		now := time.Now().UTC()
+		switch {
+		case session.RevokedAt != nil || !now.Before(session.ExpiresAt):
+			return errSessionEnded
+		case session.PreviousHash != "" && tokensEqual(hash, session.PreviousHash):
+			log.Printf("Revoking session %s of %s: a replaced refresh token was reused", session.ID, session.Subject)
+			session.RevokedAt = &now
+			return nil
+		case !tokensEqual(hash, session.RefreshHash):
+			return errSessionEnded
+		}
+		refresh = newRefreshToken(session.ID)
+		session.PreviousHash, session.RefreshHash = session.RefreshHash, hashToken(refresh)
+		session.RefreshedAt = &now
+		refreshed = *session
+		return nil
+	})
+	if err == nil && refresh == "" {
+		err = errSessionEnded
+	}
+	if err == errSessionEnded || apierrors.IsNotFound(err) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Session has ended; log in again",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to refresh session: %v", err),
+		})
+		return
+	}
+	tokens, err := s.issueTokens(c.Request.Context(), refreshed, refresh)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to refresh session: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// logout revokes the session of the access token, refresh token or player
+// cookie sent, and clears the player cookie
+func (s *Server) logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid request body: %v", err),
+			})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	id := ""
+	if claims, err := s.verifyAccessToken(ctx, bearerToken(c.Request)); err == nil {
+		id = claims.SessionID
+	} else if req.RefreshToken != "" {
+		id, _, _ = strings.Cut(req.RefreshToken, ".")
+		// Without the matching token the session stays untouched
+		session, _, err := s.getSession(ctx, id)
+		if err != nil && !apierrors.IsNotFound(err) {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err != nil || !tokensEqual(hashToken(req.RefreshToken), session.RefreshHash) {
+			id = ""
+		}
+	} else {
+		token := bearerToken(c.Request)
+		if token == "" {
+			token, _ = c.Cookie(playerCookie)
+		}
+		if claims, err := s.verifyPlayerToken(ctx, token); err == nil {
+			id = claims.SessionID
+		}
+	}
+
+	settings, _ := loadSteamLoginSettings()
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(playerCookie, "", -1, s.basePath+"/", "", strings.HasPrefix(settings.PublicURL, "https://"), true)
+	if id == "" {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Logged out",
+		})
+		return
+	}
+	if err := s.revokeSession(ctx, id); err != nil && !apierrors.IsNotFound(err) {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to revoke session: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Logged out",
+		"session": id,
+	})
+}
+
+// listSessions returns the caller's sessions; admins see everyone's, or
+// one subject's with ?subject=
+func (s *Server) listSessions(c *gin.Context) {
+	subject := c.GetString(handlers.SubjectKey)
+	if s.isAdmin(c) {
+		subject = c.Query("subject")
+	}
+	sessions, err := s.loadSessions(context.TODO(), "", subject)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	items := []Session{}
+	for _, session := range sessions {
+		items = append(items, session.public())
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.After(items[j].CreatedAt) })
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(items),
+	})
+}
+
+// deleteSession revokes one of the caller's sessions, or anyone's for
+// admins
+func (s *Server) deleteSession(c *gin.Context) {
+	session, _, err := s.getSession(context.TODO(), c.Param("id"))
+	if err != nil && !apierrors.IsNotFound(err) {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil || !(s.isAdmin(c) || session.Subject == c.GetString(handlers.SubjectKey)) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+		return
+	}
+	if err := s.revokeSession(context.TODO(), session.ID); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to revoke session: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked",
+	})
+}
+
+// errSessionEnded rejects refreshing a revoked, expired or unknown session
+var errSessionEnded = fmt.Errorf("session has ended")
+
+// issueTokens signs an access token for the session
+func (s *Server) issueTokens(ctx context.Context, session Session, refresh string) (sessionTokens, error) {
+	accessTTL, _ := sessionTTLs()
+	now := time.Now().UTC()
+	expires := now.Add(accessTTL)
+	if expires.After(session.ExpiresAt) {
+		expires = session.ExpiresAt
+	}
+	access, err := s.signToken(ctx, SessionClaims{
+		Subject:   session.Subject,
+		SessionID: session.ID,
+		Use:       accessTokenUse,
+		IssuedAt:  now,
+		ExpiresAt: expires,
+	})
+	if err != nil {
+		return sessionTokens{}, err
+	}
+	return sessionTokens{
+		SessionID:    session.ID,
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(expires.Sub(now).Seconds()),
+		ExpiresAt:    session.ExpiresAt,
+	}, nil
+}
+
+// verifyAccessToken checks an access token's signature, expiry and session
+func (s *Server) verifyAccessToken(ctx context.Context, token string) (SessionClaims, error) {
+	var claims SessionClaims
+	if token == "" {
+		return claims, fmt.Errorf("no token")
+	}
+	if err := s.verifyToken(ctx, token, &claims); err != nil {
+		return claims, err
+	}
+	if claims.Use != accessTokenUse || !time.Now().Before(claims.ExpiresAt) {
+		return claims, fmt.Errorf("token expired")
+	}
+	if s.sessionRevoked(ctx, claims.SessionID) {
+		return claims, fmt.Errorf("session was logged out")
+	}
+	return claims, nil
+}
+
+// sessionRevoked reports whether a session was revoked. Unreadable
+// revocations count as revoked.
+func (s *Server) sessionRevoked(ctx context.Context, id string) bool {
+	revoked, err := s.revokedSessions(ctx)
+	if err != nil {
+		log.Printf("Failed to read revoked sessions: %v", err)
+		return true
+	}
+	return revoked[id]
+}
+
+// revokedSessions returns the cached IDs of revoked sessions, refreshing
+// them when stale. Only revoked sessions are listed, so the check stays
+// cheap however many sessions are live.
+func (s *Server) revokedSessions(ctx context.Context) (map[string]bool, error) {
+	s.sessions.mu.Lock()
+	defer s.sessions.mu.Unlock()
+	if time.Since(s.sessions.fetched) < sessionCacheTTL {
+		return s.sessions.revoked, nil
+	}
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := store.kubeClient.CoreV1().Secrets(s.clusters.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: sessionRevokedLabel + "=true",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sessions: %w", err)
+	}
+	revoked := map[string]bool{}
+	for _, secret := range secrets.Items {
+		if session, err := sessionFromSecret(&secret); err == nil {
+			revoked[session.ID] = true
+		}
+	}
+	s.sessions.revoked = revoked
+	s.sessions.fetched = time.Now()
+	return revoked, nil
+}
+
+// recordSession stores a new session in a Secret of its own
+func (s *Server) recordSession(ctx context.Context, session Session) error {
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sessionSecretName(session.ID),
+			Namespace: s.clusters.namespace,
+		},
+	}
+	if err := setSessionSecret(secret, session); err != nil {
+		return err
+	}
+	_, err = store.kubeClient.CoreV1().Secrets(s.clusters.namespace).Create(ctx, secret, metav1.CreateOptions{})
+	return err
+}
+
+// revokeSession marks a session revoked, which its tokens are checked
+// against
+func (s *Server) revokeSession(ctx context.Context, id string) error {
+	return s.changeSession(ctx, id, func(session *Session) error {
+		if session.RevokedAt == nil {
+			now := time.Now().UTC()
+			session.RevokedAt = &now
+		}
+		return nil
+	})
+}
+
+// changeSession applies change to a stored session and saves it, and
+// drops this replica's cached revocations. It fails on a concurrent change.
+func (s *Server) changeSession(ctx context.Context, id string, change func(*Session) error) error {
+	session, secret, err := s.getSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := change(&session); err != nil {
+		return err
+	}
+	if err := setSessionSecret(secret, session); err != nil {
+		return err
+	}
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return err
+	}
+	if _, err := store.kubeClient.CoreV1().Secrets(s.clusters.namespace).Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	s.sessions.mu.Lock()
+	s.sessions.fetched = time.Time{}
+	s.sessions.mu.Unlock()
+	return nil
+}
+
+// getSession reads one session and its Secret. Expired sessions are not
+// found.
+func (s *Server) getSession(ctx context.Context, id string) (Session, *corev1.Secret, error) {
+	notFound := apierrors.NewNotFound(corev1.Resource("session"), id)
+	if id == "" {
+		return Session{}, nil, notFound
+	}
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return Session{}, nil, err
+	}
+	secret, err := store.kubeClient.CoreV1().Secrets(s.clusters.namespace).Get(ctx, sessionSecretName(id), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return Session{}, nil, notFound
+	}
+	if err != nil {
+		return Session{}, nil, fmt.Errorf("failed to read session: %w", err)
+	}
+	session, err := sessionFromSecret(secret)
+	if err != nil {
+		return Session{}, nil, err
+	}
+	if session.ID != id || !time.Now().Before(session.ExpiresAt) {
+		return Session{}, nil, notFound
+	}
+	return session, secret, nil
+}
+
+// loadSessions reads the live sessions, of one kind and subject when given
+func (s *Server) loadSessions(ctx context.Context, kind, subject string) ([]Session, error) {
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return nil, err
+	}
+	selector := sessionLabel
+	if kind != "" {
+		selector = sessionLabel + "=" + kind
+	}
+	if subject != "" {
+		selector += "," + sessionSubjectLabel + "=" + subjectHash(subject)
+	}
+	secrets, err := store.kubeClient.CoreV1().Secrets(s.clusters.namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sessions: %w", err)
+	}
+	now := time.Now()
+	sessions := []Session{}
+	for _, secret := range secrets.Items {
+		session, err := sessionFromSecret(&secret)
+		if err != nil {
+			log.Printf("Skipping session Secret %s: %v", secret.Name, err)
+			continue
+		}
+		// The subject label is a truncated hash; compare the subject itself
+		if now.Before(session.ExpiresAt) && (subject == "" || session.Subject == subject) {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+// reapSessions deletes the Secrets of expired sessions. Sessions live in
+// the local cluster.
+func (s *Server) reapSessions(ctx context.Context) error {
+	if s.cluster != s.clusters.local {
+		return nil
+	}
+	secrets := s.kubeClient.CoreV1().Secrets(s.clusters.namespace)
+	list, err := secrets.List(ctx, metav1.ListOptions{LabelSelector: sessionLabel})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, secret := range list.Items {
+		session, err := sessionFromSecret(&secret)
+		if err == nil && now.Before(session.ExpiresAt) {
+			continue
+		}
+		if err := secrets.Delete(ctx, secret.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			log.Printf("Failed to delete expired session Secret %s: %v", secret.Name, err)
+		}
+	}
+	return nil
+}
+
+// sessionSecretName is the Secret holding a session. Session IDs are
+// base64url, so they are hex-encoded into a valid name.
+func sessionSecretName(id string) string {
+	return sessionSecretPrefix + hex.EncodeToString([]byte(id))
+}
+
+// setSessionSecret writes a session and its labels into its Secret
+func setSessionSecret(secret *corev1.Secret, session Session) error {
+	raw, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	secret.Data = map[string][]byte{sessionKey: raw}
+	labels := map[string]string{
+		"app.kubernetes.io/managed-by": "gameplane-api",
+		sessionLabel:                   session.Kind,
+		sessionSubjectLabel:            subjectHash(session.Subject),
+	}
+	if session.RevokedAt != nil {
+		labels[sessionRevokedLabel] = "true"
+	}
+	secret.Labels = labels
+	return nil
+}
+
+// sessionFromSecret reads the session a Secret holds
+func sessionFromSecret(secret *corev1.Secret) (Session, error) {
+	var session Session
+	if err := json.Unmarshal(secret.Data[sessionKey], &session); err != nil {
+		return session, fmt.Errorf("failed to parse session: %w", err)
+	}
+	return session, nil
+}
+
+// subjectHash fits a subject, which may hold any characters, into a label
+// value
+func subjectHash(subject string) string {
+	sum := sha256.Sum256([]byte(subject))
+	return hex.EncodeToString(sum[:16])
+}
+
+// bearerToken returns the request's Authorization: Bearer token
+func bearerToken(r *http.Request) string {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// newSessionID returns a random session ID
+func newSessionID() string {
+	id := make([]byte, 9)
+	_, _ = rand.Read(id)
+	return base64.RawURLEncoding.EncodeToString(id)
+}
+
+// newRefreshToken returns a random refresh token naming its session
+func newRefreshToken(sessionID string) string {
+	secret := make([]byte, 32)
+	_, _ = rand.Read(secret)
+	return sessionID + "." + base64.RawURLEncoding.EncodeToString(secret)
+}
+
+// hashToken returns the hex SHA-256 of a token, as stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokensEqual compares token hashes in constant time
+func tokensEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
@@ -0,0 +1,84 @@
+package server_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kubelize/gameplane/api/pkg/gameplane/gameplanetest"
+)
+
+// sessionTokens is the response to a login or refresh
+type sessionTokens struct {
+	SessionID    string `json:"sessionId"`
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+func TestSessions(t *testing.T) {
+	h := newHarness(t)
+
+	var login sessionTokens
+	if code := call(t, h, "alice", gameplanetest.Request(http.MethodPost, "/api/v1/auth/sessions", nil), &login); code != http.StatusCreated || login.AccessToken == "" {
+		t.Fatalf("starting a session: got %d, want 201 with tokens", code)
+	}
+	me := func(token string) int {
+		return call(t, h, "", bearer(gameplanetest.Request(http.MethodGet, "/api/v1/auth/sessions", nil), token), nil)
+	}
+	if code := me(login.AccessToken); code != http.StatusOK {
+		t.Errorf("access token: got %d, want 200", code)
+	}
+	if code := call(t, h, "", bearer(gameplanetest.Request(http.MethodPost, "/api/v1/auth/sessions", nil), login.AccessToken), nil); code != http.StatusForbidden {
+		t.Errorf("starting a session from a session: got %d, want 403", code)
+	}
+
+	var list struct {
+		Total int `json:"total"`
+	}
+	if code := call(t, h, "bob", gameplanetest.Request(http.MethodGet, "/api/v1/auth/sessions", nil), &list); code != http.StatusOK || list.Total != 0 {
+		t.Errorf("listing as another subject: got %d with %d sessions, want 200 with none", code, list.Total)
+	}
+	if code := call(t, h, "bob", gameplanetest.Request(http.MethodDelete, "/api/v1/auth/sessions/"+login.SessionID, nil), nil); code != http.StatusNotFound {
+		t.Errorf("revoking another's session: got %d, want 404", code)
+	}
+
+	refresh := func(token string) (int, sessionTokens) {
+		var tokens sessionTokens
+		code := call(t, h, "", gameplanetest.Request(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refreshToken": token}), &tokens)
+		return code, tokens
+	}
+	code, refreshed := refresh(login.RefreshToken)
+	if code != http.StatusOK || refreshed.RefreshToken == login.RefreshToken {
+		t.Fatalf("refreshing: got %d, want 200 with a new refresh token", code)
+	}
+	if code := me(refreshed.AccessToken); code != http.StatusOK {
+		t.Errorf("refreshed access token: got %d, want 200", code)
+	}
+	// Reusing a replaced refresh token ends the session
+	if code, _ := refresh(login.RefreshToken); code != http.StatusUnauthorized {
+		t.Errorf("reusing a replaced refresh token: got %d, want 401", code)
+	}
+	if code := me(refreshed.AccessToken); code != http.StatusUnauthorized {
+		t.Errorf("access token after refresh token reuse: got %d, want 401", code)
+	}
+	if code, _ := refresh(refreshed.RefreshToken); code != http.StatusUnauthorized {
+		t.Errorf("refreshing a revoked session: got %d, want 401", code)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	h := newHarness(t)
+
+	var login sessionTokens
+	if code := call(t, h, "alice", gameplanetest.Request(http.MethodPost, "/api/v1/auth/sessions", nil), &login); code != http.StatusCreated {
+		t.Fatalf("starting a session: got %d, want 201", code)
+	}
+	if code := call(t, h, "", bearer(gameplanetest.Request(http.MethodPost, "/api/v1/auth/logout", nil), login.AccessToken), nil); code != http.StatusOK {
+		t.Fatalf("logging out: got %d, want 200", code)
+	}
+	if code := call(t, h, "", bearer(gameplanetest.Request(http.MethodGet, "/api/v1/auth/sessions", nil), login.AccessToken), nil); code != http.StatusUnauthorized {
+		t.Errorf("access token after logout: got %d, want 401", code)
+	}
+	if code := call(t, h, "", gameplanetest.Request(http.MethodPost, "/api/v1/auth/refresh", map[string]string{"refreshToken": login.RefreshToken}), nil); code != http.StatusUnauthorized {
+		t.Errorf("refresh after logout: got %d, want 401", code)
+	}
+}
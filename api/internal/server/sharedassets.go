@@ -0,0 +1,690 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// sharedAssetLabel names the asset of library claims and of the
+	// read-only copies provided to GameServer namespaces
+	sharedAssetLabel = "gameplane.kubelize.io/shared-asset"
+
+	// sharedAssetDescriptionAnnotation describes a library asset
+	sharedAssetDescriptionAnnotation = "gameplane.kubelize.io/description"
+
+	// sharedAssetSourceAnnotation records the URL an asset was last loaded
+	// from
+	sharedAssetSourceAnnotation = "gameplane.kubelize.io/source"
+
+	// sharedAssetGameVersionsAnnotation lists the game versions an asset is
+	// known to work with, comma separated
+	sharedAssetGameVersionsAnnotation = "gameplane.kubelize.io/game-versions"
+
+	// sharedAssetLoadedAtAnnotation records when an asset's content last
+	// changed, so servers mounting it know they run an older version
+	sharedAssetLoadedAtAnnotation = "gameplane.kubelize.io/loaded-at"
+
+	// sharedAssetClaimPrefix prefixes library claims in the cluster
+	// registry namespace
+	sharedAssetClaimPrefix = "asset-"
+
+	// sharedVolumeClaimPrefix prefixes the claims game compositions mount
+	// shared assets from
+	sharedVolumeClaimPrefix = "shared-"
+
+	// sharedVolumeSyncInterval is how often shared assets are provided to
+	// the namespaces using them
+	sharedVolumeSyncInterval = 30 * time.Second
+
+	// sharedAssetLoadTimeout bounds loading an archive into an asset
+	sharedAssetLoadTimeout = 2 * time.Hour
+
+	maxSharedVolumes = 8
+)
+
+// Shared asset load steps, in order
+const (
+	sharedAssetStepLoad = "load"
+)
+
+// sharedAssetName matches asset names, which end up in claim, volume and
+// PersistentVolume names
+var sharedAssetName = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,38}[a-z0-9])?$`)
+
+// SharedAsset is a volume of the shared asset library
+type SharedAsset struct {
+	Name         string `json:"name"`
+	Description  string `json:"description,omitempty"`
+	Size         string `json:"size"`
+	StorageClass string `json:"storageClass,omitempty"`
+	// Phase is the phase of the library claim; assets are mounted once it
+	// is Bound
+	Phase  string `json:"phase"`
+	Source string `json:"source,omitempty"`
+	// LoadedAt is when content was last loaded into the asset
+	LoadedAt *time.Time `json:"loadedAt,omitempty"`
+	// GameVersions are the game versions the asset is known to work with
+	GameVersions []string `json:"gameVersions,omitempty"`
+	// Servers are the GameServers mounting the asset
+	Servers   []string  `json:"servers"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SharedAssetRequest creates a library asset
+type SharedAssetRequest struct {
+	Name         string   `json:"name"`
+	Description  string   `json:"description,omitempty"`
+	Size         string   `json:"size"`
+	StorageClass string   `json:"storageClass,omitempty"`
+	GameVersions []string `json:"gameVersions,omitempty"`
+}
+
+// SharedAssetUpdate changes the metadata of an asset; omitted fields are
+// kept
+type SharedAssetUpdate struct {
+	Description  *string  `json:"description,omitempty"`
+	GameVersions []string `json:"gameVersions"`
+}
+
+// SharedAssetLoad fills an asset from an archive
+type SharedAssetLoad struct {
+	// URL of a .zip, .tar, .tar.gz or .tgz archive, or a single file
+	URL string `json:"url"`
+	// Replace empties the asset first instead of adding to it
+	Replace bool `json:"replace,omitempty"`
+	// GameVersions are the game versions the mod source lists as
+	// compatible. A replacing load without them clears the old list.
+	GameVersions []string `json:"gameVersions,omitempty"`
+}
+
+// sharedVolumesSpec converts spec.sharedVolumes for the claim
+func sharedVolumesSpec(volumes []GameServerSharedVolume) []interface{} {
+	out := make([]interface{}, 0, len(volumes))
+	for _, volume := range volumes {
+		entry := map[string]interface{}{
+			"name":      volume.Name,
+			"mountPath": volume.MountPath,
+		}
+		if volume.SubPath != "" {
+			entry["subPath"] = volume.SubPath
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+// gameServerSharedVolumes reads spec.sharedVolumes
+func gameServerSharedVolumes(obj *unstructured.Unstructured) []GameServerSharedVolume {
+	raw, _, _ := unstructured.NestedSlice(obj.Object, "spec", "sharedVolumes")
+	volumes := []GameServerSharedVolume{}
+	for _, item := range raw {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		volume := GameServerSharedVolume{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(entry, &volume); err == nil {
+			volumes = append(volumes, volume)
+		}
+	}
+	return volumes
+}
+
+// validateSharedVolumes checks the form of spec.sharedVolumes
+func validateSharedVolumes(volumes []GameServerSharedVolume) error {
+	if len(volumes) > maxSharedVolumes {
+		return fmt.Errorf("a GameServer mounts at most %d shared volumes", maxSharedVolumes)
+	}
+	seen := map[string]bool{}
+	for _, volume := range volumes {
+		switch {
+		case !sharedAssetName.MatchString(volume.Name):
+			return fmt.Errorf("invalid shared volume name %q", volume.Name)
+		case seen[volume.Name]:
+			return fmt.Errorf("shared volume %s is mounted twice", volume.Name)
+		case !path.IsAbs(volume.MountPath) || path.Clean(volume.MountPath) != volume.MountPath:
+			return fmt.Errorf("mountPath of shared volume %s must be a clean absolute path", volume.Name)
+		case volume.MountPath == gameDataMountPath:
+			return fmt.Errorf("shared volume %s cannot replace the game data directory", volume.Name)
+		case path.IsAbs(volume.SubPath) || strings.Contains(volume.SubPath, ".."):
+			return fmt.Errorf("subPath of shared volume %s must be relative", volume.Name)
+		}
+		seen[volume.Name] = true
+	}
+	return nil
+}
+
+// checkSharedAssets validates spec.sharedVolumes and checks every asset is
+// in the library
+func (s *Server) checkSharedAssets(ctx context.Context, volumes []GameServerSharedVolume) error {
+	if err := validateSharedVolumes(volumes); err != nil {
+		return err
+	}
+	for _, volume := range volumes {
+		_, err := s.kubeClient.CoreV1().PersistentVolumeClaims(s.clusters.namespace).Get(ctx, sharedAssetClaimPrefix+volume.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return fmt.Errorf("shared asset %s is not in the library", volume.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listSharedAssets returns the shared asset library
+func (s *Server) listSharedAssets(c *gin.Context) {
+	claims, err := s.kubeClient.CoreV1().PersistentVolumeClaims(s.clusters.namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: sharedAssetLabel})
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list shared assets: %v", err),
+		})
+		return
+	}
+	users, err := s.sharedAssetUsers(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list GameServers: %v", err),
+		})
+		return
+	}
+
+	items := make([]SharedAsset, 0, len(claims.Items))
+	for i := range claims.Items {
+		asset := sharedAssetFromClaim(&claims.Items[i])
+		asset.Servers = append(asset.Servers, users[asset.Name]...)
+		items = append(items, asset)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Name < items[j].Name
+	})
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(items),
+	})
+}
+
+// createSharedAsset adds an empty ReadWriteMany volume to the library
+func (s *Server) createSharedAsset(c *gin.Context) {
+	var req SharedAssetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if !sharedAssetName.MatchString(req.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "name must be a DNS label of at most 40 characters",
+		})
+		return
+	}
+	size, err := resource.ParseQuantity(req.Size)
+	if err != nil || size.Sign() <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid size %q", req.Size),
+		})
+		return
+	}
+	if err := validateGameVersions(req.GameVersions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sharedAssetClaimPrefix + req.Name,
+			Namespace: s.clusters.namespace,
+			Labels: map[string]string{
+				sharedAssetLabel:               req.Name,
+				"app.kubernetes.io/managed-by": "gameplane-api",
+			},
+			Annotations: map[string]string{},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+			},
+		},
+	}
+	if req.Description != "" {
+		claim.Annotations[sharedAssetDescriptionAnnotation] = req.Description
+	}
+	if len(req.GameVersions) > 0 {
+		claim.Annotations[sharedAssetGameVersionsAnnotation] = strings.Join(req.GameVersions, ",")
+	}
+	if req.StorageClass != "" {
+		claim.Spec.StorageClassName = &req.StorageClass
+	}
+	created, err := s.kubeClient.CoreV1().PersistentVolumeClaims(s.clusters.namespace).Create(context.TODO(), claim, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("Shared asset %s already exists", req.Name),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to create shared asset: %v", err),
+		})
+		return
+	}
+	asset := sharedAssetFromClaim(created)
+	c.JSON(http.StatusCreated, asset)
+}
+
+// getSharedAsset returns one library asset with the servers mounting it
+func (s *Server) getSharedAsset(c *gin.Context) {
+	claim, ok := s.loadSharedAsset(c)
+	if !ok {
+		return
+	}
+	users, err := s.sharedAssetUsers(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list GameServers: %v", err),
+		})
+		return
+	}
+	asset := sharedAssetFromClaim(claim)
+	asset.Servers = append(asset.Servers, users[asset.Name]...)
+	c.JSON(http.StatusOK, asset)
+}
+
+// deleteSharedAsset removes an asset no GameServer mounts anymore
+func (s *Server) deleteSharedAsset(c *gin.Context) {
+	claim, ok := s.loadSharedAsset(c)
+	if !ok {
+		return
+	}
+	users, err := s.sharedAssetUsers(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list GameServers: %v", err),
+		})
+		return
+	}
+	name := c.Param("asset")
+	if servers := users[name]; len(servers) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   fmt.Sprintf("Shared asset %s is mounted by %d GameServers", name, len(servers)),
+			"servers": servers,
+		})
+		return
+	}
+	if err := s.kubeClient.CoreV1().PersistentVolumeClaims(claim.Namespace).Delete(context.TODO(), claim.Name, metav1.DeleteOptions{}); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete shared asset: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Shared asset %s deleted", name),
+	})
+}
+
+// updateSharedAsset changes the description or compatible game versions of
+// an asset, such as when a mod is confirmed to work with a new game patch
+func (s *Server) updateSharedAsset(c *gin.Context) {
+	var req SharedAssetUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if err := validateGameVersions(req.GameVersions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	claim, ok := s.loadSharedAsset(c)
+	if !ok {
+		return
+	}
+	if claim.Annotations == nil {
+		claim.Annotations = map[string]string{}
+	}
+	if req.Description != nil {
+		if *req.Description == "" {
+			delete(claim.Annotations, sharedAssetDescriptionAnnotation)
+		} else {
+			claim.Annotations[sharedAssetDescriptionAnnotation] = *req.Description
+		}
+	}
+	if req.GameVersions != nil {
+		if len(req.GameVersions) == 0 {
+			delete(claim.Annotations, sharedAssetGameVersionsAnnotation)
+		} else {
+			claim.Annotations[sharedAssetGameVersionsAnnotation] = strings.Join(req.GameVersions, ",")
+		}
+	}
+	updated, err := s.kubeClient.CoreV1().PersistentVolumeClaims(claim.Namespace).Update(context.TODO(), claim, metav1.UpdateOptions{})
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update shared asset: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, sharedAssetFromClaim(updated))
+}
+
+// loadSharedAssetContent downloads an archive into an asset as an
+// operation. Servers mounting the asset see the new files right away, so
+// larger changes are best loaded into a new asset.
+func (s *Server) loadSharedAssetContent(c *gin.Context) {
+	var req SharedAssetLoad
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	source, err := url.Parse(req.URL)
+	if err != nil || (source.Scheme != "http" && source.Scheme != "https") || source.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "url must be an http or https URL",
+		})
+		return
+	}
+	if err := validateGameVersions(req.GameVersions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	claim, ok := s.loadSharedAsset(c)
+	if !ok {
+		return
+	}
+
+	name := c.Param("asset")
+	op := s.startOperation("shared-asset-load", claim.Namespace, name, []string{sharedAssetStepLoad}, sharedAssetLoadTimeout, func(ctx context.Context, t *operationTracker) (interface{}, error) {
+		var output string
+		err := t.step(sharedAssetStepLoad, func() (string, error) {
+			var err error
+			output, err = s.runVolumeTaskIn(ctx, claim.Namespace, volumeTask{
+				Name:    "load-asset",
+				Script:  sharedAssetLoadScript(req),
+				Claim:   claim.Name,
+				Timeout: sharedAssetLoadTimeout,
+			})
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSpace(output), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		latest, err := s.kubeClient.CoreV1().PersistentVolumeClaims(claim.Namespace).Get(ctx, claim.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if latest.Annotations == nil {
+			latest.Annotations = map[string]string{}
+		}
+		latest.Annotations[sharedAssetSourceAnnotation] = req.URL
+		latest.Annotations[sharedAssetLoadedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+		if len(req.GameVersions) > 0 {
+			latest.Annotations[sharedAssetGameVersionsAnnotation] = strings.Join(req.GameVersions, ",")
+		} else if req.Replace {
+			delete(latest.Annotations, sharedAssetGameVersionsAnnotation)
+		}
+		if _, err := s.kubeClient.CoreV1().PersistentVolumeClaims(claim.Namespace).Update(ctx, latest, metav1.UpdateOptions{}); err != nil {
+			log.Printf("Failed to record source of shared asset %s: %v", name, err)
+		}
+		return gin.H{"asset": name, "source": req.URL, "output": strings.TrimSpace(output)}, nil
+	})
+	c.JSON(http.StatusAccepted, op)
+}
+
+// sharedAssetLoadScript downloads and unpacks an archive into the asset
+// volume, mounted at gameDataMountPath
+func sharedAssetLoadScript(req SharedAssetLoad) string {
+	var script strings.Builder
+	if req.Replace {
+		fmt.Fprintf(&script, "find %s -mindepth 1 -delete\n", gameDataMountPath)
+	}
+	file := path.Base(strings.SplitN(req.URL, "?", 2)[0])
+	fmt.Fprintf(&script, "wget -q -O /tmp/asset %s\n", shellQuote(req.URL))
+	fmt.Fprintf(&script, "cd %s\n", gameDataMountPath)
+	switch {
+	case strings.HasSuffix(file, ".zip"):
+		script.WriteString("unzip -o -q /tmp/asset\n")
+	case strings.HasSuffix(file, ".tar.gz") || strings.HasSuffix(file, ".tgz"):
+		script.WriteString("tar -xzf /tmp/asset\n")
+	case strings.HasSuffix(file, ".tar"):
+		script.WriteString("tar -xf /tmp/asset\n")
+	default:
+		fmt.Fprintf(&script, "mv /tmp/asset %s\n", shellQuote(file))
+	}
+	script.WriteString("du -sh . | cut -f1\n")
+	return script.String()
+}
+
+// loadSharedAsset fetches the library claim of the asset named in the
+// route, writing the error response itself when it cannot
+func (s *Server) loadSharedAsset(c *gin.Context) (*corev1.PersistentVolumeClaim, bool) {
+	claim, err := s.kubeClient.CoreV1().PersistentVolumeClaims(s.clusters.namespace).Get(context.TODO(), sharedAssetClaimPrefix+c.Param("asset"), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || (err == nil && claim.Labels[sharedAssetLabel] == "") {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Shared asset not found",
+		})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get shared asset: %v", err),
+		})
+		return nil, false
+	}
+	return claim, true
+}
+
+// sharedAssetFromClaim summarizes a library claim
+func sharedAssetFromClaim(claim *corev1.PersistentVolumeClaim) SharedAsset {
+	asset := SharedAsset{
+		Name:        claim.Labels[sharedAssetLabel],
+		Description: claim.Annotations[sharedAssetDescriptionAnnotation],
+		Phase:       string(claim.Status.Phase),
+		Source:      claim.Annotations[sharedAssetSourceAnnotation],
+		Servers:     []string{},
+		CreatedAt:   claim.CreationTimestamp.Time,
+	}
+	if versions := splitList(claim.Annotations[sharedAssetGameVersionsAnnotation]); len(versions) > 0 {
+		asset.GameVersions = versions
+	}
+	if loadedAt, err := time.Parse(time.RFC3339, claim.Annotations[sharedAssetLoadedAtAnnotation]); err == nil {
+		asset.LoadedAt = &loadedAt
+	}
+	if size, ok := claim.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+		asset.Size = size.String()
+	}
+	if claim.Spec.StorageClassName != nil {
+		asset.StorageClass = *claim.Spec.StorageClassName
+	}
+	return asset
+}
+
+// sharedAssetUsers maps asset names to the GameServers mounting them, as
+// namespace/name
+func (s *Server) sharedAssetUsers(ctx context.Context) (map[string][]string, error) {
+	list, err := s.listAllGameServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	users := map[string][]string{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		for _, volume := range gameServerSharedVolumes(obj) {
+			users[volume.Name] = append(users[volume.Name], obj.GetNamespace()+"/"+obj.GetName())
+		}
+	}
+	return users, nil
+}
+
+// listAllGameServers lists GameServers in every namespace
+func (s *Server) listAllGameServers(ctx context.Context) (*unstructured.UnstructuredList, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// syncSharedVolumes provides shared assets to the managed namespaces of the
+// GameServers mounting them and removes copies no server mounts anymore.
+// Claims cannot cross namespaces, so each copy is a read-only
+// PersistentVolume with the library volume's source, bound to a claim in
+// the GameServer's namespace.
+func (s *Server) syncSharedVolumes(ctx context.Context) error {
+	list, err := s.listAllGameServers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+	wanted := map[string]bool{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		volumes := gameServerSharedVolumes(obj)
+		if len(volumes) == 0 {
+			continue
+		}
+		namespace, err := managedNamespace(obj)
+		if err != nil {
+			// Not provisioned yet
+			continue
+		}
+		for _, volume := range volumes {
+			wanted[namespace+"/"+volume.Name] = true
+			if err := s.provideSharedVolume(ctx, namespace, volume.Name); err != nil {
+				log.Printf("Failed to provide shared asset %s to GameServer %s/%s: %v", volume.Name, obj.GetNamespace(), obj.GetName(), err)
+			}
+		}
+	}
+
+	claims, err := s.kubeClient.CoreV1().PersistentVolumeClaims(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: sharedAssetLabel})
+	if err != nil {
+		return fmt.Errorf("failed to list shared volume claims: %w", err)
+	}
+	for _, claim := range claims.Items {
+		asset := claim.Labels[sharedAssetLabel]
+		if claim.Namespace == s.clusters.namespace || wanted[claim.Namespace+"/"+asset] {
+			continue
+		}
+		if err := s.kubeClient.CoreV1().PersistentVolumeClaims(claim.Namespace).Delete(ctx, claim.Name, metav1.DeleteOptions{}); client.IgnoreNotFound(err) != nil {
+			log.Printf("Failed to remove shared asset %s from namespace %s: %v", asset, claim.Namespace, err)
+			continue
+		}
+		if err := s.kubeClient.CoreV1().PersistentVolumes().Delete(ctx, sharedVolumeName(asset, claim.Namespace), metav1.DeleteOptions{}); client.IgnoreNotFound(err) != nil {
+			log.Printf("Failed to remove shared asset %s volume of namespace %s: %v", asset, claim.Namespace, err)
+		}
+	}
+	return nil
+}
+
+// provideSharedVolume creates the read-only copy of an asset in a managed
+// namespace once the library claim is bound
+func (s *Server) provideSharedVolume(ctx context.Context, namespace, asset string) error {
+	claims := s.kubeClient.CoreV1().PersistentVolumeClaims(namespace)
+	if _, err := claims.Get(ctx, sharedVolumeClaimPrefix+asset, metav1.GetOptions{}); err == nil {
+		return nil
+	} else if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	library, err := s.kubeClient.CoreV1().PersistentVolumeClaims(s.clusters.namespace).Get(ctx, sharedAssetClaimPrefix+asset, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if library.Status.Phase != corev1.ClaimBound || library.Spec.VolumeName == "" {
+		return fmt.Errorf("library claim is %s", library.Status.Phase)
+	}
+	source, err := s.kubeClient.CoreV1().PersistentVolumes().Get(ctx, library.Spec.VolumeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	labels := map[string]string{
+		sharedAssetLabel:               asset,
+		"kubelize.io/gameserver":       namespace,
+		"app.kubernetes.io/managed-by": "gameplane-api",
+	}
+	volumeName := sharedVolumeName(asset, namespace)
+	noClass := ""
+	volume := &corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: volumeName, Labels: labels},
+		Spec: corev1.PersistentVolumeSpec{
+			Capacity:                      source.Spec.Capacity,
+			PersistentVolumeSource:        source.Spec.PersistentVolumeSource,
+			AccessModes:                   []corev1.PersistentVolumeAccessMode{corev1.ReadOnlyMany},
+			PersistentVolumeReclaimPolicy: corev1.PersistentVolumeReclaimRetain,
+			MountOptions:                  source.Spec.MountOptions,
+			NodeAffinity:                  source.Spec.NodeAffinity,
+			StorageClassName:              noClass,
+			ClaimRef: &corev1.ObjectReference{
+				Kind:      "PersistentVolumeClaim",
+				Namespace: namespace,
+				Name:      sharedVolumeClaimPrefix + asset,
+			},
+		},
+	}
+	if _, err := s.kubeClient.CoreV1().PersistentVolumes().Create(ctx, volume, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create volume: %w", err)
+	}
+	_, err = claims.Create(ctx, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      sharedVolumeClaimPrefix + asset,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadOnlyMany},
+			StorageClassName: &noClass,
+			VolumeName:       volumeName,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: source.Spec.Capacity[corev1.ResourceStorage]},
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create claim: %w", err)
+	}
+	log.Printf("Provided shared asset %s to namespace %s", asset, namespace)
+	return nil
+}
+
+// sharedVolumeName names the PersistentVolume copying an asset into a
+// namespace
+func sharedVolumeName(asset, namespace string) string {
+	return "gameplane-" + sharedVolumeClaimPrefix + asset + "-" + namespace
+}
@@ -0,0 +1,359 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Labels on VolumeSnapshots taken by the API
+	backupTypeLabel        = "gameplane.kubelize.io/backup-type"
+	backupOperationLabel   = "gameplane.kubelize.io/operation"
+	backupOperationIDLabel = "gameplane.kubelize.io/operation-id"
+
+	// backupTypeAuto marks safety snapshots taken before destructive
+	// operations; other snapshots of a server's volume are manual
+	backupTypeAuto   = "auto"
+	backupTypeManual = "manual"
+
+	defaultAutoSnapshotRetain = 5
+	defaultAutoSnapshotMaxAge = 7 * 24 * time.Hour
+
+	// snapshotCutTimeout bounds the wait for the storage driver to take a
+	// snapshot; the volume is only touched once it has
+	snapshotCutTimeout   = 2 * time.Minute
+	snapshotPollInterval = 2 * time.Second
+
+	autoSnapshotPruneInterval = time.Hour
+)
+
+// volumeSnapshotGVK is the CSI VolumeSnapshot kind
+var volumeSnapshotGVK = schema.GroupVersionKind{Group: "snapshot.storage.k8s.io", Version: "v1", Kind: "VolumeSnapshot"}
+
+// snapshotPolicy configures safety snapshots. Without a snapshot class none
+// are taken.
+type snapshotPolicy struct {
+	class string
+	// retain is how many safety snapshots are kept per server, maxAge how
+	// long any of them is kept
+	retain int
+	maxAge time.Duration
+	// verifyInterval is how often the newest backup of each server is
+	// verified, never when zero; verifyRestore makes those verifications
+	// test restores
+	verifyInterval time.Duration
+	verifyRestore  bool
+}
+
+// newSnapshotPolicy reads VOLUME_SNAPSHOT_CLASS, AUTO_SNAPSHOT_RETAIN,
+// AUTO_SNAPSHOT_MAX_AGE, BACKUP_VERIFY_INTERVAL and BACKUP_VERIFY_RESTORE
+func newSnapshotPolicy() (*snapshotPolicy, error) {
+	policy := &snapshotPolicy{
+		class:          os.Getenv("VOLUME_SNAPSHOT_CLASS"),
+		retain:         defaultAutoSnapshotRetain,
+		maxAge:         defaultAutoSnapshotMaxAge,
+		verifyInterval: defaultBackupVerifyInterval,
+	}
+	if raw := os.Getenv("AUTO_SNAPSHOT_RETAIN"); raw != "" {
+		retain, err := strconv.Atoi(raw)
+		if err != nil || retain < 1 {
+			return nil, fmt.Errorf("invalid AUTO_SNAPSHOT_RETAIN %q", raw)
+		}
+		policy.retain = retain
+	}
+	if raw := os.Getenv("AUTO_SNAPSHOT_MAX_AGE"); raw != "" {
+		maxAge, err := time.ParseDuration(raw)
+		if err != nil || maxAge <= 0 {
+			return nil, fmt.Errorf("invalid AUTO_SNAPSHOT_MAX_AGE %q", raw)
+		}
+		policy.maxAge = maxAge
+	}
+	if raw := os.Getenv("BACKUP_VERIFY_INTERVAL"); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval < 0 {
+			return nil, fmt.Errorf("invalid BACKUP_VERIFY_INTERVAL %q", raw)
+		}
+		policy.verifyInterval = interval
+	}
+	if raw := os.Getenv("BACKUP_VERIFY_RESTORE"); raw != "" {
+		restore, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BACKUP_VERIFY_RESTORE %q", raw)
+		}
+		policy.verifyRestore = restore
+	}
+	return policy, nil
+}
+
+// Backup is a snapshot of a GameServer's data volume
+type Backup struct {
+	Name string `json:"name"`
+	// Type is auto for safety snapshots, manual otherwise
+	Type string `json:"type"`
+	// Operation and OperationID identify what a safety snapshot was taken for
+	Operation     string    `json:"operation,omitempty"`
+	OperationID   string    `json:"operationId,omitempty"`
+	Volume        string    `json:"volume"`
+	SnapshotClass string    `json:"snapshotClass,omitempty"`
+	Ready         bool      `json:"ready"`
+	Size          string    `json:"size,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	// ExpiresAt is when retention removes a safety snapshot at the latest
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	// Verification is the outcome of the last verification
+	Verification *BackupVerification `json:"verification,omitempty"`
+}
+
+// listBackups returns the snapshots of a GameServer's data volume, newest
+// first. ?type=auto or ?type=manual filters them.
+func (s *Server) listBackups(c *gin.Context) {
+	backupType := c.Query("type")
+	if backupType != "" && backupType != backupTypeAuto && backupType != backupTypeManual {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid type %q: must be auto or manual", backupType),
+		})
+		return
+	}
+
+	obj, err := s.getGameServerObject(context.TODO(), c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+	namespace, err := managedNamespace(obj)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	snapshots, err := s.listVolumeSnapshots(context.TODO(), client.InNamespace(namespace))
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list volume snapshots: %v", err),
+		})
+		return
+	}
+	backups := []Backup{}
+	for i := range snapshots {
+		backup := s.backupFromSnapshot(&snapshots[i])
+		if backup.Volume != dataVolumeName(namespace) || (backupType != "" && backup.Type != backupType) {
+			continue
+		}
+		backups = append(backups, backup)
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":            backups,
+		"chain":            backupChain(backups),
+		"autoSnapshots":    s.snapshots.class != "",
+		"retain":           s.snapshots.retain,
+		"retainMaxAge":     s.snapshots.maxAge.String(),
+		"snapshotClass":    s.snapshots.class,
+		"managedVolume":    dataVolumeName(namespace),
+		"managedNamespace": namespace,
+	})
+}
+
+// dataVolumeName is the PVC holding a server's data in its managed namespace
+func dataVolumeName(namespace string) string {
+	return namespace + "-storage"
+}
+
+// safetySnapshot snapshots a GameServer's data volume before a destructive
+// operation and waits until the storage driver has taken it. It returns nil
+// without a snapshot class or before the volume exists; an empty
+// operationID gets a new one.
+func (s *Server) safetySnapshot(ctx context.Context, obj *unstructured.Unstructured, operation, operationID string) (*Backup, error) {
+	if s.snapshots.class == "" {
+		return nil, nil
+	}
+	namespace, err := managedNamespace(obj)
+	if err != nil {
+		return nil, nil
+	}
+	volume := dataVolumeName(namespace)
+	if _, err := s.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, volume, metav1.GetOptions{}); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if operationID == "" {
+		operationID = newOperationID()
+	}
+
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	snapshot.SetNamespace(namespace)
+	snapshot.SetName(fmt.Sprintf("%s-auto-%s", namespace, time.Now().UTC().Format("20060102t150405")))
+	snapshot.SetLabels(map[string]string{
+		backupTypeLabel:          backupTypeAuto,
+		backupOperationLabel:     operation,
+		backupOperationIDLabel:   operationID,
+		"kubelize.io/gameserver": namespace,
+	})
+	snapshot.SetAnnotations(map[string]string{
+		"gameplane.kubelize.io/claim": obj.GetNamespace() + "/" + obj.GetName(),
+	})
+	snapshot.Object["spec"] = map[string]interface{}{
+		"volumeSnapshotClassName": s.snapshots.class,
+		"source": map[string]interface{}{
+			"persistentVolumeClaimName": volume,
+		},
+	}
+	if err := s.k8sClient.Create(ctx, snapshot); err != nil {
+		return nil, fmt.Errorf("failed to create volume snapshot: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, snapshotCutTimeout)
+	defer cancel()
+	ticker := time.NewTicker(snapshotPollInterval)
+	defer ticker.Stop()
+	for {
+		latest := &unstructured.Unstructured{}
+		latest.SetGroupVersionKind(volumeSnapshotGVK)
+		if err := s.k8sClient.Get(ctx, client.ObjectKeyFromObject(snapshot), latest); err == nil {
+			backup := s.backupFromSnapshot(latest)
+			if backup.Error != "" {
+				return nil, fmt.Errorf("volume snapshot %s failed: %s", snapshot.GetName(), backup.Error)
+			}
+			// creationTime is the point in time the snapshot holds, even
+			// while the driver is still uploading it
+			if _, taken, _ := unstructured.NestedString(latest.Object, "status", "creationTime"); taken || backup.Ready {
+				log.Printf("Took safety snapshot %s/%s of GameServer %s/%s before %s", namespace, snapshot.GetName(), obj.GetNamespace(), obj.GetName(), operation)
+				s.pruneAutoSnapshots(ctx, namespace)
+				return &backup, nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("volume snapshot %s not taken within %s", snapshot.GetName(), snapshotCutTimeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// listVolumeSnapshots lists VolumeSnapshots; none when the snapshot CRDs are
+// not installed
+func (s *Server) listVolumeSnapshots(ctx context.Context, opts ...client.ListOption) ([]unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(volumeSnapshotGVK.GroupVersion().WithKind("VolumeSnapshotList"))
+	if err := s.k8sClient.List(ctx, list, opts...); err != nil {
+		if meta.IsNoMatchError(err) || apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+// backupFromSnapshot describes a VolumeSnapshot
+func (s *Server) backupFromSnapshot(snapshot *unstructured.Unstructured) Backup {
+	labels := snapshot.GetLabels()
+	backup := Backup{
+		Name:        snapshot.GetName(),
+		Type:        backupTypeManual,
+		Operation:   labels[backupOperationLabel],
+		OperationID: labels[backupOperationIDLabel],
+		CreatedAt:   snapshot.GetCreationTimestamp().UTC(),
+	}
+	if labels[backupTypeLabel] == backupTypeAuto {
+		backup.Type = backupTypeAuto
+	}
+	backup.Volume, _, _ = unstructured.NestedString(snapshot.Object, "spec", "source", "persistentVolumeClaimName")
+	backup.SnapshotClass, _, _ = unstructured.NestedString(snapshot.Object, "spec", "volumeSnapshotClassName")
+	backup.Ready, _, _ = unstructured.NestedBool(snapshot.Object, "status", "readyToUse")
+	backup.Size, _, _ = unstructured.NestedString(snapshot.Object, "status", "restoreSize")
+	backup.Error, _, _ = unstructured.NestedString(snapshot.Object, "status", "error", "message")
+	if raw, _, _ := unstructured.NestedString(snapshot.Object, "status", "creationTime"); raw != "" {
+		if taken, err := time.Parse(time.RFC3339, raw); err == nil {
+			backup.CreatedAt = taken.UTC()
+		}
+	}
+	if backup.Type == backupTypeAuto {
+		expires := backup.CreatedAt.Add(s.snapshots.maxAge)
+		backup.ExpiresAt = &expires
+	}
+	if raw := snapshot.GetAnnotations()[backupVerificationAnnotation]; raw != "" {
+		var verification BackupVerification
+		if err := json.Unmarshal([]byte(raw), &verification); err == nil {
+			backup.Verification = &verification
+		}
+	}
+	return backup
+}
+
+// pruneAutoSnapshots applies the retention policy to the safety snapshots
+// of one managed namespace, or of all of them when namespace is empty
+func (s *Server) pruneAutoSnapshots(ctx context.Context, namespace string) {
+	opts := []client.ListOption{client.MatchingLabels{backupTypeLabel: backupTypeAuto}}
+	if namespace != "" {
+		opts = append(opts, client.InNamespace(namespace))
+	}
+	snapshots, err := s.listVolumeSnapshots(ctx, opts...)
+	if err != nil {
+		log.Printf("Failed to list safety snapshots: %v", err)
+		return
+	}
+
+	type volumeKey struct{ namespace, volume string }
+	byVolume := map[volumeKey][]Backup{}
+	for i := range snapshots {
+		backup := s.backupFromSnapshot(&snapshots[i])
+		key := volumeKey{snapshots[i].GetNamespace(), backup.Volume}
+		byVolume[key] = append(byVolume[key], backup)
+	}
+	now := time.Now().UTC()
+	for key, backups := range byVolume {
+		sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+		snapshotNamespace := key.namespace
+		for i, backup := range backups {
+			if i < s.snapshots.retain && now.Sub(backup.CreatedAt) < s.snapshots.maxAge {
+				continue
+			}
+			snapshot := &unstructured.Unstructured{}
+			snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+			snapshot.SetNamespace(snapshotNamespace)
+			snapshot.SetName(backup.Name)
+			if err := s.k8sClient.Delete(ctx, snapshot); client.IgnoreNotFound(err) != nil {
+				log.Printf("Failed to delete expired safety snapshot %s/%s: %v", snapshotNamespace, backup.Name, err)
+			}
+		}
+	}
+}
+
+// pruneAllAutoSnapshots is the background task expiring safety snapshots
+func (s *Server) pruneAllAutoSnapshots(ctx context.Context) error {
+	if s.snapshots.class == "" {
+		return nil
+	}
+	s.pruneAutoSnapshots(ctx, "")
+	return nil
+}
@@ -0,0 +1,647 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// steamCacheName names the Deployment, Service and claim of the Steam
+	// cache in the cluster registry namespace
+	steamCacheName = "gameplane-steamcache"
+
+	// steamCacheConfigAnnotation records the settings the cache was
+	// deployed with on its Deployment
+	steamCacheConfigAnnotation = "gameplane.kubelize.io/steam-cache"
+
+	defaultSteamCacheImage       = "lancachenet/monolithic:latest"
+	defaultSteamCacheStorageSize = "200Gi"
+	defaultSteamCacheCPU         = "1"
+	defaultSteamCacheMemory      = "1Gi"
+
+	// steamCacheLogImage runs the sidecar printing the cache's access log,
+	// from which hit rates are read
+	steamCacheLogImage = "busybox"
+
+	// steamCacheSyncInterval is how often GameServers are pointed at the
+	// cache, or away from it once it is removed
+	steamCacheSyncInterval = time.Minute
+
+	// steamCacheStatsLines is how many recent requests hit rates cover
+	steamCacheStatsLines = 1000
+
+	// Environment variables pointing game images at the cache
+	envSteamCache     = "GAMEPLANE_STEAM_CACHE"
+	envSteamCacheHost = "GAMEPLANE_STEAM_CACHE_HOST"
+)
+
+// SteamCacheConfig configures the cluster-local Steam download cache
+type SteamCacheConfig struct {
+	Image string `json:"image,omitempty"`
+	// StorageSize is the size of the cache volume; it can grow but not
+	// shrink
+	StorageSize  string `json:"storageSize,omitempty"`
+	StorageClass string `json:"storageClass,omitempty"`
+	CPU          string `json:"cpu,omitempty"`
+	Memory       string `json:"memory,omitempty"`
+}
+
+// SteamCacheStatus reports the Steam cache and the GameServers using it
+type SteamCacheStatus struct {
+	Enabled bool              `json:"enabled"`
+	Config  *SteamCacheConfig `json:"config,omitempty"`
+	// URL is the address game images download Steam content through
+	URL      string             `json:"url,omitempty"`
+	Ready    bool               `json:"ready"`
+	Restarts int                `json:"restarts"`
+	Storage  *SteamCacheStorage `json:"storage,omitempty"`
+	Traffic  *SteamCacheTraffic `json:"traffic,omitempty"`
+	Servers  SteamCacheServers  `json:"servers"`
+}
+
+// SteamCacheStorage is the state of the cache volume
+type SteamCacheStorage struct {
+	Phase     string `json:"phase"`
+	Requested string `json:"requested"`
+	Capacity  string `json:"capacity,omitempty"`
+}
+
+// SteamCacheTraffic summarizes the cache's most recent requests
+type SteamCacheTraffic struct {
+	Requests int `json:"requests"`
+	Hits     int `json:"hits"`
+	Misses   int `json:"misses"`
+	// HitRatio is the share of bytes served from the cache
+	HitRatio  float64 `json:"hitRatio"`
+	BytesHit  int64   `json:"bytesHit"`
+	BytesMiss int64   `json:"bytesMiss"`
+}
+
+// SteamCacheServers counts the GameServers of Steam games and how many are
+// pointed at the cache. Pending servers are still to be pointed at the
+// cache, or away from a removed one, which waits until they are empty or
+// stopped.
+type SteamCacheServers struct {
+	Steam   int      `json:"steam"`
+	Using   int      `json:"using"`
+	Pending []string `json:"pending"`
+}
+
+// validate checks the settings and fills in defaults
+func (c *SteamCacheConfig) validate() error {
+	if c.Image == "" {
+		c.Image = defaultSteamCacheImage
+	}
+	if c.StorageSize == "" {
+		c.StorageSize = defaultSteamCacheStorageSize
+	}
+	if c.CPU == "" {
+		c.CPU = defaultSteamCacheCPU
+	}
+	if c.Memory == "" {
+		c.Memory = defaultSteamCacheMemory
+	}
+	if strings.ContainsAny(c.Image, " \t") {
+		return fmt.Errorf("invalid image %q", c.Image)
+	}
+	for name, value := range map[string]string{"storageSize": c.StorageSize, "cpu": c.CPU, "memory": c.Memory} {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil || quantity.Sign() <= 0 {
+			return fmt.Errorf("invalid %s %q", name, value)
+		}
+	}
+	if size := resource.MustParse(c.StorageSize); size.Cmp(resource.MustParse("10Gi")) < 0 {
+		return fmt.Errorf("storageSize must be at least 10Gi")
+	}
+	return nil
+}
+
+// steamCacheHost is the in-cluster name of the cache
+func (s *Server) steamCacheHost() string {
+	return fmt.Sprintf("%s.%s.svc.cluster.local", steamCacheName, s.clusters.namespace)
+}
+
+// isSteamCacheEnv reports whether an environment variable is managed by
+// the Steam cache
+func isSteamCacheEnv(name string) bool {
+	return name == envSteamCache || name == envSteamCacheHost
+}
+
+// steamCacheEnv returns the environment pointing game images at the cache,
+// or nil while no cache is deployed
+func (s *Server) steamCacheEnv(ctx context.Context) (map[string]string, error) {
+	_, err := s.kubeClient.AppsV1().Deployments(s.clusters.namespace).Get(ctx, steamCacheName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	host := s.steamCacheHost()
+	return map[string]string{
+		envSteamCache:     "http://" + host,
+		envSteamCacheHost: host,
+	}, nil
+}
+
+// withSteamCacheEnv adds the Steam cache environment to a new claim spec,
+// so servers install through the cache from their first start instead of
+// being restarted by the sync
+func withSteamCacheEnv(spec map[string]interface{}, env map[string]string) {
+	if len(env) == 0 {
+		return
+	}
+	advanced, _ := spec["advanced"].(map[string]interface{})
+	if advanced == nil {
+		advanced = map[string]interface{}{}
+	}
+	merged := map[string]interface{}{}
+	switch current := advanced["customEnvVars"].(type) {
+	case map[string]string:
+		for key, value := range current {
+			merged[key] = value
+		}
+	case map[string]interface{}:
+		for key, value := range current {
+			merged[key] = value
+		}
+	}
+	for key, value := range env {
+		merged[key] = value
+	}
+	advanced["customEnvVars"] = merged
+	spec["advanced"] = advanced
+}
+
+// getSteamCache reports the Steam cache, its hit rate and the servers
+// using it. Only admins may see it.
+func (s *Server) getSteamCache(c *gin.Context) {
+	if !s.isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Only admins may see the Steam cache",
+		})
+		return
+	}
+	status, err := s.steamCacheStatus(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to read Steam cache: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// putSteamCache deploys the Steam cache or changes its settings. Servers
+// of Steam games are pointed at it by the sync.
+func (s *Server) putSteamCache(c *gin.Context) {
+	var config SteamCacheConfig
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&config); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid request body: %v", err),
+			})
+			return
+		}
+	}
+	if err := config.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	ctx := context.TODO()
+	if status, err := s.applySteamCacheClaim(ctx, config); err != nil {
+		if status == 0 {
+			status = errorStatus(c, err)
+		}
+		c.JSON(status, gin.H{
+			"error": fmt.Sprintf("Failed to provision Steam cache storage: %v", err),
+		})
+		return
+	}
+	if err := s.applySteamCacheService(ctx); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to create Steam cache Service: %v", err),
+		})
+		return
+	}
+	if err := s.applySteamCacheDeployment(ctx, config); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to deploy Steam cache: %v", err),
+		})
+		return
+	}
+
+	status, err := s.steamCacheStatus(ctx)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to read Steam cache: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// deleteSteamCache removes the Steam cache. Its volume is kept for a later
+// redeploy unless ?purge=true. Servers are pointed away from it by the sync.
+func (s *Server) deleteSteamCache(c *gin.Context) {
+	ctx := context.TODO()
+	namespace := s.clusters.namespace
+	err := s.kubeClient.AppsV1().Deployments(namespace).Delete(ctx, steamCacheName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Steam cache is not deployed",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete Steam cache: %v", err),
+		})
+		return
+	}
+	if err := s.kubeClient.CoreV1().Services(namespace).Delete(ctx, steamCacheName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete Steam cache Service: %v", err),
+		})
+		return
+	}
+	message := "Steam cache removed, its volume is kept"
+	if c.Query("purge") == "true" {
+		if err := s.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, steamCacheName, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to delete Steam cache volume: %v", err),
+			})
+			return
+		}
+		message = "Steam cache and its volume removed"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+	})
+}
+
+// applySteamCacheClaim creates the cache volume or grows it. A non-zero
+// status is returned for settings the existing volume cannot take.
+func (s *Server) applySteamCacheClaim(ctx context.Context, config SteamCacheConfig) (int, error) {
+	claims := s.kubeClient.CoreV1().PersistentVolumeClaims(s.clusters.namespace)
+	size := resource.MustParse(config.StorageSize)
+	claim, err := claims.Get(ctx, steamCacheName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		claim = &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      steamCacheName,
+				Namespace: s.clusters.namespace,
+				Labels:    steamCacheLabels(),
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: size},
+				},
+			},
+		}
+		if config.StorageClass != "" {
+			claim.Spec.StorageClassName = &config.StorageClass
+		}
+		_, err = claims.Create(ctx, claim, metav1.CreateOptions{})
+		return 0, err
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if config.StorageClass != "" && (claim.Spec.StorageClassName == nil || *claim.Spec.StorageClassName != config.StorageClass) {
+		return http.StatusConflict, fmt.Errorf("the volume exists with another storage class; delete the cache with ?purge=true first")
+	}
+	current := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+	switch size.Cmp(current) {
+	case -1:
+		return http.StatusConflict, fmt.Errorf("the volume cannot shrink below %s", current.String())
+	case 1:
+		if claim.Spec.Resources.Requests == nil {
+			claim.Spec.Resources.Requests = corev1.ResourceList{}
+		}
+		claim.Spec.Resources.Requests[corev1.ResourceStorage] = size
+		_, err = claims.Update(ctx, claim, metav1.UpdateOptions{})
+		return 0, err
+	}
+	return 0, nil
+}
+
+// applySteamCacheService creates the Service game pods reach the cache by
+func (s *Server) applySteamCacheService(ctx context.Context) error {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      steamCacheName,
+			Namespace: s.clusters.namespace,
+			Labels:    steamCacheLabels(),
+		},
+		Spec: corev1.ServiceSpec{
+			Selector: steamCacheLabels(),
+			Ports: []corev1.ServicePort{{
+				Name:       "http",
+				Port:       80,
+				TargetPort: intstr.FromString("http"),
+				Protocol:   corev1.ProtocolTCP,
+			}},
+		},
+	}
+	_, err := s.kubeClient.CoreV1().Services(s.clusters.namespace).Create(ctx, service, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		return nil
+	}
+	return err
+}
+
+// applySteamCacheDeployment creates or updates the cache Deployment
+func (s *Server) applySteamCacheDeployment(ctx context.Context, config SteamCacheConfig) error {
+	deployments := s.kubeClient.AppsV1().Deployments(s.clusters.namespace)
+	desired := steamCacheDeployment(s.clusters.namespace, config)
+	existing, err := deployments.Get(ctx, steamCacheName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = deployments.Create(ctx, desired, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	existing.Labels = desired.Labels
+	existing.Annotations = desired.Annotations
+	existing.Spec.Template = desired.Spec.Template
+	_, err = deployments.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// steamCacheStatus reads the cache's Deployment, volume, recent traffic and
+// the servers pointed at it
+func (s *Server) steamCacheStatus(ctx context.Context) (*SteamCacheStatus, error) {
+	namespace := s.clusters.namespace
+	status := &SteamCacheStatus{Servers: SteamCacheServers{Pending: []string{}}}
+	deployment, err := s.kubeClient.AppsV1().Deployments(namespace).Get(ctx, steamCacheName, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+	case err != nil:
+		return nil, err
+	default:
+		status.Enabled = true
+		status.URL = "http://" + s.steamCacheHost()
+		status.Ready = deployment.Status.ReadyReplicas > 0
+		config := &SteamCacheConfig{}
+		if json.Unmarshal([]byte(deployment.Annotations[steamCacheConfigAnnotation]), config) == nil {
+			status.Config = config
+		}
+	}
+
+	claim, err := s.kubeClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, steamCacheName, metav1.GetOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if err == nil {
+		requested := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+		status.Storage = &SteamCacheStorage{
+			Phase:     string(claim.Status.Phase),
+			Requested: requested.String(),
+		}
+		if capacity, ok := claim.Status.Capacity[corev1.ResourceStorage]; ok {
+			status.Storage.Capacity = capacity.String()
+		}
+	}
+
+	if status.Enabled {
+		pods, err := s.kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: labels.Set(steamCacheLabels()).String(),
+		})
+		if err != nil {
+			return nil, err
+		}
+		for i := range pods.Items {
+			pod := &pods.Items[i]
+			for _, container := range pod.Status.ContainerStatuses {
+				status.Restarts += int(container.RestartCount)
+			}
+			if pod.Status.Phase == corev1.PodRunning && status.Traffic == nil {
+				// Traffic is best-effort, the log may be rotated or empty
+				if traffic, err := s.steamCacheTraffic(ctx, pod.Name); err == nil {
+					status.Traffic = traffic
+				}
+			}
+		}
+	}
+
+	list, err := s.listAllGameServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+		if def, ok := lookupGame(gameType); !ok || def.SteamAppID == 0 {
+			continue
+		}
+		status.Servers.Steam++
+		env, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "advanced", "customEnvVars")
+		_, using := env[envSteamCache]
+		if using {
+			status.Servers.Using++
+		}
+		if using != status.Enabled {
+			status.Servers.Pending = append(status.Servers.Pending, obj.GetNamespace()+"/"+obj.GetName())
+		}
+	}
+	sort.Strings(status.Servers.Pending)
+	return status, nil
+}
+
+// steamCacheTraffic tallies the most recent Steam requests in the access
+// log the sidecar prints. Lines are in lancache's cachelog format, whose
+// fourth quoted field is the cache status.
+func (s *Server) steamCacheTraffic(ctx context.Context, pod string) (*SteamCacheTraffic, error) {
+	lines := int64(steamCacheStatsLines)
+	stream, err := s.kubeClient.CoreV1().Pods(s.clusters.namespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container: "access-log",
+		TailLines: &lines,
+	}).Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	traffic := &SteamCacheTraffic{}
+	scanner := bufio.NewScanner(stream)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "[steam]") {
+			continue
+		}
+		fields := strings.Split(line, `"`)
+		if len(fields) < 8 {
+			continue
+		}
+		var bytes int64
+		if response := strings.Fields(fields[2]); len(response) >= 2 {
+			bytes, _ = strconv.ParseInt(response[1], 10, 64)
+		}
+		traffic.Requests++
+		switch fields[7] {
+		case "HIT":
+			traffic.Hits++
+			traffic.BytesHit += bytes
+		case "MISS":
+			traffic.Misses++
+			traffic.BytesMiss += bytes
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if total := traffic.BytesHit + traffic.BytesMiss; total > 0 {
+		traffic.HitRatio = float64(traffic.BytesHit) / float64(total)
+	}
+	return traffic, nil
+}
+
+// syncSteamCache points GameServers of Steam games at the cache while it is
+// deployed and away from it once it is removed. Changing the environment
+// restarts the game, so servers with players online wait until they are
+// empty or stopped.
+func (s *Server) syncSteamCache(ctx context.Context) error {
+	env, err := s.steamCacheEnv(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read Steam cache: %w", err)
+	}
+	list, err := s.listAllGameServers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+		if def, ok := lookupGame(gameType); !ok || def.SteamAppID == 0 {
+			continue
+		}
+		stopped, _, _ := unstructured.NestedBool(obj.Object, "spec", "stopped")
+		if !stopped && gameServerPlayers(obj) > 0 {
+			continue
+		}
+		if err := s.syncManagedEnv(ctx, obj, isSteamCacheEnv, env); err != nil {
+			log.Printf("Failed to sync Steam cache environment of GameServer %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// steamCacheLabels select the cache's pods
+func steamCacheLabels() map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       steamCacheName,
+		"app.kubernetes.io/managed-by": "gameplane-api",
+	}
+}
+
+// steamCacheDeployment builds the cache Deployment: lancache caching Steam
+// content on the volume, and a sidecar printing its access log
+func steamCacheDeployment(namespace string, config SteamCacheConfig) *appsv1.Deployment {
+	raw, _ := json.Marshal(config)
+	replicas := int32(1)
+	// lancache needs headroom on the volume for its index and temp files
+	size := resource.MustParse(config.StorageSize)
+	cacheSize := size.Value() / 10 * 9 >> 30
+	if cacheSize < 1 {
+		cacheSize = 1
+	}
+	compute := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(config.CPU),
+		corev1.ResourceMemory: resource.MustParse(config.Memory),
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        steamCacheName,
+			Namespace:   namespace,
+			Labels:      steamCacheLabels(),
+			Annotations: map[string]string{steamCacheConfigAnnotation: string(raw)},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			// The volume can only be mounted by one pod
+			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+			Selector: &metav1.LabelSelector{MatchLabels: steamCacheLabels()},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: steamCacheLabels()},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "cache",
+							Image: config.Image,
+							Env: []corev1.EnvVar{
+								{Name: "CACHE_DISK_SIZE", Value: fmt.Sprintf("%dg", cacheSize)},
+							},
+							Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 80, Protocol: corev1.ProtocolTCP}},
+							Resources: corev1.ResourceRequirements{
+								Requests: compute,
+								Limits:   compute,
+							},
+							ReadinessProbe: &corev1.Probe{
+								ProbeHandler: corev1.ProbeHandler{
+									TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("http")},
+								},
+								PeriodSeconds: 10,
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "cache", MountPath: "/data/cache"},
+								{Name: "logs", MountPath: "/data/logs"},
+							},
+						},
+						{
+							Name:    "access-log",
+							Image:   steamCacheLogImage,
+							Command: []string{"tail", "-n", "0", "-F", "/data/logs/access.log"},
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse("5m"),
+									corev1.ResourceMemory: resource.MustParse("16Mi"),
+								},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "logs", MountPath: "/data/logs", ReadOnly: true},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "cache",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: steamCacheName},
+							},
+						},
+						{
+							Name:         "logs",
+							VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,378 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+	"github.com/kubelize/gameplane/api/internal/metrics"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// playerSessionSecret holds the key player and session tokens are signed
+	// with, in the cluster registry namespace of the local cluster, so every
+	// API replica accepts the same tokens
+	playerSessionSecret = "gameplane-player-sessions"
+
+	// playerSessionKeyName is the key of the signing key in the Secret
+	playerSessionKeyName = "signing-key"
+
+	// playerCookie carries the player token of a browser session
+	playerCookie = "gameplane_player"
+
+	// defaultPlayerSessionTTL is how long a player stays logged in
+	defaultPlayerSessionTTL = 24 * time.Hour
+
+	// playerRole is the role of Steam logins; it reads servers and may only
+	// change the player's own whitelist claims
+	playerRole = "player"
+
+	// steamOpenIDNamespace is the OpenID 2.0 protocol Steam speaks
+	steamOpenIDNamespace = "http://specs.openid.net/auth/2.0"
+)
+
+// playerKey is the gin context key holding the logged-in player
+const playerKey = "gameplane.player"
+
+// steamClaimedIDPattern extracts the SteamID64 from Steam's claimed ID
+var steamClaimedIDPattern = regexp.MustCompile(`^https://steamcommunity\.com/openid/id/(7656119[0-9]{10})$`)
+
+// steamLoginSettings configures Steam login. It is read from PUBLIC_URL,
+// the address players reach the API at, and PLAYER_SESSION_TTL; Steam login
+// is disabled without STEAM_LOGIN_ENABLED=true.
+type steamLoginSettings struct {
+	PublicURL  string
+	SessionTTL time.Duration
+}
+
+// loadSteamLoginSettings reads Steam login settings from the environment
+func loadSteamLoginSettings() (steamLoginSettings, bool) {
+	settings := steamLoginSettings{
+		PublicURL:  strings.TrimRight(os.Getenv("PUBLIC_URL"), "/"),
+		SessionTTL: defaultPlayerSessionTTL,
+	}
+	if d, err := time.ParseDuration(os.Getenv("PLAYER_SESSION_TTL")); err == nil && d > 0 {
+		settings.SessionTTL = d
+	}
+	enabled := os.Getenv("STEAM_LOGIN_ENABLED") == "true" && strings.HasPrefix(settings.PublicURL, "http")
+	return settings, enabled
+}
+
+// PlayerClaims identify a player logged in with Steam
+type PlayerClaims struct {
+	Subject string `json:"sub"`
+	// SessionID lets the player log the token out before it expires
+	SessionID string    `json:"sid,omitempty"`
+	SteamID   string    `json:"steamId"`
+	Role      string    `json:"role"`
+	IssuedAt  time.Time `json:"iat"`
+	ExpiresAt time.Time `json:"exp"`
+}
+
+// playerSessionKey caches the token signing key once it was read
+type playerSessionKey struct {
+	mu  sync.Mutex
+	key []byte
+}
+
+// steamLogin redirects the browser to Steam to log in. ?returnTo= is a
+// path on this site to come back to afterwards.
+func (s *Server) steamLogin(c *gin.Context) {
+	settings, ok := loadSteamLoginSettings()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Steam login is not enabled",
+		})
+		return
+	}
+	returnTo := url.Values{"returnTo": {safeReturnPath(c.Query("returnTo"))}}
+	params := url.Values{
+		"openid.ns":         {steamOpenIDNamespace},
+		"openid.mode":       {"checkid_setup"},
+		"openid.return_to":  {s.steamCallbackURL(c, settings) + "?" + returnTo.Encode()},
+		"openid.realm":      {settings.PublicURL},
+		"openid.identity":   {steamOpenIDNamespace + "/identifier_select"},
+		"openid.claimed_id": {steamOpenIDNamespace + "/identifier_select"},
+	}
+	c.Redirect(http.StatusFound, steamCommunityURL+"/openid/login?"+params.Encode())
+}
+
+// steamCallback completes a Steam login: Steam vouches for the returned
+// assertion, and the player gets a session cookie
+func (s *Server) steamCallback(c *gin.Context) {
+	settings, ok := loadSteamLoginSettings()
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Steam login is not enabled",
+		})
+		return
+	}
+	query := c.Request.URL.Query()
+	returnTo := strings.SplitN(query.Get("openid.return_to"), "?", 2)[0]
+	if query.Get("openid.mode") != "id_res" || returnTo != s.steamCallbackURL(c, settings) {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Steam login was cancelled or is invalid",
+		})
+		return
+	}
+	match := steamClaimedIDPattern.FindStringSubmatch(query.Get("openid.claimed_id"))
+	if match == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Steam login returned an unexpected identity",
+		})
+		return
+	}
+	if err := verifySteamAssertion(c.Request.Context(), query); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": fmt.Sprintf("Steam did not confirm the login: %v", err),
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	claims := PlayerClaims{
+		Subject:   "steam:" + match[1],
+		SessionID: newSessionID(),
+		SteamID:   match[1],
+		Role:      playerRole,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(settings.SessionTTL),
+	}
+	err := s.recordSession(c.Request.Context(), Session{
+		ID:        claims.SessionID,
+		Subject:   claims.Subject,
+		Kind:      sessionKindPlayer,
+		CreatedAt: now,
+		ExpiresAt: claims.ExpiresAt,
+		UserAgent: c.Request.UserAgent(),
+	})
+	token := ""
+	if err == nil {
+		token, err = s.signPlayerToken(c.Request.Context(), claims)
+	}
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to start session: %v", err),
+		})
+		return
+	}
+	log.Printf("Player %s logged in with Steam", claims.Subject)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(playerCookie, token, int(settings.SessionTTL.Seconds()), s.basePath+"/", "", strings.HasPrefix(settings.PublicURL, "https://"), true)
+	c.Redirect(http.StatusFound, s.basePath+safeReturnPath(query.Get("returnTo")))
+}
+
+// requirePlayer rejects requests without a valid player token, taken from
+// the session cookie or an Authorization: Bearer header
+func (s *Server) requirePlayer(c *gin.Context) {
+	token := bearerToken(c.Request)
+	if token == "" {
+		token, _ = c.Cookie(playerCookie)
+	}
+	claims, err := s.verifyPlayerToken(c.Request.Context(), token)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Log in with Steam to continue",
+		})
+		return
+	}
+	c.Set(playerKey, claims)
+	c.Set(handlers.SubjectKey, claims.Subject)
+	c.Next()
+}
+
+// currentPlayer returns the claims requirePlayer stored
+func currentPlayer(c *gin.Context) PlayerClaims {
+	claims, _ := c.MustGet(playerKey).(PlayerClaims)
+	return claims
+}
+
+// steamCallbackURL is the address Steam sends players back to
+func (s *Server) steamCallbackURL(c *gin.Context, settings steamLoginSettings) string {
+	version := "/api/v1"
+	if strings.HasPrefix(c.FullPath(), s.basePath+"/api/v2/") {
+		version = "/api/v2"
+	}
+	return settings.PublicURL + s.basePath + version + "/auth/steam/callback"
+}
+
+// verifySteamAssertion asks Steam whether it issued the assertion, which
+// also keeps the assertion from being used twice
+func verifySteamAssertion(ctx context.Context, query url.Values) error {
+	form := url.Values{}
+	for key, values := range query {
+		if strings.HasPrefix(key, "openid.") {
+			form[key] = values
+		}
+	}
+	form.Set("openid.mode", "check_authentication")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, steamCommunityURL+"/openid/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", "gameplane-api")
+
+	start := time.Now()
+	resp, err := whitelistClient.Do(req)
+	metrics.ObserveDependency(metrics.DependencyWebhook, "steam-openid", start, err != nil || resp.StatusCode >= 300)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("steam returned %s", resp.Status)
+	}
+	for _, line := range strings.Split(string(body), "\n") {
+		if strings.TrimSpace(line) == "is_valid:true" {
+			return nil
+		}
+	}
+	return fmt.Errorf("assertion is not valid")
+}
+
+// signPlayerToken encodes claims as a signed token
+func (s *Server) signPlayerToken(ctx context.Context, claims PlayerClaims) (string, error) {
+	return s.signToken(ctx, claims)
+}
+
+// verifyPlayerToken checks a token's signature and expiry
+func (s *Server) verifyPlayerToken(ctx context.Context, token string) (PlayerClaims, error) {
+	var claims PlayerClaims
+	if err := s.verifyToken(ctx, token, &claims); err != nil {
+		return claims, err
+	}
+	if claims.Role != playerRole || !time.Now().Before(claims.ExpiresAt) {
+		return claims, fmt.Errorf("token expired")
+	}
+	if claims.SessionID != "" && s.sessionRevoked(ctx, claims.SessionID) {
+		return claims, fmt.Errorf("session was logged out")
+	}
+	return claims, nil
+}
+
+// signToken encodes claims as base64url JSON followed by its HMAC
+func (s *Server) signToken(ctx context.Context, claims interface{}) (string, error) {
+	key, err := s.playerSigningKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	raw, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(raw)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// verifyToken checks a token's signature and decodes its claims; callers
+// check what the claims are for and their expiry
+func (s *Server) verifyToken(ctx context.Context, token string, claims interface{}) error {
+	payload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return fmt.Errorf("malformed token")
+	}
+	given, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("malformed token")
+	}
+	key, err := s.playerSigningKey(ctx)
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	if !hmac.Equal(given, mac.Sum(nil)) {
+		return fmt.Errorf("invalid signature")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return fmt.Errorf("malformed token")
+	}
+	if err := json.Unmarshal(raw, claims); err != nil {
+		return fmt.Errorf("malformed token")
+	}
+	return nil
+}
+
+// playerSigningKey returns the token signing key, creating the Secret
+// holding it on first use
+func (s *Server) playerSigningKey(ctx context.Context) ([]byte, error) {
+	s.playerSessions.mu.Lock()
+	defer s.playerSessions.mu.Unlock()
+	if s.playerSessions.key != nil {
+		return s.playerSessions.key, nil
+	}
+
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return nil, err
+	}
+	secrets := store.kubeClient.CoreV1().Secrets(s.clusters.namespace)
+	secret, err := secrets.Get(ctx, playerSessionSecret, metav1.GetOptions{})
+	if err == nil && len(secret.Data[playerSessionKeyName]) > 0 {
+		s.playerSessions.key = secret.Data[playerSessionKeyName]
+		return s.playerSessions.key, nil
+	}
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	_, err = secrets.Create(ctx, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      playerSessionSecret,
+			Namespace: s.clusters.namespace,
+			Labels:    map[string]string{"app.kubernetes.io/managed-by": "gameplane-api"},
+		},
+		Data: map[string][]byte{playerSessionKeyName: key},
+	}, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		// Another replica created it first; use its key
+		secret, err = secrets.Get(ctx, playerSessionSecret, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		key = secret.Data[playerSessionKeyName]
+	} else if err != nil {
+		return nil, err
+	}
+	s.playerSessions.key = key
+	return key, nil
+}
+
+// safeReturnPath keeps post-login redirects on this site
+func safeReturnPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.Contains(path, `\`) {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,319 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/pkg/storage"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// storageConfigMap configures off-cluster storage. The one in the
+	// cluster registry namespace of the local cluster applies to the whole
+	// installation; one in a GameServer namespace overrides it there.
+	storageConfigMap = "gameplane-storage"
+
+	// storageKey is the ConfigMap key of the StorageConfig
+	storageKey = "storage.json"
+
+	// storageHealthTimeout bounds the probe of GET /storage/health
+	storageHealthTimeout = 30 * time.Second
+
+	// worldExportTimeout bounds a whole world export
+	worldExportTimeout = 2 * time.Hour
+
+	// worldExportPrefix is where world exports are stored, followed by
+	// namespace/name/
+	worldExportPrefix = "exports/"
+)
+
+// World export steps, in order
+const (
+	exportStepStart  = "start-transfer-pod"
+	exportStepUpload = "upload"
+)
+
+// Where a storage configuration came from
+const (
+	storageSourceNamespace = "namespace"
+	storageSourceGlobal    = "global"
+)
+
+// StorageConfig is the storage.json of a gameplane-storage ConfigMap
+type StorageConfig struct {
+	storage.Config
+	// CredentialsSecret names a Secret in the ConfigMap's namespace holding
+	// the keys read by storage.CredentialsFromSecret
+	CredentialsSecret string `json:"credentialsSecret,omitempty"`
+}
+
+// StorageHealth is the result of probing the storage of a namespace
+type StorageHealth struct {
+	Status    string `json:"status"`
+	Source    string `json:"source,omitempty"`
+	Backend   string `json:"backend,omitempty"`
+	LatencyMs int64  `json:"latencyMs,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// WorldExport is a world archive written to storage
+type WorldExport struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+}
+
+// storageFor returns the backend GameServers in namespace store archives in
+// and whether it is the namespace's own or the installation's. The backend
+// is nil when neither is configured.
+func (s *Server) storageFor(ctx context.Context, namespace string) (storage.Backend, string, error) {
+	if namespace != "" {
+		backend, found, err := loadStorage(ctx, s.kubeClient, namespace)
+		if err != nil || found {
+			return backend, storageSourceNamespace, err
+		}
+	}
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return nil, "", err
+	}
+	backend, found, err := loadStorage(ctx, store.kubeClient, s.clusters.namespace)
+	if err != nil || !found {
+		return nil, "", err
+	}
+	return backend, storageSourceGlobal, nil
+}
+
+// loadStorage builds the backend configured by the gameplane-storage
+// ConfigMap of a namespace, if there is one
+func loadStorage(ctx context.Context, kube kubernetes.Interface, namespace string) (storage.Backend, bool, error) {
+	cm, err := kube.CoreV1().ConfigMaps(namespace).Get(ctx, storageConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read storage config: %w", err)
+	}
+	var cfg StorageConfig
+	if err := json.Unmarshal([]byte(cm.Data[storageKey]), &cfg); err != nil {
+		return nil, true, fmt.Errorf("invalid %s in ConfigMap %s/%s: %w", storageKey, namespace, storageConfigMap, err)
+	}
+	var creds storage.Credentials
+	if cfg.CredentialsSecret != "" {
+		secret, err := kube.CoreV1().Secrets(namespace).Get(ctx, cfg.CredentialsSecret, metav1.GetOptions{})
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to read storage credentials %s/%s: %w", namespace, cfg.CredentialsSecret, err)
+		}
+		creds = storage.CredentialsFromSecret(secret.Data)
+	}
+	backend, err := storage.New(cfg.Config, creds)
+	if err != nil {
+		return nil, true, fmt.Errorf("invalid storage config in %s/%s: %w", namespace, storageConfigMap, err)
+	}
+	return backend, true, nil
+}
+
+// getStorageHealth writes, reads back and deletes a probe object in the
+// storage of ?namespace=, or of the installation without one
+func (s *Server) getStorageHealth(c *gin.Context) {
+	namespace := c.Query("namespace")
+	if namespace != "" {
+		if scope, err := s.readableNamespaces(c); err != nil || !scope.allows(namespace) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": fmt.Sprintf("Not permitted to access namespace %s", namespace),
+			})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), storageHealthTimeout)
+	defer cancel()
+	backend, source, err := s.storageFor(ctx, namespace)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, StorageHealth{Status: "error", Error: err.Error()})
+		return
+	}
+	if backend == nil {
+		c.JSON(http.StatusOK, StorageHealth{Status: "unconfigured"})
+		return
+	}
+
+	health := StorageHealth{Status: "ok", Source: source, Backend: backend.String()}
+	start := time.Now()
+	err = storage.Check(ctx, backend)
+	health.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		health.Status = "error"
+		health.Error = err.Error()
+		c.JSON(http.StatusServiceUnavailable, health)
+		return
+	}
+	c.JSON(http.StatusOK, health)
+}
+
+// exportWorld archives a GameServer's data to storage as an async
+// operation and returns the operation to poll
+func (s *Server) exportWorld(c *gin.Context) {
+	obj, err := s.getGameServerObject(context.TODO(), c.Param("namespace"), c.Param("name"))
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+	if _, err := managedNamespace(obj); err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	backend, _, err := s.storageFor(context.TODO(), obj.GetNamespace())
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": fmt.Sprintf("Failed to load storage: %v", err),
+		})
+		return
+	}
+	if backend == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("No storage is configured for namespace %s", obj.GetNamespace()),
+		})
+		return
+	}
+
+	op := s.startOperation("export", obj.GetNamespace(), obj.GetName(), []string{exportStepStart, exportStepUpload}, worldExportTimeout, func(ctx context.Context, t *operationTracker) (interface{}, error) {
+		return s.runWorldExport(ctx, t, obj, backend)
+	})
+	c.JSON(http.StatusAccepted, op)
+}
+
+// runWorldExport streams the data volume as a gzipped tar from a read-only
+// transfer pod into storage
+func (s *Server) runWorldExport(ctx context.Context, t *operationTracker, obj *unstructured.Unstructured, backend storage.Backend) (interface{}, error) {
+	namespace, _ := managedNamespace(obj)
+	key := fmt.Sprintf("%s%s/%s/%s.tar.gz", worldExportPrefix, obj.GetNamespace(), obj.GetName(), time.Now().UTC().Format("20060102T150405Z"))
+	result := gin.H{"key": key, "storage": backend.String()}
+
+	var pod string
+	var stop func()
+	err := t.step(exportStepStart, func() (string, error) {
+		var err error
+		pod, stop, err = s.startVolumeServer(ctx, obj, volumeTask{
+			Name:     "export",
+			Files:    map[string][]byte{"download": []byte(migrationDownloadScript)},
+			Script:   migrationServeScript,
+			ReadOnly: true,
+			Port:     migrationPort,
+		})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Transfer pod %s is ready", pod), nil
+	})
+	if err != nil {
+		return result, err
+	}
+	defer stop()
+
+	err = t.step(exportStepUpload, func() (string, error) {
+		stream, err := migrationProxy(s, http.MethodGet, namespace, pod, "download").Stream(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to read data: %w", err)
+		}
+		defer stream.Close()
+		counted := &countingReader{Reader: stream}
+		if err := backend.Put(ctx, key, counted, -1); err != nil {
+			return "", fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+		result["size"] = counted.n
+		return fmt.Sprintf("Uploaded %d bytes to %s", counted.n, key), nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	s.notifySubscribers(ctx, notificationEvent{
+		Type:      "backup",
+		Namespace: obj.GetNamespace(),
+		Server:    obj.GetName(),
+		Title:     "World exported",
+		Message:   fmt.Sprintf("World exported to %s", key),
+		Fields:    map[string]string{"Key": key, "Storage": backend.String(), "Reason": "export"},
+	})
+	return result, nil
+}
+
+// listWorldExports lists the world exports of a GameServer, newest first
+func (s *Server) listWorldExports(c *gin.Context) {
+	namespace, name := c.Param("namespace"), c.Param("name")
+	if _, err := s.getGameServerObject(context.TODO(), namespace, name); err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+	backend, _, err := s.storageFor(context.TODO(), namespace)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": fmt.Sprintf("Failed to load storage: %v", err),
+		})
+		return
+	}
+	exports := []WorldExport{}
+	if backend == nil {
+		c.JSON(http.StatusOK, gin.H{"exports": exports})
+		return
+	}
+
+	objects, err := backend.List(context.TODO(), fmt.Sprintf("%s%s/%s/", worldExportPrefix, namespace, name))
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": fmt.Sprintf("Failed to list exports: %v", err),
+		})
+		return
+	}
+	for _, object := range objects {
+		if strings.HasSuffix(object.Key, ".tar.gz") {
+			exports = append(exports, WorldExport{Key: object.Key, Size: object.Size, Created: object.Modified})
+		}
+	}
+	// Keys end in a sortable timestamp
+	sort.Slice(exports, func(i, j int) bool { return exports[i].Key > exports[j].Key })
+	c.JSON(http.StatusOK, gin.H{"storage": backend.String(), "exports": exports})
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// defaultStorageClassAnnotation marks the cluster's default StorageClass
+	defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+	// defaultSnapshotClassAnnotation marks the default VolumeSnapshotClass
+	defaultSnapshotClassAnnotation = "snapshot.storage.kubernetes.io/is-default-class"
+)
+
+// StorageClassSummary is a StorageClass volumes can be provisioned from
+type StorageClassSummary struct {
+	Name              string `json:"name"`
+	Provisioner       string `json:"provisioner"`
+	Default           bool   `json:"default"`
+	ReclaimPolicy     string `json:"reclaimPolicy,omitempty"`
+	VolumeBindingMode string `json:"volumeBindingMode,omitempty"`
+	// Expandable volumes can be grown in place when resizing a server
+	Expandable bool `json:"expandable"`
+	// Snapshots are possible when a VolumeSnapshotClass uses the same
+	// driver, which backups need
+	Snapshots bool `json:"snapshots"`
+}
+
+// SnapshotClassSummary is a VolumeSnapshotClass backups can use
+type SnapshotClassSummary struct {
+	Name    string `json:"name"`
+	Driver  string `json:"driver"`
+	Default bool   `json:"default"`
+}
+
+// listSnapshotClasses lists the cluster's VolumeSnapshotClasses; none
+// when the snapshot API is not installed
+func (s *Server) listSnapshotClasses(ctx context.Context) ([]SnapshotClassSummary, error) {
+	classes, err := s.listPlatform(ctx, schema.GroupVersionKind{Group: "snapshot.storage.k8s.io", Version: "v1", Kind: "VolumeSnapshotClassList"})
+	if err != nil {
+		return nil, err
+	}
+	summaries := []SnapshotClassSummary{}
+	for _, class := range classes {
+		driver, _, _ := unstructured.NestedString(class.Object, "driver")
+		summaries = append(summaries, SnapshotClassSummary{
+			Name:    class.GetName(),
+			Driver:  driver,
+			Default: class.GetAnnotations()[defaultSnapshotClassAnnotation] == "true",
+		})
+	}
+	return summaries, nil
+}
+
+// listStorageClasses lists the cluster's StorageClasses, sorted by name,
+// with what volumes of each support
+func (s *Server) listStorageClasses(ctx context.Context, snapshotClasses []SnapshotClassSummary) ([]StorageClassSummary, error) {
+	classes, err := s.kubeClient.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	drivers := map[string]bool{}
+	for _, class := range snapshotClasses {
+		drivers[class.Driver] = true
+	}
+	summaries := []StorageClassSummary{}
+	for _, class := range classes.Items {
+		summary := StorageClassSummary{
+			Name:        class.Name,
+			Provisioner: class.Provisioner,
+			Default:     class.Annotations[defaultStorageClassAnnotation] == "true",
+			Expandable:  class.AllowVolumeExpansion != nil && *class.AllowVolumeExpansion,
+			Snapshots:   drivers[class.Provisioner],
+		}
+		if class.ReclaimPolicy != nil {
+			summary.ReclaimPolicy = string(*class.ReclaimPolicy)
+		}
+		if class.VolumeBindingMode != nil {
+			summary.VolumeBindingMode = string(*class.VolumeBindingMode)
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	return summaries, nil
+}
+
+// getStorageClasses lists the StorageClasses GameServer volumes can use
+func (s *Server) getStorageClasses(c *gin.Context) {
+	snapshotClasses, err := s.listSnapshotClasses(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list VolumeSnapshotClasses: %v", err),
+		})
+		return
+	}
+	classes, err := s.listStorageClasses(context.TODO(), snapshotClasses)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to list StorageClasses: %v", err),
+		})
+		return
+	}
+	defaultClass := ""
+	for _, class := range classes {
+		if class.Default {
+			defaultClass = class.Name
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"storageClasses":  classes,
+		"default":         defaultClass,
+		"snapshotClasses": snapshotClasses,
+	})
+}
+
+// checkStorageClass verifies that a GameServer's volumes can be
+// provisioned: the class it names must exist, and without one the cluster
+// needs a default. Clusters without any StorageClass, which provision
+// volumes statically, and classes that cannot be listed are not checked.
+func (s *Server) checkStorageClass(ctx context.Context, name string) error {
+	classes, err := s.listStorageClasses(ctx, nil)
+	if apierrors.IsForbidden(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(classes) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(classes))
+	defaultClass := ""
+	for _, class := range classes {
+		if class.Name == name {
+			return nil
+		}
+		names = append(names, class.Name)
+		if class.Default {
+			defaultClass = class.Name
+		}
+	}
+	if name == "" {
+		if defaultClass != "" {
+			return nil
+		}
+		return fmt.Errorf("%w: the cluster has no default StorageClass; set spec.resources.storageClass to one of %s", errInvalidStorageClass, strings.Join(names, ", "))
+	}
+	available := strings.Join(names, ", ")
+	if defaultClass != "" {
+		available += fmt.Sprintf(" (default %s, used when storageClass is empty)", defaultClass)
+	}
+	return fmt.Errorf("%w: StorageClass %s does not exist; available: %s", errInvalidStorageClass, name, available)
+}
+
+// errInvalidStorageClass marks storage classes volumes cannot be
+// provisioned from
+var errInvalidStorageClass = errors.New("invalid spec.resources.storageClass")
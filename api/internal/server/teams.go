@@ -0,0 +1,471 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// teamsConfigMap holds the teams in the cluster registry namespace of
+	// the local cluster
+	teamsConfigMap = "gameplane-teams"
+
+	// teamsKey is the ConfigMap key of the team list
+	teamsKey = "teams.json"
+
+	// teamCacheTTL bounds how long other API replicas take to notice a
+	// membership or role change
+	teamCacheTTL = 5 * time.Second
+
+	// allNamespaces grants a team role in every namespace
+	allNamespaces = "*"
+)
+
+// Team roles, from least to most privileged. Viewers read a namespace,
+// operators change every GameServer in it and admins may also manage its
+// fleets and projects and decide its server requests; an admin role in
+// every namespace administers the installation.
+const (
+	roleViewer   = "viewer"
+	roleOperator = "operator"
+	roleAdmin    = "admin"
+)
+
+// teamRoles ranks the roles by privilege
+var teamRoles = []string{roleViewer, roleOperator, roleAdmin}
+
+// Team grants its members roles in namespaces
+type Team struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Members are Authenticator subjects
+	Members   []string   `json:"members"`
+	Roles     []TeamRole `json:"roles"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// TeamRole assigns a role in a namespace, or in every namespace for "*"
+type TeamRole struct {
+	Namespace string `json:"namespace"`
+	Role      string `json:"role"`
+}
+
+// validate checks a team before it is stored
+func (t Team) validate() error {
+	if errs := validation.IsDNS1123Label(t.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid name %q: %s", t.Name, strings.Join(errs, ", "))
+	}
+	for _, member := range t.Members {
+		if strings.TrimSpace(member) == "" {
+			return fmt.Errorf("members must not be empty")
+		}
+	}
+	for _, role := range t.Roles {
+		if role.Namespace != allNamespaces {
+			if errs := validation.IsDNS1123Label(role.Namespace); len(errs) > 0 {
+				return fmt.Errorf("invalid namespace %q: %s", role.Namespace, strings.Join(errs, ", "))
+			}
+		}
+		if !containsString(teamRoles, role.Role) {
+			return fmt.Errorf("unknown role %q (valid: %s)", role.Role, strings.Join(teamRoles, ", "))
+		}
+	}
+	return nil
+}
+
+// teamCache keeps the last read teams for authorization, which would
+// otherwise read the ConfigMap on every request
+type teamCache struct {
+	mu      sync.Mutex
+	teams   []Team
+	fetched time.Time
+}
+
+// currentTeams returns the cached teams, refreshing them when stale
+func (s *Server) currentTeams(ctx context.Context) ([]Team, error) {
+	s.teams.mu.Lock()
+	defer s.teams.mu.Unlock()
+	if time.Since(s.teams.fetched) < teamCacheTTL {
+		return s.teams.teams, nil
+	}
+	teams, _, err := s.loadTeams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.teams.teams = teams
+	s.teams.fetched = time.Now()
+	return teams, nil
+}
+
+// teamRole returns the highest role the caller's teams grant in namespace,
+// or "" without one. Unreadable teams grant nothing.
+func (s *Server) teamRole(c *gin.Context, namespace string) string {
+	subject := c.GetString(handlers.SubjectKey)
+	if subject == "" {
+		return ""
+	}
+	teams, err := s.currentTeams(c.Request.Context())
+	if err != nil {
+		log.Printf("Failed to read teams: %v", err)
+		return ""
+	}
+	held := ""
+	for _, team := range teams {
+		if !containsString(team.Members, subject) {
+			continue
+		}
+		for _, role := range team.Roles {
+			if (role.Namespace == allNamespaces || role.Namespace == namespace) && roleRank(role.Role) > roleRank(held) {
+				held = role.Role
+			}
+		}
+	}
+	return held
+}
+
+// hasRole reports whether the caller holds at least role in namespace,
+// which installation admins do everywhere
+func (s *Server) hasRole(c *gin.Context, namespace, role string) bool {
+	if s.isAdmin(c) {
+		return true
+	}
+	held := s.teamRole(c, namespace)
+	return held != "" && roleRank(held) >= roleRank(role)
+}
+
+// roleRank orders roles by privilege, -1 for none
+func roleRank(role string) int {
+	for i, name := range teamRoles {
+		if name == role {
+			return i
+		}
+	}
+	return -1
+}
+
+// teamNamespaces returns the namespaces the caller's teams grant any role
+// in, or all=true for a role in every namespace
+func (s *Server) teamNamespaces(c *gin.Context) (namespaces []string, all bool) {
+	subject := c.GetString(handlers.SubjectKey)
+	if subject == "" {
+		return nil, false
+	}
+	teams, err := s.currentTeams(c.Request.Context())
+	if err != nil {
+		log.Printf("Failed to read teams: %v", err)
+		return nil, false
+	}
+	for _, team := range teams {
+		if !containsString(team.Members, subject) {
+			continue
+		}
+		for _, role := range team.Roles {
+			if role.Namespace == allNamespaces {
+				return nil, true
+			}
+			namespaces = append(namespaces, role.Namespace)
+		}
+	}
+	return namespaces, false
+}
+
+// loadTeams reads all teams and the ConfigMap holding them, which is nil
+// when none were stored yet
+func (s *Server) loadTeams(ctx context.Context) ([]Team, *corev1.ConfigMap, error) {
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return nil, nil, err
+	}
+	cm, err := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace).Get(ctx, teamsConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return []Team{}, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read teams: %w", err)
+	}
+	teams := []Team{}
+	if raw := cm.Data[teamsKey]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &teams); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse teams: %w", err)
+		}
+	}
+	return teams, cm, nil
+}
+
+// saveTeams writes the team list back, failing on a concurrent change, and
+// drops this replica's cached teams
+func (s *Server) saveTeams(ctx context.Context, cm *corev1.ConfigMap, teams []Team) error {
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(teams)
+	if err != nil {
+		return err
+	}
+	configMaps := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace)
+	if cm == nil {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      teamsConfigMap,
+				Namespace: s.clusters.namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "gameplane-api"},
+			},
+			Data: map[string]string{teamsKey: string(raw)},
+		}, metav1.CreateOptions{})
+	} else {
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[teamsKey] = string(raw)
+		_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	}
+	if err == nil {
+		s.teams.mu.Lock()
+		s.teams.fetched = time.Time{}
+		s.teams.mu.Unlock()
+	}
+	return err
+}
+
+// findTeam returns the named team's index, or -1
+func findTeam(teams []Team, name string) int {
+	for i, team := range teams {
+		if team.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// listTeams returns every team, or with ?member=me the caller's
+func (s *Server) listTeams(c *gin.Context) {
+	teams, _, err := s.loadTeams(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	member := c.Query("member")
+	if member == "me" {
+		member = c.GetString(handlers.SubjectKey)
+	}
+	items := []Team{}
+	for _, team := range teams {
+		if c.Query("member") == "" || containsString(team.Members, member) {
+			items = append(items, team)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"total": len(items),
+		"roles": teamRoles,
+	})
+}
+
+// createTeam stores a new team
+func (s *Server) createTeam(c *gin.Context) {
+	var req Team
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	teams, cm, err := s.loadTeams(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if findTeam(teams, req.Name) >= 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("Team %s already exists", req.Name),
+		})
+		return
+	}
+	if req.Members == nil {
+		req.Members = []string{}
+	}
+	if req.Roles == nil {
+		req.Roles = []TeamRole{}
+	}
+	req.CreatedAt = time.Now().UTC()
+	teams = append(teams, req)
+	if err := s.saveTeams(context.TODO(), cm, teams); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to save team: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, req)
+}
+
+// getTeam returns one team
+func (s *Server) getTeam(c *gin.Context) {
+	teams, _, err := s.loadTeams(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	i := findTeam(teams, c.Param("team"))
+	if i < 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Team not found",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, teams[i])
+}
+
+// updateTeam replaces a team, keeping its name and creation time
+func (s *Server) updateTeam(c *gin.Context) {
+	var req Team
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	req.Name = c.Param("team")
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if req.Members == nil {
+		req.Members = []string{}
+	}
+	if req.Roles == nil {
+		req.Roles = []TeamRole{}
+	}
+
+	s.changeTeam(c, func(team *Team) error {
+		req.CreatedAt = team.CreatedAt
+		*team = req
+		return nil
+	})
+}
+
+// deleteTeam removes a team and the roles it granted
+func (s *Server) deleteTeam(c *gin.Context) {
+	teams, cm, err := s.loadTeams(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	i := findTeam(teams, c.Param("team"))
+	if i < 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Team not found",
+		})
+		return
+	}
+	teams = append(teams[:i], teams[i+1:]...)
+	if err := s.saveTeams(context.TODO(), cm, teams); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete team: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Team deleted",
+	})
+}
+
+// addTeamMember adds a subject to a team
+func (s *Server) addTeamMember(c *gin.Context) {
+	var req struct {
+		Subject string `json:"subject" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	s.changeTeam(c, func(team *Team) error {
+		if !containsString(team.Members, req.Subject) {
+			team.Members = append(team.Members, req.Subject)
+		}
+		return nil
+	})
+}
+
+// removeTeamMember removes a subject from a team
+func (s *Server) removeTeamMember(c *gin.Context) {
+	subject := c.Param("subject")
+	s.changeTeam(c, func(team *Team) error {
+		members := []string{}
+		for _, member := range team.Members {
+			if member != subject {
+				members = append(members, member)
+			}
+		}
+		if len(members) == len(team.Members) {
+			return apierrors.NewNotFound(corev1.Resource("team-member"), subject)
+		}
+		team.Members = members
+		return nil
+	})
+}
+
+// changeTeam applies change to the :team team and stores it
+func (s *Server) changeTeam(c *gin.Context, change func(*Team) error) {
+	teams, cm, err := s.loadTeams(context.TODO())
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	i := findTeam(teams, c.Param("team"))
+	if i < 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Team not found",
+		})
+		return
+	}
+	if err := change(&teams[i]); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := s.saveTeams(context.TODO(), cm, teams); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to save team: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, teams[i])
+}
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+	"golang.org/x/net/websocket"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// tunnelGameServer forwards a TCP port of a GameServer's pod over a
+// WebSocket, like kubectl port-forward, so operators can reach telnet,
+// RCON or query ports without cluster credentials. Binary messages carry
+// the raw stream. Tunnels are admin-only and recorded in the activity feed.
+func (s *Server) tunnelGameServer(c *gin.Context) {
+	if !s.isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Only admins may open tunnels",
+		})
+		return
+	}
+	if err := sameOriginHandshake(&websocket.Config{}, c.Request); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	port, err := strconv.Atoi(c.Query("port"))
+	if err != nil || port < 1 || port > 65535 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "port must be between 1 and 65535",
+		})
+		return
+	}
+	namespace, name := c.Param("namespace"), c.Param("name")
+	obj, err := s.getGameServerObject(c.Request.Context(), namespace, name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+	podIP, err := s.runningPodIP(c.Request.Context(), obj)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	dialer := net.Dialer{Timeout: consoleDialTimeout}
+	conn, err := dialer.DialContext(c.Request.Context(), "tcp", net.JoinHostPort(podIP, strconv.Itoa(port)))
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": fmt.Sprintf("Failed to connect to port %d: %v", port, err),
+		})
+		return
+	}
+	defer conn.Close()
+
+	actor := c.GetString(handlers.SubjectKey)
+	if actor == "" {
+		actor = anonymousSubject
+	}
+	s.recordActivity(Activity{
+		Kind:      activityAudit,
+		Type:      "GET /gameservers/:namespace/:name/tunnel",
+		Actor:     actor,
+		Cluster:   s.cluster,
+		Namespace: namespace,
+		Server:    name,
+		Summary:   fmt.Sprintf("Opened a tunnel to port %d", port),
+	})
+	log.Printf("%s opened a tunnel to %s/%s port %d", actor, namespace, name, port)
+
+	tunnel := websocket.Server{
+		Handshake: sameOriginHandshake,
+		Handler: func(ws *websocket.Conn) {
+			ws.PayloadType = websocket.BinaryFrame
+			done := make(chan struct{}, 2)
+			go func() {
+				_, _ = io.Copy(conn, ws)
+				done <- struct{}{}
+			}()
+			go func() {
+				_, _ = io.Copy(ws, conn)
+				done <- struct{}{}
+			}()
+			<-done
+			ws.Close()
+			conn.Close()
+		},
+	}
+	tunnel.ServeHTTP(c.Writer, c.Request)
+}
+
+// sameOriginHandshake accepts WebSockets from clients without an Origin,
+// such as CLIs, and from pages of the API's own host, so other sites
+// cannot open tunnels with a visitor's cookies
+func sameOriginHandshake(config *websocket.Config, req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil || parsed.Host != req.Host {
+		return fmt.Errorf("origin %s may not open tunnels", origin)
+	}
+	config.Origin = parsed
+	return nil
+}
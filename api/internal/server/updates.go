@@ -0,0 +1,659 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// updatePolicyAnnotation holds a GameServer's UpdatePolicy as JSON
+	updatePolicyAnnotation = "gameplane.kubelize.io/update-policy"
+
+	// updateStateAnnotation holds the UpdateState of a GameServer
+	updateStateAnnotation = "gameplane.kubelize.io/update-state"
+
+	// updaterInterval is how often servers are checked for updates
+	updaterInterval = time.Minute
+
+	// updateHistoryLimit is how many applied updates are kept per server
+	updateHistoryLimit = 20
+
+	// updateTimeout bounds applying one update, including the backup
+	updateTimeout = time.Hour
+)
+
+// Update channels
+const (
+	// updateManual only reports available updates
+	updateManual = "manual"
+	// updateNotify also alerts about them
+	updateNotify = "notify"
+	// updateAutoWithBackup alerts and applies them within the maintenance
+	// window after backing up the server
+	updateAutoWithBackup = "auto-with-backup"
+)
+
+// updateChannels are the valid channels in the order they are documented
+var updateChannels = []string{updateManual, updateNotify, updateAutoWithBackup}
+
+// UpdatePolicy selects how a GameServer follows new game and mod versions
+type UpdatePolicy struct {
+	Channel string `json:"channel"`
+	// Window is when auto-with-backup may apply updates; without one they
+	// are applied as soon as they are found
+	Window   *UpdateWindow   `json:"window,omitempty"`
+	Webhooks []webhookTarget `json:"webhooks"`
+}
+
+// UpdateWindow is a maintenance window opening on Schedule and lasting
+// Duration, e.g. {"cron": "0 4 * * *", "duration": "2h"}
+type UpdateWindow struct {
+	Schedule
+	Duration string `json:"duration"`
+}
+
+// AvailableUpdate is what updating a GameServer would change
+type AvailableUpdate struct {
+	// Image is the catalog image replacing the server's pinned one
+	Image       string `json:"image,omitempty"`
+	FromVersion string `json:"fromVersion,omitempty"`
+	ToVersion   string `json:"toVersion,omitempty"`
+	// Mods are the mounted shared assets reloaded since the server started
+	Mods []ModUpdate `json:"mods,omitempty"`
+	// FoundAt is when the updater first found the update
+	FoundAt *time.Time `json:"foundAt,omitempty"`
+}
+
+// ModUpdate is a mounted shared asset with newer content than the server
+// runs
+type ModUpdate struct {
+	Asset    string    `json:"asset"`
+	LoadedAt time.Time `json:"loadedAt"`
+}
+
+// UpdateRecord describes one update applied by the updater
+type UpdateRecord struct {
+	Time        time.Time `json:"time"`
+	Success     bool      `json:"success"`
+	Message     string    `json:"message"`
+	Image       string    `json:"image,omitempty"`
+	FromVersion string    `json:"fromVersion,omitempty"`
+	ToVersion   string    `json:"toVersion,omitempty"`
+	Mods        []string  `json:"mods,omitempty"`
+	// Backup is the snapshot taken before updating
+	Backup string `json:"backup,omitempty"`
+}
+
+// UpdateState tracks the updates of a GameServer
+type UpdateState struct {
+	// Available is the last update found and announced
+	Available *AvailableUpdate `json:"available,omitempty"`
+	// Failed identifies an update that failed to apply; it is not retried
+	// until a newer one is available
+	Failed  string         `json:"failed,omitempty"`
+	History []UpdateRecord `json:"history"`
+}
+
+// updaterState remembers which servers have an update in progress
+type updaterState struct {
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// validate checks a policy before it is stored
+func (p UpdatePolicy) validate() error {
+	if !containsString(updateChannels, p.Channel) {
+		return fmt.Errorf("unsupported update channel %q (valid: %s)", p.Channel, strings.Join(updateChannels, ", "))
+	}
+	if p.Window != nil {
+		if err := p.Window.Schedule.validate(); err != nil {
+			return err
+		}
+		if d, err := time.ParseDuration(p.Window.Duration); err != nil || d <= 0 {
+			return fmt.Errorf("invalid window duration %q", p.Window.Duration)
+		}
+	}
+	for _, target := range p.Webhooks {
+		if err := validateWebhookTarget(target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// windowOpen reports whether updates may be applied at now
+func (p UpdatePolicy) windowOpen(now time.Time) bool {
+	if p.Window == nil {
+		return true
+	}
+	d, err := time.ParseDuration(p.Window.Duration)
+	if err != nil {
+		return false
+	}
+	opened, ok := p.Window.next(now.Add(-d))
+	return ok && !opened.After(now)
+}
+
+// key identifies an available update, to announce and attempt it once
+func (u *AvailableUpdate) key() string {
+	parts := []string{u.Image}
+	for _, mod := range u.Mods {
+		parts = append(parts, mod.Asset+"@"+mod.LoadedAt.Format(time.RFC3339))
+	}
+	return strings.Join(parts, ",")
+}
+
+// summary describes an available update, e.g. "game 0.2.9 to 0.3.0, mods
+// darkness-falls"
+func (u *AvailableUpdate) summary() string {
+	parts := []string{}
+	if u.Image != "" {
+		parts = append(parts, fmt.Sprintf("game %s to %s", u.FromVersion, u.ToVersion))
+	}
+	if len(u.Mods) > 0 {
+		parts = append(parts, "mods "+strings.Join(u.modNames(), ", "))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// modNames lists the updated assets
+func (u *AvailableUpdate) modNames() []string {
+	names := []string{}
+	for _, mod := range u.Mods {
+		names = append(names, mod.Asset)
+	}
+	return names
+}
+
+// getUpdates returns the update policy, the update available now and the
+// updates applied so far
+func (s *Server) getUpdates(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	policy, err := updatePolicy(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	available, err := s.availableUpdate(context.TODO(), obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to check for updates: %v", err),
+		})
+		return
+	}
+	state := updateState(obj)
+	if available != nil && state.Available != nil && state.Available.key() == available.key() {
+		available = state.Available
+	}
+	response := gin.H{
+		"policy":    policy,
+		"available": available,
+		"state":     state,
+	}
+	if policy != nil && policy.Window != nil {
+		now := time.Now()
+		response["windowOpen"] = policy.windowOpen(now)
+		if next, ok := policy.Window.next(now); ok {
+			response["nextWindow"] = next
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// putUpdatePolicy stores the GameServer's update policy
+func (s *Server) putUpdatePolicy(c *gin.Context) {
+	var policy UpdatePolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if err := policy.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if policy.Channel == updateAutoWithBackup && s.snapshots.class == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "auto-with-backup needs VOLUME_SNAPSHOT_CLASS to back servers up",
+		})
+		return
+	}
+	if policy.Webhooks == nil {
+		policy.Webhooks = []webhookTarget{}
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[updatePolicyAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update update policy: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"policy": policy,
+		"state":  updateState(obj),
+	})
+}
+
+// deleteUpdatePolicy removes the GameServer's update policy
+func (s *Server) deleteUpdatePolicy(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	annotations := obj.GetAnnotations()
+	if _, found := annotations[updatePolicyAnnotation]; !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "GameServer has no update policy",
+		})
+		return
+	}
+	delete(annotations, updatePolicyAnnotation)
+	obj.SetAnnotations(annotations)
+
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete update policy: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Update policy deleted",
+	})
+}
+
+// runUpdates is the background task following update channels. A new
+// update is announced once on the notify and auto-with-backup channels, and
+// auto-with-backup applies it once the maintenance window is open. Stopped
+// servers are left alone until they run again.
+func (s *Server) runUpdates(ctx context.Context) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+
+	now := time.Now().UTC()
+	s.updater.mu.Lock()
+	defer s.updater.mu.Unlock()
+	if s.updater.running == nil {
+		s.updater.running = map[string]bool{}
+	}
+
+	for i := range list.Items {
+		obj := &list.Items[i]
+		key := obj.GetNamespace() + "/" + obj.GetName()
+		policy, err := updatePolicy(obj)
+		if err != nil || policy == nil || policy.Channel == updateManual || s.updater.running[key] {
+			continue
+		}
+		if stopped, _, _ := unstructured.NestedBool(obj.Object, "spec", "stopped"); stopped {
+			continue
+		}
+		available, err := s.availableUpdate(ctx, obj)
+		if err != nil {
+			log.Printf("Failed to check %s for updates: %v", key, err)
+			continue
+		}
+
+		state := updateState(obj)
+		if available == nil {
+			if state.Available != nil || state.Failed != "" {
+				state.Available = nil
+				state.Failed = ""
+				if err := s.saveUpdateState(ctx, obj, state); err != nil {
+					log.Printf("Failed to clear available update of %s: %v", key, err)
+				}
+			}
+			continue
+		}
+		if state.Available == nil || state.Available.key() != available.key() {
+			available.FoundAt = &now
+			state.Available = available
+			state.Failed = ""
+			if err := s.saveUpdateState(ctx, obj, state); err != nil {
+				log.Printf("Failed to record available update of %s: %v", key, err)
+				continue
+			}
+			s.announceUpdate(ctx, obj, policy, available)
+		}
+		if policy.Channel != updateAutoWithBackup || state.Failed == available.key() || !policy.windowOpen(now) {
+			continue
+		}
+
+		s.updater.running[key] = true
+		go func(obj *unstructured.Unstructured, key string, policy *UpdatePolicy, update *AvailableUpdate) {
+			defer func() {
+				s.updater.mu.Lock()
+				delete(s.updater.running, key)
+				s.updater.mu.Unlock()
+			}()
+			ctx, cancel := context.WithTimeout(context.Background(), updateTimeout)
+			defer cancel()
+			s.applyUpdate(ctx, obj, policy, update)
+		}(obj, key, policy, state.Available)
+	}
+	return nil
+}
+
+// availableUpdate finds what updating a GameServer would change, returning
+// nil when it is up to date. A server pinned to an older image of the
+// catalog's repository can move to the catalog image; a server that
+// started before a mounted asset was last loaded runs an older version of
+// that mod until it restarts.
+func (s *Server) availableUpdate(ctx context.Context, obj *unstructured.Unstructured) (*AvailableUpdate, error) {
+	update := &AvailableUpdate{}
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	pinned, _, _ := unstructured.NestedString(obj.Object, "spec", "advanced", "image")
+	if def, ok := lookupGame(gameType); ok && pinned != "" && imageRepository(pinned) == imageRepository(def.Image) {
+		from, to := imageVersion(pinned, gameType), imageVersion(def.Image, gameType)
+		if from != "" && to != "" && newerVersion(to, from) {
+			update.Image = def.Image
+			update.FromVersion = from
+			update.ToVersion = to
+		}
+	}
+
+	if volumes := gameServerSharedVolumes(obj); len(volumes) > 0 {
+		pods, _, err := s.findGameServerPods(ctx, obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list game pods: %w", err)
+		}
+		if started, ok := podsStarted(pods); ok {
+			for _, volume := range volumes {
+				claim, err := s.kubeClient.CoreV1().PersistentVolumeClaims(s.clusters.namespace).Get(ctx, sharedAssetClaimPrefix+volume.Name, metav1.GetOptions{})
+				if apierrors.IsNotFound(err) {
+					continue
+				} else if err != nil {
+					return nil, fmt.Errorf("failed to get shared asset %s: %w", volume.Name, err)
+				}
+				loadedAt, err := time.Parse(time.RFC3339, claim.Annotations[sharedAssetLoadedAtAnnotation])
+				if err == nil && loadedAt.After(started) {
+					update.Mods = append(update.Mods, ModUpdate{Asset: volume.Name, LoadedAt: loadedAt})
+				}
+			}
+		}
+	}
+
+	if update.Image == "" && len(update.Mods) == 0 {
+		return nil, nil
+	}
+	sort.Slice(update.Mods, func(i, j int) bool { return update.Mods[i].Asset < update.Mods[j].Asset })
+	return update, nil
+}
+
+// podsStarted returns when the oldest running game pod started; false
+// while none runs
+func podsStarted(pods []corev1.Pod) (time.Time, bool) {
+	var started time.Time
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil || pod.Status.StartTime == nil {
+			continue
+		}
+		if started.IsZero() || pod.Status.StartTime.Time.Before(started) {
+			started = pod.Status.StartTime.Time
+		}
+	}
+	return started, !started.IsZero()
+}
+
+// announceUpdate alerts about a newly found update
+func (s *Server) announceUpdate(ctx context.Context, obj *unstructured.Unstructured, policy *UpdatePolicy, update *AvailableUpdate) {
+	message := "Apply it with an upgrade or a restart"
+	if policy.Channel == updateAutoWithBackup {
+		message = "It will be applied after a backup in the next maintenance window"
+		if policy.Window == nil {
+			message = "It is being applied after a backup"
+		}
+	}
+	event := notificationEvent{
+		Type:      "update-available",
+		Namespace: obj.GetNamespace(),
+		Server:    obj.GetName(),
+		Title:     fmt.Sprintf("Update available for %s: %s", obj.GetName(), update.summary()),
+		Message:   message,
+		Fields:    map[string]string{"Channel": policy.Channel},
+	}
+	if update.Image != "" {
+		event.Fields["Image"] = update.Image
+	}
+	for _, target := range policy.Webhooks {
+		if err := postWebhook(ctx, target, event); err != nil {
+			log.Printf("Failed to deliver available update of %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	s.notifySubscribers(ctx, event)
+	s.publishEvent(eventGameServerUpdateAvailable, obj.GetNamespace(), obj.GetName(), map[string]interface{}{
+		"image":       update.Image,
+		"fromVersion": update.FromVersion,
+		"toVersion":   update.ToVersion,
+		"mods":        update.modNames(),
+	})
+}
+
+// applyUpdate backs a server up and applies an update: a new image is
+// rolled out, which restarts the server with the latest mods as well, and
+// mod updates alone restart it. The result is recorded in the server's
+// update history and the activity feed and alerted.
+func (s *Server) applyUpdate(ctx context.Context, obj *unstructured.Unstructured, policy *UpdatePolicy, update *AvailableUpdate) {
+	key := obj.GetNamespace() + "/" + obj.GetName()
+	log.Printf("Updating GameServer %s: %s", key, update.summary())
+
+	record := UpdateRecord{
+		Time:        time.Now().UTC(),
+		Image:       update.Image,
+		FromVersion: update.FromVersion,
+		ToVersion:   update.ToVersion,
+		Mods:        update.modNames(),
+	}
+	err := s.runUpdate(ctx, obj, update, &record)
+	record.Success = err == nil
+	if err != nil {
+		record.Message = fmt.Sprintf("Update failed: %v", err)
+		log.Printf("Update of %s failed: %v", key, err)
+	} else {
+		record.Message = "Updated " + update.summary()
+	}
+
+	latest, getErr := s.getGameServerObject(ctx, obj.GetNamespace(), obj.GetName())
+	if getErr == nil {
+		state := updateState(latest)
+		if record.Success {
+			state.Available = nil
+		} else {
+			state.Failed = update.key()
+		}
+		state.History = append([]UpdateRecord{record}, state.History...)
+		if len(state.History) > updateHistoryLimit {
+			state.History = state.History[:updateHistoryLimit]
+		}
+		getErr = s.saveUpdateState(ctx, latest, state)
+	}
+	if getErr != nil {
+		log.Printf("Failed to record update of %s: %v", key, getErr)
+	}
+
+	title := fmt.Sprintf("%s was updated: %s", obj.GetName(), update.summary())
+	if err != nil {
+		title = fmt.Sprintf("%s failed to update: %s", obj.GetName(), update.summary())
+	}
+	event := notificationEvent{
+		Type:      "update",
+		Namespace: obj.GetNamespace(),
+		Server:    obj.GetName(),
+		Title:     title,
+		Message:   record.Message,
+		Fields:    map[string]string{},
+	}
+	if record.Image != "" {
+		event.Fields["Image"] = record.Image
+	}
+	if record.Backup != "" {
+		event.Fields["Backup"] = record.Backup
+	}
+	for _, target := range policy.Webhooks {
+		if err := postWebhook(ctx, target, event); err != nil {
+			log.Printf("Failed to deliver update of %s: %v", key, err)
+		}
+	}
+	s.notifySubscribers(ctx, event)
+}
+
+// runUpdate runs the steps of applyUpdate
+func (s *Server) runUpdate(ctx context.Context, obj *unstructured.Unstructured, update *AvailableUpdate, record *UpdateRecord) error {
+	target := RolloutTarget{Image: update.Image}
+	mods, err := s.checkModCompatibility(ctx, obj, target, false)
+	if err != nil {
+		return fmt.Errorf("failed to check mod compatibility: %w", err)
+	}
+	if mods != nil && mods.Blocked {
+		return errors.New(mods.Reason)
+	}
+
+	backup, err := s.safetySnapshot(ctx, obj, "update", "")
+	if err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+	if backup == nil {
+		return errors.New("the server's data could not be backed up")
+	}
+	record.Backup = backup.Name
+
+	if update.Image != "" {
+		latest, err := s.getGameServerObject(ctx, obj.GetNamespace(), obj.GetName())
+		if err != nil {
+			return err
+		}
+		if err := applyRolloutTarget(latest, target); err != nil {
+			return err
+		}
+		if err := s.k8sClient.Update(ctx, latest); err != nil {
+			return fmt.Errorf("failed to update: %w", err)
+		}
+		s.publishEvent(eventGameServerUpdated, obj.GetNamespace(), obj.GetName(), map[string]interface{}{"rollout": target})
+	} else {
+		pods, namespace, err := s.findGameServerPods(ctx, obj)
+		if err != nil {
+			return fmt.Errorf("failed to find pods: %w", err)
+		}
+		if _, err := s.deleteGameServerPods(ctx, namespace, pods, nil); err != nil {
+			return fmt.Errorf("failed to restart: %w", err)
+		}
+	}
+	_, err = s.waitForRolloutReady(ctx, obj.GetNamespace(), obj.GetName(), update.Image, defaultRolloutReadyTimeout)
+	return err
+}
+
+// imageRepository returns an image reference without its tag or digest
+func imageRepository(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i]
+	}
+	return image
+}
+
+// newerVersion reports whether version a is newer than b, comparing dot
+// separated parts numerically where both are numbers
+func newerVersion(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		if aErr == nil && bErr == nil {
+			if an != bn {
+				return an > bn
+			}
+			continue
+		}
+		if as[i] != bs[i] {
+			return as[i] > bs[i]
+		}
+	}
+	return len(as) > len(bs)
+}
+
+// updatePolicy reads the update policy annotation, returning nil when
+// unset
+func updatePolicy(obj *unstructured.Unstructured) (*UpdatePolicy, error) {
+	raw, ok := obj.GetAnnotations()[updatePolicyAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var policy UpdatePolicy
+	if err := json.Unmarshal([]byte(raw), &policy); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", updatePolicyAnnotation, err)
+	}
+	return &policy, nil
+}
+
+// updateState reads the update state annotation. A missing or invalid one
+// starts over.
+func updateState(obj *unstructured.Unstructured) UpdateState {
+	state := UpdateState{History: []UpdateRecord{}}
+	if raw, ok := obj.GetAnnotations()[updateStateAnnotation]; ok {
+		_ = json.Unmarshal([]byte(raw), &state)
+	}
+	return state
+}
+
+// saveUpdateState writes the update state annotation
+func (s *Server) saveUpdateState(ctx context.Context, obj *unstructured.Unstructured, state UpdateState) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[updateStateAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+	return s.k8sClient.Update(ctx, obj)
+}
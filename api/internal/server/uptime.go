@@ -0,0 +1,310 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// availabilityInterval is how often GameServer readiness is checked
+	availabilityInterval = 30 * time.Second
+
+	// availabilityRetention is how long readiness transitions are kept
+	availabilityRetention = 90 * 24 * time.Hour
+
+	// availabilityKey is the usage ConfigMap key of the transitions
+	availabilityKey = "availability.json"
+)
+
+// AvailabilityTransition records a GameServer becoming ready or not ready
+type AvailabilityTransition struct {
+	Time   time.Time `json:"t"`
+	Ready  bool      `json:"ready"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Incident is a period in which a GameServer was not ready
+type Incident struct {
+	Start           time.Time  `json:"start"`
+	End             *time.Time `json:"end,omitempty"`
+	DurationSeconds int64      `json:"durationSeconds"`
+	Reason          string     `json:"reason,omitempty"`
+}
+
+// UptimeReport summarises a GameServer's availability over a range
+type UptimeReport struct {
+	Range           string     `json:"range"`
+	Ready           bool       `json:"ready"`
+	TrackedSince    *time.Time `json:"trackedSince,omitempty"`
+	UptimePercent   *float64   `json:"uptimePercent"`
+	DowntimeSeconds int64      `json:"downtimeSeconds"`
+	MTTRSeconds     *int64     `json:"mttrSeconds"`
+	Incidents       []Incident `json:"incidents"`
+}
+
+// availabilityTracker remembers the last recorded readiness and player count
+// per GameServer so changes are only written and published once
+type availabilityTracker struct {
+	mu      sync.Mutex
+	ready   map[string]bool
+	players map[string]int
+}
+
+// trackAvailability records readiness transitions of every GameServer
+func (s *Server) trackAvailability(ctx context.Context) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+
+	now := time.Now().UTC()
+	active := map[string]bool{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		key := obj.GetNamespace() + "/" + obj.GetName()
+		active[key] = true
+		ready := gameServerReady(obj)
+
+		players := gameServerPlayers(obj)
+
+		s.availability.mu.Lock()
+		if s.availability.ready == nil {
+			s.availability.ready = map[string]bool{}
+			s.availability.players = map[string]int{}
+		}
+		last, known := s.availability.ready[key]
+		previous, seen := s.availability.players[key]
+		s.availability.players[key] = players
+		s.availability.mu.Unlock()
+		if seen && previous != players {
+			s.publishEvent(eventPlayersChanged, obj.GetNamespace(), obj.GetName(), map[string]interface{}{
+				"previous": previous,
+				"current":  players,
+				"capacity": gameServerCapacity(obj),
+			})
+		}
+		if known && last == ready {
+			continue
+		}
+
+		if err := s.recordAvailability(ctx, obj, ready, now); err != nil {
+			log.Printf("Failed to record availability of GameServer %s: %v", key, err)
+			continue
+		}
+		s.availability.mu.Lock()
+		s.availability.ready[key] = ready
+		s.availability.mu.Unlock()
+	}
+
+	s.availability.mu.Lock()
+	for key := range s.availability.players {
+		if !active[key] {
+			delete(s.availability.ready, key)
+			delete(s.availability.players, key)
+		}
+	}
+	s.availability.mu.Unlock()
+	return nil
+}
+
+// recordAvailability appends a transition unless the stored history already
+// ends in the same state. Servers are only tracked once they first become
+// ready, so provisioning does not count as downtime.
+func (s *Server) recordAvailability(ctx context.Context, obj *unstructured.Unstructured, ready bool, now time.Time) error {
+	namespace, err := managedNamespace(obj)
+	if err != nil {
+		return err
+	}
+	transitions := []AvailabilityTransition{}
+	if err := s.loadUsageData(ctx, namespace, availabilityKey, &transitions); err != nil {
+		return fmt.Errorf("failed to read availability history: %w", err)
+	}
+	if len(transitions) == 0 && !ready {
+		return nil
+	}
+	if len(transitions) > 0 && transitions[len(transitions)-1].Ready == ready {
+		return nil
+	}
+
+	transition := AvailabilityTransition{Time: now, Ready: ready}
+	if !ready {
+		transition.Reason = notReadyReason(obj)
+	}
+	transitions = append(transitions, transition)
+
+	// Keep the last transition before the cutoff so the state at the start
+	// of the retained window is known
+	cutoff := now.Add(-availabilityRetention)
+	for len(transitions) > 1 && transitions[1].Time.Before(cutoff) {
+		transitions = transitions[1:]
+	}
+	if err := s.saveUsageData(ctx, namespace, availabilityKey, transitions); err != nil {
+		return err
+	}
+
+	if ready {
+		s.publishEvent(eventGameServerReady, obj.GetNamespace(), obj.GetName(), nil)
+	} else {
+		s.publishEvent(eventGameServerDown, obj.GetNamespace(), obj.GetName(), map[string]interface{}{
+			"reason": transition.Reason,
+		})
+		s.notifySubscribers(ctx, notificationEvent{
+			Type:      "crash",
+			Namespace: obj.GetNamespace(),
+			Server:    obj.GetName(),
+			Title:     "Server down",
+			Message:   transition.Reason,
+			Time:      now,
+		})
+	}
+	return nil
+}
+
+// notReadyReason describes why a GameServer is not ready
+func notReadyReason(obj *unstructured.Unstructured) string {
+	conditions, _ := gameServerConditions(obj)
+	for _, condition := range conditions {
+		if condition.Type == "Ready" && condition.Message != "" {
+			return condition.Message
+		}
+		if condition.Type == "Ready" && condition.Reason != "" {
+			return condition.Reason
+		}
+	}
+	if phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase"); phase != "" {
+		return "Phase " + phase
+	}
+	return ""
+}
+
+// getGameServerUptime reports uptime, incidents and mean time to recovery
+// over ?range= (default 30d)
+func (s *Server) getGameServerUptime(c *gin.Context) {
+	rangeParam := c.DefaultQuery("range", "30d")
+	window, err := parseRange(rangeParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	namespace, err := managedNamespace(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to resolve GameServer namespace: %v", err),
+		})
+		return
+	}
+	transitions := []AvailabilityTransition{}
+	if err := s.loadUsageData(context.TODO(), namespace, availabilityKey, &transitions); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to read availability history: %v", err),
+		})
+		return
+	}
+
+	report := uptimeReport(transitions, time.Now().UTC(), window)
+	report.Range = rangeParam
+	report.Ready = gameServerReady(obj)
+	c.JSON(http.StatusOK, report)
+}
+
+// uptimeReport replays transitions over the window ending at now
+func uptimeReport(transitions []AvailabilityTransition, now time.Time, window time.Duration) UptimeReport {
+	report := UptimeReport{Incidents: []Incident{}}
+	if len(transitions) == 0 {
+		return report
+	}
+
+	start := now.Add(-window)
+	if first := transitions[0].Time; first.After(start) {
+		start = first
+	}
+	report.TrackedSince = &start
+
+	// State at the start of the window
+	ready := transitions[0].Ready
+	reason := transitions[0].Reason
+	for _, transition := range transitions {
+		if transition.Time.After(start) {
+			break
+		}
+		ready, reason = transition.Ready, transition.Reason
+	}
+
+	var up, down time.Duration
+	var current *Incident
+	if !ready {
+		current = &Incident{Start: start, Reason: reason}
+	}
+	cursor := start
+	for _, transition := range transitions {
+		if !transition.Time.After(start) {
+			continue
+		}
+		if ready {
+			up += transition.Time.Sub(cursor)
+		} else {
+			down += transition.Time.Sub(cursor)
+		}
+		cursor = transition.Time
+		if transition.Ready == ready {
+			continue
+		}
+		ready = transition.Ready
+		if ready && current != nil {
+			end := transition.Time
+			current.End = &end
+			current.DurationSeconds = int64(end.Sub(current.Start).Seconds())
+			report.Incidents = append(report.Incidents, *current)
+			current = nil
+		} else if !ready {
+			current = &Incident{Start: transition.Time, Reason: transition.Reason}
+		}
+	}
+	if ready {
+		up += now.Sub(cursor)
+	} else {
+		down += now.Sub(cursor)
+	}
+
+	var recovered time.Duration
+	resolved := int64(len(report.Incidents))
+	for _, incident := range report.Incidents {
+		recovered += incident.End.Sub(incident.Start)
+	}
+	if resolved > 0 {
+		mttr := int64(recovered.Seconds()) / resolved
+		report.MTTRSeconds = &mttr
+	}
+	if current != nil {
+		current.DurationSeconds = int64(now.Sub(current.Start).Seconds())
+		report.Incidents = append(report.Incidents, *current)
+	}
+
+	report.DowntimeSeconds = int64(down.Seconds())
+	if total := up + down; total > 0 {
+		percent := math.Round(float64(up)/float64(total)*100000) / 1000
+		report.UptimePercent = &percent
+	}
+	return report
+}
@@ -0,0 +1,214 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// usageSampleInterval is how often GameServer usage is sampled
+	usageSampleInterval = 5 * time.Minute
+
+	// usageRetention is how much hourly history is kept
+	usageRetention = 30 * 24 * time.Hour
+
+	// usageConfigMap holds a GameServer's usage and availability history in
+	// its managed namespace, so it is removed together with the server
+	usageConfigMap = "gameplane-usage"
+
+	// usageHistoryKey is the ConfigMap key of the hourly history
+	usageHistoryKey = "hourly.json"
+)
+
+// UsageHour aggregates the samples taken during one hour. CPU is in
+// millicores and memory in bytes; they only cover samples where
+// metrics-server answered.
+type UsageHour struct {
+	Hour          time.Time `json:"t"`
+	Samples       int       `json:"n"`
+	ReadySamples  int       `json:"ready"`
+	PlayersMax    int       `json:"playersMax"`
+	PlayersSum    int       `json:"playersSum"`
+	MetricSamples int       `json:"metricN"`
+	CPUSum        int64     `json:"cpuSum"`
+	CPUMax        int64     `json:"cpuMax"`
+	MemorySum     int64     `json:"memSum"`
+	MemoryMax     int64     `json:"memMax"`
+}
+
+// sampleUsage records one usage sample for every GameServer
+func (s *Server) sampleUsage(ctx context.Context) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+
+	now := time.Now().UTC()
+	s.workers.each(ctx, len(list.Items), func(i int) {
+		obj := &list.Items[i]
+		if err := s.recordUsageSample(ctx, obj, now); err != nil {
+			log.Printf("Failed to sample usage of GameServer %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	})
+	return nil
+}
+
+// recordUsageSample adds the current usage of a GameServer to its history
+func (s *Server) recordUsageSample(ctx context.Context, obj *unstructured.Unstructured, now time.Time) error {
+	pods, namespace, err := s.findGameServerPods(ctx, obj)
+	if err != nil {
+		// Not provisioned yet
+		return nil
+	}
+	var pod *corev1.Pod
+	for i := range pods {
+		if pods[i].Status.Phase == corev1.PodRunning {
+			pod = &pods[i]
+			break
+		}
+	}
+
+	history, err := s.loadUsageHistory(ctx, namespace)
+	if err != nil {
+		return err
+	}
+	hour := now.Truncate(time.Hour)
+	if len(history) == 0 || !history[len(history)-1].Hour.Equal(hour) {
+		history = append(history, UsageHour{Hour: hour})
+	}
+	bucket := &history[len(history)-1]
+
+	bucket.Samples++
+	if gameServerReady(obj) {
+		bucket.ReadySamples++
+	}
+	players := gameServerPlayers(obj)
+	bucket.PlayersSum += players
+	if players > bucket.PlayersMax {
+		bucket.PlayersMax = players
+	}
+	if pod != nil {
+		if cpu, memory, err := s.getPodMetrics(pod.Name, namespace); err == nil {
+			cpuMillis, memoryBytes := quantityMilli(cpu), quantityMilli(memory)/1000
+			bucket.MetricSamples++
+			bucket.CPUSum += cpuMillis
+			bucket.MemorySum += memoryBytes
+			if cpuMillis > bucket.CPUMax {
+				bucket.CPUMax = cpuMillis
+			}
+			if memoryBytes > bucket.MemoryMax {
+				bucket.MemoryMax = memoryBytes
+			}
+		}
+	}
+
+	cutoff := now.Add(-usageRetention)
+	for len(history) > 0 && history[0].Hour.Before(cutoff) {
+		history = history[1:]
+	}
+	return s.saveUsageHistory(ctx, namespace, history)
+}
+
+// loadUsageHistory reads the hourly usage history of a managed namespace,
+// oldest first
+func (s *Server) loadUsageHistory(ctx context.Context, namespace string) ([]UsageHour, error) {
+	history := []UsageHour{}
+	if err := s.loadUsageData(ctx, namespace, usageHistoryKey, &history); err != nil {
+		return nil, fmt.Errorf("failed to read usage history: %w", err)
+	}
+	return history, nil
+}
+
+// saveUsageHistory writes the hourly usage history of a managed namespace
+func (s *Server) saveUsageHistory(ctx context.Context, namespace string, history []UsageHour) error {
+	return s.saveUsageData(ctx, namespace, usageHistoryKey, history)
+}
+
+// loadUsageData decodes a key of the usage ConfigMap, leaving out untouched
+// when the ConfigMap or key does not exist
+func (s *Server) loadUsageData(ctx context.Context, namespace, key string, out interface{}) error {
+	cm, err := s.kubeClient.CoreV1().ConfigMaps(namespace).Get(ctx, usageConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if raw := cm.Data[key]; raw != "" {
+		return json.Unmarshal([]byte(raw), out)
+	}
+	return nil
+}
+
+// saveUsageData encodes a value into a key of the usage ConfigMap, creating
+// the ConfigMap if needed
+func (s *Server) saveUsageData(ctx context.Context, namespace, key string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	configMaps := s.kubeClient.CoreV1().ConfigMaps(namespace)
+	cm, err := configMaps.Get(ctx, usageConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      usageConfigMap,
+				Namespace: namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "gameplane-api"},
+			},
+			Data: map[string]string{key: string(raw)},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[key] = string(raw)
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// gameServerUsage returns the usage history of a GameServer within a window
+func (s *Server) gameServerUsage(ctx context.Context, obj *unstructured.Unstructured, window time.Duration) ([]UsageHour, error) {
+	namespace, err := managedNamespace(obj)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.loadUsageHistory(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().Add(-window)
+	for len(history) > 0 && history[0].Hour.Add(time.Hour).Before(cutoff) {
+		history = history[1:]
+	}
+	return history, nil
+}
+
+// quantityMilli parses a Kubernetes quantity in thousandths, returning 0 for
+// invalid values
+func quantityMilli(value string) int64 {
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0
+	}
+	return quantity.MilliValue()
+}
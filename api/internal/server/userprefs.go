@@ -0,0 +1,335 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// userPrefsConfigMap holds every user's preferences, one key per user
+	userPrefsConfigMap = "gameplane-userprefs"
+
+	// anonymousSubject owns the preferences when authentication is off
+	anonymousSubject = "anonymous"
+
+	// maxPreferredServers bounds the servers a user can annotate, keeping
+	// the shared ConfigMap well below its size limit
+	maxPreferredServers = 500
+	maxColorTags        = 32
+	maxDisplayNameLen   = 128
+)
+
+var (
+	// serverRefPattern matches namespace/name references
+	serverRefPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?/[a-z0-9]([-.a-z0-9]*[a-z0-9])?$`)
+	colorPattern     = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+	tagNamePattern   = regexp.MustCompile(`^[\pL\pN][\pL\pN _.-]{0,31}$`)
+)
+
+// UserPreferences is an operator's personal view of the server list. Servers
+// are referenced as namespace/name.
+type UserPreferences struct {
+	Subject string `json:"subject"`
+	// Favorites are starred servers, shown first
+	Favorites []string `json:"favorites"`
+	// Order is a custom ordering; unlisted servers follow in default order
+	Order []string `json:"order"`
+	// Tags are the user's color tags
+	Tags []ColorTag `json:"tags"`
+	// Servers holds per-server display names and tag assignments
+	Servers   map[string]ServerPreferences `json:"servers"`
+	UpdatedAt *time.Time                   `json:"updatedAt,omitempty"`
+}
+
+// ColorTag is a named color label
+type ColorTag struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// ServerPreferences customise how one server is shown to the user
+type ServerPreferences struct {
+	DisplayName string   `json:"displayName,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// normalize fills empty collections so clients always get arrays and maps
+func (p *UserPreferences) normalize() {
+	if p.Favorites == nil {
+		p.Favorites = []string{}
+	}
+	if p.Order == nil {
+		p.Order = []string{}
+	}
+	if p.Tags == nil {
+		p.Tags = []ColorTag{}
+	}
+	if p.Servers == nil {
+		p.Servers = map[string]ServerPreferences{}
+	}
+}
+
+// validate checks references, tag definitions and size limits
+func (p UserPreferences) validate() error {
+	if len(p.Favorites) > maxPreferredServers || len(p.Order) > maxPreferredServers || len(p.Servers) > maxPreferredServers {
+		return fmt.Errorf("at most %d servers can be favorited, ordered or customised", maxPreferredServers)
+	}
+	for _, ref := range append(append([]string{}, p.Favorites...), p.Order...) {
+		if !serverRefPattern.MatchString(ref) {
+			return fmt.Errorf("invalid server reference %q (expected namespace/name)", ref)
+		}
+	}
+
+	if len(p.Tags) > maxColorTags {
+		return fmt.Errorf("at most %d tags can be defined", maxColorTags)
+	}
+	tags := map[string]bool{}
+	for _, tag := range p.Tags {
+		if !tagNamePattern.MatchString(tag.Name) {
+			return fmt.Errorf("invalid tag name %q", tag.Name)
+		}
+		if !colorPattern.MatchString(tag.Color) {
+			return fmt.Errorf("invalid color %q for tag %s (expected #rrggbb)", tag.Color, tag.Name)
+		}
+		if tags[tag.Name] {
+			return fmt.Errorf("tag %s is defined twice", tag.Name)
+		}
+		tags[tag.Name] = true
+	}
+
+	for ref, prefs := range p.Servers {
+		if !serverRefPattern.MatchString(ref) {
+			return fmt.Errorf("invalid server reference %q (expected namespace/name)", ref)
+		}
+		if len([]rune(prefs.DisplayName)) > maxDisplayNameLen {
+			return fmt.Errorf("display name of %s is longer than %d characters", ref, maxDisplayNameLen)
+		}
+		for _, tag := range prefs.Tags {
+			if !tags[tag] {
+				return fmt.Errorf("server %s uses undefined tag %q", ref, tag)
+			}
+		}
+	}
+	return nil
+}
+
+// userPrefsSubject is the caller whose preferences a request reads
+func userPrefsSubject(c *gin.Context) string {
+	if subject := c.GetString(handlers.SubjectKey); subject != "" {
+		return subject
+	}
+	return anonymousSubject
+}
+
+// userPrefsKey is the ConfigMap key of a subject; subjects such as email
+// addresses aren't valid keys, so they are hashed
+func userPrefsKey(subject string) string {
+	sum := sha256.Sum256([]byte(subject))
+	return "user-" + hex.EncodeToString(sum[:16])
+}
+
+// loadUserPrefs reads a user's preferences and the ConfigMap holding them,
+// which is nil when nobody stored preferences yet
+func (s *Server) loadUserPrefs(ctx context.Context, subject string) (UserPreferences, *corev1.ConfigMap, error) {
+	prefs := UserPreferences{Subject: subject}
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return prefs, nil, err
+	}
+	cm, err := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace).Get(ctx, userPrefsConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		prefs.normalize()
+		return prefs, nil, nil
+	}
+	if err != nil {
+		return prefs, nil, fmt.Errorf("failed to read user preferences: %w", err)
+	}
+	if raw := cm.Data[userPrefsKey(subject)]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &prefs); err != nil {
+			return prefs, nil, fmt.Errorf("failed to parse user preferences: %w", err)
+		}
+	}
+	prefs.Subject = subject
+	prefs.normalize()
+	return prefs, cm, nil
+}
+
+// saveUserPrefs writes a user's preferences, or removes them when prefs is
+// nil, failing on a concurrent change
+func (s *Server) saveUserPrefs(ctx context.Context, cm *corev1.ConfigMap, subject string, prefs *UserPreferences) error {
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return err
+	}
+	configMaps := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace)
+	key := userPrefsKey(subject)
+
+	var raw []byte
+	if prefs != nil {
+		if raw, err = json.Marshal(prefs); err != nil {
+			return err
+		}
+	}
+	if cm == nil {
+		if prefs == nil {
+			return nil
+		}
+		_, err = configMaps.Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      userPrefsConfigMap,
+				Namespace: s.clusters.namespace,
+				Labels:    map[string]string{"app.kubernetes.io/managed-by": "gameplane-api"},
+			},
+			Data: map[string]string{key: string(raw)},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	if prefs == nil {
+		delete(cm.Data, key)
+	} else {
+		cm.Data[key] = string(raw)
+	}
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// getUserPrefs returns the caller's preferences
+func (s *Server) getUserPrefs(c *gin.Context) {
+	prefs, _, err := s.loadUserPrefs(context.TODO(), userPrefsSubject(c))
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
+
+// putUserPrefs replaces the caller's preferences
+func (s *Server) putUserPrefs(c *gin.Context) {
+	var req UserPreferences
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	req.normalize()
+	if err := req.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	subject := userPrefsSubject(c)
+	_, cm, err := s.loadUserPrefs(context.TODO(), subject)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	now := time.Now().UTC()
+	req.Subject = subject
+	req.UpdatedAt = &now
+	if err := s.saveUserPrefs(context.TODO(), cm, subject, &req); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to save user preferences: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+// deleteUserPrefs resets the caller's preferences
+func (s *Server) deleteUserPrefs(c *gin.Context) {
+	subject := userPrefsSubject(c)
+	_, cm, err := s.loadUserPrefs(context.TODO(), subject)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err := s.saveUserPrefs(context.TODO(), cm, subject, nil); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to reset user preferences: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User preferences reset",
+	})
+}
+
+// putFavorite stars a server for the caller
+func (s *Server) putFavorite(c *gin.Context) {
+	s.updateFavorites(c, true)
+}
+
+// deleteFavorite unstars a server for the caller
+func (s *Server) deleteFavorite(c *gin.Context) {
+	s.updateFavorites(c, false)
+}
+
+// updateFavorites adds or removes :namespace/:name from the favorites
+func (s *Server) updateFavorites(c *gin.Context, favorite bool) {
+	ref := c.Param("namespace") + "/" + c.Param("name")
+	if !serverRefPattern.MatchString(ref) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid server reference %q", ref),
+		})
+		return
+	}
+
+	subject := userPrefsSubject(c)
+	prefs, cm, err := s.loadUserPrefs(context.TODO(), subject)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	favorites := make([]string, 0, len(prefs.Favorites)+1)
+	for _, existing := range prefs.Favorites {
+		if existing != ref {
+			favorites = append(favorites, existing)
+		}
+	}
+	if favorite {
+		favorites = append(favorites, ref)
+	}
+	prefs.Favorites = favorites
+	if err := prefs.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	prefs.UpdatedAt = &now
+	if err := s.saveUserPrefs(context.TODO(), cm, subject, &prefs); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to save user preferences: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, prefs)
+}
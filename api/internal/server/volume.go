@@ -0,0 +1,354 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// gameDataMountPath is where the game server image keeps its data, and
+	// where volume tasks mount the same PVC
+	gameDataMountPath = "/home/kubelize/server"
+
+	// volumeTaskImage runs the shell scripts of volume tasks
+	volumeTaskImage = "busybox"
+
+	// volumeTaskLabel marks pods created for volume tasks
+	volumeTaskLabel = "gameplane.kubelize.io/volume-task"
+
+	// defaultVolumeTaskTimeout bounds how long a volume task may run
+	defaultVolumeTaskTimeout = 2 * time.Minute
+
+	// volumeTaskSourcePath is where a volume task mounts its source claim
+	volumeTaskSourcePath = "/source"
+)
+
+// errVolumeTasksUnavailable is returned in dev mode, where no pods run and a
+// volume task would never finish
+var errVolumeTasksUnavailable = errors.New("volume tasks need a real cluster and are not available in dev mode")
+
+// volumeTask describes a short-lived pod that runs a shell script against a
+// GameServer's data volume
+type volumeTask struct {
+	// Name is a short identifier used in the pod name (e.g. "read-config")
+	Name string
+	// Script runs under sh -c with the data volume mounted at gameDataMountPath
+	Script string
+	// Files are written to /tmp/task/<name> before the script runs
+	Files map[string][]byte
+	// ReadOnly mounts the data volume read-only
+	ReadOnly bool
+	// Timeout overrides defaultVolumeTaskTimeout
+	Timeout time.Duration
+	// Port is a TCP port the script listens on. The pod is only Ready once
+	// the port accepts connections.
+	Port int32
+	// Claim mounts another PVC of the managed namespace instead of the data
+	// volume, such as a scratch restore. The pod is then not pinned to the
+	// game server's node.
+	Claim string
+	// Source mounts another PVC of the managed namespace read-only at
+	// volumeTaskSourcePath, such as a backup to copy from
+	Source string
+}
+
+// runVolumeTask runs a script in a pod that mounts the GameServer's PVC and
+// returns the script's output. The volume is ReadWriteOnce, so the pod is
+// pinned to the node running the game server with pod affinity.
+func (s *Server) runVolumeTask(ctx context.Context, obj *unstructured.Unstructured, task volumeTask) (string, error) {
+	namespace, err := managedNamespace(obj)
+	if err != nil {
+		return "", err
+	}
+	return s.runVolumeTaskIn(ctx, namespace, task)
+}
+
+// runVolumeTaskIn runs a volume task in any namespace, such as the shared
+// asset library
+func (s *Server) runVolumeTaskIn(ctx context.Context, namespace string, task volumeTask) (string, error) {
+	if s.devCluster != nil {
+		return "", errVolumeTasksUnavailable
+	}
+	timeout := task.Timeout
+	if timeout == 0 {
+		timeout = defaultVolumeTaskTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	pods := s.kubeClient.CoreV1().Pods(namespace)
+	created, err := pods.Create(ctx, volumeTaskPod(namespace, task), metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to create volume task pod: %w", err)
+	}
+	defer func() {
+		// Use a fresh context so cleanup still happens after a timeout
+		_ = pods.Delete(context.Background(), created.Name, metav1.DeleteOptions{})
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		current, err := pods.Get(ctx, created.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", fmt.Errorf("failed to get volume task pod: %w", err)
+		}
+		if current.Status.Phase == corev1.PodSucceeded || current.Status.Phase == corev1.PodFailed {
+			raw, err := pods.GetLogs(created.Name, &corev1.PodLogOptions{}).DoRaw(ctx)
+			if err != nil {
+				return "", fmt.Errorf("failed to read volume task output: %w", err)
+			}
+			if current.Status.Phase == corev1.PodFailed {
+				return string(raw), fmt.Errorf("volume task %s failed: %s", task.Name, strings.TrimSpace(string(raw)))
+			}
+			return string(raw), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("volume task %s did not finish within %s", task.Name, timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// startVolumeServer starts a volume task whose script keeps serving on
+// task.Port and waits until it is Ready. It returns the pod name and a
+// function that deletes the pod; task.Timeout bounds only the startup.
+func (s *Server) startVolumeServer(ctx context.Context, obj *unstructured.Unstructured, task volumeTask) (string, func(), error) {
+	namespace, err := managedNamespace(obj)
+	if err != nil {
+		return "", nil, err
+	}
+	if task.Port == 0 {
+		return "", nil, fmt.Errorf("volume server %s has no port", task.Name)
+	}
+	if s.devCluster != nil {
+		return "", nil, errVolumeTasksUnavailable
+	}
+
+	pods := s.kubeClient.CoreV1().Pods(namespace)
+	created, err := pods.Create(ctx, volumeTaskPod(namespace, task), metav1.CreateOptions{})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create volume server pod: %w", err)
+	}
+	stop := func() {
+		_ = pods.Delete(context.Background(), created.Name, metav1.DeleteOptions{})
+	}
+
+	timeout := task.Timeout
+	if timeout == 0 {
+		timeout = defaultVolumeTaskTimeout
+	}
+	startCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		current, err := pods.Get(startCtx, created.Name, metav1.GetOptions{})
+		if err != nil {
+			stop()
+			return "", nil, fmt.Errorf("failed to get volume server pod: %w", err)
+		}
+		if current.Status.Phase == corev1.PodSucceeded || current.Status.Phase == corev1.PodFailed {
+			stop()
+			return "", nil, fmt.Errorf("volume server %s exited", task.Name)
+		}
+		for _, condition := range current.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+				return created.Name, stop, nil
+			}
+		}
+
+		select {
+		case <-startCtx.Done():
+			stop()
+			return "", nil, fmt.Errorf("volume server %s did not become ready within %s", task.Name, timeout)
+		case <-ticker.C:
+		}
+	}
+}
+
+// volumeTaskPod builds the pod for a volume task
+func volumeTaskPod(namespace string, task volumeTask) *corev1.Pod {
+	// Files are passed as base64 environment variables and decoded by a
+	// preamble so the task needs no ConfigMap of its own
+	var script strings.Builder
+	script.WriteString("set -e\nmkdir -p /tmp/task\n")
+	env := []corev1.EnvVar{}
+	i := 0
+	for name, content := range task.Files {
+		envName := fmt.Sprintf("TASK_FILE_%d", i)
+		env = append(env, corev1.EnvVar{Name: envName, Value: base64.StdEncoding.EncodeToString(content)})
+		fmt.Fprintf(&script, "echo \"$%s\" | base64 -d > /tmp/task/%s\n", envName, name)
+		i++
+	}
+	script.WriteString(task.Script)
+
+	claim := task.Claim
+	if claim == "" {
+		claim = dataVolumeName(namespace)
+	}
+	runAsUser := int64(1000)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-%s-", namespace, task.Name),
+			Namespace:    namespace,
+			Labels: map[string]string{
+				volumeTaskLabel:        task.Name,
+				"kubelize.io/task-for": namespace,
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Affinity: &corev1.Affinity{
+				PodAffinity: &corev1.PodAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+						LabelSelector: &metav1.LabelSelector{
+							MatchLabels: map[string]string{"kubelize.io/gameserver": namespace},
+						},
+						TopologyKey: "kubernetes.io/hostname",
+					}},
+				},
+			},
+			SecurityContext: &corev1.PodSecurityContext{
+				RunAsUser:  &runAsUser,
+				RunAsGroup: &runAsUser,
+			},
+			Containers: []corev1.Container{{
+				Name:    "task",
+				Image:   volumeTaskImage,
+				Command: []string{"sh", "-c", script.String()},
+				Env:     env,
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "game-data",
+					MountPath: gameDataMountPath,
+					ReadOnly:  task.ReadOnly,
+				}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "game-data",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: claim,
+						ReadOnly:  task.ReadOnly,
+					},
+				},
+			}},
+		},
+	}
+
+	if task.Claim != "" {
+		pod.Spec.Affinity = nil
+	}
+	if task.Source != "" {
+		container := &pod.Spec.Containers[0]
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "source",
+			MountPath: volumeTaskSourcePath,
+			ReadOnly:  true,
+		})
+		pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+			Name: "source",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: task.Source,
+					ReadOnly:  true,
+				},
+			},
+		})
+	}
+	if task.Port != 0 {
+		container := &pod.Spec.Containers[0]
+		container.Ports = []corev1.ContainerPort{{Name: "task", ContainerPort: task.Port}}
+		container.ReadinessProbe = &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt32(task.Port)},
+			},
+			PeriodSeconds: 1,
+		}
+	}
+	return pod
+}
+
+// readGameFile reads a file below the game data directory
+func (s *Server) readGameFile(ctx context.Context, obj *unstructured.Unstructured, relative string) ([]byte, error) {
+	target, err := gameDataPath(relative)
+	if err != nil {
+		return nil, err
+	}
+	out, err := s.runVolumeTask(ctx, obj, volumeTask{
+		Name:     "read-file",
+		Script:   fmt.Sprintf("cat %s", shellQuote(target)),
+		ReadOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+// missingFileMarker is printed by readOptionalGameFile when the file is absent
+const missingFileMarker = "__gameplane_file_not_found__"
+
+// readOptionalGameFile reads a file below the game data directory, reporting
+// whether it exists instead of failing when it does not
+func (s *Server) readOptionalGameFile(ctx context.Context, obj *unstructured.Unstructured, relative string) ([]byte, bool, error) {
+	target, err := gameDataPath(relative)
+	if err != nil {
+		return nil, false, err
+	}
+	quoted := shellQuote(target)
+	out, err := s.runVolumeTask(ctx, obj, volumeTask{
+		Name:     "read-file",
+		Script:   fmt.Sprintf("if [ -f %s ]; then cat %s; else printf '%%s' %s; fi", quoted, quoted, missingFileMarker),
+		ReadOnly: true,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if out == missingFileMarker {
+		return nil, false, nil
+	}
+	return []byte(out), true, nil
+}
+
+// writeGameFile replaces a file below the game data directory
+func (s *Server) writeGameFile(ctx context.Context, obj *unstructured.Unstructured, relative string, content []byte) error {
+	target, err := gameDataPath(relative)
+	if err != nil {
+		return err
+	}
+	quoted := shellQuote(target)
+	_, err = s.runVolumeTask(ctx, obj, volumeTask{
+		Name:   "write-file",
+		Files:  map[string][]byte{"content": content},
+		Script: fmt.Sprintf("mkdir -p \"$(dirname %s)\"\ncp /tmp/task/content %s\n", quoted, quoted),
+	})
+	return err
+}
+
+// gameDataPath resolves a path relative to the game data directory, refusing
+// paths that escape it
+func gameDataPath(relative string) (string, error) {
+	cleaned := path.Clean("/" + relative)
+	if cleaned == "/" {
+		return "", fmt.Errorf("invalid game data path %q", relative)
+	}
+	return gameDataMountPath + cleaned, nil
+}
+
+// shellQuote quotes a string for safe use in a POSIX shell script
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
@@ -0,0 +1,547 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// whitelistAnnotation holds a GameServer's WhitelistConfig as JSON
+	whitelistAnnotation = "gameplane.kubelize.io/whitelist"
+
+	// whitelistStatusAnnotation holds the WhitelistStatus of the last sync
+	whitelistStatusAnnotation = "gameplane.kubelize.io/whitelist-status"
+
+	// whitelistCheckInterval is how often whitelists are checked for a due sync
+	whitelistCheckInterval = time.Minute
+
+	// defaultWhitelistRefresh and minWhitelistRefresh bound how often a
+	// whitelist's sources are read
+	defaultWhitelistRefresh = 15 * time.Minute
+	minWhitelistRefresh     = time.Minute
+
+	// whitelistSyncTimeout bounds reading the sources and writing the file
+	whitelistSyncTimeout = 5 * time.Minute
+)
+
+// Whitelist source types
+const (
+	whitelistSourceSteamGroup  = "steam-group"
+	whitelistSourceDiscordRole = "discord-role"
+	whitelistSourceURL         = "url"
+)
+
+// WhitelistConfig points a GameServer's whitelist at external sources
+type WhitelistConfig struct {
+	Sources []WhitelistSource `json:"sources"`
+	// Allow are whitelisted whatever the sources say
+	Allow []PlayerEntry `json:"allow,omitempty"`
+	// Deny are kept off the whitelist even when a source lists them
+	Deny []PlayerEntry `json:"deny,omitempty"`
+	// Refresh is how often the sources are read (default 15m)
+	Refresh string `json:"refresh,omitempty"`
+	// ClaimSlots lets this many players logged in with Steam add themselves
+	ClaimSlots int `json:"claimSlots,omitempty"`
+	// Claims are the players who claimed a slot
+	Claims []PlayerEntry `json:"claims,omitempty"`
+}
+
+// WhitelistSource is one external list of players
+type WhitelistSource struct {
+	Type string `json:"type" binding:"required"`
+	// Group is a Steam group's URL name or groupID64
+	Group string `json:"group,omitempty"`
+	// Guild and Role select Discord members holding the role
+	Guild string `json:"guild,omitempty"`
+	Role  string `json:"role,omitempty"`
+	// TokenSecret names a Secret in the GameServer's namespace holding the
+	// Discord bot token under "token"
+	TokenSecret string `json:"tokenSecret,omitempty"`
+	// URL serves a list of player IDs
+	URL string `json:"url,omitempty"`
+	// Platform of the listed IDs; Discord members are looked up in the
+	// player links under it (default Steam)
+	Platform string `json:"platform,omitempty"`
+}
+
+// WhitelistStatus records the outcome of the last sync
+type WhitelistStatus struct {
+	LastSync time.Time `json:"lastSync"`
+	// LastChange is when the sync last rewrote the whitelist file
+	LastChange *time.Time             `json:"lastChange,omitempty"`
+	Entries    int                    `json:"entries"`
+	Sources    []WhitelistSourceState `json:"sources"`
+	// RestartRequired is set when the file changed and the game only reads
+	// it on start
+	RestartRequired bool   `json:"restartRequired,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// WhitelistSourceState is what one source contributed to the last sync
+type WhitelistSourceState struct {
+	Source  string `json:"source"`
+	Entries int    `json:"entries"`
+	// Unlinked counts Discord members without a linked game account
+	Unlinked int    `json:"unlinked,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// whitelistSyncState keeps a whitelist from being synced twice at once
+type whitelistSyncState struct {
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// errWhitelistSyncing is returned while another sync of the same server runs
+var errWhitelistSyncing = errors.New("a whitelist sync is already running")
+
+// getWhitelist returns the whitelist sources, the last sync and the entries
+// currently in the game's file
+func (s *Server) getWhitelist(c *gin.Context) {
+	obj, def, ok := s.loadGameServerWhitelist(c)
+	if !ok {
+		return
+	}
+	config, err := whitelistConfig(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response := gin.H{
+		"config": config,
+		"status": whitelistStatus(obj),
+		"file":   def.Whitelist.Path,
+		"format": def.Whitelist.Format,
+	}
+	// The file is read through a helper pod; a server that cannot be read
+	// right now still reports its configuration
+	content, exists, err := s.readOptionalGameFile(context.TODO(), obj, def.Whitelist.Path)
+	if err == nil {
+		entries := []PlayerEntry{}
+		if exists {
+			entries, err = parsePlayerList(def.Whitelist, content)
+		}
+		response["entries"] = entries
+	}
+	if err != nil {
+		response["fileError"] = fmt.Sprintf("Failed to read whitelist: %v", err)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// putWhitelist stores the whitelist sources and syncs right away
+func (s *Server) putWhitelist(c *gin.Context) {
+	var config WhitelistConfig
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+
+	obj, def, ok := s.loadGameServerWhitelist(c)
+	if !ok {
+		return
+	}
+	// Claims are made by players; keep them unless the request lists them
+	if config.Claims == nil {
+		if previous, err := whitelistConfig(obj); err == nil && previous != nil {
+			config.Claims = previous.Claims
+		}
+	}
+	if err := validateWhitelistConfig(def.Whitelist, config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	raw, err := json.Marshal(config)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[whitelistAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update whitelist: %v", err),
+		})
+		return
+	}
+
+	response := gin.H{"config": config}
+	status, err := s.syncWhitelist(c.Request.Context(), obj)
+	response["status"] = status
+	if err != nil {
+		response["syncError"] = fmt.Sprintf("Whitelist stored, but the sync failed: %v", err)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// deleteWhitelist stops syncing the whitelist, leaving the file as it is
+func (s *Server) deleteWhitelist(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	annotations := obj.GetAnnotations()
+	if _, found := annotations[whitelistAnnotation]; !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "GameServer has no whitelist sync",
+		})
+		return
+	}
+	delete(annotations, whitelistAnnotation)
+	delete(annotations, whitelistStatusAnnotation)
+	obj.SetAnnotations(annotations)
+
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete whitelist sync: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Whitelist sync removed; the whitelist file was left unchanged",
+	})
+}
+
+// syncWhitelistNow reads the sources and rewrites the whitelist immediately
+func (s *Server) syncWhitelistNow(c *gin.Context) {
+	obj, _, ok := s.loadGameServerWhitelist(c)
+	if !ok {
+		return
+	}
+	if _, found := obj.GetAnnotations()[whitelistAnnotation]; !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "GameServer has no whitelist sync",
+		})
+		return
+	}
+
+	status, err := s.syncWhitelist(c.Request.Context(), obj)
+	if errors.Is(err, errWhitelistSyncing) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":  fmt.Sprintf("Whitelist sync failed: %v", err),
+			"status": status,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// loadGameServerWhitelist fetches the GameServer and its game's whitelist
+// definition, writing the error response itself when either is missing
+func (s *Server) loadGameServerWhitelist(c *gin.Context) (*unstructured.Unstructured, GameDefinition, bool) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return nil, GameDefinition{}, false
+	}
+
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, found := lookupGame(gameType)
+	if !found || def.Whitelist == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("Game type %s does not support a whitelist", gameType),
+		})
+		return nil, GameDefinition{}, false
+	}
+	return obj, def, true
+}
+
+// validateWhitelistConfig checks the sources and manual entries of a config
+func validateWhitelistConfig(list *PlayerList, config WhitelistConfig) error {
+	if len(config.Sources) == 0 && len(config.Allow) == 0 && config.ClaimSlots == 0 {
+		return fmt.Errorf("at least one source, allow entry or claim slot is required")
+	}
+	if config.ClaimSlots < 0 {
+		return fmt.Errorf("claimSlots must not be negative")
+	}
+	// Claims are made with Steam logins, which Minecraft's UUIDs cannot match
+	if list.Format == "whitelist-json" && config.ClaimSlots > 0 {
+		return fmt.Errorf("claim slots cannot be used with this game's whitelist")
+	}
+	for _, src := range config.Sources {
+		if err := src.validate(); err != nil {
+			return err
+		}
+		// Minecraft lists players by UUID, which Steam groups cannot provide
+		if list.Format == "whitelist-json" && src.Type == whitelistSourceSteamGroup {
+			return fmt.Errorf("steam-group sources cannot be used with this game's whitelist")
+		}
+	}
+	for _, entry := range append(append(append([]PlayerEntry{}, config.Allow...), config.Deny...), config.Claims...) {
+		if err := validatePlayerEntry(entry); err != nil {
+			return err
+		}
+	}
+	if config.Refresh != "" {
+		d, err := time.ParseDuration(config.Refresh)
+		if err != nil || d < minWhitelistRefresh {
+			return fmt.Errorf("refresh must be a duration of at least %s", minWhitelistRefresh)
+		}
+	}
+	return nil
+}
+
+// syncWhitelist reads every source of the GameServer's whitelist, merges
+// them with the manual entries and writes the game's file when the result
+// changed. A failing source leaves the file untouched rather than dropping
+// its players. The outcome is recorded on the GameServer either way.
+func (s *Server) syncWhitelist(ctx context.Context, obj *unstructured.Unstructured) (*WhitelistStatus, error) {
+	key := s.cluster + "/" + obj.GetNamespace() + "/" + obj.GetName()
+	s.whitelists.mu.Lock()
+	if s.whitelists.running == nil {
+		s.whitelists.running = map[string]bool{}
+	}
+	if s.whitelists.running[key] {
+		s.whitelists.mu.Unlock()
+		return nil, errWhitelistSyncing
+	}
+	s.whitelists.running[key] = true
+	s.whitelists.mu.Unlock()
+	defer func() {
+		s.whitelists.mu.Lock()
+		delete(s.whitelists.running, key)
+		s.whitelists.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, whitelistSyncTimeout)
+	defer cancel()
+
+	previous := whitelistStatus(obj)
+	status := &WhitelistStatus{LastSync: time.Now().UTC(), Sources: []WhitelistSourceState{}}
+	if previous != nil {
+		status.LastChange = previous.LastChange
+	}
+	err := s.buildWhitelist(ctx, obj, status)
+	if err != nil {
+		status.Error = err.Error()
+	}
+	if recordErr := s.recordWhitelistStatus(ctx, obj, status); recordErr != nil {
+		log.Printf("Failed to record whitelist status of %s/%s: %v", obj.GetNamespace(), obj.GetName(), recordErr)
+	}
+	return status, err
+}
+
+// buildWhitelist does the work of syncWhitelist, filling in status
+func (s *Server) buildWhitelist(ctx context.Context, obj *unstructured.Unstructured, status *WhitelistStatus) error {
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, found := lookupGame(gameType)
+	if !found || def.Whitelist == nil {
+		return fmt.Errorf("game type %s does not support a whitelist", gameType)
+	}
+	config, err := whitelistConfig(obj)
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		return fmt.Errorf("GameServer has no whitelist sync")
+	}
+
+	merged := map[string]PlayerEntry{}
+	failed := 0
+	for _, src := range config.Sources {
+		state := WhitelistSourceState{Source: src.String()}
+		result, err := s.fetchWhitelistSource(ctx, obj.GetNamespace(), src)
+		if err != nil {
+			state.Error = err.Error()
+			failed++
+		} else {
+			state.Entries = len(result.Entries)
+			state.Unlinked = result.Unlinked
+			for _, entry := range result.Entries {
+				if validatePlayerEntry(entry) != nil {
+					continue
+				}
+				if _, seen := merged[entry.key()]; !seen {
+					merged[entry.key()] = entry
+				}
+			}
+		}
+		status.Sources = append(status.Sources, state)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sources failed; the whitelist was left unchanged", failed, len(config.Sources))
+	}
+
+	// Manual entries win over the sources and claims, so their names are kept
+	for _, entry := range config.Claims {
+		merged[entry.key()] = entry
+	}
+	for _, entry := range config.Allow {
+		merged[entry.key()] = entry
+	}
+	for _, entry := range config.Deny {
+		delete(merged, entry.key())
+	}
+	entries := make([]PlayerEntry, 0, len(merged))
+	for _, entry := range merged {
+		entries = append(entries, entry)
+	}
+	sortPlayers(entries)
+	status.Entries = len(entries)
+
+	// serveradmin.xml also holds admins and permissions, which must survive
+	existing, _, err := s.readOptionalGameFile(ctx, obj, def.Whitelist.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read whitelist: %w", err)
+	}
+	content, err := renderPlayerList(def.Whitelist, existing, entries)
+	if err != nil {
+		return fmt.Errorf("failed to render whitelist: %w", err)
+	}
+	if bytes.Equal(content, existing) {
+		return nil
+	}
+	if err := s.writeGameFile(ctx, obj, def.Whitelist.Path, content); err != nil {
+		return fmt.Errorf("failed to write whitelist: %w", err)
+	}
+	changed := status.LastSync
+	status.LastChange = &changed
+	status.RestartRequired = !def.Whitelist.LiveReload && def.Whitelist.ReloadCommand == ""
+	s.reloadPlayerList(ctx, obj, def.Whitelist)
+	return nil
+}
+
+// reloadPlayerList makes the running game re-read a rewritten list file when
+// it has a command for that
+func (s *Server) reloadPlayerList(ctx context.Context, obj *unstructured.Unstructured, list *PlayerList) {
+	if list.ReloadCommand == "" {
+		return
+	}
+	console, err := s.openGameConsole(ctx, obj)
+	if err == nil {
+		_, err = console.Exec(list.ReloadCommand)
+		console.Close()
+	}
+	if err != nil && !errors.Is(err, errConsoleUnsupported) {
+		// A stopped server reads the new file when it starts
+		log.Printf("Failed to reload %s of %s/%s: %v", list.Path, obj.GetNamespace(), obj.GetName(), err)
+	}
+}
+
+// recordWhitelistStatus stores the sync outcome on the current GameServer
+func (s *Server) recordWhitelistStatus(ctx context.Context, obj *unstructured.Unstructured, status *WhitelistStatus) error {
+	raw, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	// Re-read the GameServer, it may have changed during the sync
+	current, err := s.getGameServerObject(ctx, obj.GetNamespace(), obj.GetName())
+	if err != nil {
+		return err
+	}
+	annotations := current.GetAnnotations()
+	if _, found := annotations[whitelistAnnotation]; !found {
+		return nil
+	}
+	annotations[whitelistStatusAnnotation] = string(raw)
+	current.SetAnnotations(annotations)
+	return s.k8sClient.Update(ctx, current)
+}
+
+// syncDueWhitelists syncs every whitelist whose refresh interval has passed
+// since its last sync
+func (s *Server) syncDueWhitelists(ctx context.Context) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+
+	now := time.Now()
+	for i := range list.Items {
+		obj := &list.Items[i]
+		config, err := whitelistConfig(obj)
+		if err != nil || config == nil {
+			continue
+		}
+		refresh := defaultWhitelistRefresh
+		if d, err := time.ParseDuration(config.Refresh); err == nil && d >= minWhitelistRefresh {
+			refresh = d
+		}
+		if status := whitelistStatus(obj); status != nil && now.Sub(status.LastSync) < refresh {
+			continue
+		}
+		if _, err := s.syncWhitelist(ctx, obj); err != nil && !errors.Is(err, errWhitelistSyncing) {
+			log.Printf("Whitelist sync of %s/%s failed: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// whitelistConfig reads the GameServer's whitelist sources, or nil when the
+// whitelist is not synced
+func whitelistConfig(obj *unstructured.Unstructured) (*WhitelistConfig, error) {
+	raw, ok := obj.GetAnnotations()[whitelistAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var config WhitelistConfig
+	if err := json.Unmarshal([]byte(raw), &config); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", whitelistAnnotation, err)
+	}
+	return &config, nil
+}
+
+// setWhitelistConfig stores config in the GameServer's whitelist annotation
+func setWhitelistConfig(obj *unstructured.Unstructured, config *WhitelistConfig) error {
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[whitelistAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// whitelistStatus reads the outcome of the last sync, or nil before the first
+func whitelistStatus(obj *unstructured.Unstructured) *WhitelistStatus {
+	raw, ok := obj.GetAnnotations()[whitelistStatusAnnotation]
+	if !ok {
+		return nil
+	}
+	var status WhitelistStatus
+	if err := json.Unmarshal([]byte(raw), &status); err != nil {
+		return nil
+	}
+	return &status
+}
@@ -0,0 +1,300 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// playerLinksConfigMap maps Discord users to their game accounts, one
+	// key per Discord user ID holding a JSON object of platform to ID
+	playerLinksConfigMap = "gameplane-player-links"
+
+	// maxWhitelistSourceBytes bounds a fetched source
+	maxWhitelistSourceBytes = 4 << 20
+
+	// maxSteamGroupPages bounds the member pages read from a Steam group,
+	// at 1000 members each
+	maxSteamGroupPages = 100
+)
+
+// Endpoints of the services sources are read from
+var (
+	steamCommunityURL = "https://steamcommunity.com"
+	discordAPIURL     = "https://discord.com/api/v10"
+)
+
+// whitelistClient fetches whitelist sources
+var whitelistClient = &http.Client{Timeout: 30 * time.Second}
+
+// sourceResult is what one source contributed to a sync
+type sourceResult struct {
+	Entries []PlayerEntry
+	// Unlinked counts Discord members without a linked game account
+	Unlinked int
+}
+
+// validate checks a source has the fields its type needs
+func (src WhitelistSource) validate() error {
+	switch src.Type {
+	case whitelistSourceSteamGroup:
+		if src.Group == "" || strings.ContainsAny(src.Group, "/?# ") {
+			return fmt.Errorf("steam-group sources need a group URL name or ID")
+		}
+	case whitelistSourceDiscordRole:
+		if src.Guild == "" || src.Role == "" || src.TokenSecret == "" {
+			return fmt.Errorf("discord-role sources need guild, role and tokenSecret")
+		}
+	case whitelistSourceURL:
+		u, err := url.Parse(src.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("url sources need an http or https URL")
+		}
+	default:
+		return fmt.Errorf("unknown source type %q (valid: %s, %s, %s)", src.Type, whitelistSourceSteamGroup, whitelistSourceDiscordRole, whitelistSourceURL)
+	}
+	return nil
+}
+
+// String describes a source in sync status
+func (src WhitelistSource) String() string {
+	switch src.Type {
+	case whitelistSourceSteamGroup:
+		return "steam-group " + src.Group
+	case whitelistSourceDiscordRole:
+		return fmt.Sprintf("discord-role %s/%s", src.Guild, src.Role)
+	}
+	return src.Type + " " + src.URL
+}
+
+// platform is the platform of the IDs the source yields
+func (src WhitelistSource) platform() string {
+	if src.Platform != "" {
+		return src.Platform
+	}
+	return "Steam"
+}
+
+// fetchWhitelistSource reads the players a source lists. namespace is the
+// GameServer's, where Discord bot tokens are kept.
+func (s *Server) fetchWhitelistSource(ctx context.Context, namespace string, src WhitelistSource) (*sourceResult, error) {
+	switch src.Type {
+	case whitelistSourceSteamGroup:
+		return fetchSteamGroup(ctx, src)
+	case whitelistSourceDiscordRole:
+		return s.fetchDiscordRole(ctx, namespace, src)
+	case whitelistSourceURL:
+		return fetchPlayerURL(ctx, src)
+	}
+	return nil, src.validate()
+}
+
+// fetchSteamGroup reads the members of a public Steam group
+func fetchSteamGroup(ctx context.Context, src WhitelistSource) (*sourceResult, error) {
+	base := steamCommunityURL + "/groups/" + url.PathEscape(src.Group)
+	if isDigits(src.Group) {
+		base = steamCommunityURL + "/gid/" + src.Group
+	}
+
+	result := &sourceResult{}
+	for page := 1; page <= maxSteamGroupPages; page++ {
+		body, err := fetchSourceBody(ctx, fmt.Sprintf("%s/memberslistxml/?xml=1&p=%d", base, page), nil)
+		if err != nil {
+			return nil, err
+		}
+		var list struct {
+			TotalPages int      `xml:"totalPages"`
+			Members    []string `xml:"members>steamID64"`
+		}
+		if err := xml.Unmarshal(body, &list); err != nil {
+			return nil, fmt.Errorf("unexpected Steam group response (is the group public?): %w", err)
+		}
+		for _, id := range list.Members {
+			result.Entries = append(result.Entries, PlayerEntry{ID: id, Platform: src.platform()})
+		}
+		if page >= list.TotalPages {
+			break
+		}
+	}
+	return result, nil
+}
+
+// fetchDiscordRole reads the members holding a Discord role through a bot,
+// which needs the Server Members intent, and maps them to game accounts
+// through the player links
+func (s *Server) fetchDiscordRole(ctx context.Context, namespace string, src WhitelistSource) (*sourceResult, error) {
+	secret, err := s.kubeClient.CoreV1().Secrets(namespace).Get(ctx, src.TokenSecret, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bot token %s/%s: %w", namespace, src.TokenSecret, err)
+	}
+	token := strings.TrimSpace(string(secret.Data["token"]))
+	if token == "" {
+		return nil, fmt.Errorf("secret %s/%s has no token key", namespace, src.TokenSecret)
+	}
+	links, err := s.playerLinks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &sourceResult{}
+	header := http.Header{"Authorization": []string{"Bot " + token}}
+	after := "0"
+	for {
+		body, err := fetchSourceBody(ctx, fmt.Sprintf("%s/guilds/%s/members?limit=1000&after=%s", discordAPIURL, url.PathEscape(src.Guild), after), header)
+		if err != nil {
+			return nil, err
+		}
+		var members []struct {
+			User struct {
+				ID         string `json:"id"`
+				Username   string `json:"username"`
+				GlobalName string `json:"global_name"`
+			} `json:"user"`
+			Nick  string   `json:"nick"`
+			Roles []string `json:"roles"`
+		}
+		if err := json.Unmarshal(body, &members); err != nil {
+			return nil, fmt.Errorf("unexpected Discord response: %w", err)
+		}
+		for _, member := range members {
+			after = member.User.ID
+			if !containsString(member.Roles, src.Role) {
+				continue
+			}
+			id := links[member.User.ID][src.platform()]
+			if id == "" {
+				result.Unlinked++
+				continue
+			}
+			name := member.Nick
+			if name == "" {
+				name = member.User.GlobalName
+			}
+			if name == "" {
+				name = member.User.Username
+			}
+			result.Entries = append(result.Entries, PlayerEntry{ID: id, Platform: src.platform(), Name: name})
+		}
+		if len(members) < 1000 {
+			return result, nil
+		}
+	}
+}
+
+// fetchPlayerURL reads a list of IDs: a JSON array of IDs or of entries, or
+// text with one ID per line, optionally followed by a name
+func fetchPlayerURL(ctx context.Context, src WhitelistSource) (*sourceResult, error) {
+	body, err := fetchSourceBody(ctx, src.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &sourceResult{}
+	if trimmed := bytes.TrimSpace(body); bytes.HasPrefix(trimmed, []byte("[")) {
+		var items []json.RawMessage
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, fmt.Errorf("invalid JSON list: %w", err)
+		}
+		for _, item := range items {
+			var id string
+			if json.Unmarshal(item, &id) == nil {
+				result.Entries = append(result.Entries, PlayerEntry{ID: id, Platform: src.platform()})
+				continue
+			}
+			var entry PlayerEntry
+			if err := json.Unmarshal(item, &entry); err != nil {
+				return nil, fmt.Errorf("invalid JSON list entry %s", item)
+			}
+			if entry.Platform == "" {
+				entry.Platform = src.platform()
+			}
+			result.Entries = append(result.Entries, entry)
+		}
+		return result, nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		id, name, _ := strings.Cut(line, " ")
+		result.Entries = append(result.Entries, PlayerEntry{ID: id, Platform: src.platform(), Name: strings.TrimSpace(name)})
+	}
+	return result, scanner.Err()
+}
+
+// fetchSourceBody GETs a source, refusing error statuses and oversized bodies
+func fetchSourceBody(ctx context.Context, target string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := whitelistClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s answered %s", req.URL.Host, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxWhitelistSourceBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxWhitelistSourceBytes {
+		return nil, fmt.Errorf("%s returned more than %d bytes", req.URL.Host, maxWhitelistSourceBytes)
+	}
+	return body, nil
+}
+
+// playerLinks reads the installation's Discord account links from the
+// cluster registry namespace
+func (s *Server) playerLinks(ctx context.Context) (map[string]map[string]string, error) {
+	store, err := s.clusters.clusterServer("")
+	if err != nil {
+		return nil, err
+	}
+	links := map[string]map[string]string{}
+	cm, err := store.kubeClient.CoreV1().ConfigMaps(s.clusters.namespace).Get(ctx, playerLinksConfigMap, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return links, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read player links: %w", err)
+	}
+	for user, raw := range cm.Data {
+		accounts := map[string]string{}
+		if err := json.Unmarshal([]byte(raw), &accounts); err != nil {
+			continue
+		}
+		links[user] = accounts
+	}
+	return links, nil
+}
+
+// isDigits reports whether value is a non-empty run of ASCII digits
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
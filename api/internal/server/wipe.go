@@ -0,0 +1,518 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// wipePolicyAnnotation holds a GameServer's WipePolicy as JSON
+	wipePolicyAnnotation = "gameplane.kubelize.io/wipe-policy"
+
+	// lastWipeAnnotation holds the WipeRecord of the most recent wipe
+	lastWipeAnnotation = "gameplane.kubelize.io/last-wipe"
+
+	// wipeSchedulerInterval is how often wipe policies are evaluated
+	wipeSchedulerInterval = 30 * time.Second
+
+	// wipeBackupDir is where wipe backups are kept on the data volume
+	wipeBackupDir = ".gameplane/backups"
+
+	// wipeTimeout bounds archiving and deleting world data
+	wipeTimeout = 15 * time.Minute
+)
+
+// defaultWipeAnnouncements are sent before a scheduled wipe
+var defaultWipeAnnouncements = []string{"1h", "15m", "5m", "1m"}
+
+// errWipeUnsupported is returned for games without known world data paths
+var errWipeUnsupported = errors.New("game type does not support world wipes")
+
+// WipePolicy schedules recurring or one-off world wipes for a GameServer
+type WipePolicy struct {
+	Enabled  bool     `json:"enabled"`
+	Schedule Schedule `json:"schedule"`
+	// SkipBackup wipes without archiving the world first
+	SkipBackup bool `json:"skipBackup,omitempty"`
+	// NewSeed regenerates the world with a random seed, where supported
+	NewSeed bool `json:"newSeed,omitempty"`
+	// Announce lists durations before the wipe to warn players
+	Announce []string `json:"announce,omitempty"`
+}
+
+// WipeRecord describes a completed wipe
+type WipeRecord struct {
+	Time    time.Time `json:"time"`
+	Trigger string    `json:"trigger"` // manual or scheduled
+	Backup  string    `json:"backup,omitempty"`
+	// Snapshot is the safety VolumeSnapshot taken before the wipe
+	Snapshot string   `json:"snapshot,omitempty"`
+	Seed     string   `json:"seed,omitempty"`
+	Paths    []string `json:"paths"`
+	Pods     []string `json:"pods,omitempty"`
+}
+
+// wipeOptions controls a single wipe
+type wipeOptions struct {
+	Backup bool
+	// NewSeed picks a random seed unless World sets one
+	NewSeed bool
+	// World overrides world parameters for the fresh world
+	World   *GameServerWorld
+	Trigger string
+}
+
+// wipeSchedulerState remembers when each policy was last evaluated so
+// announcements and wipes fire once per window
+type wipeSchedulerState struct {
+	mu        sync.Mutex
+	lastCheck map[string]time.Time
+	running   map[string]bool
+}
+
+// getWipe returns the wipe policy, next scheduled wipe and last wipe
+func (s *Server) getWipe(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	policy, err := wipePolicy(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	response := gin.H{
+		"policy": policy,
+	}
+	if policy != nil && policy.Enabled {
+		if next, ok := policy.Schedule.next(time.Now()); ok {
+			response["nextWipe"] = next
+		}
+	}
+	if raw, ok := obj.GetAnnotations()[lastWipeAnnotation]; ok {
+		var record WipeRecord
+		if err := json.Unmarshal([]byte(raw), &record); err == nil {
+			response["lastWipe"] = record
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// putWipePolicy stores the GameServer's wipe policy
+func (s *Server) putWipePolicy(c *gin.Context) {
+	var policy WipePolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if err := policy.Schedule.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	for _, announce := range policy.Announce {
+		if d, err := time.ParseDuration(announce); err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid announce duration %q", announce),
+			})
+			return
+		}
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	if def, found := lookupGame(gameType); !found || def.Wipe == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Game type %s does not support world wipes", gameType),
+		})
+		return
+	}
+
+	raw, err := json.Marshal(policy)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[wipePolicyAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to update wipe policy: %v", err),
+		})
+		return
+	}
+
+	response := gin.H{
+		"policy": policy,
+	}
+	if next, ok := policy.Schedule.next(time.Now()); ok && policy.Enabled {
+		response["nextWipe"] = next
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// deleteWipePolicy removes the GameServer's wipe policy
+func (s *Server) deleteWipePolicy(c *gin.Context) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	annotations := obj.GetAnnotations()
+	if _, found := annotations[wipePolicyAnnotation]; !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "GameServer has no wipe policy",
+		})
+		return
+	}
+	delete(annotations, wipePolicyAnnotation)
+	obj.SetAnnotations(annotations)
+
+	if err := s.k8sClient.Update(context.TODO(), obj); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete wipe policy: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Wipe policy deleted",
+	})
+}
+
+// wipeGameServer wipes the world immediately
+func (s *Server) wipeGameServer(c *gin.Context) {
+	req := struct {
+		Backup  *bool  `json:"backup"`
+		NewSeed bool   `json:"newSeed"`
+		Seed    string `json:"seed"`
+	}{}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid request body: %v", err),
+			})
+			return
+		}
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+	if req.Seed != "" {
+		gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+		def, _ := lookupGame(gameType)
+		if _, err := applyWorldToGameConfig(def, nil, &GameServerWorld{Seed: req.Seed}); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	record, err := s.wipeWorld(context.TODO(), obj, wipeOptions{
+		Backup:  req.Backup == nil || *req.Backup,
+		NewSeed: req.NewSeed,
+		World:   &GameServerWorld{Seed: req.Seed},
+		Trigger: "manual",
+	})
+	if err != nil {
+		status := errorStatus(c, err)
+		if errors.Is(err, errWipeUnsupported) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error": fmt.Sprintf("Failed to wipe GameServer: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+// wipeWorld archives and deletes a GameServer's world data, optionally sets
+// a new seed, and force-restarts the game so it starts a fresh world
+func (s *Server) wipeWorld(ctx context.Context, obj *unstructured.Unstructured, opts wipeOptions) (*WipeRecord, error) {
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, ok := lookupGame(gameType)
+	if !ok || def.Wipe == nil {
+		return nil, errWipeUnsupported
+	}
+
+	record := &WipeRecord{Time: time.Now().UTC(), Trigger: opts.Trigger}
+
+	operation := "wipe"
+	if opts.Trigger == "regenerate" {
+		operation = "regenerate"
+	}
+	snapshot, err := s.safetySnapshot(ctx, obj, operation, "")
+	if err != nil {
+		return nil, fmt.Errorf("safety snapshot failed: %w", err)
+	}
+	if snapshot != nil {
+		record.Snapshot = snapshot.Name
+	}
+
+	// Tell anyone still online; the console may be disabled so this is best effort
+	if _, err := s.broadcastInGame(ctx, obj, "Server wipe starting now"); err != nil && !errors.Is(err, errConsoleUnsupported) {
+		log.Printf("Wipe announcement for %s/%s failed: %v", obj.GetNamespace(), obj.GetName(), err)
+	}
+
+	// Write the new world settings first so the rendered config has them by
+	// the restart
+	world := GameServerWorld{}
+	if opts.World != nil {
+		world = *opts.World
+	}
+	if opts.NewSeed && world.Seed == "" && def.World != nil && def.World.SeedField != "" {
+		world.Seed = randomSeed()
+	}
+	if world != (GameServerWorld{}) {
+		spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+		gameConfig, _, _ := unstructured.NestedMap(spec, "gameConfig")
+		gameConfig, err := applyWorldToGameConfig(def, gameConfig, &world)
+		if err != nil {
+			return nil, err
+		}
+		spec["gameConfig"] = gameConfig
+		if _, err := s.writeGameServerSpec(ctx, obj, spec); err != nil {
+			return nil, fmt.Errorf("failed to update world settings: %w", err)
+		}
+		record.Seed = world.Seed
+	}
+
+	// Globs come from the catalog and are left unquoted so the shell expands them
+	var script strings.Builder
+	fmt.Fprintf(&script, "cd %s\nset --\n", gameDataMountPath)
+	fmt.Fprintf(&script, "for p in %s; do if [ -e \"$p\" ]; then set -- \"$@\" \"${p%%/}\"; fi; done\n", strings.Join(def.Wipe.Paths, " "))
+	script.WriteString("if [ $# -eq 0 ]; then exit 0; fi\n")
+	if opts.Backup {
+		backup := fmt.Sprintf("%s/wipe-%s.tar.gz", wipeBackupDir, record.Time.Format("20060102T150405Z"))
+		fmt.Fprintf(&script, "mkdir -p %s\ntar czf %s \"$@\"\n", wipeBackupDir, backup)
+		record.Backup = backup
+	}
+	script.WriteString("rm -rf \"$@\"\nprintf '%s\\n' \"$@\"\n")
+
+	out, err := s.runVolumeTask(ctx, obj, volumeTask{
+		Name:    "wipe",
+		Script:  script.String(),
+		Timeout: wipeTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	record.Paths = []string{}
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			record.Paths = append(record.Paths, line)
+		}
+	}
+	if len(record.Paths) == 0 {
+		record.Backup = ""
+	}
+	if record.Backup != "" {
+		s.notifySubscribers(ctx, notificationEvent{
+			Type:      "backup",
+			Namespace: obj.GetNamespace(),
+			Server:    obj.GetName(),
+			Title:     "Backup written",
+			Message:   fmt.Sprintf("World backed up before wipe to %s", record.Backup),
+			Fields:    map[string]string{"Path": record.Backup, "Reason": "wipe"},
+		})
+	}
+
+	// Kill the game without a graceful shutdown, which would save the
+	// in-memory world over the wiped files
+	pods, namespace, err := s.findGameServerPods(ctx, obj)
+	if err != nil {
+		return nil, fmt.Errorf("world wiped but failed to find pods: %w", err)
+	}
+	noGrace := int64(0)
+	if record.Pods, err = s.deleteGameServerPods(ctx, namespace, pods, &noGrace); err != nil {
+		return nil, fmt.Errorf("world wiped but failed to restart GameServer: %w", err)
+	}
+
+	// Re-read the GameServer so the record is not lost to a conflict
+	latest, err := s.getGameServerObject(ctx, obj.GetNamespace(), obj.GetName())
+	if err != nil {
+		return record, nil
+	}
+	raw, _ := json.Marshal(record)
+	annotations := latest.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[lastWipeAnnotation] = string(raw)
+	latest.SetAnnotations(annotations)
+	if len(pendingRestartFields(latest)) > 0 {
+		err = s.clearPendingRestart(ctx, latest, "Pending configuration changes were applied by a world wipe")
+	} else {
+		err = s.k8sClient.Update(ctx, latest)
+	}
+	if err != nil {
+		log.Printf("Failed to record wipe of %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+	}
+	s.publishEvent(eventGameServerWiped, obj.GetNamespace(), obj.GetName(), map[string]interface{}{
+		"trigger": record.Trigger,
+		"backup":  record.Backup,
+		"seed":    record.Seed,
+	})
+	return record, nil
+}
+
+// runWipeSchedules announces upcoming scheduled wipes and runs due ones
+func (s *Server) runWipeSchedules(ctx context.Context) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := s.k8sClient.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+
+	now := time.Now()
+	s.wipeScheduler.mu.Lock()
+	defer s.wipeScheduler.mu.Unlock()
+	if s.wipeScheduler.lastCheck == nil {
+		s.wipeScheduler.lastCheck = map[string]time.Time{}
+		s.wipeScheduler.running = map[string]bool{}
+	}
+
+	for i := range list.Items {
+		obj := &list.Items[i]
+		key := obj.GetNamespace() + "/" + obj.GetName()
+		policy, err := wipePolicy(obj)
+		if err != nil || policy == nil || !policy.Enabled {
+			delete(s.wipeScheduler.lastCheck, key)
+			continue
+		}
+
+		// The first evaluation only sets the window; wipes missed while the
+		// API was down are skipped rather than run late
+		lastCheck, seen := s.wipeScheduler.lastCheck[key]
+		s.wipeScheduler.lastCheck[key] = now
+		if !seen || s.wipeScheduler.running[key] {
+			continue
+		}
+
+		next, ok := policy.Schedule.next(lastCheck)
+		if !ok {
+			continue
+		}
+
+		announcements := policy.Announce
+		if len(announcements) == 0 {
+			announcements = defaultWipeAnnouncements
+		}
+		for _, announce := range announcements {
+			before, err := time.ParseDuration(announce)
+			if err != nil {
+				continue
+			}
+			at := next.Add(-before)
+			if at.After(lastCheck) && !at.After(now) {
+				message := fmt.Sprintf("Scheduled world wipe in %s", humanizeDuration(before))
+				if _, err := s.broadcastInGame(ctx, obj, message); err != nil {
+					log.Printf("Wipe announcement for %s failed: %v", key, err)
+				}
+			}
+		}
+
+		if next.After(now) {
+			continue
+		}
+		s.wipeScheduler.running[key] = true
+		go func(obj *unstructured.Unstructured, key string, policy *WipePolicy) {
+			defer func() {
+				s.wipeScheduler.mu.Lock()
+				delete(s.wipeScheduler.running, key)
+				s.wipeScheduler.mu.Unlock()
+			}()
+			if _, err := s.wipeWorld(context.Background(), obj, wipeOptions{
+				Backup:  !policy.SkipBackup,
+				NewSeed: policy.NewSeed,
+				Trigger: "scheduled",
+			}); err != nil {
+				log.Printf("Scheduled wipe of %s failed: %v", key, err)
+				return
+			}
+			log.Printf("Scheduled wipe of %s completed", key)
+		}(obj, key, policy)
+	}
+	return nil
+}
+
+// wipePolicy reads the wipe policy annotation, returning nil when unset
+func wipePolicy(obj *unstructured.Unstructured) (*WipePolicy, error) {
+	raw, ok := obj.GetAnnotations()[wipePolicyAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var policy WipePolicy
+	if err := json.Unmarshal([]byte(raw), &policy); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", wipePolicyAnnotation, err)
+	}
+	return &policy, nil
+}
+
+// randomSeed returns a random alphanumeric world seed
+func randomSeed() string {
+	const letters = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	seed := make([]byte, 12)
+	for i := range seed {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(letters))))
+		if err != nil {
+			n = big.NewInt(int64(time.Now().UnixNano() % int64(len(letters))))
+		}
+		seed[i] = letters[n.Int64()]
+	}
+	return string(seed)
+}
+
+// humanizeDuration formats a duration for player-facing announcements
+func humanizeDuration(d time.Duration) string {
+	unit := func(n int, name string) string {
+		if n == 1 {
+			return "1 " + name
+		}
+		return fmt.Sprintf("%d %ss", n, name)
+	}
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		return unit(int(d/time.Hour), "hour")
+	case d >= time.Minute:
+		return unit(int(d.Round(time.Minute)/time.Minute), "minute")
+	}
+	return unit(int(d.Round(time.Second)/time.Second), "second")
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+)
+
+const (
+	// defaultWorkerPoolSize bounds the Kubernetes calls fanned out by bulk
+	// handlers and background tasks at once, across all requests
+	defaultWorkerPoolSize = 16
+
+	// routeQueueFactor and routeQueueWait size the queue of a limited route
+	// relative to its concurrency
+	routeQueueFactor = 4
+	routeQueueWait   = 10 * time.Second
+)
+
+// workerPool is shared by every handler that fans out to many Kubernetes
+// calls, so concurrent bulk requests queue behind each other instead of
+// multiplying load on the API server
+type workerPool struct {
+	slots chan struct{}
+}
+
+// newWorkerPool sizes the pool from WORKER_POOL_SIZE
+func newWorkerPool() (*workerPool, error) {
+	size := defaultWorkerPoolSize
+	if raw := os.Getenv("WORKER_POOL_SIZE"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid WORKER_POOL_SIZE %q", raw)
+		}
+		size = n
+	}
+	return &workerPool{slots: make(chan struct{}, size)}, nil
+}
+
+// each calls fn for every index below n, running as many at once as the pool
+// has free workers, and returns when all calls have finished. Indexes not
+// started before ctx ends are skipped.
+func (p *workerPool) each(ctx context.Context, n int, fn func(i int)) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			log.Printf("Worker pool: skipped %d of %d tasks: %v", n-i, n, ctx.Err())
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-p.slots
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
+	}
+	wg.Wait()
+}
+
+// routeLimits holds the concurrency limiter of every limited route, shared
+// between API versions
+type routeLimits struct {
+	mu       sync.Mutex
+	limiters map[string]gin.HandlerFunc
+}
+
+// limit returns the limiter allowing concurrency requests of the named
+// route at once
+func (s *Server) limit(name string, concurrency int) gin.HandlerFunc {
+	s.limits.mu.Lock()
+	defer s.limits.mu.Unlock()
+	if s.limits.limiters == nil {
+		s.limits.limiters = map[string]gin.HandlerFunc{}
+	}
+	limiter, ok := s.limits.limiters[name]
+	if !ok {
+		limiter = handlers.Limit(concurrency, concurrency*routeQueueFactor, routeQueueWait)
+		s.limits.limiters[name] = limiter
+	}
+	return limiter
+}
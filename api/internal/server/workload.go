@@ -0,0 +1,431 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Workload is a read-only view of a GameServer's managed namespace. It only
+// carries status and addressing, never pod specs, environment or Secrets, so
+// it is safe to show to anyone who can read the claim.
+type Workload struct {
+	Namespace  string            `json:"namespace"`
+	Workloads  []WorkloadSet     `json:"workloads"`
+	Services   []WorkloadService `json:"services"`
+	Ingresses  []WorkloadIngress `json:"ingresses"`
+	Volumes    []WorkloadVolume  `json:"volumes"`
+	Pods       []WorkloadPod     `json:"pods"`
+	ObservedAt time.Time         `json:"observedAt"`
+}
+
+// WorkloadSet is a Deployment or StatefulSet
+type WorkloadSet struct {
+	Kind               string              `json:"kind"`
+	Name               string              `json:"name"`
+	Replicas           int32               `json:"replicas"`
+	ReadyReplicas      int32               `json:"readyReplicas"`
+	UpdatedReplicas    int32               `json:"updatedReplicas"`
+	AvailableReplicas  int32               `json:"availableReplicas"`
+	Images             []string            `json:"images"`
+	Generation         int64               `json:"generation"`
+	ObservedGeneration int64               `json:"observedGeneration"`
+	Conditions         []WorkloadCondition `json:"conditions,omitempty"`
+}
+
+// WorkloadCondition is a status condition of a workload object
+type WorkloadCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// WorkloadService is a Service and where it is reachable
+type WorkloadService struct {
+	Name      string                `json:"name"`
+	Type      string                `json:"type"`
+	ClusterIP string                `json:"clusterIP,omitempty"`
+	External  []string              `json:"external,omitempty"`
+	Ports     []WorkloadServicePort `json:"ports"`
+}
+
+// WorkloadServicePort is a port of a Service
+type WorkloadServicePort struct {
+	Name       string `json:"name,omitempty"`
+	Protocol   string `json:"protocol"`
+	Port       int32  `json:"port"`
+	TargetPort string `json:"targetPort,omitempty"`
+	NodePort   int32  `json:"nodePort,omitempty"`
+}
+
+// WorkloadIngress is an Ingress and the hosts it routes
+type WorkloadIngress struct {
+	Name      string   `json:"name"`
+	Class     string   `json:"class,omitempty"`
+	Hosts     []string `json:"hosts"`
+	TLS       bool     `json:"tls"`
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// WorkloadVolume is a PersistentVolumeClaim
+type WorkloadVolume struct {
+	Name         string   `json:"name"`
+	Phase        string   `json:"phase"`
+	StorageClass string   `json:"storageClass,omitempty"`
+	Requested    string   `json:"requested,omitempty"`
+	Capacity     string   `json:"capacity,omitempty"`
+	AccessModes  []string `json:"accessModes,omitempty"`
+	VolumeName   string   `json:"volumeName,omitempty"`
+}
+
+// WorkloadPod is a pod and the state of its containers
+type WorkloadPod struct {
+	Name       string              `json:"name"`
+	Phase      string              `json:"phase"`
+	Ready      bool                `json:"ready"`
+	Restarts   int32               `json:"restarts"`
+	Node       string              `json:"node,omitempty"`
+	PodIP      string              `json:"podIP,omitempty"`
+	StartTime  *time.Time          `json:"startTime,omitempty"`
+	Reason     string              `json:"reason,omitempty"`
+	Containers []WorkloadContainer `json:"containers"`
+}
+
+// WorkloadContainer is the status of a container in a pod
+type WorkloadContainer struct {
+	Name     string `json:"name"`
+	Image    string `json:"image"`
+	Ready    bool   `json:"ready"`
+	Restarts int32  `json:"restarts"`
+	// State is running, waiting or terminated, with Reason for the latter two
+	State  string `json:"state"`
+	Reason string `json:"reason,omitempty"`
+	// LastTermination is why the previous run of the container ended
+	LastTermination string `json:"lastTermination,omitempty"`
+}
+
+// getGameServerWorkload returns the Deployments, StatefulSets, Services,
+// Ingresses, volumes and pods in a GameServer's managed namespace in one
+// response, so callers need no access to that namespace
+func (s *Server) getGameServerWorkload(c *gin.Context) {
+	namespace := c.Param("namespace")
+	name := c.Param("name")
+
+	obj, err := s.getGameServerObject(context.TODO(), namespace, name)
+	if err != nil {
+		if client.IgnoreNotFound(err) == nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "GameServer not found",
+			})
+			return
+		}
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to get GameServer: %v", err),
+		})
+		return
+	}
+	managed, err := managedNamespace(obj)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	workload, err := s.describeWorkload(context.TODO(), managed)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to read workload in namespace %s: %v", managed, err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, workload)
+}
+
+// describeWorkload reads everything in a managed namespace, listing each
+// kind on the shared worker pool
+func (s *Server) describeWorkload(ctx context.Context, namespace string) (*Workload, error) {
+	workload := &Workload{
+		Namespace:  namespace,
+		Workloads:  []WorkloadSet{},
+		Services:   []WorkloadService{},
+		Ingresses:  []WorkloadIngress{},
+		Volumes:    []WorkloadVolume{},
+		Pods:       []WorkloadPod{},
+		ObservedAt: time.Now().UTC(),
+	}
+	kube := s.kubeClient
+	var deployments []WorkloadSet
+	var statefulSets []WorkloadSet
+	readers := []func() error{
+		func() error {
+			list, err := kube.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			for i := range list.Items {
+				deployments = append(deployments, deploymentSet(&list.Items[i]))
+			}
+			return nil
+		},
+		func() error {
+			list, err := kube.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			for i := range list.Items {
+				statefulSets = append(statefulSets, statefulSetSet(&list.Items[i]))
+			}
+			return nil
+		},
+		func() error {
+			list, err := kube.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			for i := range list.Items {
+				workload.Services = append(workload.Services, workloadService(&list.Items[i]))
+			}
+			return nil
+		},
+		func() error {
+			list, err := kube.NetworkingV1().Ingresses(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			for i := range list.Items {
+				workload.Ingresses = append(workload.Ingresses, workloadIngress(&list.Items[i]))
+			}
+			return nil
+		},
+		func() error {
+			list, err := kube.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			for i := range list.Items {
+				workload.Volumes = append(workload.Volumes, workloadVolume(&list.Items[i]))
+			}
+			return nil
+		},
+		func() error {
+			list, err := kube.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return err
+			}
+			for i := range list.Items {
+				workload.Pods = append(workload.Pods, workloadPod(&list.Items[i]))
+			}
+			sort.Slice(workload.Pods, func(i, j int) bool { return workload.Pods[i].Name < workload.Pods[j].Name })
+			return nil
+		},
+	}
+
+	errs := make([]error, len(readers))
+	s.workers.each(ctx, len(readers), func(i int) {
+		errs[i] = readers[i]()
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	workload.Workloads = append(append(workload.Workloads, deployments...), statefulSets...)
+	return workload, nil
+}
+
+// deploymentSet summarizes a Deployment
+func deploymentSet(deployment *appsv1.Deployment) WorkloadSet {
+	set := WorkloadSet{
+		Kind:               "Deployment",
+		Name:               deployment.Name,
+		Replicas:           1,
+		ReadyReplicas:      deployment.Status.ReadyReplicas,
+		UpdatedReplicas:    deployment.Status.UpdatedReplicas,
+		AvailableReplicas:  deployment.Status.AvailableReplicas,
+		Images:             containerImages(deployment.Spec.Template.Spec),
+		Generation:         deployment.Generation,
+		ObservedGeneration: deployment.Status.ObservedGeneration,
+	}
+	if deployment.Spec.Replicas != nil {
+		set.Replicas = *deployment.Spec.Replicas
+	}
+	for _, condition := range deployment.Status.Conditions {
+		set.Conditions = append(set.Conditions, WorkloadCondition{
+			Type:    string(condition.Type),
+			Status:  string(condition.Status),
+			Reason:  condition.Reason,
+			Message: condition.Message,
+		})
+	}
+	return set
+}
+
+// statefulSetSet summarizes a StatefulSet
+func statefulSetSet(statefulSet *appsv1.StatefulSet) WorkloadSet {
+	set := WorkloadSet{
+		Kind:               "StatefulSet",
+		Name:               statefulSet.Name,
+		Replicas:           1,
+		ReadyReplicas:      statefulSet.Status.ReadyReplicas,
+		UpdatedReplicas:    statefulSet.Status.UpdatedReplicas,
+		AvailableReplicas:  statefulSet.Status.AvailableReplicas,
+		Images:             containerImages(statefulSet.Spec.Template.Spec),
+		Generation:         statefulSet.Generation,
+		ObservedGeneration: statefulSet.Status.ObservedGeneration,
+	}
+	if statefulSet.Spec.Replicas != nil {
+		set.Replicas = *statefulSet.Spec.Replicas
+	}
+	for _, condition := range statefulSet.Status.Conditions {
+		set.Conditions = append(set.Conditions, WorkloadCondition{
+			Type:    string(condition.Type),
+			Status:  string(condition.Status),
+			Reason:  condition.Reason,
+			Message: condition.Message,
+		})
+	}
+	return set
+}
+
+// containerImages lists the images of the containers in a pod template
+func containerImages(spec corev1.PodSpec) []string {
+	images := make([]string, 0, len(spec.Containers))
+	for _, container := range spec.Containers {
+		images = append(images, container.Image)
+	}
+	return images
+}
+
+// workloadService summarizes a Service
+func workloadService(service *corev1.Service) WorkloadService {
+	summary := WorkloadService{
+		Name:      service.Name,
+		Type:      string(service.Spec.Type),
+		ClusterIP: service.Spec.ClusterIP,
+		External:  append([]string{}, service.Spec.ExternalIPs...),
+		Ports:     []WorkloadServicePort{},
+	}
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			summary.External = append(summary.External, ingress.IP)
+		} else if ingress.Hostname != "" {
+			summary.External = append(summary.External, ingress.Hostname)
+		}
+	}
+	for _, port := range service.Spec.Ports {
+		summaryPort := WorkloadServicePort{
+			Name:     port.Name,
+			Protocol: string(port.Protocol),
+			Port:     port.Port,
+			NodePort: port.NodePort,
+		}
+		if port.TargetPort.String() != "0" {
+			summaryPort.TargetPort = port.TargetPort.String()
+		}
+		summary.Ports = append(summary.Ports, summaryPort)
+	}
+	return summary
+}
+
+// workloadIngress summarizes an Ingress
+func workloadIngress(ingress *networkingv1.Ingress) WorkloadIngress {
+	summary := WorkloadIngress{
+		Name:  ingress.Name,
+		Hosts: []string{},
+		TLS:   len(ingress.Spec.TLS) > 0,
+	}
+	if ingress.Spec.IngressClassName != nil {
+		summary.Class = *ingress.Spec.IngressClassName
+	}
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host != "" {
+			summary.Hosts = append(summary.Hosts, rule.Host)
+		}
+	}
+	for _, lb := range ingress.Status.LoadBalancer.Ingress {
+		if lb.IP != "" {
+			summary.Addresses = append(summary.Addresses, lb.IP)
+		} else if lb.Hostname != "" {
+			summary.Addresses = append(summary.Addresses, lb.Hostname)
+		}
+	}
+	return summary
+}
+
+// workloadVolume summarizes a PersistentVolumeClaim
+func workloadVolume(claim *corev1.PersistentVolumeClaim) WorkloadVolume {
+	volume := WorkloadVolume{
+		Name:       claim.Name,
+		Phase:      string(claim.Status.Phase),
+		VolumeName: claim.Spec.VolumeName,
+	}
+	if claim.Spec.StorageClassName != nil {
+		volume.StorageClass = *claim.Spec.StorageClassName
+	}
+	if requested, ok := claim.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+		volume.Requested = requested.String()
+	}
+	if capacity, ok := claim.Status.Capacity[corev1.ResourceStorage]; ok {
+		volume.Capacity = capacity.String()
+	}
+	for _, mode := range claim.Status.AccessModes {
+		volume.AccessModes = append(volume.AccessModes, string(mode))
+	}
+	return volume
+}
+
+// workloadPod summarizes a pod and its containers
+func workloadPod(pod *corev1.Pod) WorkloadPod {
+	summary := WorkloadPod{
+		Name:       pod.Name,
+		Phase:      string(pod.Status.Phase),
+		Node:       pod.Spec.NodeName,
+		PodIP:      pod.Status.PodIP,
+		Reason:     pod.Status.Reason,
+		Containers: []WorkloadContainer{},
+	}
+	if pod.Status.StartTime != nil {
+		start := pod.Status.StartTime.Time
+		summary.StartTime = &start
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			summary.Ready = condition.Status == corev1.ConditionTrue
+		}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		container := WorkloadContainer{
+			Name:     status.Name,
+			Image:    status.Image,
+			Ready:    status.Ready,
+			Restarts: status.RestartCount,
+		}
+		switch {
+		case status.State.Running != nil:
+			container.State = "running"
+		case status.State.Waiting != nil:
+			container.State = "waiting"
+			container.Reason = status.State.Waiting.Reason
+		case status.State.Terminated != nil:
+			container.State = "terminated"
+			container.Reason = status.State.Terminated.Reason
+		}
+		if last := status.LastTerminationState.Terminated; last != nil {
+			container.LastTermination = fmt.Sprintf("%s (exit code %d)", last.Reason, last.ExitCode)
+		}
+		summary.Restarts += status.RestartCount
+		summary.Containers = append(summary.Containers, container)
+	}
+	return summary
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// errWorldUnsupported is returned when world parameters are set for a game
+// without a world schema
+var errWorldUnsupported = errors.New("game type has no world parameters")
+
+// regenerateWorld archives the current world and starts a fresh one with new
+// world parameters
+func (s *Server) regenerateWorld(c *gin.Context) {
+	req := struct {
+		GameServerWorld
+		Backup *bool `json:"backup"`
+	}{}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid request body: %v", err),
+			})
+			return
+		}
+	}
+
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return
+	}
+
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, _ := lookupGame(gameType)
+	if def.World == nil || def.Wipe == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Game type %s does not support world regeneration", gameType),
+		})
+		return
+	}
+	if _, err := applyWorldToGameConfig(def, nil, &req.GameServerWorld); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	world := req.GameServerWorld
+	record, err := s.wipeWorld(context.TODO(), obj, wipeOptions{
+		Backup:  req.Backup == nil || *req.Backup,
+		NewSeed: true,
+		World:   &world,
+		Trigger: "regenerate",
+	})
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to regenerate world: %v", err),
+		})
+		return
+	}
+
+	latest, err := s.getGameServerObject(context.TODO(), obj.GetNamespace(), obj.GetName())
+	if err == nil {
+		gameConfig, _, _ := unstructured.NestedMap(latest.Object, "spec", "gameConfig")
+		c.JSON(http.StatusOK, gin.H{
+			"world": worldFromGameConfig(def, gameConfig),
+			"wipe":  record,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"wipe": record,
+	})
+}
+
+// applyWorldToGameConfig writes world parameters into their gameConfig
+// fields, validating them against the game's schema. The input map is not
+// modified.
+func applyWorldToGameConfig(def GameDefinition, gameConfig map[string]interface{}, world *GameServerWorld) (map[string]interface{}, error) {
+	out := map[string]interface{}{}
+	if gameConfig != nil {
+		out = runtime.DeepCopyJSON(gameConfig)
+	}
+	if world == nil || *world == (GameServerWorld{}) {
+		return out, nil
+	}
+	if def.World == nil {
+		return nil, errWorldUnsupported
+	}
+
+	values := []struct {
+		field string
+		name  string
+		set   bool
+		value interface{}
+	}{
+		{def.World.NameField, "name", world.Name != "", world.Name},
+		{def.World.SeedField, "seed", world.Seed != "", world.Seed},
+		{def.World.SizeField, "size", world.Size != 0, world.Size},
+	}
+	for _, v := range values {
+		if !v.set {
+			continue
+		}
+		if v.field == "" {
+			return nil, fmt.Errorf("game type %s has no world %s setting", def.Type, v.name)
+		}
+		if field, ok := def.Field(v.field); ok {
+			if err := validateConfigValue(field, v.value); err != nil {
+				return nil, fmt.Errorf("world.%s: %v", v.name, err)
+			}
+		}
+		if err := unstructured.SetNestedField(out, v.value, strings.Split(v.field, ".")...); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// worldFromGameConfig reads world parameters back out of gameConfig,
+// falling back to the schema defaults
+func worldFromGameConfig(def GameDefinition, gameConfig map[string]interface{}) *GameServerWorld {
+	if def.World == nil {
+		return nil
+	}
+	values := flattenConfig("", gameConfig)
+	lookup := func(path string) interface{} {
+		if path == "" {
+			return nil
+		}
+		if value, ok := values[path]; ok {
+			return value
+		}
+		if field, ok := def.Field(path); ok {
+			return field.Default
+		}
+		return nil
+	}
+
+	world := &GameServerWorld{}
+	if name, ok := lookup(def.World.NameField).(string); ok {
+		world.Name = name
+	}
+	if seed, ok := lookup(def.World.SeedField).(string); ok {
+		world.Seed = seed
+	}
+	if size, ok := toFloat(lookup(def.World.SizeField)); ok {
+		world.Size = int64(size)
+	}
+	return world
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/saves"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// worldImportMaxBytes bounds an uploaded save archive
+	worldImportMaxBytes = 8 << 30
+
+	// worldImportTimeout bounds copying an imported world onto the volume
+	worldImportTimeout = time.Hour
+)
+
+// World import steps, in order
+const (
+	importStepStart   = "start-transfer-pod"
+	importStepUpload  = "upload"
+	importStepExtract = "extract"
+	importStepRecord  = "record"
+)
+
+// WorldImport summarizes how a stored world was imported
+type WorldImport struct {
+	Layout     string    `json:"layout"`
+	Files      int       `json:"files"`
+	Skipped    int       `json:"skipped"`
+	ImportedAt time.Time `json:"importedAt"`
+}
+
+// importWorld imports a zip or tar.gz save archive from another host as an
+// inactive named world. The archive is mapped onto the game's layout first;
+// with ?dryRun=true only the mapping report is returned.
+func (s *Server) importWorld(c *gin.Context) {
+	name := c.Param("world")
+	if !worldNamePattern.MatchString(name) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "World name must be lowercase letters, digits, '-' or '_'",
+		})
+		return
+	}
+	obj, _, ok := s.loadGameServerWorlds(c)
+	if !ok {
+		return
+	}
+	catalog, err := gameServerWorlds(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if catalog.Active == name {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("World %s is active; import into another world and activate it", name),
+		})
+		return
+	}
+
+	upload, err := os.CreateTemp("", "world-import-*")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("Failed to buffer upload: %v", err),
+		})
+		return
+	}
+	// The operation takes over the file once it starts
+	keep := false
+	defer func() {
+		if !keep {
+			upload.Close()
+			os.Remove(upload.Name())
+		}
+	}()
+	if _, err := io.Copy(upload, http.MaxBytesReader(c.Writer, c.Request.Body, worldImportMaxBytes)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Failed to read upload: %v", err),
+		})
+		return
+	}
+
+	archive, err := saves.Open(upload)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	files, err := archive.Files()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	report, err := saves.Plan(gameType, c.Query("layout"), files)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if len(report.Mapped) == 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "No world data found in the archive",
+			"report": report,
+		})
+		return
+	}
+	if c.Query("dryRun") == "true" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+	if _, err := managedNamespace(obj); err != nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	keep = true
+	steps := []string{importStepStart, importStepUpload, importStepExtract, importStepRecord}
+	op := s.startOperation("world-import", obj.GetNamespace(), obj.GetName(), steps, worldImportTimeout, func(ctx context.Context, t *operationTracker) (interface{}, error) {
+		defer os.Remove(upload.Name())
+		defer upload.Close()
+		return s.runWorldImport(ctx, t, obj, name, archive, report)
+	})
+	c.JSON(http.StatusAccepted, op)
+}
+
+// runWorldImport rewrites the archive to the game's layout, streams it to a
+// transfer pod and unpacks it into the world's store directory
+func (s *Server) runWorldImport(ctx context.Context, t *operationTracker, obj *unstructured.Unstructured, name string, archive *saves.Archive, report *saves.Report) (interface{}, error) {
+	namespace, _ := managedNamespace(obj)
+	stored := worldStoreDir + "/" + name
+	result := gin.H{"world": name, "report": report}
+
+	var pod string
+	var stop func()
+	err := t.step(importStepStart, func() (string, error) {
+		var err error
+		pod, stop, err = s.startVolumeServer(ctx, obj, volumeTask{
+			Name: "world-import",
+			Files: map[string][]byte{
+				"upload":  []byte(migrationUploadScript),
+				"extract": []byte(worldImportExtractScript(stored)),
+			},
+			Script: "rm -f " + gameDataMountPath + "/" + migrationDir + "/incoming.tar.gz\n" + migrationServeScript,
+			Port:   migrationPort,
+		})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Transfer pod %s is ready", pod), nil
+	})
+	if err != nil {
+		return result, err
+	}
+	defer stop()
+
+	err = t.step(importStepUpload, func() (string, error) {
+		reader, writer := io.Pipe()
+		go func() {
+			_, err := archive.Rewrite(writer, report.Targets())
+			writer.CloseWithError(err)
+		}()
+		sent, err := migrationUpload(ctx, s, namespace, pod, reader)
+		reader.CloseWithError(err)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Uploaded %d files (%d bytes)", len(report.Mapped), sent), nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	err = t.step(importStepExtract, func() (string, error) {
+		if err := migrationPost(ctx, s, namespace, pod, "extract", nil); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Unpacked into %s", stored), nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	err = t.step(importStepRecord, func() (string, error) {
+		// Re-read the GameServer, it may have changed during the upload
+		current, err := s.getGameServerObject(ctx, obj.GetNamespace(), obj.GetName())
+		if err != nil {
+			return "", err
+		}
+		catalog, err := gameServerWorlds(current)
+		if err != nil {
+			return "", err
+		}
+		world, exists := catalog.Worlds[name]
+		if !exists {
+			world = StoredWorld{Name: name, Created: time.Now().UTC()}
+		}
+		world.Import = &WorldImport{
+			Layout:     report.Layout,
+			Files:      len(report.Mapped),
+			Skipped:    len(report.Skipped),
+			ImportedAt: time.Now().UTC(),
+		}
+		catalog.Worlds[name] = world
+		if err := s.saveWorldCatalog(ctx, current, catalog); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Recorded world %s", name), nil
+	})
+	return result, err
+}
+
+// worldImportExtractScript replaces a stored world with the incoming archive
+func worldImportExtractScript(stored string) string {
+	return `#!/bin/sh
+echo "Content-Type: text/plain"
+echo ""
+cd ` + gameDataMountPath + ` || exit 0
+if out=$(rm -rf ` + stored + ` && mkdir -p ` + stored + ` && tar xzf ` + migrationDir + `/incoming.tar.gz -C ` + stored + ` 2>&1); then
+  rm -f ` + migrationDir + `/incoming.tar.gz
+  echo ok
+else
+  echo "error: $out"
+fi
+`
+}
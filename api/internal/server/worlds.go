@@ -0,0 +1,458 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// worldsAnnotation holds a GameServer's worldCatalog as JSON
+	worldsAnnotation = "gameplane.kubelize.io/worlds"
+
+	// worldStoreDir keeps inactive worlds on the data volume, one directory
+	// per world mirroring the game's own layout
+	worldStoreDir = ".gameplane/worlds"
+
+	// defaultWorldName names the world a server starts with
+	defaultWorldName = "default"
+)
+
+// worldNamePattern keeps world names safe to use as directory names
+var worldNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// StoredWorld is a named world kept on a GameServer's volume
+type StoredWorld struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	World       *GameServerWorld `json:"world,omitempty"`
+	Created     time.Time        `json:"created"`
+	Active      bool             `json:"active"`
+	SizeBytes   int64            `json:"sizeBytes,omitempty"`
+	Import      *WorldImport     `json:"import,omitempty"`
+}
+
+// worldCatalog records the named worlds and which one is live
+type worldCatalog struct {
+	Active string                 `json:"active"`
+	Worlds map[string]StoredWorld `json:"worlds"`
+}
+
+// listWorlds returns the named worlds stored for a GameServer
+func (s *Server) listWorlds(c *gin.Context) {
+	obj, def, ok := s.loadGameServerWorlds(c)
+	if !ok {
+		return
+	}
+
+	catalog, err := gameServerWorlds(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// Disk usage needs a volume task, so it is opt-in
+	usage := map[string]int64{}
+	if c.Query("usage") == "true" {
+		usage, err = s.worldDiskUsage(context.TODO(), obj, def, catalog.Active)
+		if err != nil {
+			c.JSON(errorStatus(c, err), gin.H{
+				"error": fmt.Sprintf("Failed to read world disk usage: %v", err),
+			})
+			return
+		}
+	}
+
+	gameConfig, _, _ := unstructured.NestedMap(obj.Object, "spec", "gameConfig")
+	worlds := make([]StoredWorld, 0, len(catalog.Worlds))
+	for name, world := range catalog.Worlds {
+		world.Name = name
+		world.Active = name == catalog.Active
+		if world.Active {
+			world.World = worldFromGameConfig(def, gameConfig)
+		}
+		world.SizeBytes = usage[name]
+		worlds = append(worlds, world)
+	}
+	sort.Slice(worlds, func(i, j int) bool { return worlds[i].Name < worlds[j].Name })
+
+	c.JSON(http.StatusOK, gin.H{
+		"active": catalog.Active,
+		"worlds": worlds,
+	})
+}
+
+// createWorld registers a new named world, either empty so the game
+// generates it on first activation or cloned from the active world
+func (s *Server) createWorld(c *gin.Context) {
+	var req struct {
+		Name        string           `json:"name" binding:"required"`
+		Description string           `json:"description"`
+		World       *GameServerWorld `json:"world"`
+		CloneActive bool             `json:"cloneActive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request body: %v", err),
+		})
+		return
+	}
+	if !worldNamePattern.MatchString(req.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "World name must be lowercase letters, digits, '-' or '_'",
+		})
+		return
+	}
+
+	obj, def, ok := s.loadGameServerWorlds(c)
+	if !ok {
+		return
+	}
+	if _, err := applyWorldToGameConfig(def, nil, req.World); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	catalog, err := gameServerWorlds(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if _, exists := catalog.Worlds[req.Name]; exists {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("World %s already exists", req.Name),
+		})
+		return
+	}
+
+	stored := worldStoreDir + "/" + req.Name
+	script := fmt.Sprintf("cd %s\nrm -rf %s\nmkdir -p %s\n", gameDataMountPath, stored, stored)
+	if req.CloneActive {
+		if _, err := s.saveWorldInGame(context.TODO(), obj); err != nil && !errors.Is(err, errConsoleUnsupported) {
+			log.Printf("Failed to save world of %s/%s before cloning: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+		script += worldCopyScript(def.Wipe.Paths, ".", stored)
+		if req.World == nil {
+			gameConfig, _, _ := unstructured.NestedMap(obj.Object, "spec", "gameConfig")
+			req.World = worldFromGameConfig(def, gameConfig)
+		}
+	}
+	if _, err := s.runVolumeTask(context.TODO(), obj, volumeTask{
+		Name:    "world-create",
+		Script:  script,
+		Timeout: wipeTimeout,
+	}); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to create world: %v", err),
+		})
+		return
+	}
+
+	world := StoredWorld{
+		Name:        req.Name,
+		Description: req.Description,
+		World:       req.World,
+		Created:     time.Now().UTC(),
+	}
+	catalog.Worlds[req.Name] = world
+	if err := s.saveWorldCatalog(context.TODO(), obj, catalog); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to record world: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, world)
+}
+
+// deleteWorld removes an inactive named world and its files
+func (s *Server) deleteWorld(c *gin.Context) {
+	name := c.Param("world")
+	obj, _, ok := s.loadGameServerWorlds(c)
+	if !ok {
+		return
+	}
+
+	catalog, err := gameServerWorlds(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if _, exists := catalog.Worlds[name]; !exists || !worldNamePattern.MatchString(name) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("World %s not found", name),
+		})
+		return
+	}
+	if name == catalog.Active {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "The active world cannot be deleted; activate another world first",
+		})
+		return
+	}
+
+	if _, err := s.runVolumeTask(context.TODO(), obj, volumeTask{
+		Name:   "world-delete",
+		Script: fmt.Sprintf("rm -rf %s/%s/%s", gameDataMountPath, worldStoreDir, name),
+	}); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to delete world: %v", err),
+		})
+		return
+	}
+
+	delete(catalog.Worlds, name)
+	if err := s.saveWorldCatalog(context.TODO(), obj, catalog); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to record world deletion: %v", err),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("World %s deleted", name),
+	})
+}
+
+// activateWorld swaps the live world for a stored one, applies its world
+// parameters and restarts the game server
+func (s *Server) activateWorld(c *gin.Context) {
+	name := c.Param("world")
+	obj, def, ok := s.loadGameServerWorlds(c)
+	if !ok {
+		return
+	}
+	ctx := context.TODO()
+
+	catalog, err := gameServerWorlds(obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	target, exists := catalog.Worlds[name]
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": fmt.Sprintf("World %s not found", name),
+		})
+		return
+	}
+	if name == catalog.Active {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("World %s is already active", name),
+		})
+		return
+	}
+
+	// Flush the live world before moving its files; both steps are best
+	// effort because the console may be disabled
+	if _, err := s.broadcastInGame(ctx, obj, fmt.Sprintf("Switching to world %s, the server will restart", name)); err != nil && !errors.Is(err, errConsoleUnsupported) {
+		log.Printf("World switch announcement for %s/%s failed: %v", obj.GetNamespace(), obj.GetName(), err)
+	}
+	if _, err := s.saveWorldInGame(ctx, obj); err != nil && !errors.Is(err, errConsoleUnsupported) {
+		log.Printf("Failed to save world of %s/%s before switching: %v", obj.GetNamespace(), obj.GetName(), err)
+	}
+
+	previous := worldStoreDir + "/" + catalog.Active
+	stored := worldStoreDir + "/" + name
+	script := fmt.Sprintf("cd %s\nrm -rf %s\nmkdir -p %s\n", gameDataMountPath, previous, previous) +
+		worldMoveScript(def.Wipe.Paths, ".", previous) +
+		worldMoveScript(def.Wipe.Paths, stored, ".") +
+		fmt.Sprintf("rm -rf %s\n", stored)
+	if _, err := s.runVolumeTask(ctx, obj, volumeTask{
+		Name:    "world-switch",
+		Script:  script,
+		Timeout: wipeTimeout,
+	}); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to switch world files: %v", err),
+		})
+		return
+	}
+
+	// Remember the outgoing world's parameters so switching back restores them
+	spec, _, _ := unstructured.NestedMap(obj.Object, "spec")
+	gameConfig, _, _ := unstructured.NestedMap(spec, "gameConfig")
+	outgoing := catalog.Worlds[catalog.Active]
+	outgoing.World = worldFromGameConfig(def, gameConfig)
+	catalog.Worlds[catalog.Active] = outgoing
+	catalog.Active = name
+	if err := setWorldCatalog(obj, catalog); err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	gameConfig, err = applyWorldToGameConfig(def, gameConfig, target.World)
+	if err == nil && len(gameConfig) > 0 {
+		spec["gameConfig"] = gameConfig
+		_, err = s.writeGameServerSpec(ctx, obj, spec)
+	} else if err == nil {
+		err = s.k8sClient.Update(ctx, obj)
+	}
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("World files switched but failed to update GameServer: %v", err),
+		})
+		return
+	}
+
+	// The swapped files are already in place; a graceful shutdown would save
+	// the old in-memory world over them
+	pods, namespace, err := s.findGameServerPods(ctx, obj)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to find pods: %v", err),
+		})
+		return
+	}
+	noGrace := int64(0)
+	restarted, err := s.deleteGameServerPods(ctx, namespace, pods, &noGrace)
+	if err != nil {
+		c.JSON(errorStatus(c, err), gin.H{
+			"error": fmt.Sprintf("Failed to restart GameServer: %v", err),
+		})
+		return
+	}
+	if len(pendingRestartFields(obj)) > 0 {
+		if err := s.clearPendingRestart(ctx, obj, fmt.Sprintf("Pending configuration changes were applied by switching to world %s", name)); err != nil {
+			log.Printf("Failed to clear pending restart of %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+
+	target.Name = name
+	target.Active = true
+	target.World = worldFromGameConfig(def, gameConfig)
+	c.JSON(http.StatusOK, gin.H{
+		"message": fmt.Sprintf("Switched to world %s", name),
+		"world":   target,
+		"pods":    restarted,
+	})
+}
+
+// loadGameServerWorlds fetches the GameServer and checks its game has known
+// world data paths, writing the error response itself
+func (s *Server) loadGameServerWorlds(c *gin.Context) (*unstructured.Unstructured, GameDefinition, bool) {
+	obj, ok := s.loadGameServerForConfig(c)
+	if !ok {
+		return nil, GameDefinition{}, false
+	}
+
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	def, found := lookupGame(gameType)
+	if !found || def.Wipe == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Game type %s does not support multiple worlds", gameType),
+		})
+		return nil, GameDefinition{}, false
+	}
+	return obj, def, true
+}
+
+// worldDiskUsage reports the size in bytes of each world, counting the live
+// world's paths for the active one
+func (s *Server) worldDiskUsage(ctx context.Context, obj *unstructured.Unstructured, def GameDefinition, active string) (map[string]int64, error) {
+	var script strings.Builder
+	fmt.Fprintf(&script, "cd %s\n", gameDataMountPath)
+	fmt.Fprintf(&script, "total=0\nfor p in %s; do if [ -e \"$p\" ]; then total=$((total + $(du -sk \"$p\" | cut -f1))); fi; done\necho \"%s $total\"\n", strings.Join(def.Wipe.Paths, " "), active)
+	fmt.Fprintf(&script, "if [ -d %s ]; then cd %s; for d in */; do [ -d \"$d\" ] && echo \"${d%%/} $(du -sk \"$d\" | cut -f1)\"; done; fi\n", worldStoreDir, worldStoreDir)
+
+	out, err := s.runVolumeTask(ctx, obj, volumeTask{
+		Name:     "world-usage",
+		Script:   script.String(),
+		ReadOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	usage := map[string]int64{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if kb, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			usage[fields[0]] = kb * 1024
+		}
+	}
+	return usage, nil
+}
+
+// worldMoveScript moves the world paths found below from into the same
+// relative location below to
+func worldMoveScript(paths []string, from, to string) string {
+	return worldTransferScript("mv", paths, from, to)
+}
+
+// worldCopyScript copies the world paths found below from into to
+func worldCopyScript(paths []string, from, to string) string {
+	return worldTransferScript("cp -a", paths, from, to)
+}
+
+// worldTransferScript runs op on each matching world path, relative to the
+// data directory. Globs come from the catalog and stay unquoted.
+func worldTransferScript(op string, paths []string, from, to string) string {
+	return fmt.Sprintf("(dest=\"$PWD/%s\"; cd %s && for p in %s; do if [ -e \"$p\" ]; then mkdir -p \"$dest/$(dirname \"$p\")\"; %s \"${p%%/}\" \"$dest/$(dirname \"$p\")/\"; fi; done)\n",
+		to, from, strings.Join(paths, " "), op)
+}
+
+// gameServerWorlds reads the world catalog, defaulting to a single active
+// "default" world
+func gameServerWorlds(obj *unstructured.Unstructured) (*worldCatalog, error) {
+	catalog := &worldCatalog{Active: defaultWorldName, Worlds: map[string]StoredWorld{}}
+	if raw, ok := obj.GetAnnotations()[worldsAnnotation]; ok {
+		if err := json.Unmarshal([]byte(raw), catalog); err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %w", worldsAnnotation, err)
+		}
+	}
+	if catalog.Worlds == nil {
+		catalog.Worlds = map[string]StoredWorld{}
+	}
+	if _, ok := catalog.Worlds[catalog.Active]; !ok {
+		catalog.Worlds[catalog.Active] = StoredWorld{Name: catalog.Active, Created: obj.GetCreationTimestamp().UTC()}
+	}
+	return catalog, nil
+}
+
+// setWorldCatalog stores the world catalog annotation without writing the object
+func setWorldCatalog(obj *unstructured.Unstructured, catalog *worldCatalog) error {
+	raw, err := json.Marshal(catalog)
+	if err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[worldsAnnotation] = string(raw)
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// saveWorldCatalog stores the world catalog on the GameServer
+func (s *Server) saveWorldCatalog(ctx context.Context, obj *unstructured.Unstructured, catalog *worldCatalog) error {
+	if err := setWorldCatalog(obj, catalog); err != nil {
+		return err
+	}
+	return s.k8sClient.Update(ctx, obj)
+}
@@ -0,0 +1,93 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kubelize/gameplane/api/apis/gameplane/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// dependencyDialTimeout bounds each reachability check
+const dependencyDialTimeout = 2 * time.Second
+
+// dependencyAddress is the host:port a dependency is reached at. Services
+// without a namespace are looked up in the GameServer's namespace.
+func dependencyAddress(dependency v1alpha1.GameServerDependency, namespace string) string {
+	host := dependency.Host
+	if dependency.Service != "" {
+		if dependency.Namespace != "" {
+			namespace = dependency.Namespace
+		}
+		host = fmt.Sprintf("%s.%s.svc.cluster.local", dependency.Service, namespace)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(dependency.Port))
+}
+
+// gameServerDependencies reads spec.dependencies
+func gameServerDependencies(obj *unstructured.Unstructured) []v1alpha1.GameServerDependency {
+	raw, _, _ := unstructured.NestedSlice(obj.Object, "spec", "dependencies")
+	dependencies := []v1alpha1.GameServerDependency{}
+	for _, item := range raw {
+		entry, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		dependency := v1alpha1.GameServerDependency{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(entry, &dependency); err == nil {
+			dependencies = append(dependencies, dependency)
+		}
+	}
+	return dependencies
+}
+
+// dependenciesCondition reports whether every dependency accepts TCP
+// connections. The checks run in parallel so one slow dependency does not
+// hold up the pass.
+func dependenciesCondition(ctx context.Context, obj *unstructured.Unstructured, dependencies []v1alpha1.GameServerDependency) metav1.Condition {
+	failures := make([]string, len(dependencies))
+	var wg sync.WaitGroup
+	for i, dependency := range dependencies {
+		wg.Add(1)
+		go func(i int, dependency v1alpha1.GameServerDependency) {
+			defer wg.Done()
+			address := dependencyAddress(dependency, obj.GetNamespace())
+			dialer := net.Dialer{Timeout: dependencyDialTimeout}
+			conn, err := dialer.DialContext(ctx, "tcp", address)
+			if err != nil {
+				failures[i] = fmt.Sprintf("%s (%s)", dependency.Name, address)
+				return
+			}
+			conn.Close()
+		}(i, dependency)
+	}
+	wg.Wait()
+
+	unreachable := []string{}
+	for _, failure := range failures {
+		if failure != "" {
+			unreachable = append(unreachable, failure)
+		}
+	}
+	if len(unreachable) > 0 {
+		return metav1.Condition{
+			Type:    ConditionDependenciesReady,
+			Status:  metav1.ConditionFalse,
+			Reason:  "DependencyUnreachable",
+			Message: fmt.Sprintf("Unreachable: %s", strings.Join(unreachable, ", ")),
+		}
+	}
+	return metav1.Condition{
+		Type:    ConditionDependenciesReady,
+		Status:  metav1.ConditionTrue,
+		Reason:  "DependenciesReachable",
+		Message: fmt.Sprintf("All %d dependencies accept connections", len(dependencies)),
+	}
+}
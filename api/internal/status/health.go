@@ -0,0 +1,220 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/kubelize/gameplane/api/apis/gameplane/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Health check weights. Checks that cannot be made, such as saturation
+// without metrics-server, are left out of the score.
+const (
+	healthWeightReadiness  = 30
+	healthWeightRestarts   = 20
+	healthWeightSaturation = 20
+	healthWeightDisk       = 15
+	healthWeightQuery      = 15
+)
+
+// healthInputs is what a reconcile found that the health score is made of
+type healthInputs struct {
+	pods    []corev1.Pod
+	running *corev1.Pod
+	probe   *Probe
+	crash   metav1.Condition
+	// diskRatio is the used fraction of the game volume, when known
+	diskRatio *float64
+}
+
+// podMetrics is the part of a metrics.k8s.io PodMetrics used for saturation
+type podMetrics struct {
+	Containers []struct {
+		Name  string              `json:"name"`
+		Usage corev1.ResourceList `json:"usage"`
+	} `json:"containers"`
+}
+
+// health scores a GameServer from readiness, restart rate, resource
+// saturation, disk pressure and query reachability
+func (r *Reconciler) health(ctx context.Context, in healthInputs, now time.Time) *v1alpha1.GameServerHealth {
+	checks := []v1alpha1.GameServerHealthCheck{readinessCheck(in)}
+	if check, ok := restartsCheck(in, now); ok {
+		checks = append(checks, check)
+	}
+	if in.running != nil {
+		if check, ok := r.saturationCheck(ctx, in.running); ok {
+			checks = append(checks, check)
+		}
+	}
+	if in.diskRatio != nil {
+		checks = append(checks, diskCheck(*in.diskRatio))
+	}
+	if check, ok := queryCheck(in.probe); ok {
+		checks = append(checks, check)
+	}
+
+	total, weights := 0, 0
+	for _, check := range checks {
+		total += check.Score * check.Weight
+		weights += check.Weight
+	}
+	return &v1alpha1.GameServerHealth{
+		Score:  int(math.Round(float64(total) / float64(weights))),
+		Checks: checks,
+	}
+}
+
+// readinessCheck scores whether the game pod is up and ready
+func readinessCheck(in healthInputs) v1alpha1.GameServerHealthCheck {
+	check := v1alpha1.GameServerHealthCheck{Name: "readiness", Weight: healthWeightReadiness}
+	switch {
+	case in.running != nil && in.probe.Ready:
+		check.Score, check.Message = 100, "Game pod is ready"
+	case in.running != nil:
+		check.Score, check.Message = 40, "Game pod is running but not ready"
+	case len(in.pods) > 0:
+		check.Score, check.Message = 10, fmt.Sprintf("Game pod is %s", in.pods[0].Status.Phase)
+	default:
+		check.Message = "No game pod"
+	}
+	return check
+}
+
+// restartsCheck scores the game containers' restarts per hour since their
+// pod started; a crash loop scores 0
+func restartsCheck(in healthInputs, now time.Time) (v1alpha1.GameServerHealthCheck, bool) {
+	check := v1alpha1.GameServerHealthCheck{Name: "restarts", Weight: healthWeightRestarts}
+	if len(in.pods) == 0 {
+		return check, false
+	}
+	if in.crash.Status == metav1.ConditionTrue {
+		check.Message = in.crash.Message
+		if in.crash.Reason != "CrashLoopBackOff" {
+			check.Score = 40
+		}
+		return check, true
+	}
+
+	pod := in.running
+	if pod == nil {
+		pod = &in.pods[0]
+	}
+	restarts := int32(0)
+	for _, container := range pod.Status.ContainerStatuses {
+		restarts += container.RestartCount
+	}
+	hours := 1.0
+	if pod.Status.StartTime != nil {
+		hours = math.Max(now.Sub(pod.Status.StartTime.Time).Hours(), 1)
+	}
+	rate := float64(restarts) / hours
+	check.Score = clampScore(100 - int(math.Round(rate*50)))
+	check.Message = fmt.Sprintf("%d restarts since the pod started", restarts)
+	return check, true
+}
+
+// saturationCheck scores the game pod's CPU and memory usage against its
+// limits, or its requests where no limit is set. Without metrics-server the
+// check is skipped.
+func (r *Reconciler) saturationCheck(ctx context.Context, pod *corev1.Pod) (v1alpha1.GameServerHealthCheck, bool) {
+	check := v1alpha1.GameServerHealthCheck{Name: "saturation", Weight: healthWeightSaturation}
+	raw, err := r.Kube.CoreV1().RESTClient().
+		Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1/namespaces", pod.Namespace, "pods", pod.Name).
+		Do(ctx).
+		Raw()
+	if err != nil {
+		return check, false
+	}
+	metrics := &podMetrics{}
+	if err := json.Unmarshal(raw, metrics); err != nil {
+		return check, false
+	}
+
+	used, allowed := corev1.ResourceList{}, corev1.ResourceList{}
+	for _, container := range metrics.Containers {
+		for name, quantity := range container.Usage {
+			sum := used[name]
+			sum.Add(quantity)
+			used[name] = sum
+		}
+	}
+	for _, container := range pod.Spec.Containers {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			quantity, ok := container.Resources.Limits[name]
+			if !ok {
+				quantity, ok = container.Resources.Requests[name]
+			}
+			if ok {
+				sum := allowed[name]
+				sum.Add(quantity)
+				allowed[name] = sum
+			}
+		}
+	}
+
+	cpu, cpuKnown := usageRatio(used, allowed, corev1.ResourceCPU)
+	memory, memoryKnown := usageRatio(used, allowed, corev1.ResourceMemory)
+	if !cpuKnown && !memoryKnown {
+		return check, false
+	}
+	// 80% or less is healthy; the score falls to 0 at the limit
+	percent := roundPercent(math.Max(cpu, memory))
+	check.Score = clampScore((100 - percent) * 5)
+	check.Message = fmt.Sprintf("CPU at %d%% and memory at %d%% of limits", roundPercent(cpu), roundPercent(memory))
+	return check, true
+}
+
+// diskCheck scores the used fraction of the game volume: 75% or less is
+// healthy, and the score falls to 0 at 95%
+func diskCheck(ratio float64) v1alpha1.GameServerHealthCheck {
+	percent := roundPercent(ratio)
+	return v1alpha1.GameServerHealthCheck{
+		Name:    "disk",
+		Weight:  healthWeightDisk,
+		Score:   clampScore((95 - percent) * 5),
+		Message: fmt.Sprintf("Storage %d%% used", percent),
+	}
+}
+
+// queryCheck scores whether the game answered its query. Games without a
+// query protocol, and servers without a running pod, are not checked.
+func queryCheck(probe *Probe) (v1alpha1.GameServerHealthCheck, bool) {
+	check := v1alpha1.GameServerHealthCheck{Name: "query", Weight: healthWeightQuery}
+	switch probe.Query {
+	case QueryAnswered:
+		check.Score, check.Message = 100, "Game answered its query"
+	case QueryUnanswered:
+		check.Message = "Game did not answer its query"
+	default:
+		return check, false
+	}
+	return check, true
+}
+
+// usageRatio is the used fraction of an allowed resource
+func usageRatio(used, allowed corev1.ResourceList, name corev1.ResourceName) (float64, bool) {
+	limit, ok := allowed[name]
+	if !ok || limit.IsZero() {
+		return 0, false
+	}
+	usage := used[name]
+	return float64(usage.MilliValue()) / float64(limit.MilliValue()), true
+}
+
+// roundPercent rounds a fraction to a multiple of 5%, so small changes in
+// usage do not rewrite the status on every pass
+func roundPercent(ratio float64) int {
+	return int(math.Round(ratio*20)) * 5
+}
+
+// clampScore keeps a score within 0 to 100
+func clampScore(score int) int {
+	return int(math.Min(100, math.Max(0, float64(score))))
+}
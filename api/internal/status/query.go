@@ -0,0 +1,114 @@
+package status
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/kubelize/gameplane/api/internal/catalog"
+)
+
+// queryTimeout bounds a single player count query
+const queryTimeout = 3 * time.Second
+
+// Querier asks a running game server how many players are online
+type Querier interface {
+	Players(ctx context.Context, host string, port int) (int, error)
+}
+
+// queriers are the query adapters by catalog QueryInfo.Protocol
+var queriers = map[string]Querier{
+	"a2s": a2sQuerier{},
+}
+
+// ErrNoQuery is returned for games without a query adapter
+var ErrNoQuery = errors.New("game has no query protocol")
+
+// QueryPlayers asks the game server at host for its player count through
+// the catalog's query adapter for its game type
+func QueryPlayers(ctx context.Context, gameType, host string) (int, error) {
+	def, ok := catalog.Lookup(gameType)
+	if !ok || def.Query == nil {
+		return 0, ErrNoQuery
+	}
+	querier, ok := queriers[def.Query.Protocol]
+	if !ok {
+		return 0, ErrNoQuery
+	}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+	return querier.Players(ctx, host, def.Query.Port)
+}
+
+// a2sQuerier implements the Steam server query A2S_INFO request
+type a2sQuerier struct{}
+
+// a2sInfoRequest is the A2S_INFO request payload without a challenge
+var a2sInfoRequest = append([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0x54}, []byte("Source Engine Query\x00")...)
+
+// Players sends A2S_INFO, answering a challenge if the server sends one
+func (a2sQuerier) Players(ctx context.Context, host string, port int) (int, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(queryTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	request := a2sInfoRequest
+	buf := make([]byte, 1400)
+	for attempt := 0; attempt < 2; attempt++ {
+		if _, err := conn.Write(request); err != nil {
+			return 0, err
+		}
+		n, err := conn.Read(buf)
+		if err != nil {
+			return 0, err
+		}
+		if n < 5 || !bytes.Equal(buf[:4], []byte{0xFF, 0xFF, 0xFF, 0xFF}) {
+			return 0, fmt.Errorf("unexpected A2S response")
+		}
+		switch buf[4] {
+		case 0x41: // S2C_CHALLENGE
+			if n < 9 {
+				return 0, fmt.Errorf("short A2S challenge")
+			}
+			request = append(append([]byte{}, a2sInfoRequest...), buf[5:9]...)
+		case 0x49: // A2S_INFO response
+			return parseA2SPlayers(buf[5:n])
+		default:
+			return 0, fmt.Errorf("unexpected A2S response type 0x%x", buf[4])
+		}
+	}
+	return 0, fmt.Errorf("A2S server kept sending challenges")
+}
+
+// parseA2SPlayers reads the player count from an A2S_INFO body: protocol,
+// name, map, folder and game, then the app ID and player count
+func parseA2SPlayers(body []byte) (int, error) {
+	if len(body) < 1 {
+		return 0, fmt.Errorf("short A2S_INFO response")
+	}
+	offset := 1
+	for i := 0; i < 4; i++ {
+		end := bytes.IndexByte(body[offset:], 0)
+		if end < 0 {
+			return 0, fmt.Errorf("malformed A2S_INFO response")
+		}
+		offset += end + 1
+	}
+	if len(body) < offset+3 {
+		return 0, fmt.Errorf("short A2S_INFO response")
+	}
+	// Skip the two-byte app ID
+	return int(body[offset+2]), nil
+}
@@ -0,0 +1,92 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// QueryReadyGate is the readiness gate of game pods whose game has a
+// query protocol. Compositions add it unless spec.advanced.probes
+// .queryReadiness is false, and the pod only becomes ready once the game
+// answered a query, so Ready means players can connect.
+const QueryReadyGate corev1.PodConditionType = "gameplane.kubelize.io/query-ready"
+
+// gateQueryReadiness opens the query readiness gate of running pods whose
+// game answered. The gate stays open for the life of the pod; the
+// readiness probe notices a game that stops answering later.
+func (r *Reconciler) gateQueryReadiness(ctx context.Context, gameType string, pods []corev1.Pod) error {
+	for i := range pods {
+		pod := &pods[i]
+		if !hasReadinessGate(pod, QueryReadyGate) || podConditionTrue(pod, QueryReadyGate) {
+			continue
+		}
+		if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		condition := corev1.PodCondition{
+			Type:               QueryReadyGate,
+			Status:             corev1.ConditionTrue,
+			Reason:             "QueryAnswered",
+			Message:            "The game answered its server query",
+			LastTransitionTime: metav1.Now(),
+		}
+		// A gate on a game without a query adapter would never open
+		if _, err := QueryPlayers(ctx, gameType, pod.Status.PodIP); err != nil && !errors.Is(err, ErrNoQuery) {
+			condition.Status = corev1.ConditionFalse
+			condition.Reason = "QueryUnanswered"
+			condition.Message = fmt.Sprintf("The game does not answer its server query yet: %v", err)
+		}
+		if !setPodCondition(pod, condition) {
+			continue
+		}
+		if _, err := r.Kube.CoreV1().Pods(pod.Namespace).UpdateStatus(ctx, pod, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("pod %s: %w", pod.Name, err)
+		}
+	}
+	return nil
+}
+
+// hasReadinessGate reports whether a pod waits for a condition to be ready
+func hasReadinessGate(pod *corev1.Pod, conditionType corev1.PodConditionType) bool {
+	for _, gate := range pod.Spec.ReadinessGates {
+		if gate.ConditionType == conditionType {
+			return true
+		}
+	}
+	return false
+}
+
+// podConditionTrue reports whether a pod condition is True
+func podConditionTrue(pod *corev1.Pod, conditionType corev1.PodConditionType) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// setPodCondition adds or updates a pod condition, keeping the transition
+// time when the status is unchanged. It reports whether anything changed.
+func setPodCondition(pod *corev1.Pod, condition corev1.PodCondition) bool {
+	for i := range pod.Status.Conditions {
+		existing := &pod.Status.Conditions[i]
+		if existing.Type != condition.Type {
+			continue
+		}
+		if existing.Status == condition.Status && existing.Reason == condition.Reason {
+			return false
+		}
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+		*existing = condition
+		return true
+	}
+	pod.Status.Conditions = append(pod.Status.Conditions, condition)
+	return true
+}
@@ -0,0 +1,274 @@
+// Package status keeps GameServer status up to date with live data from the
+// game workload: players online, the player-facing endpoint, crashes,
+// storage pressure and the reachability of the services it depends on. It
+// runs in the controller so status is correct even when nobody is calling
+// the API.
+package status
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kubelize/gameplane/api/internal/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ConditionCrashing is True while a game container is crash looping or
+	// recently exited with an error
+	ConditionCrashing = "Crashing"
+
+	// ConditionStoragePressure is True when the game volume is nearly full
+	ConditionStoragePressure = "StoragePressure"
+
+	// ConditionDependenciesReady is True while every service in
+	// spec.dependencies accepts connections
+	ConditionDependenciesReady = "DependenciesReady"
+
+	// crashWindow is how long a failed container exit keeps Crashing True
+	crashWindow = 10 * time.Minute
+
+	// storagePressureRatio is the used fraction of the game volume at which
+	// StoragePressure becomes True
+	storagePressureRatio = 0.9
+)
+
+// Query outcomes of a Probe
+const (
+	QueryAnswered    = "answered"
+	QueryUnanswered  = "unanswered"
+	QueryUnsupported = "unsupported"
+)
+
+// Probe is what one poll of a GameServer's workload found
+type Probe struct {
+	// Pod is the phase of the game pod; empty while there is none
+	Pod      string `json:"pod,omitempty"`
+	Ready    bool   `json:"ready"`
+	Endpoint string `json:"endpoint,omitempty"`
+	// Query is answered, unanswered or unsupported; empty without a
+	// running pod
+	Query      string `json:"query,omitempty"`
+	QueryError string `json:"queryError,omitempty"`
+}
+
+// Reconciler enriches the status of every GameServer claim
+type Reconciler struct {
+	Client client.Client
+	Kube   kubernetes.Interface
+}
+
+// Run reconciles all GameServers every interval until the context ends
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.ReconcileAll(ctx); err != nil {
+			log.Printf("Status reconcile failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReconcileAll reconciles every GameServer in the cluster. Failures of a
+// single GameServer are logged and retried on the next pass.
+func (r *Reconciler) ReconcileAll(ctx context.Context) error {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   "gameplane.kubelize.io",
+		Version: "v1alpha1",
+		Kind:    "GameServerList",
+	})
+	if err := r.Client.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list GameServers: %w", err)
+	}
+
+	stats := volumeStatsCache{}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		if _, err := r.reconcile(ctx, obj, stats); err != nil {
+			log.Printf("Failed to update status of GameServer %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+		}
+	}
+	return nil
+}
+
+// Refresh polls one GameServer now rather than on the next pass, writes
+// its status back if it changed and reports what the poll found
+func (r *Reconciler) Refresh(ctx context.Context, obj *unstructured.Unstructured) (*Probe, error) {
+	return r.reconcile(ctx, obj, volumeStatsCache{})
+}
+
+// reconcile refreshes one GameServer's status and writes it back if it
+// changed. Servers without a managed namespace yet are left to Crossplane.
+func (r *Reconciler) reconcile(ctx context.Context, obj *unstructured.Unstructured, stats volumeStatsCache) (*Probe, error) {
+	probe := &Probe{}
+	if _, err := k8s.ManagedNamespace(obj); err != nil {
+		return probe, nil
+	}
+	before, _, _ := unstructured.NestedFieldCopy(obj.Object, "status")
+
+	pods, namespace, err := k8s.GameServerPods(ctx, r.Kube, obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list game pods: %w", err)
+	}
+	running := runningPod(pods)
+	switch {
+	case running != nil:
+		probe.Pod = string(running.Status.Phase)
+		probe.Ready = podConditionTrue(running, corev1.PodReady)
+	case len(pods) > 0:
+		probe.Pod = string(pods[0].Status.Phase)
+	}
+
+	if info, err := k8s.ResolveConnectInfo(ctx, r.Kube, obj); err == nil && info.Host != "" {
+		probe.Endpoint = info.Address()
+		_ = unstructured.SetNestedField(obj.Object, info.Host, "status", "serverIP")
+		_ = unstructured.SetNestedField(obj.Object, info.Address(), "status", "serverEndpoint")
+		if len(info.Ports) > 0 {
+			_ = unstructured.SetNestedField(obj.Object, int64(info.Ports[0].Port), "status", "gamePort")
+		}
+	}
+
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	if err := r.gateQueryReadiness(ctx, gameType, pods); err != nil {
+		log.Printf("Failed to update query readiness of GameServer %s/%s: %v", obj.GetNamespace(), obj.GetName(), err)
+	}
+
+	if running == nil {
+		_ = unstructured.SetNestedField(obj.Object, int64(0), "status", "playersOnline")
+	} else {
+		players, err := r.queryPlayers(ctx, obj, running)
+		switch {
+		case errors.Is(err, ErrNoQuery):
+			probe.Query = QueryUnsupported
+		case err != nil:
+			// The game may still be loading; keep the last count
+			probe.Query = QueryUnanswered
+			probe.QueryError = err.Error()
+		default:
+			probe.Query = QueryAnswered
+			_ = unstructured.SetNestedField(obj.Object, int64(players), "status", "playersOnline")
+		}
+	}
+
+	now := time.Now()
+	crash := crashCondition(pods, now)
+	if err := k8s.SetCondition(obj, crash); err != nil {
+		return nil, err
+	}
+	if dependencies := gameServerDependencies(obj); len(dependencies) > 0 {
+		if err := k8s.SetCondition(obj, dependenciesCondition(ctx, obj, dependencies)); err != nil {
+			return nil, err
+		}
+	} else if err := k8s.RemoveCondition(obj, ConditionDependenciesReady); err != nil {
+		return nil, err
+	}
+	inputs := healthInputs{pods: pods, running: running, probe: probe, crash: crash}
+	if running != nil {
+		if condition, ratio, ok := r.storageCondition(ctx, running, namespace+"-storage", stats); ok {
+			if err := k8s.SetCondition(obj, condition); err != nil {
+				return nil, err
+			}
+			inputs.diskRatio = &ratio
+		}
+	}
+
+	// Stopped servers are down on purpose and not scored
+	if stopped, _, _ := unstructured.NestedBool(obj.Object, "spec", "stopped"); stopped {
+		unstructured.RemoveNestedField(obj.Object, "status", "health")
+	} else {
+		health, err := runtime.DefaultUnstructuredConverter.ToUnstructured(r.health(ctx, inputs, now))
+		if err != nil {
+			return nil, err
+		}
+		if err := unstructured.SetNestedMap(obj.Object, health, "status", "health"); err != nil {
+			return nil, err
+		}
+	}
+
+	after, _, _ := unstructured.NestedFieldNoCopy(obj.Object, "status")
+	if equality.Semantic.DeepEqual(before, after) {
+		return probe, nil
+	}
+	_ = unstructured.SetNestedField(obj.Object, time.Now().UTC().Format(time.RFC3339), "status", "lastUpdate")
+	return probe, r.Client.Status().Update(ctx, obj)
+}
+
+// runningPod returns a running game pod with an IP, if there is one
+func runningPod(pods []corev1.Pod) *corev1.Pod {
+	for i := range pods {
+		if pods[i].Status.Phase == corev1.PodRunning && pods[i].Status.PodIP != "" && pods[i].DeletionTimestamp == nil {
+			return &pods[i]
+		}
+	}
+	return nil
+}
+
+// queryPlayers asks the game for its player count through the catalog's
+// query adapter. Games without one return ErrNoQuery and keep their
+// reported count.
+func (r *Reconciler) queryPlayers(ctx context.Context, obj *unstructured.Unstructured, pod *corev1.Pod) (int, error) {
+	gameType, _, _ := unstructured.NestedString(obj.Object, "spec", "gameType")
+	return QueryPlayers(ctx, gameType, pod.Status.PodIP)
+}
+
+// crashCondition reports crash loops and recent failed exits of the game
+// containers
+func crashCondition(pods []corev1.Pod, now time.Time) metav1.Condition {
+	for _, pod := range pods {
+		for _, container := range pod.Status.ContainerStatuses {
+			if waiting := container.State.Waiting; waiting != nil && waiting.Reason == "CrashLoopBackOff" {
+				return metav1.Condition{
+					Type:    ConditionCrashing,
+					Status:  metav1.ConditionTrue,
+					Reason:  "CrashLoopBackOff",
+					Message: fmt.Sprintf("Container %s is crash looping after %d restarts%s", container.Name, container.RestartCount, lastExit(container)),
+				}
+			}
+			terminated := container.LastTerminationState.Terminated
+			if terminated == nil || terminated.ExitCode == 0 || now.Sub(terminated.FinishedAt.Time) > crashWindow {
+				continue
+			}
+			reason := terminated.Reason
+			if reason == "" {
+				reason = "Error"
+			}
+			return metav1.Condition{
+				Type:    ConditionCrashing,
+				Status:  metav1.ConditionTrue,
+				Reason:  reason,
+				Message: fmt.Sprintf("Container %s restarted at %s%s", container.Name, terminated.FinishedAt.UTC().Format(time.RFC3339), lastExit(container)),
+			}
+		}
+	}
+	return metav1.Condition{
+		Type:    ConditionCrashing,
+		Status:  metav1.ConditionFalse,
+		Reason:  "NoRecentCrashes",
+		Message: "Game containers have not crashed recently",
+	}
+}
+
+// lastExit describes a container's last termination
+func lastExit(container corev1.ContainerStatus) string {
+	terminated := container.LastTerminationState.Terminated
+	if terminated == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (last exit: %s, code %d)", terminated.Reason, terminated.ExitCode)
+}
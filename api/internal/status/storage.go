@@ -0,0 +1,92 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// nodeSummary is the part of the kubelet stats summary used for volumes
+type nodeSummary struct {
+	Pods []struct {
+		Volumes []volumeStats `json:"volume"`
+	} `json:"pods"`
+}
+
+// volumeStats is the kubelet's usage of one pod volume
+type volumeStats struct {
+	UsedBytes     *uint64 `json:"usedBytes"`
+	CapacityBytes *uint64 `json:"capacityBytes"`
+	PVCRef        *struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"pvcRef"`
+}
+
+// volumeStatsCache holds node summaries fetched during one reconcile pass
+type volumeStatsCache map[string]*nodeSummary
+
+// storageCondition reports whether the game volume of a pod is nearly full,
+// from the kubelet stats of the pod's node, and the used fraction
+func (r *Reconciler) storageCondition(ctx context.Context, pod *corev1.Pod, claim string, cache volumeStatsCache) (metav1.Condition, float64, bool) {
+	if pod.Spec.NodeName == "" {
+		return metav1.Condition{}, 0, false
+	}
+	summary, ok := cache[pod.Spec.NodeName]
+	if !ok {
+		summary, _ = r.nodeSummary(ctx, pod.Spec.NodeName)
+		cache[pod.Spec.NodeName] = summary
+	}
+	if summary == nil {
+		return metav1.Condition{}, 0, false
+	}
+
+	for _, p := range summary.Pods {
+		for _, volume := range p.Volumes {
+			if volume.PVCRef == nil || volume.PVCRef.Name != claim || volume.PVCRef.Namespace != pod.Namespace {
+				continue
+			}
+			if volume.UsedBytes == nil || volume.CapacityBytes == nil || *volume.CapacityBytes == 0 {
+				return metav1.Condition{}, 0, false
+			}
+			used, capacity := *volume.UsedBytes, *volume.CapacityBytes
+			ratio := float64(used) / float64(capacity)
+			message := fmt.Sprintf("Storage %.0f%% used (%s of %s)", ratio*100,
+				resource.NewQuantity(int64(used), resource.BinarySI).String(),
+				resource.NewQuantity(int64(capacity), resource.BinarySI).String())
+			condition := metav1.Condition{
+				Type:    ConditionStoragePressure,
+				Status:  metav1.ConditionFalse,
+				Reason:  "SufficientStorage",
+				Message: message,
+			}
+			if ratio >= storagePressureRatio {
+				condition.Status = metav1.ConditionTrue
+				condition.Reason = "StorageNearlyFull"
+			}
+			return condition, ratio, true
+		}
+	}
+	return metav1.Condition{}, 0, false
+}
+
+// nodeSummary fetches the kubelet stats summary through the API server proxy
+func (r *Reconciler) nodeSummary(ctx context.Context, node string) (*nodeSummary, error) {
+	raw, err := r.Kube.CoreV1().RESTClient().
+		Get().
+		AbsPath("/api/v1/nodes", node, "proxy", "stats", "summary").
+		Do(ctx).
+		Raw()
+	if err != nil {
+		return nil, err
+	}
+	summary := &nodeSummary{}
+	if err := json.Unmarshal(raw, summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
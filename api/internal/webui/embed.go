@@ -0,0 +1,29 @@
+//go:build embedui
+
+package webui
+
+import (
+	"embed"
+	"io/fs"
+)
+
+var (
+	//go:embed all:public
+	publicFiles embed.FS
+
+	//go:embed all:static
+	staticFiles embed.FS
+)
+
+// embeddedAssets returns the assets compiled into the binary
+func embeddedAssets() (public, static fs.FS, ok bool) {
+	public, err := fs.Sub(publicFiles, "public")
+	if err != nil {
+		return nil, nil, false
+	}
+	static, err = fs.Sub(staticFiles, "static")
+	if err != nil {
+		return nil, nil, false
+	}
+	return public, static, true
+}
@@ -0,0 +1,10 @@
+//go:build !embedui
+
+package webui
+
+import "io/fs"
+
+// embeddedAssets reports that this binary was built without the dashboard
+func embeddedAssets() (public, static fs.FS, ok bool) {
+	return nil, nil, false
+}
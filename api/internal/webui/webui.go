@@ -0,0 +1,78 @@
+// Package webui serves the Hugo dashboard. Binaries built with
+// `-tags embedui` carry the dashboard inside them, so they work from any
+// working directory:
+//
+//	go generate ./internal/webui
+//	go build -tags embedui .
+//
+// STATIC_DIR points at a directory holding public/ (the Hugo build output)
+// and static/, and takes precedence over the embedded copy so the dashboard
+// can be edited without rebuilding the API.
+package webui
+
+//go:generate sh -c "rm -rf public static && cp -R ../../../web-ui/public public && cp -R ../../../web-ui/themes/gameplane/static static && touch public/.gitkeep static/.gitkeep"
+
+import (
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Assets returns the dashboard's public and static file trees
+func Assets() (public, static fs.FS) {
+	if dir := os.Getenv("STATIC_DIR"); dir != "" {
+		log.Printf("Serving dashboard from %s", dir)
+		return dirAssets(dir)
+	}
+	if public, static, ok := embeddedAssets(); ok {
+		return public, static
+	}
+	// Unembedded builds keep serving relative to the working directory
+	return dirAssets(".")
+}
+
+// dirAssets reads the assets from dir/public and dir/static
+func dirAssets(dir string) (public, static fs.FS) {
+	return os.DirFS(filepath.Join(dir, "public")), os.DirFS(filepath.Join(dir, "static"))
+}
+
+// Handler serves files from public for paths under prefix, answering paths
+// that don't match a file with the dashboard's index page
+func Handler(public fs.FS, prefix string) gin.HandlerFunc {
+	files := http.StripPrefix(prefix, http.FileServer(http.FS(public)))
+	return func(c *gin.Context) {
+		urlPath := c.Request.URL.Path
+		if urlPath != prefix && !strings.HasPrefix(urlPath, prefix+"/") {
+			c.String(http.StatusNotFound, "404 page not found")
+			return
+		}
+		name := strings.TrimPrefix(path.Clean("/"+strings.TrimPrefix(urlPath, prefix)), "/")
+		if name == "" {
+			name = "."
+		}
+		if info, err := fs.Stat(public, name); err == nil {
+			if !info.IsDir() || fileExists(public, path.Join(name, "index.html")) {
+				files.ServeHTTP(c.Writer, c.Request)
+				return
+			}
+		}
+
+		index, err := fs.ReadFile(public, "index.html")
+		if err != nil {
+			c.String(http.StatusNotFound, "404 page not found")
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", index)
+	}
+}
+
+func fileExists(fsys fs.FS, name string) bool {
+	info, err := fs.Stat(fsys, name)
+	return err == nil && !info.IsDir()
+}
@@ -1,155 +1,88 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
-	"time"
 
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/bootstrap"
+	"github.com/kubelize/gameplane/api/pkg/gameplane"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
 )
 
-// Server represents the API server
-type Server struct {
-	k8sClient   client.Client
-	kubeClient  kubernetes.Interface
-	router      *gin.Engine
-	port        string
-}
+func main() {
+	dev := flag.Bool("dev", false, "serve demo GameServers from an in-memory cluster instead of Kubernetes")
+	flag.Parse()
 
-// NewServer creates a new API server instance
-func NewServer() (*Server, error) {
-	// Create Kubernetes client
-	config, err := getKubernetesConfig()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get kubernetes config: %w", err)
+	if flag.Arg(0) == "bootstrap" {
+		os.Exit(runBootstrap(flag.Args()[1:]))
 	}
 
-	// Create controller-runtime client for custom resources
-	scheme := runtime.NewScheme()
-	k8sClient, err := client.New(config, client.Options{Scheme: scheme})
-	if err != nil {
-		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	opts := []gameplane.Option{}
+	if *dev {
+		log.Printf("Dev mode: using an in-memory cluster with demo GameServers")
+		opts = append(opts, gameplane.WithDevCluster())
 	}
 
-	// Create standard kubernetes client for core resources
-	kubeClient, err := kubernetes.NewForConfig(config)
+	server, err := gameplane.NewServer(opts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create kubernetes core client: %w", err)
-	}
-
-	// Setup Gin router
-	router := gin.Default()
-	
-	// Configure CORS
-	corsConfig := cors.DefaultConfig()
-	corsConfig.AllowOrigins = []string{"http://localhost:1313", "http://localhost:3000"}
-	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
-	router.Use(cors.New(corsConfig))
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+		log.Fatalf("Failed to create server: %v", err)
 	}
 
-	server := &Server{
-		k8sClient:  k8sClient,
-		kubeClient: kubeClient,
-		router:     router,
-		port:       port,
+	if err := server.Start(); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
 	}
-
-	server.setupRoutes()
-	return server, nil
 }
 
-// getKubernetesConfig gets the Kubernetes configuration
-func getKubernetesConfig() (*rest.Config, error) {
-	// Try in-cluster config first
-	config, err := rest.InClusterConfig()
-	if err == nil {
-		return config, nil
-	}
+// runBootstrap installs or verifies the platform in the current kubeconfig
+// context and returns the exit code: 0 when complete, 1 on errors and 2
+// while something is still missing or pending
+func runBootstrap(args []string) int {
+	flags := flag.NewFlagSet("bootstrap", flag.ExitOnError)
+	verify := flags.Bool("verify", false, "only report what is missing, create nothing")
+	manifests := flags.String("manifests", envOr("MANIFESTS_DIR", bootstrap.DefaultManifestsDir), "the repository's crossplane directory")
+	flags.Parse(args)
 
-	// Fall back to kubeconfig file
-	kubeconfig := os.Getenv("KUBECONFIG")
-	if kubeconfig == "" {
-		kubeconfig = os.ExpandEnv("$HOME/.kube/config")
+	restConfig, err := config.GetConfig()
+	if err != nil {
+		log.Printf("Failed to get kubernetes config: %v", err)
+		return 1
 	}
-
-	config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	k8sClient, err := client.New(restConfig, client.Options{Scheme: runtime.NewScheme()})
 	if err != nil {
-		return nil, fmt.Errorf("failed to build config from kubeconfig: %w", err)
+		log.Printf("Failed to create kubernetes client: %v", err)
+		return 1
 	}
 
-	return config, nil
-}
-
-// setupRoutes configures the API routes
-func (s *Server) setupRoutes() {
-	api := s.router.Group("/api/v1")
-	{
-		// Health check
-		api.GET("/health", s.healthCheck)
-		
-		// GameServer management
-		gameservers := api.Group("/gameservers")
-		{
-			gameservers.GET("", s.listGameServers)
-			gameservers.POST("", s.createGameServer)
-			gameservers.GET("/:namespace/:name", s.getGameServer)
-			gameservers.PUT("/:namespace/:name", s.updateGameServer)
-			gameservers.DELETE("/:namespace/:name", s.deleteGameServer)
-			gameservers.GET("/:namespace/:name/logs", s.getGameServerLogs)
-			gameservers.GET("/:namespace/:name/metrics", s.getGameServerMetrics)
-			gameservers.POST("/:namespace/:name/restart", s.restartGameServer)
+	report, err := bootstrap.Run(context.Background(), k8sClient, bootstrap.Options{ManifestsDir: *manifests, Verify: *verify})
+	if err != nil {
+		log.Printf("Failed to bootstrap: %v", err)
+		return 1
+	}
+	for _, result := range report.Results {
+		line := fmt.Sprintf("%-8s %s/%s", result.Action, result.Kind, result.Name)
+		if result.Message != "" {
+			line += ": " + result.Message
 		}
-
-		// Namespace management
-		api.GET("/namespaces", s.listNamespaces)
-		
-		// Cluster info
-		api.GET("/cluster/info", s.getClusterInfo)
+		fmt.Println(line)
 	}
-
-	// Serve static files (Hugo build output)
-	s.router.Static("/static", "./static")
-	s.router.StaticFile("/", "./public/index.html")
-	s.router.NoRoute(func(c *gin.Context) {
-		c.File("./public/index.html")
-	})
-}
-
-// healthCheck returns the health status of the API
-func (s *Server) healthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status":    "healthy",
-		"timestamp": time.Now().UTC(),
-		"version":   "1.0.0",
-	})
-}
-
-// Start starts the API server
-func (s *Server) Start() error {
-	log.Printf("Starting GamePlane API server on port %s", s.port)
-	return s.router.Run(":" + s.port)
-}
-
-func main() {
-	server, err := NewServer()
-	if err != nil {
-		log.Fatalf("Failed to create server: %v", err)
+	if report.Counts[bootstrap.ActionFailed] > 0 {
+		return 1
 	}
+	if !report.Complete {
+		return 2
+	}
+	return 0
+}
 
-	if err := server.Start(); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+// envOr returns the environment variable key, or fallback when unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
+	return fallback
 }
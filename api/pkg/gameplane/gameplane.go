@@ -0,0 +1,164 @@
+// Package gameplane embeds the GamePlane API server. NewServer builds the same
+// server as the gameplane-api binary; options plug in authentication,
+// metrics, the cluster registry and, for tests, Kubernetes clients.
+//
+//	server, err := gameplane.NewServer(
+//		gameplane.WithAuth(myAuthenticator),
+//		gameplane.WithMetrics(myRecorder),
+//	)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	server.Router().GET("/custom", customHandler)
+//	log.Fatal(server.Start())
+package gameplane
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kubelize/gameplane/api/internal/devcluster"
+	"github.com/kubelize/gameplane/api/internal/handlers"
+	"github.com/kubelize/gameplane/api/internal/server"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type (
+	// Authenticator identifies the caller of an API request; an error
+	// rejects the request with 401 Unauthorized
+	Authenticator = handlers.Authenticator
+
+	// MetricsRecorder observes every request with its matched route
+	MetricsRecorder = handlers.MetricsRecorder
+
+	// NamespaceAccess limits the namespaces a caller may read and act in
+	NamespaceAccess = handlers.NamespaceAccess
+)
+
+// SubjectKey is the gin context key holding the authenticated subject
+const SubjectKey = handlers.SubjectKey
+
+// ClusterRegistry configures the multi-cluster registry. Empty fields fall
+// back to CLUSTER_NAME and CLUSTER_REGISTRY_NAMESPACE.
+type ClusterRegistry struct {
+	// LocalName names the cluster the server runs in
+	LocalName string
+	// Namespace holds the kubeconfig secrets of remote clusters
+	Namespace string
+}
+
+// Option customises a Server
+type Option func(*server.Options)
+
+// WithAuth requires every API request except the health check to pass auth
+func WithAuth(auth Authenticator) Option {
+	return func(o *server.Options) {
+		o.Authenticator = auth
+	}
+}
+
+// WithNamespaceAccess limits callers to the namespaces access allows:
+// lists only include them and requests for others get 403 Forbidden
+func WithNamespaceAccess(access NamespaceAccess) Option {
+	return func(o *server.Options) {
+		o.NamespaceAccess = access
+	}
+}
+
+// WithAdmins names the subjects allowed to administer the installation.
+// Other callers submit server requests for an admin to approve instead of
+// creating GameServers themselves.
+func WithAdmins(subjects ...string) Option {
+	return func(o *server.Options) {
+		o.Admins = subjects
+	}
+}
+
+// WithNamespaces limits the namespaces GameServers may live in to those
+// matching an allow pattern, or labelled gameplane.kubelize.io/enabled,
+// and none of the deny patterns; patterns are shell globs. System
+// namespaces are always denied.
+func WithNamespaces(allow, deny []string) Option {
+	return func(o *server.Options) {
+		o.NamespaceAllow = allow
+		o.NamespaceDeny = deny
+	}
+}
+
+// WithMetrics reports every request to recorder
+func WithMetrics(recorder MetricsRecorder) Option {
+	return func(o *server.Options) {
+		o.Metrics = recorder
+	}
+}
+
+// WithClusterRegistry configures the local cluster name and the namespace
+// remote clusters are registered in
+func WithClusterRegistry(registry ClusterRegistry) Option {
+	return func(o *server.Options) {
+		o.ClusterName = registry.LocalName
+		o.ClusterRegistryNamespace = registry.Namespace
+	}
+}
+
+// WithKubernetesClients talks to the local cluster through the given
+// clients instead of ones built from the environment, e.g. fakes in tests.
+// config is only used for the cluster's address and may be nil.
+func WithKubernetesClients(config *rest.Config, k8sClient client.Client, kubeClient kubernetes.Interface) Option {
+	return func(o *server.Options) {
+		o.Config = config
+		o.K8sClient = k8sClient
+		o.KubeClient = kubeClient
+	}
+}
+
+// WithDevCluster serves seeded demo GameServers from an in-memory cluster
+// instead of a real one. GameServers created through the API are
+// provisioned a few seconds later, as Crossplane would.
+func WithDevCluster() Option {
+	return func(o *server.Options) {
+		o.DevCluster = devcluster.Demo()
+	}
+}
+
+// Server is an embeddable GamePlane API server
+type Server struct {
+	server *server.Server
+}
+
+// NewServer creates a server with the given options
+func NewServer(opts ...Option) (*Server, error) {
+	options := server.Options{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	s, err := server.NewServer(options)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{server: s}, nil
+}
+
+// Router returns the server's router, for adding routes or middleware
+func (s *Server) Router() *gin.Engine {
+	return s.server.Router()
+}
+
+// Handler returns the server as an http.Handler. Background tasks only run
+// after Start, or StartBackground when serving the handler yourself.
+func (s *Server) Handler() http.Handler {
+	return s.server.Router()
+}
+
+// StartBackground starts the cluster registry refresh, event bus, activity
+// log and background tasks without listening for requests
+func (s *Server) StartBackground() {
+	s.server.StartBackground()
+}
+
+// Start starts the background work and serves the API on PORT
+func (s *Server) Start() error {
+	return s.server.Start()
+}
@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// azureAPIVersion is the Blob service version requests are made against;
+// from 2019-12-12 a single Put Blob may upload up to 5000 MiB
+const azureAPIVersion = "2021-08-06"
+
+// azureMaxBlobSize is the largest blob a single Put Blob may upload
+const azureMaxBlobSize = 5000 << 20
+
+// azureBackend talks to Azure Blob Storage with Shared Key or SAS
+// authentication
+type azureBackend struct {
+	endpoint  *url.URL
+	account   string
+	container string
+	key       []byte
+	sas       url.Values
+}
+
+func newAzure(cfg Config, creds Credentials) (*azureBackend, error) {
+	if cfg.Account == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("azure storage needs an account and a bucket (the container)")
+	}
+	backend := &azureBackend{account: cfg.Account, container: cfg.Bucket}
+	switch {
+	case creds.SASToken != "":
+		sas, err := url.ParseQuery(creds.SASToken)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sasToken: %w", err)
+		}
+		backend.sas = sas
+	case creds.AccountKey != "":
+		key, err := base64.StdEncoding.DecodeString(creds.AccountKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid accountKey: %w", err)
+		}
+		backend.key = key
+	default:
+		return nil, fmt.Errorf("azure storage needs accountKey or sasToken credentials")
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", cfg.Account)
+	}
+	parsed, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid storage endpoint %q", endpoint)
+	}
+	backend.endpoint = parsed
+	return backend, nil
+}
+
+func (b *azureBackend) String() string {
+	return fmt.Sprintf("azure://%s/%s", b.account, b.container)
+}
+
+// blobURL addresses a blob, or the container for an empty name
+func (b *azureBackend) blobURL(name string, query url.Values) *url.URL {
+	u := *b.endpoint
+	path := "/" + b.container
+	if name != "" {
+		path += "/" + name
+	}
+	u.Path = u.Path + path
+	u.RawPath = b.endpoint.EscapedPath() + escapePath(path)
+	if query == nil {
+		query = url.Values{}
+	}
+	for key, values := range b.sas {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+func (b *azureBackend) Put(ctx context.Context, key string, body io.Reader, size int64) error {
+	body, size, done, err := sized(body, size)
+	if err != nil {
+		return err
+	}
+	defer done()
+	if size > azureMaxBlobSize {
+		return fmt.Errorf("object of %d bytes is larger than the 5000 MiB a single upload allows", size)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, b.blobURL(key, nil).String(), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("X-Ms-Blob-Type", "BlockBlob")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	resp, err := b.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (b *azureBackend) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.blobURL(key, nil).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (b *azureBackend) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, b.blobURL(key, nil).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := b.do(req)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// azureListResult is a page of List Blobs
+type azureListResult struct {
+	Blobs []struct {
+		Name       string `xml:"Name"`
+		Properties struct {
+			LastModified  string `xml:"Last-Modified"`
+			ContentLength int64  `xml:"Content-Length"`
+		} `xml:"Properties"`
+	} `xml:"Blobs>Blob"`
+	NextMarker string `xml:"NextMarker"`
+}
+
+func (b *azureBackend) List(ctx context.Context, prefix string) ([]Object, error) {
+	objects := []Object{}
+	marker := ""
+	for {
+		query := url.Values{"restype": {"container"}, "comp": {"list"}, "prefix": {prefix}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.blobURL("", query).String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := b.do(req)
+		if err != nil {
+			return nil, err
+		}
+		var page azureListResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid list response: %w", err)
+		}
+		for _, blob := range page.Blobs {
+			modified, _ := time.Parse(time.RFC1123, blob.Properties.LastModified)
+			objects = append(objects, Object{Key: blob.Name, Size: blob.Properties.ContentLength, Modified: modified.UTC()})
+		}
+		if page.NextMarker == "" {
+			return objects, nil
+		}
+		marker = page.NextMarker
+	}
+}
+
+// do authorizes and sends a request, returning an error for non-2xx
+// responses
+func (b *azureBackend) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("X-Ms-Date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("X-Ms-Version", azureAPIVersion)
+	if b.key != nil {
+		req.Header.Set("Authorization", "SharedKey "+b.account+":"+b.signature(req))
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		return nil, responseError(resp)
+	}
+	return resp, nil
+}
+
+// signature computes the Shared Key signature of a request
+func (b *azureBackend) signature(req *http.Request) string {
+	length := ""
+	if req.ContentLength > 0 {
+		length = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	headers := []string{}
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-ms-") {
+			headers = append(headers, lower)
+		}
+	}
+	sort.Strings(headers)
+	var canonicalHeaders strings.Builder
+	for _, name := range headers {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(req.Header.Get(name)) + "\n")
+	}
+
+	// Azurite and other emulators serve the account as the first path
+	// segment, which is then part of the path already
+	var resource strings.Builder
+	resource.WriteString("/" + b.account + req.URL.EscapedPath())
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, strings.ToLower(key))
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		values := append([]string{}, query[key]...)
+		sort.Strings(values)
+		resource.WriteString("\n" + key + ":" + strings.Join(values, ","))
+	}
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		length,
+		req.Header.Get("Content-Md5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, superseded by x-ms-date
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		canonicalHeaders.String() + resource.String(),
+	}, "\n")
+	mac := hmac.New(sha256.New, b.key)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
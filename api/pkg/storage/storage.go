@@ -0,0 +1,248 @@
+// Package storage stores archives such as backups, world exports and log
+// archives outside the cluster. Backends are S3 and S3-compatible services,
+// Google Cloud Storage (through its S3-compatible XML API with HMAC keys),
+// Azure Blob Storage and a local directory, typically a mounted
+// PersistentVolumeClaim.
+//
+//	backend, err := storage.New(storage.Config{Type: "s3", Bucket: "worlds", Region: "eu-west-1"},
+//		storage.Credentials{AccessKeyID: id, SecretAccessKey: secret})
+//	if err != nil {
+//		return err
+//	}
+//	err = backend.Put(ctx, "exports/world.tar.gz", archive, -1)
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Backend types
+const (
+	TypeS3    = "s3"
+	TypeGCS   = "gcs"
+	TypeAzure = "azure"
+	TypeLocal = "local"
+)
+
+// ErrNotFound is returned for keys that do not exist
+var ErrNotFound = errors.New("object not found")
+
+// Backend stores objects under slash-separated keys
+type Backend interface {
+	// Put stores body under key, replacing any existing object. A negative
+	// size spools the body to a temporary file first, since the object
+	// stores need the length up front.
+	Put(ctx context.Context, key string, body io.Reader, size int64) error
+	// Get opens the object stored under key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// List returns the objects whose keys start with prefix
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// Delete removes the object stored under key; missing objects are not
+	// an error
+	Delete(ctx context.Context, key string) error
+	// String describes where objects are stored, without credentials
+	String() string
+}
+
+// Object describes a stored object
+type Object struct {
+	Key      string    `json:"key"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+// Config selects and locates a backend
+type Config struct {
+	// Type is s3, gcs, azure or local
+	Type string `json:"type"`
+	// Bucket is the bucket, or the container for Azure
+	Bucket string `json:"bucket,omitempty"`
+	// Prefix is prepended to every key
+	Prefix string `json:"prefix,omitempty"`
+	// Endpoint overrides the service URL, e.g. for MinIO or Azurite
+	Endpoint string `json:"endpoint,omitempty"`
+	// Region of an S3 bucket, us-east-1 by default
+	Region string `json:"region,omitempty"`
+	// PathStyle addresses S3 buckets as endpoint/bucket instead of
+	// bucket.endpoint, as most S3-compatible services need
+	PathStyle bool `json:"pathStyle,omitempty"`
+	// Account is the Azure storage account
+	Account string `json:"account,omitempty"`
+	// Path is the directory of the local backend
+	Path string `json:"path,omitempty"`
+}
+
+// Credentials authenticate to a backend. S3 and GCS use an access key pair
+// (HMAC keys for GCS); Azure uses an account key or a SAS token.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	AccountKey      string
+	SASToken        string
+}
+
+// CredentialsFromSecret reads credentials from the data of a Kubernetes
+// Secret with the keys accessKeyId, secretAccessKey, sessionToken,
+// accountKey and sasToken
+func CredentialsFromSecret(data map[string][]byte) Credentials {
+	value := func(key string) string {
+		return strings.TrimSpace(string(data[key]))
+	}
+	return Credentials{
+		AccessKeyID:     value("accessKeyId"),
+		SecretAccessKey: value("secretAccessKey"),
+		SessionToken:    value("sessionToken"),
+		AccountKey:      value("accountKey"),
+		SASToken:        strings.TrimPrefix(value("sasToken"), "?"),
+	}
+}
+
+// New creates the backend described by cfg
+func New(cfg Config, creds Credentials) (Backend, error) {
+	var backend Backend
+	var err error
+	switch cfg.Type {
+	case TypeS3, TypeGCS:
+		backend, err = newS3(cfg, creds)
+	case TypeAzure:
+		backend, err = newAzure(cfg, creds)
+	case TypeLocal:
+		backend, err = newLocal(cfg)
+	case "":
+		return nil, fmt.Errorf("storage type is required")
+	default:
+		return nil, fmt.Errorf("unknown storage type %q: must be s3, gcs, azure or local", cfg.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if prefix := strings.Trim(cfg.Prefix, "/"); prefix != "" {
+		backend = &prefixed{Backend: backend, prefix: prefix + "/"}
+	}
+	return backend, nil
+}
+
+// Check writes, reads back and deletes a small probe object, verifying that
+// the backend is reachable and the credentials may write
+func Check(ctx context.Context, backend Backend) error {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	key := ".gameplane-health/" + hex.EncodeToString(id)
+	probe := []byte("gameplane storage health check " + time.Now().UTC().Format(time.RFC3339))
+
+	if err := backend.Put(ctx, key, bytes.NewReader(probe), int64(len(probe))); err != nil {
+		return fmt.Errorf("write: %w", err)
+	}
+	defer func() { _ = backend.Delete(context.WithoutCancel(ctx), key) }()
+	reader, err := backend.Get(ctx, key)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+	defer reader.Close()
+	read, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("read: %w", err)
+	}
+	if !bytes.Equal(read, probe) {
+		return fmt.Errorf("read back %d bytes that differ from the %d written", len(read), len(probe))
+	}
+	if err := backend.Delete(ctx, key); err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	return nil
+}
+
+// prefixed stores every key below a prefix
+type prefixed struct {
+	Backend
+	prefix string
+}
+
+func (p *prefixed) Put(ctx context.Context, key string, body io.Reader, size int64) error {
+	return p.Backend.Put(ctx, p.prefix+key, body, size)
+}
+
+func (p *prefixed) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return p.Backend.Get(ctx, p.prefix+key)
+}
+
+func (p *prefixed) List(ctx context.Context, prefix string) ([]Object, error) {
+	objects, err := p.Backend.List(ctx, p.prefix+prefix)
+	for i := range objects {
+		objects[i].Key = strings.TrimPrefix(objects[i].Key, p.prefix)
+	}
+	return objects, err
+}
+
+func (p *prefixed) Delete(ctx context.Context, key string) error {
+	return p.Backend.Delete(ctx, p.prefix+key)
+}
+
+func (p *prefixed) String() string {
+	return p.Backend.String() + "/" + strings.TrimSuffix(p.prefix, "/")
+}
+
+// sized returns body with its length, spooling it to a temporary file when
+// size is negative. done releases the file.
+func sized(body io.Reader, size int64) (io.Reader, int64, func(), error) {
+	if size >= 0 {
+		return body, size, func() {}, nil
+	}
+	spool, err := os.CreateTemp("", "gameplane-storage-*")
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	done := func() {
+		spool.Close()
+		os.Remove(spool.Name())
+	}
+	size, err = io.Copy(spool, body)
+	if err == nil {
+		_, err = spool.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		done()
+		return nil, 0, nil, err
+	}
+	return spool, size, done, nil
+}
+
+// httpClient is shared by the HTTP backends. Archives can be large, so only
+// connecting and waiting for response headers are bounded.
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: time.Minute,
+		MaxIdleConnsPerHost:   4,
+	},
+}
+
+// responseError turns a failed response into an error, ErrNotFound for 404
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	message := strings.TrimSpace(string(body))
+	if start := strings.Index(message, "<Message>"); start >= 0 {
+		if end := strings.Index(message[start:], "</Message>"); end > 0 {
+			message = message[start+len("<Message>") : start+end]
+		}
+	}
+	if message == "" {
+		message = http.StatusText(resp.StatusCode)
+	}
+	return fmt.Errorf("%s: %s", resp.Status, message)
+}
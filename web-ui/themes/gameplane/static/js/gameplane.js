@@ -5,7 +5,7 @@ class GamePlaneAPI {
         this.baseURL = baseURL;
     }
 
-    async fetchServers(namespace = 'default') {
+    async fetchServers(namespace = 'all') {
         try {
             const url = `${this.baseURL}/gameservers?namespace=${namespace}`;
             const response = await fetch(url);